@@ -2,13 +2,38 @@ package apitypes
 
 import (
 	"encoding/json"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultProblemThreshold = 15 * time.Minute
+	defaultPauseDuration    = 5 * time.Minute
 )
 
 type KappSpecificData struct {
 	*v1alpha1.AppSpec `json:",inline"`
 	InternalExport    map[string]InternalExportEntry `json:"internalExport,omitempty"`
+	CircuitBreaker    *CircuitBreakerPolicy          `json:"circuitBreaker,omitempty"`
+	// DeletionTimeout is the time after which the finalizers of a kapp app are removed, if kapp-controller has not
+	// deleted it until then. Defaults to the deletion timeout of the hub controller.
+	DeletionTimeout *metav1.Duration `json:"deletionTimeout,omitempty"`
+	// DebugStatus adds the unmodified status of the kapp app to the type specific status of the deploy item
+	DebugStatus bool `json:"debugStatus,omitempty"`
+}
+
+// CircuitBreakerPolicy defines when the reconciliation of a kapp app with problems is paused, so that a failing app
+// does not keep kapp-controller busy. After the pause duration the app is reconciled again.
+type CircuitBreakerPolicy struct {
+	// Disabled switches the circuit breaker off, so that the app is never paused
+	Disabled bool `json:"disabled,omitempty"`
+	// ProblemThreshold is the time an app must have problems before it is paused. Defaults to 15m.
+	ProblemThreshold *metav1.Duration `json:"problemThreshold,omitempty"`
+	// PauseDuration is the time an app stays paused before it is reconciled again. Defaults to 5m.
+	PauseDuration *metav1.Duration `json:"pauseDuration,omitempty"`
 }
 
 func NewKappSpecificData(typeSpecificData []byte) (*KappSpecificData, error) {
@@ -19,3 +44,56 @@ func NewKappSpecificData(typeSpecificData []byte) (*KappSpecificData, error) {
 
 	return &kappSpecificData, nil
 }
+
+func (d *KappSpecificData) ValidateDeletionTimeout() error {
+	if d.DeletionTimeout != nil && d.DeletionTimeout.Duration <= 0 {
+		return errors.New("property \"deletionTimeout\" must be positive")
+	}
+
+	return nil
+}
+
+// GetDeletionTimeout returns the deletion timeout of the kapp app, or the default deletion timeout if none is specified.
+func (d *KappSpecificData) GetDeletionTimeout(defaultDeletionTimeout time.Duration) time.Duration {
+	if d.DeletionTimeout == nil {
+		return defaultDeletionTimeout
+	}
+
+	return d.DeletionTimeout.Duration
+}
+
+func (p *CircuitBreakerPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.ProblemThreshold != nil && p.ProblemThreshold.Duration <= 0 {
+		return errors.New("property \"circuitBreaker.problemThreshold\" must be positive")
+	}
+
+	if p.PauseDuration != nil && p.PauseDuration.Duration <= 0 {
+		return errors.New("property \"circuitBreaker.pauseDuration\" must be positive")
+	}
+
+	return nil
+}
+
+func (p *CircuitBreakerPolicy) IsDisabled() bool {
+	return p != nil && p.Disabled
+}
+
+func (p *CircuitBreakerPolicy) GetProblemThreshold() time.Duration {
+	if p == nil || p.ProblemThreshold == nil {
+		return defaultProblemThreshold
+	}
+
+	return p.ProblemThreshold.Duration
+}
+
+func (p *CircuitBreakerPolicy) GetPauseDuration() time.Duration {
+	if p == nil || p.PauseDuration == nil {
+		return defaultPauseDuration
+	}
+
+	return p.PauseDuration.Duration
+}
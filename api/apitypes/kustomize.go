@@ -0,0 +1,179 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AllowedGitSchemes are the schemes of the git urls from which kustomization sources are cloned. Other schemes, in
+// particular file urls and local paths, are rejected, so that the controller cannot be made to read its own files.
+var AllowedGitSchemes = []string{"https", "ssh"}
+
+// scpLikeGitURL matches git urls in the scp-like syntax, e.g. "git@github.com:org/repo.git", which use ssh
+var scpLikeGitURL = regexp.MustCompile(`^(?:[^@/\s]+@)?[^:/\s]+:[^\\]`)
+
+type KustomizeSpecificData struct {
+	// Namespace is used for namespaced objects without namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// Path of the directory with the kustomization file within the source
+	Path string `json:"path,omitempty"`
+
+	// Exactly one of the following sources must be specified
+	Tarball *KustomizeTarball `json:"tarball,omitempty"`
+	Git     *KustomizeGit     `json:"git,omitempty"`
+	Files   map[string]string `json:"files,omitempty"`
+
+	// Secrets are generated from the secret values or named secret values of the application
+	Secrets []KustomizeSecret `json:"secrets,omitempty"`
+	// SecretPatches are patches which are read from named secret values of the application
+	SecretPatches []KustomizeSecretPatch `json:"secretPatches,omitempty"`
+
+	InternalExport map[string]InternalExportEntry `json:"internalExport,omitempty"`
+}
+
+// KustomizeTarball references a gzipped tar archive
+type KustomizeTarball struct {
+	URL          string `json:"url,omitempty"`
+	CustomCAData string `json:"customCAData,omitempty"`
+}
+
+// KustomizeGit references a git repository. Ref is a branch or tag; if empty the default branch is used.
+type KustomizeGit struct {
+	URL string `json:"url,omitempty"`
+	Ref string `json:"ref,omitempty"`
+}
+
+// KustomizeSecret describes a secret on the target cluster, which is generated from the secret values of the
+// application, or, if SecretRef is set, from the named secret values with that logical name.
+type KustomizeSecret struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+// KustomizeSecretPatch describes a patch which is stored under a key of named secret values. Without target the
+// patch is a strategic merge patch, otherwise a json or strategic merge patch for the selected objects.
+type KustomizeSecretPatch struct {
+	SecretRef string                `json:"secretRef,omitempty"`
+	Key       string                `json:"key,omitempty"`
+	Target    *KustomizePatchTarget `json:"target,omitempty"`
+}
+
+type KustomizePatchTarget struct {
+	Group              string `json:"group,omitempty"`
+	Version            string `json:"version,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+func NewKustomizeSpecificData(typeSpecificData *runtime.RawExtension) (*KustomizeSpecificData, error) {
+	var kustomizeSpecificData KustomizeSpecificData
+	if err := json.Unmarshal(typeSpecificData.Raw, &kustomizeSpecificData); err != nil {
+		return nil, err
+	}
+
+	if err := kustomizeSpecificData.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &kustomizeSpecificData, nil
+}
+
+func (k *KustomizeSpecificData) Validate() error { // nolint
+	numberOfSources := 0
+	if k.Tarball != nil {
+		numberOfSources++
+	}
+	if k.Git != nil {
+		numberOfSources++
+	}
+	if len(k.Files) > 0 {
+		numberOfSources++
+	}
+
+	if numberOfSources != 1 {
+		return errors.New("exactly one of the properties \"tarball\", \"git\" and \"files\" must be specified")
+	}
+
+	if k.Tarball != nil && k.Tarball.URL == "" {
+		return errors.New("property \"tarball.url\" not found")
+	}
+
+	if k.Git != nil && k.Git.URL == "" {
+		return errors.New("property \"git.url\" not found")
+	}
+
+	if k.Git != nil {
+		if err := ValidateGitURL(k.Git.URL); err != nil {
+			return err
+		}
+	}
+
+	if !isRelativePath(k.Path) {
+		return errors.New("property \"path\" must be a relative path within the source")
+	}
+
+	for filePath := range k.Files {
+		if filePath == "" || !isRelativePath(filePath) {
+			return errors.New("file " + filePath + " must have a relative path")
+		}
+	}
+
+	for i := range k.Secrets {
+		if k.Secrets[i].Name == "" {
+			return errors.New("property \"secrets.name\" not found")
+		}
+	}
+
+	for i := range k.SecretPatches {
+		if k.SecretPatches[i].SecretRef == "" || k.SecretPatches[i].Key == "" {
+			return errors.New("properties \"secretPatches.secretRef\" and \"secretPatches.key\" are required")
+		}
+	}
+
+	return nil
+}
+
+func isRelativePath(p string) bool {
+	if p == "" {
+		return true
+	}
+
+	cleaned := path.Clean(p)
+	return !path.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
+
+// ValidateGitURL returns an error if the git url does not use one of the AllowedGitSchemes
+func ValidateGitURL(gitURL string) error {
+	scheme := getGitURLScheme(gitURL)
+	for _, allowedScheme := range AllowedGitSchemes {
+		if scheme == allowedScheme {
+			return nil
+		}
+	}
+
+	return errors.Errorf("property \"git.url\" must use one of the schemes %s, but uses %s",
+		strings.Join(AllowedGitSchemes, ", "), scheme)
+}
+
+// getGitURLScheme returns the scheme of a git url. Scp-like urls use ssh, and urls without scheme are local paths.
+func getGitURLScheme(gitURL string) string {
+	if i := strings.Index(gitURL, "://"); i >= 0 {
+		return strings.ToLower(gitURL[:i])
+	}
+
+	if scpLikeGitURL.MatchString(gitURL) {
+		return "ssh"
+	}
+
+	return "file"
+}
@@ -0,0 +1,72 @@
+package apitypes
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const DefaultManifestConfigMapKey = "manifest"
+
+type ManifestSpecificData struct {
+	// Namespace is used for namespaced objects without namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// Exactly one of the following sources must be specified
+	Manifests    []runtime.RawExtension `json:"manifests,omitempty"`
+	URL          string                 `json:"url,omitempty"`
+	ConfigMapRef *ConfigMapRef          `json:"configMapRef,omitempty"`
+
+	InternalExport map[string]InternalExportEntry `json:"internalExport,omitempty"`
+}
+
+// ConfigMapRef references a key of a config map in the namespace of the clusterbom. The value of the key contains
+// the manifests as multi document yaml.
+type ConfigMapRef struct {
+	Name string `json:"name,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+func NewManifestSpecificData(typeSpecificData *runtime.RawExtension) (*ManifestSpecificData, error) {
+	var manifestSpecificData ManifestSpecificData
+	if err := json.Unmarshal(typeSpecificData.Raw, &manifestSpecificData); err != nil {
+		return nil, err
+	}
+
+	if err := manifestSpecificData.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &manifestSpecificData, nil
+}
+
+func (m *ManifestSpecificData) Validate() error {
+	numberOfSources := 0
+	if len(m.Manifests) > 0 {
+		numberOfSources++
+	}
+	if m.URL != "" {
+		numberOfSources++
+	}
+	if m.ConfigMapRef != nil {
+		numberOfSources++
+	}
+
+	if numberOfSources != 1 {
+		return errors.New("exactly one of the properties \"manifests\", \"url\" and \"configMapRef\" must be specified")
+	}
+
+	if m.ConfigMapRef != nil && m.ConfigMapRef.Name == "" {
+		return errors.New("property \"configMapRef.name\" not found")
+	}
+
+	return nil
+}
+
+func (m *ManifestSpecificData) GetConfigMapKey() string {
+	if m.ConfigMapRef == nil || m.ConfigMapRef.Key == "" {
+		return DefaultManifestConfigMapKey
+	}
+	return m.ConfigMapRef.Key
+}
@@ -0,0 +1,75 @@
+package apitypes
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	packaging "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/packaging/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type PackageSpecificData struct {
+	// PackageName is the name of the package, e.g. cert-manager.community.tanzu.vmware.com
+	PackageName string `json:"packageName,omitempty"`
+	// Version is a semver constraint for the version of the package, e.g. ">=1.0.0 <2.0.0". If empty, the newest
+	// version is installed.
+	Version string `json:"version,omitempty"`
+	// Prereleases allows prerelease versions to match the version constraint
+	Prereleases bool `json:"prereleases,omitempty"`
+
+	// Namespace on the target cluster in which kapp stores the state of the package installation
+	Namespace string `json:"namespace,omitempty"`
+	// SyncPeriod is the interval in which kapp-controller reconciles the package installation
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+
+	// Values are the inline values of the package. The secret values of the application are applied on top of them.
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// Repository is an optional package repository which provides the package
+	Repository *packaging.PackageRepositoryFetch `json:"repository,omitempty"`
+
+	InternalExport map[string]InternalExportEntry `json:"internalExport,omitempty"`
+}
+
+func NewPackageSpecificData(typeSpecificData *runtime.RawExtension) (*PackageSpecificData, error) {
+	var packageSpecificData PackageSpecificData
+	if err := json.Unmarshal(typeSpecificData.Raw, &packageSpecificData); err != nil {
+		return nil, err
+	}
+
+	if err := packageSpecificData.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &packageSpecificData, nil
+}
+
+func (p *PackageSpecificData) Validate() error {
+	if p.PackageName == "" {
+		return errors.New("property \"packageName\" not found")
+	}
+
+	if p.Repository != nil {
+		numberOfSources := 0
+		if p.Repository.Image != nil {
+			numberOfSources++
+		}
+		if p.Repository.HTTP != nil {
+			numberOfSources++
+		}
+		if p.Repository.Git != nil {
+			numberOfSources++
+		}
+		if p.Repository.ImgpkgBundle != nil {
+			numberOfSources++
+		}
+
+		if numberOfSources != 1 {
+			return errors.New("exactly one of the properties \"repository.image\", \"repository.http\", " +
+				"\"repository.git\" and \"repository.imgpkgBundle\" must be specified")
+		}
+	}
+
+	return nil
+}
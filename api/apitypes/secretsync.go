@@ -0,0 +1,120 @@
+package apitypes
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type SecretSyncSpecificData struct {
+	// Secrets which are copied from the namespace of the clusterbom into the target cluster
+	Secrets []SyncedSecret `json:"secrets,omitempty"`
+}
+
+// SyncedSecret describes the copies of a source secret in the namespace of the clusterbom. The source secret must
+// have the label hub.k8s.sap.com/purpose: secretsync-source.
+type SyncedSecret struct {
+	// Name of the source secret
+	Name string `json:"name,omitempty"`
+	// TargetName is the name of the copies; default is the name of the source secret
+	TargetName string `json:"targetName,omitempty"`
+	// Namespaces on the target cluster into which the secret is copied
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Keys which are copied, optionally under a different name. If empty, all keys are copied.
+	Keys []SyncedSecretKey `json:"keys,omitempty"`
+	// Type of the copies; default is the type of the source secret
+	Type corev1.SecretType `json:"type,omitempty"`
+}
+
+type SyncedSecretKey struct {
+	Key       string `json:"key,omitempty"`
+	TargetKey string `json:"targetKey,omitempty"`
+}
+
+func NewSecretSyncSpecificData(typeSpecificData *runtime.RawExtension) (*SecretSyncSpecificData, error) {
+	var secretSyncSpecificData SecretSyncSpecificData
+	if err := json.Unmarshal(typeSpecificData.Raw, &secretSyncSpecificData); err != nil {
+		return nil, err
+	}
+
+	if err := secretSyncSpecificData.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &secretSyncSpecificData, nil
+}
+
+func (s *SecretSyncSpecificData) Validate() error {
+	if len(s.Secrets) == 0 {
+		return errors.New("property \"secrets\" not found")
+	}
+
+	copies := make(map[string]bool)
+
+	for i := range s.Secrets {
+		secret := &s.Secrets[i]
+
+		if secret.Name == "" {
+			return errors.New("property \"secrets.name\" not found")
+		}
+
+		if len(secret.Namespaces) == 0 {
+			return errors.New("property \"secrets.namespaces\" not found for secret " + secret.Name)
+		}
+
+		for _, namespace := range secret.Namespaces {
+			if namespace == "" {
+				return errors.New("empty namespace for secret " + secret.Name)
+			}
+
+			copyKey := namespace + "/" + secret.GetTargetName()
+			if copies[copyKey] {
+				return errors.New("secret " + copyKey + " is specified more than once")
+			}
+			copies[copyKey] = true
+		}
+
+		targetKeys := make(map[string]bool)
+		for _, key := range secret.Keys {
+			if key.Key == "" {
+				return errors.New("property \"secrets.keys.key\" not found for secret " + secret.Name)
+			}
+
+			if targetKeys[key.GetTargetKey()] {
+				return errors.New("key " + key.GetTargetKey() + " is specified more than once for secret " + secret.Name)
+			}
+			targetKeys[key.GetTargetKey()] = true
+		}
+	}
+
+	return nil
+}
+
+// References returns true if the source secret with the given name is copied.
+func (s *SecretSyncSpecificData) References(secretName string) bool {
+	for i := range s.Secrets {
+		if s.Secrets[i].Name == secretName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *SyncedSecret) GetTargetName() string {
+	if s.TargetName != "" {
+		return s.TargetName
+	}
+
+	return s.Name
+}
+
+func (k *SyncedSecretKey) GetTargetKey() string {
+	if k.TargetKey != "" {
+		return k.TargetKey
+	}
+
+	return k.Key
+}
@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&AdmissionPolicy{}, &AdmissionPolicyList{})
+}
+
+// AdmissionPolicySpec defines rules which the admission webhook enforces for the clusterboms in its scope
+type AdmissionPolicySpec struct {
+	// Scope selects the clusterboms to which the rules apply; all clusterboms if empty
+	// +optional
+	Scope AdmissionPolicyScope `json:"scope,omitempty"`
+
+	// Rules which the clusterboms in the scope must satisfy
+	Rules AdmissionPolicyRules `json:"rules"`
+}
+
+// AdmissionPolicyScope selects clusterboms. A clusterbom is in the scope if it matches all fields which are set.
+type AdmissionPolicyScope struct {
+	// Namespaces of the clusterboms, e.g. the namespaces of Gardener projects
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ClusterBomSelector selects clusterboms by their labels
+	// +optional
+	ClusterBomSelector *metav1.LabelSelector `json:"clusterBomSelector,omitempty"`
+}
+
+// AdmissionPolicyRules are the rules of an admission policy. Rules which are not set are not checked. Patterns may
+// contain the wildcard "*", which matches any sequence of characters.
+type AdmissionPolicyRules struct {
+	// AllowedConfigTypes are the config types which application configs may have
+	// +optional
+	AllowedConfigTypes []string `json:"allowedConfigTypes,omitempty"`
+
+	// AllowedChartRepositories are patterns for the catalog repositories and tarball urls of helm application configs
+	// +optional
+	AllowedChartRepositories []string `json:"allowedChartRepositories,omitempty"`
+
+	// AllowedChartNames are patterns for the chart names of helm application configs. Application configs with
+	// tarballAccess are denied, because their chart name is unknown.
+	// +optional
+	AllowedChartNames []string `json:"allowedChartNames,omitempty"`
+
+	// AllowedTargetNamespaces are patterns for the namespaces on the target cluster into which application configs
+	// deploy
+	// +optional
+	AllowedTargetNamespaces []string `json:"allowedTargetNamespaces,omitempty"`
+
+	// MaxApplicationConfigs is the maximal number of application configs of a clusterbom
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxApplicationConfigs *int32 `json:"maxApplicationConfigs,omitempty"`
+
+	// RequiredLabels are labels which clusterboms must have
+	// +optional
+	RequiredLabels []RequiredLabel `json:"requiredLabels,omitempty"`
+}
+
+// RequiredLabel is a label which a clusterbom must have
+type RequiredLabel struct {
+	// Key of the label
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Values are patterns for the allowed values of the label; any value is allowed if empty
+	// +optional
+	Values []string `json:"values,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// AdmissionPolicy is the Schema for the admissionpolicies API
+type AdmissionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AdmissionPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AdmissionPolicyList contains a list of AdmissionPolicy
+type AdmissionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AdmissionPolicy `json:"items"`
+}
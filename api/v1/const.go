@@ -3,6 +3,9 @@ package v1
 const (
 	AnnotationKeyLandscaperManaged   = "potter.gardener.cloud/landscaper-managed"
 	AnnotationValueLandscaperManaged = "true"
+	// AnnotationKeyAcknowledgedWarnings is a comma separated list of the risky changes which are acknowledged, each
+	// given by the kind of its admission warning and its subject, e.g. "MajorChartVersionBump:my-app@2.0.0".
+	AnnotationKeyAcknowledgedWarnings = "potter.gardener.cloud/acknowledged-warnings"
 
 	LabelClusterBomName         = "hub.kubernetes.sap.com/bom-name"
 	LabelLandscaperManaged      = "potter.gardener.cloud/landscaper-managed"
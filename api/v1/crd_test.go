@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const enumMarker = "+kubebuilder:validation:Enum="
+
+// TestCRDEnumsMatchTypes tests that the enums of the CRDs in config/crd/bases match the enum markers of the types, so
+// that a changed marker is not missed when the CRDs are regenerated with "make manifests".
+func TestCRDEnumsMatchTypes(t *testing.T) {
+	rootTypes := map[string]reflect.Type{
+		"AdmissionPolicy":             reflect.TypeOf(AdmissionPolicy{}),
+		"ClusterBom":                  reflect.TypeOf(ClusterBom{}),
+		"ClusterBomSync":              reflect.TypeOf(ClusterBomSync{}),
+		"DeployerRegistration":        reflect.TypeOf(DeployerRegistration{}),
+		"HubDeployItemConfiguration":  reflect.TypeOf(HubDeployItemConfiguration{}),
+		"HubDeployItemProviderStatus": reflect.TypeOf(HubDeployItemProviderStatus{}),
+		"NotificationPolicy":          reflect.TypeOf(NotificationPolicy{}),
+	}
+
+	markers := readEnumMarkers(t)
+
+	files, err := filepath.Glob("../../config/crd/bases/hub.k8s.sap.com_*.yaml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no crds found: %v", err)
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("could not read %s: %v", file, err)
+		}
+
+		crd := map[string]interface{}{}
+		if err = yaml.Unmarshal(data, &crd); err != nil {
+			t.Fatalf("could not parse %s: %v", file, err)
+		}
+
+		kind, _ := getPath(crd, "spec", "names", "kind").(string)
+		rootType, ok := rootTypes[kind]
+		if !ok {
+			t.Errorf("%s: no type for kind %q", file, kind)
+			continue
+		}
+
+		versions, _ := getPath(crd, "spec", "versions").([]interface{})
+		for _, version := range versions {
+			schema, _ := getPath(version, "schema", "openAPIV3Schema").(map[string]interface{})
+			compareEnums(t, markers, filepath.Base(file), rootType, schema)
+		}
+	}
+}
+
+// readEnumMarkers returns the enum values of the fields and types of this package, with keys "Type.Field" and "Type"
+func readEnumMarkers(t *testing.T) map[string][]string {
+	packages, err := parser.ParseDir(token.NewFileSet(), ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("could not parse types: %v", err)
+	}
+
+	markers := map[string][]string{}
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok {
+					continue
+				}
+
+				for _, spec := range genDecl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+
+					if values := getEnumValues(genDecl.Doc, typeSpec.Doc); values != nil {
+						markers[typeSpec.Name.Name] = values
+					}
+
+					structType, ok := typeSpec.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+
+					for _, field := range structType.Fields.List {
+						values := getEnumValues(field.Doc)
+						for _, name := range field.Names {
+							if values != nil {
+								markers[typeSpec.Name.Name+"."+name.Name] = values
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return markers
+}
+
+func getEnumValues(comments ...*ast.CommentGroup) []string {
+	for _, commentGroup := range comments {
+		if commentGroup == nil {
+			continue
+		}
+
+		for _, comment := range commentGroup.List {
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if strings.HasPrefix(text, enumMarker) {
+				return strings.Split(strings.TrimPrefix(text, enumMarker), ";")
+			}
+		}
+	}
+
+	return nil
+}
+
+// compareEnums walks through the type and its schema, and reports properties whose enum differs from the marker
+func compareEnums(t *testing.T, markers map[string][]string, path string, goType reflect.Type, schema map[string]interface{}) {
+	if schema == nil {
+		return
+	}
+
+	switch goType.Kind() {
+	case reflect.Ptr:
+		compareEnums(t, markers, path, goType.Elem(), schema)
+		return
+	case reflect.Slice, reflect.Array:
+		items, _ := schema["items"].(map[string]interface{})
+		compareEnums(t, markers, path+"[]", goType.Elem(), items)
+		return
+	case reflect.Map:
+		additionalProperties, _ := schema["additionalProperties"].(map[string]interface{})
+		compareEnums(t, markers, path+"{}", goType.Elem(), additionalProperties)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	if goType.PkgPath() != reflect.TypeOf(ClusterBom{}).PkgPath() {
+		return
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for i := 0; i < goType.NumField(); i++ {
+		field := goType.Field(i)
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if strings.Contains(field.Tag.Get("json"), ",inline") || (field.Anonymous && name == "") {
+			compareEnums(t, markers, path, field.Type, schema)
+			continue
+		}
+
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s.%s: property missing in crd", path, name)
+			continue
+		}
+
+		expected, ok := markers[goType.Name()+"."+field.Name]
+		if !ok {
+			expected = markers[field.Type.Name()]
+		}
+
+		var actual []string
+		if enum, ok := property["enum"].([]interface{}); ok {
+			for _, value := range enum {
+				actual = append(actual, value.(string))
+			}
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s.%s: enum in crd %v differs from marker %v; run \"make manifests\"", path, name, actual, expected)
+		}
+
+		compareEnums(t, markers, path+"."+name, field.Type, property)
+	}
+}
+
+func getPath(obj interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = m[key]
+	}
+	return obj
+}
@@ -0,0 +1,71 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&DeployerRegistration{}, &DeployerRegistrationList{})
+}
+
+// DeployerRegistrationSpec registers an out-of-process deployer plugin for a config type
+type DeployerRegistrationSpec struct {
+	// ConfigType is the config type of application configs which are deployed by the plugin
+	// +kubebuilder:validation:MinLength=1
+	ConfigType string `json:"configType"`
+
+	// URL is the base url of the plugin, e.g. https://my-plugin.my-namespace.svc:8443
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// ProtocolVersion is the version of the plugin protocol which is implemented by the plugin
+	// +kubebuilder:validation:Enum=v1
+	// +optional
+	ProtocolVersion string `json:"protocolVersion,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle which is used to validate the certificate of the plugin
+	// +optional
+	CABundle string `json:"caBundle,omitempty"`
+
+	// TimeoutSeconds is the timeout of a request to the plugin; default 60 seconds
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// DeployerRegistration is the Schema for the deployerregistrations API
+// +kubebuilder:printcolumn:name="CONFIGTYPE",type="string",JSONPath=".spec.configType"
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.url"
+type DeployerRegistration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DeployerRegistrationSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DeployerRegistrationList contains a list of DeployerRegistration
+type DeployerRegistrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DeployerRegistration `json:"items"`
+}
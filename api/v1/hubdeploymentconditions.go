@@ -44,6 +44,8 @@ type HubDeploymentConditionType string
 // These are valid conditions of a hubdeploymentconfig.
 const (
 	HubDeploymentReady HubDeploymentConditionType = "Ready"
+	// HubDeploymentPaused is true if the circuit breaker has paused the reconciliation of a kapp app with problems
+	HubDeploymentPaused HubDeploymentConditionType = "Paused"
 )
 
 type HubDeploymentConditionReason string
@@ -59,4 +61,9 @@ const (
 	ReasonFinallyFailed        HubDeploymentConditionReason = "FinallyFailed"
 	ReasonNotCurrentGeneration HubDeploymentConditionReason = "NotCurrentGeneration"
 	ReasonCouldNotGetExport    HubDeploymentConditionReason = "CouldNotGetExport"
+	ReasonRetriesExhausted     HubDeploymentConditionReason = "RetriesExhausted"
+	ReasonRetriesStopped       HubDeploymentConditionReason = "RetriesStopped"
+	ReasonDeletionTimeout      HubDeploymentConditionReason = "DeletionTimeout"
+	ReasonCircuitBreakerOpen   HubDeploymentConditionReason = "CircuitBreakerOpen"
+	ReasonCircuitBreakerClosed HubDeploymentConditionReason = "CircuitBreakerClosed"
 )
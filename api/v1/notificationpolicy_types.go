@@ -0,0 +1,142 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&NotificationPolicy{}, &NotificationPolicyList{})
+}
+
+// +kubebuilder:validation:Enum=ApplicationFailed;ApplicationFinallyFailed;ApplicationOk;ClusterUnreachable;ClusterBomReady
+type NotificationEventType string
+
+const (
+	// EventApplicationFailed is sent when the state of an application changes to failed
+	EventApplicationFailed NotificationEventType = "ApplicationFailed"
+	// EventApplicationFinallyFailed is sent when the readiness of an application changes to finallyFailed, i.e. the
+	// controller has given up to retry its deployment
+	EventApplicationFinallyFailed NotificationEventType = "ApplicationFinallyFailed"
+	// EventApplicationOk is sent when the state of an application changes to ok
+	EventApplicationOk NotificationEventType = "ApplicationOk"
+	// EventClusterUnreachable is sent when the ClusterReachable condition of a clusterbom changes to False
+	EventClusterUnreachable NotificationEventType = "ClusterUnreachable"
+	// EventClusterBomReady is sent when the Ready condition of a clusterbom changes to True
+	EventClusterBomReady NotificationEventType = "ClusterBomReady"
+)
+
+// +kubebuilder:validation:Enum=CloudEvents;JSON
+type NotificationFormat string
+
+const (
+	// NotificationFormatCloudEvents sends CloudEvents 1.0 in structured content mode
+	NotificationFormatCloudEvents NotificationFormat = "CloudEvents"
+	// NotificationFormatJSON sends the plain event as JSON
+	NotificationFormatJSON NotificationFormat = "JSON"
+)
+
+// NotificationPolicySpec defines which events of the clusterboms in the namespace of the policy are sent to a sink
+type NotificationPolicySpec struct {
+	// Filter selects the events which are sent; all events are sent if no filter is specified
+	// +optional
+	Filter NotificationFilter `json:"filter,omitempty"`
+
+	// Sink is the http endpoint to which the events are sent
+	Sink NotificationSink `json:"sink"`
+
+	// Delivery configures retries and rate limiting
+	// +optional
+	Delivery NotificationDelivery `json:"delivery,omitempty"`
+}
+
+// NotificationFilter selects events. An event is selected if it matches all specified criteria.
+type NotificationFilter struct {
+	// EventTypes are the types of the selected events; all types are selected if empty
+	// +optional
+	EventTypes []NotificationEventType `json:"eventTypes,omitempty"`
+
+	// ClusterBomSelector selects the clusterboms by their labels; all clusterboms are selected if not specified
+	// +optional
+	ClusterBomSelector *metav1.LabelSelector `json:"clusterBomSelector,omitempty"`
+
+	// AppIDs are the ids of the selected applications; all applications are selected if empty. Events which do not
+	// belong to an application, e.g. ClusterUnreachable, are not filtered by the app ids.
+	// +optional
+	AppIDs []string `json:"appIDs,omitempty"`
+}
+
+// NotificationSink is an http endpoint which receives events by POST requests
+type NotificationSink struct {
+	// URL of the endpoint, which must use https, e.g. https://my-receiver.example.com:8443/events
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+
+	// Format of the events; default CloudEvents
+	// +optional
+	Format NotificationFormat `json:"format,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle which is used to validate the certificate of the endpoint
+	// +optional
+	CABundle string `json:"caBundle,omitempty"`
+
+	// SecretRef references a secret in the namespace of the policy. The value of its key "authorization" is sent
+	// as Authorization header, e.g. "Bearer <token>".
+	// +optional
+	SecretRef *v1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// TimeoutSeconds is the timeout of a request to the endpoint; default 10 seconds
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// NotificationDelivery configures the delivery of events
+type NotificationDelivery struct {
+	// MaxRetries is the maximal number of retries of a failed request; default 5
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// MaxEventsPerMinute limits the number of events sent by the policy; further events are dropped; default 60
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxEventsPerMinute *int32 `json:"maxEventsPerMinute,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NotificationPolicy is the Schema for the notificationpolicies API
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.sink.url"
+// +kubebuilder:printcolumn:name="FORMAT",type="string",JSONPath=".spec.sink.format"
+type NotificationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotificationPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NotificationPolicyList contains a list of NotificationPolicy
+type NotificationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationPolicy `json:"items"`
+}
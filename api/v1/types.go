@@ -70,6 +70,25 @@ type ApplicationConfig struct {
 	ImportParameters         []ImportParameter        `json:"importParameters,omitempty"`
 
 	ExportParameters ExportParameters `json:"exportParameters,omitempty"`
+
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// RetryPolicy defines how failed install and remove operations of an application are retried
+type RetryPolicy struct {
+	// BaseDelay is the delay before the first retry. It is doubled with every further retry. Defaults to 10s.
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+	// MaxDelay is the upper limit for the delay between two retries. Defaults to 1h.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+	// MaxAttempts is the maximal number of tries of an operation. Zero means unlimited.
+	// +kubebuilder:validation:Minimum=0
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+	// GiveUp defines whether the application becomes finallyFailed after MaxAttempts tries. Otherwise the application
+	// stays failed, and is only retried after a change of its configuration or a manual reset.
+	GiveUp bool `json:"giveUp,omitempty"`
+	// NonRetryableDelay is the delay before an operation which failed with an error that is not retryable is tried
+	// again, without exponential backoff. Defaults to 6h.
+	NonRetryableDelay *metav1.Duration `json:"nonRetryableDelay,omitempty"`
 }
 
 type SecretValues struct {
@@ -110,6 +129,8 @@ type DeploymentConfig struct {
 	ReadyRequirements ReadyRequirements `json:"readyRequirements,omitempty"`
 
 	InternalImportParameters InternalImportParameters `json:"internalImportParameters,omitempty"`
+
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
 }
 
 // ApplicationState describes the state of the deployment of an application
@@ -134,6 +155,12 @@ type Readiness struct {
 
 type ErrorEntry struct {
 	Description string `json:"description,omitempty"`
+	// Code classifies the error, e.g. ChartFetchFailed or APIConflict
+	Code string `json:"code,omitempty"`
+	// Retryable tells whether the controller retries the operation automatically
+	Retryable bool `json:"retryable,omitempty"`
+	// Hint describes how the error can be remedied
+	Hint string `json:"hint,omitempty"`
 	// +kubebulder:validation:Format="date-time"
 	Time metav1.Time `json:"time,omitempty"`
 }
@@ -190,7 +217,7 @@ type LastOperation struct {
 
 	SuccessGeneration int64 `json:"successGeneration,omitempty"`
 
-	// +kubebuilder:validation:Enum=failed;ok
+	// +kubebuilder:validation:Enum=failed;ok;pending
 	State         string `json:"state,omitempty"`
 	NumberOfTries int32  `json:"numberOfTries,omitempty"`
 	// +kubebulder:validation:Format="date-time"
@@ -16,6 +16,8 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"net/http"
@@ -30,6 +32,7 @@ import (
 
 	"github.com/go-logr/logr"
 	kappcrtl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	packaging "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/packaging/v1alpha1"
 	uberzap "go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	apicorev1 "k8s.io/api/core/v1"
@@ -51,9 +54,16 @@ import (
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/admission"
+	"github.com/gardener/potter-controller/pkg/auditlog"
 	"github.com/gardener/potter-controller/pkg/avcheck"
 	"github.com/gardener/potter-controller/pkg/controllersdi"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
+	"github.com/gardener/potter-controller/pkg/kapp"
+	"github.com/gardener/potter-controller/pkg/metrics"
+	"github.com/gardener/potter-controller/pkg/notification"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
+	"github.com/gardener/potter-controller/pkg/webhookcert"
 )
 
 var (
@@ -69,6 +79,7 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = appRepov1.AddToScheme(scheme)
 	_ = kappcrtl.AddToScheme(scheme)
+	_ = packaging.AddToScheme(scheme)
 	_ = hubv1.AddToScheme(scheme)
 	_ = landscaper.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
@@ -88,11 +99,24 @@ func main() {
 	var extendedLogEnabled bool
 	var tokenReviewEnabled bool
 	var tokenIssuer string
+	var tokenAudiences string
+	var tokenJWKSURL string
+	var tokenJWKSFile string
+	var tokenClockSkew time.Duration
 	var reconcileIntervalMinutes int64
 	var restartKappIntervalMinutes int64
 	var auditLog bool
 	var logLevel string
 	var configTypesStringList string
+	var helmSchemaValidation string
+	var warningsRequiringAck string
+	var webhookCertManagement bool
+	var webhookCertDNSNames string
+	var webhookConfigurations string
+	var webhookCertConfig webhookcert.Config
+	var notificationAllowedHosts string
+	var tracingConfig tracing.Config
+	var auditLogConfig auditlog.Config
 
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&appRepoKubeconfig, "apprepo-kubeconfig", "", "Kubeconfig of the cluster with the appRepo resource")
@@ -101,6 +125,13 @@ func main() {
 	flag.BoolVar(&landscaperEnabled, "landscaper-enabled", false, "Flag to enable clusterbom handling via landscaper")
 	flag.BoolVar(&tokenReviewEnabled, "tokenreview-enabled", false, "Flag to enable token reviewing for the admission webhook")
 	flag.StringVar(&tokenIssuer, "token-issuer", "", "Issuer for validation of webhook jwt tokens")
+	flag.StringVar(&tokenAudiences, "token-audiences", admission.DefaultTokenAudience,
+		"Comma separated audiences of which a webhook jwt token must contain one")
+	flag.StringVar(&tokenJWKSURL, "token-jwks-url", "",
+		"URL of the JWKS with the signing keys of webhook jwt tokens. If empty, the URL is taken from the OIDC discovery document of the issuer")
+	flag.StringVar(&tokenJWKSFile, "token-jwks-file", "", "File with the JWKS with the signing keys of webhook jwt tokens; takes precedence over the JWKS URL")
+	flag.DurationVar(&tokenClockSkew, "token-clock-skew", admission.DefaultTokenClockSkew,
+		"Tolerated clock skew for the expiry and not-before time of webhook jwt tokens")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&runsLocally, "runs-locally", false, "Flag to distinguish between local and productive run. Default value is false (productive).")
@@ -110,9 +141,39 @@ func main() {
 	flag.Int64Var(&restartKappIntervalMinutes, "restart-kapp-interval-minutes", 0, "Restart kapp-controller interval in minutes")
 	flag.StringVar(&logLevel, "loglevel", util.LogLevelStringInfo, "log level debug/info/warning/error")
 	flag.StringVar(&configTypesStringList, "configtypes", util.ConfigTypeHelm, "supported config types")
-	flag.BoolVar(&auditLog, "audit-log", false, "Flag to enable audit logging (requires additional container). Default false")
+	flag.StringVar(&helmSchemaValidation, "helm-schema-validation", admission.HelmSchemaValidationDisabled,
+		"Validation of helm values against the values schema of the chart by the admission webhook: disabled/deny/warn")
+	flag.StringVar(&warningsRequiringAck, "admission-warnings-requiring-ack", "",
+		"Comma separated kinds of admission warnings which deny a clusterbom unless it acknowledges them with an annotation")
+	flag.BoolVar(&webhookCertManagement, "webhook-cert-management", false,
+		"Flag to serve the admission webhook with TLS, using a CA and certificate which the controller creates and renews itself")
+	flag.StringVar(&webhookCertConfig.SecretName, "webhook-cert-secret", "potter-webhook-certs",
+		"Secret in the namespace of the controller in which the webhook certificates are stored")
+	flag.StringVar(&webhookCertDNSNames, "webhook-cert-dns-names", "", "Comma separated dns names of the webhook serving certificate")
+	flag.StringVar(&webhookConfigurations, "webhook-configurations", "clusterbomadmission.hub.k8s.sap.com,secretadmission.hub.k8s.sap.com",
+		"Comma separated names of the webhook configurations whose caBundle is set if the webhook certificates are managed")
+	flag.StringVar(&notificationAllowedHosts, "notification-allowed-hosts", "",
+		"Comma separated hosts, or domains with a leading dot, to which notifications may be sent. If empty, notifications may be sent to all hosts except private, loopback and link-local addresses")
+	flag.BoolVar(&auditLog, "audit-log", false, "Flag to enable audit logging with the tcp backend (requires additional container). Default false")
+	flag.StringVar(&auditLogConfig.Backend, "audit-log-backend", "", "Backend of the audit log: tcp, file, stdout or webhook. Audit logging is disabled if empty")
+	flag.StringVar(&auditLogConfig.TCPAddress, "audit-log-tcp-address", ":10520", "Address of the audit log container of the tcp backend")
+	flag.StringVar(&auditLogConfig.FilePath, "audit-log-file", "", "File of the file backend of the audit log")
+	flag.IntVar(&auditLogConfig.FileMaxSizeMB, "audit-log-file-max-size-mb", 100, "Size in megabytes at which the audit log file is rotated")
+	flag.IntVar(&auditLogConfig.FileMaxBackups, "audit-log-file-max-backups", 5, "Number of rotated audit log files which are kept")
+	flag.StringVar(&auditLogConfig.WebhookURL, "audit-log-webhook-url", "", "URL to which the webhook backend posts the audit log messages")
+	flag.StringVar(&auditLogConfig.WebhookCAFile, "audit-log-webhook-ca-file", "", "PEM file with the CA of the audit log webhook")
+	flag.StringVar(&auditLogConfig.WebhookTokenFile, "audit-log-webhook-token-file", "", "File with a bearer token for the audit log webhook")
+	flag.StringVar(&auditLogConfig.SpoolDir, "audit-log-spool-dir", "/var/spool/potter-auditlog", "Directory in which audit log messages are buffered until they are delivered")
+	flag.IntVar(&auditLogConfig.SpoolMaxMessages, "audit-log-spool-max-messages", 10000, "Maximal number of buffered audit log messages; further messages are dropped")
+	flag.StringVar(&tracingConfig.Endpoint, "tracing-endpoint", "", "OTLP gRPC endpoint (host:port) to which traces are exported. Tracing is disabled if empty")
+	flag.BoolVar(&tracingConfig.Insecure, "tracing-insecure", false, "Flag to export traces without TLS")
+	flag.Float64Var(&tracingConfig.SampleRatio, "tracing-sample-ratio", 1, "Fraction of clusterbom changes which are traced")
 	flag.Parse()
 
+	if auditLog && auditLogConfig.Backend == "" {
+		auditLogConfig.Backend = auditlog.BackendTCP
+	}
+
 	zapcoreLogLevel := zapcore.InfoLevel
 	if logLevel == util.LogLevelStringDebug {
 		zapcoreLogLevel = zapcore.DebugLevel
@@ -130,6 +191,8 @@ func main() {
 
 	setupLog.V(util.LogLevelWarning).Info("Starting hub controller")
 
+	shutDownTracing := setupTracing(&tracingConfig)
+
 	config := ctrl.GetConfigOrDie()
 
 	appRepoClient := getAppRepoClient(appRepoKubeconfig)
@@ -140,6 +203,8 @@ func main() {
 
 	mgr := createManager(config, metricsAddr, enableLeaderElection)
 
+	metrics.RegisterApplicationStateCollector(mgr.GetClient(), ctrl.Log.WithName("metrics"))
+
 	eventBroadcaster, eventRecorder := setupEventRecording(config)
 	defer eventBroadcaster.Shutdown()
 
@@ -147,25 +212,54 @@ func main() {
 
 	blockObject := setupBlockObject(avCheckConfig, runsLocally)
 
-	cbReconciler := setupClusterBomReconciler(mgr, uncachedClient, hubControllerClient, blockObject, auditLog, avCheckConfig)
+	cbReconciler := setupClusterBomReconciler(mgr, uncachedClient, hubControllerClient, blockObject, &auditLogConfig, avCheckConfig)
 	defer cbReconciler.Close()
 
-	cbStateReconciler := setupClusterBomStateReconciler(mgr, uncachedClient, blockObject, avCheckConfig)
+	cbStateReconciler := setupClusterBomStateReconciler(mgr, uncachedClient, blockObject, avCheckConfig,
+		&notification.SinkRestrictions{AllowedHosts: splitCommaSeparated(notificationAllowedHosts)})
+
+	configTypes := strings.Split(configTypesStringList, ",")
+
+	// the clients of the deployer plugins are shared by the deployment controller and the admission webhook
+	pluginClients := deployerplugin.NewClientCache()
 
 	deploymentReconciler := setupDeploymentReconciler(mgr, appRepoClient, uncachedClient, blockObject, eventRecorder,
-		reconcileIntervalMinutes)
+		pluginClients, reconcileIntervalMinutes, configTypes)
+
+	setupSecretSyncReconciler(mgr)
+
+	warningKindsRequiringAck, err := admission.ParseWarningKinds(warningsRequiringAck)
+	if err != nil {
+		setupLog.Error(err, "Invalid admission warnings requiring acknowledgement")
+		os.Exit(1)
+	}
 
-	configTypes := strings.Split(configTypesStringList, ",")
 	admissionHookConfig := admission.AdmissionHookConfig{
-		UncachedClient:      uncachedClient,
-		HubControllerClient: hubControllerClient,
-		ConfigTypes:         configTypes,
-		ExtendedLogEnabled:  extendedLogEnabled,
-		LandscaperEnabled:   landscaperEnabled,
-		RunsLocally:         runsLocally,
-		TokenIssuer:         tokenIssuer,
-		TokenReviewEnabled:  tokenReviewEnabled,
+		UncachedClient:       uncachedClient,
+		HubControllerClient:  hubControllerClient,
+		AppRepoClient:        appRepoClient,
+		ConfigTypes:          configTypes,
+		HelmSchemaValidation: helmSchemaValidation,
+		ExtendedLogEnabled:   extendedLogEnabled,
+		LandscaperEnabled:    landscaperEnabled,
+		RunsLocally:          runsLocally,
+		TokenIssuer:          tokenIssuer,
+		TokenReviewEnabled:   tokenReviewEnabled,
+		TokenAudiences:       splitCommaSeparated(tokenAudiences),
+		TokenJWKSURL:         tokenJWKSURL,
+		TokenJWKSFile:        tokenJWKSFile,
+		TokenClockSkew:       tokenClockSkew,
+		WarningsRequiringAck: warningKindsRequiringAck,
+		PluginClients:        pluginClients,
 	}
+
+	if webhookCertManagement && !skipAdmissionHook {
+		webhookCertConfig.Namespace = util.GetPodNamespace()
+		webhookCertConfig.DNSNames = splitCommaSeparated(webhookCertDNSNames)
+		webhookCertConfig.WebhookConfigurations = splitCommaSeparated(webhookConfigurations)
+		admissionHookConfig.GetCertificate = setupWebhookCertificates(mgr, hubControllerClient, uncachedClient, &webhookCertConfig)
+	}
+
 	startAdmissionHook(&admissionHookConfig, skipAdmissionHook)
 
 	startReconciler(mgr, uncachedClient, hubControllerClient, reconcileIntervalMinutes, restartKappIntervalMinutes, skipReconcile, runsLocally)
@@ -188,10 +282,12 @@ func main() {
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "Problem running manager")
 		shutDown(setupLog)
+		shutDownTracing()
 		os.Exit(1)
 	}
 
 	shutDown(setupLog)
+	shutDownTracing()
 }
 
 func createManager(config *rest.Config, metricsAddr string, enableLeaderElection bool) manager.Manager {
@@ -210,6 +306,26 @@ func createManager(config *rest.Config, metricsAddr string, enableLeaderElection
 	return mgr
 }
 
+// setupTracing starts the export of traces and returns a function which flushes and stops it.
+func setupTracing(tracingConfig *tracing.Config) func() {
+	setupLog.V(util.LogLevelDebug).Info("Setup tracing")
+
+	shutDownTracing, err := tracing.Setup(context.Background(), tracingConfig, ctrl.Log.WithName("tracing"))
+	if err != nil {
+		setupLog.Error(err, "unable to setup tracing")
+		os.Exit(1)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutDownTracing(ctx); err != nil {
+			setupLog.Error(err, "error flushing traces")
+		}
+	}
+}
+
 func setupEventRecording(config *rest.Config) (record.EventBroadcaster, record.EventRecorder) {
 	setupLog.V(util.LogLevelDebug).Info("Setup event recording")
 
@@ -241,14 +357,14 @@ func setupBlockObject(avCheckConfig *avcheck.Configuration, syncDisabled bool) *
 }
 
 func setupClusterBomReconciler(mgr manager.Manager, uncachedClient, hubControllerClient synchronize.UncachedClient,
-	blockObject *synchronize.BlockObject, auditLog bool, avCheckConfig *avcheck.Configuration) *controllersdi.ClusterBomReconciler {
+	blockObject *synchronize.BlockObject, auditLogConfig *auditlog.Config, avCheckConfig *avcheck.Configuration) *controllersdi.ClusterBomReconciler {
 	setupLog.V(util.LogLevelDebug).Info("Setup clusterbom reconciler")
 
 	cbReconciler, err := controllersdi.NewClusterBomReconciler(
 		mgr.GetClient(),
 		ctrl.Log.WithName("controllers").WithName("ClusterBomReconciler"),
 		mgr.GetScheme(),
-		auditLog,
+		auditLogConfig,
 		blockObject,
 		avcheck.NewAVCheck(),
 		uncachedClient,
@@ -267,9 +383,16 @@ func setupClusterBomReconciler(mgr manager.Manager, uncachedClient, hubControlle
 }
 
 func setupClusterBomStateReconciler(mgr manager.Manager, uncachedClient synchronize.UncachedClient,
-	blockObject *synchronize.BlockObject, avCheckConfig *avcheck.Configuration) avcheck.Controller {
+	blockObject *synchronize.BlockObject, avCheckConfig *avcheck.Configuration,
+	sinkRestrictions *notification.SinkRestrictions) avcheck.Controller {
 	setupLog.V(util.LogLevelDebug).Info("Setup clusterbom state reconciler")
 
+	notifier := notification.NewNotifier(mgr.GetClient(), sinkRestrictions, ctrl.Log.WithName("notification"))
+	if err := mgr.Add(notifier); err != nil {
+		setupLog.Error(err, "unable to add notifier")
+		os.Exit(1)
+	}
+
 	cbStateReconciler := &controllersdi.ClusterBomStateReconciler{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("controllers").WithName("ClusterBomStateReconciler"),
@@ -282,6 +405,7 @@ func setupClusterBomStateReconciler(mgr manager.Manager, uncachedClient synchron
 		AVCheck:        avcheck.NewAVCheck(),
 		AvCheckConfig:  avCheckConfig,
 		UncachedClient: uncachedClient,
+		Notifier:       notifier,
 	}
 
 	if err := cbStateReconciler.SetupWithManager(mgr); err != nil {
@@ -293,17 +417,20 @@ func setupClusterBomStateReconciler(mgr manager.Manager, uncachedClient synchron
 }
 
 func setupDeploymentReconciler(mgr manager.Manager, appRepoClient client.Client, uncachedClient synchronize.UncachedClient,
-	blockObject *synchronize.BlockObject, eventRecorder record.EventRecorder, reconcileIntervalMinutes int64) avcheck.Controller {
+	blockObject *synchronize.BlockObject, eventRecorder record.EventRecorder, pluginClients *deployerplugin.ClientCache,
+	reconcileIntervalMinutes int64, configTypes []string) avcheck.Controller {
 	setupLog.V(util.LogLevelDebug).Info("Setup deployment controller")
 
 	logger := ctrl.Log.WithName("controllers").WithName("DeploymentReconciler")
 
 	crAndSecretClient := mgr.GetClient()
 
-	deployerFactory := controllersdi.NewDeploymentFactory(crAndSecretClient, uncachedClient, appRepoClient, blockObject, reconcileIntervalMinutes)
+	deployerFactory := controllersdi.NewDeploymentFactory(crAndSecretClient, uncachedClient, appRepoClient, blockObject,
+		pluginClients, reconcileIntervalMinutes, kapp.GetDefaultDeletionTimeout(logger))
 
 	deploymentReconciler := controllersdi.NewDeploymentReconciler(deployerFactory, crAndSecretClient, logger, mgr.GetScheme(),
-		util.NewThreadCounterMap(logger), blockObject, avcheck.NewAVCheck(), uncachedClient, eventRecorder)
+		util.NewThreadCounterMap(logger), blockObject, avcheck.NewAVCheck(), uncachedClient, eventRecorder,
+		util.ContainsString(util.ConfigTypeKapp, configTypes))
 
 	if err := deploymentReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DeploymentReconciler")
@@ -312,6 +439,41 @@ func setupDeploymentReconciler(mgr manager.Manager, appRepoClient client.Client,
 	return deploymentReconciler
 }
 
+func setupSecretSyncReconciler(mgr manager.Manager) {
+	setupLog.V(util.LogLevelDebug).Info("Setup secretsync reconciler")
+
+	secretSyncReconciler := &controllersdi.SecretSyncReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("SecretSyncReconciler"),
+	}
+
+	if err := secretSyncReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SecretSyncReconciler")
+		os.Exit(1)
+	}
+}
+
+// setupWebhookCertificates loads the webhook certificates before the admission server is started, and adds the
+// periodic renewal to the manager
+func setupWebhookCertificates(mgr manager.Manager, hubControllerClient, uncachedClient synchronize.UncachedClient,
+	webhookCertConfig *webhookcert.Config) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	setupLog.V(util.LogLevelDebug).Info("Setup webhook certificates")
+
+	certManager := webhookcert.NewManager(hubControllerClient, uncachedClient, webhookCertConfig, ctrl.Log.WithName("webhookcert"))
+
+	if err := certManager.Reconcile(context.Background()); err != nil {
+		setupLog.Error(err, "unable to setup webhook certificates")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(certManager); err != nil {
+		setupLog.Error(err, "unable to add webhook certificate manager")
+		os.Exit(1)
+	}
+
+	return certManager.GetCertificate
+}
+
 func startAdmissionHook(admissionHookConfig *admission.AdmissionHookConfig, skipAdmissionHook bool) {
 	if !skipAdmissionHook {
 		setupLog.V(util.LogLevelWarning).Info("Starting admission hook")
@@ -428,6 +590,16 @@ func getHubControllerClient(hubControllerKubeconfig string, runsLocally bool) sy
 	return hubControllerClient
 }
 
+func splitCommaSeparated(list string) []string {
+	result := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 func createLogEncoder() zapcore.Encoder {
 	encodeTime := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 		enc.AppendString(t.Format(time.RFC3339))
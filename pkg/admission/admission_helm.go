@@ -7,207 +7,207 @@ import (
 	"github.com/gardener/potter-controller/api/apitypes"
 	helmref "github.com/gardener/potter-controller/pkg/helm"
 
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
-type helmReviewer struct{}
-
-func newHelmReviewer() *helmReviewer {
-	return &helmReviewer{}
+type helmReviewer struct {
+	// schemaValidator validates the values against the chart schema; nil if the validation is disabled
+	schemaValidator *helmSchemaValidator
 }
 
-func (r *helmReviewer) reviewTypeSpecificData(report *report, typeSpecificData, oldTypeSpecificData *runtime.RawExtension) {
-	ok, message := r.checkHelmSpecificData(typeSpecificData, oldTypeSpecificData)
-	if !ok {
-		report.deny(message)
-		return
+func newHelmReviewer(schemaValidator *helmSchemaValidator) *helmReviewer {
+	return &helmReviewer{
+		schemaValidator: schemaValidator,
 	}
 }
 
-func (r *helmReviewer) checkHelmSpecificData(typeSpecificData, oldTypeSpecificData *runtime.RawExtension) (bool, string) {
+// validateTypeSpecificData returns all violations of the helm specific data. During an update, only the chart version
+// and the settings which do not identify the release are allowed to be changed.
+func (r *helmReviewer) validateTypeSpecificData(fldPath *field.Path, typeSpecificData, oldTypeSpecificData *runtime.RawExtension) field.ErrorList {
 	var helmData apitypes.HelmSpecificData
 	var oldHelmData apitypes.HelmSpecificData
 
 	err := json.Unmarshal(typeSpecificData.Raw, &helmData)
 	if err != nil {
-		return false, "error typeSpecificData - " + err.Error()
+		return field.ErrorList{invalidData(fldPath, err)}
 	}
 
 	if oldTypeSpecificData != nil {
 		err := json.Unmarshal(oldTypeSpecificData.Raw, &oldHelmData)
 		if err != nil {
-			return false, "error when unmarshalling old typeSpecificData - " + err.Error()
+			return field.ErrorList{field.InternalError(fldPath, errors.Wrap(err, "old typeSpecificData could not be unmarshalled"))}
 		}
 	}
 
-	if ok, message := r.checkInstallationNameAndNamespace(&helmData); !ok {
-		return false, message
-	}
-
-	if ok, message := r.checkTimeouts(&helmData); !ok {
-		return false, message
-	}
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, r.validateInstallationNameAndNamespace(fldPath, &helmData)...)
+	allErrs = append(allErrs, r.validateTimeouts(fldPath, &helmData)...)
 
 	if helmData.TarballAccess == nil && helmData.CatalogAccess == nil {
-		return false, "either helm.tarballAccess or helm.catalogAccess must be set"
+		allErrs = append(allErrs, field.Required(fldPath.Child("catalogAccess"), "either tarballAccess or catalogAccess must be set"))
 	} else if helmData.TarballAccess != nil && helmData.CatalogAccess != nil {
-		return false, "not both helm.catalogAccess and helm.tarballAccess are allowed to have entries"
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("tarballAccess"), "not allowed together with catalogAccess"))
 	}
 
-	if ok, message := r.checkTarballAccess(&helmData); !ok {
-		return false, message
+	allErrs = append(allErrs, r.validateTarballAccess(fldPath.Child("tarballAccess"), &helmData)...)
+	allErrs = append(allErrs, r.validateCatalogAccess(fldPath.Child("catalogAccess"), &helmData)...)
+
+	if oldTypeSpecificData != nil {
+		allErrs = append(allErrs, r.validateUpdate(fldPath, &helmData, &oldHelmData)...)
 	}
 
-	if ok, message := r.checkCatalogAccess(&helmData); !ok {
-		return false, message
+	allErrs = append(allErrs, r.validateArguments(fldPath, &helmData)...)
+
+	return allErrs
+}
+
+func (r *helmReviewer) validateUpdate(fldPath *field.Path, helmData, oldHelmData *apitypes.HelmSpecificData) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if helmData.InstallName != oldHelmData.InstallName {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("installName"), "must not be updated"))
 	}
 
-	// during an update, only the version is allowed to be changed
-	if oldTypeSpecificData != nil {
-		if helmData.InstallName != oldHelmData.InstallName {
-			return false, "helm.installName must not be updated"
-		}
+	if helmData.Namespace != oldHelmData.Namespace {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("namespace"), "must not be updated"))
+	}
 
-		if helmData.Namespace != oldHelmData.Namespace {
-			return false, "helm.namespace must not be updated"
+	if helmData.CatalogAccess != nil && oldHelmData.CatalogAccess == nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("catalogAccess"), "switch to catalogAccess not allowed"))
+	} else if helmData.CatalogAccess == nil && oldHelmData.CatalogAccess != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("tarballAccess"), "switch to tarballAccess not allowed"))
+	} else if helmData.CatalogAccess != nil {
+		if helmData.CatalogAccess.ChartName != oldHelmData.CatalogAccess.ChartName {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("catalogAccess", "chartName"), "must not be changed"))
 		}
 
-		if helmData.CatalogAccess != nil && oldHelmData.CatalogAccess == nil {
-			return false, "switch to catalogAccess not allowed"
-		} else if helmData.CatalogAccess == nil && oldHelmData.CatalogAccess != nil {
-			return false, "switch to tarballAccess not allowed"
-		} else if helmData.CatalogAccess != nil {
-			if helmData.CatalogAccess.ChartName != oldHelmData.CatalogAccess.ChartName {
-				return false, "helm.catalogAccess.chartName must not be changed"
-			}
-
-			if helmData.CatalogAccess.Repo != oldHelmData.CatalogAccess.Repo {
-				return false, "helm.catalogAccess.repo must not be changed"
-			}
+		if helmData.CatalogAccess.Repo != oldHelmData.CatalogAccess.Repo {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("catalogAccess", "repo"), "must not be changed"))
 		}
 	}
 
-	if ok, message := r.checkArguments(&helmData); !ok {
-		return false, message
-	}
-
-	return true, ""
+	return allErrs
 }
 
-func (r *helmReviewer) checkInstallationNameAndNamespace(helmData *apitypes.HelmSpecificData) (bool, string) {
-	if helmData.InstallName == "" {
-		return false, "helm.installName is empty"
-	}
+func (r *helmReviewer) validateInstallationNameAndNamespace(fldPath *field.Path, helmData *apitypes.HelmSpecificData) field.ErrorList {
+	allErrs := field.ErrorList{}
 
-	if containsSpiffTemplate(helmData.InstallName) {
-		return false, "helm.installName cannot be templated"
+	if helmData.InstallName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("installName"), ""))
+	} else if containsSpiffTemplate(helmData.InstallName) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("installName"), helmData.InstallName, "cannot be templated"))
 	}
 
 	if helmData.Namespace == "" {
-		return false, "helm.namespace is empty"
-	}
-
-	if containsSpiffTemplate(helmData.Namespace) {
-		return false, "helm.namespace cannot be templated"
+		allErrs = append(allErrs, field.Required(fldPath.Child("namespace"), ""))
+	} else if containsSpiffTemplate(helmData.Namespace) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("namespace"), helmData.Namespace, "cannot be templated"))
 	}
 
-	return true, ""
+	return allErrs
 }
 
-func (r *helmReviewer) checkTimeouts(helmData *apitypes.HelmSpecificData) (bool, string) {
-	if helmData.InstallTimeout != nil && *helmData.InstallTimeout <= 0 {
-		return false, "helm.installTimeout must be larger than 0"
-	}
-
-	if helmData.UpgradeTimeout != nil && *helmData.UpgradeTimeout <= 0 {
-		return false, "helm.upgradeTimeout must be larger than 0"
-	}
+func (r *helmReviewer) validateTimeouts(fldPath *field.Path, helmData *apitypes.HelmSpecificData) field.ErrorList {
+	allErrs := field.ErrorList{}
 
-	if helmData.RollbackTimeout != nil && *helmData.RollbackTimeout <= 0 {
-		return false, "helm.rollbackTimeout must be larger than 0"
+	timeouts := []struct {
+		name    string
+		timeout *int64
+	}{
+		{name: "installTimeout", timeout: helmData.InstallTimeout},
+		{name: "upgradeTimeout", timeout: helmData.UpgradeTimeout},
+		{name: "rollbackTimeout", timeout: helmData.RollbackTimeout},
+		{name: "uninstallTimeout", timeout: helmData.UninstallTimeout},
 	}
 
-	if helmData.UninstallTimeout != nil && *helmData.UninstallTimeout <= 0 {
-		return false, "helm.uninstallTimeout must be larger than 0"
+	for _, t := range timeouts {
+		if t.timeout != nil && *t.timeout <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(t.name), *t.timeout, "must be larger than 0"))
+		}
 	}
 
-	return true, ""
+	return allErrs
 }
 
-func (r *helmReviewer) checkCatalogAccess(helmData *apitypes.HelmSpecificData) (bool, string) {
-	if helmData.CatalogAccess == nil {
-		return true, ""
-	}
-
-	if helmData.CatalogAccess.Repo == "" {
-		return false, "helm.catalogAccess.repo missing"
-	}
+func (r *helmReviewer) validateCatalogAccess(fldPath *field.Path, helmData *apitypes.HelmSpecificData) field.ErrorList {
+	allErrs := field.ErrorList{}
 
-	if containsSpiffTemplate(helmData.CatalogAccess.Repo) {
-		return false, "helm.catalogAccess.repo cannot be templated"
+	catalogAccess := helmData.CatalogAccess
+	if catalogAccess == nil {
+		return allErrs
 	}
 
-	if helmData.CatalogAccess.ChartName == "" {
-		return false, "helm.catalogAccess.chartName missing"
+	if catalogAccess.Repo == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("repo"), ""))
+	} else if containsSpiffTemplate(catalogAccess.Repo) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("repo"), catalogAccess.Repo, "cannot be templated"))
 	}
 
-	if containsSpiffTemplate(helmData.CatalogAccess.ChartName) {
-		return false, "helm.catalogAccess.chartName cannot be templated"
+	if catalogAccess.ChartName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("chartName"), ""))
+	} else if containsSpiffTemplate(catalogAccess.ChartName) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("chartName"), catalogAccess.ChartName, "cannot be templated"))
 	}
 
-	if helmData.CatalogAccess.ChartVersion == "" {
-		return false, "helm.catalogAccess.chartVersion missing"
+	if catalogAccess.ChartVersion == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("chartVersion"), ""))
+		return allErrs
 	}
 
 	pattern := `^(?P<major>0|[1-9]\d*)\.(?P<minor>0|[1-9]\d*)\.(?P<patch>0|[1-9]\d*)(?:-(?P<prerelease>(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+(?P<buildmetadata>[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`
-	matched, err := regexp.MatchString(pattern, helmData.CatalogAccess.ChartVersion)
+	matched, err := regexp.MatchString(pattern, catalogAccess.ChartVersion)
 	if err != nil {
-		return false, "error when matching helm.catalogAccess.chartVersion against pattern " + pattern + " - " + err.Error()
+		allErrs = append(allErrs, field.InternalError(fldPath.Child("chartVersion"), errors.Wrap(err, "error when matching against pattern "+pattern)))
+	} else if !matched {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("chartVersion"), catalogAccess.ChartVersion, "does not fulfill the pattern "+pattern))
 	}
 
-	if !matched {
-		return false, "helm.catalogAccess.chartVersion does not fulfill the pattern " + pattern
-	}
-
-	return true, ""
+	return allErrs
 }
 
-func (r *helmReviewer) checkTarballAccess(helmData *apitypes.HelmSpecificData) (bool, string) {
-	if helmData.TarballAccess == nil {
-		return true, ""
-	}
+func (r *helmReviewer) validateTarballAccess(fldPath *field.Path, helmData *apitypes.HelmSpecificData) field.ErrorList {
+	allErrs := field.ErrorList{}
 
-	if helmData.TarballAccess.URL == "" {
-		return false, "helm.tarballAccess.url missing"
+	tarballAccess := helmData.TarballAccess
+	if tarballAccess == nil {
+		return allErrs
 	}
 
-	if containsSpiffTemplate(helmData.TarballAccess.URL) {
-		return false, "helm.tarballAccess.url cannot be templated"
+	if tarballAccess.URL == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("url"), ""))
+	} else if containsSpiffTemplate(tarballAccess.URL) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), tarballAccess.URL, "cannot be templated"))
 	}
 
-	if helmData.TarballAccess.AuthHeader != "" && helmData.TarballAccess.SecretRef.Name != "" {
-		return false, "helm.tarballAccess.authHeader and helm.tarballAccess.secretRef.name both set"
+	if tarballAccess.AuthHeader != "" && tarballAccess.SecretRef.Name != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("secretRef", "name"), "not allowed together with authHeader"))
 	}
 
-	return true, ""
+	return allErrs
 }
 
-func (r *helmReviewer) checkArguments(helmData *apitypes.HelmSpecificData) (bool, string) {
-	for _, argument := range helmData.InstallArguments {
+func (r *helmReviewer) validateArguments(fldPath *field.Path, helmData *apitypes.HelmSpecificData) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, argument := range helmData.InstallArguments {
 		if argument != helmref.InstallArgAtomic {
-			return false, "install argument not supported: " + argument
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("installArguments").Index(i), argument,
+				[]string{helmref.InstallArgAtomic}))
 		}
 	}
 
-	for _, argument := range helmData.UpdateArguments {
+	for i, argument := range helmData.UpdateArguments {
 		if argument != helmref.UpdateArgAtomic {
-			return false, "update argument not supported: " + argument
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("updateArguments").Index(i), argument,
+				[]string{helmref.UpdateArgAtomic}))
 		}
 	}
 
-	for _, argument := range helmData.RemoveArguments {
-		return false, "remove argument not supported: " + argument
+	for i, argument := range helmData.RemoveArguments {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("removeArguments").Index(i), argument, []string{}))
 	}
 
-	return true, ""
+	return allErrs
 }
@@ -0,0 +1,293 @@
+package admission
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	helmref "github.com/gardener/potter-controller/pkg/helm"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+// Settings of the validation of helm values against the values.schema.json of the chart
+const (
+	HelmSchemaValidationDisabled = "disabled"
+	// HelmSchemaValidationDeny rejects clusterboms with values which violate the schema
+	HelmSchemaValidationDeny = "deny"
+	// HelmSchemaValidationWarn accepts clusterboms with values which violate the schema, but returns a warning
+	HelmSchemaValidationWarn = "warn"
+)
+
+const (
+	chartCacheTTL        = 10 * time.Minute
+	chartCacheMaxEntries = 200
+	chartLoadTimeout     = 5 * time.Second
+)
+
+// errChartLoadTimeout is returned if a chart was not loaded before the deadline of the review
+var errChartLoadTimeout = errors.New("loading the chart did not finish in time")
+
+// helmSchemaValidator resolves the chart of a helm application config and validates the values against the values
+// schema of the chart. The charts are cached, so that not every review downloads them.
+type helmSchemaValidator struct {
+	warnOnly      bool
+	appRepoClient client.Client
+	chartCache    *helmref.ChartCache
+	loadTimeout   time.Duration
+}
+
+// newHelmSchemaValidator returns nil if the schema validation is disabled
+func newHelmSchemaValidator(config *AdmissionHookConfig) *helmSchemaValidator {
+	if config.HelmSchemaValidation != HelmSchemaValidationDeny && config.HelmSchemaValidation != HelmSchemaValidationWarn {
+		return nil
+	}
+
+	return &helmSchemaValidator{
+		warnOnly:      config.HelmSchemaValidation == HelmSchemaValidationWarn,
+		appRepoClient: config.AppRepoClient,
+		chartCache:    helmref.NewChartCache(chartCacheTTL, chartCacheMaxEntries),
+		loadTimeout:   chartLoadTimeout,
+	}
+}
+
+// helmValuesCheck is the validation of the values of one helm application config against the values schema of its chart
+type helmValuesCheck struct {
+	fldPath    *field.Path
+	applConfig *hubv1.ApplicationConfig
+	helmData   *apitypes.HelmSpecificData
+	values     map[string]interface{}
+	chart      *chart.Chart
+	err        error
+}
+
+// newValuesCheck returns the validation of the values of a helm application config, or nil if the values cannot or
+// need not be validated. Values which cannot be unmarshalled are reported. The type specific data must be valid.
+func (r *helmReviewer) newValuesCheck(log logr.Logger, report *report, fldPath *field.Path, applConfig, oldApplConfig *hubv1.ApplicationConfig) *helmValuesCheck {
+	if r.schemaValidator == nil {
+		return nil
+	}
+
+	var helmData apitypes.HelmSpecificData
+	if err := json.Unmarshal(applConfig.TypeSpecificData.Raw, &helmData); err != nil {
+		return nil
+	}
+
+	if skipReason := r.getSkipReason(&helmData, applConfig, oldApplConfig); skipReason != "" {
+		log.V(util.LogLevelDebug).Info("skipping validation of helm values against the chart schema: "+skipReason,
+			"applConfig.ID", applConfig.ID)
+		return nil
+	}
+
+	values, err := r.getValues(applConfig)
+	if err != nil {
+		report.addErrors(field.ErrorList{invalidData(fldPath.Child("values"), err)})
+		return nil
+	}
+
+	return &helmValuesCheck{
+		fldPath:    fldPath,
+		applConfig: applConfig,
+		helmData:   &helmData,
+		values:     values,
+	}
+}
+
+// reviewValues validates the values of helm application configs against the values schema of their charts. The charts
+// are loaded concurrently, with one deadline for all of them, which is at most the loadTimeout and ends with the
+// context of the review. Charts which cannot be loaded in time do not block the request, they only result in a warning.
+func (r *helmReviewer) reviewValues(ctx context.Context, log logr.Logger, report *report, checks []*helmValuesCheck) {
+	if r.schemaValidator == nil || len(checks) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.schemaValidator.loadTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check *helmValuesCheck) {
+			defer wg.Done()
+			check.chart, check.err = r.schemaValidator.loadChart(ctx, log.WithValues("applConfig.ID", check.applConfig.ID),
+				check.helmData, check.applConfig)
+		}(check)
+	}
+	wg.Wait()
+
+	for _, check := range checks {
+		r.reportValuesCheck(log.WithValues("applConfig.ID", check.applConfig.ID), report, check)
+	}
+}
+
+// reportValuesCheck validates the values of a check whose chart was loaded, and reports the violations
+func (r *helmReviewer) reportValuesCheck(log logr.Logger, report *report, check *helmValuesCheck) {
+	applConfig := check.applConfig
+
+	if check.err == errChartLoadTimeout {
+		log.V(util.LogLevelWarning).Info("skipping validation of helm values, because the chart was not loaded in time")
+		report.warn(fmt.Sprintf("validation of the values of application config %s against the chart schema was skipped, "+
+			"because the chart was not loaded in time", applConfig.ID))
+		return
+	} else if check.err != nil {
+		log.Error(check.err, "could not load chart to validate helm values")
+		report.warn(fmt.Sprintf("values of application config %s could not be validated against the chart schema: %s",
+			applConfig.ID, check.err.Error()))
+		return
+	}
+
+	ch := check.chart
+	violations, err := helmref.ValidateValuesAgainstSchema(ch, check.values)
+	if err != nil {
+		log.Error(err, "could not validate helm values")
+		report.warn(fmt.Sprintf("values of application config %s could not be validated against the chart schema: %s",
+			applConfig.ID, err.Error()))
+		return
+	} else if len(violations) == 0 {
+		return
+	}
+
+	messages := make([]string, len(violations))
+	for i := range violations {
+		messages[i] = violations[i].String()
+	}
+
+	if r.schemaValidator.warnOnly {
+		log.V(util.LogLevelWarning).Info("helm values violate the chart schema", "violations", messages)
+		report.warn(fmt.Sprintf("values of application config %s violate the values schema of chart %s: %s",
+			applConfig.ID, ch.Name(), strings.Join(messages, "; ")))
+		return
+	}
+
+	// the violations are reported at the values with a JSON pointer to the offending field
+	allErrs := field.ErrorList{}
+	for i := range violations {
+		allErrs = append(allErrs, field.Invalid(check.fldPath.Child("values"), violations[i].Path,
+			"violates the values schema of chart "+ch.Name()+": "+violations[i].Message))
+	}
+	report.addErrors(allErrs)
+}
+
+// getSkipReason returns why the values cannot be validated at admission time, or an empty string if they can. This is
+// the case if not all values are known, because some are merged only during the deployment.
+func (r *helmReviewer) getSkipReason(helmData *apitypes.HelmSpecificData, applConfig, oldApplConfig *hubv1.ApplicationConfig) string {
+	if len(applConfig.ImportParameters) > 0 {
+		return "values contain import parameters"
+	}
+
+	if len(applConfig.NamedSecretValues) > 0 {
+		return "values contain named secret values"
+	}
+
+	if r.keepsSecretValues(applConfig, oldApplConfig) {
+		return "secret values are kept from a previous version"
+	}
+
+	if helmData.TarballAccess != nil && helmData.TarballAccess.SecretRef.Name != "" {
+		return "chart access requires a named secret"
+	}
+
+	if helmData.CatalogAccess != nil && r.schemaValidator.appRepoClient == nil {
+		return "no client for app repositories"
+	}
+
+	return ""
+}
+
+// keepsSecretValues returns whether the secret values are not part of the request, but stored in a secret
+func (r *helmReviewer) keepsSecretValues(applConfig, oldApplConfig *hubv1.ApplicationConfig) bool {
+	secretValues := applConfig.SecretValues
+	if secretValues != nil && secretValues.Operation == operationDelete {
+		return false
+	}
+
+	if secretValues != nil && secretValues.Data != nil && secretValues.Operation != operationKeep {
+		return false
+	}
+
+	return oldApplConfig != nil && oldApplConfig.SecretValues != nil
+}
+
+// getValues returns the values of an application config, merged with the secret values of the request
+func (r *helmReviewer) getValues(applConfig *hubv1.ApplicationConfig) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if applConfig.Values != nil {
+		if err := json.Unmarshal(applConfig.Values.Raw, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	secretValues := applConfig.SecretValues
+	if secretValues != nil && secretValues.Data != nil && secretValues.Operation != operationDelete && secretValues.Operation != operationKeep {
+		var data map[string]interface{}
+		if err := json.Unmarshal(secretValues.Data.Raw, &data); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal secret values")
+		}
+		values = deployutil.MergeMaps(values, data)
+	}
+
+	return values, nil
+}
+
+type chartLoadResult struct {
+	chart *chart.Chart
+	err   error
+}
+
+// loadChart loads the chart with the chart loading of the helm deployer. If the context ends first, errChartLoadTimeout
+// is returned, and the loading continues in the background, so that a later review finds the chart in the cache.
+// Concurrent reviews of the same chart wait for the same download, see ChartCache.Load.
+func (v *helmSchemaValidator) loadChart(ctx context.Context, log logr.Logger, helmData *apitypes.HelmSpecificData,
+	applConfig *hubv1.ApplicationConfig) (*chart.Chart, error) {
+	load := func() (*chart.Chart, error) {
+		loadCtx := context.WithValue(context.Background(), util.LoggerKey{}, log)
+		deploymentConfig := &hubv1.DeploymentConfig{ID: applConfig.ID}
+		chartData, _, err := helmref.ParseTypeSpecificData(loadCtx, nil, deploymentConfig, helmData, true, v.appRepoClient)
+		if err != nil {
+			return nil, err
+		}
+
+		return chartData.Load()
+	}
+
+	resultChan := make(chan chartLoadResult, 1)
+	go func() {
+		ch, err := v.chartCache.Load(getChartCacheKey(helmData), load)
+		resultChan <- chartLoadResult{chart: ch, err: err}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.chart, result.err
+	case <-ctx.Done():
+		return nil, errChartLoadTimeout
+	}
+}
+
+// getChartCacheKey returns the key of a chart in the cache. The key of a tarball contains a hash of its auth header, so
+// that a chart which was downloaded with credentials is not returned for a request with other or without credentials.
+func getChartCacheKey(helmData *apitypes.HelmSpecificData) string {
+	if helmData.CatalogAccess != nil {
+		return "catalog/" + helmData.CatalogAccess.Repo + "/" + helmData.CatalogAccess.ChartName + "/" + helmData.CatalogAccess.ChartVersion
+	}
+
+	if helmData.TarballAccess.AuthHeader != "" {
+		authHash := sha256.Sum256([]byte(helmData.TarballAccess.AuthHeader))
+		return "tarball/" + hex.EncodeToString(authHash[:]) + "/" + helmData.TarballAccess.URL
+	}
+
+	return "tarball/" + helmData.TarballAccess.URL
+}
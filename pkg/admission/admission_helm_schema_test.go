@@ -0,0 +1,244 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+const testChartURL = "https://charts.example.com/test-1.0.0.tgz"
+
+func TestReviewHelmValues(t *testing.T) {
+	var log = ctrl.Log.WithName("ClusterBom Admission Hook Unit Tests")
+
+	tests := []struct {
+		name             string
+		mode             string
+		values           string
+		secretValues     *hubv1.SecretValues
+		oldSecretValues  *hubv1.SecretValues
+		expectedDenied   bool
+		expectedWarnings int
+		expectedMessage  string
+	}{
+		{
+			name:   "valid values",
+			mode:   HelmSchemaValidationDeny,
+			values: `{"replicaCount": 2}`,
+		},
+		{
+			name:           "invalid values",
+			mode:           HelmSchemaValidationDeny,
+			values:         `{"replicaCount": "2", "imagePullPolicy": "Always"}`,
+			expectedDenied: true,
+			expectedMessage: `spec.applicationConfigs[0].values: Invalid value: "/imagePullPolicy": violates the values schema ` +
+				`of chart test: Additional property imagePullPolicy is not allowed, spec.applicationConfigs[0].values: ` +
+				`Invalid value: "/replicaCount": violates the values schema of chart test: Invalid type`,
+		},
+		{
+			name:             "invalid values with warning",
+			mode:             HelmSchemaValidationWarn,
+			values:           `{"replicaCount": "2"}`,
+			expectedWarnings: 1,
+			expectedMessage:  "/replicaCount: Invalid type",
+		},
+		{
+			name:           "invalid secret values",
+			mode:           HelmSchemaValidationDeny,
+			values:         `{}`,
+			secretValues:   &hubv1.SecretValues{Data: &runtime.RawExtension{Raw: []byte(`{"password": 1}`)}},
+			expectedDenied: true,
+			expectedMessage: `spec.applicationConfigs[0].values: Invalid value: "/password": violates the values schema ` +
+				`of chart test: Invalid type`,
+		},
+		{
+			name:            "kept secret values",
+			mode:            HelmSchemaValidationDeny,
+			values:          `{"replicaCount": "2"}`,
+			oldSecretValues: &hubv1.SecretValues{InternalSecretName: "test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := newHelmSchemaValidator(&AdmissionHookConfig{HelmSchemaValidation: tt.mode})
+			primeChartCache(t, validator)
+
+			typeSpecificData, err := raw(&apitypes.HelmSpecificData{
+				InstallName:   "test",
+				Namespace:     "test",
+				TarballAccess: &apitypes.TarballAccess{URL: testChartURL},
+			})
+			assert.NoErr(t, err)
+
+			applConfig := &hubv1.ApplicationConfig{
+				ID:               "test",
+				ConfigType:       util.ConfigTypeHelm,
+				TypeSpecificData: *typeSpecificData,
+				Values:           &runtime.RawExtension{Raw: []byte(tt.values)},
+				SecretValues:     tt.secretValues,
+			}
+
+			var oldApplConfig *hubv1.ApplicationConfig
+			if tt.oldSecretValues != nil {
+				oldApplConfig = applConfig.DeepCopy()
+				oldApplConfig.SecretValues = tt.oldSecretValues
+			}
+
+			report := newReport(nil)
+			reviewValuesOfApplConfig(log, report, validator, applConfig, oldApplConfig)
+			assert.Equal(t, report.denied(), tt.expectedDenied, "denied")
+			assert.Equal(t, len(report.warnings), tt.expectedWarnings, "number of warnings")
+
+			message := ""
+			if len(report.errs) > 0 {
+				message = report.errs.ToAggregate().Error()
+			} else if len(report.warnings) > 0 {
+				message = report.warnings[0]
+			}
+			assert.True(t, strings.Contains(message, tt.expectedMessage), "message "+message)
+		})
+	}
+}
+
+func TestReviewHelmValuesWithUnavailableChart(t *testing.T) {
+	var log = ctrl.Log.WithName("ClusterBom Admission Hook Unit Tests")
+
+	validator := newHelmSchemaValidator(&AdmissionHookConfig{HelmSchemaValidation: HelmSchemaValidationDeny})
+	validator.loadTimeout = 10 * time.Millisecond
+
+	typeSpecificData, err := raw(&apitypes.HelmSpecificData{
+		InstallName:   "test",
+		Namespace:     "test",
+		TarballAccess: &apitypes.TarballAccess{URL: "http://127.0.0.1:0/test-1.0.0.tgz"},
+	})
+	assert.NoErr(t, err)
+
+	applConfig := &hubv1.ApplicationConfig{
+		ID:               "test",
+		ConfigType:       util.ConfigTypeHelm,
+		TypeSpecificData: *typeSpecificData,
+	}
+
+	report := newReport(nil)
+	reviewValuesOfApplConfig(log, report, validator, applConfig, nil)
+	assert.Equal(t, report.denied(), false, "denied")
+	assert.Equal(t, len(report.warnings), 1, "number of warnings")
+}
+
+// TestReviewHelmValuesWithinDeadline tests that the charts are loaded concurrently under one deadline, and that the
+// validation is skipped with a warning for charts which are not loaded in time.
+func TestReviewHelmValuesWithinDeadline(t *testing.T) {
+	var log = ctrl.Log.WithName("ClusterBom Admission Hook Unit Tests")
+
+	validator := newHelmSchemaValidator(&AdmissionHookConfig{HelmSchemaValidation: HelmSchemaValidationDeny})
+	validator.loadTimeout = 100 * time.Millisecond
+
+	// loads of the test chart wait for this pending load, which does not finish before the deadline
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		_, _ = validator.chartCache.Load(getChartCacheKey(&apitypes.HelmSpecificData{
+			TarballAccess: &apitypes.TarballAccess{URL: testChartURL},
+		}), func() (*chart.Chart, error) {
+			<-release
+			return nil, errors.New("released")
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	typeSpecificData, err := raw(&apitypes.HelmSpecificData{
+		InstallName:   "test",
+		Namespace:     "test",
+		TarballAccess: &apitypes.TarballAccess{URL: testChartURL},
+	})
+	assert.NoErr(t, err)
+
+	helmReviewer := newHelmReviewer(validator)
+	report := newReport(nil)
+	checks := []*helmValuesCheck{}
+	for i := 0; i < 3; i++ {
+		applConfig := &hubv1.ApplicationConfig{
+			ID:               "test-" + strconv.Itoa(i),
+			ConfigType:       util.ConfigTypeHelm,
+			TypeSpecificData: *typeSpecificData,
+		}
+		fldPath := field.NewPath("spec", "applicationConfigs").Index(i)
+		checks = append(checks, helmReviewer.newValuesCheck(log, report, fldPath, applConfig, nil))
+	}
+
+	start := time.Now()
+	helmReviewer.reviewValues(context.Background(), log, report, checks)
+	duration := time.Since(start)
+
+	assert.True(t, duration < 2*validator.loadTimeout, "charts were not loaded concurrently: "+duration.String())
+	assert.Equal(t, report.denied(), false, "denied")
+	assert.Equal(t, len(report.warnings), 3, "number of warnings")
+	for _, warning := range report.warnings {
+		assert.True(t, strings.Contains(warning, "was skipped"), "warning "+warning)
+	}
+}
+
+func reviewValuesOfApplConfig(log logr.Logger, report *report, validator *helmSchemaValidator,
+	applConfig, oldApplConfig *hubv1.ApplicationConfig) {
+	helmReviewer := newHelmReviewer(validator)
+	fldPath := field.NewPath("spec", "applicationConfigs").Index(0)
+	if check := helmReviewer.newValuesCheck(log, report, fldPath, applConfig, oldApplConfig); check != nil {
+		helmReviewer.reviewValues(context.Background(), log, report, []*helmValuesCheck{check})
+	}
+}
+
+func TestHelmSchemaValidationDisabled(t *testing.T) {
+	assert.True(t, newHelmSchemaValidator(&AdmissionHookConfig{}) == nil, "validator of empty setting")
+	assert.True(t, newHelmSchemaValidator(&AdmissionHookConfig{HelmSchemaValidation: HelmSchemaValidationDisabled}) == nil,
+		"validator of disabled setting")
+}
+
+func primeChartCache(t *testing.T, validator *helmSchemaValidator) {
+	testChart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "test"},
+		Values:   map[string]interface{}{"replicaCount": 1},
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {
+				"replicaCount": {"type": "integer"},
+				"password": {"type": "string"}
+			}
+		}`),
+	}
+
+	_, err := validator.chartCache.Load(getChartCacheKey(&apitypes.HelmSpecificData{
+		TarballAccess: &apitypes.TarballAccess{URL: testChartURL},
+	}), func() (*chart.Chart, error) { return testChart, nil })
+	assert.NoErr(t, err)
+}
+
+func TestChartCacheKeyOfTarballWithAuthHeader(t *testing.T) {
+	tarball := func(authHeader string) *apitypes.HelmSpecificData {
+		return &apitypes.HelmSpecificData{TarballAccess: &apitypes.TarballAccess{URL: testChartURL, AuthHeader: authHeader}}
+	}
+
+	keyWithoutAuth := getChartCacheKey(tarball(""))
+	keyWithAuth := getChartCacheKey(tarball("Basic dXNlcjpwYXNzd29yZA=="))
+	keyWithOtherAuth := getChartCacheKey(tarball("Basic b3RoZXI6cGFzc3dvcmQ="))
+
+	assert.True(t, keyWithAuth != keyWithoutAuth, "key with auth header differs from key without auth header")
+	assert.True(t, keyWithAuth != keyWithOtherAuth, "keys with different auth headers differ")
+	assert.Equal(t, getChartCacheKey(tarball("Basic dXNlcjpwYXNzd29yZA==")), keyWithAuth, "key with same auth header")
+	assert.False(t, strings.Contains(keyWithAuth, "dXNlcjpwYXNzd29yZA=="), "key contains auth header")
+}
@@ -7,22 +7,19 @@ import (
 
 	"github.com/arschles/assert"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 func TestInvalidHelmSpecificData(t *testing.T) {
-	report := newReport(nil)
-
 	typeSpecificData := &runtime.RawExtension{
 		Raw: []byte{4},
 	}
 
-	newHelmReviewer().reviewTypeSpecificData(report, typeSpecificData, nil)
-	assert.Equal(t, report.denied(), true, "denied")
+	errs := newHelmReviewer(nil).validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, nil)
+	assert.True(t, len(errs) > 0, "denied")
 }
 
 func TestInvalidOldHelmSpecificData(t *testing.T) {
-	report := newReport(nil)
-
 	typeSpecificData, err := raw(&apitypes.HelmSpecificData{
 		InstallName: "test",
 		Namespace:   "test",
@@ -36,8 +33,8 @@ func TestInvalidOldHelmSpecificData(t *testing.T) {
 		Raw: []byte{4},
 	}
 
-	newHelmReviewer().reviewTypeSpecificData(report, typeSpecificData, oldTypeSpecificData)
-	assert.Equal(t, report.denied(), true, "denied")
+	errs := newHelmReviewer(nil).validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, oldTypeSpecificData)
+	assert.True(t, len(errs) > 0, "denied")
 }
 
 func TestReviewHelmSpecificData(t *testing.T) {
@@ -258,13 +255,11 @@ func TestReviewHelmSpecificData(t *testing.T) {
 	for i := range tests {
 		test := &tests[i]
 		t.Run(test.name, func(t *testing.T) {
-			report := newReport(nil)
-
 			typeSpecificData, err := raw(test.helmData)
 			assert.Nil(t, err, "error building type specific data")
 
-			newHelmReviewer().reviewTypeSpecificData(report, typeSpecificData, nil)
-			assert.Equal(t, report.denied(), test.expectedDenied, "denied")
+			errs := newHelmReviewer(nil).validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, nil)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
 		})
 	}
 }
@@ -431,16 +426,14 @@ func TestReviewHelmSpecificDataUpdate(t *testing.T) {
 	for i := range tests {
 		test := &tests[i]
 		t.Run(test.name, func(t *testing.T) {
-			report := newReport(nil)
-
 			typeSpecificData, err := raw(test.helmData)
 			assert.Nil(t, err, "error building type specific data")
 
 			oldTypeSpecificData, err := raw(test.oldHelmData)
 			assert.Nil(t, err, "error building old type specific data")
 
-			newHelmReviewer().reviewTypeSpecificData(report, typeSpecificData, oldTypeSpecificData)
-			assert.Equal(t, report.denied(), test.expectedDenied, "denied")
+			errs := newHelmReviewer(nil).validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, oldTypeSpecificData)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
 		})
 	}
 }
@@ -3,11 +3,11 @@ package admission
 import (
 	"encoding/json"
 
-	"github.com/gardener/potter-controller/pkg/util"
+	"github.com/gardener/potter-controller/api/apitypes"
 
-	"github.com/go-logr/logr"
 	"github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type kappReviewer struct{}
@@ -16,21 +16,35 @@ func newKappReviewer() *kappReviewer {
 	return &kappReviewer{}
 }
 
-func (r *kappReviewer) reviewTypeSpecificData(log logr.Logger, report *report, typeSpecificData *runtime.RawExtension, secretRef string) {
+func (r *kappReviewer) validateTypeSpecificData(fldPath *field.Path, typeSpecificData *runtime.RawExtension, secretRef string) field.ErrorList {
 	var appSpec *v1alpha1.AppSpec
 	err := json.Unmarshal(typeSpecificData.Raw, &appSpec)
 	if err != nil {
-		log.V(util.LogLevelWarning).Info("error when unmarshalling kapp specific data", "error", err)
-		report.deny("error when unmarshalling kapp specific data: " + err.Error())
-		return
+		return field.ErrorList{invalidData(fldPath, err)}
+	}
+
+	kappSpecificData, err := apitypes.NewKappSpecificData(typeSpecificData.Raw)
+	if err != nil {
+		return field.ErrorList{invalidData(fldPath, err)}
+	}
+
+	allErrs := field.ErrorList{}
+
+	if err = kappSpecificData.CircuitBreaker.Validate(); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("circuitBreaker"), omittedValue{}, err.Error()))
+	}
+
+	if err = kappSpecificData.ValidateDeletionTimeout(); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("deletionTimeout"), omittedValue{}, err.Error()))
 	}
 
 	if appSpec.Cluster != nil && appSpec.Cluster.KubeconfigSecretRef != nil {
-		if appSpec.Cluster.KubeconfigSecretRef.Name != "" && appSpec.Cluster.KubeconfigSecretRef.Name != secretRef {
-			message := "target cluster of kapp app differs from target cluster in clusterbom"
-			log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + message)
-			report.deny(message)
-			return
+		secretRefName := appSpec.Cluster.KubeconfigSecretRef.Name
+		if secretRefName != "" && secretRefName != secretRef {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("cluster", "kubeconfigSecretRef", "name"), secretRefName,
+				"target cluster of kapp app differs from target cluster in clusterbom"))
 		}
 	}
+
+	return allErrs
 }
@@ -3,28 +3,27 @@ package admission
 import (
 	"encoding/json"
 	"testing"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
 
 	"github.com/arschles/assert"
 	"github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 func TestInvalidKappSpecificData(t *testing.T) {
-	var log = ctrl.Log.WithName("ClusterBom Admission Hook Unit Tests")
-	report := newReport(nil)
-
 	typeSpecificData := &runtime.RawExtension{
 		Raw: []byte{4},
 	}
 
-	newKappReviewer().reviewTypeSpecificData(log, report, typeSpecificData, "a.kubeconfig")
-	assert.Equal(t, report.denied(), true, "denied")
+	errs := newKappReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, "a.kubeconfig")
+	assert.True(t, len(errs) > 0, "denied")
 }
 
 func TestReviewKappSpecificData(t *testing.T) {
-	var log = ctrl.Log.WithName("ClusterBom Admission Hook Unit Tests")
-
 	tests := []struct {
 		name           string
 		cluster        *v1alpha1.AppCluster
@@ -76,16 +75,102 @@ func TestReviewKappSpecificData(t *testing.T) {
 	for i := range tests {
 		test := &tests[i]
 		t.Run(test.name, func(t *testing.T) {
-			report := newReport(nil)
-
 			typeSpecificData, err := raw(&v1alpha1.AppSpec{
 				Cluster: test.cluster,
 			})
 			assert.Nil(t, err, "error building type specific data")
 
-			newKappReviewer().reviewTypeSpecificData(log, report, typeSpecificData, test.secretRef)
+			errs := newKappReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, test.secretRef)
+
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
+
+func TestReviewKappCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name           string
+		circuitBreaker *apitypes.CircuitBreakerPolicy
+		expectedDenied bool
+	}{
+		{
+			name:           "allow default policy",
+			circuitBreaker: nil,
+			expectedDenied: false,
+		},
+		{
+			name:           "allow disabled circuit breaker",
+			circuitBreaker: &apitypes.CircuitBreakerPolicy{Disabled: true},
+			expectedDenied: false,
+		},
+		{
+			name: "allow custom durations",
+			circuitBreaker: &apitypes.CircuitBreakerPolicy{
+				ProblemThreshold: &metav1.Duration{Duration: time.Hour},
+				PauseDuration:    &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			expectedDenied: false,
+		},
+		{
+			name: "reject negative pause duration",
+			circuitBreaker: &apitypes.CircuitBreakerPolicy{
+				PauseDuration: &metav1.Duration{Duration: -time.Minute},
+			},
+			expectedDenied: true,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			typeSpecificData, err := raw(&apitypes.KappSpecificData{
+				AppSpec:        &v1alpha1.AppSpec{},
+				CircuitBreaker: test.circuitBreaker,
+			})
+			assert.Nil(t, err, "error building type specific data")
+
+			errs := newKappReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, "a.kubeconfig")
+
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
+
+func TestReviewKappDeletionTimeout(t *testing.T) {
+	tests := []struct {
+		name            string
+		deletionTimeout *metav1.Duration
+		expectedDenied  bool
+	}{
+		{
+			name:            "default deletion timeout",
+			deletionTimeout: nil,
+			expectedDenied:  false,
+		},
+		{
+			name:            "positive deletion timeout",
+			deletionTimeout: &metav1.Duration{Duration: 2 * time.Hour},
+			expectedDenied:  false,
+		},
+		{
+			name:            "zero deletion timeout",
+			deletionTimeout: &metav1.Duration{},
+			expectedDenied:  true,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			typeSpecificData, err := raw(&apitypes.KappSpecificData{
+				AppSpec:         &v1alpha1.AppSpec{},
+				DeletionTimeout: test.deletionTimeout,
+			})
+			assert.Nil(t, err, "error building type specific data")
+
+			errs := newKappReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData, "a.kubeconfig")
 
-			assert.Equal(t, report.denied(), test.expectedDenied, "denied")
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
 		})
 	}
 }
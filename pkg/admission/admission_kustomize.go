@@ -0,0 +1,32 @@
+package admission
+
+import (
+	"encoding/json"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type kustomizeReviewer struct{}
+
+func newKustomizeReviewer() *kustomizeReviewer {
+	return &kustomizeReviewer{}
+}
+
+// The existence of the secret values referenced by secrets and secret patches is not checked here, because secret
+// values which are omitted in an update are kept. Missing secret values are reported when the application is deployed.
+func (r *kustomizeReviewer) validateTypeSpecificData(fldPath *field.Path, typeSpecificData *runtime.RawExtension) field.ErrorList {
+	var kustomizeData apitypes.KustomizeSpecificData
+	err := json.Unmarshal(typeSpecificData.Raw, &kustomizeData)
+	if err != nil {
+		return field.ErrorList{invalidData(fldPath, err)}
+	}
+
+	if err = kustomizeData.Validate(); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, omittedValue{}, "invalid kustomize specific data: "+err.Error())}
+	}
+
+	return nil
+}
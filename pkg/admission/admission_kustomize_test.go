@@ -0,0 +1,97 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestReviewKustomizeSpecificData(t *testing.T) {
+	tests := []struct {
+		name             string
+		typeSpecificData string
+		expectedDenied   bool
+	}{
+		{
+			name:             "allow files",
+			typeSpecificData: `{"files":{"kustomization.yaml":"resources: []"}}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow tarball with path",
+			typeSpecificData: `{"tarball":{"url":"https://example.com/app.tgz"},"path":"overlays/prod"}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow git",
+			typeSpecificData: `{"git":{"url":"https://example.com/app.git","ref":"v1.0.0"}}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow scp-like git url",
+			typeSpecificData: `{"git":{"url":"git@example.com:org/app.git"}}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "reject file git url",
+			typeSpecificData: `{"git":{"url":"file:///etc"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject local git path",
+			typeSpecificData: `{"git":{"url":"/var/run/secrets"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject http git url",
+			typeSpecificData: `{"git":{"url":"http://example.com/app.git"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject missing source",
+			typeSpecificData: `{"path":"base"}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject multiple sources",
+			typeSpecificData: `{"tarball":{"url":"https://example.com/app.tgz"},"git":{"url":"https://example.com/app.git"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject path outside of source",
+			typeSpecificData: `{"git":{"url":"https://example.com/app.git"},"path":"../other"}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject absolute file path",
+			typeSpecificData: `{"files":{"/etc/kustomization.yaml":"resources: []"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "allow secrets and secret patches",
+			typeSpecificData: `{"files":{"kustomization.yaml":"resources: []"},"secrets":[{"name":"creds"}],"secretPatches":[{"secretRef":"patches","key":"replicas"}]}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "reject secret without name",
+			typeSpecificData: `{"files":{"kustomization.yaml":"resources: []"},"secrets":[{"secretRef":"creds"}]}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject secret patch without key",
+			typeSpecificData: `{"files":{"kustomization.yaml":"resources: []"},"secretPatches":[{"secretRef":"patches"}]}`,
+			expectedDenied:   true,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			typeSpecificData := &runtime.RawExtension{Raw: []byte(test.typeSpecificData)}
+			errs := newKustomizeReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package admission
+
+import (
+	"encoding/json"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type manifestReviewer struct{}
+
+func newManifestReviewer() *manifestReviewer {
+	return &manifestReviewer{}
+}
+
+func (r *manifestReviewer) validateTypeSpecificData(fldPath *field.Path, typeSpecificData *runtime.RawExtension) field.ErrorList {
+	var manifestData apitypes.ManifestSpecificData
+	err := json.Unmarshal(typeSpecificData.Raw, &manifestData)
+	if err != nil {
+		return field.ErrorList{invalidData(fldPath, err)}
+	}
+
+	if err = manifestData.Validate(); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, omittedValue{}, "invalid manifest specific data: "+err.Error())}
+	}
+
+	allErrs := field.ErrorList{}
+	for i := range manifestData.Manifests {
+		if ok, message := r.checkManifest(&manifestData.Manifests[i]); !ok {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("manifests").Index(i), omittedValue{}, message))
+		}
+	}
+
+	return allErrs
+}
+
+// Inline manifests are checked to be complete objects. Manifests from urls and config maps are only checked when they
+// are deployed.
+func (r *manifestReviewer) checkManifest(manifest *runtime.RawExtension) (bool, string) {
+	obj := unstructured.Unstructured{}
+	if err := json.Unmarshal(manifest.Raw, &obj.Object); err != nil {
+		return false, "is not an object: " + err.Error()
+	}
+
+	if obj.GetAPIVersion() == "" {
+		return false, "has no apiVersion"
+	}
+
+	if obj.GetKind() == "" {
+		return false, "has no kind"
+	}
+
+	if obj.GetName() == "" {
+		return false, "has no metadata.name"
+	}
+
+	return true, ""
+}
@@ -0,0 +1,67 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestReviewManifestSpecificData(t *testing.T) {
+	tests := []struct {
+		name             string
+		typeSpecificData string
+		expectedDenied   bool
+	}{
+		{
+			name:             "allow inline manifests",
+			typeSpecificData: `{"namespace":"test","manifests":[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}]}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow url",
+			typeSpecificData: `{"url":"https://example.com/manifests.yaml"}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow config map",
+			typeSpecificData: `{"configMapRef":{"name":"manifests"}}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "reject missing source",
+			typeSpecificData: `{"namespace":"test"}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject multiple sources",
+			typeSpecificData: `{"url":"https://example.com/manifests.yaml","configMapRef":{"name":"manifests"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject config map without name",
+			typeSpecificData: `{"configMapRef":{"key":"manifests"}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject manifest without kind",
+			typeSpecificData: `{"manifests":[{"apiVersion":"v1","metadata":{"name":"cm"}}]}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject manifest without name",
+			typeSpecificData: `{"manifests":[{"apiVersion":"v1","kind":"ConfigMap"}]}`,
+			expectedDenied:   true,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			typeSpecificData := &runtime.RawExtension{Raw: []byte(test.typeSpecificData)}
+			errs := newManifestReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
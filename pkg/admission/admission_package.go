@@ -0,0 +1,30 @@
+package admission
+
+import (
+	"encoding/json"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type packageReviewer struct{}
+
+func newPackageReviewer() *packageReviewer {
+	return &packageReviewer{}
+}
+
+func (r *packageReviewer) validateTypeSpecificData(fldPath *field.Path, typeSpecificData *runtime.RawExtension) field.ErrorList {
+	var packageData apitypes.PackageSpecificData
+	err := json.Unmarshal(typeSpecificData.Raw, &packageData)
+	if err != nil {
+		return field.ErrorList{invalidData(fldPath, err)}
+	}
+
+	if err = packageData.Validate(); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, omittedValue{}, "invalid package specific data: "+err.Error())}
+	}
+
+	return nil
+}
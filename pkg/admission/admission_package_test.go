@@ -0,0 +1,52 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestReviewPackageSpecificData(t *testing.T) {
+	tests := []struct {
+		name             string
+		typeSpecificData string
+		expectedDenied   bool
+	}{
+		{
+			name:             "allow package with version constraint and values",
+			typeSpecificData: `{"packageName":"cert-manager.community.tanzu.vmware.com","version":">=1.0.0 <2.0.0","values":{"namespace":"cert-manager"}}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow package with repository",
+			typeSpecificData: `{"packageName":"cert-manager.community.tanzu.vmware.com","repository":{"imgpkgBundle":{"image":"projects.registry.vmware.com/tce/main:0.9.1"}}}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "reject missing package name",
+			typeSpecificData: `{"version":"1.5.3"}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject repository without source",
+			typeSpecificData: `{"packageName":"cert-manager.community.tanzu.vmware.com","repository":{}}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject invalid values",
+			typeSpecificData: `{"packageName":"cert-manager.community.tanzu.vmware.com","values":"replicas: 2"}`,
+			expectedDenied:   true,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			typeSpecificData := &runtime.RawExtension{Raw: []byte(test.typeSpecificData)}
+			errs := newPackageReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package admission
+
+import (
+	"context"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// pluginReviewer delegates the validation of typeSpecificData to the deployer plugin registered for the config type.
+type pluginReviewer struct {
+	registration  *hubv1.DeployerRegistration
+	pluginClients *deployerplugin.ClientCache
+}
+
+func newPluginReviewer(registration *hubv1.DeployerRegistration, pluginClients *deployerplugin.ClientCache) *pluginReviewer {
+	return &pluginReviewer{
+		registration:  registration,
+		pluginClients: pluginClients,
+	}
+}
+
+// validateTypeSpecificData calls the deployer plugin with the context of the review, so that all plugin calls of a
+// review share its deadline.
+func (r *pluginReviewer) validateTypeSpecificData(ctx context.Context, fldPath *field.Path, typeSpecificData *runtime.RawExtension) field.ErrorList {
+	pluginClient, err := r.pluginClients.Get(r.registration)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, errors.Wrap(err, "could not create client for deployer plugin"))}
+	}
+
+	response, err := pluginClient.Validate(ctx, typeSpecificData)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, errors.Wrap(err, "validation by deployer plugin failed"))}
+	}
+
+	if !response.Allowed {
+		message := "invalid " + r.registration.Spec.ConfigType + " specific data: " + response.Message
+		return field.ErrorList{field.Invalid(fldPath, omittedValue{}, message)}
+	}
+
+	return nil
+}
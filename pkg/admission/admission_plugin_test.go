@@ -0,0 +1,75 @@
+package admission
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
+	"github.com/gardener/potter-controller/pkg/deployerplugin/reference"
+
+	"github.com/arschles/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestReviewPluginSpecificData(t *testing.T) {
+	var log = ctrl.Log.WithName("ClusterBom Admission Hook Unit Tests")
+
+	server := httptest.NewServer(deployerplugin.NewHandler(reference.NewReferenceDeployer(), reference.Validate, log))
+	defer server.Close()
+
+	unavailableServer := httptest.NewServer(nil)
+	unavailableServer.Close()
+
+	tests := []struct {
+		name             string
+		url              string
+		typeSpecificData string
+		expectedDenied   bool
+	}{
+		{
+			name:             "allowed by plugin",
+			url:              server.URL,
+			typeSpecificData: `{"message":"hello"}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "rejected by plugin",
+			url:              server.URL,
+			typeSpecificData: `{"fail":true}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "plugin unavailable",
+			url:              unavailableServer.URL,
+			typeSpecificData: `{"message":"hello"}`,
+			expectedDenied:   true,
+		},
+	}
+
+	pluginClients := deployerplugin.NewClientCache()
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			registration := &hubv1.DeployerRegistration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: test.name,
+				},
+				Spec: hubv1.DeployerRegistrationSpec{
+					ConfigType: reference.ConfigType,
+					URL:        test.url,
+				},
+			}
+
+			typeSpecificData := &runtime.RawExtension{Raw: []byte(test.typeSpecificData)}
+			errs := newPluginReviewer(registration, pluginClients).validateTypeSpecificData(context.Background(),
+				field.NewPath("typeSpecificData"), typeSpecificData)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+// checkAdmissionPolicies denies a clusterbom which violates a rule of an admission policy in whose scope it is. All
+// violations of all policies are collected, so that they are returned together; a policy with an invalid scope is
+// reported as internal error without hiding the violations of the other policies. Updates which change neither the spec
+// nor the labels are not checked, so that a clusterbom which violates a policy that was created later can still be
+// deleted.
+func (r *clusterBomReviewer) checkAdmissionPolicies(report *report, clusterBom, oldClusterBom *hubv1.ClusterBom) {
+	if r.isUpdate() && reflect.DeepEqual(clusterBom.Spec, oldClusterBom.Spec) &&
+		reflect.DeepEqual(clusterBom.GetLabels(), oldClusterBom.GetLabels()) {
+		return
+	}
+
+	policyList := &hubv1.AdmissionPolicyList{}
+	if err := r.reader.ListUncached(r.ctx, policyList); err != nil {
+		r.log.Error(err, "cannot list admission policies")
+		report.fail("cannot list admission policies: " + err.Error())
+		return
+	}
+
+	allErrs := field.ErrorList{}
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+
+		inScope, err := isInPolicyScope(&policy.Spec.Scope, clusterBom)
+		if err != nil {
+			r.log.Error(err, "admission policy has an invalid scope", "policy", policy.Name)
+			allErrs = append(allErrs, field.InternalError(field.NewPath("metadata", "labels"),
+				fmt.Errorf("admission policy %s has an invalid scope: %s", policy.Name, err.Error())))
+			continue
+		} else if !inScope {
+			continue
+		}
+
+		if errs := checkPolicyRules(policy.Name, &policy.Spec.Rules, clusterBom); len(errs) > 0 {
+			r.log.Info("ClusterBom violates admission policy", "policy", policy.Name, "violations", errs.ToAggregate().Error())
+			allErrs = append(allErrs, errs...)
+		}
+	}
+
+	report.addErrors(allErrs)
+}
+
+func isInPolicyScope(scope *hubv1.AdmissionPolicyScope, clusterBom *hubv1.ClusterBom) (bool, error) {
+	if len(scope.Namespaces) > 0 && !util.ContainsString(clusterBom.GetNamespace(), scope.Namespaces) {
+		return false, nil
+	}
+
+	if scope.ClusterBomSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(scope.ClusterBomSelector)
+		if err != nil {
+			return false, err
+		}
+
+		if !selector.Matches(labels.Set(clusterBom.GetLabels())) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// checkPolicyRules returns the violations of the rules of a policy
+func checkPolicyRules(policyName string, rules *hubv1.AdmissionPolicyRules, clusterBom *hubv1.ClusterBom) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i := range rules.RequiredLabels {
+		requiredLabel := &rules.RequiredLabels[i]
+		fldPath := field.NewPath("metadata", "labels").Key(requiredLabel.Key)
+
+		value, ok := clusterBom.GetLabels()[requiredLabel.Key]
+		if !ok {
+			allErrs = append(allErrs, policyViolation(fldPath, policyName,
+				"rule requiredLabels violated: label %s is missing", requiredLabel.Key))
+		} else if len(requiredLabel.Values) > 0 && !matchesAnyPattern(value, requiredLabel.Values) {
+			allErrs = append(allErrs, policyViolation(fldPath, policyName,
+				"rule requiredLabels violated: value %s of label %s is not allowed", value, requiredLabel.Key))
+		}
+	}
+
+	appConfigsPath := field.NewPath("spec", "applicationConfigs")
+
+	if rules.MaxApplicationConfigs != nil && len(clusterBom.Spec.ApplicationConfigs) > int(*rules.MaxApplicationConfigs) {
+		allErrs = append(allErrs, policyViolation(appConfigsPath, policyName,
+			"rule maxApplicationConfigs violated: %d application configs exceed the maximum of %d",
+			len(clusterBom.Spec.ApplicationConfigs), *rules.MaxApplicationConfigs))
+	}
+
+	for i := range clusterBom.Spec.ApplicationConfigs {
+		allErrs = append(allErrs, checkPolicyRulesForAppConfig(appConfigsPath.Index(i), policyName, rules,
+			&clusterBom.Spec.ApplicationConfigs[i])...)
+	}
+
+	return allErrs
+}
+
+func checkPolicyRulesForAppConfig(fldPath *field.Path, policyName string, rules *hubv1.AdmissionPolicyRules,
+	appConfig *hubv1.ApplicationConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(rules.AllowedConfigTypes) > 0 && !util.ContainsString(appConfig.ConfigType, rules.AllowedConfigTypes) {
+		allErrs = append(allErrs, policyViolation(fldPath.Child("configType"), policyName,
+			"rule allowedConfigTypes violated: config type %s of application config %s is not allowed",
+			appConfig.ConfigType, appConfig.ID))
+	}
+
+	if appConfig.ConfigType == util.ConfigTypeHelm {
+		allErrs = append(allErrs, checkPolicyRulesForChart(fldPath.Child("typeSpecificData"), policyName, rules, appConfig)...)
+	}
+
+	if len(rules.AllowedTargetNamespaces) > 0 {
+		namespaces, ok := getTargetNamespaces(appConfig)
+		if !ok {
+			allErrs = append(allErrs, policyViolation(fldPath.Child("typeSpecificData"), policyName,
+				"rule allowedTargetNamespaces violated: target namespaces of application config %s with config type %s cannot be determined",
+				appConfig.ID, appConfig.ConfigType))
+		}
+
+		for _, namespace := range namespaces {
+			if !matchesAnyPattern(namespace, rules.AllowedTargetNamespaces) {
+				allErrs = append(allErrs, policyViolation(fldPath.Child("typeSpecificData"), policyName,
+					"rule allowedTargetNamespaces violated: target namespace %s of application config %s is not allowed",
+					namespace, appConfig.ID))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func checkPolicyRulesForChart(fldPath *field.Path, policyName string, rules *hubv1.AdmissionPolicyRules,
+	appConfig *hubv1.ApplicationConfig) field.ErrorList {
+	if len(rules.AllowedChartRepositories) == 0 && len(rules.AllowedChartNames) == 0 {
+		return nil
+	}
+
+	var helmData apitypes.HelmSpecificData
+	if err := json.Unmarshal(appConfig.TypeSpecificData.Raw, &helmData); err != nil {
+		return field.ErrorList{policyViolation(fldPath, policyName,
+			"chart of application config %s cannot be checked: %s", appConfig.ID, err.Error())}
+	}
+
+	allErrs := field.ErrorList{}
+
+	repository := ""
+	if helmData.CatalogAccess != nil {
+		repository = helmData.CatalogAccess.Repo
+	} else if helmData.TarballAccess != nil {
+		repository = helmData.TarballAccess.URL
+	}
+
+	if len(rules.AllowedChartRepositories) > 0 && !matchesAnyPattern(repository, rules.AllowedChartRepositories) {
+		allErrs = append(allErrs, policyViolation(fldPath, policyName,
+			"rule allowedChartRepositories violated: chart repository %s of application config %s is not allowed",
+			repository, appConfig.ID))
+	}
+
+	if len(rules.AllowedChartNames) > 0 {
+		if helmData.CatalogAccess == nil {
+			allErrs = append(allErrs, policyViolation(fldPath, policyName,
+				"rule allowedChartNames violated: chart name of application config %s cannot be checked, because it uses tarballAccess",
+				appConfig.ID))
+		} else if !matchesAnyPattern(helmData.CatalogAccess.ChartName, rules.AllowedChartNames) {
+			allErrs = append(allErrs, policyViolation(fldPath, policyName,
+				"rule allowedChartNames violated: chart %s of application config %s is not allowed",
+				helmData.CatalogAccess.ChartName, appConfig.ID))
+		}
+	}
+
+	return allErrs
+}
+
+// policyViolation returns a forbidden error whose message names the policy and the violated rule
+func policyViolation(fldPath *field.Path, policyName, format string, args ...interface{}) *field.Error {
+	return field.Forbidden(fldPath, fmt.Sprintf("admission policy %s: ", policyName)+fmt.Sprintf(format, args...))
+}
+
+// getTargetNamespaces returns the namespaces on the target cluster into which an application config deploys, and
+// whether they could be determined. Manifests from urls or config maps, kustomizations, kapp apps and deployer plugins
+// can deploy into any namespace, which is only known when they are rendered on the target cluster.
+func getTargetNamespaces(appConfig *hubv1.ApplicationConfig) ([]string, bool) {
+	raw := appConfig.TypeSpecificData.Raw
+	namespaces := []string{}
+
+	switch appConfig.ConfigType {
+	case util.ConfigTypeHelm:
+		var data apitypes.HelmSpecificData
+		if json.Unmarshal(raw, &data) != nil {
+			return nil, false
+		}
+		namespaces = append(namespaces, data.Namespace)
+	case util.ConfigTypeManifest:
+		var data apitypes.ManifestSpecificData
+		if json.Unmarshal(raw, &data) != nil || len(data.Manifests) == 0 {
+			return nil, false
+		}
+		return getManifestNamespaces(&data)
+	case util.ConfigTypePackage:
+		var data apitypes.PackageSpecificData
+		if json.Unmarshal(raw, &data) != nil {
+			return nil, false
+		}
+		namespaces = append(namespaces, data.Namespace)
+	case util.ConfigTypeSecretSync:
+		var data apitypes.SecretSyncSpecificData
+		if json.Unmarshal(raw, &data) != nil {
+			return nil, false
+		}
+		for i := range data.Secrets {
+			namespaces = append(namespaces, data.Secrets[i].Namespaces...)
+		}
+	default:
+		return nil, false
+	}
+
+	result := []string{}
+	for _, namespace := range namespaces {
+		if namespace != "" {
+			result = append(result, namespace)
+		}
+	}
+
+	return result, true
+}
+
+// getManifestNamespaces returns the namespaces of inline manifests. Objects without namespace are counted in the
+// namespace which the manifest deployer uses for them, also if they are cluster scoped, because their scope is not
+// known without the target cluster.
+func getManifestNamespaces(data *apitypes.ManifestSpecificData) ([]string, bool) {
+	defaultNamespace := data.Namespace
+	if defaultNamespace == "" {
+		defaultNamespace = "default"
+	}
+
+	namespaces := []string{}
+	for i := range data.Manifests {
+		obj := unstructured.Unstructured{}
+		if err := json.Unmarshal(data.Manifests[i].Raw, &obj.Object); err != nil {
+			return nil, false
+		}
+
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+
+		if !util.ContainsString(namespace, namespaces) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces, true
+}
+
+// matchesAnyPattern checks whether a value matches one of the patterns, in which "*" matches any sequence of characters
+func matchesAnyPattern(value string, patterns []string) bool {
+	for _, pattern := range patterns {
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, err := regexp.MatchString(expr, value); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
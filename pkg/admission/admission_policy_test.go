@@ -0,0 +1,318 @@
+package admission
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+func TestCheckAdmissionPolicies(t *testing.T) {
+	maxTwo := int32(2)
+	maxOne := int32(1)
+
+	tests := []struct {
+		name            string
+		scope           hubv1.AdmissionPolicyScope
+		rules           hubv1.AdmissionPolicyRules
+		expectedMessage string
+	}{
+		{
+			name: "satisfied",
+			rules: hubv1.AdmissionPolicyRules{
+				AllowedConfigTypes:       []string{util.ConfigTypeHelm},
+				AllowedChartRepositories: []string{"testrepo*", "testurl*"},
+				AllowedTargetNamespaces:  []string{"testnamespace*"},
+				MaxApplicationConfigs:    &maxTwo,
+				RequiredLabels:           []hubv1.RequiredLabel{{Key: "team", Values: []string{"team-*"}}},
+			},
+		},
+		{
+			name:            "config-type",
+			rules:           hubv1.AdmissionPolicyRules{AllowedConfigTypes: []string{util.ConfigTypeKapp}},
+			expectedMessage: "admission policy test-policy: rule allowedConfigTypes violated",
+		},
+		{
+			name:            "chart-repository",
+			rules:           hubv1.AdmissionPolicyRules{AllowedChartRepositories: []string{"testrepo*"}},
+			expectedMessage: "admission policy test-policy: rule allowedChartRepositories violated: chart repository testurl02",
+		},
+		{
+			name:            "chart-name-of-tarball",
+			rules:           hubv1.AdmissionPolicyRules{AllowedChartNames: []string{"*"}},
+			expectedMessage: "admission policy test-policy: rule allowedChartNames violated: chart name of application config id02",
+		},
+		{
+			name:            "target-namespace",
+			rules:           hubv1.AdmissionPolicyRules{AllowedTargetNamespaces: []string{"testnamespace01"}},
+			expectedMessage: "admission policy test-policy: rule allowedTargetNamespaces violated: target namespace testnamespace02",
+		},
+		{
+			name:            "max-application-configs",
+			rules:           hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne},
+			expectedMessage: "admission policy test-policy: rule maxApplicationConfigs violated",
+		},
+		{
+			name:            "required-label-missing",
+			rules:           hubv1.AdmissionPolicyRules{RequiredLabels: []hubv1.RequiredLabel{{Key: "cost-center"}}},
+			expectedMessage: "admission policy test-policy: rule requiredLabels violated: label cost-center is missing",
+		},
+		{
+			name:            "required-label-value",
+			rules:           hubv1.AdmissionPolicyRules{RequiredLabels: []hubv1.RequiredLabel{{Key: "team", Values: []string{"other"}}}},
+			expectedMessage: "admission policy test-policy: rule requiredLabels violated: value team-a of label team",
+		},
+		{
+			name:  "other-namespace",
+			scope: hubv1.AdmissionPolicyScope{Namespaces: []string{"garden-other"}},
+			rules: hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne},
+		},
+		{
+			name: "not-selected",
+			scope: hubv1.AdmissionPolicyScope{
+				ClusterBomSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "team-b"}},
+			},
+			rules: hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne},
+		},
+		{
+			name: "selected",
+			scope: hubv1.AdmissionPolicyScope{
+				Namespaces:         []string{"garden-test"},
+				ClusterBomSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "team-a"}},
+			},
+			rules:           hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne},
+			expectedMessage: "admission policy test-policy: rule maxApplicationConfigs violated",
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			clusterBom := clusterBom01(t)
+			clusterBom.Namespace = "garden-test"
+			clusterBom.Labels = map[string]string{"team": "team-a"}
+
+			reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+			reviewer.reader = &readerMock{
+				admissionPolicies: []hubv1.AdmissionPolicy{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+						Spec:       hubv1.AdmissionPolicySpec{Scope: test.scope, Rules: test.rules},
+					},
+				},
+			}
+
+			responseReview := reviewer.review()
+			if test.expectedMessage == "" {
+				assert.True(t, responseReview.Response.Allowed, "allowed")
+				return
+			}
+
+			assert.False(t, responseReview.Response.Allowed, "allowed")
+			assert.Equal(t, responseReview.Response.Result.Reason, metav1.StatusReasonForbidden, "reason")
+			assert.True(t, strings.Contains(responseReview.Response.Result.Message, test.expectedMessage),
+				"unexpected message: "+responseReview.Response.Result.Message)
+		})
+	}
+}
+
+// TestCheckAdmissionPoliciesCollectsViolations tests that the violations of all rules and policies are returned together
+func TestCheckAdmissionPoliciesCollectsViolations(t *testing.T) {
+	maxOne := int32(1)
+
+	clusterBom := clusterBom01(t)
+	reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+	reviewer.reader = &readerMock{
+		admissionPolicies: []hubv1.AdmissionPolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-a"},
+				Spec: hubv1.AdmissionPolicySpec{Rules: hubv1.AdmissionPolicyRules{
+					MaxApplicationConfigs:   &maxOne,
+					AllowedTargetNamespaces: []string{"other"},
+				}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-b"},
+				Spec: hubv1.AdmissionPolicySpec{Rules: hubv1.AdmissionPolicyRules{
+					AllowedConfigTypes: []string{util.ConfigTypeKapp},
+				}},
+			},
+		},
+	}
+
+	responseReview := reviewer.review()
+	assert.False(t, responseReview.Response.Allowed, "allowed")
+	assert.Equal(t, responseReview.Response.Result.Reason, metav1.StatusReasonForbidden, "reason")
+
+	fields := []string{}
+	for _, cause := range responseReview.Response.Result.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	assert.Equal(t, fields, []string{
+		"spec.applicationConfigs",
+		"spec.applicationConfigs[0].typeSpecificData",
+		"spec.applicationConfigs[1].typeSpecificData",
+		"spec.applicationConfigs[0].configType",
+		"spec.applicationConfigs[1].configType",
+	}, "fields of the violations")
+}
+
+// TestCheckAdmissionPoliciesWithInvalidScope tests that a policy with an invalid scope does not hide the violations
+// of the other policies
+func TestCheckAdmissionPoliciesWithInvalidScope(t *testing.T) {
+	maxOne := int32(1)
+
+	clusterBom := clusterBom01(t)
+	reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+	reviewer.reader = &readerMock{
+		admissionPolicies: []hubv1.AdmissionPolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-invalid"},
+				Spec: hubv1.AdmissionPolicySpec{Scope: hubv1.AdmissionPolicyScope{
+					ClusterBomSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "team", Operator: "Unknown"},
+					}},
+				}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-a"},
+				Spec:       hubv1.AdmissionPolicySpec{Rules: hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne}},
+			},
+		},
+	}
+
+	responseReview := reviewer.review()
+	assert.False(t, responseReview.Response.Allowed, "allowed")
+	assert.Equal(t, responseReview.Response.Result.Reason, metav1.StatusReasonInternalError, "reason")
+
+	causes := responseReview.Response.Result.Details.Causes
+	assert.Equal(t, len(causes), 2, "number of causes")
+	assert.Equal(t, causes[0].Type, metav1.CauseType(field.ErrorTypeInternal), "type of the invalid scope")
+	assert.True(t, strings.Contains(causes[0].Message, "admission policy policy-invalid has an invalid scope"),
+		"unexpected message: "+causes[0].Message)
+	assert.Equal(t, causes[1].Field, "spec.applicationConfigs", "field of the violation")
+	assert.True(t, strings.Contains(causes[1].Message, "admission policy policy-a: rule maxApplicationConfigs violated"),
+		"unexpected message: "+causes[1].Message)
+}
+
+func TestCheckTargetNamespaces(t *testing.T) {
+	rules := &hubv1.AdmissionPolicyRules{AllowedTargetNamespaces: []string{"team-*"}}
+
+	tests := []struct {
+		name             string
+		configType       string
+		typeSpecificData interface{}
+		expectedMessages []string
+	}{
+		{
+			name:             "helm",
+			configType:       util.ConfigTypeHelm,
+			typeSpecificData: apitypes.HelmSpecificData{Namespace: "team-a"},
+		},
+		{
+			name:       "secretsync",
+			configType: util.ConfigTypeSecretSync,
+			typeSpecificData: apitypes.SecretSyncSpecificData{Secrets: []apitypes.SyncedSecret{
+				{Namespaces: []string{"team-a", "kube-system"}},
+			}},
+			expectedMessages: []string{"target namespace kube-system of application config test-id is not allowed"},
+		},
+		{
+			name:       "manifest with namespaces of the objects",
+			configType: util.ConfigTypeManifest,
+			typeSpecificData: map[string]interface{}{
+				"namespace": "team-a",
+				"manifests": []interface{}{
+					testConfigMap(""),
+					testConfigMap("team-b"),
+					testConfigMap("kube-system"),
+				},
+			},
+			expectedMessages: []string{"target namespace kube-system of application config test-id is not allowed"},
+		},
+		{
+			name:       "manifest without namespace",
+			configType: util.ConfigTypeManifest,
+			typeSpecificData: map[string]interface{}{
+				"manifests": []interface{}{testConfigMap("")},
+			},
+			expectedMessages: []string{"target namespace default of application config test-id is not allowed"},
+		},
+		{
+			name:             "manifest from url",
+			configType:       util.ConfigTypeManifest,
+			typeSpecificData: apitypes.ManifestSpecificData{Namespace: "team-a", URL: "https://example.com/manifests.yaml"},
+			expectedMessages: []string{"target namespaces of application config test-id with config type manifest cannot be determined"},
+		},
+		{
+			name:             "kustomize",
+			configType:       util.ConfigTypeKustomize,
+			typeSpecificData: apitypes.KustomizeSpecificData{Namespace: "team-a"},
+			expectedMessages: []string{"target namespaces of application config test-id with config type kustomize cannot be determined"},
+		},
+		{
+			name:             "kapp",
+			configType:       util.ConfigTypeKapp,
+			typeSpecificData: map[string]interface{}{},
+			expectedMessages: []string{"target namespaces of application config test-id with config type kapp cannot be determined"},
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			appConfig := &hubv1.ApplicationConfig{
+				ID:               "test-id",
+				ConfigType:       test.configType,
+				TypeSpecificData: buildRawExtension(t, test.typeSpecificData),
+			}
+
+			errs := checkPolicyRulesForAppConfig(field.NewPath("test"), "test-policy", rules, appConfig)
+			assert.Equal(t, len(errs), len(test.expectedMessages), "number of violations")
+			for j := range test.expectedMessages {
+				assert.True(t, strings.HasSuffix(errs[j].Detail, test.expectedMessages[j]), "unexpected message: "+errs[j].Detail)
+				assert.Equal(t, errs[j].Type, field.ErrorTypeForbidden, "error type")
+			}
+		})
+	}
+}
+
+func testConfigMap(namespace string) map[string]interface{} {
+	metadata := map[string]interface{}{"name": "test"}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+
+	return map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": metadata}
+}
+
+func TestCheckAdmissionPoliciesOnUpdate(t *testing.T) {
+	maxOne := int32(1)
+	policies := []hubv1.AdmissionPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+			Spec:       hubv1.AdmissionPolicySpec{Rules: hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne}},
+		},
+	}
+
+	// an update which does not change spec or labels is allowed, although the clusterbom violates the policy
+	clusterBom := clusterBom01(t)
+	oldClusterBom := clusterBom01(t)
+	util.AddAnnotation(&clusterBom, "other", "value")
+
+	reviewer := buildReviewerForClusterBomUpdate(t, &clusterBom, &oldClusterBom)
+	reviewer.reader = &readerMock{admissionPolicies: policies}
+	assert.True(t, reviewer.review().Response.Allowed, "allowed")
+
+	// an update of the spec is checked
+	oldClusterBom.Spec.ApplicationConfigs = oldClusterBom.Spec.ApplicationConfigs[:1]
+	reviewer = buildReviewerForClusterBomUpdate(t, &clusterBom, &oldClusterBom)
+	reviewer.reader = &readerMock{admissionPolicies: policies}
+	assert.False(t, reviewer.review().Response.Allowed, "allowed")
+}
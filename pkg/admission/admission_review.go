@@ -0,0 +1,117 @@
+package admission
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const kindAdmissionReview = "AdmissionReview"
+
+// decodeAdmissionReview decodes an AdmissionReview of version admission.k8s.io/v1 or admission.k8s.io/v1beta1. The
+// reviewers work with the v1beta1 types, so that a v1 review is converted. The returned version is the version in which
+// the response must be encoded.
+func decodeAdmissionReview(body []byte) (*v1beta1.AdmissionReview, string, error) {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(body, &typeMeta); err != nil {
+		return nil, "", err
+	}
+
+	switch typeMeta.APIVersion {
+	case admissionv1.SchemeGroupVersion.String():
+		review := admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil {
+			return nil, "", err
+		}
+
+		if review.Request == nil {
+			return nil, "", errors.New("admission review contains no request")
+		}
+
+		return &v1beta1.AdmissionReview{
+			TypeMeta: typeMeta,
+			Request:  convertRequestToV1beta1(review.Request),
+		}, typeMeta.APIVersion, nil
+
+	case v1beta1.SchemeGroupVersion.String(), "":
+		// the API server always sends the version, which is omitted only by old clients
+		review := v1beta1.AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil {
+			return nil, "", err
+		}
+
+		if review.Request == nil {
+			return nil, "", errors.New("admission review contains no request")
+		}
+
+		return &review, v1beta1.SchemeGroupVersion.String(), nil
+
+	default:
+		return nil, "", errors.Errorf("unsupported version %s of admission review", typeMeta.APIVersion)
+	}
+}
+
+// encodeAdmissionReview encodes the response review in the version of the request review
+func encodeAdmissionReview(responseReview *v1beta1.AdmissionReview, apiVersion string) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{
+		APIVersion: apiVersion,
+		Kind:       kindAdmissionReview,
+	}
+
+	if apiVersion != admissionv1.SchemeGroupVersion.String() {
+		return json.Marshal(&v1beta1.AdmissionReview{
+			TypeMeta: typeMeta,
+			Response: responseReview.Response,
+		})
+	}
+
+	return json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: typeMeta,
+		Response: convertResponseToV1(responseReview.Response),
+	})
+}
+
+func convertRequestToV1beta1(request *admissionv1.AdmissionRequest) *v1beta1.AdmissionRequest {
+	return &v1beta1.AdmissionRequest{
+		UID:                request.UID,
+		Kind:               request.Kind,
+		Resource:           request.Resource,
+		SubResource:        request.SubResource,
+		RequestKind:        request.RequestKind,
+		RequestResource:    request.RequestResource,
+		RequestSubResource: request.RequestSubResource,
+		Name:               request.Name,
+		Namespace:          request.Namespace,
+		Operation:          v1beta1.Operation(request.Operation),
+		UserInfo:           request.UserInfo,
+		Object:             request.Object,
+		OldObject:          request.OldObject,
+		DryRun:             request.DryRun,
+		Options:            request.Options,
+	}
+}
+
+func convertResponseToV1(response *v1beta1.AdmissionResponse) *admissionv1.AdmissionResponse {
+	if response == nil {
+		return nil
+	}
+
+	var patchType *admissionv1.PatchType
+	if response.PatchType != nil {
+		pt := admissionv1.PatchType(*response.PatchType)
+		patchType = &pt
+	}
+
+	return &admissionv1.AdmissionResponse{
+		UID:              response.UID,
+		Allowed:          response.Allowed,
+		Result:           response.Result,
+		Patch:            response.Patch,
+		PatchType:        patchType,
+		AuditAnnotations: response.AuditAnnotations,
+		Warnings:         response.Warnings,
+	}
+}
@@ -0,0 +1,30 @@
+package admission
+
+import (
+	"encoding/json"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type secretSyncReviewer struct{}
+
+func newSecretSyncReviewer() *secretSyncReviewer {
+	return &secretSyncReviewer{}
+}
+
+func (r *secretSyncReviewer) validateTypeSpecificData(fldPath *field.Path, typeSpecificData *runtime.RawExtension) field.ErrorList {
+	var secretSyncData apitypes.SecretSyncSpecificData
+	err := json.Unmarshal(typeSpecificData.Raw, &secretSyncData)
+	if err != nil {
+		return field.ErrorList{invalidData(fldPath, err)}
+	}
+
+	if err = secretSyncData.Validate(); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, omittedValue{}, "invalid secretsync specific data: "+err.Error())}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestReviewSecretSyncSpecificData(t *testing.T) {
+	tests := []struct {
+		name             string
+		typeSpecificData string
+		expectedDenied   bool
+	}{
+		{
+			name:             "allow secret",
+			typeSpecificData: `{"secrets":[{"name":"registry","namespaces":["app1","app2"]}]}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "allow renamed keys and type",
+			typeSpecificData: `{"secrets":[{"name":"tls","targetName":"app-tls","namespaces":["app"],"type":"kubernetes.io/tls","keys":[{"key":"cert","targetKey":"tls.crt"},{"key":"key","targetKey":"tls.key"}]}]}`,
+			expectedDenied:   false,
+		},
+		{
+			name:             "reject missing secrets",
+			typeSpecificData: `{}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject secret without namespaces",
+			typeSpecificData: `{"secrets":[{"name":"registry"}]}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject duplicate copy",
+			typeSpecificData: `{"secrets":[{"name":"registry","namespaces":["app"]},{"name":"other","targetName":"registry","namespaces":["app"]}]}`,
+			expectedDenied:   true,
+		},
+		{
+			name:             "reject duplicate target key",
+			typeSpecificData: `{"secrets":[{"name":"registry","namespaces":["app"],"keys":[{"key":"a","targetKey":"c"},{"key":"c"}]}]}`,
+			expectedDenied:   true,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			typeSpecificData := &runtime.RawExtension{Raw: []byte(test.typeSpecificData)}
+			errs := newSecretSyncReviewer().validateTypeSpecificData(field.NewPath("typeSpecificData"), typeSpecificData)
+			assert.Equal(t, len(errs) > 0, test.expectedDenied, "denied")
+		})
+	}
+}
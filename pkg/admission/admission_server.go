@@ -1,29 +1,63 @@
 package admission
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
 	"io/ioutil"
 	"net/http"
 	"time"
 
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
+	"github.com/gardener/potter-controller/pkg/metrics"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	"github.com/go-logr/logr"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/api/admission/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reviewTimeout is the deadline of a clusterbom review, including the calls of deployer plugins. The clusterbom webhook
+// has no timeoutSeconds, so that the API server waits the default of 10 seconds before it rejects the request.
+const reviewTimeout = 8 * time.Second
+
+// resources reviewed by the admission webhook, as used in the admission metrics
+const (
+	resourceClusterBom = "clusterbom"
+	resourceSecret     = "secret"
 )
 
 type AdmissionHookConfig struct { // nolint
 	UncachedClient      synchronize.UncachedClient
 	HubControllerClient synchronize.UncachedClient
+	AppRepoClient       client.Client
 	ConfigTypes         []string
 	ExtendedLogEnabled  bool
-	LandscaperEnabled   bool
-	RunsLocally         bool
-	TokenIssuer         string
-	TokenReviewEnabled  bool
+	// HelmSchemaValidation is one of HelmSchemaValidationDisabled, HelmSchemaValidationDeny, HelmSchemaValidationWarn
+	HelmSchemaValidation string
+	LandscaperEnabled    bool
+	RunsLocally          bool
+	TokenIssuer          string
+	TokenReviewEnabled   bool
+	// TokenAudiences are the audiences of which a webhook token must contain one; DefaultTokenAudience if empty
+	TokenAudiences []string
+	// TokenJWKSURL and TokenJWKSFile are the sources of the keys which sign the webhook tokens. If both are empty, the
+	// keys are fetched from the JWKS endpoint in the OIDC discovery document of the TokenIssuer.
+	TokenJWKSURL   string
+	TokenJWKSFile  string
+	TokenClockSkew time.Duration
+	// WarningsRequiringAck are the kinds of warnings which deny a clusterbom unless they are acknowledged
+	WarningsRequiringAck []string
+	// GetCertificate returns the serving certificate if the webhook server manages its own certificates. Otherwise the
+	// server runs without TLS, behind an ingress which terminates TLS.
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	// PluginClients are the clients of the deployer plugins, which are shared with the deployment controller
+	PluginClients *deployerplugin.ClientCache
 }
 
 func StartAdmissionServer(config *AdmissionHookConfig) {
@@ -39,32 +73,34 @@ func StartAdmissionServer(config *AdmissionHookConfig) {
 	secretHandler = buildHandlerChain(secretHandler, config, log)
 	router.Handle("/checkSecret", secretHandler).Methods("POST")
 
+	addr := ":8085"
 	if config.RunsLocally {
 		// execution in local mode
-		server := &http.Server{
-			Handler:      router,
-			Addr:         "0.0.0.0:8000",
-			WriteTimeout: 15 * time.Second,
-			ReadTimeout:  15 * time.Second,
-		}
-
-		err := server.ListenAndServeTLS("/home/vagrant/tmp/certs/cfssl/server.pem", "/home/vagrant/tmp/certs/cfssl/server-key.pem")
-		if err != nil {
-			log.Error(err, "http server of admission webhook failed")
-		}
-
-		return
+		addr = "0.0.0.0:8000"
 	}
 
-	// execution in productive mode
 	server := &http.Server{
 		Handler:      router,
-		Addr:         ":8085",
+		Addr:         addr,
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
 	}
 
-	err := server.ListenAndServe()
+	var err error
+	if config.GetCertificate != nil {
+		// the certificate is loaded for every connection, so that a renewed certificate is used without a restart
+		server.TLSConfig = &tls.Config{
+			GetCertificate: config.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+		err = server.ListenAndServeTLS("", "")
+	} else if config.RunsLocally {
+		err = server.ListenAndServeTLS("/home/vagrant/tmp/certs/cfssl/server.pem", "/home/vagrant/tmp/certs/cfssl/server-key.pem")
+	} else {
+		// execution in productive mode
+		err = server.ListenAndServe()
+	}
+
 	if err != nil {
 		log.Error(err, "http server of clusterbom admission webhook failed")
 	}
@@ -77,17 +113,19 @@ func buildHandlerChain(handler http.Handler, config *AdmissionHookConfig, log lo
 	}
 
 	log.V(util.LogLevelWarning).Info("webhook token review is enabled")
-	return newTokenReviewer(handler, config.TokenIssuer, log)
+	return newTokenReviewer(handler, config, log)
 }
 
 type secretHandler struct {
 	hubControllerClient synchronize.UncachedClient
+	uncachedClient      synchronize.UncachedClient
 	log                 logr.Logger
 }
 
 func newSecretHandler(config *AdmissionHookConfig, log logr.Logger) http.Handler {
 	return &secretHandler{
 		hubControllerClient: config.HubControllerClient,
+		uncachedClient:      config.UncachedClient,
 		log:                 log,
 	}
 }
@@ -103,8 +141,7 @@ func (h *secretHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	requestReview := v1beta1.AdmissionReview{}
-	err = json.Unmarshal(body, &requestReview)
+	requestReview, apiVersion, err := decodeAdmissionReview(body)
 	if err != nil {
 		message := "unmarshaling request admission review failed for secret"
 		h.log.Error(err, message)
@@ -112,14 +149,18 @@ func (h *secretHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	_, span := startReviewSpan(req, "Admission.reviewSecret", requestReview)
 	reviewer := secretReviewer{
 		log:                 h.log,
-		requestReview:       &requestReview,
+		requestReview:       requestReview,
 		hubControllerClient: h.hubControllerClient,
+		uncachedClient:      h.uncachedClient,
 	}
 	responseReview := reviewer.review()
+	observeDecision(resourceSecret, responseReview)
+	endReviewSpan(span, responseReview)
 
-	responseBody, err := json.Marshal(responseReview)
+	responseBody, err := encodeAdmissionReview(responseReview, apiVersion)
 	if err != nil {
 		message := "marshaling response admission review failed for secret"
 		h.log.Error(err, message)
@@ -140,15 +181,22 @@ type clusterBomHandler struct {
 	configTypes        []string
 	extendedLogEnabled bool
 	landscaperEnabled  bool
+	// helmSchemaValidator is shared by all reviews, so that they share its chart cache
+	helmSchemaValidator  *helmSchemaValidator
+	pluginClients        *deployerplugin.ClientCache
+	warningsRequiringAck []string
 }
 
 func newClusterBomHandler(config *AdmissionHookConfig, log logr.Logger) http.Handler {
 	return &clusterBomHandler{
-		cl:                 config.UncachedClient,
-		log:                log,
-		configTypes:        config.ConfigTypes,
-		extendedLogEnabled: config.ExtendedLogEnabled,
-		landscaperEnabled:  config.LandscaperEnabled,
+		cl:                   config.UncachedClient,
+		log:                  log,
+		configTypes:          config.ConfigTypes,
+		extendedLogEnabled:   config.ExtendedLogEnabled,
+		landscaperEnabled:    config.LandscaperEnabled,
+		helmSchemaValidator:  newHelmSchemaValidator(config),
+		pluginClients:        config.PluginClients,
+		warningsRequiringAck: config.WarningsRequiringAck,
 	}
 }
 
@@ -173,8 +221,7 @@ func (h *clusterBomHandler) ServeHTTP(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	requestReview := v1beta1.AdmissionReview{}
-	err = json.Unmarshal(body, &requestReview)
+	requestReview, apiVersion, err := decodeAdmissionReview(body)
 	if err != nil {
 		message := "unmarshaling request admission review failed for cluster bom"
 		h.log.Error(err, message)
@@ -182,16 +229,27 @@ func (h *clusterBomHandler) ServeHTTP(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	ctx, span := startReviewSpan(req, "Admission.reviewClusterBom", requestReview)
+	ctx, cancel := context.WithTimeout(ctx, reviewTimeout)
+	defer cancel()
+
 	reviewer := clusterBomReviewer{
-		log:               h.log,
-		requestReview:     &requestReview,
-		reader:            h.cl,
-		configTypes:       h.configTypes,
-		landscaperEnabled: h.landscaperEnabled,
+		ctx:                  ctx,
+		log:                  h.log,
+		requestReview:        requestReview,
+		reader:               h.cl,
+		configTypes:          h.configTypes,
+		landscaperEnabled:    h.landscaperEnabled,
+		helmSchemaValidator:  h.helmSchemaValidator,
+		pluginClients:        h.pluginClients,
+		warningsRequiringAck: h.warningsRequiringAck,
+		spanContext:          trace.SpanContextFromContext(ctx),
 	}
 	responseReview := reviewer.review()
+	observeDecision(resourceClusterBom, responseReview)
+	endReviewSpan(span, responseReview)
 
-	responseBody, err := json.Marshal(responseReview)
+	responseBody, err := encodeAdmissionReview(responseReview, apiVersion)
 	if err != nil {
 		message := "marshaling response admission review failed for cluster bom"
 		h.log.Error(err, message)
@@ -205,3 +263,39 @@ func (h *clusterBomHandler) ServeHTTP(w http.ResponseWriter, req *http.Request)
 		h.log.Error(err, "writing http response failed for cluster bom")
 	}
 }
+
+// startReviewSpan starts the span of a review. The API server does not send a trace context, so that the review
+// span is the root span of the trace of a clusterbom change.
+func startReviewSpan(req *http.Request, spanName string, requestReview *v1beta1.AdmissionReview) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{}
+	if requestReview.Request != nil {
+		attributes = append(attributes, attribute.String(tracing.AttributeAdmissionUID, string(requestReview.Request.UID)),
+			attribute.String(tracing.AttributeOperation, string(requestReview.Request.Operation)))
+	}
+
+	return tracing.Start(req.Context(), spanName, attributes...)
+}
+
+func endReviewSpan(span trace.Span, responseReview *v1beta1.AdmissionReview) {
+	if responseReview.Response != nil {
+		span.SetAttributes(attribute.Bool(tracing.AttributeAllowed, responseReview.Response.Allowed))
+	}
+
+	span.End()
+}
+
+// observeDecision records the decision of a review in the admission metrics. The reason of a denial is one of the
+// few status reasons used by the reviewers, so that the number of time series stays small.
+func observeDecision(resource string, responseReview *v1beta1.AdmissionReview) {
+	response := responseReview.Response
+	if response == nil {
+		return
+	}
+
+	reason := ""
+	if !response.Allowed && response.Result != nil {
+		reason = string(response.Result.Reason)
+	}
+
+	metrics.ObserveAdmissionDecision(resource, response.Allowed, reason)
+}
@@ -0,0 +1,192 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arschles/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+const testUID = types.UID("0f5e3a4b-0000-4000-8000-000000000001")
+
+var admissionReviewVersions = []string{
+	admissionv1.SchemeGroupVersion.String(),
+	v1beta1.SchemeGroupVersion.String(),
+}
+
+func TestClusterBomHandler(t *testing.T) {
+	invalidClusterBom := clusterBom01(t)
+	invalidClusterBom.Spec.SecretRef = ""
+
+	autoDeleteClusterBom := clusterBom01(t)
+	autoDeleteClusterBom.Spec.AutoDelete = &hubv1.AutoDelete{ClusterBomAge: 60}
+
+	tests := []struct {
+		name             string
+		clusterBom       hubv1.ClusterBom
+		expectedAllowed  bool
+		expectedPatch    bool
+		expectedWarnings int
+		expectedCauses   int
+	}{
+		{
+			name:            "valid clusterbom",
+			clusterBom:      clusterBom01(t),
+			expectedAllowed: true,
+			expectedPatch:   true,
+		},
+		{
+			name:           "invalid clusterbom",
+			clusterBom:     invalidClusterBom,
+			expectedCauses: 1,
+		},
+		{
+			name:             "clusterbom with warning",
+			clusterBom:       autoDeleteClusterBom,
+			expectedAllowed:  true,
+			expectedPatch:    true,
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, apiVersion := range admissionReviewVersions {
+		for i := range tests {
+			test := &tests[i]
+			t.Run(apiVersion+" "+test.name, func(t *testing.T) {
+				handler := newClusterBomHandler(&AdmissionHookConfig{
+					UncachedClient: &readerMock{},
+					ConfigTypes:    []string{util.ConfigTypeHelm},
+				}, ctrl.Log.WithName("ClusterBom Admission Hook Test"))
+
+				body := buildRequestBody(t, apiVersion, admissionv1.Create, buildRawExtension(t, test.clusterBom), runtime.RawExtension{})
+				responseReview := postAdmissionReview(t, handler, body)
+
+				response := responseReview.Response
+				assert.Equal(t, responseReview.APIVersion, apiVersion, "apiVersion")
+				assert.Equal(t, responseReview.Kind, "AdmissionReview", "kind")
+				assert.Equal(t, response.UID, testUID, "uid")
+				assert.Equal(t, response.Allowed, test.expectedAllowed, "allowed")
+				assert.Equal(t, response.PatchType != nil, test.expectedPatch, "patch type")
+				assert.Equal(t, len(response.Patch) > 0, test.expectedPatch, "patch")
+				assert.Equal(t, len(response.Warnings), test.expectedWarnings, "number of warnings")
+				if test.expectedPatch {
+					assert.Equal(t, *response.PatchType, admissionv1.PatchTypeJSONPatch, "patch type")
+				}
+				if !test.expectedAllowed {
+					assert.Equal(t, len(response.Result.Details.Causes), test.expectedCauses, "number of causes")
+				}
+			})
+		}
+	}
+}
+
+func TestSecretHandler(t *testing.T) {
+	sourceSecret := newTestSecretSyncSource("registry", true, "user")
+
+	tests := []struct {
+		name            string
+		referencedName  string
+		expectedAllowed bool
+	}{
+		{
+			name:            "deletion of referenced source",
+			referencedName:  "registry",
+			expectedAllowed: false,
+		},
+		{
+			name:            "deletion of unreferenced source",
+			referencedName:  "other",
+			expectedAllowed: true,
+		},
+	}
+
+	for _, apiVersion := range admissionReviewVersions {
+		for i := range tests {
+			test := &tests[i]
+			t.Run(apiVersion+" "+test.name, func(t *testing.T) {
+				handler := newSecretHandler(&AdmissionHookConfig{
+					UncachedClient: &clusterBomListMock{clusterBoms: []hubv1.ClusterBom{newTestSecretSyncClusterBom(t, test.referencedName)}},
+				}, ctrl.Log.WithName("Secret Admission Hook Test"))
+
+				body := buildRequestBody(t, apiVersion, admissionv1.Delete, runtime.RawExtension{}, buildRawExtension(t, sourceSecret))
+				responseReview := postAdmissionReview(t, handler, body)
+
+				assert.Equal(t, responseReview.APIVersion, apiVersion, "apiVersion")
+				assert.Equal(t, responseReview.Kind, "AdmissionReview", "kind")
+				assert.Equal(t, responseReview.Response.UID, testUID, "uid")
+				assert.Equal(t, responseReview.Response.Allowed, test.expectedAllowed, "allowed")
+			})
+		}
+	}
+}
+
+func TestHandlerRejectsUnsupportedVersion(t *testing.T) {
+	handler := newClusterBomHandler(&AdmissionHookConfig{UncachedClient: &readerMock{}},
+		ctrl.Log.WithName("ClusterBom Admission Hook Test"))
+
+	body := []byte(`{"apiVersion":"admission.k8s.io/v2","kind":"AdmissionReview","request":{"uid":"test"}}`)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/checkClusterBom", bytes.NewReader(body)))
+	assert.Equal(t, recorder.Code, http.StatusBadRequest, "status code")
+}
+
+// buildRequestBody returns an AdmissionReview as the API server sends it in the given version
+func buildRequestBody(t *testing.T, apiVersion string, operation admissionv1.Operation, object, oldObject runtime.RawExtension) []byte {
+	typeMeta := metav1.TypeMeta{
+		APIVersion: apiVersion,
+		Kind:       "AdmissionReview",
+	}
+
+	var requestReview interface{}
+	if apiVersion == admissionv1.SchemeGroupVersion.String() {
+		requestReview = &admissionv1.AdmissionReview{
+			TypeMeta: typeMeta,
+			Request: &admissionv1.AdmissionRequest{
+				UID:       testUID,
+				Operation: operation,
+				Object:    object,
+				OldObject: oldObject,
+			},
+		}
+	} else {
+		requestReview = &v1beta1.AdmissionReview{
+			TypeMeta: typeMeta,
+			Request: &v1beta1.AdmissionRequest{
+				UID:       testUID,
+				Operation: v1beta1.Operation(operation),
+				Object:    object,
+				OldObject: oldObject,
+			},
+		}
+	}
+
+	body, err := json.Marshal(requestReview)
+	assert.NoErr(t, err)
+	return body
+}
+
+// postAdmissionReview posts the request body to the handler and decodes the response. Both versions of the
+// AdmissionReview have the same fields, so that the response is decoded into the v1 type.
+func postAdmissionReview(t *testing.T, handler http.Handler, body []byte) *admissionv1.AdmissionReview {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	assert.Equal(t, recorder.Code, http.StatusOK, "status code")
+
+	responseReview := &admissionv1.AdmissionReview{}
+	err := json.Unmarshal(recorder.Body.Bytes(), responseReview)
+	assert.NoErr(t, err)
+	assert.NotNil(t, responseReview.Response, "response")
+	return responseReview
+}
@@ -0,0 +1,349 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+// Kinds of changes which are allowed, but risky. The admission webhook returns a warning for them, or denies them if
+// they require an acknowledgement which the clusterbom does not contain.
+const (
+	// WarningMajorChartVersionBump is an update of a helm chart to a new major version
+	WarningMajorChartVersionBump = "MajorChartVersionBump"
+	// WarningReinstall is a change of the installation name or namespace of an app, which uninstalls and reinstalls it
+	WarningReinstall = "Reinstall"
+	// WarningRemovalFromUnreachableCluster is the removal of an app whose target cluster is not reachable
+	WarningRemovalFromUnreachableCluster = "RemovalFromUnreachableCluster"
+	// WarningAutoDelete is setting the automatic deletion of a clusterbom
+	WarningAutoDelete = "AutoDelete"
+)
+
+var warningKinds = []string{
+	WarningMajorChartVersionBump,
+	WarningReinstall,
+	WarningRemovalFromUnreachableCluster,
+	WarningAutoDelete,
+}
+
+// ParseWarningKinds parses a comma separated list of warning kinds, e.g. the warnings which require an acknowledgement.
+func ParseWarningKinds(list string) ([]string, error) {
+	kinds := []string{}
+
+	for _, kind := range strings.Split(list, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+
+		if !util.ContainsString(kind, warningKinds) {
+			return nil, errors.Errorf("unknown warning kind %s, supported are %s", kind, strings.Join(warningKinds, ", "))
+		}
+
+		kinds = append(kinds, kind)
+	}
+
+	return kinds, nil
+}
+
+// riskyChange is a change of a clusterbom for which the admission webhook returns a warning
+type riskyChange struct {
+	kind    string
+	fldPath *field.Path
+	message string
+	// subject identifies the change within its kind, e.g. the application config and the chart version to which it is
+	// updated, so that an acknowledgement applies to this change only, and not to later changes of the same kind
+	subject string
+}
+
+func (c *riskyChange) String() string {
+	return c.fldPath.String() + ": " + c.message
+}
+
+// acknowledgement returns the entry of the acknowledgement annotation which acknowledges the change
+func (c *riskyChange) acknowledgement() string {
+	return c.kind + ":" + c.subject
+}
+
+// reviewRiskyChanges returns a warning for every risky change. A risky change whose kind requires an acknowledgement is
+// denied if it is not listed in the acknowledgement annotation of the clusterbom. An acknowledgement names the kind and
+// the subject of the change, so that an annotation which is kept, e.g. in the manifest of a GitOps repository, does
+// not acknowledge later changes of the same kind.
+func (r *clusterBomReviewer) reviewRiskyChanges(report *report, clusterBom, oldClusterBom *hubv1.ClusterBom) {
+	acknowledgements := getAcknowledgedWarnings(clusterBom)
+
+	allErrs := field.ErrorList{}
+	for _, change := range findRiskyChanges(clusterBom, oldClusterBom) {
+		if util.ContainsString(change.kind, r.warningsRequiringAck) && !util.ContainsString(change.acknowledgement(), acknowledgements) {
+			allErrs = append(allErrs, field.Forbidden(change.fldPath, fmt.Sprintf("%s; acknowledge it with annotation %s: %s",
+				change.message, hubv1.AnnotationKeyAcknowledgedWarnings, change.acknowledgement())))
+			continue
+		}
+
+		r.log.V(util.LogLevelDebug).Info("risky change of clusterbom", "kind", change.kind, "change", change.String())
+		report.warn(change.String())
+	}
+
+	report.addErrors(allErrs)
+}
+
+// getAcknowledgedWarnings returns the acknowledgements listed in the acknowledgement annotation of the clusterbom, each
+// of the form <kind>:<subject>
+func getAcknowledgedWarnings(clusterBom *hubv1.ClusterBom) []string {
+	value, ok := util.GetAnnotation(clusterBom, hubv1.AnnotationKeyAcknowledgedWarnings)
+	if !ok {
+		return nil
+	}
+
+	acknowledgements := []string{}
+	for _, acknowledgement := range strings.Split(value, ",") {
+		acknowledgements = append(acknowledgements, strings.TrimSpace(acknowledgement))
+	}
+
+	return acknowledgements
+}
+
+// findRiskyChanges compares the clusterbom with its old version; oldClusterBom is nil for a create. Type specific data
+// which cannot be unmarshalled are ignored, because they are already reported by the validation.
+func findRiskyChanges(clusterBom, oldClusterBom *hubv1.ClusterBom) []riskyChange {
+	changes := []riskyChange{}
+
+	if autoDeleteChange := findAutoDeleteChange(clusterBom, oldClusterBom); autoDeleteChange != nil {
+		changes = append(changes, *autoDeleteChange)
+	}
+
+	if oldClusterBom == nil {
+		return changes
+	}
+
+	oldApplConfigs := map[string]*hubv1.ApplicationConfig{}
+	for i := range oldClusterBom.Spec.ApplicationConfigs {
+		oldApplConfigs[oldClusterBom.Spec.ApplicationConfigs[i].ID] = &oldClusterBom.Spec.ApplicationConfigs[i]
+	}
+
+	removedApplConfigs := make(map[string]*hubv1.ApplicationConfig, len(oldApplConfigs))
+	for id, oldApplConfig := range oldApplConfigs {
+		removedApplConfigs[id] = oldApplConfig
+	}
+
+	for i := range clusterBom.Spec.ApplicationConfigs {
+		delete(removedApplConfigs, clusterBom.Spec.ApplicationConfigs[i].ID)
+	}
+
+	for i := range clusterBom.Spec.ApplicationConfigs {
+		applConfig := &clusterBom.Spec.ApplicationConfigs[i]
+		fldPath := field.NewPath("spec", "applicationConfigs").Index(i).Child("typeSpecificData")
+
+		oldApplConfig, ok := oldApplConfigs[applConfig.ID]
+		if !ok {
+			changes = append(changes, findReinstallByReplacement(fldPath, applConfig, removedApplConfigs)...)
+		} else if applConfig.ConfigType == oldApplConfig.ConfigType {
+			changes = append(changes, findRiskyUpdates(fldPath, applConfig, oldApplConfig)...)
+		}
+	}
+
+	if isClusterUnreachable(oldClusterBom) {
+		removedIDs := []string{}
+		for id := range removedApplConfigs {
+			removedIDs = append(removedIDs, id)
+		}
+		sort.Strings(removedIDs)
+
+		for _, id := range removedIDs {
+			changes = append(changes, riskyChange{
+				kind:    WarningRemovalFromUnreachableCluster,
+				fldPath: field.NewPath("spec", "applicationConfigs"),
+				message: fmt.Sprintf("application config %s is removed, but the target cluster is not reachable, "+
+					"so that its resources cannot be deleted until the cluster is reachable again", id),
+				subject: id,
+			})
+		}
+	}
+
+	return changes
+}
+
+func findAutoDeleteChange(clusterBom, oldClusterBom *hubv1.ClusterBom) *riskyChange {
+	autoDelete := clusterBom.Spec.AutoDelete
+	if autoDelete == nil || autoDelete.ClusterBomAge <= 0 {
+		return nil
+	}
+
+	if oldClusterBom != nil && oldClusterBom.Spec.AutoDelete != nil &&
+		oldClusterBom.Spec.AutoDelete.ClusterBomAge == autoDelete.ClusterBomAge {
+		return nil
+	}
+
+	return &riskyChange{
+		kind:    WarningAutoDelete,
+		fldPath: field.NewPath("spec", "autoDelete", "clusterBomAge"),
+		message: fmt.Sprintf("the clusterbom is deleted automatically if its target cluster does not exist "+
+			"%d minutes after its creation", autoDelete.ClusterBomAge),
+		subject: strconv.FormatInt(autoDelete.ClusterBomAge, 10),
+	}
+}
+
+// findRiskyUpdates compares an application config with its old version of the same config type
+func findRiskyUpdates(fldPath *field.Path, applConfig, oldApplConfig *hubv1.ApplicationConfig) []riskyChange {
+	changes := []riskyChange{}
+
+	switch applConfig.ConfigType {
+	case util.ConfigTypeHelm:
+		helmData, oldHelmData := &apitypes.HelmSpecificData{}, &apitypes.HelmSpecificData{}
+		if !unmarshalBoth(applConfig, oldApplConfig, helmData, oldHelmData) {
+			return changes
+		}
+
+		if helmData.CatalogAccess == nil || oldHelmData.CatalogAccess == nil {
+			return changes
+		}
+
+		version, err := semver.NewVersion(helmData.CatalogAccess.ChartVersion)
+		if err != nil {
+			return changes
+		}
+
+		oldVersion, err := semver.NewVersion(oldHelmData.CatalogAccess.ChartVersion)
+		if err != nil {
+			return changes
+		}
+
+		if version.Major() > oldVersion.Major() {
+			changes = append(changes, riskyChange{
+				kind:    WarningMajorChartVersionBump,
+				fldPath: fldPath.Child("catalogAccess", "chartVersion"),
+				message: fmt.Sprintf("chart %s of application config %s is updated to a new major version, from %s to %s",
+					helmData.CatalogAccess.ChartName, applConfig.ID, oldVersion, version),
+				subject: applConfig.ID + "@" + helmData.CatalogAccess.ChartVersion,
+			})
+		}
+
+	case util.ConfigTypePackage:
+		packageData, oldPackageData := &apitypes.PackageSpecificData{}, &apitypes.PackageSpecificData{}
+		if !unmarshalBoth(applConfig, oldApplConfig, packageData, oldPackageData) {
+			return changes
+		}
+
+		if packageData.Namespace != oldPackageData.Namespace {
+			changes = append(changes, riskyChange{
+				kind:    WarningReinstall,
+				fldPath: fldPath.Child("namespace"),
+				message: fmt.Sprintf("the namespace of application config %s is changed from %q to %q, "+
+					"so that the package is uninstalled and installed again", applConfig.ID, oldPackageData.Namespace,
+					packageData.Namespace),
+				subject: applConfig.ID + "@" + packageData.Namespace,
+			})
+		}
+	}
+
+	return changes
+}
+
+// findReinstallByReplacement detects a helm application config which replaces a removed one with the same chart, but
+// with a different installation name or namespace. The removed release is uninstalled, and the new one installed.
+func findReinstallByReplacement(fldPath *field.Path, applConfig *hubv1.ApplicationConfig,
+	removedApplConfigs map[string]*hubv1.ApplicationConfig) []riskyChange {
+	changes := []riskyChange{}
+
+	if applConfig.ConfigType != util.ConfigTypeHelm {
+		return changes
+	}
+
+	helmData := &apitypes.HelmSpecificData{}
+	if err := json.Unmarshal(applConfig.TypeSpecificData.Raw, helmData); err != nil {
+		return changes
+	}
+
+	removedIDs := []string{}
+	for id := range removedApplConfigs {
+		removedIDs = append(removedIDs, id)
+	}
+	sort.Strings(removedIDs)
+
+	for _, id := range removedIDs {
+		removedApplConfig := removedApplConfigs[id]
+		if removedApplConfig.ConfigType != util.ConfigTypeHelm {
+			continue
+		}
+
+		removedHelmData := &apitypes.HelmSpecificData{}
+		if err := json.Unmarshal(removedApplConfig.TypeSpecificData.Raw, removedHelmData); err != nil {
+			continue
+		}
+
+		if !isSameChart(helmData, removedHelmData) {
+			continue
+		}
+
+		if helmData.InstallName != removedHelmData.InstallName {
+			changes = append(changes, riskyChange{
+				kind:    WarningReinstall,
+				fldPath: fldPath.Child("installName"),
+				message: fmt.Sprintf("application config %s replaces application config %s with a different installName, "+
+					"so that release %s is uninstalled and installed again as %s", applConfig.ID, id,
+					removedHelmData.InstallName, helmData.InstallName),
+				subject: applConfig.ID + "@" + helmData.InstallName,
+			})
+		}
+
+		if helmData.Namespace != removedHelmData.Namespace {
+			changes = append(changes, riskyChange{
+				kind:    WarningReinstall,
+				fldPath: fldPath.Child("namespace"),
+				message: fmt.Sprintf("application config %s replaces application config %s with a different namespace, "+
+					"so that the release is uninstalled from namespace %s and installed again in namespace %s",
+					applConfig.ID, id, removedHelmData.Namespace, helmData.Namespace),
+				subject: applConfig.ID + "@" + helmData.Namespace,
+			})
+		}
+	}
+
+	return changes
+}
+
+func isSameChart(helmData, otherHelmData *apitypes.HelmSpecificData) bool {
+	if helmData.CatalogAccess != nil && otherHelmData.CatalogAccess != nil {
+		return helmData.CatalogAccess.Repo == otherHelmData.CatalogAccess.Repo &&
+			helmData.CatalogAccess.ChartName == otherHelmData.CatalogAccess.ChartName
+	}
+
+	if helmData.TarballAccess != nil && otherHelmData.TarballAccess != nil {
+		return helmData.TarballAccess.URL == otherHelmData.TarballAccess.URL
+	}
+
+	return false
+}
+
+func unmarshalBoth(applConfig, oldApplConfig *hubv1.ApplicationConfig, data, oldData interface{}) bool {
+	if err := json.Unmarshal(applConfig.TypeSpecificData.Raw, data); err != nil {
+		return false
+	}
+
+	if err := json.Unmarshal(oldApplConfig.TypeSpecificData.Raw, oldData); err != nil {
+		return false
+	}
+
+	return true
+}
+
+func isClusterUnreachable(clusterBom *hubv1.ClusterBom) bool {
+	for i := range clusterBom.Status.Conditions {
+		condition := &clusterBom.Status.Conditions[i]
+		if condition.Type == hubv1.ClusterReachable {
+			return condition.Status == corev1.ConditionFalse
+		}
+	}
+
+	return false
+}
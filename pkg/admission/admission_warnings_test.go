@@ -0,0 +1,270 @@
+package admission
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+func TestFindRiskyChanges(t *testing.T) {
+	tests := []struct {
+		name           string
+		modify         func(clusterBom *hubv1.ClusterBom)
+		modifyOld      func(oldClusterBom *hubv1.ClusterBom)
+		create         bool
+		expectedKinds  []string
+		expectedFields []string
+	}{
+		{
+			name:   "no changes",
+			modify: func(clusterBom *hubv1.ClusterBom) {},
+		},
+		{
+			name: "minor chart version bump",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, helmData("testinstallname01", "testnamespace01", "1.3.0"))
+			},
+		},
+		{
+			name: "major chart version bump",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, helmData("testinstallname01", "testnamespace01", "2.0.0"))
+			},
+			expectedKinds:  []string{WarningMajorChartVersionBump},
+			expectedFields: []string{"spec.applicationConfigs[0].typeSpecificData.catalogAccess.chartVersion"},
+		},
+		{
+			name: "replacement with different install name and namespace",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.ApplicationConfigs[0].ID = "id03"
+				clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, helmData("testinstallname03", "testnamespace03", "1.2.3"))
+			},
+			expectedKinds: []string{WarningReinstall, WarningReinstall},
+			expectedFields: []string{
+				"spec.applicationConfigs[0].typeSpecificData.installName",
+				"spec.applicationConfigs[0].typeSpecificData.namespace",
+			},
+		},
+		{
+			name: "replacement with different chart",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				data := helmData("testinstallname03", "testnamespace01", "1.2.3")
+				data.CatalogAccess.ChartName = "testchartname03"
+				clusterBom.Spec.ApplicationConfigs[0].ID = "id03"
+				clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, data)
+			},
+		},
+		{
+			name: "removal from reachable cluster",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.ApplicationConfigs = clusterBom.Spec.ApplicationConfigs[:1]
+			},
+		},
+		{
+			name: "removal from unreachable cluster",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.ApplicationConfigs = clusterBom.Spec.ApplicationConfigs[:1]
+			},
+			modifyOld: func(oldClusterBom *hubv1.ClusterBom) {
+				setClusterReachable(oldClusterBom, corev1.ConditionFalse)
+			},
+			expectedKinds:  []string{WarningRemovalFromUnreachableCluster},
+			expectedFields: []string{"spec.applicationConfigs"},
+		},
+		{
+			name: "update on unreachable cluster without removal",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, helmData("testinstallname01", "testnamespace01", "1.3.0"))
+			},
+			modifyOld: func(oldClusterBom *hubv1.ClusterBom) {
+				setClusterReachable(oldClusterBom, corev1.ConditionFalse)
+			},
+		},
+		{
+			name: "auto delete set",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.AutoDelete = &hubv1.AutoDelete{ClusterBomAge: 60}
+			},
+			expectedKinds:  []string{WarningAutoDelete},
+			expectedFields: []string{"spec.autoDelete.clusterBomAge"},
+		},
+		{
+			name: "auto delete unchanged",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.AutoDelete = &hubv1.AutoDelete{ClusterBomAge: 60}
+			},
+			modifyOld: func(oldClusterBom *hubv1.ClusterBom) {
+				oldClusterBom.Spec.AutoDelete = &hubv1.AutoDelete{ClusterBomAge: 60}
+			},
+		},
+		{
+			name: "auto delete on create",
+			modify: func(clusterBom *hubv1.ClusterBom) {
+				clusterBom.Spec.AutoDelete = &hubv1.AutoDelete{ClusterBomAge: 60}
+			},
+			create:         true,
+			expectedKinds:  []string{WarningAutoDelete},
+			expectedFields: []string{"spec.autoDelete.clusterBomAge"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterBom := clusterBom01(t)
+			tt.modify(&clusterBom)
+
+			var oldClusterBom *hubv1.ClusterBom
+			if !tt.create {
+				old := clusterBom01(t)
+				if tt.modifyOld != nil {
+					tt.modifyOld(&old)
+				}
+				oldClusterBom = &old
+			}
+
+			changes := findRiskyChanges(&clusterBom, oldClusterBom)
+
+			kinds := []string{}
+			fields := []string{}
+			for i := range changes {
+				kinds = append(kinds, changes[i].kind)
+				fields = append(fields, changes[i].fldPath.String())
+			}
+			assert.Equal(t, strings.Join(kinds, ","), strings.Join(tt.expectedKinds, ","), "kinds")
+			assert.Equal(t, strings.Join(fields, ","), strings.Join(tt.expectedFields, ","), "fields")
+		})
+	}
+}
+
+func TestReviewRiskyChanges(t *testing.T) {
+	tests := []struct {
+		name                 string
+		warningsRequiringAck []string
+		acknowledged         string
+		expectedAllowed      bool
+		expectedWarnings     int
+	}{
+		{
+			name:             "warning only",
+			expectedAllowed:  true,
+			expectedWarnings: 1,
+		},
+		{
+			name:                 "denied without acknowledgement",
+			warningsRequiringAck: []string{WarningMajorChartVersionBump},
+			expectedAllowed:      false,
+		},
+		{
+			name:                 "denied with acknowledgement of another kind",
+			warningsRequiringAck: []string{WarningMajorChartVersionBump},
+			acknowledged:         WarningAutoDelete,
+			expectedAllowed:      false,
+		},
+		{
+			name:                 "denied with acknowledgement of the kind only",
+			warningsRequiringAck: []string{WarningMajorChartVersionBump},
+			acknowledged:         WarningMajorChartVersionBump,
+			expectedAllowed:      false,
+		},
+		{
+			name:                 "denied with acknowledgement of another version",
+			warningsRequiringAck: []string{WarningMajorChartVersionBump},
+			acknowledged:         WarningMajorChartVersionBump + ":id01@3.0.0",
+			expectedAllowed:      false,
+		},
+		{
+			name:                 "allowed with acknowledgement",
+			warningsRequiringAck: []string{WarningMajorChartVersionBump},
+			acknowledged:         WarningAutoDelete + ":60, " + WarningMajorChartVersionBump + ":id01@2.0.0",
+			expectedAllowed:      true,
+			expectedWarnings:     1,
+		},
+		{
+			name:                 "other kind requires acknowledgement",
+			warningsRequiringAck: []string{WarningReinstall},
+			expectedAllowed:      true,
+			expectedWarnings:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldClusterBom := clusterBom01(t)
+			clusterBom := clusterBom01(t)
+			clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, helmData("testinstallname01", "testnamespace01", "2.0.0"))
+			if tt.acknowledged != "" {
+				util.AddAnnotation(&clusterBom, hubv1.AnnotationKeyAcknowledgedWarnings, tt.acknowledged)
+			}
+
+			reviewer := buildReviewerForClusterBomUpdate(t, &clusterBom, &oldClusterBom)
+			reviewer.warningsRequiringAck = tt.warningsRequiringAck
+			responseReview := reviewer.review()
+
+			assert.Equal(t, responseReview.Response.Allowed, tt.expectedAllowed, "allowed")
+			assert.Equal(t, len(responseReview.Response.Warnings), tt.expectedWarnings, "number of warnings")
+			if !tt.expectedAllowed {
+				result := responseReview.Response.Result
+				assert.Equal(t, result.Reason, metav1.StatusReasonForbidden, "reason")
+				assert.True(t, strings.Contains(result.Message, hubv1.AnnotationKeyAcknowledgedWarnings+": "+WarningMajorChartVersionBump+":id01@2.0.0"),
+					"message "+result.Message)
+				return
+			}
+
+			if tt.acknowledged != "" {
+				// the acknowledgement is kept, because it only applies to the acknowledged change
+				patches := []patch{}
+				err := json.Unmarshal(responseReview.Response.Patch, &patches)
+				assert.NoErr(t, err)
+
+				var annotations map[string]interface{}
+				for i := range patches {
+					if patches[i].Path == "/metadata/annotations" {
+						annotations = patches[i].Value.(map[string]interface{})
+					}
+				}
+				assert.NotNil(t, annotations, "annotations patch")
+				assert.Equal(t, annotations[hubv1.AnnotationKeyAcknowledgedWarnings], tt.acknowledged, "acknowledged warnings annotation")
+			}
+		})
+	}
+}
+
+func TestParseWarningKinds(t *testing.T) {
+	kinds, err := ParseWarningKinds("")
+	assert.NoErr(t, err)
+	assert.Equal(t, len(kinds), 0, "number of kinds")
+
+	kinds, err = ParseWarningKinds(WarningReinstall + ", " + WarningAutoDelete)
+	assert.NoErr(t, err)
+	assert.Equal(t, strings.Join(kinds, ","), WarningReinstall+","+WarningAutoDelete, "kinds")
+
+	_, err = ParseWarningKinds("Unknown")
+	assert.NotNil(t, err, "error")
+}
+
+func helmData(installName, namespace, chartVersion string) *apitypes.HelmSpecificData {
+	return &apitypes.HelmSpecificData{
+		InstallName: installName,
+		Namespace:   namespace,
+		CatalogAccess: &apitypes.CatalogAccess{
+			Repo:         "testrepo01",
+			ChartName:    "testchartname01",
+			ChartVersion: chartVersion,
+		},
+	}
+}
+
+func setClusterReachable(clusterBom *hubv1.ClusterBom, status corev1.ConditionStatus) {
+	clusterBom.Status.Conditions = append(clusterBom.Status.Conditions, hubv1.ClusterBomCondition{
+		Type:   hubv1.ClusterReachable,
+		Status: status,
+	})
+}
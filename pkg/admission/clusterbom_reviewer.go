@@ -4,28 +4,54 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gardener/landscaper/apis/core/v1alpha1"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/api/admission/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/util/jsonpath"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/auditlog"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 )
 
+// builtInConfigTypes are the config types which are handled by the controller itself, and not by a deployer plugin
+var builtInConfigTypes = []string{
+	util.ConfigTypeHelm,
+	util.ConfigTypeKapp,
+	util.ConfigTypeManifest,
+	util.ConfigTypeKustomize,
+	util.ConfigTypePackage,
+	util.ConfigTypeSecretSync,
+}
+
 type clusterBomReviewer struct {
-	log               logr.Logger
-	requestReview     *v1beta1.AdmissionReview
-	reader            synchronize.UncachedClient
-	configTypes       []string
-	landscaperEnabled bool
+	// ctx is the context of the request, with a deadline for the whole review, see reviewTimeout
+	ctx                   context.Context
+	log                   logr.Logger
+	requestReview         *v1beta1.AdmissionReview
+	reader                synchronize.UncachedClient
+	configTypes           []string
+	landscaperEnabled     bool
+	deployerRegistrations map[string]*hubv1.DeployerRegistration
+	helmSchemaValidator   *helmSchemaValidator
+	pluginClients         *deployerplugin.ClientCache
+	// warningsRequiringAck are the kinds of risky changes which are denied unless the clusterbom acknowledges them
+	warningsRequiringAck []string
+	// spanContext is the span of the review, whose trace context is written into the annotations of the clusterbom
+	spanContext trace.SpanContext
 }
 
 func (r *clusterBomReviewer) review() *v1beta1.AdmissionReview {
@@ -39,29 +65,70 @@ func (r *clusterBomReviewer) review() *v1beta1.AdmissionReview {
 	r.log = r.log.WithValues(util.LogKeyClusterBomName, util.GetKey(clusterBom))
 	r.log.Info("Reviewing ClusterBom")
 
-	r.checkName(report, clusterBom)
+	// all checks run before the decision, so that all violations are returned together
+	writer := newSecretWriter(r.reader)
+	report.addErrors(r.validateClusterBom(clusterBom, oldClusterBom, oldApplConfigs))
+	r.reviewHelmValues(report, clusterBom, oldApplConfigs)
+	r.reviewRiskyChanges(report, clusterBom, oldClusterBom)
+	r.checkAdmissionPolicies(report, clusterBom, oldClusterBom)
+	r.reviewSecretValues(report, writer, clusterBom, oldApplConfigs)
 	if report.denied() {
+		r.log.V(util.LogLevelWarning).Info("rejected clusterbom", "message", report.message, "errors", len(report.errs))
 		return report.getResponseReview()
 	}
 
-	r.checkSecretRef(report, clusterBom, oldClusterBom)
-	if report.denied() {
-		return report.getResponseReview()
-	}
+	r.mutateClusterBom(report, writer, clusterBom, oldClusterBom)
 
-	r.checkLandscaperManaged(report, clusterBom, oldClusterBom)
-	if report.denied() {
-		return report.getResponseReview()
-	}
+	return report.getResponseReview()
+}
 
-	r.checkApplicationConfigs(report, clusterBom, oldApplConfigs)
-	if report.denied() {
-		return report.getResponseReview()
+// validateClusterBom returns all violations of the clusterbom, so that they are returned together. Apart from reading
+// deployer registrations and deploy items, it has no side effects.
+func (r *clusterBomReviewer) validateClusterBom(clusterBom, oldClusterBom *hubv1.ClusterBom,
+	oldApplConfigs map[string]*hubv1.ApplicationConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, r.validateName(clusterBom)...)
+	allErrs = append(allErrs, r.validateSecretRef(clusterBom, oldClusterBom)...)
+	allErrs = append(allErrs, r.validateLandscaperManaged(clusterBom, oldClusterBom)...)
+	allErrs = append(allErrs, r.validateApplicationConfigs(clusterBom, oldApplConfigs)...)
+	return allErrs
+}
+
+// reviewHelmValues validates the values of the helm application configs against the values schema of their charts.
+// Application configs with invalid type specific data are skipped, because their chart cannot be determined. The charts
+// are loaded concurrently within the deadline of the review.
+func (r *clusterBomReviewer) reviewHelmValues(report *report, clusterBom *hubv1.ClusterBom,
+	oldApplConfigs map[string]*hubv1.ApplicationConfig) {
+	if r.helmSchemaValidator == nil {
+		return
 	}
 
-	r.mutateClusterBom(report, clusterBom, oldApplConfigs)
+	helmReviewer := newHelmReviewer(r.helmSchemaValidator)
+	checks := []*helmValuesCheck{}
 
-	return report.getResponseReview()
+	for i := range clusterBom.Spec.ApplicationConfigs {
+		applConfig := &clusterBom.Spec.ApplicationConfigs[i]
+		if applConfig.ConfigType != util.ConfigTypeHelm || applConfig.TypeSpecificData.Raw == nil {
+			continue
+		}
+
+		oldApplConfig := oldApplConfigs[applConfig.ID]
+		var oldTypeSpecificData *runtime.RawExtension
+		if oldApplConfig != nil && oldApplConfig.ConfigType == util.ConfigTypeHelm {
+			oldTypeSpecificData = &(oldApplConfig.TypeSpecificData)
+		}
+
+		fldPath := field.NewPath("spec", "applicationConfigs").Index(i)
+		if errs := helmReviewer.validateTypeSpecificData(fldPath.Child("typeSpecificData"), &applConfig.TypeSpecificData, oldTypeSpecificData); len(errs) > 0 {
+			continue
+		}
+
+		if check := helmReviewer.newValuesCheck(r.log, report, fldPath, applConfig, oldApplConfig); check != nil {
+			checks = append(checks, check)
+		}
+	}
+
+	helmReviewer.reviewValues(r.ctx, r.log, report, checks)
 }
 
 func (r *clusterBomReviewer) getObjectsToBeChecked(report *report) (*hubv1.ClusterBom, *hubv1.ClusterBom, map[string]*hubv1.ApplicationConfig) {
@@ -93,228 +160,268 @@ func (r *clusterBomReviewer) getObjectsToBeChecked(report *report) (*hubv1.Clust
 	return clusterBom, oldClusterBom, oldApplConfigs
 }
 
-func (r *clusterBomReviewer) checkLandscaperManaged(report *report, clusterBom, oldClusterBom *hubv1.ClusterBom) {
+// validateLandscaperManaged checks the landscaper-managed annotation, and that only landscaper managed clusterboms use
+// export and import parameters.
+func (r *clusterBomReviewer) validateLandscaperManaged(clusterBom, oldClusterBom *hubv1.ClusterBom) field.ErrorList {
+	allErrs := field.ErrorList{}
+	annotationPath := field.NewPath("metadata", "annotations").Key(hubv1.AnnotationKeyLandscaperManaged)
+
 	isLandscaperManaged := util.HasAnnotation(clusterBom, hubv1.AnnotationKeyLandscaperManaged, hubv1.AnnotationValueLandscaperManaged)
 
 	if isLandscaperManaged && !r.landscaperEnabled {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because landscaper managed clusterboms are not supported")
-		report.deny("landscaper managed clusterboms are not supported")
-		return
+		allErrs = append(allErrs, field.Forbidden(annotationPath, "landscaper managed clusterboms are not supported"))
 	}
 
-	if r.requestReview.Request.Operation == v1beta1.Update {
+	if oldClusterBom != nil {
 		wasLandscaperManaged := util.HasAnnotation(oldClusterBom, hubv1.AnnotationKeyLandscaperManaged, hubv1.AnnotationValueLandscaperManaged)
 		if (isLandscaperManaged && !wasLandscaperManaged) ||
 			(!isLandscaperManaged && wasLandscaperManaged && r.landscaperEnabled) {
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because a switch between landscaper managed and not landscaper managed is forbidden")
-			report.deny("a switch between landscaper managed and not landscaper managed is forbidden")
-			return
+			allErrs = append(allErrs, field.Forbidden(annotationPath,
+				"a switch between landscaper managed and not landscaper managed is forbidden"))
 		}
 	}
 
-	if !isLandscaperManaged && r.hasExportOrImportParameters(clusterBom) {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because export/import is only supported for landscaper managed clusterboms")
-		report.deny("export/import is only supported for landscaper managed clusterboms, i.e. clusterboms with annotation potter.gardener.cloud/landscaper-managed: true")
-		return
+	if !isLandscaperManaged {
+		allErrs = append(allErrs, r.validateExportAndImportParameters(clusterBom)...)
 	}
+
+	return allErrs
 }
 
-func (r *clusterBomReviewer) hasExportOrImportParameters(clusterBom *hubv1.ClusterBom) bool {
+// validateExportAndImportParameters rejects export and import parameters, which are only supported for landscaper
+// managed clusterboms.
+func (r *clusterBomReviewer) validateExportAndImportParameters(clusterBom *hubv1.ClusterBom) field.ErrorList {
+	allErrs := field.ErrorList{}
+	detail := "export/import is only supported for landscaper managed clusterboms, i.e. clusterboms with annotation " +
+		hubv1.AnnotationKeyLandscaperManaged + ": " + hubv1.AnnotationValueLandscaperManaged
+
 	for i := range clusterBom.Spec.ApplicationConfigs {
 		appConfig := &clusterBom.Spec.ApplicationConfigs[i]
+		appConfigPath := field.NewPath("spec", "applicationConfigs").Index(i)
+
+		if len(appConfig.ExportParameters.Parameters) > 0 {
+			allErrs = append(allErrs, field.Forbidden(appConfigPath.Child("exportParameters", "parameters"), detail))
+		}
 
-		if len(appConfig.ExportParameters.Parameters) > 0 ||
-			len(appConfig.ImportParameters) > 0 ||
-			len(appConfig.InternalImportParameters.Parameters) > 0 {
-			return true
+		if len(appConfig.ImportParameters) > 0 {
+			allErrs = append(allErrs, field.Forbidden(appConfigPath.Child("importParameters"), detail))
+		}
+
+		if len(appConfig.InternalImportParameters.Parameters) > 0 {
+			allErrs = append(allErrs, field.Forbidden(appConfigPath.Child("internalImportParameters", "parameters"), detail))
 		}
 	}
 
-	return false
+	return allErrs
 }
 
-func (r *clusterBomReviewer) checkName(report *report, clusterBom *hubv1.ClusterBom) {
+func (r *clusterBomReviewer) validateName(clusterBom *hubv1.ClusterBom) field.ErrorList {
+	namePath := field.NewPath("metadata", "name")
+	name := clusterBom.ObjectMeta.Name
+
 	message := "The name of a clusterbom must consist of lower case alphanumeric characters or '-' or '.', " +
 		"must start and end with an alphanumeric character, " +
 		"and must not be longer than 63 characters (e.g. 'testclusterbom.01')."
 
-	if len(clusterBom.ObjectMeta.Name) > 63 || len(clusterBom.ObjectMeta.Name) < 1 {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because its name is longer than 63 or shorter than one character")
-		report.deny(message)
-		return
+	if len(name) > 63 || len(name) < 1 {
+		return field.ErrorList{field.Invalid(namePath, name, message)}
 	}
 
 	pattern := `^[0-9a-z\.\-]+$`
-	matched, err := regexp.MatchString(pattern, clusterBom.ObjectMeta.Name)
+	matched, err := regexp.MatchString(pattern, name)
 	if err != nil {
-		r.log.Error(err, "error when matching name against pattern", "pattern", pattern)
-		report.deny(message + " - " + err.Error())
-		return
+		return field.ErrorList{field.InternalError(namePath, fmt.Errorf("error when matching name against pattern %s: %w", pattern, err))}
 	}
 
 	if !matched {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because its name does not fulfill the pattern", "pattern", pattern)
-		report.deny(message)
-		return
+		return field.ErrorList{field.Invalid(namePath, name, message)}
 	}
 
-	if strings.Contains(clusterBom.ObjectMeta.Name, util.DoubleSeparator) {
-		text := "rejected clusterbom, because its name contains more than one consecutive minus sign"
-		r.log.V(util.LogLevelWarning).Info(text)
-		report.deny(text)
-		return
+	if strings.Contains(name, util.DoubleSeparator) {
+		return field.ErrorList{field.Invalid(namePath, name, "must not contain more than one consecutive minus sign")}
 	}
+
+	return nil
 }
 
-func (r *clusterBomReviewer) checkSecretRef(report *report, clusterBom, oldClusterBom *hubv1.ClusterBom) {
-	r.log.Info("Reviewing SecretRef")
+func (r *clusterBomReviewer) validateSecretRef(clusterBom, oldClusterBom *hubv1.ClusterBom) field.ErrorList {
+	allErrs := field.ErrorList{}
+	secretRefPath := field.NewPath("spec", "secretRef")
 
 	if clusterBom.Spec.SecretRef == "" {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.secretRef is empty")
-		report.deny("spec.secretRef is empty")
-		return
+		allErrs = append(allErrs, field.Required(secretRefPath, ""))
+		return allErrs
 	}
 
-	if r.requestReview.Request.Operation == v1beta1.Update && clusterBom.Spec.SecretRef != oldClusterBom.Spec.SecretRef {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.secretRef must not be changed")
-		report.deny("spec.secretRef must not be changed")
-		return
+	if oldClusterBom != nil && clusterBom.Spec.SecretRef != oldClusterBom.Spec.SecretRef {
+		allErrs = append(allErrs, field.Forbidden(secretRefPath, "must not be changed"))
 	}
 
 	if containsSpiffTemplate(clusterBom.Spec.SecretRef) {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.secretRef cannot be templated")
-		report.deny("spec.secretRef cannot be templated")
-		return
+		allErrs = append(allErrs, field.Invalid(secretRefPath, clusterBom.Spec.SecretRef, "cannot be templated"))
 	}
+
+	return allErrs
 }
 
-func (r *clusterBomReviewer) checkApplicationConfigs(report *report, clusterBom *hubv1.ClusterBom, oldApplConfigs map[string]*hubv1.ApplicationConfig) {
+func (r *clusterBomReviewer) validateApplicationConfigs(clusterBom *hubv1.ClusterBom, oldApplConfigs map[string]*hubv1.ApplicationConfig) field.ErrorList {
 	r.log.Info("Reviewing Application Configs")
 
-	r.checkApplicationConfigIDs(report, clusterBom)
-	if report.denied() {
-		return
-	}
+	allErrs := r.validateApplicationConfigIDs(clusterBom)
 
 	for i := range clusterBom.Spec.ApplicationConfigs {
 		applConfig := &clusterBom.Spec.ApplicationConfigs[i]
-		oldApplConfig, oldApplConfigExists := oldApplConfigs[applConfig.ID]
+		oldApplConfig := oldApplConfigs[applConfig.ID]
+		fldPath := field.NewPath("spec", "applicationConfigs").Index(i)
 
-		r.checkConfigType(report, applConfig, oldApplConfig, oldApplConfigExists)
-		if report.denied() {
-			return
-		}
-
-		r.checkTypeSpecificData(report, clusterBom, applConfig, oldApplConfig, oldApplConfigExists)
-		if report.denied() {
-			return
+		configTypeErrs := r.validateConfigType(fldPath.Child("configType"), applConfig, oldApplConfig)
+		allErrs = append(allErrs, configTypeErrs...)
+		if len(configTypeErrs) == 0 {
+			allErrs = append(allErrs, r.validateTypeSpecificData(fldPath.Child("typeSpecificData"), clusterBom, applConfig, oldApplConfig)...)
 		}
 
-		r.checkConflictWithExistingDeployment(report, clusterBom, applConfig, oldApplConfigExists)
-		if report.denied() {
-			return
-		}
+		allErrs = append(allErrs, validateSecretValues(fldPath.Child("secretValues"), applConfig, oldApplConfig)...)
+		allErrs = append(allErrs, validateNamedSecretValues(fldPath.Child("namedSecretValues"), applConfig, oldApplConfig)...)
+		allErrs = append(allErrs, r.validateResourceReadyRequirements(fldPath.Child("readyRequirements", "resources"), applConfig)...)
 
-		r.checkResourceReadyRequirements(report, applConfig)
-		if report.denied() {
-			return
+		if applConfig.ID != "" {
+			allErrs = append(allErrs, r.checkConflictWithExistingDeployItem(fldPath.Child("id"), clusterBom, applConfig, oldApplConfig != nil)...)
 		}
 	}
+
+	return allErrs
 }
 
-func (r *clusterBomReviewer) checkApplicationConfigIDs(report *report, clusterBom *hubv1.ClusterBom) {
+func (r *clusterBomReviewer) validateApplicationConfigIDs(clusterBom *hubv1.ClusterBom) field.ErrorList {
+	allErrs := field.ErrorList{}
 	containedIdsMap := make(map[string]bool)
 
 	for i := range clusterBom.Spec.ApplicationConfigs {
 		applConfig := clusterBom.Spec.ApplicationConfigs[i]
+		idPath := field.NewPath("spec", "applicationConfigs").Index(i).Child("id")
+
 		if applConfig.ID == "" {
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.applicationConfigs.id is empty")
-			report.deny("spec.applicationConfigs.id is empty")
-			return
+			allErrs = append(allErrs, field.Required(idPath, ""))
+			continue
 		}
 
 		if containsSpiffTemplate(applConfig.ID) {
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.applicationConfigs.id cannot be templated")
-			report.deny("spec.applicationConfigs.id")
-			return
+			allErrs = append(allErrs, field.Invalid(idPath, applConfig.ID, "cannot be templated"))
 		}
 
 		if _, ok := containedIdsMap[applConfig.ID]; ok {
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because applicationConfigs.id is a duplicate", "applConfig.ID", applConfig.ID)
-			report.deny("applConfig.ID is a duplicate " + applConfig.ID)
-			return
+			allErrs = append(allErrs, field.Duplicate(idPath, applConfig.ID))
 		}
 
 		containedIdsMap[applConfig.ID] = true
 	}
+
+	return allErrs
 }
 
-func (r *clusterBomReviewer) checkConfigType(report *report, applConfig, oldApplConfig *hubv1.ApplicationConfig, oldApplConfigExists bool) {
+func (r *clusterBomReviewer) validateConfigType(fldPath *field.Path, applConfig, oldApplConfig *hubv1.ApplicationConfig) field.ErrorList {
 	if applConfig.ConfigType == "" {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.applicationConfigs.configType is empty", "applConfig.ID", applConfig.ID)
-		report.deny("spec.applicationConfigs.configType is empty")
-		return
+		return field.ErrorList{field.Required(fldPath, "")}
 	}
 
-	if !util.ContainsString(applConfig.ConfigType, r.configTypes) {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.applicationConfigs.configType is not supported", "applConfig.ID", applConfig.ID)
-		report.deny("spec.applicationConfigs.configType " + applConfig.ConfigType + " is not supported")
-		return
+	registration, err := r.getDeployerRegistration(applConfig.ConfigType)
+	if err != nil {
+		r.log.Error(err, "could not read deployer registrations", "applConfig.ID", applConfig.ID)
+		return field.ErrorList{field.InternalError(fldPath, fmt.Errorf("could not read deployer registrations: %w", err))}
+	}
+
+	if !util.ContainsString(applConfig.ConfigType, r.configTypes) && registration == nil {
+		return field.ErrorList{field.NotSupported(fldPath, applConfig.ConfigType, r.configTypes)}
 	}
 
 	// check that the configType was not updated
-	if r.isUpdate() && oldApplConfigExists && applConfig.ConfigType != oldApplConfig.ConfigType {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.applicationConfigs.configType must not be updated", "applConfig.ID", applConfig.ID)
-		report.deny("spec.applicationConfigs.configType must not be updated")
-		return
+	if oldApplConfig != nil && applConfig.ConfigType != oldApplConfig.ConfigType {
+		return field.ErrorList{field.Forbidden(fldPath, "must not be updated")}
 	}
+
+	return nil
 }
 
-func (r *clusterBomReviewer) checkTypeSpecificData(report *report, clusterBom *hubv1.ClusterBom, applConfig, oldApplConfig *hubv1.ApplicationConfig, oldApplConfigExists bool) {
+// validateTypeSpecificData returns the violations of the typeSpecificData. The config type must be valid.
+func (r *clusterBomReviewer) validateTypeSpecificData(fldPath *field.Path, clusterBom *hubv1.ClusterBom,
+	applConfig, oldApplConfig *hubv1.ApplicationConfig) field.ErrorList {
 	if applConfig.TypeSpecificData.Raw == nil {
-		r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because spec.applicationConfigs.typeSpecificData is empty", "applConfig.ID", applConfig.ID)
-		report.deny("spec.applicationConfigs.typeSpecificData is empty")
-		return
+		return field.ErrorList{field.Required(fldPath, "")}
 	}
 
 	var oldTypeSpecificData *runtime.RawExtension
-	if oldApplConfigExists {
+	if oldApplConfig != nil {
 		oldTypeSpecificData = &(oldApplConfig.TypeSpecificData)
 	}
 
 	switch applConfig.ConfigType {
 	case util.ConfigTypeHelm:
-		newHelmReviewer().reviewTypeSpecificData(report, &applConfig.TypeSpecificData, oldTypeSpecificData)
-		if report.denied() {
-			return
-		}
+		return newHelmReviewer(nil).validateTypeSpecificData(fldPath, &applConfig.TypeSpecificData, oldTypeSpecificData)
 
 	case util.ConfigTypeKapp:
-		newKappReviewer().reviewTypeSpecificData(r.log, report, &applConfig.TypeSpecificData, clusterBom.Spec.SecretRef)
-		if report.denied() {
-			return
+		return newKappReviewer().validateTypeSpecificData(fldPath, &applConfig.TypeSpecificData, clusterBom.Spec.SecretRef)
+
+	case util.ConfigTypeManifest:
+		return newManifestReviewer().validateTypeSpecificData(fldPath, &applConfig.TypeSpecificData)
+
+	case util.ConfigTypeKustomize:
+		return newKustomizeReviewer().validateTypeSpecificData(fldPath, &applConfig.TypeSpecificData)
+
+	case util.ConfigTypePackage:
+		return newPackageReviewer().validateTypeSpecificData(fldPath, &applConfig.TypeSpecificData)
+
+	case util.ConfigTypeSecretSync:
+		return newSecretSyncReviewer().validateTypeSpecificData(fldPath, &applConfig.TypeSpecificData)
+
+	default:
+		// the registration was already read when the config type was checked
+		registration, _ := r.getDeployerRegistration(applConfig.ConfigType)
+		if registration != nil {
+			return newPluginReviewer(registration, r.pluginClients).validateTypeSpecificData(r.ctx, fldPath, &applConfig.TypeSpecificData)
 		}
+		return nil
 	}
 }
 
-func (r *clusterBomReviewer) checkConflictWithExistingDeployment(report *report, clusterBom *hubv1.ClusterBom, applConfig *hubv1.ApplicationConfig, oldApplConfigExists bool) {
-	r.checkConflictWithExistingDeployItem(report, clusterBom, applConfig, oldApplConfigExists)
+// getDeployerRegistration returns the registration of a deployer plugin for a config type, or nil if there is none.
+// Built-in config types cannot be registered. The registrations are read once per review.
+func (r *clusterBomReviewer) getDeployerRegistration(configType string) (*hubv1.DeployerRegistration, error) {
+	if util.ContainsString(configType, builtInConfigTypes) {
+		return nil, nil
+	}
+
+	if r.deployerRegistrations == nil {
+		registrationList := &hubv1.DeployerRegistrationList{}
+		if err := r.reader.ListUncached(r.ctx, registrationList); err != nil {
+			return nil, err
+		}
+
+		r.deployerRegistrations = make(map[string]*hubv1.DeployerRegistration)
+		for i := range registrationList.Items {
+			registration := &registrationList.Items[i]
+			r.deployerRegistrations[registration.Spec.ConfigType] = registration
+		}
+	}
+
+	return r.deployerRegistrations[configType], nil
 }
 
-func (r *clusterBomReviewer) checkConflictWithExistingDeployItem(report *report, clusterBom *hubv1.ClusterBom, applConfig *hubv1.ApplicationConfig, oldApplConfigExists bool) {
+func (r *clusterBomReviewer) checkConflictWithExistingDeployItem(fldPath *field.Path, clusterBom *hubv1.ClusterBom,
+	applConfig *hubv1.ApplicationConfig, oldApplConfigExists bool) field.ErrorList {
 	if oldApplConfigExists {
-		return
+		return nil
 	}
 
 	exists, err := r.existsDeployItem(clusterBom, applConfig.ID)
 	if err != nil {
 		r.log.Error(err, "cannot find out whether there still exists a deployitem for a new applicationConfig", "applConfig.ID", applConfig.ID)
-		report.deny("cannot find out whether there still exists a deployitem for a new applicationConfig: " + err.Error())
-		return
+		return field.ErrorList{field.InternalError(fldPath,
+			fmt.Errorf("cannot find out whether there still exists a deployitem for a new applicationConfig: %w", err))}
 	} else if exists {
-		report.deny("there still exists a deployitem for a new applicationConfig")
-		return
+		return field.ErrorList{field.Forbidden(fldPath, "there still exists a deployitem for a new applicationConfig")}
 	}
+
+	return nil
 }
 
 func (r *clusterBomReviewer) isCreate() bool {
@@ -325,14 +432,105 @@ func (r *clusterBomReviewer) isUpdate() bool {
 	return r.requestReview.Request.Operation == v1beta1.Update
 }
 
-func (r *clusterBomReviewer) mutateClusterBom(report *report, clusterBom *hubv1.ClusterBom, oldApplConfigs map[string]*hubv1.ApplicationConfig) {
+// mutateClusterBom adds the patches of the clusterbom. The patches for secret values were already added by
+// reviewSecretValues, and the writer contains the secrets to which they refer.
+func (r *clusterBomReviewer) mutateClusterBom(report *report, writer *secretWriter, clusterBom, oldClusterBom *hubv1.ClusterBom) {
 	r.log.Info("Mutate ClusterBom")
 
 	r.patchClusterBomLabels(report, clusterBom)
 	r.patchFinalizer(report)
-	r.patchSecretValues(report, clusterBom, oldApplConfigs)
-	r.patchNamedSecretValues(report, clusterBom, oldApplConfigs)
 	r.patchMissingFields(report)
+	r.patchAnnotations(report, clusterBom, oldClusterBom)
+
+	r.persistSecrets(report, writer)
+}
+
+// persistSecrets creates the secrets for secret values if the clusterbom is allowed. For a dry-run request, the patches
+// refer to secrets which are not created, as nothing of a dry-run request is persisted.
+func (r *clusterBomReviewer) persistSecrets(report *report, writer *secretWriter) {
+	if report.denied() || len(writer.secrets) == 0 {
+		return
+	}
+
+	if r.isDryRun() {
+		r.log.V(util.LogLevelDebug).Info("Dry run: skip creation of secrets", "secrets", len(writer.secrets))
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), util.LoggerKey{}, r.log)
+	if err := writer.persist(ctx); err != nil {
+		report.fail("error when creating secrets for secret values: " + err.Error())
+	}
+}
+
+func (r *clusterBomReviewer) isDryRun() bool {
+	return r.requestReview.Request.DryRun != nil && *r.requestReview.Request.DryRun
+}
+
+// Adds the trace context of the review and the requester to the annotations, if the spec of the clusterbom is created
+// or changed. The controllers continue the trace of the change from there, and record the requester in the audit log.
+// Other updates, e.g. of annotations, keep the trace context and the requester of the last change of the spec.
+func (r *clusterBomReviewer) patchAnnotations(report *report, clusterBom, oldClusterBom *hubv1.ClusterBom) {
+	annotations := make(map[string]string, len(clusterBom.GetAnnotations())+1)
+	for key, value := range clusterBom.GetAnnotations() {
+		annotations[key] = value
+	}
+
+	if r.isUpdate() && reflect.DeepEqual(clusterBom.Spec, oldClusterBom.Spec) {
+		// the requester cannot be set by the user
+		if oldRequester, ok := util.GetAnnotation(oldClusterBom, util.AnnotationKeyRequester); ok {
+			annotations[util.AnnotationKeyRequester] = oldRequester
+		} else {
+			delete(annotations, util.AnnotationKeyRequester)
+		}
+	} else {
+		requester, err := r.getRequester()
+		if err != nil {
+			r.log.Error(err, "Could not marshal requester")
+			delete(annotations, util.AnnotationKeyRequester)
+		} else {
+			annotations[util.AnnotationKeyRequester] = requester
+		}
+
+		if r.spanContext.IsValid() {
+			ctx := trace.ContextWithSpanContext(context.Background(), r.spanContext)
+			annotations = tracing.InjectIntoMap(ctx, annotations)
+		}
+	}
+
+	if reflect.DeepEqual(annotations, clusterBom.GetAnnotations()) ||
+		len(annotations) == 0 && len(clusterBom.GetAnnotations()) == 0 {
+		return
+	}
+
+	report.appendPatch("add", "/metadata/annotations", annotations)
+}
+
+// getRequester returns the user of the admission request as value of the requester annotation
+func (r *clusterBomReviewer) getRequester() (string, error) {
+	userInfo := &r.requestReview.Request.UserInfo
+
+	requester := auditlog.Requester{
+		Username:  userInfo.Username,
+		UID:       userInfo.UID,
+		Groups:    userInfo.Groups,
+		Operation: string(r.requestReview.Request.Operation),
+		Time:      time.Now().UTC().Truncate(time.Second),
+	}
+
+	if len(userInfo.Extra) > 0 {
+		requester.Extra = make(map[string][]string, len(userInfo.Extra))
+		for key, value := range userInfo.Extra {
+			requester.Extra[key] = value
+		}
+	}
+
+	data, err := json.Marshal(&requester)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
 }
 
 // Adds secretRef to labels
@@ -356,67 +554,66 @@ func (r *clusterBomReviewer) patchFinalizer(report *report) {
 	}
 }
 
-func (r *clusterBomReviewer) patchNamedSecretValues(report *report, clusterBom *hubv1.ClusterBom, oldApplConfigs map[string]*hubv1.ApplicationConfig) {
-	r.log.Info("Patching Named Secret Values")
-
-	patches := []patch{}
-
-	for i := range clusterBom.Spec.ApplicationConfigs {
-		appConfig := &clusterBom.Spec.ApplicationConfigs[i]
-		oldAppConfig := oldApplConfigs[appConfig.ID]
-
-		log := r.log.WithValues("app-id", appConfig.ID, "app-index", i)
-		ctx := context.Background()
-		ctx = context.WithValue(ctx, util.LoggerKey{}, log)
-
-		var err error
-
-		secretKeeper := &NamedSecretKeeper{
-			client:       r.reader,
-			dryRun:       (r.requestReview.Request.DryRun != nil) && *r.requestReview.Request.DryRun,
-			clusterBom:   clusterBom,
-			appIndex:     i,
-			oldAppConfig: oldAppConfig,
-			appConfig:    appConfig,
-		}
-
-		patches, err = secretKeeper.handleAppConfig(ctx, patches)
-		if err != nil {
-			report.deny("error when handling named secret values: " + err.Error())
-			return
-		}
-	}
-
-	report.appendPatches(patches...)
-}
-
-func (r *clusterBomReviewer) patchSecretValues(report *report, clusterBom *hubv1.ClusterBom, oldApplConfigs map[string]*hubv1.ApplicationConfig) {
-	r.log.Info("Patching Secret Values")
+// reviewSecretValues moves the secret values and named secret values into secrets, which are added to the writer, and
+// adds the patches which replace them by references to the secrets. Application configs whose secret values are
+// invalid are skipped, because their violations are already reported by the validation. Secrets which cannot be read
+// are reported at the field of the secret values, together with the other violations.
+func (r *clusterBomReviewer) reviewSecretValues(report *report, writer *secretWriter, clusterBom *hubv1.ClusterBom,
+	oldApplConfigs map[string]*hubv1.ApplicationConfig) {
+	r.log.Info("Reviewing Secret Values")
 
 	secretKeeper := &SecretKeeper{
-		client: r.reader,
-		dryRun: (r.requestReview.Request.DryRun != nil) && *r.requestReview.Request.DryRun,
+		client:     r.reader,
+		writer:     writer,
+		requestUID: r.requestReview.Request.UID,
 	}
 
 	patches := []patch{}
+	namedPatches := []patch{}
+	allErrs := field.ErrorList{}
 
 	for i := range clusterBom.Spec.ApplicationConfigs {
 		appConfig := &clusterBom.Spec.ApplicationConfigs[i]
 		oldAppConfig := oldApplConfigs[appConfig.ID]
+		fldPath := field.NewPath("spec", "applicationConfigs").Index(i)
 
 		log := r.log.WithValues("app-id", appConfig.ID, "app-index", i)
-		ctx := context.Background()
-		ctx = context.WithValue(ctx, util.LoggerKey{}, log)
+		ctx := context.WithValue(context.Background(), util.LoggerKey{}, log)
+
+		secretValuesPath := fldPath.Child("secretValues")
+		if len(validateSecretValues(secretValuesPath, appConfig, oldAppConfig)) == 0 {
+			appPatches, err := secretKeeper.handleAppConfig(ctx, clusterBom, i, appConfig, oldAppConfig, []patch{})
+			if err != nil {
+				allErrs = append(allErrs, field.InternalError(secretValuesPath, err))
+			} else {
+				patches = append(patches, appPatches...)
+			}
+		}
 
-		var err error
-		patches, err = secretKeeper.handleAppConfig(ctx, clusterBom, i, appConfig, oldAppConfig, patches)
-		if err != nil {
-			report.deny("error when handling secret values: " + err.Error())
-			return
+		namedSecretValuesPath := fldPath.Child("namedSecretValues")
+		if len(validateNamedSecretValues(namedSecretValuesPath, appConfig, oldAppConfig)) == 0 {
+			namedSecretKeeper := &NamedSecretKeeper{
+				client:       r.reader,
+				writer:       writer,
+				clusterBom:   clusterBom,
+				appIndex:     i,
+				oldAppConfig: oldAppConfig,
+				appConfig:    appConfig,
+				requestUID:   r.requestReview.Request.UID,
+			}
+
+			appPatches, errs := namedSecretKeeper.handleAppConfig(ctx, namedSecretValuesPath, []patch{})
+			if len(errs) > 0 {
+				allErrs = append(allErrs, errs...)
+			} else {
+				namedPatches = append(namedPatches, appPatches...)
+			}
 		}
 	}
 
+	report.addErrors(allErrs)
 	report.appendPatches(patches...)
+	report.appendPatches(namedPatches...)
 }
 
 func (r *clusterBomReviewer) patchMissingFields(report *report) {
@@ -497,10 +694,8 @@ func (r *clusterBomReviewer) patchMissingFields(report *report) {
 }
 
 func (r *clusterBomReviewer) existsDeployItem(clusterBom *hubv1.ClusterBom, appConfigID string) (exists bool, err error) {
-	ctx := context.Background()
-
 	var deployItemList = v1alpha1.DeployItemList{}
-	err = r.reader.ListUncached(ctx, &deployItemList,
+	err = r.reader.ListUncached(r.ctx, &deployItemList,
 		client.InNamespace(clusterBom.Namespace),
 		client.MatchingLabels{
 			hubv1.LabelClusterBomName:      clusterBom.Name,
@@ -515,65 +710,46 @@ func (r *clusterBomReviewer) existsDeployItem(clusterBom *hubv1.ClusterBom, appC
 	return exists, nil
 }
 
-func (r *clusterBomReviewer) checkResourceReadyRequirements(report *report, applConfig *hubv1.ApplicationConfig) {
+func (r *clusterBomReviewer) validateResourceReadyRequirements(fldPath *field.Path, applConfig *hubv1.ApplicationConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+
 	for i, readyRequirement := range applConfig.ReadyRequirements.Resources {
+		resourcePath := fldPath.Index(i)
+
 		if readyRequirement.Name == "" {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].name is empty", applConfig.ID, i)
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
+			allErrs = append(allErrs, field.Required(resourcePath.Child("name"), ""))
 		}
 
 		if readyRequirement.Namespace == "" {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].namespace is empty", applConfig.ID, i)
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
+			allErrs = append(allErrs, field.Required(resourcePath.Child("namespace"), ""))
 		}
 
 		if readyRequirement.APIVersion == "" {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].apiVersion is empty", applConfig.ID, i)
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
+			allErrs = append(allErrs, field.Required(resourcePath.Child("apiVersion"), ""))
 		}
 
 		if readyRequirement.Resource == "" {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].resource is empty", applConfig.ID, i)
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
+			allErrs = append(allErrs, field.Required(resourcePath.Child("resource"), ""))
 		}
 
 		if readyRequirement.FieldPath == "" {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].fieldPath is empty", applConfig.ID, i)
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
-		}
-
-		jsonPath := jsonpath.New("fieldPath")
-		err := jsonPath.Parse(readyRequirement.FieldPath)
-		if err != nil {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].fieldPath cannot be parsed: %s", applConfig.ID, i, err.Error())
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
+			allErrs = append(allErrs, field.Required(resourcePath.Child("fieldPath"), ""))
+		} else {
+			jsonPath := jsonpath.New("fieldPath")
+			if err := jsonPath.Parse(readyRequirement.FieldPath); err != nil {
+				allErrs = append(allErrs, field.Invalid(resourcePath.Child("fieldPath"), readyRequirement.FieldPath,
+					"cannot be parsed: "+err.Error()))
+			}
 		}
 
 		successValues, err := util.ParseSuccessValues(readyRequirement.SuccessValues)
 		if err != nil {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].successValues cannot be parsed: %s", applConfig.ID, i, err.Error())
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
-		}
-
-		if len(successValues) == 0 {
-			msg := fmt.Sprintf("%s.readyRequirements.resources[%d].successValues is empty", applConfig.ID, i)
-			r.log.V(util.LogLevelWarning).Info("rejected clusterbom, because " + msg)
-			report.deny(msg)
-			return
+			allErrs = append(allErrs, field.Invalid(resourcePath.Child("successValues"), omittedValue{},
+				"cannot be parsed: "+err.Error()))
+		} else if len(successValues) == 0 {
+			allErrs = append(allErrs, field.Required(resourcePath.Child("successValues"), ""))
 		}
 	}
+
+	return allErrs
 }
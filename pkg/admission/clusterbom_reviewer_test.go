@@ -8,14 +8,18 @@ import (
 
 	"github.com/gardener/potter-controller/api/apitypes"
 	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/auditlog"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	"github.com/arschles/assert"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -204,6 +208,103 @@ func TestHelmInstallNameEmpty(t *testing.T) {
 	}
 }
 
+// TestValidateClusterBomCollectsAllViolations tests that the validation returns all violations of a clusterbom
+// together, each with the path of the violated field.
+func TestValidateClusterBomCollectsAllViolations(t *testing.T) {
+	clusterBom := clusterBom01(t)
+	clusterBom.Spec.SecretRef = ""
+	clusterBom.Spec.ApplicationConfigs[0].TypeSpecificData = buildRawExtension(t, apitypes.HelmSpecificData{
+		Namespace: "testnamespace01",
+		CatalogAccess: &apitypes.CatalogAccess{
+			Repo:         "testrepo01",
+			ChartName:    "testchartname01",
+			ChartVersion: "1.2",
+		},
+	})
+	clusterBom.Spec.ApplicationConfigs[1].SecretValues = &hubv1.SecretValues{Operation: "test"}
+
+	reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+	errs := reviewer.validateClusterBom(&clusterBom, nil, nil)
+
+	fields := make([]string, len(errs))
+	for i := range errs {
+		fields[i] = errs[i].Field
+	}
+
+	assert.Equal(t, strings.Join(fields, ", "), "spec.secretRef, "+
+		"spec.applicationConfigs[0].typeSpecificData.installName, "+
+		"spec.applicationConfigs[0].typeSpecificData.catalogAccess.chartVersion, "+
+		"spec.applicationConfigs[1].secretValues.operation", "fields")
+}
+
+// TestReviewReturnsAllViolations tests that the response contains one cause per violation.
+func TestReviewReturnsAllViolations(t *testing.T) {
+	clusterBom := clusterBom01(t)
+	clusterBom.Spec.ApplicationConfigs[0].ID = ""
+	clusterBom.Spec.ApplicationConfigs[1].ConfigType = "unknown"
+
+	reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+	responseReview := reviewer.review()
+
+	assert.False(t, responseReview.Response.Allowed, "allowed")
+	result := responseReview.Response.Result
+	assert.Equal(t, result.Reason, metav1.StatusReasonInvalid, "reason")
+	assert.Equal(t, len(result.Details.Causes), 2, "number of causes")
+	assert.Equal(t, result.Details.Causes[0].Field, "spec.applicationConfigs[0].id", "field of first cause")
+	assert.Equal(t, result.Details.Causes[1].Field, "spec.applicationConfigs[1].configType", "field of second cause")
+	assert.True(t, strings.Contains(result.Message, `spec.applicationConfigs[1].configType: Unsupported value: "unknown"`),
+		"message "+result.Message)
+}
+
+// TestReviewReturnsStructuralAndPolicyViolations tests that the violations of admission policies are returned together
+// with the structural violations, and not only once the clusterbom is otherwise valid.
+func TestReviewReturnsStructuralAndPolicyViolations(t *testing.T) {
+	maxOne := int32(1)
+
+	clusterBom := clusterBom01(t)
+	clusterBom.Spec.ApplicationConfigs[1].ConfigType = "unknown"
+
+	reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+	reviewer.reader = &readerMock{
+		admissionPolicies: []hubv1.AdmissionPolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-a"},
+				Spec:       hubv1.AdmissionPolicySpec{Rules: hubv1.AdmissionPolicyRules{MaxApplicationConfigs: &maxOne}},
+			},
+		},
+	}
+
+	responseReview := reviewer.review()
+	assert.False(t, responseReview.Response.Allowed, "allowed")
+
+	causes := responseReview.Response.Result.Details.Causes
+	assert.Equal(t, len(causes), 2, "number of causes")
+	assert.Equal(t, causes[0].Field, "spec.applicationConfigs[1].configType", "field of the structural violation")
+	assert.Equal(t, causes[1].Field, "spec.applicationConfigs", "field of the policy violation")
+}
+
+// TestReviewReportsUnreadableSecretValues tests that secret values whose secret cannot be read are reported at the
+// field of the secret values, together with the other violations.
+func TestReviewReportsUnreadableSecretValues(t *testing.T) {
+	oldClusterBom := clusterBom01(t)
+	oldClusterBom.Spec.ApplicationConfigs[0].SecretValues = &hubv1.SecretValues{InternalSecretName: "missing-secret"}
+
+	clusterBom := clusterBom01(t)
+	data := buildRawExtension(t, map[string]interface{}{"password": "test"})
+	clusterBom.Spec.ApplicationConfigs[0].SecretValues = &hubv1.SecretValues{Operation: operationReplace, Data: &data}
+	clusterBom.Spec.ApplicationConfigs[1].ConfigType = "unknown"
+
+	reviewer := buildReviewerForClusterBomUpdate(t, &clusterBom, &oldClusterBom)
+	responseReview := reviewer.review()
+	assert.False(t, responseReview.Response.Allowed, "allowed")
+
+	causes := responseReview.Response.Result.Details.Causes
+	assert.Equal(t, len(causes), 2, "number of causes")
+	assert.Equal(t, causes[0].Field, "spec.applicationConfigs[1].configType", "field of the structural violation")
+	assert.Equal(t, causes[1].Field, "spec.applicationConfigs[0].secretValues", "field of the secret values")
+	assert.Equal(t, causes[1].Type, metav1.CauseType(field.ErrorTypeInternal), "type of the secret values violation")
+}
+
 func TestResourceReadyRequirements(t *testing.T) {
 	tests := []struct {
 		name                      string
@@ -242,7 +343,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].name is empty",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].name: Required value",
 		},
 		{
 			name: "namespace is empty",
@@ -259,7 +360,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].namespace is empty",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].namespace: Required value",
 		},
 		{
 			name: "apiVersion is empty",
@@ -276,7 +377,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].apiVersion is empty",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].apiVersion: Required value",
 		},
 		{
 			name: "resource is empty",
@@ -293,7 +394,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].resource is empty",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].resource: Required value",
 		},
 		{
 			name: "fieldPath is empty",
@@ -310,7 +411,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].fieldPath is empty",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].fieldPath: Required value",
 		},
 		{
 			name: "invalid fieldPath",
@@ -328,7 +429,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].fieldPath cannot be parsed:",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].fieldPath: Invalid value",
 		},
 		{
 			name: "successValues is empty",
@@ -342,7 +443,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					SuccessValues: []runtime.RawExtension{},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].successValues is empty",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].successValues: Required value",
 		},
 		{
 			name: "successValue is not an object",
@@ -358,7 +459,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].successValues cannot be parsed:",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].successValues: Invalid value",
 		},
 		{
 			name: "successValue does not contain value key",
@@ -376,7 +477,7 @@ func TestResourceReadyRequirements(t *testing.T) {
 					},
 				},
 			},
-			errorMsg: "id01.readyRequirements.resources[0].successValues cannot be parsed:",
+			errorMsg: "spec.applicationConfigs[0].readyRequirements.resources[0].successValues: Invalid value",
 		},
 	}
 
@@ -534,8 +635,109 @@ func TestSwitchLandscaperManaged(t *testing.T) {
 	}
 }
 
+func TestPatchAnnotations(t *testing.T) {
+	const oldRequester = `{"username":"old-user","time":"2021-12-01T10:00:00Z"}`
+
+	tests := []struct {
+		name              string
+		isUpdate          bool
+		specChanged       bool
+		forgedRequester   bool
+		expectedPatch     bool
+		expectedRequester string
+	}{
+		{name: "create", isUpdate: false, expectedPatch: true, expectedRequester: "test-user"},
+		{name: "update-with-spec-change", isUpdate: true, specChanged: true, expectedPatch: true, expectedRequester: "test-user"},
+		{name: "update-without-spec-change", isUpdate: true, specChanged: false, expectedPatch: false},
+		{name: "update-with-forged-requester", isUpdate: true, forgedRequester: true, expectedPatch: true, expectedRequester: "old-user"},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			clusterBom := clusterBom01(t)
+			util.AddAnnotation(&clusterBom, "other", "value")
+
+			var reviewer *clusterBomReviewer
+			if test.isUpdate {
+				oldClusterBom := clusterBom01(t)
+				util.AddAnnotation(&oldClusterBom, "other", "value")
+				util.AddAnnotation(&oldClusterBom, util.AnnotationKeyRequester, oldRequester)
+				util.AddAnnotation(&clusterBom, util.AnnotationKeyRequester, oldRequester)
+
+				if test.specChanged {
+					oldClusterBom.Spec.ApplicationConfigs = oldClusterBom.Spec.ApplicationConfigs[:1]
+				}
+				if test.forgedRequester {
+					util.AddAnnotation(&clusterBom, util.AnnotationKeyRequester, `{"username":"someone-else"}`)
+				}
+				reviewer = buildReviewerForClusterBomUpdate(t, &clusterBom, &oldClusterBom)
+			} else {
+				reviewer = buildReviewerFromClusterBom(t, &clusterBom)
+			}
+			reviewer.spanContext = newTestSpanContext(t)
+			reviewer.requestReview.Request.UserInfo = authenticationv1.UserInfo{
+				Username: "test-user",
+				Groups:   []string{"test-group"},
+				Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"test-scope"}},
+			}
+
+			responseReview := reviewer.review()
+			assert.True(t, responseReview.Response.Allowed, "allowed")
+
+			patches := []patch{}
+			err := json.Unmarshal(responseReview.Response.Patch, &patches)
+			assert.Nil(t, err, "error")
+
+			var annotations map[string]interface{}
+			for j := range patches {
+				if patches[j].Path == "/metadata/annotations" {
+					annotations = patches[j].Value.(map[string]interface{})
+				}
+			}
+
+			if !test.expectedPatch {
+				assert.Nil(t, annotations, "annotations patch")
+				return
+			}
+
+			assert.Equal(t, annotations["other"], "value", "other annotation")
+
+			requester := auditlog.Requester{}
+			err = json.Unmarshal([]byte(annotations[util.AnnotationKeyRequester].(string)), &requester)
+			assert.Nil(t, err, "error")
+			assert.Equal(t, requester.Username, test.expectedRequester, "requester")
+
+			if test.forgedRequester {
+				assert.Equal(t, annotations[util.AnnotationKeyRequester], oldRequester, "requester annotation")
+				assert.Nil(t, annotations["potter.gardener.cloud/traceparent"], "traceparent annotation")
+				return
+			}
+
+			assert.Equal(t, requester.Groups, []string{"test-group"}, "requester groups")
+			assert.Equal(t, requester.Extra["scopes"], []string{"test-scope"}, "requester extra")
+			assert.Equal(t, annotations["potter.gardener.cloud/traceparent"],
+				"00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01", "traceparent annotation")
+		})
+	}
+}
+
+func newTestSpanContext(t *testing.T) trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	assert.Nil(t, err, "error")
+	spanID, err := trace.SpanIDFromHex("0102030405060708")
+	assert.Nil(t, err, "error")
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
 type readerMock struct {
-	existingKeys []client.ObjectKey
+	existingKeys      []client.ObjectKey
+	admissionPolicies []hubv1.AdmissionPolicy
 }
 
 func (r *readerMock) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
@@ -573,11 +775,15 @@ func (r *readerMock) List(ctx context.Context, list client.ObjectList, opts ...c
 }
 
 func (r *readerMock) ListUncached(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if policyList, ok := list.(*hubv1.AdmissionPolicyList); ok {
+		policyList.Items = r.admissionPolicies
+	}
 	return nil
 }
 
 func buildReviewerFromClusterBom(t *testing.T, clusterBom *hubv1.ClusterBom) *clusterBomReviewer {
 	return &clusterBomReviewer{
+		ctx:    context.Background(),
 		log:    ctrl.Log.WithName("ClusterBom Admission Hook Test"),
 		reader: &readerMock{},
 		requestReview: &v1beta1.AdmissionReview{
@@ -591,6 +797,7 @@ func buildReviewerFromClusterBom(t *testing.T, clusterBom *hubv1.ClusterBom) *cl
 
 func buildReviewerForClusterBomUpdate(t *testing.T, clusterBom, oldClusterBom *hubv1.ClusterBom) *clusterBomReviewer {
 	return &clusterBomReviewer{
+		ctx:    context.Background(),
 		log:    ctrl.Log.WithName("ClusterBom Admission Hook Test"),
 		reader: &readerMock{},
 		requestReview: &v1beta1.AdmissionReview{
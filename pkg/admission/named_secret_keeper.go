@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sort"
 	"strconv"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
@@ -14,18 +15,25 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
+// NamedSecretKeeper moves the named secret values of an application config into secrets. As the SecretKeeper, it adds
+// the new secrets to the writer, which persists them after the review.
 type NamedSecretKeeper struct {
 	client       synchronize.UncachedClient
-	dryRun       bool
+	writer       *secretWriter
 	clusterBom   *hubv1.ClusterBom
 	appIndex     int
 	appConfig    *hubv1.ApplicationConfig
 	oldAppConfig *hubv1.ApplicationConfig
+	// requestUID is the uid of the admission request, from which the names of the new secrets are derived
+	requestUID types.UID
 }
 
-func (s *NamedSecretKeeper) handleAppConfig(ctx context.Context, patches []patch) ([]patch, error) {
+// handleAppConfig returns the patches for the named secret values of the application config, or the violations of the
+// named secret values which could not be handled, each at the path of its logical secret name.
+func (s *NamedSecretKeeper) handleAppConfig(ctx context.Context, fldPath *field.Path, patches []patch) ([]patch, field.ErrorList) {
 	log := util.GetLoggerFromContext(ctx)
 	log.Info("Handling named secrets of app")
 
@@ -47,6 +55,7 @@ func (s *NamedSecretKeeper) handleAppConfig(ctx context.Context, patches []patch
 	namedSecretSectionNeeded := false
 
 	tmpPatches := []patch{}
+	allErrs := field.ErrorList{}
 
 	for _, logicalSecretName := range logicalSecretNames {
 		oldNamedSecretsValue, oldOk := oldNamedSecretValues[logicalSecretName]
@@ -55,14 +64,21 @@ func (s *NamedSecretKeeper) handleAppConfig(ctx context.Context, patches []patch
 		var err error
 		var needed bool
 
-		tmpPatches, needed, err = s.handleNamedSecret(ctx, logicalSecretName, oldNamedSecretsValue, oldOk, &newNamedSecretsValue, newOk, tmpPatches)
+		var secretPatches []patch
+		secretPatches, needed, err = s.handleNamedSecret(ctx, logicalSecretName, oldNamedSecretsValue, oldOk, &newNamedSecretsValue, newOk, tmpPatches)
 		if err != nil {
-			return nil, err
+			allErrs = append(allErrs, field.InternalError(fldPath.Key(logicalSecretName), err))
+			continue
 		}
 
+		tmpPatches = secretPatches
 		namedSecretSectionNeeded = namedSecretSectionNeeded || needed
 	}
 
+	if len(allErrs) > 0 {
+		return nil, allErrs
+	}
+
 	patches = append(patches, tmpPatches...)
 
 	if !namedSecretSectionNeeded {
@@ -72,6 +88,45 @@ func (s *NamedSecretKeeper) handleAppConfig(ctx context.Context, patches []patch
 	return patches, nil
 }
 
+// validateNamedSecretValues returns the violations of the named secret values of an application config, which
+// handleAppConfig would otherwise report one by one when it moves the named secret values into secrets.
+func validateNamedSecretValues(fldPath *field.Path, appConfig, oldAppConfig *hubv1.ApplicationConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	// sorted, so that the violations are reported in a stable order
+	logicalSecretNames := make([]string, 0, len(appConfig.NamedSecretValues))
+	for logicalSecretName := range appConfig.NamedSecretValues {
+		logicalSecretNames = append(logicalSecretNames, logicalSecretName)
+	}
+	sort.Strings(logicalSecretNames)
+
+	for _, logicalSecretName := range logicalSecretNames {
+		namedSecretValues := appConfig.NamedSecretValues[logicalSecretName]
+		namedPath := fldPath.Key(logicalSecretName)
+
+		switch namedSecretValues.Operation {
+		case operationDelete:
+			continue
+		case operationEmpty:
+		default:
+			allErrs = append(allErrs, field.NotSupported(namedPath.Child("operation"), namedSecretValues.Operation,
+				[]string{operationDelete}))
+			continue
+		}
+
+		oldOk := false
+		if oldAppConfig != nil {
+			_, oldOk = oldAppConfig.NamedSecretValues[logicalSecretName]
+		}
+
+		if !oldOk && namedSecretValues.StringData == nil {
+			allErrs = append(allErrs, field.Required(namedPath.Child("data"), "must be provided to create secret values"))
+		}
+	}
+
+	return allErrs
+}
+
 func (s *NamedSecretKeeper) getNamedSecretValues(appConfig *hubv1.ApplicationConfig) map[string]hubv1.NamedSecretValues {
 	if appConfig == nil || appConfig.NamedSecretValues == nil {
 		return make(map[string]hubv1.NamedSecretValues)
@@ -113,10 +168,7 @@ func (s *NamedSecretKeeper) handleNamedSecret(ctx context.Context, logicalSecret
 			return nil, false, err
 		}
 
-		err = s.createSecret(ctx, secret)
-		if err != nil {
-			return nil, false, err
-		}
+		s.writer.add(secret)
 
 		patches = s.appendPatchToReplaceSecretValues(patches, logicalSecretName, secret.GetName())
 		return patches, true, nil
@@ -169,11 +221,7 @@ func (s *NamedSecretKeeper) replaceSecretValues(ctx context.Context, logicalSecr
 
 	internalSecretName := oldInternalSecretName
 	if !equal {
-		err = s.createSecret(ctx, newSecret)
-		if err != nil {
-			return nil, err
-		}
-
+		s.writer.add(newSecret)
 		internalSecretName = newSecret.GetName()
 	}
 	patches = s.appendPatchToReplaceSecretValues(patches, logicalSecretName, internalSecretName)
@@ -190,7 +238,7 @@ func (s *NamedSecretKeeper) buildSecretObject(ctx context.Context, logicalSecret
 		return nil, errors.New(message)
 	}
 
-	internalSecretName := util.CreateSecretName(s.clusterBom.Name, s.appConfig.ID)
+	internalSecretName := util.CreateSecretNameForRequest(s.clusterBom.Name, s.appConfig.ID, s.requestUID, logicalSecretName)
 	secret := s.makeSecret(internalSecretName, logicalSecretName, newNamedSecretsValue.StringData)
 
 	return secret, nil
@@ -222,23 +270,6 @@ func (s *NamedSecretKeeper) makeSecret(secretName, logicalSecretName string,
 	return secret
 }
 
-func (s *NamedSecretKeeper) createSecret(ctx context.Context, secret *v1.Secret) error {
-	log := ctx.Value(util.LoggerKey{}).(logr.Logger)
-
-	if s.dryRun {
-		log.V(util.LogLevelWarning).Info("Dry run: skip secret creation")
-		return nil
-	}
-
-	err := s.client.Create(ctx, secret)
-	if err != nil {
-		message := "Error creating secret"
-		log.Error(err, message)
-		return errors.New(message + " - " + err.Error())
-	}
-	return nil
-}
-
 func (s *NamedSecretKeeper) getSecret(ctx context.Context, secretKey *types.NamespacedName) (*v1.Secret, error) {
 	log := ctx.Value(util.LoggerKey{}).(logr.Logger)
 
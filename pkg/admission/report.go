@@ -5,6 +5,7 @@ import (
 
 	"k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 func newReport(requestReview *v1beta1.AdmissionReview) *report {
@@ -16,12 +17,15 @@ func newReport(requestReview *v1beta1.AdmissionReview) *report {
 	}
 }
 
-// A report collects the review result: whether clusterbom creation/update is allowed or denied; a message in case of
-// denial; and the patches to mutate the clusterbom.
+// A report collects the review result: whether clusterbom creation/update is allowed or denied; a message or the
+// violated fields in case of denial; the patches to mutate the clusterbom; and warnings which are returned to the client.
 type report struct {
 	ok            bool
 	message       string
+	reason        metav1.StatusReason
+	errs          field.ErrorList
 	patches       []patch
+	warnings      []string
 	requestReview *v1beta1.AdmissionReview
 }
 
@@ -43,13 +47,40 @@ func (r *report) appendPatches(pp ...patch) {
 	r.patches = append(r.patches, pp...)
 }
 
+// deny rejects the request because it is invalid.
 func (r *report) deny(message string) {
+	r.denyWithReason(metav1.StatusReasonInvalid, message)
+}
+
+// forbid rejects the request because the requested change is not allowed, although it is valid in itself.
+func (r *report) forbid(message string) {
+	r.denyWithReason(metav1.StatusReasonForbidden, message)
+}
+
+// fail rejects the request because it could not be checked, e.g. due to an unavailable dependency.
+func (r *report) fail(message string) {
+	r.denyWithReason(metav1.StatusReasonInternalError, message)
+}
+
+func (r *report) denyWithReason(reason metav1.StatusReason, message string) {
 	r.ok = false
 	r.message = message
+	r.reason = reason
+}
+
+// warn adds a warning, which is shown to the client, e.g. by kubectl, whether the request is allowed or not.
+func (r *report) warn(message string) {
+	r.warnings = append(r.warnings, message)
+}
+
+// addErrors rejects the request because of the given violations. In contrast to deny, the review can continue, so that
+// all violations are returned together.
+func (r *report) addErrors(errs field.ErrorList) {
+	r.errs = append(r.errs, errs...)
 }
 
 func (r *report) denied() bool {
-	return !r.ok
+	return !r.ok || len(r.errs) > 0
 }
 
 func (r *report) getResponseReview() *v1beta1.AdmissionReview {
@@ -64,7 +95,7 @@ func (r *report) getResponseReview() *v1beta1.AdmissionReview {
 	patchType := v1beta1.PatchTypeJSONPatch
 	patchJSON, err := json.Marshal(r.patches)
 	if err != nil {
-		r.deny("cannot mutate clusterbom; error when marshaling patch: " + err.Error())
+		r.fail("cannot mutate clusterbom; error when marshaling patch: " + err.Error())
 		return r.negativeReview()
 	}
 
@@ -79,19 +110,93 @@ func (r *report) positiveReview(patchType *v1beta1.PatchType, patchJSON []byte)
 			Allowed:   true,
 			PatchType: patchType,
 			Patch:     patchJSON,
+			Warnings:  r.warnings,
 		},
 	}
 }
 
+// negativeReview returns the review of a denied request. If the request was rejected with a message, e.g. because a
+// check failed, the violations collected before are kept in the details of the status.
 func (r *report) negativeReview() *v1beta1.AdmissionReview {
+	var result *metav1.Status
+	if r.ok {
+		result = r.errorsStatus()
+	} else {
+		result = &metav1.Status{
+			Message: r.message,
+			Reason:  r.reason,
+		}
+
+		if len(r.errs) > 0 {
+			result.Message += "; clusterbom is invalid: " + r.errs.ToAggregate().Error()
+			result.Details = &metav1.StatusDetails{
+				Causes: r.errorCauses(),
+			}
+		}
+	}
+
 	return &v1beta1.AdmissionReview{
 		TypeMeta: r.requestReview.TypeMeta,
 		Response: &v1beta1.AdmissionResponse{
-			UID:     r.requestReview.Request.UID,
-			Allowed: false,
-			Result: &metav1.Status{
-				Message: r.message,
-			},
+			UID:      r.requestReview.Request.UID,
+			Allowed:  false,
+			Result:   result,
+			Warnings: r.warnings,
+		},
+	}
+}
+
+// errorsStatus returns the status for the collected violations, with one cause per violated field, in the same way as
+// the API server reports invalid objects.
+func (r *report) errorsStatus() *metav1.Status {
+	return &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: "clusterbom is invalid: " + r.errs.ToAggregate().Error(),
+		Reason:  r.errorsReason(),
+		Details: &metav1.StatusDetails{
+			Causes: r.errorCauses(),
 		},
 	}
 }
+
+func (r *report) errorCauses() []metav1.StatusCause {
+	causes := make([]metav1.StatusCause, len(r.errs))
+	for i, err := range r.errs {
+		causes[i] = metav1.StatusCause{
+			Type:    metav1.CauseType(err.Type),
+			Message: err.ErrorBody(),
+			Field:   err.Field,
+		}
+	}
+
+	return causes
+}
+
+// errorsReason returns InternalError if a violation could not be checked, Forbidden if all violations are forbidden
+// changes, and Invalid otherwise.
+func (r *report) errorsReason() metav1.StatusReason {
+	reason := metav1.StatusReasonForbidden
+	for _, err := range r.errs {
+		switch err.Type {
+		case field.ErrorTypeInternal:
+			return metav1.StatusReasonInternalError
+		case field.ErrorTypeForbidden:
+		default:
+			reason = metav1.StatusReasonInvalid
+		}
+	}
+
+	return reason
+}
+
+// invalidData returns the violation of a field whose data could not be unmarshalled. The data are not repeated in the
+// message, because they might be large or contain secrets.
+func invalidData(fldPath *field.Path, err error) *field.Error {
+	return field.Invalid(fldPath, omittedValue{}, "could not be unmarshalled: "+err.Error())
+}
+
+type omittedValue struct{}
+
+func (omittedValue) String() string {
+	return "(omitted)"
+}
@@ -0,0 +1,44 @@
+package admission
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// TestNegativeReviewKeepsErrors tests that the collected violations are returned, also if the request is rejected
+// with a message afterwards
+func TestNegativeReviewKeepsErrors(t *testing.T) {
+	violation := field.Required(field.NewPath("spec", "secretRef"), "must not be empty")
+
+	report := newReport(&v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{UID: "test-uid"}})
+	report.addErrors(field.ErrorList{violation})
+	report.fail("cannot list admission policies")
+
+	responseReview := report.getResponseReview()
+	assert.False(t, responseReview.Response.Allowed, "allowed")
+
+	result := responseReview.Response.Result
+	assert.Equal(t, result.Reason, metav1.StatusReasonInternalError, "reason")
+	assert.True(t, strings.HasPrefix(result.Message, "cannot list admission policies; clusterbom is invalid: "),
+		"unexpected message: "+result.Message)
+	assert.True(t, strings.Contains(result.Message, "spec.secretRef"), "unexpected message: "+result.Message)
+	assert.NotNil(t, result.Details, "details")
+	assert.Equal(t, result.Details.Causes, []metav1.StatusCause{{
+		Type:    metav1.CauseTypeFieldValueRequired,
+		Message: violation.ErrorBody(),
+		Field:   "spec.secretRef",
+	}}, "causes")
+
+	// without collected violations only the message is returned
+	report = newReport(&v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{UID: "test-uid"}})
+	report.fail("cannot list admission policies")
+
+	result = report.getResponseReview().Response.Result
+	assert.Equal(t, result.Message, "cannot list admission policies", "message")
+	assert.Nil(t, result.Details, "details")
+}
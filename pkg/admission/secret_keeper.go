@@ -15,6 +15,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 const (
@@ -24,9 +25,13 @@ const (
 	operationEmpty   = ""
 )
 
+// SecretKeeper moves the secret values of the application configs of a clusterbom into secrets. It only reads existing
+// secrets; the new secrets are added to the writer, which persists them after the review.
 type SecretKeeper struct {
 	client synchronize.UncachedClient
-	dryRun bool
+	writer *secretWriter
+	// requestUID is the uid of the admission request, from which the names of the new secrets are derived
+	requestUID types.UID
 }
 
 // Suppose the old app config has no secret values.
@@ -105,6 +110,45 @@ func (s *SecretKeeper) handleAppConfig(ctx context.Context, clusterBom *hubv1.Cl
 	return nil, errors.New(message)
 }
 
+// validateSecretValues returns the violations of the secret values of an application config, which handleAppConfig
+// would otherwise report one by one when it moves the secret values into a secret.
+func validateSecretValues(fldPath *field.Path, appConfig, oldAppConfig *hubv1.ApplicationConfig) field.ErrorList {
+	secretValues := appConfig.SecretValues
+	if secretValues == nil {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+
+	switch secretValues.Operation {
+	case operationDelete:
+		return allErrs
+	case operationReplace, operationKeep, operationEmpty:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("operation"), secretValues.Operation,
+			[]string{operationReplace, operationKeep, operationDelete}))
+		return allErrs
+	}
+
+	hasOldSecretValues := oldAppConfig != nil && oldAppConfig.SecretValues != nil
+
+	if secretValues.Data == nil {
+		if !hasOldSecretValues {
+			allErrs = append(allErrs, field.Required(fldPath.Child("data"), "must be provided to create secret values"))
+		} else if secretValues.Operation == operationReplace {
+			allErrs = append(allErrs, field.Required(fldPath.Child("data"), "must be provided to replace secret values"))
+		}
+		return allErrs
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(secretValues.Data.Raw, &data); err != nil {
+		allErrs = append(allErrs, invalidData(fldPath.Child("data"), err))
+	}
+
+	return allErrs
+}
+
 func (s *SecretKeeper) keepSecretValues(appIndex int,
 	appConfig *hubv1.ApplicationConfig, oldInternalSecretName string, patches []patch) ([]patch, error) {
 	if appConfig.SecretValues != nil && appConfig.SecretValues.InternalSecretName == oldInternalSecretName {
@@ -215,12 +259,9 @@ func (s *SecretKeeper) moveSecretValuesToSecret(ctx context.Context, clusterBom
 		return "", err
 	}
 
-	secretName := util.CreateSecretName(clusterBom.Name, appConfig.ID)
+	secretName := util.CreateSecretNameForRequest(clusterBom.Name, appConfig.ID, s.requestUID, util.SecretValuesKey)
 	secret := s.makeSecret(clusterBom, appConfig, secretName)
-	err = s.createSecret(ctx, secret)
-	if err != nil {
-		return "", err
-	}
+	s.writer.add(secret)
 
 	return secretName, nil
 }
@@ -244,23 +285,6 @@ func (s *SecretKeeper) makeSecret(clusterBom *hubv1.ClusterBom, appConfig *hubv1
 	return secret
 }
 
-func (s *SecretKeeper) createSecret(ctx context.Context, secret *v1.Secret) error {
-	log := ctx.Value(util.LoggerKey{}).(logr.Logger)
-
-	if s.dryRun {
-		log.V(util.LogLevelWarning).Info("Dry run: skip secret creation")
-		return nil
-	}
-
-	err := s.client.Create(ctx, secret)
-	if err != nil {
-		message := "Error creating secret"
-		log.Error(err, message)
-		return errors.New(message + " - " + err.Error())
-	}
-	return nil
-}
-
 func (s *SecretKeeper) getSecret(ctx context.Context, secretKey *types.NamespacedName) (*v1.Secret, error) {
 	log := ctx.Value(util.LoggerKey{}).(logr.Logger)
 
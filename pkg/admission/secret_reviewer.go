@@ -11,7 +11,9 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/gardener/potter-controller/api/apitypes"
 	v12 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/secrets"
 	"github.com/gardener/potter-controller/pkg/synchronize"
@@ -22,6 +24,7 @@ type secretReviewer struct {
 	log                 logr.Logger
 	requestReview       *v1beta1.AdmissionReview
 	hubControllerClient synchronize.UncachedClient
+	uncachedClient      synchronize.UncachedClient
 }
 
 func (reviewer *secretReviewer) review() *v1beta1.AdmissionReview {
@@ -42,14 +45,14 @@ func (reviewer *secretReviewer) reviewUpdate() *v1beta1.AdmissionReview {
 	err := json.Unmarshal(reviewer.requestReview.Request.OldObject.Raw, &oldSecret)
 	if err != nil {
 		reviewer.log.Error(err, "error when unmarshalling old secret")
-		return reviewer.deny("error when unmarshalling old secret : " + err.Error())
+		return reviewer.deny(metav1.StatusReasonInvalid, "error when unmarshalling old secret : " + err.Error())
 	}
 
 	var newSecret *v1.Secret
 	err = json.Unmarshal(reviewer.requestReview.Request.Object.Raw, &newSecret)
 	if err != nil {
 		reviewer.log.Error(err, "error when unmarshalling new secret")
-		return reviewer.deny("error when unmarshalling new secret : " + err.Error())
+		return reviewer.deny(metav1.StatusReasonInvalid, "error when unmarshalling new secret : " + err.Error())
 	}
 
 	reviewer.log = reviewer.log.WithValues(util.LogKeySecretName, types.NamespacedName{
@@ -65,6 +68,15 @@ func (reviewer *secretReviewer) reviewUpdate() *v1beta1.AdmissionReview {
 		return reviewer.allow()
 	}
 
+	if reviewer.isSecretSyncSource(oldSecret) {
+		if reviewer.isSecretSyncSource(newSecret) {
+			// changes of source secrets are allowed; they are propagated to the copies
+			return reviewer.allow()
+		}
+
+		return reviewer.reviewSecretSyncSourceRemoval(oldSecret, "remove the label of")
+	}
+
 	if reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
 		reviewer.log.V(util.LogLevelDebug).Info( "Secret admission hook called for an unchanged secret")
 		return reviewer.allow()
@@ -73,9 +85,9 @@ func (reviewer *secretReviewer) reviewUpdate() *v1beta1.AdmissionReview {
 	ok, err = reviewer.validateSecretDeletionToken(newSecret)
 	if err != nil {
 		reviewer.log.Error(err, "Error when validating update request")
-		return reviewer.deny("Error when validating update request")
+		return reviewer.deny(metav1.StatusReasonInternalError, "Error when validating update request")
 	} else if !ok {
-		return reviewer.deny("You are not allowed to update a hub-managed secret")
+		return reviewer.deny(metav1.StatusReasonForbidden, "You are not allowed to update a hub-managed secret")
 	}
 
 	return reviewer.allow()
@@ -86,7 +98,7 @@ func (reviewer *secretReviewer) reviewDelete() *v1beta1.AdmissionReview {
 	err := json.Unmarshal(reviewer.requestReview.Request.OldObject.Raw, &oldSecret)
 	if err != nil {
 		reviewer.log.Error(err, "error when unmarshalling old secret")
-		return reviewer.deny("error when unmarshalling old secret : " + err.Error())
+		return reviewer.deny(metav1.StatusReasonInvalid, "error when unmarshalling old secret : " + err.Error())
 	}
 
 	reviewer.log = reviewer.log.WithValues(util.LogKeySecretName, types.NamespacedName{
@@ -102,12 +114,16 @@ func (reviewer *secretReviewer) reviewDelete() *v1beta1.AdmissionReview {
 		return reviewer.allow()
 	}
 
+	if reviewer.isSecretSyncSource(oldSecret) {
+		return reviewer.reviewSecretSyncSourceRemoval(oldSecret, "delete")
+	}
+
 	ok, err = reviewer.validateSecretDeletionToken(oldSecret)
 	if err != nil {
 		reviewer.log.Error(err, "Error when validating deletion request")
-		return reviewer.deny("Error when validating deletion request")
+		return reviewer.deny(metav1.StatusReasonInternalError, "Error when validating deletion request")
 	} else if !ok {
-		return reviewer.deny("You are not allowed to delete a hub-managed secret")
+		return reviewer.deny(metav1.StatusReasonForbidden, "You are not allowed to delete a hub-managed secret")
 	}
 
 	return reviewer.allow()
@@ -115,7 +131,68 @@ func (reviewer *secretReviewer) reviewDelete() *v1beta1.AdmissionReview {
 
 func (reviewer *secretReviewer) checkResponsibility(secret *v1.Secret) bool {
 	value, ok := secret.ObjectMeta.Labels[v12.LabelPurpose]
-	return ok && (value == util.PurposeSecretValues)
+	return ok && (value == util.PurposeSecretValues || value == util.PurposeSecretSyncSource)
+}
+
+func (reviewer *secretReviewer) isSecretSyncSource(secret *v1.Secret) bool {
+	return secret.ObjectMeta.Labels[v12.LabelPurpose] == util.PurposeSecretSyncSource
+}
+
+// reviewSecretSyncSourceRemoval protects the source secrets of config type secretsync. As for hub-managed secrets,
+// a valid deletion token allows the removal. Without token, a source secret can only be removed if no clusterbom
+// copies it.
+func (reviewer *secretReviewer) reviewSecretSyncSourceRemoval(secret *v1.Secret, action string) *v1beta1.AdmissionReview {
+	ok, err := reviewer.validateSecretDeletionToken(secret)
+	if err != nil {
+		reviewer.log.Error(err, "Error when validating removal of secretsync source")
+		return reviewer.deny(metav1.StatusReasonInternalError, "Error when validating removal of secretsync source")
+	} else if ok {
+		return reviewer.allow()
+	}
+
+	clusterBomName, err := reviewer.findSecretSyncReference(secret)
+	if err != nil {
+		reviewer.log.Error(err, "Error when checking references to secretsync source")
+		return reviewer.deny(metav1.StatusReasonInternalError, "Error when checking references to secretsync source")
+	} else if clusterBomName != "" {
+		return reviewer.deny(metav1.StatusReasonForbidden, "You are not allowed to " + action + " secret " + secret.Name +
+			", because it is synchronized by clusterbom " + clusterBomName)
+	}
+
+	return reviewer.allow()
+}
+
+// findSecretSyncReference returns the name of a clusterbom which copies the secret, or an empty string if there is none.
+func (reviewer *secretReviewer) findSecretSyncReference(secret *v1.Secret) (string, error) {
+	clusterBomList := &v12.ClusterBomList{}
+	err := reviewer.uncachedClient.ListUncached(context.Background(), clusterBomList, client.InNamespace(secret.Namespace))
+	if err != nil {
+		return "", err
+	}
+
+	for i := range clusterBomList.Items {
+		clusterBom := &clusterBomList.Items[i]
+
+		for j := range clusterBom.Spec.ApplicationConfigs {
+			applConfig := &clusterBom.Spec.ApplicationConfigs[j]
+			if applConfig.ConfigType != util.ConfigTypeSecretSync {
+				continue
+			}
+
+			var secretSyncData apitypes.SecretSyncSpecificData
+			if err = json.Unmarshal(applConfig.TypeSpecificData.Raw, &secretSyncData); err != nil {
+				reviewer.log.V(util.LogLevelWarning).Info("could not parse secretsync specific data",
+					"clusterbom", clusterBom.Name, "applConfig.ID", applConfig.ID, "error", err)
+				continue
+			}
+
+			if secretSyncData.References(secret.Name) {
+				return clusterBom.Name, nil
+			}
+		}
+	}
+
+	return "", nil
 }
 
 func (reviewer *secretReviewer) validateSecretDeletionToken(secret *v1.Secret) (bool, error) {
@@ -173,7 +250,7 @@ func (reviewer *secretReviewer) allow() *v1beta1.AdmissionReview {
 	}
 }
 
-func (reviewer *secretReviewer) deny(message string) *v1beta1.AdmissionReview {
+func (reviewer *secretReviewer) deny(reason metav1.StatusReason, message string) *v1beta1.AdmissionReview {
 	return &v1beta1.AdmissionReview{
 		TypeMeta: reviewer.requestReview.TypeMeta,
 		Response: &v1beta1.AdmissionResponse{
@@ -181,6 +258,7 @@ func (reviewer *secretReviewer) deny(message string) *v1beta1.AdmissionReview {
 			Allowed: false,
 			Result: &metav1.Status{
 				Message: message,
+				Reason:  reason,
 			},
 		},
 	}
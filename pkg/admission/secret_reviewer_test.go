@@ -0,0 +1,135 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReviewSecretSyncSource(t *testing.T) {
+	sourceSecret := newTestSecretSyncSource("registry", true, "user")
+
+	tests := []struct {
+		name            string
+		operation       v1beta1.Operation
+		newSecret       *corev1.Secret
+		referencedName  string
+		expectedAllowed bool
+	}{
+		{
+			name:            "reject deletion of referenced source",
+			operation:       v1beta1.Delete,
+			referencedName:  "registry",
+			expectedAllowed: false,
+		},
+		{
+			name:            "allow deletion of unreferenced source",
+			operation:       v1beta1.Delete,
+			referencedName:  "other",
+			expectedAllowed: true,
+		},
+		{
+			name:            "allow update of referenced source",
+			operation:       v1beta1.Update,
+			newSecret:       newTestSecretSyncSource("registry", true, "admin"),
+			referencedName:  "registry",
+			expectedAllowed: true,
+		},
+		{
+			name:            "reject removal of label of referenced source",
+			operation:       v1beta1.Update,
+			newSecret:       newTestSecretSyncSource("registry", false, "user"),
+			referencedName:  "registry",
+			expectedAllowed: false,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			request := &v1beta1.AdmissionRequest{
+				Operation: test.operation,
+				OldObject: buildRawExtension(t, sourceSecret),
+			}
+			if test.newSecret != nil {
+				request.Object = buildRawExtension(t, test.newSecret)
+			}
+
+			reviewer := &secretReviewer{
+				log:            ctrl.Log.WithName("Secret Admission Hook Test"),
+				requestReview:  &v1beta1.AdmissionReview{Request: request},
+				uncachedClient: &clusterBomListMock{clusterBoms: []hubv1.ClusterBom{newTestSecretSyncClusterBom(t, test.referencedName)}},
+			}
+
+			responseReview := reviewer.review()
+			assert.Equal(t, responseReview.Response.Allowed, test.expectedAllowed, "allowed")
+		})
+	}
+}
+
+func newTestSecretSyncSource(name string, labeled bool, username string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "garden-test",
+			Labels:    map[string]string{"other": "label"},
+		},
+		Data: map[string][]byte{
+			"username": []byte(username),
+		},
+	}
+
+	if labeled {
+		secret.Labels[hubv1.LabelPurpose] = util.PurposeSecretSyncSource
+	}
+
+	return secret
+}
+
+func newTestSecretSyncClusterBom(t *testing.T, secretName string) hubv1.ClusterBom {
+	typeSpecificData, err := json.Marshal(map[string]interface{}{
+		"secrets": []interface{}{
+			map[string]interface{}{"name": secretName, "namespaces": []string{"app"}},
+		},
+	})
+	assert.Nil(t, err, "error")
+
+	return hubv1.ClusterBom{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testbom",
+			Namespace: "garden-test",
+		},
+		Spec: hubv1.ClusterBomSpec{
+			ApplicationConfigs: []hubv1.ApplicationConfig{
+				{
+					ID:               "secrets",
+					ConfigType:       util.ConfigTypeSecretSync,
+					TypeSpecificData: runtime.RawExtension{Raw: typeSpecificData},
+				},
+			},
+		},
+	}
+}
+
+type clusterBomListMock struct {
+	readerMock
+	clusterBoms []hubv1.ClusterBom
+}
+
+func (r *clusterBomListMock) ListUncached(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if clusterBomList, ok := list.(*hubv1.ClusterBomList); ok {
+		clusterBomList.Items = r.clusterBoms
+	}
+	return nil
+}
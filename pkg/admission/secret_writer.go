@@ -0,0 +1,89 @@
+package admission
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+// secretWriter collects the secrets for secret values which the review of a clusterbom creates. They are persisted
+// only after the review has allowed the clusterbom, and never for a dry-run request, so that a denied or dry-run request
+// leaves no secrets behind. If the write of the clusterbom fails nevertheless, the garbage collection of the controller
+// deletes the secrets, because no clusterbom references them.
+type secretWriter struct {
+	client  synchronize.UncachedClient
+	secrets []*v1.Secret
+	now     func() time.Time
+}
+
+func newSecretWriter(client synchronize.UncachedClient) *secretWriter {
+	return &secretWriter{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (w *secretWriter) add(secret *v1.Secret) {
+	w.secrets = append(w.secrets, secret)
+}
+
+// persist creates the collected secrets. The names of the secrets are derived from the uid of the admission request, so
+// that a secret which already exists with the same data, e.g. from a repeated call of the webhook for the same request,
+// is reused.
+func (w *secretWriter) persist(ctx context.Context) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	admittedAt := w.now().UTC().Format(time.RFC3339)
+
+	for _, secret := range w.secrets {
+		util.AddAnnotation(secret, util.AnnotationKeyAdmittedAt, admittedAt)
+
+		err := w.client.Create(ctx, secret)
+		if err == nil {
+			continue
+		} else if !apierrors.IsAlreadyExists(err) {
+			log.Error(err, "Error creating secret", util.LogKeySecretName, secret.Name)
+			return errors.Wrapf(err, "could not create secret %s", secret.Name)
+		}
+
+		if err = w.reuse(ctx, secret, admittedAt); err != nil {
+			log.Error(err, "Error reusing secret", util.LogKeySecretName, secret.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reuse renews the admission time of an existing secret with the same data, so that the garbage collection does not
+// delete it before the clusterbom references it.
+func (w *secretWriter) reuse(ctx context.Context, secret *v1.Secret, admittedAt string) error {
+	existingSecret := &v1.Secret{}
+	secretKey := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}
+	if err := w.client.GetUncached(ctx, secretKey, existingSecret); err != nil {
+		return errors.Wrapf(err, "could not read existing secret %s", secret.Name)
+	}
+
+	if _, ok := existingSecret.Data[util.KeyDeletionToken]; ok {
+		return errors.Errorf("secret %s is being deleted; please retry", secret.Name)
+	}
+
+	if !reflect.DeepEqual(existingSecret.Data, secret.Data) {
+		return errors.Errorf("secret %s already exists with other data", secret.Name)
+	}
+
+	util.AddAnnotation(existingSecret, util.AnnotationKeyAdmittedAt, admittedAt)
+	if err := w.client.Update(ctx, existingSecret); err != nil {
+		return errors.Wrapf(err, "could not update existing secret %s", secret.Name)
+	}
+
+	return nil
+}
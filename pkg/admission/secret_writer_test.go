@@ -0,0 +1,188 @@
+package admission
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+func TestSecretWriterPersist(t *testing.T) {
+	admittedAt := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		existingData    map[string][]byte
+		expectedErr     string
+		expectedCreates int
+		expectedUpdates int
+	}{
+		{
+			name:            "new secret",
+			expectedCreates: 1,
+		},
+		{
+			name:            "existing secret with same data",
+			existingData:    map[string][]byte{util.SecretValuesKey: []byte("value")},
+			expectedUpdates: 1,
+		},
+		{
+			name:         "existing secret with other data",
+			existingData: map[string][]byte{util.SecretValuesKey: []byte("other")},
+			expectedErr:  "already exists with other data",
+		},
+		{
+			name: "existing secret being deleted",
+			existingData: map[string][]byte{
+				util.SecretValuesKey:  []byte("value"),
+				util.KeyDeletionToken: []byte("token"),
+			},
+			expectedErr: "is being deleted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretClient := &secretClientMock{secrets: map[client.ObjectKey]*v1.Secret{}}
+			if tt.existingData != nil {
+				secretClient.secrets[client.ObjectKey{Namespace: "testnamespace", Name: "testsecret"}] = &v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "testnamespace", Name: "testsecret"},
+					Data:       tt.existingData,
+				}
+			}
+
+			writer := newSecretWriter(secretClient)
+			writer.now = func() time.Time { return admittedAt }
+			writer.add(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "testnamespace", Name: "testsecret"},
+				Data:       map[string][]byte{util.SecretValuesKey: []byte("value")},
+			})
+
+			ctx := context.WithValue(context.Background(), util.LoggerKey{}, ctrl.Log.WithName("test"))
+			err := writer.persist(ctx)
+			if tt.expectedErr != "" {
+				assert.NotNil(t, err, "error")
+				assert.True(t, strings.Contains(err.Error(), tt.expectedErr), "error "+err.Error())
+				return
+			}
+
+			assert.NoErr(t, err)
+			assert.Equal(t, secretClient.creates, tt.expectedCreates, "number of creates")
+			assert.Equal(t, secretClient.updates, tt.expectedUpdates, "number of updates")
+
+			secret := secretClient.secrets[client.ObjectKey{Namespace: "testnamespace", Name: "testsecret"}]
+			value, ok := util.GetAnnotation(secret, util.AnnotationKeyAdmittedAt)
+			assert.True(t, ok, "admitted-at annotation")
+			assert.Equal(t, value, "2021-03-01T10:00:00Z", "admitted-at annotation")
+		})
+	}
+}
+
+func TestSecretNameForRequest(t *testing.T) {
+	name := util.CreateSecretNameForRequest("testclusterbom", "id01", "uid01", util.SecretValuesKey)
+	assert.Equal(t, util.CreateSecretNameForRequest("testclusterbom", "id01", "uid01", util.SecretValuesKey), name, "name for same request")
+	assert.True(t, util.CreateSecretNameForRequest("testclusterbom", "id01", "uid02", util.SecretValuesKey) != name, "name for other request")
+	assert.True(t, util.CreateSecretNameForRequest("testclusterbom", "id01", "uid01", "logical") != name, "name for other logical secret")
+	assert.True(t, strings.HasPrefix(name, "testclusterbom-id01-"), "name "+name)
+
+	assert.True(t, util.CreateSecretNameForRequest("testclusterbom", "id01", "", util.SecretValuesKey) !=
+		util.CreateSecretNameForRequest("testclusterbom", "id01", "", util.SecretValuesKey), "random name without request uid")
+}
+
+// TestReviewPersistsSecretValues tests that the secrets for secret values are created only for an allowed request
+// which is not a dry run.
+func TestReviewPersistsSecretValues(t *testing.T) {
+	tests := []struct {
+		name            string
+		dryRun          bool
+		invalid         bool
+		expectedAllowed bool
+		expectedCreates int
+	}{
+		{
+			name:            "allowed request",
+			expectedAllowed: true,
+			expectedCreates: 1,
+		},
+		{
+			name:            "dry run",
+			dryRun:          true,
+			expectedAllowed: true,
+		},
+		{
+			name:    "denied request",
+			invalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterBom := clusterBom01(t)
+			clusterBom.Spec.ApplicationConfigs[0].SecretValues = &hubv1.SecretValues{
+				Data: &runtime.RawExtension{Raw: []byte(`{"password":"secret"}`)},
+			}
+			if tt.invalid {
+				clusterBom.Spec.SecretRef = ""
+			}
+
+			secretClient := &secretClientMock{secrets: map[client.ObjectKey]*v1.Secret{}}
+			reviewer := buildReviewerFromClusterBom(t, &clusterBom)
+			reviewer.reader = secretClient
+			reviewer.requestReview.Request.DryRun = &tt.dryRun
+
+			responseReview := reviewer.review()
+
+			assert.Equal(t, responseReview.Response.Allowed, tt.expectedAllowed, "allowed")
+			assert.Equal(t, secretClient.creates, tt.expectedCreates, "number of creates")
+			if tt.expectedAllowed {
+				assert.True(t, strings.Contains(string(responseReview.Response.Patch), "internalSecretName"),
+					"patch "+string(responseReview.Response.Patch))
+			}
+		})
+	}
+}
+
+// secretClientMock keeps secrets in memory
+type secretClientMock struct {
+	readerMock
+	secrets map[client.ObjectKey]*v1.Secret
+	creates int
+	updates int
+}
+
+func (s *secretClientMock) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := s.secrets[key]; ok {
+		return errors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	s.secrets[key] = obj.(*v1.Secret).DeepCopy()
+	s.creates++
+	return nil
+}
+
+func (s *secretClientMock) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	s.secrets[client.ObjectKeyFromObject(obj)] = obj.(*v1.Secret).DeepCopy()
+	s.updates++
+	return nil
+}
+
+func (s *secretClientMock) GetUncached(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	secret, ok := s.secrets[key]
+	if !ok {
+		return errors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	secret.DeepCopyInto(obj.(*v1.Secret))
+	return nil
+}
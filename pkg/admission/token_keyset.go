@@ -0,0 +1,278 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+const (
+	// jwksMaxAge is the time after which the cached keys are fetched again, so that removed keys are no longer accepted
+	jwksMaxAge = time.Hour
+	// jwksMinRefreshInterval limits the fetches of the keys caused by tokens with an unknown key id
+	jwksMinRefreshInterval = 10 * time.Second
+
+	jwksFetchTimeout = 10 * time.Second
+	// jwksMaxResponseSize limits the size of a JWKS fetched from an endpoint
+	jwksMaxResponseSize = 1024 * 1024
+)
+
+// jwksKeySet implements oidc.KeySet with the signing keys from a JWKS file, a JWKS endpoint, or the JWKS endpoint
+// announced by the OIDC discovery document of the issuer. The keys are cached, and fetched again if a token has an
+// unknown key id, so that rotated signing keys are accepted without a restart. Concurrent refreshes are combined into
+// one fetch, which runs without holding the mutex, so that requests with known keys are not blocked by it.
+type jwksKeySet struct {
+	issuer     string
+	jwksURL    string
+	jwksFile   string
+	httpClient *http.Client
+	log        logr.Logger
+	now        func() time.Time
+
+	fetchGroup singleflight.Group
+
+	mutex       sync.Mutex
+	keys        []jose.JSONWebKey
+	lastRefresh time.Time
+	lastErr     error
+}
+
+func newJWKSKeySet(issuer, jwksURL, jwksFile string, log logr.Logger) *jwksKeySet {
+	return &jwksKeySet{
+		issuer:     issuer,
+		jwksURL:    jwksURL,
+		jwksFile:   jwksFile,
+		httpClient: &http.Client{Timeout: jwksFetchTimeout},
+		log:        log,
+		now:        time.Now,
+	}
+}
+
+// VerifySignature verifies the signature of the token with the key of its key id, and returns the payload
+func (k *jwksKeySet) VerifySignature(ctx context.Context, token string) ([]byte, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token")
+	}
+
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("token must have exactly one signature")
+	}
+	keyID := jws.Signatures[0].Header.KeyID
+
+	keys, err := k.getKeys(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		if keyID == "" || keys[i].KeyID == keyID {
+			if payload, err := jws.Verify(&keys[i]); err == nil {
+				return payload, nil
+			}
+		}
+	}
+
+	return nil, errors.Errorf("no valid signature with key id %q", keyID)
+}
+
+// getKeys returns the cached keys. They are fetched again if they are older than jwksMaxAge, or if they are missing or
+// the key id is unknown. In all cases, the keys are not fetched more often than jwksMinRefreshInterval.
+func (k *jwksKeySet) getKeys(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
+	k.mutex.Lock()
+	keys, lastErr := k.keys, k.lastErr
+	refresh := k.needsRefresh(keyID)
+	k.mutex.Unlock()
+
+	if !refresh {
+		if keys == nil {
+			return nil, errors.Wrap(lastErr, "signing keys not available")
+		}
+		return keys, nil
+	}
+
+	// the fetch is not bound to the context of the request, so that a cancelled request does not fail the fetch for
+	// the other requests waiting for it
+	result := k.fetchGroup.DoChan("keys", func() (interface{}, error) {
+		return k.refreshKeys()
+	})
+
+	select {
+	case <-ctx.Done():
+		if keys == nil {
+			return nil, ctx.Err()
+		}
+		return keys, nil
+	case r := <-result:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.([]jose.JSONWebKey), nil
+	}
+}
+
+// needsRefresh returns whether the keys must be fetched. The mutex must be held.
+func (k *jwksKeySet) needsRefresh(keyID string) bool {
+	age := k.now().Sub(k.lastRefresh)
+	if !k.lastRefresh.IsZero() && age <= jwksMinRefreshInterval {
+		return false
+	}
+
+	if k.keys == nil || age > jwksMaxAge {
+		return true
+	}
+
+	if keyID != "" && !containsKeyID(k.keys, keyID) {
+		k.log.V(util.LogLevelDebug).Info("webhook token review: unknown key id, refreshing keys", "kid", keyID)
+		return true
+	}
+
+	return false
+}
+
+// refreshKeys fetches the keys and stores them. If the fetch fails, the cached keys are kept, but the fetch is not
+// retried before jwksMinRefreshInterval.
+func (k *jwksKeySet) refreshKeys() ([]jose.JSONWebKey, error) {
+	k.mutex.Lock()
+	if !k.lastRefresh.IsZero() && k.now().Sub(k.lastRefresh) <= jwksMinRefreshInterval {
+		// another fetch finished after the caller decided to refresh
+		keys, lastErr := k.keys, k.lastErr
+		k.mutex.Unlock()
+		if keys == nil {
+			return nil, errors.Wrap(lastErr, "signing keys not available")
+		}
+		return keys, nil
+	}
+	k.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	keys, err := k.fetchKeys(ctx)
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.lastRefresh = k.now()
+	k.lastErr = err
+
+	if err != nil {
+		k.log.Error(err, "webhook token review: fetching signing keys failed")
+		if k.keys == nil {
+			return nil, err
+		}
+		return k.keys, nil
+	}
+
+	k.keys = keys
+	return k.keys, nil
+}
+
+func (k *jwksKeySet) fetchKeys(ctx context.Context) ([]jose.JSONWebKey, error) {
+	var data []byte
+	var err error
+
+	if k.jwksFile != "" {
+		// the file is read again for every refresh, because a mounted secret or config map is updated in place
+		data, err = ioutil.ReadFile(k.jwksFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read jwks file %s", k.jwksFile)
+		}
+	} else {
+		var jwksURL string
+		jwksURL, err = k.getJWKSURL(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = k.get(ctx, jwksURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not fetch jwks from %s", jwksURL)
+		}
+	}
+
+	keySet := jose.JSONWebKeySet{}
+	if err = json.Unmarshal(data, &keySet); err != nil {
+		return nil, errors.Wrap(err, "could not parse jwks")
+	}
+
+	if len(keySet.Keys) == 0 {
+		return nil, errors.New("jwks contains no keys")
+	}
+
+	return keySet.Keys, nil
+}
+
+// getJWKSURL returns the configured JWKS URL, or the one from the OIDC discovery document of the issuer
+func (k *jwksKeySet) getJWKSURL(ctx context.Context) (string, error) {
+	if k.jwksURL != "" {
+		return k.jwksURL, nil
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, k.httpClient), k.issuer)
+	if err != nil {
+		return "", errors.Wrap(err, "oidc discovery failed")
+	}
+
+	var discovery struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	if err = provider.Claims(&discovery); err != nil {
+		return "", errors.Wrap(err, "could not parse oidc discovery document")
+	}
+
+	if discovery.JWKSURL == "" {
+		return "", errors.New("oidc discovery document contains no jwks_uri")
+	}
+
+	return discovery.JWKSURL, nil
+}
+
+func (k *jwksKeySet) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, jwksMaxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > jwksMaxResponseSize {
+		return nil, errors.Errorf("response exceeds the maximal size of %d bytes", jwksMaxResponseSize)
+	}
+
+	return body, nil
+}
+
+func containsKeyID(keys []jose.JSONWebKey, keyID string) bool {
+	for i := range keys {
+		if keys[i].KeyID == keyID {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,11 @@ package admission
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gardener/potter-controller/pkg/util"
 
@@ -13,24 +16,48 @@ import (
 )
 
 const (
-	audienceMutatingWebhook = "mutating-webhook"
-	bearerPrefix            = "BEARER "
+	// DefaultTokenAudience is the audience which the webhook tokens must contain if no other audiences are configured
+	DefaultTokenAudience = "mutating-webhook"
+	// DefaultTokenClockSkew is the tolerated difference between the clocks of the token issuer and the controller
+	DefaultTokenClockSkew = time.Minute
+
+	bearerPrefix = "BEARER "
 )
 
-func newTokenReviewer(handler http.Handler, issuer string, log logr.Logger) *tokenReviewer {
+var supportedSigningAlgs = []string{oidc.RS256, oidc.RS384, oidc.RS512, oidc.ES256, oidc.ES384, oidc.ES512}
+
+func newTokenReviewer(handler http.Handler, config *AdmissionHookConfig, log logr.Logger) *tokenReviewer {
+	audiences := config.TokenAudiences
+	if len(audiences) == 0 {
+		audiences = []string{DefaultTokenAudience}
+	}
+
+	keySet := newJWKSKeySet(config.TokenIssuer, config.TokenJWKSURL, config.TokenJWKSFile, log)
+
 	return &tokenReviewer{
 		handler: handler,
-		issuer:  issuer,
-		log:     log,
+		// the expiry is checked by the reviewer with the tolerated clock skew, which the oidc verifier does not support
+		verifier: oidc.NewVerifier(config.TokenIssuer, keySet, &oidc.Config{
+			SupportedSigningAlgs: supportedSigningAlgs,
+			SkipClientIDCheck:    true,
+			SkipExpiryCheck:      true,
+		}),
+		audiences: audiences,
+		clockSkew: config.TokenClockSkew,
+		now:       time.Now,
+		log:       log,
 	}
 }
 
 // Implements http.Handler. Serves a request by first reviewing the bearer token
 // and then delegating to the given http handler.
 type tokenReviewer struct {
-	handler http.Handler
-	issuer  string
-	log     logr.Logger
+	handler   http.Handler
+	verifier  *oidc.IDTokenVerifier
+	audiences []string
+	clockSkew time.Duration
+	now       func() time.Time
+	log       logr.Logger
 }
 
 func (r *tokenReviewer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -40,7 +67,7 @@ func (r *tokenReviewer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = r.validateToken(token)
+	err = r.validateToken(req.Context(), token)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
@@ -70,30 +97,65 @@ func (r *tokenReviewer) getBearerToken(req *http.Request) (string, error) {
 	return "", errors.New("no bearer token found")
 }
 
-func (r *tokenReviewer) validateToken(token string) error {
-	ctx := context.Background()
-
-	provider, err := oidc.NewProvider(ctx, r.issuer)
+func (r *tokenReviewer) validateToken(ctx context.Context, token string) error {
+	// Verify signature and issuer of the token.
+	idToken, err := r.verifier.Verify(ctx, token)
 	if err != nil {
-		r.log.Error(err, "webhook token review: oidc provider initialization failed")
-		return errors.New("oidc provider initialization failed. " + err.Error())
+		r.log.Error(err, "webhook token review: invalid bearer token")
+		return errors.New("invalid bearer token: " + err.Error())
 	}
 
-	var verifier = provider.Verifier(&oidc.Config{
-		ClientID:             audienceMutatingWebhook,
-		SupportedSigningAlgs: []string{oidc.RS256, oidc.RS384, oidc.RS512},
-		SkipClientIDCheck:    false,
-		SkipExpiryCheck:      false,
-		SkipIssuerCheck:      false,
-		Now:                  nil,
-	})
-
-	// Parse and verify ID Token payload.
-	_, err = verifier.Verify(ctx, token)
-	if err != nil {
+	if err = r.validateAudience(idToken); err != nil {
+		r.log.Error(err, "webhook token review: invalid bearer token")
+		return errors.New("invalid bearer token: " + err.Error())
+	}
+
+	if err = r.validateTimes(idToken); err != nil {
 		r.log.Error(err, "webhook token review: invalid bearer token")
 		return errors.New("invalid bearer token: " + err.Error())
 	}
 
 	return nil
 }
+
+// validateAudience checks that the token contains one of the configured audiences
+func (r *tokenReviewer) validateAudience(idToken *oidc.IDToken) error {
+	for _, audience := range idToken.Audience {
+		if util.ContainsString(audience, r.audiences) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("expected one of the audiences %q, got %q", r.audiences, idToken.Audience)
+}
+
+// validateTimes checks the expiry and the not-before time of the token, tolerating the configured clock skew
+func (r *tokenReviewer) validateTimes(idToken *oidc.IDToken) error {
+	now := r.now()
+
+	if idToken.Expiry.IsZero() {
+		return errors.New("token has no expiry")
+	}
+
+	if now.Add(-r.clockSkew).After(idToken.Expiry) {
+		return fmt.Errorf("token is expired (expiry: %v)", idToken.Expiry)
+	}
+
+	// nbf is a NumericDate, which may have a fractional part
+	var claims struct {
+		NotBefore *float64 `json:"nbf"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return err
+	}
+
+	if claims.NotBefore != nil {
+		seconds, fraction := math.Modf(*claims.NotBefore)
+		notBefore := time.Unix(int64(seconds), int64(fraction*float64(time.Second)))
+		if now.Add(r.clockSkew).Before(notBefore) {
+			return fmt.Errorf("token is not yet valid (not before: %v)", notBefore)
+		}
+	}
+
+	return nil
+}
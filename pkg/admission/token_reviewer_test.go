@@ -0,0 +1,394 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"gopkg.in/square/go-jose.v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestTokenReviewer(t *testing.T) {
+	jwksServer := newTestJWKSServer(t)
+	defer jwksServer.Close()
+
+	signingKey := newTestSigningKey(t, "key01")
+	unknownKey := newTestSigningKey(t, "key02")
+	jwksServer.setKeys(signingKey)
+
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		key            *jose.JSONWebKey
+		claims         map[string]interface{}
+		noHeader       bool
+		expectedStatus int
+	}{
+		{
+			name:           "valid token",
+			key:            signingKey,
+			claims:         map[string]interface{}{"aud": DefaultTokenAudience},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token with other configured audience",
+			key:            signingKey,
+			claims:         map[string]interface{}{"aud": []string{"other", "potter"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token with wrong audience",
+			key:            signingKey,
+			claims:         map[string]interface{}{"aud": "other"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token of other issuer",
+			key:            signingKey,
+			claims:         map[string]interface{}{"iss": "https://other.example.com"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token expired within clock skew",
+			key:            signingKey,
+			claims:         map[string]interface{}{"exp": now.Add(-20 * time.Second).Unix()},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token expired beyond clock skew",
+			key:            signingKey,
+			claims:         map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token valid within clock skew",
+			key:            signingKey,
+			claims:         map[string]interface{}{"nbf": now.Add(20 * time.Second).Unix()},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token with fractional not-before time",
+			key:            signingKey,
+			claims:         map[string]interface{}{"nbf": float64(now.Add(-time.Minute).Unix()) + 0.5},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token with fractional not-before time in the future",
+			key:            signingKey,
+			claims:         map[string]interface{}{"nbf": float64(now.Add(2*time.Minute).Unix()) + 0.5},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token not yet valid",
+			key:            signingKey,
+			claims:         map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token signed with unknown key",
+			key:            unknownKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no token",
+			noHeader:       true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, jwksURL := range []string{"", jwksServer.URL + "/keys"} {
+		reviewer := newTokenReviewer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}), &AdmissionHookConfig{
+			TokenIssuer:    jwksServer.URL,
+			TokenJWKSURL:   jwksURL,
+			TokenAudiences: []string{DefaultTokenAudience, "potter"},
+			TokenClockSkew: 30 * time.Second,
+		}, ctrl.Log.WithName("Token Reviewer Test"))
+		reviewer.now = func() time.Time { return now }
+
+		for _, tt := range tests {
+			t.Run(tt.name+" jwksURL="+jwksURL, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/checkClusterBom", nil)
+				if !tt.noHeader {
+					claims := map[string]interface{}{
+						"iss": jwksServer.URL,
+						"aud": DefaultTokenAudience,
+						"exp": now.Add(time.Hour).Unix(),
+					}
+					for key, value := range tt.claims {
+						claims[key] = value
+					}
+					req.Header.Set("Authorization", "Bearer "+signTestToken(t, tt.key, claims))
+				}
+
+				recorder := httptest.NewRecorder()
+				reviewer.ServeHTTP(recorder, req)
+				assert.Equal(t, recorder.Code, tt.expectedStatus, "status code")
+			})
+		}
+	}
+}
+
+// TestJWKSKeySetRefresh tests that the keys are fetched again for an unknown key id, so that rotated keys are accepted
+// without a restart, and that the fetches are limited.
+func TestJWKSKeySetRefresh(t *testing.T) {
+	jwksServer := newTestJWKSServer(t)
+	defer jwksServer.Close()
+
+	oldKey := newTestSigningKey(t, "old")
+	newKey := newTestSigningKey(t, "new")
+	jwksServer.setKeys(oldKey)
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	keySet := newJWKSKeySet(jwksServer.URL, jwksServer.URL+"/keys", "", ctrl.Log.WithName("Token Reviewer Test"))
+	keySet.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := keySet.VerifySignature(ctx, signTestToken(t, oldKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, oldKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+	assert.Equal(t, jwksServer.getFetches(), 1, "number of fetches with cached key")
+
+	// the signing key is rotated
+	jwksServer.setKeys(oldKey, newKey)
+
+	// an unknown key id is fetched again, but not more often than jwksMinRefreshInterval
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, newKey, map[string]interface{}{}))
+	assert.NotNil(t, err, "error for unknown key within the minimal refresh interval")
+	assert.Equal(t, jwksServer.getFetches(), 1, "number of fetches within the minimal refresh interval")
+
+	now = now.Add(jwksMinRefreshInterval + time.Second)
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, newKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+	assert.Equal(t, jwksServer.getFetches(), 2, "number of fetches after the rotation")
+
+	// the old key is removed and no longer accepted when the cache expires
+	jwksServer.setKeys(newKey)
+	now = now.Add(jwksMaxAge + time.Second)
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, oldKey, map[string]interface{}{}))
+	assert.NotNil(t, err, "error for removed key")
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, newKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+
+	// the cached keys are used if the endpoint fails
+	jwksServer.setKeys()
+	now = now.Add(jwksMaxAge + time.Second)
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, newKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+}
+
+// TestJWKSKeySetConcurrentRefresh tests that concurrent requests with an unknown key id cause only one fetch, and that
+// a request whose context is done does not wait for the fetch.
+func TestJWKSKeySetConcurrentRefresh(t *testing.T) {
+	jwksServer := newTestJWKSServer(t)
+	defer jwksServer.Close()
+
+	signingKey := newTestSigningKey(t, "key01")
+	jwksServer.setKeys(signingKey)
+	jwksServer.setDelay(100 * time.Millisecond)
+
+	keySet := newJWKSKeySet(jwksServer.URL, jwksServer.URL+"/keys", "", ctrl.Log.WithName("Token Reviewer Test"))
+	token := signTestToken(t, signingKey, map[string]interface{}{})
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := keySet.VerifySignature(cancelledCtx, token)
+	assert.NotNil(t, err, "error for cancelled request without cached keys")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := keySet.VerifySignature(context.Background(), token)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoErr(t, err)
+	}
+	assert.Equal(t, jwksServer.getFetches(), 1, "number of fetches")
+}
+
+// TestJWKSKeySetUnavailable tests that the fetches are limited by jwksMinRefreshInterval also if no keys are cached
+func TestJWKSKeySetUnavailable(t *testing.T) {
+	jwksServer := newTestJWKSServer(t)
+	defer jwksServer.Close()
+
+	signingKey := newTestSigningKey(t, "key01")
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	keySet := newJWKSKeySet(jwksServer.URL, jwksServer.URL+"/keys", "", ctrl.Log.WithName("Token Reviewer Test"))
+	keySet.now = func() time.Time { return now }
+	ctx := context.Background()
+	token := signTestToken(t, signingKey, map[string]interface{}{})
+
+	for i := 0; i < 3; i++ {
+		_, err := keySet.VerifySignature(ctx, token)
+		assert.NotNil(t, err, "error without keys")
+	}
+	assert.Equal(t, jwksServer.getFetches(), 1, "number of fetches within the minimal refresh interval")
+
+	jwksServer.setKeys(signingKey)
+	now = now.Add(jwksMinRefreshInterval + time.Second)
+	_, err := keySet.VerifySignature(ctx, token)
+	assert.NoErr(t, err)
+	assert.Equal(t, jwksServer.getFetches(), 2, "number of fetches after the minimal refresh interval")
+}
+
+// TestJWKSKeySetResponseTooLarge tests that a JWKS endpoint cannot make the webhook read an unbounded response
+func TestJWKSKeySetResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte(" "), jwksMaxResponseSize+1))
+	}))
+	defer server.Close()
+
+	keySet := newJWKSKeySet(server.URL, server.URL+"/keys", "", ctrl.Log.WithName("Token Reviewer Test"))
+
+	_, err := keySet.get(context.Background(), server.URL+"/keys")
+	assert.NotNil(t, err, "error for too large response")
+}
+
+func TestJWKSKeySetFromFile(t *testing.T) {
+	oldKey := newTestSigningKey(t, "old")
+	newKey := newTestSigningKey(t, "new")
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeTestJWKSFile(t, jwksFile, oldKey)
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	keySet := newJWKSKeySet("https://issuer.example.com", "", jwksFile, ctrl.Log.WithName("Token Reviewer Test"))
+	keySet.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := keySet.VerifySignature(ctx, signTestToken(t, oldKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+
+	// the mounted file is updated with the rotated key
+	writeTestJWKSFile(t, jwksFile, newKey)
+	now = now.Add(jwksMinRefreshInterval + time.Second)
+
+	_, err = keySet.VerifySignature(ctx, signTestToken(t, newKey, map[string]interface{}{}))
+	assert.NoErr(t, err)
+}
+
+// testJWKSServer serves an OIDC discovery document and a JWKS with changeable keys
+type testJWKSServer struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	keys    []jose.JSONWebKey
+	fetches int
+	delay   time.Duration
+}
+
+func newTestJWKSServer(t *testing.T) *testJWKSServer {
+	s := &testJWKSServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		writeTestJSON(t, w, map[string]string{
+			"issuer":   s.URL,
+			"jwks_uri": s.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, req *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		time.Sleep(s.delay)
+		s.fetches++
+		if len(s.keys) == 0 {
+			http.Error(w, "no keys", http.StatusServiceUnavailable)
+			return
+		}
+		writeTestJSON(t, w, jose.JSONWebKeySet{Keys: s.keys})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// setKeys sets the public keys of the given signing keys
+func (s *testJWKSServer) setKeys(signingKeys ...*jose.JSONWebKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys = nil
+	for _, signingKey := range signingKeys {
+		s.keys = append(s.keys, signingKey.Public())
+	}
+}
+
+func (s *testJWKSServer) setDelay(delay time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delay = delay
+}
+
+func (s *testJWKSServer) getFetches() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.fetches
+}
+
+func newTestSigningKey(t *testing.T, keyID string) *jose.JSONWebKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoErr(t, err)
+
+	return &jose.JSONWebKey{
+		Key:       privateKey,
+		KeyID:     keyID,
+		Algorithm: string(jose.ES256),
+		Use:       "sig",
+	}
+}
+
+func signTestToken(t *testing.T, signingKey *jose.JSONWebKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: signingKey}, nil)
+	assert.NoErr(t, err)
+
+	payload, err := json.Marshal(claims)
+	assert.NoErr(t, err)
+
+	jws, err := signer.Sign(payload)
+	assert.NoErr(t, err)
+
+	token, err := jws.CompactSerialize()
+	assert.NoErr(t, err)
+	return token
+}
+
+func writeTestJWKSFile(t *testing.T, path string, signingKeys ...*jose.JSONWebKey) {
+	keySet := jose.JSONWebKeySet{}
+	for _, signingKey := range signingKeys {
+		keySet.Keys = append(keySet.Keys, signingKey.Public())
+	}
+
+	data, err := json.Marshal(keySet)
+	assert.NoErr(t, err)
+	err = ioutil.WriteFile(path, data, 0600)
+	assert.NoErr(t, err)
+}
+
+func writeTestJSON(t *testing.T, w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	assert.NoErr(t, err)
+}
@@ -0,0 +1,145 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// names of the backends
+const (
+	BackendTCP     = "tcp"
+	BackendFile    = "file"
+	BackendStdout  = "stdout"
+	BackendWebhook = "webhook"
+)
+
+const (
+	defaultTCPAddress       = ":10520"
+	defaultSpoolDir         = "/var/spool/potter-auditlog"
+	defaultSpoolMaxMessages = 10000
+	defaultFileMaxSizeMB    = 100
+	defaultFileMaxBackups   = 5
+)
+
+// Backend delivers audit records to an audit log sink.
+type Backend interface {
+	Name() string
+	// Send delivers a record. A record is sent again if Send returns an error, so that sinks can receive a record
+	// more than once. They can use the id of the record to recognize duplicates.
+	Send(ctx context.Context, record *Record) error
+	Close() error
+}
+
+// Record is an audit message as it is stored in the spool and sent to the backends
+type Record struct {
+	// ID identifies the record
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Action is the action of the message as readable string
+	Action  string            `json:"action"`
+	Message *AuditMessageInfo `json:"message"`
+}
+
+// Config selects and configures the backend of the audit log.
+type Config struct {
+	// Backend is one of tcp, file, stdout and webhook. Audit logging is disabled if the backend is empty.
+	Backend string
+
+	// TCPAddress is the address of the audit log sidecar of the tcp backend
+	TCPAddress string
+
+	// FilePath is the path of the file of the file backend. The file is rotated when it exceeds FileMaxSizeMB;
+	// FileMaxBackups rotated files are kept.
+	FilePath       string
+	FileMaxSizeMB  int
+	FileMaxBackups int
+
+	// WebhookURL is the url to which the webhook backend posts the records. WebhookCAFile is an optional PEM file
+	// with the CA of the webhook. WebhookTokenFile is an optional file with a bearer token for the webhook.
+	WebhookURL       string
+	WebhookCAFile    string
+	WebhookTokenFile string
+
+	// SpoolDir is the directory in which audit messages are buffered until they are delivered. At most
+	// SpoolMaxMessages messages are buffered; further messages are dropped.
+	SpoolDir         string
+	SpoolMaxMessages int
+}
+
+// IsEnabled returns whether a backend is configured.
+func (c *Config) IsEnabled() bool {
+	return c != nil && c.Backend != ""
+}
+
+// NewAuditLogger creates an audit logger which buffers the messages in a spool on disk and delivers them
+// asynchronously to the configured backend.
+func NewAuditLogger(config *Config, log logr.Logger) (AuditLogger, error) {
+	backend, err := newBackend(config, log)
+	if err != nil {
+		return nil, err
+	}
+
+	spoolDir := config.SpoolDir
+	if spoolDir == "" {
+		spoolDir = defaultSpoolDir
+	}
+
+	spoolMaxMessages := config.SpoolMaxMessages
+	if spoolMaxMessages <= 0 {
+		spoolMaxMessages = defaultSpoolMaxMessages
+	}
+
+	spool, err := openSpool(spoolDir, spoolMaxMessages)
+	if err != nil {
+		_ = backend.Close()
+		return nil, err
+	}
+
+	log.Info("Audit logging is enabled", "backend", backend.Name(), "spoolDir", spoolDir,
+		"pendingMessages", spool.len())
+
+	return newSpoolingAuditLogger(spool, backend, log), nil
+}
+
+func newBackend(config *Config, log logr.Logger) (Backend, error) {
+	switch config.Backend {
+	case BackendTCP:
+		address := config.TCPAddress
+		if address == "" {
+			address = defaultTCPAddress
+		}
+		return newTCPBackend(address, log), nil
+
+	case BackendFile:
+		if config.FilePath == "" {
+			return nil, fmt.Errorf("audit log backend %s requires a file path", BackendFile)
+		}
+
+		maxSizeMB := config.FileMaxSizeMB
+		if maxSizeMB <= 0 {
+			maxSizeMB = defaultFileMaxSizeMB
+		}
+
+		maxBackups := config.FileMaxBackups
+		if maxBackups < 0 {
+			maxBackups = defaultFileMaxBackups
+		}
+
+		return newFileBackend(config.FilePath, int64(maxSizeMB)*1024*1024, maxBackups)
+
+	case BackendStdout:
+		return newStdoutBackend(), nil
+
+	case BackendWebhook:
+		if config.WebhookURL == "" {
+			return nil, fmt.Errorf("audit log backend %s requires a url", BackendWebhook)
+		}
+		return newWebhookBackend(config.WebhookURL, config.WebhookCAFile, config.WebhookTokenFile)
+
+	default:
+		return nil, fmt.Errorf("unknown audit log backend %s", config.Backend)
+	}
+}
@@ -0,0 +1,102 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+)
+
+func TestFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+
+	line, err := marshalLine(newTestRecord("record-1"))
+	assert.NoErr(t, err)
+
+	// the file is rotated after every second record
+	backend, err := newFileBackend(path, int64(2*len(line)), 1)
+	assert.NoErr(t, err)
+
+	for _, id := range []string{"record-1", "record-2", "record-3", "record-4", "record-5"} {
+		assert.NoErr(t, backend.Send(context.Background(), newTestRecord(id)))
+	}
+	assert.NoErr(t, backend.Close())
+
+	assert.Equal(t, readRecordIDs(t, path), []string{"record-5"}, "records in current file")
+	assert.Equal(t, readRecordIDs(t, path+".1"), []string{"record-3", "record-4"}, "records in backup")
+
+	_, err = os.Stat(path + ".2")
+	assert.True(t, os.IsNotExist(err), "only one backup is kept")
+}
+
+func TestWebhookBackend(t *testing.T) {
+	tokenFile, err := ioutil.TempFile("", "token")
+	assert.NoErr(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, err = tokenFile.WriteString("test-token\n")
+	assert.NoErr(t, err)
+	assert.NoErr(t, tokenFile.Close())
+
+	status := http.StatusServiceUnavailable
+	var received Record
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		_ = json.NewDecoder(req.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	backend, err := newWebhookBackend(server.URL, "", tokenFile.Name())
+	assert.NoErr(t, err)
+
+	err = backend.Send(context.Background(), newTestRecord("record-1"))
+	assert.True(t, err != nil, "error if the webhook is not available")
+
+	status = http.StatusOK
+	assert.NoErr(t, backend.Send(context.Background(), newTestRecord("record-1")))
+	assert.Equal(t, received.ID, "record-1", "id of received record")
+	assert.Equal(t, received.Message.ProjectName, "test-project", "project of received record")
+	assert.Equal(t, authorization, "Bearer test-token", "authorization header")
+}
+
+func TestNewBackend(t *testing.T) {
+	_, err := newBackend(&Config{Backend: BackendFile}, nil)
+	assert.True(t, err != nil, "file backend requires a path")
+
+	_, err = newBackend(&Config{Backend: BackendWebhook}, nil)
+	assert.True(t, err != nil, "webhook backend requires a url")
+
+	_, err = newBackend(&Config{Backend: "unknown"}, nil)
+	assert.True(t, err != nil, "unknown backend")
+
+	backend, err := newBackend(&Config{Backend: BackendStdout}, nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, backend.Name(), BackendStdout, "name of backend")
+}
+
+func readRecordIDs(t *testing.T, path string) []string {
+	data, err := ioutil.ReadFile(path)
+	assert.NoErr(t, err)
+
+	ids := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		record := Record{}
+		assert.NoErr(t, json.Unmarshal([]byte(line), &record))
+		ids = append(ids, record.ID)
+	}
+
+	return ids
+}
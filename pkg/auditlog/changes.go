@@ -0,0 +1,243 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+
+	redactedValue = `"<redacted>"`
+)
+
+// sensitiveKeyParts are parts of field names, whose values are redacted in the changes, because they might contain
+// credentials, e.g. the authHeader of a helm chart repository
+var sensitiveKeyParts = []string{"password", "secret", "token", "authheader", "credential", "privatekey", "apikey"}
+
+// Requester is the user who created or last changed the spec of a clusterbom, as reported by the admission request
+type Requester struct {
+	Username  string              `json:"username"`
+	UID       string              `json:"uid,omitempty"`
+	Groups    []string            `json:"groups,omitempty"`
+	Extra     map[string][]string `json:"extra,omitempty"`
+	Operation string              `json:"operation,omitempty"`
+	Time      time.Time           `json:"time"`
+}
+
+// AppConfigChange describes how an application config of a clusterbom differs from the deployed configuration
+type AppConfigChange struct {
+	ID         string        `json:"id"`
+	ConfigType string        `json:"configType"`
+	Change     string        `json:"change"`
+	Fields     []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange is the change of a single field of an application config. Old and New contain the compact JSON
+// encoding of the values, and are empty if the field did not exist before or does not exist anymore.
+type FieldChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// auditedConfig contains the fields of a deployment config which are taken from the application config. Secret values
+// are represented by the names of the internal secrets, so that a change of the values is detected without revealing
+// them.
+type auditedConfig struct {
+	ConfigType        string                  `json:"configType,omitempty"`
+	TypeSpecificData  *runtime.RawExtension   `json:"typeSpecificData,omitempty"`
+	Values            *runtime.RawExtension   `json:"values,omitempty"`
+	SecretValues      string                  `json:"secretValues,omitempty"`
+	NamedSecretValues map[string]string       `json:"namedSecretValues,omitempty"`
+	NoReconcile       bool                    `json:"noReconcile,omitempty"`
+	ReadyRequirements hubv1.ReadyRequirements `json:"readyRequirements,omitempty"`
+	RetryPolicy       *hubv1.RetryPolicy      `json:"retryPolicy,omitempty"`
+}
+
+// DiffAppConfig compares the old and the new deployment config of an application config. An old config of nil means
+// that the application config was added, a new config of nil that it was removed. The result is nil if nothing
+// has changed. The values of secret values and of fields with sensitive names are redacted.
+func DiffAppConfig(oldConfigType string, oldConfig *hubv1.DeploymentConfig, newConfigType string,
+	newConfig *hubv1.DeploymentConfig) (*AppConfigChange, error) {
+	if oldConfig == nil && newConfig == nil {
+		return nil, nil
+	}
+
+	oldFields, err := toAuditedFields(oldConfigType, oldConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := toAuditedFields(newConfigType, newConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &AppConfigChange{Fields: []FieldChange{}}
+	switch {
+	case oldConfig == nil:
+		change.ID, change.ConfigType, change.Change = newConfig.ID, newConfigType, ChangeAdded
+	case newConfig == nil:
+		change.ID, change.ConfigType, change.Change = oldConfig.ID, oldConfigType, ChangeRemoved
+	default:
+		change.ID, change.ConfigType, change.Change = newConfig.ID, newConfigType, ChangeModified
+	}
+
+	diffValues(&change.Fields, "", oldFields, newFields, false)
+	if change.Change == ChangeModified && len(change.Fields) == 0 {
+		return nil, nil
+	}
+
+	return change, nil
+}
+
+func toAuditedFields(configType string, config *hubv1.DeploymentConfig) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if config == nil {
+		return fields, nil
+	}
+
+	audited := auditedConfig{
+		ConfigType:        configType,
+		Values:            config.Values,
+		SecretValues:      config.InternalSecretName,
+		NamedSecretValues: config.NamedInternalSecretNames,
+		NoReconcile:       config.NoReconcile,
+		ReadyRequirements: config.ReadyRequirements,
+		RetryPolicy:       config.RetryPolicy,
+	}
+
+	if len(config.TypeSpecificData.Raw) > 0 {
+		audited.TypeSpecificData = &config.TypeSpecificData
+	}
+
+	if audited.Values != nil && len(audited.Values.Raw) == 0 {
+		audited.Values = nil
+	}
+
+	data, err := json.Marshal(&audited)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// diffValues appends the changes between two values to the list of field changes. Objects are compared field by
+// field and lists element by element, with the index as path segment, so that the redaction of sensitive fields also
+// applies to objects in lists. All other values are compared as a whole.
+func diffValues(changes *[]FieldChange, path string, oldValue, newValue interface{}, redact bool) {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	oldList, oldIsList := oldValue.([]interface{})
+	newList, newIsList := newValue.([]interface{})
+
+	if oldIsMap && newIsMap || oldIsMap && newValue == nil || oldValue == nil && newIsMap {
+		keySet := map[string]bool{}
+		for key := range oldMap {
+			keySet[key] = true
+		}
+		for key := range newMap {
+			keySet[key] = true
+		}
+
+		// the changes are sorted by the keys and the list indexes
+		keys := make([]string, 0, len(keySet))
+		for key := range keySet {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			diffValues(changes, joinPath(path, key), oldMap[key], newMap[key], redact || isRedactedField(path, key))
+		}
+
+		return
+	}
+
+	if oldIsList && newIsList || oldIsList && newValue == nil || oldValue == nil && newIsList {
+		length := len(oldList)
+		if len(newList) > length {
+			length = len(newList)
+		}
+
+		for i := 0; i < length; i++ {
+			diffValues(changes, path+"["+strconv.Itoa(i)+"]", listElement(oldList, i), listElement(newList, i), redact)
+		}
+
+		return
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+
+	*changes = append(*changes, FieldChange{
+		Path: path,
+		Old:  encodeValue(oldValue, redact),
+		New:  encodeValue(newValue, redact),
+	})
+}
+
+func listElement(list []interface{}, i int) interface{} {
+	if i >= len(list) {
+		return nil
+	}
+
+	return list[i]
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func isRedactedField(path, key string) bool {
+	if path == "" && (key == "secretValues" || key == "namedSecretValues") {
+		return true
+	}
+
+	lowerKey := strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(lowerKey, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encodeValue(value interface{}, redact bool) string {
+	if value == nil {
+		return ""
+	}
+
+	if redact {
+		return redactedValue
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
@@ -0,0 +1,119 @@
+package auditlog
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+)
+
+func TestDiffAppConfigModified(t *testing.T) {
+	oldConfig := &hubv1.DeploymentConfig{
+		ID:                 "app1",
+		TypeSpecificData:   runtime.RawExtension{Raw: []byte(`{"chartName":"redis","version":"1.0.0","authHeader":"Basic old"}`)},
+		Values:             &runtime.RawExtension{Raw: []byte(`{"replicas":1,"tags":["a"]}`)},
+		InternalSecretName: "secret-1",
+		NamedInternalSecretNames: map[string]string{
+			"creds": "named-secret-1",
+		},
+	}
+
+	newConfig := &hubv1.DeploymentConfig{
+		ID:                 "app1",
+		TypeSpecificData:   runtime.RawExtension{Raw: []byte(`{"chartName":"redis","version":"1.1.0","authHeader":"Basic new"}`)},
+		Values:             &runtime.RawExtension{Raw: []byte(`{"replicas":1,"tags":["a","b"],"dbPassword":"secret"}`)},
+		InternalSecretName: "secret-2",
+		NamedInternalSecretNames: map[string]string{
+			"creds": "named-secret-1",
+		},
+		NoReconcile: true,
+	}
+
+	change, err := DiffAppConfig("helm", oldConfig, "helm", newConfig)
+	assert.NoErr(t, err)
+	assert.Equal(t, change.ID, "app1", "id")
+	assert.Equal(t, change.ConfigType, "helm", "config type")
+	assert.Equal(t, change.Change, ChangeModified, "change")
+	assert.Equal(t, change.Fields, []FieldChange{
+		{Path: "noReconcile", New: "true"},
+		{Path: "secretValues", Old: redactedValue, New: redactedValue},
+		{Path: "typeSpecificData.authHeader", Old: redactedValue, New: redactedValue},
+		{Path: "typeSpecificData.version", Old: `"1.0.0"`, New: `"1.1.0"`},
+		{Path: "values.dbPassword", New: redactedValue},
+		{Path: "values.tags[1]", New: `"b"`},
+	}, "fields")
+
+	change, err = DiffAppConfig("helm", oldConfig, "helm", oldConfig.DeepCopy())
+	assert.NoErr(t, err)
+	assert.True(t, change == nil, "no change of an unchanged config")
+}
+
+func TestDiffAppConfigLists(t *testing.T) {
+	oldConfig := &hubv1.DeploymentConfig{
+		ID: "app1",
+		Values: &runtime.RawExtension{Raw: []byte(
+			`{"users":[{"name":"a","password":"old"},{"name":"b","password":"pw"}],"hosts":["x","y","z"]}`)},
+	}
+
+	newConfig := &hubv1.DeploymentConfig{
+		ID: "app1",
+		Values: &runtime.RawExtension{Raw: []byte(
+			`{"users":[{"name":"a","password":"new"},{"name":"c","password":"pw"}],"hosts":["x","y"],"secrets":["s1"]}`)},
+	}
+
+	change, err := DiffAppConfig("helm", oldConfig, "helm", newConfig)
+	assert.NoErr(t, err)
+	assert.Equal(t, change.Fields, []FieldChange{
+		{Path: "values.hosts[2]", Old: `"z"`},
+		{Path: "values.secrets[0]", New: redactedValue},
+		{Path: "values.users[0].password", Old: redactedValue, New: redactedValue},
+		{Path: "values.users[1].name", Old: `"b"`, New: `"c"`},
+	}, "fields")
+}
+
+func TestDiffAppConfigRedactsSecretFields(t *testing.T) {
+	oldConfig := &hubv1.DeploymentConfig{
+		ID:     "app1",
+		Values: &runtime.RawExtension{Raw: []byte(`{"clientSecret":"old","tls":{"secretKey":"k1"}}`)},
+	}
+
+	newConfig := &hubv1.DeploymentConfig{
+		ID:     "app1",
+		Values: &runtime.RawExtension{Raw: []byte(`{"clientSecret":"new","tls":{"secretKey":"k2"}}`)},
+	}
+
+	change, err := DiffAppConfig("helm", oldConfig, "helm", newConfig)
+	assert.NoErr(t, err)
+	assert.Equal(t, change.Fields, []FieldChange{
+		{Path: "values.clientSecret", Old: redactedValue, New: redactedValue},
+		{Path: "values.tls.secretKey", Old: redactedValue, New: redactedValue},
+	}, "fields")
+}
+
+func TestDiffAppConfigAddedAndRemoved(t *testing.T) {
+	config := &hubv1.DeploymentConfig{
+		ID:               "app1",
+		TypeSpecificData: runtime.RawExtension{Raw: []byte(`{"version":"1.0.0"}`)},
+		NamedInternalSecretNames: map[string]string{
+			"creds": "named-secret-1",
+		},
+	}
+
+	change, err := DiffAppConfig("", nil, "helm", config)
+	assert.NoErr(t, err)
+	assert.Equal(t, change.Change, ChangeAdded, "change")
+	assert.Equal(t, change.Fields, []FieldChange{
+		{Path: "configType", New: `"helm"`},
+		{Path: "namedSecretValues.creds", New: redactedValue},
+		{Path: "typeSpecificData.version", New: `"1.0.0"`},
+	}, "fields of added config")
+
+	change, err = DiffAppConfig("helm", config, "", nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, change.ID, "app1", "id")
+	assert.Equal(t, change.Change, ChangeRemoved, "change")
+	assert.Equal(t, len(change.Fields), 3, "number of fields of removed config")
+	assert.Equal(t, change.Fields[0], FieldChange{Path: "configType", Old: `"helm"`}, "config type of removed config")
+}
@@ -0,0 +1,171 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// fileBackend writes the records as JSON lines into a file. The file is rotated when it would exceed the maximal
+// size: file.log is renamed to file.log.1, file.log.1 to file.log.2 and so on, and the oldest backup is removed.
+type fileBackend struct {
+	mutex      sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileBackend(path string, maxSize int64, maxBackups int) (*fileBackend, error) {
+	b := &fileBackend{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *fileBackend) Name() string {
+	return BackendFile
+}
+
+func (b *fileBackend) Send(_ context.Context, record *Record) error {
+	line, err := marshalLine(record)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.file == nil {
+		if err = b.open(); err != nil {
+			return err
+		}
+	}
+
+	if b.size > 0 && b.size+int64(len(line)) > b.maxSize {
+		if err = b.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := b.file.Write(line)
+	b.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "could not write audit log file")
+	}
+
+	return errors.Wrap(b.file.Sync(), "could not sync audit log file")
+}
+
+func (b *fileBackend) open() error {
+	file, err := os.OpenFile(b.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "could not open audit log file")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "could not read size of audit log file")
+	}
+
+	b.file = file
+	b.size = info.Size()
+	return nil
+}
+
+func (b *fileBackend) rotate() error {
+	if err := b.file.Close(); err != nil {
+		return errors.Wrap(err, "could not close audit log file")
+	}
+	b.file = nil
+
+	if b.maxBackups == 0 {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "could not remove audit log file")
+		}
+		return b.open()
+	}
+
+	for i := b.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(b.backupPath(i), b.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "could not rotate audit log file")
+		}
+	}
+
+	if err := os.Rename(b.path, b.backupPath(1)); err != nil {
+		return errors.Wrap(err, "could not rotate audit log file")
+	}
+
+	return b.open()
+}
+
+func (b *fileBackend) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", b.path, i)
+}
+
+func (b *fileBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.file == nil {
+		return nil
+	}
+
+	err := b.file.Close()
+	b.file = nil
+	return err
+}
+
+// stdoutBackend writes the records as JSON lines to stdout, e.g. to be collected by the log infrastructure
+type stdoutBackend struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func newStdoutBackend() *stdoutBackend {
+	return &stdoutBackend{writer: os.Stdout}
+}
+
+func (b *stdoutBackend) Name() string {
+	return BackendStdout
+}
+
+func (b *stdoutBackend) Send(_ context.Context, record *Record) error {
+	line, err := marshalLine(record)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_, err = b.writer.Write(line)
+	return errors.Wrap(err, "could not write audit record to stdout")
+}
+
+func (b *stdoutBackend) Close() error {
+	return nil
+}
+
+func marshalLine(record *Record) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal audit record")
+	}
+
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,189 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	spoolFileSuffix = ".json"
+	spoolTempPrefix = ".tmp-"
+)
+
+// errSpoolFull is returned if a record cannot be spooled, because the spool contains the maximal number of records
+var errSpoolFull = errors.New("audit log spool is full")
+
+// spool is a bounded queue of records on disk. Every record is stored in its own file, whose name is a sequence
+// number, so that the records are delivered in the order in which they were added. The records remain in the spool
+// until they are removed after their delivery, so that they survive restarts of the controller.
+type spool struct {
+	mutex       sync.Mutex
+	dir         string
+	maxMessages int
+	nextSeq     uint64
+	entries     []string
+}
+
+func openSpool(dir string, maxMessages int) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "could not create audit log spool directory")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read audit log spool directory")
+	}
+
+	s := &spool{
+		dir:         dir,
+		maxMessages: maxMessages,
+		nextSeq:     1,
+	}
+
+	for _, file := range files {
+		name := file.Name()
+
+		// a temporary file is a record which was not completely written before a restart
+		if strings.HasPrefix(name, spoolTempPrefix) {
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+
+		seq, ok := parseSpoolFileName(name)
+		if !ok {
+			continue
+		}
+
+		s.entries = append(s.entries, name)
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+
+	// the names have a fixed length, so that the lexical order is the order of the sequence numbers
+	sort.Strings(s.entries)
+	return s, nil
+}
+
+// put adds a record to the spool. The record is written to a temporary file, which is renamed when it is complete.
+func (s *spool) put(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal audit record")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.entries) >= s.maxMessages {
+		return errSpoolFull
+	}
+
+	name := fmt.Sprintf("%020d%s", s.nextSeq, spoolFileSuffix)
+	tempPath := filepath.Join(s.dir, spoolTempPrefix+name)
+
+	if err = writeFileSync(tempPath, data); err != nil {
+		_ = os.Remove(tempPath)
+		return errors.Wrap(err, "could not write audit record to spool")
+	}
+
+	if err = os.Rename(tempPath, filepath.Join(s.dir, name)); err != nil {
+		_ = os.Remove(tempPath)
+		return errors.Wrap(err, "could not write audit record to spool")
+	}
+
+	s.nextSeq++
+	s.entries = append(s.entries, name)
+	return nil
+}
+
+// peek returns the name of the oldest record, or false if the spool is empty
+func (s *spool) peek() (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.entries) == 0 {
+		return "", false
+	}
+
+	return s.entries[0], true
+}
+
+func (s *spool) read(name string) (*Record, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read audit record from spool")
+	}
+
+	record := &Record{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal audit record from spool")
+	}
+
+	return record, nil
+}
+
+// remove removes the oldest record, which must have the given name
+func (s *spool) remove(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.entries) == 0 || s.entries[0] != name {
+		return fmt.Errorf("audit record %s is not the oldest record of the spool", name)
+	}
+
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "could not remove audit record from spool")
+	}
+
+	s.entries = s.entries[1:]
+	return nil
+}
+
+func (s *spool) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.entries)
+}
+
+func parseSpoolFileName(name string) (uint64, bool) {
+	if !strings.HasSuffix(name, spoolFileSuffix) {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileSuffix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return seq, true
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
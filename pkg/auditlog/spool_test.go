@@ -0,0 +1,177 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := openSpool(dir, 2)
+	assert.NoErr(t, err)
+
+	assert.NoErr(t, s.put(newTestRecord("record-1")))
+	assert.NoErr(t, s.put(newTestRecord("record-2")))
+	assert.Equal(t, s.put(newTestRecord("record-3")), errSpoolFull, "error of full spool")
+
+	// an incomplete record is removed, and the remaining records survive a restart in their order
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, spoolTempPrefix+"incomplete.json"), []byte("{"), 0600))
+
+	s, err = openSpool(dir, 2)
+	assert.NoErr(t, err)
+	assert.Equal(t, s.len(), 2, "number of records")
+
+	name, ok := s.peek()
+	assert.True(t, ok, "spool has records")
+	record, err := s.read(name)
+	assert.NoErr(t, err)
+	assert.Equal(t, record.ID, "record-1", "id of oldest record")
+	assert.Equal(t, record.Message.ClusterBOM, "test-bom", "clusterbom of record")
+
+	assert.NoErr(t, s.remove(name))
+	assert.NoErr(t, s.put(newTestRecord("record-3")))
+
+	name, _ = s.peek()
+	record, err = s.read(name)
+	assert.NoErr(t, err)
+	assert.Equal(t, record.ID, "record-2", "id of oldest record")
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(files), 2, "number of files")
+}
+
+func TestSpoolingAuditLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := openSpool(dir, 10)
+	assert.NoErr(t, err)
+
+	// the backend fails for the first attempt
+	backend := &testBackend{failures: 1, sent: make(chan *Record, 10)}
+	logger := startSpoolingAuditLogger(s, backend, ctrl.Log.WithName("test"), time.Millisecond)
+	defer logger.Close()
+
+	success := true
+	message := NewAuditMessage(CreateOrUpdate, "test-bom", "test-project", "", "", "", "", "", nil)
+	id1, err := logger.Log(message)
+	assert.NoErr(t, err)
+
+	message.Success = &success
+	id2, err := logger.Log(message)
+	assert.NoErr(t, err)
+
+	record := waitForRecord(t, backend.sent)
+	assert.Equal(t, record.ID, id1, "id of first record")
+	assert.Equal(t, record.Action, "CreateOrUpdate", "action")
+	assert.True(t, record.Message.Success == nil, "first record has no result")
+
+	record = waitForRecord(t, backend.sent)
+	assert.Equal(t, record.ID, id2, "id of second record")
+	assert.Equal(t, *record.Message.Success, true, "result of second record")
+
+	// delivered records are removed from the spool
+	assert.NoErr(t, logger.Close())
+	assert.Equal(t, s.len(), 0, "number of pending records")
+}
+
+// TestSpoolingAuditLoggerDrainsOnClose tests that Close delivers the spooled records, and stops after the drain timeout
+// if the backend is not available
+func TestSpoolingAuditLoggerDrainsOnClose(t *testing.T) {
+	tests := []struct {
+		name            string
+		failures        int
+		expectedPending int
+	}{
+		{name: "backend available", failures: 0, expectedPending: 0},
+		{name: "backend not available", failures: 1000, expectedPending: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "spool")
+			assert.NoErr(t, err)
+			defer os.RemoveAll(dir)
+
+			s, err := openSpool(dir, 10)
+			assert.NoErr(t, err)
+
+			backend := &testBackend{failures: tt.failures, sent: make(chan *Record, 10), delay: 10 * time.Millisecond}
+			logger := startSpoolingAuditLogger(s, backend, ctrl.Log.WithName("test"), time.Millisecond)
+			logger.drainTimeout = 200 * time.Millisecond
+
+			for i := 0; i < 3; i++ {
+				_, err = logger.Log(NewAuditMessage(CreateOrUpdate, "test-bom", "test-project", "", "", "", "", "", nil))
+				assert.NoErr(t, err)
+			}
+
+			start := time.Now()
+			assert.NoErr(t, logger.Close())
+			assert.True(t, time.Since(start) < 2*time.Second, "close returns after the drain timeout")
+			assert.Equal(t, s.len(), tt.expectedPending, "number of pending records")
+			assert.Equal(t, len(backend.sent), 3-tt.expectedPending, "number of sent records")
+		})
+	}
+}
+
+type testBackend struct {
+	mutex    sync.Mutex
+	failures int
+	sent     chan *Record
+	delay    time.Duration
+}
+
+func (b *testBackend) Name() string {
+	return "test"
+}
+
+func (b *testBackend) Send(_ context.Context, record *Record) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	time.Sleep(b.delay)
+
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("backend not available")
+	}
+
+	b.sent <- record
+	return nil
+}
+
+func (b *testBackend) Close() error {
+	return nil
+}
+
+func newTestRecord(id string) *Record {
+	return &Record{
+		ID:      id,
+		Time:    time.Date(2021, time.December, 1, 10, 0, 0, 0, time.UTC),
+		Message: NewAuditMessage(CreateOrUpdate, "test-bom", "test-project", "", "", "", "", "", nil),
+	}
+}
+
+func waitForRecord(t *testing.T, sent chan *Record) *Record {
+	select {
+	case record := <-sent:
+		return record
+	case <-time.After(5 * time.Second):
+		t.Fatal("audit record was not sent")
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package auditlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gardener/potter-controller/pkg/metrics"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	sendTimeout           = 30 * time.Second
+	defaultRetryBaseDelay = time.Second
+	maxRetryDelay         = time.Minute
+
+	// defaultDrainTimeout is the time for which Close waits until the spooled records are delivered; it must fit into
+	// the termination grace period of the pod together with the shutdown interval of the controller
+	defaultDrainTimeout = 5 * time.Second
+	drainPollInterval   = 50 * time.Millisecond
+)
+
+// spoolingAuditLogger writes the audit messages into a spool, from which a background worker delivers them to the
+// backend. Messages which cannot be delivered are retried with exponential backoff until the delivery succeeds, so
+// that an outage of the backend does not lose messages as long as the spool is not full.
+type spoolingAuditLogger struct {
+	spool          *spool
+	backend        Backend
+	log            logr.Logger
+	retryBaseDelay time.Duration
+	drainTimeout   time.Duration
+
+	wakeup    chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSpoolingAuditLogger(spool *spool, backend Backend, log logr.Logger) *spoolingAuditLogger {
+	return startSpoolingAuditLogger(spool, backend, log, defaultRetryBaseDelay)
+}
+
+func startSpoolingAuditLogger(spool *spool, backend Backend, log logr.Logger, retryBaseDelay time.Duration) *spoolingAuditLogger {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := &spoolingAuditLogger{
+		spool:          spool,
+		backend:        backend,
+		log:            log,
+		retryBaseDelay: retryBaseDelay,
+		drainTimeout:   defaultDrainTimeout,
+		wakeup:         make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+	}
+
+	metrics.SetAuditLogPending(spool.len())
+	go l.run()
+	return l
+}
+
+// Log adds a message to the spool and returns the id of the spooled record. The message is dropped, and an error
+// returned, if it cannot be spooled.
+func (l *spoolingAuditLogger) Log(auditMessageInfo *AuditMessageInfo) (string, error) {
+	record := &Record{
+		ID:      uuid.New().String(),
+		Time:    time.Now().UTC(),
+		Action:  auditMessageInfo.Action.GetActionAsString(),
+		Message: auditMessageInfo,
+	}
+
+	if err := l.spool.put(record); err != nil {
+		reason := metrics.AuditLogDroppedSpoolError
+		if errors.Is(err, errSpoolFull) {
+			reason = metrics.AuditLogDroppedSpoolFull
+		}
+
+		metrics.ObserveAuditLogDropped(reason)
+		return "", err
+	}
+
+	metrics.SetAuditLogPending(l.spool.len())
+
+	select {
+	case l.wakeup <- struct{}{}:
+	default:
+	}
+
+	return record.ID, nil
+}
+
+// Close waits up to the drain timeout until the spooled records are delivered, and stops the delivery. Records which
+// are not yet delivered remain in the spool and are delivered after a restart if the spool directory survives it.
+func (l *spoolingAuditLogger) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		if pending := l.drain(); pending > 0 {
+			l.log.Error(nil, "Audit log messages not delivered before shutdown, they remain in the spool",
+				"backend", l.backend.Name(), "pending", pending)
+		}
+
+		l.cancel()
+		<-l.done
+		err = l.backend.Close()
+	})
+
+	return err
+}
+
+// drain waits until the spool is empty or the drain timeout has passed, and returns the number of pending records
+func (l *spoolingAuditLogger) drain() int {
+	timeout := time.NewTimer(l.drainTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending := l.spool.len()
+		if pending == 0 {
+			return 0
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			return pending
+		}
+	}
+}
+
+func (l *spoolingAuditLogger) run() {
+	defer close(l.done)
+
+	delay := l.retryBaseDelay
+	for {
+		name, ok := l.spool.peek()
+		if !ok {
+			select {
+			case <-l.wakeup:
+				continue
+			case <-l.ctx.Done():
+				return
+			}
+		}
+
+		if err := l.deliver(name); err != nil {
+			l.log.Error(err, "Failed to deliver audit log message, will retry", "backend", l.backend.Name(),
+				"retryIn", delay.String())
+
+			select {
+			case <-time.After(delay):
+			case <-l.ctx.Done():
+				return
+			}
+
+			delay *= 2
+			if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+
+			continue
+		}
+
+		delay = l.retryBaseDelay
+		metrics.SetAuditLogPending(l.spool.len())
+	}
+}
+
+// deliver sends the oldest record of the spool to the backend and removes it from the spool. A record which cannot
+// be read is dropped, because it would block the delivery of all later records.
+func (l *spoolingAuditLogger) deliver(name string) error {
+	record, err := l.spool.read(name)
+	if err != nil {
+		l.log.Error(err, "Dropping unreadable audit log message", "file", name)
+		metrics.ObserveAuditLogDropped(metrics.AuditLogDroppedCorrupt)
+		return l.spool.remove(name)
+	}
+
+	ctx, cancel := context.WithTimeout(l.ctx, sendTimeout)
+	defer cancel()
+
+	err = l.backend.Send(ctx, record)
+	metrics.ObserveAuditLogDelivery(l.backend.Name(), err)
+	if err != nil {
+		return errors.Wrapf(err, "could not send audit record %s", record.ID)
+	}
+
+	return l.spool.remove(name)
+}
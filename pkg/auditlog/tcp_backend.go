@@ -0,0 +1,107 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/gob"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+)
+
+const tcpTimeout = 5 * time.Second
+
+// tcpBackend sends gob encoded audit messages to the audit log sidecar. The connection is established when the first
+// message is sent, and re-established after an error.
+type tcpBackend struct {
+	log     logr.Logger
+	address string
+	netLock sync.Mutex
+	conn    net.Conn
+	dec     *gob.Decoder
+	enc     *gob.Encoder
+}
+
+func newTCPBackend(address string, log logr.Logger) *tcpBackend {
+	return &tcpBackend{
+		log:     log,
+		address: address,
+	}
+}
+
+func (b *tcpBackend) Name() string {
+	return BackendTCP
+}
+
+func (b *tcpBackend) Send(ctx context.Context, record *Record) error {
+	var auditResponse AuditMessageResponse
+
+	// synchronize multiple audit-requests by serializing messages
+	b.netLock.Lock()
+	defer b.netLock.Unlock()
+
+	if b.conn == nil {
+		if err := b.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	b.log.Info("Sending auditlog message", "auditID", record.ID)
+
+	if err := b.conn.SetDeadline(time.Now().Add(tcpTimeout)); err != nil {
+		b.disconnect()
+		return errors.Wrap(err, "error setting deadline of auditlog connection")
+	}
+
+	if err := b.enc.Encode(record.Message); err != nil {
+		b.disconnect()
+		return errors.Wrap(err, "error while encoding audit message")
+	}
+
+	// wait for response:
+	if err := b.dec.Decode(&auditResponse); err != nil {
+		b.disconnect()
+		return errors.Wrap(err, "decode error of audit message response")
+	}
+
+	b.log.Info("Received answer from auditlog, id: "+auditResponse.ID, "auditID", record.ID)
+	return nil
+}
+
+func (b *tcpBackend) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: tcpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.address)
+	if err != nil {
+		return errors.Wrap(err, "error connecting to auditlog server")
+	}
+
+	b.conn = conn
+	b.enc = gob.NewEncoder(conn)
+	b.dec = gob.NewDecoder(conn)
+	return nil
+}
+
+func (b *tcpBackend) disconnect() {
+	if b.conn != nil {
+		_ = b.conn.Close()
+		b.conn = nil
+	}
+}
+
+func (b *tcpBackend) Close() error {
+	b.netLock.Lock()
+	defer b.netLock.Unlock()
+
+	if b.conn == nil {
+		return nil
+	}
+
+	err := b.conn.Close()
+	b.conn = nil
+	if err != nil {
+		b.log.Error(err, "Failed to close network connection for auditlog")
+	}
+	return err
+}
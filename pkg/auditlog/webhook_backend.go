@@ -0,0 +1,105 @@
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	webhookTimeout         = 10 * time.Second
+	webhookMaxResponseSize = 64 * 1024
+)
+
+// webhookBackend posts the records as JSON to an http endpoint
+type webhookBackend struct {
+	url        string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+func newWebhookBackend(url, caFile, tokenFile string) (*webhookBackend, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		caBundle, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read CA file of audit log webhook")
+		}
+
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM(caBundle); !ok {
+			return nil, errors.New("could not parse CA file of audit log webhook")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	return &webhookBackend{
+		url:       url,
+		tokenFile: tokenFile,
+		httpClient: &http.Client{
+			Timeout: webhookTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+func (b *webhookBackend) Name() string {
+	return BackendWebhook
+}
+
+func (b *webhookBackend) Send(ctx context.Context, record *Record) error {
+	body, err := marshalLine(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not create audit log request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// the token is read for every request, so that a rotated token is used without restart
+	if b.tokenFile != "" {
+		token, err := ioutil.ReadFile(b.tokenFile)
+		if err != nil {
+			return errors.Wrap(err, "could not read token file of audit log webhook")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "audit log request failed")
+	}
+	defer res.Body.Close()
+
+	// the response body is read, so that the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, webhookMaxResponseSize))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("audit log request returned status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (b *webhookBackend) Close() error {
+	b.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,931 @@
+package carvelpackage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	landscaper "github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	kappctrl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	packaging "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/packaging/v1alpha1"
+	versions "github.com/vmware-tanzu/carvel-vendir/pkg/vendir/versions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	couldNotParse = "could not parse typeSpecificData"
+
+	kubeconfigSecretKey = "kubeconfig"
+
+	// keys of the values secret; kapp-controller applies the values in the order of the keys below, so that the
+	// secret values override the inline values
+	valuesKey       = "values.yaml"
+	secretValuesKey = "secret-values.yaml"
+
+	valuesSecretSuffix = "-values"
+
+	deletionInProgressDescription = "deletion of package install in progress"
+
+	// maxMessageLength is the maximal length of the messages of the PackageInstall in the type specific status
+	maxMessageLength = 500
+	// maxConditions is the maximal number of conditions of the PackageInstall in the type specific status
+	maxConditions = 10
+
+	repositoryInfix = "repo"
+
+	// labelPackageRepository is set on a PackageInstall to the name of the shared PackageRepository which its
+	// application declares
+	labelPackageRepository = "potter.gardener.cloud/package-repository"
+)
+
+// packageDeployerDI installs Carvel packages. For every deploy item it maintains a PackageInstall on the hub, which
+// is reconciled by kapp-controller, together with a secret containing the values. The PackageRepositories declared by
+// the applications are shared by all applications of a clusterbom which declare the same repository.
+type packageDeployerDI struct {
+	crAndSecretClient        client.Client
+	uncachedClient           synchronize.UncachedClient
+	blockObject              *synchronize.BlockObject
+	reconcileIntervalMinutes int64
+}
+
+func NewPackageDeployerDI(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
+	blockObject *synchronize.BlockObject, reconcileIntervalMinutes int64) deployutil.DeployItemDeployer {
+	return &packageDeployerDI{
+		crAndSecretClient:        crAndSecretClient,
+		uncachedClient:           uncachedClient,
+		blockObject:              blockObject,
+		reconcileIntervalMinutes: reconcileIntervalMinutes,
+	}
+}
+
+func (r *packageDeployerDI) ProcessNewOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, "Deployment", 1)
+}
+
+func (r *packageDeployerDI) ReconcileOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, "Reconcile", 1)
+}
+
+func (r *packageDeployerDI) RetryFailedOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	lastOp := deployData.ProviderStatus.LastOperation
+	r.processItem(ctx, deployData, "Retry of deployment", lastOp.NumberOfTries+1)
+}
+
+func (r *packageDeployerDI) ProcessPendingOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	if deployData.IsDeletionInProgress() {
+		r.processItem(ctx, deployData, "Removal", 1)
+		return
+	}
+
+	// check reachability of target cluster
+	secretKey := deployData.GetSecretKey()
+	_, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.ClusterUnreachableError:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
+				"Process pending failed for application "+configID+", because cluster is unreachable", err)
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Reconcile failed for application "+configID, err)
+		}
+		deployData.SetStatusForUnreachableCluster()
+	} else {
+		deployData.SetStatusForReachableCluster()
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, metav1.Now())
+}
+
+func (r *packageDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.DeployData) {}
+
+func (r *packageDeployerDI) processItem(ctx context.Context, deployData *deployutil.DeployData, operationText string,
+	numberOfTries int32) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	log := util.GetLoggerFromContext(ctx)
+	log = log.WithValues(util.LogKeyPackageInstallName, deployData.GetDeployItemKey())
+	ctx = context.WithValue(ctx, util.LoggerKey{}, log)
+
+	var err error
+	if deployData.IsDeleteOperation() {
+		err = r.Cleanup(ctx, deployData, true)
+	} else {
+		err = r.installOrUpdate(ctx, deployData)
+	}
+
+	now := metav1.Now()
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.ClusterUnreachableError:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
+				operationText+" failed for application "+configID+", because cluster is unreachable", err)
+			deployData.SetStatusForUnreachableCluster()
+		case *deployutil.DeletionInProgressError:
+			deployData.SetStatus(util.StatePending, err.Error(), 1, now)
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, operationText+" failed for application "+configID, err)
+			deployData.SetFailedStatus(err, numberOfTries, now)
+		}
+	} else {
+		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, operationText+" done for application "+configID)
+		deployData.SetStatus(util.StateOk, r.successDescription(deployData), 1, now)
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, now)
+}
+
+func (r *packageDeployerDI) installOrUpdate(ctx context.Context, deployData *deployutil.DeployData) error {
+	packageSpecificData, err := apitypes.NewPackageSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return errors.Wrap(err, couldNotParse)
+	}
+
+	if err = r.createNamespace(ctx, deployData, packageSpecificData.Namespace); err != nil {
+		return err
+	}
+
+	repositoryName := ""
+	if packageSpecificData.Repository != nil {
+		repositoryKey, err := r.getRepositoryKey(deployData, packageSpecificData.Repository)
+		if err != nil {
+			return err
+		}
+
+		if err = r.applyRepository(ctx, deployData, repositoryKey, packageSpecificData.Repository); err != nil {
+			return err
+		}
+
+		repositoryName = repositoryKey.Name
+	}
+
+	valuesData, err := r.getValuesData(ctx, deployData, packageSpecificData)
+	if err != nil {
+		return err
+	}
+
+	if len(valuesData) > 0 {
+		err = r.applyValuesSecret(ctx, deployData, valuesData)
+	} else {
+		err = r.deleteObject(ctx, r.getValuesSecretKey(deployData), &corev1.Secret{})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	spec := r.newPackageInstallSpec(deployData, packageSpecificData, valuesData)
+	oldRepositoryName, err := r.applyPackageInstall(ctx, deployData, spec, repositoryName)
+	if err != nil {
+		return err
+	}
+
+	if oldRepositoryName != "" && oldRepositoryName != repositoryName {
+		if err = r.releaseRepository(ctx, deployData, oldRepositoryName); err != nil {
+			return err
+		}
+	}
+
+	return r.deleteLegacyRepository(ctx, deployData)
+}
+
+// newPackageInstallSpec returns the spec of the PackageInstall. The target cluster is always the cluster of the
+// clusterbom.
+func (r *packageDeployerDI) newPackageInstallSpec(deployData *deployutil.DeployData,
+	packageSpecificData *apitypes.PackageSpecificData, valuesData map[string][]byte) *packaging.PackageInstallSpec {
+	namespace := packageSpecificData.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	syncPeriod := packageSpecificData.SyncPeriod
+	if syncPeriod == nil {
+		syncPeriod = &metav1.Duration{
+			Duration: time.Duration(r.reconcileIntervalMinutes) * time.Minute,
+		}
+	}
+
+	spec := &packaging.PackageInstallSpec{
+		Cluster: &kappctrl.AppCluster{
+			Namespace: namespace,
+			KubeconfigSecretRef: &kappctrl.AppClusterKubeconfigSecretRef{
+				Name: deployData.Configuration.LocalSecretRef,
+				Key:  kubeconfigSecretKey,
+			},
+		},
+		PackageRef: &packaging.PackageRef{
+			RefName: packageSpecificData.PackageName,
+		},
+		SyncPeriod: syncPeriod,
+	}
+
+	if packageSpecificData.Version != "" || packageSpecificData.Prereleases {
+		selection := &versions.VersionSelectionSemver{
+			Constraints: packageSpecificData.Version,
+		}
+		if packageSpecificData.Prereleases {
+			selection.Prereleases = &versions.VersionSelectionSemverPrereleases{}
+		}
+		spec.PackageRef.VersionSelection = selection
+	}
+
+	for _, key := range []string{valuesKey, secretValuesKey} {
+		if _, ok := valuesData[key]; ok {
+			spec.Values = append(spec.Values, packaging.PackageInstallValues{
+				SecretRef: &packaging.PackageInstallValuesSecretRef{
+					Name: r.getValuesSecretKey(deployData).Name,
+					Key:  key,
+				},
+			})
+		}
+	}
+
+	return spec
+}
+
+// Creates the namespace on the target cluster, if it does not yet exist. Checks in any case that the target cluster
+// is reachable.
+func (r *packageDeployerDI) createNamespace(ctx context.Context, deployData *deployutil.DeployData, namespace string) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	secretKey := deployData.GetSecretKey()
+	targetClient, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		return err
+	}
+
+	if namespace == "" || namespace == "default" {
+		return nil
+	}
+
+	namespaceObject := corev1.Namespace{}
+	err = targetClient.Get(ctx, types.NamespacedName{Name: namespace}, &namespaceObject)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "error fetching namespace of target cluster", "namespace", namespace)
+			return err
+		}
+
+		namespaceObject = corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		}
+
+		err = targetClient.Create(ctx, &namespaceObject)
+		if err != nil {
+			log.Error(err, "error creating namespace on target cluster", "namespace", namespace)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getValuesData returns the content of the values secret: the inline values and the secret values of the application.
+func (r *packageDeployerDI) getValuesData(ctx context.Context, deployData *deployutil.DeployData,
+	packageSpecificData *apitypes.PackageSpecificData) (map[string][]byte, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	valuesData := make(map[string][]byte)
+
+	if len(packageSpecificData.Values) > 0 {
+		values, err := yaml.Marshal(packageSpecificData.Values)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal values")
+		}
+		valuesData[valuesKey] = values
+	}
+
+	internalSecretName := deployData.Configuration.DeploymentConfig.InternalSecretName
+	if internalSecretName != "" {
+		secretKey := types.NamespacedName{
+			Name:      internalSecretName,
+			Namespace: deployData.GetNamespace(),
+		}
+
+		secret := &corev1.Secret{}
+		if err := r.crAndSecretClient.Get(ctx, secretKey, secret); err != nil {
+			msg := "could not read secret values"
+			log.Error(err, msg, util.LogKeySecretName, internalSecretName)
+			return nil, errors.Wrap(err, msg)
+		}
+
+		// the secret values are stored as json, which is also valid yaml
+		if secretValues, ok := secret.Data[util.SecretValuesKey]; ok {
+			valuesData[secretValuesKey] = secretValues
+		}
+	}
+
+	return valuesData, nil
+}
+
+func (r *packageDeployerDI) applyValuesSecret(ctx context.Context, deployData *deployutil.DeployData, valuesData map[string][]byte) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	secretKey := r.getValuesSecretKey(deployData)
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+
+	secret := corev1.Secret{}
+	err := r.crAndSecretClient.Get(ctx, *secretKey, &secret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "error fetching values secret", util.LogKeySecretName, secretKey.Name)
+			return err
+		}
+
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretKey.Name,
+				Namespace: secretKey.Namespace,
+				Labels: map[string]string{
+					hubv1.LabelClusterBomName: clusterBomKey.Name,
+					hubv1.LabelPurpose:        util.PurposePackageValues,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: valuesData,
+		}
+
+		err = r.crAndSecretClient.Create(ctx, &secret)
+		if err != nil {
+			log.Error(err, "error creating values secret", util.LogKeySecretName, secretKey.Name)
+		}
+
+		return err
+	}
+
+	secret.Data = valuesData
+	err = r.crAndSecretClient.Update(ctx, &secret)
+	if err != nil {
+		log.Error(err, "error updating values secret", util.LogKeySecretName, secretKey.Name)
+	}
+
+	return err
+}
+
+// applyRepository creates or updates the shared PackageRepository. Its name is derived from the fetch source, so that
+// the update only restores changes which were made by others.
+func (r *packageDeployerDI) applyRepository(ctx context.Context, deployData *deployutil.DeployData,
+	repositoryKey *types.NamespacedName, fetch *packaging.PackageRepositoryFetch) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+
+	repository := packaging.PackageRepository{}
+	err := r.crAndSecretClient.Get(ctx, *repositoryKey, &repository)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "error fetching package repository")
+			return err
+		}
+
+		repository = packaging.PackageRepository{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      repositoryKey.Name,
+				Namespace: repositoryKey.Namespace,
+				Labels: map[string]string{
+					hubv1.LabelClusterBomName: clusterBomKey.Name,
+					hubv1.LabelPurpose:        util.PurposePackageRepository,
+				},
+			},
+			Spec: packaging.PackageRepositorySpec{
+				Fetch: fetch,
+			},
+		}
+
+		err = r.crAndSecretClient.Create(ctx, &repository)
+		if err != nil {
+			log.Error(err, "error creating package repository")
+		}
+
+		return err
+	}
+
+	repository.Spec.Fetch = fetch
+	err = r.crAndSecretClient.Update(ctx, &repository)
+	if err != nil {
+		log.Error(err, "error updating package repository")
+	}
+
+	return err
+}
+
+// applyPackageInstall creates or updates the PackageInstall, which is labeled with the name of the repository declared
+// by the application, if any. It returns the name of the repository which the PackageInstall was labeled with before.
+func (r *packageDeployerDI) applyPackageInstall(ctx context.Context, deployData *deployutil.DeployData,
+	spec *packaging.PackageInstallSpec, repositoryName string) (string, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	packageInstallKey := r.getPackageInstallKey(deployData)
+
+	packageInstall := packaging.PackageInstall{}
+	err := r.crAndSecretClient.Get(ctx, *packageInstallKey, &packageInstall)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "error fetching package install")
+			return "", err
+		}
+
+		packageInstall = packaging.PackageInstall{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      packageInstallKey.Name,
+				Namespace: packageInstallKey.Namespace,
+			},
+			Spec: *spec,
+		}
+		setRepositoryLabel(&packageInstall, repositoryName)
+
+		err = r.crAndSecretClient.Create(ctx, &packageInstall)
+		if err != nil {
+			log.Error(err, "error creating package install")
+		}
+
+		return "", err
+	}
+
+	oldRepositoryName := packageInstall.GetLabels()[labelPackageRepository]
+
+	packageInstall.Spec = *spec
+	setRepositoryLabel(&packageInstall, repositoryName)
+	err = r.crAndSecretClient.Update(ctx, &packageInstall)
+	if err != nil {
+		log.Error(err, "error updating package install")
+	}
+
+	return oldRepositoryName, err
+}
+
+func setRepositoryLabel(packageInstall *packaging.PackageInstall, repositoryName string) {
+	if repositoryName == "" {
+		delete(packageInstall.Labels, labelPackageRepository)
+		return
+	}
+
+	util.AddLabels(packageInstall, labelPackageRepository, repositoryName)
+}
+
+// releaseRepository deletes a shared PackageRepository, which the PackageInstall of the deploy item no longer uses,
+// unless the PackageInstall of another application is still labeled with it.
+func (r *packageDeployerDI) releaseRepository(ctx context.Context, deployData *deployutil.DeployData, repositoryName string) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	packageInstallKey := r.getPackageInstallKey(deployData)
+
+	packageInstalls := packaging.PackageInstallList{}
+	err := r.uncachedClient.ListUncached(ctx, &packageInstalls, client.InNamespace(packageInstallKey.Namespace),
+		client.MatchingLabels{labelPackageRepository: repositoryName})
+	if err != nil {
+		log.Error(err, "error listing package installs of package repository", "repository", repositoryName)
+		return err
+	}
+
+	for i := range packageInstalls.Items {
+		if packageInstalls.Items[i].Name != packageInstallKey.Name {
+			return nil
+		}
+	}
+
+	repositoryKey := &types.NamespacedName{Name: repositoryName, Namespace: packageInstallKey.Namespace}
+	return r.deleteObject(ctx, repositoryKey, &packaging.PackageRepository{})
+}
+
+// deleteLegacyRepository deletes the PackageRepository of the deploy item, which was created by earlier versions,
+// before the repositories were shared
+func (r *packageDeployerDI) deleteLegacyRepository(ctx context.Context, deployData *deployutil.DeployData) error {
+	return r.deleteObject(ctx, deployData.GetDeployItemKey(), &packaging.PackageRepository{})
+}
+
+// Cleanup deletes the PackageInstall, the values secret and the package repository, if no other application uses it. If the target cluster exists,
+// kapp-controller removes the package from it before the PackageInstall disappears, see remove. Otherwise the
+// finalizers of the PackageInstall are removed, so that it is deleted immediately.
+func (r *packageDeployerDI) Cleanup(ctx context.Context, deployData *deployutil.DeployData, clusterExist bool) error {
+	if clusterExist {
+		return r.remove(ctx, deployData)
+	}
+
+	log := util.GetLoggerFromContext(ctx)
+
+	packageInstallKey := r.getPackageInstallKey(deployData)
+
+	packageInstall := packaging.PackageInstall{}
+	err := r.crAndSecretClient.Get(ctx, *packageInstallKey, &packageInstall)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "error fetching package install for deletion")
+			return err
+		}
+
+		return r.cleanupDependents(ctx, deployData)
+	}
+
+	if len(packageInstall.GetFinalizers()) > 0 {
+		packageInstall.SetFinalizers([]string{})
+
+		err = r.crAndSecretClient.Update(ctx, &packageInstall)
+		if err != nil && !apierrors.IsNotFound(err) {
+			if !util.IsConcurrentModificationErr(err) {
+				log.Error(err, "error removing finalizers from package install")
+			}
+
+			return err
+		}
+	}
+
+	if packageInstall.ObjectMeta.DeletionTimestamp == nil {
+		err = r.crAndSecretClient.Delete(ctx, &packageInstall)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "error deleting package install")
+			return err
+		}
+	}
+
+	return r.cleanupDependents(ctx, deployData)
+}
+
+// remove triggers the deletion of the PackageInstall without waiting for it. As long as the PackageInstall exists, a
+// DeletionInProgressError is returned, and the deploy item is processed again as pending deletion. The values secret
+// and the package repository are released after kapp-controller has removed the package from the target cluster.
+func (r *packageDeployerDI) remove(ctx context.Context, deployData *deployutil.DeployData) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	packageInstallKey := r.getPackageInstallKey(deployData)
+
+	packageInstall := packaging.PackageInstall{}
+	err := r.crAndSecretClient.Get(ctx, *packageInstallKey, &packageInstall)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.cleanupDependents(ctx, deployData)
+		}
+
+		log.Error(err, "error fetching package install for deletion")
+		return err
+	}
+
+	if packageInstall.ObjectMeta.DeletionTimestamp == nil {
+		err = r.crAndSecretClient.Delete(ctx, &packageInstall)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return r.cleanupDependents(ctx, deployData)
+			}
+
+			log.Error(err, "error deleting package install")
+			return err
+		}
+
+		// a PackageInstall without finalizers is removed immediately
+		err = r.crAndSecretClient.Get(ctx, *packageInstallKey, &packageInstall)
+		if apierrors.IsNotFound(err) {
+			return r.cleanupDependents(ctx, deployData)
+		}
+	}
+
+	return &deployutil.DeletionInProgressError{Message: deletionInProgressDescription}
+}
+
+// cleanupDependents deletes the values secret and releases the package repository declared by the application. The
+// PackageInstall is already deleted, so that the repository is taken from the current configuration.
+func (r *packageDeployerDI) cleanupDependents(ctx context.Context, deployData *deployutil.DeployData) error {
+	if err := r.deleteObject(ctx, r.getValuesSecretKey(deployData), &corev1.Secret{}); err != nil {
+		return err
+	}
+
+	packageSpecificData, err := apitypes.NewPackageSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return errors.Wrap(err, couldNotParse)
+	}
+
+	if packageSpecificData.Repository != nil {
+		repositoryKey, err := r.getRepositoryKey(deployData, packageSpecificData.Repository)
+		if err != nil {
+			return err
+		}
+
+		if err = r.releaseRepository(ctx, deployData, repositoryKey.Name); err != nil {
+			return err
+		}
+	}
+
+	return r.deleteLegacyRepository(ctx, deployData)
+}
+
+// deleteObject deletes an object on the hub, if it exists.
+func (r *packageDeployerDI) deleteObject(ctx context.Context, key *types.NamespacedName, obj client.Object) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	err := r.crAndSecretClient.Get(ctx, *key, obj)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		log.Error(err, "error fetching object for deletion", "object", key)
+		return err
+	}
+
+	err = r.crAndSecretClient.Delete(ctx, obj)
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "error deleting object", "object", key)
+		return err
+	}
+
+	return nil
+}
+
+func (r *packageDeployerDI) computeReadinessAndExport(ctx context.Context, deployData *deployutil.DeployData, now metav1.Time) {
+	r.computeReadiness(ctx, deployData, now)
+
+	readyCondition := deployData.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	if readyCondition != nil && readyCondition.Status == landscaper.ConditionTrue && deployData.IsInstallOperation() {
+		err := r.computeExports(ctx, deployData)
+		if err != nil {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now,
+				hubv1.ReasonCouldNotGetExport, "Could not get export data")
+
+			deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
+		}
+	}
+}
+
+func (r *packageDeployerDI) computeExports(ctx context.Context, deployData *deployutil.DeployData) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	packageSpecificData, err := apitypes.NewPackageSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		log.Error(err, couldNotParse)
+		return err
+	}
+
+	if len(packageSpecificData.InternalExport) > 0 {
+		secretKey := deployData.GetSecretKey()
+		dynamicTargetClient, err := deployutil.NewDynamicTargetClient(ctx, r.crAndSecretClient, *secretKey)
+		if err != nil {
+			log.Error(err, "Error fetching dynamic target client")
+			return err
+		}
+
+		newExportData := make(map[string]interface{})
+
+		for key, exportEntry := range packageSpecificData.InternalExport {
+			exportData, err := dynamicTargetClient.GetResourceData(exportEntry.APIVersion, exportEntry.Resource,
+				exportEntry.Namespace, exportEntry.Name, exportEntry.FieldPath)
+
+			if err != nil {
+				log.Error(err, "Could not fetch resource: "+exportEntry.String())
+				return err
+			}
+
+			newExportData[key] = exportData
+		}
+
+		if len(newExportData) > 0 {
+			deployData.ExportValues = newExportData
+		}
+	}
+
+	return nil
+}
+
+func (r *packageDeployerDI) computeReadiness(ctx context.Context, deployData *deployutil.DeployData, now metav1.Time) {
+	log := util.GetLoggerFromContext(ctx)
+
+	packageInstallKey := r.getPackageInstallKey(deployData)
+	packageInstall := &packaging.PackageInstall{}
+
+	switch deployData.ProviderStatus.LastOperation.Operation {
+	case util.OperationInstall:
+		err := r.crAndSecretClient.Get(ctx, *packageInstallKey, packageInstall)
+		if err != nil {
+			log.Error(err, "error fetching package install after install")
+			r.setReadiness(deployData, util.StateUnknown, now)
+			r.setTypeSpecificStatus(ctx, nil, deployData)
+			break
+		}
+
+		readiness := getReadinessFromConditions(packageInstall)
+		if readiness == util.StateOk {
+			dynamicTargetClient, err := deployutil.NewDynamicTargetClient(ctx, r.crAndSecretClient, *deployData.GetSecretKey())
+			if err != nil {
+				log.Error(err, "Error fetching dynamic target client")
+				readiness = util.StateUnknown
+			} else {
+				readiness = deployutil.ComputeReadinessForResourceReadyRequirements(deployData.Configuration.DeploymentConfig.ReadyRequirements.Resources,
+					readiness, dynamicTargetClient, log)
+			}
+		}
+
+		r.setReadiness(deployData, readiness, now)
+		r.setTypeSpecificStatus(ctx, packageInstall, deployData)
+	case util.OperationRemove:
+		err := r.crAndSecretClient.Get(ctx, *packageInstallKey, packageInstall)
+		if err != nil {
+			packageInstall = nil
+			if !apierrors.IsNotFound(err) {
+				log.Error(err, "error fetching package install after delete")
+			}
+		}
+		r.setReadiness(deployData, util.StateNotRelevant, now)
+		r.setTypeSpecificStatus(ctx, packageInstall, deployData)
+	default:
+		log.Error(nil, "Unexpected operation", "operation", deployData.ProviderStatus.LastOperation.Operation)
+		r.setReadiness(deployData, util.StateUnknown, now)
+		r.setTypeSpecificStatus(ctx, nil, deployData)
+	}
+
+	// compute condition
+	readinessState := deployData.ProviderStatus.Readiness.State
+
+	if deployData.ProviderStatus.Reachability != nil && !deployData.ProviderStatus.Reachability.Reachable {
+		// special case for unreachable cluster - just replace condition to unknown state
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonClusterUnreachable, "Cluster is unreachable")
+		return
+	} else if deployData.GetObservedGeneration() == 0 {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonInitialState, "No deployment executed until now")
+		deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
+	} else if deployData.IsInstallOperation() {
+		currentGeneration := packageInstall != nil && packageInstall.Generation == packageInstall.Status.ObservedGeneration
+
+		if deployData.GetGeneration() != deployData.ProviderStatus.LastOperation.SuccessGeneration {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonUpgradePending, "Upgrade pending")
+			deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
+		} else if readinessState == util.StateOk && currentGeneration {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionTrue, now, hubv1.ReasonRunning,
+				runningMessage(packageInstall))
+			deployData.SetPhase(landscaper.ExecutionPhaseSucceeded)
+		} else if readinessState == util.StateOk {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonNotCurrentGeneration, "Not latest version running")
+			deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
+		} else if readinessState == util.StateFinallyFailed {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionFalse, now, hubv1.ReasonFinallyFailed, "Finally Failed")
+			deployData.SetPhase(landscaper.ExecutionPhaseFailed)
+		} else {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonNotRunning, "Readiness is "+readinessState)
+			deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
+		}
+	} else if deployData.IsDeleteOperation() {
+		if deployData.ProviderStatus.LastOperation.Operation == util.OperationInstall {
+			// should never happen
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending, "Remove pending")
+			deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
+		} else if deployData.ProviderStatus.LastOperation.Operation == util.OperationRemove {
+			if deployData.ProviderStatus.LastOperation.State == util.StateOk {
+				deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionTrue, now, hubv1.ReasonRemoved, "Removed")
+				deployData.SetPhase(landscaper.ExecutionPhaseSucceeded)
+			} else if deployData.ProviderStatus.LastOperation.State == util.StatePending {
+				deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending,
+					"Deletion in progress")
+				deployData.SetPhase(landscaper.ExecutionPhaseDeleting)
+			} else {
+				deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending,
+					"Last try to remove is pending")
+				deployData.SetPhase(landscaper.ExecutionPhaseDeleting)
+			}
+		}
+	}
+}
+
+// getReadinessFromConditions derives the readiness from the conditions which kapp-controller sets on the
+// PackageInstall.
+func getReadinessFromConditions(packageInstall *packaging.PackageInstall) string {
+	if hasCondition(packageInstall, kappctrl.ReconcileSucceeded) {
+		return util.StateOk
+	} else if hasCondition(packageInstall, kappctrl.ReconcileFailed) {
+		return util.StateFailed
+	} else if hasCondition(packageInstall, kappctrl.Reconciling) {
+		return util.StatePending
+	}
+
+	return util.StateUnknown
+}
+
+func hasCondition(packageInstall *packaging.PackageInstall, conditionType kappctrl.AppConditionType) bool {
+	for _, condition := range packageInstall.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func runningMessage(packageInstall *packaging.PackageInstall) string {
+	if packageInstall.Status.Version == "" {
+		return "Running"
+	}
+
+	return "Running version " + packageInstall.Status.Version
+}
+
+func (r *packageDeployerDI) setReadiness(deployData *deployutil.DeployData, readinessState string, now metav1.Time) {
+	deployData.ProviderStatus.Readiness = &hubv1.Readiness{
+		State: readinessState,
+		Time:  now,
+	}
+}
+
+// setTypeSpecificStatus stores the status of the PackageInstall, which contains the installed version of the package.
+// The messages and conditions are limited, so that a long error output does not exceed the size of the deploy item.
+func (r *packageDeployerDI) setTypeSpecificStatus(ctx context.Context, packageInstall *packaging.PackageInstall,
+	deployData *deployutil.DeployData) {
+	log := util.GetLoggerFromContext(ctx)
+
+	if packageInstall == nil {
+		deployData.ProviderStatus.TypeSpecificStatus = nil
+		return
+	}
+
+	statusJSON, err := json.Marshal(newBoundedStatus(&packageInstall.Status))
+	if err != nil {
+		log.Error(err, "error marshaling status of package install")
+		return
+	}
+
+	deployData.ProviderStatus.TypeSpecificStatus = &runtime.RawExtension{
+		Raw: statusJSON,
+	}
+}
+
+// newBoundedStatus returns a copy of the status of a PackageInstall with truncated messages and at most maxConditions
+// conditions
+func newBoundedStatus(status *packaging.PackageInstallStatus) *packaging.PackageInstallStatus {
+	bounded := status.DeepCopy()
+	bounded.FriendlyDescription = truncate(bounded.FriendlyDescription)
+	bounded.UsefulErrorMessage = truncate(bounded.UsefulErrorMessage)
+
+	if len(bounded.Conditions) > maxConditions {
+		bounded.Conditions = bounded.Conditions[:maxConditions]
+	}
+
+	for i := range bounded.Conditions {
+		bounded.Conditions[i].Message = truncate(bounded.Conditions[i].Message)
+	}
+
+	return bounded
+}
+
+// truncate keeps the beginning of a message, without splitting a multi-byte character
+func truncate(s string) string {
+	if len(s) <= maxMessageLength {
+		return s
+	}
+
+	end := maxMessageLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	return s[:end] + "..."
+}
+
+func (r *packageDeployerDI) getPackageInstallKey(deployData *deployutil.DeployData) *types.NamespacedName {
+	return deployData.GetDeployItemKey()
+}
+
+// getRepositoryKey returns the key of the PackageRepository which is shared by the applications of the clusterbom with
+// the same fetch source
+func (r *packageDeployerDI) getRepositoryKey(deployData *deployutil.DeployData,
+	fetch *packaging.PackageRepositoryFetch) (*types.NamespacedName, error) {
+	fetchJSON, err := json.Marshal(fetch)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal package repository")
+	}
+
+	hash := sha256.Sum256(fetchJSON)
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+	return &types.NamespacedName{
+		Name:      clusterBomKey.Name + util.Separator + repositoryInfix + util.Separator + hex.EncodeToString(hash[:8]),
+		Namespace: clusterBomKey.Namespace,
+	}, nil
+}
+
+func (r *packageDeployerDI) getValuesSecretKey(deployData *deployutil.DeployData) *types.NamespacedName {
+	deployItemKey := deployData.GetDeployItemKey()
+	return &types.NamespacedName{
+		Name:      deployItemKey.Name + valuesSecretSuffix,
+		Namespace: deployItemKey.Namespace,
+	}
+}
+
+func (r *packageDeployerDI) successDescription(deployData *deployutil.DeployData) string {
+	if deployData.IsInstallOperation() {
+		return "install successful"
+	}
+
+	return "remove successful"
+}
@@ -0,0 +1,384 @@
+package carvelpackage
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	hubtesting "github.com/gardener/potter-controller/pkg/testing"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	kappctrl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	packaging "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/packaging/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testNamespace   = "garden-test"
+	testPackageName = "cert-manager.community.tanzu.vmware.com"
+)
+
+func TestNewPackageInstallSpec(t *testing.T) {
+	deployer := &packageDeployerDI{reconcileIntervalMinutes: 60}
+
+	packageSpecificData := &apitypes.PackageSpecificData{
+		PackageName: testPackageName,
+		Version:     ">=1.0.0 <2.0.0",
+	}
+	deployData := newTestDeployData(t, packageSpecificData, nil)
+
+	valuesData := map[string][]byte{
+		secretValuesKey: []byte(`{"password":"secret"}`),
+		valuesKey:       []byte("replicas: 2\n"),
+	}
+
+	spec := deployer.newPackageInstallSpec(deployData, packageSpecificData, valuesData)
+
+	assert.Equal(t, spec.PackageRef.RefName, testPackageName, "package name")
+	assert.Equal(t, spec.PackageRef.VersionSelection.Constraints, ">=1.0.0 <2.0.0", "version constraint")
+	assert.Nil(t, spec.PackageRef.VersionSelection.Prereleases, "prereleases")
+	assert.Equal(t, spec.Cluster.Namespace, "default", "namespace")
+	assert.Equal(t, spec.Cluster.KubeconfigSecretRef.Name, "target-secret", "kubeconfig secret")
+	assert.Equal(t, spec.Cluster.KubeconfigSecretRef.Key, kubeconfigSecretKey, "kubeconfig key")
+	assert.Equal(t, spec.SyncPeriod.Duration, 60*time.Minute, "sync period")
+
+	// secret values must override the inline values
+	assert.Equal(t, len(spec.Values), 2, "number of values")
+	assert.Equal(t, spec.Values[0].SecretRef.Name, "test-item-values", "values secret")
+	assert.Equal(t, spec.Values[0].SecretRef.Key, valuesKey, "first values")
+	assert.Equal(t, spec.Values[1].SecretRef.Key, secretValuesKey, "second values")
+}
+
+func TestNewPackageInstallSpecWithoutVersionAndValues(t *testing.T) {
+	deployer := &packageDeployerDI{reconcileIntervalMinutes: 60}
+
+	packageSpecificData := &apitypes.PackageSpecificData{
+		PackageName: testPackageName,
+		Namespace:   "cert-manager",
+	}
+	deployData := newTestDeployData(t, packageSpecificData, nil)
+
+	spec := deployer.newPackageInstallSpec(deployData, packageSpecificData, map[string][]byte{})
+
+	assert.Nil(t, spec.PackageRef.VersionSelection, "version selection")
+	assert.Equal(t, spec.Cluster.Namespace, "cert-manager", "namespace")
+	assert.Equal(t, len(spec.Values), 0, "number of values")
+}
+
+func TestValuesSecret(t *testing.T) {
+	internalSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "internal-secret",
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{
+			util.SecretValuesKey: []byte(`{"password":"secret"}`),
+		},
+	}
+
+	crAndSecretClient := fake.NewFakeClientWithScheme(newTestScheme(), internalSecret) // nolint
+	deployer := &packageDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	packageSpecificData := &apitypes.PackageSpecificData{
+		PackageName: testPackageName,
+		Values:      map[string]interface{}{"replicas": 2},
+	}
+	deployData := newTestDeployData(t, packageSpecificData, &hubv1.DeploymentConfig{InternalSecretName: "internal-secret"})
+
+	valuesData, err := deployer.getValuesData(hubtesting.CreateTestContext(), deployData, packageSpecificData)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, string(valuesData[valuesKey]), "replicas: 2\n", "values")
+	assert.Equal(t, string(valuesData[secretValuesKey]), `{"password":"secret"}`, "secret values")
+
+	err = deployer.applyValuesSecret(hubtesting.CreateTestContext(), deployData, valuesData)
+	assert.Nil(t, err, "error")
+
+	valuesSecret := &corev1.Secret{}
+	err = crAndSecretClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "test-item-values"}, valuesSecret)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, valuesSecret.Labels[hubv1.LabelPurpose], util.PurposePackageValues, "purpose")
+	assert.Equal(t, len(valuesSecret.Data), 2, "number of keys")
+
+	// an update replaces the content of the secret
+	err = deployer.applyValuesSecret(hubtesting.CreateTestContext(), deployData, map[string][]byte{valuesKey: []byte("replicas: 3\n")})
+	assert.Nil(t, err, "error")
+
+	valuesSecret = &corev1.Secret{}
+	err = crAndSecretClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "test-item-values"}, valuesSecret)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(valuesSecret.Data), 1, "number of keys")
+	assert.Equal(t, string(valuesSecret.Data[valuesKey]), "replicas: 3\n", "values")
+}
+
+func TestCleanup(t *testing.T) {
+	objects := []client.Object{
+		&packaging.PackageInstall{ObjectMeta: metav1.ObjectMeta{Name: "test-item", Namespace: testNamespace}},
+		&packaging.PackageRepository{ObjectMeta: metav1.ObjectMeta{Name: "test-item", Namespace: testNamespace}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-item-values", Namespace: testNamespace}},
+	}
+
+	crAndSecretClient := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(objects...).Build()
+	deployer := &packageDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	deployData := newTestDeployData(t, &apitypes.PackageSpecificData{PackageName: testPackageName}, nil)
+
+	err := deployer.Cleanup(hubtesting.CreateTestContext(), deployData, true)
+	assert.Nil(t, err, "error")
+
+	for _, obj := range objects {
+		err = crAndSecretClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+		assert.True(t, apierrors.IsNotFound(err), "object deleted")
+	}
+}
+
+// TestCleanupWaitsForPackageInstall tests that the values secret and the package repository are only deleted after
+// kapp-controller has removed the PackageInstall, and that the cleanup does not block in the meantime.
+func TestCleanupWaitsForPackageInstall(t *testing.T) {
+	deletionTimestamp := metav1.Now()
+	packageInstall := &packaging.PackageInstall{ObjectMeta: metav1.ObjectMeta{
+		Name:              "test-item",
+		Namespace:         testNamespace,
+		DeletionTimestamp: &deletionTimestamp,
+		Finalizers:        []string{"finalizers.packageinstall.packaging.carvel.dev/delete"},
+	}}
+	dependents := []client.Object{
+		&packaging.PackageRepository{ObjectMeta: metav1.ObjectMeta{Name: "test-item", Namespace: testNamespace}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-item-values", Namespace: testNamespace}},
+	}
+
+	crAndSecretClient := fake.NewClientBuilder().WithScheme(newTestScheme()).
+		WithObjects(append(dependents, packageInstall)...).Build()
+	deployer := &packageDeployerDI{crAndSecretClient: crAndSecretClient}
+	deployData := newTestDeployData(t, &apitypes.PackageSpecificData{PackageName: testPackageName}, nil)
+
+	start := time.Now()
+	err := deployer.Cleanup(hubtesting.CreateTestContext(), deployData, true)
+	_, ok := err.(*deployutil.DeletionInProgressError)
+	assert.True(t, ok, "deletion in progress error")
+	assert.True(t, time.Since(start) < time.Second, "cleanup does not wait")
+
+	for _, obj := range dependents {
+		err = crAndSecretClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+		assert.Nil(t, err, "dependent object not yet deleted")
+	}
+
+	// kapp-controller has removed the package
+	err = crAndSecretClient.Delete(context.Background(), packageInstall)
+	assert.Nil(t, err, "error")
+
+	err = deployer.Cleanup(hubtesting.CreateTestContext(), deployData, true)
+	assert.Nil(t, err, "error")
+
+	for _, obj := range dependents {
+		err = crAndSecretClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+		assert.True(t, apierrors.IsNotFound(err), "object deleted")
+	}
+}
+
+// TestSharedRepository tests that applications of a clusterbom which declare the same repository share one
+// PackageRepository, which is deleted when it is no longer used by any of them
+func TestSharedRepository(t *testing.T) {
+	crAndSecretClient := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+	deployer := &packageDeployerDI{
+		crAndSecretClient: crAndSecretClient,
+		uncachedClient:    &uncachedFakeClient{Client: crAndSecretClient},
+	}
+
+	repository := &packaging.PackageRepositoryFetch{
+		ImgpkgBundle: &kappctrl.AppFetchImgpkgBundle{Image: "projects.registry.vmware.com/tce/main:0.9.1"},
+	}
+	otherRepository := &packaging.PackageRepositoryFetch{
+		ImgpkgBundle: &kappctrl.AppFetchImgpkgBundle{Image: "projects.registry.vmware.com/tce/main:0.10.0"},
+	}
+
+	packageSpecificData := &apitypes.PackageSpecificData{PackageName: testPackageName, Repository: repository}
+	deployData1 := newTestDeployDataForItem(t, "test-app1", packageSpecificData, nil)
+	deployData2 := newTestDeployDataForItem(t, "test-app2", packageSpecificData, nil)
+
+	repositoryKey, err := deployer.getRepositoryKey(deployData1, repository)
+	assert.Nil(t, err, "error")
+	repositoryKey2, err := deployer.getRepositoryKey(deployData2, repository)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, *repositoryKey2, *repositoryKey, "repository shared by the applications of the clusterbom")
+	otherRepositoryKey, err := deployer.getRepositoryKey(deployData1, otherRepository)
+	assert.Nil(t, err, "error")
+	assert.True(t, otherRepositoryKey.Name != repositoryKey.Name, "repository per fetch source")
+
+	for _, deployData := range []*deployutil.DeployData{deployData1, deployData2} {
+		err = deployer.applyRepository(hubtesting.CreateTestContext(), deployData, repositoryKey, repository)
+		assert.Nil(t, err, "error")
+		_, err = deployer.applyPackageInstall(hubtesting.CreateTestContext(), deployData, &packaging.PackageInstallSpec{}, repositoryKey.Name)
+		assert.Nil(t, err, "error")
+	}
+
+	storedRepository := &packaging.PackageRepository{}
+	err = crAndSecretClient.Get(context.Background(), *repositoryKey, storedRepository)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, storedRepository.Labels[hubv1.LabelClusterBomName], "test", "clusterbom name")
+
+	// the first application switches to another repository
+	err = deployer.applyRepository(hubtesting.CreateTestContext(), deployData1, otherRepositoryKey, otherRepository)
+	assert.Nil(t, err, "error")
+	oldRepositoryName, err := deployer.applyPackageInstall(hubtesting.CreateTestContext(), deployData1, &packaging.PackageInstallSpec{},
+		otherRepositoryKey.Name)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, oldRepositoryName, repositoryKey.Name, "old repository")
+
+	err = deployer.releaseRepository(hubtesting.CreateTestContext(), deployData1, oldRepositoryName)
+	assert.Nil(t, err, "error")
+	err = crAndSecretClient.Get(context.Background(), *repositoryKey, &packaging.PackageRepository{})
+	assert.Nil(t, err, "repository still used by the second application")
+
+	// the second application is removed
+	err = crAndSecretClient.Delete(context.Background(), &packaging.PackageInstall{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-app2",
+		Namespace: testNamespace,
+	}})
+	assert.Nil(t, err, "error")
+
+	err = deployer.cleanupDependents(hubtesting.CreateTestContext(), deployData2)
+	assert.Nil(t, err, "error")
+	err = crAndSecretClient.Get(context.Background(), *repositoryKey, &packaging.PackageRepository{})
+	assert.True(t, apierrors.IsNotFound(err), "unused repository deleted")
+	err = crAndSecretClient.Get(context.Background(), *otherRepositoryKey, &packaging.PackageRepository{})
+	assert.Nil(t, err, "repository of the first application not deleted")
+}
+
+func TestCleanupWithoutCluster(t *testing.T) {
+	packageInstall := &packaging.PackageInstall{ObjectMeta: metav1.ObjectMeta{
+		Name:       "test-item",
+		Namespace:  testNamespace,
+		Finalizers: []string{"finalizers.packageinstall.packaging.carvel.dev/delete"},
+	}}
+
+	crAndSecretClient := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(packageInstall).Build()
+	deployer := &packageDeployerDI{crAndSecretClient: crAndSecretClient}
+	deployData := newTestDeployData(t, &apitypes.PackageSpecificData{PackageName: testPackageName}, nil)
+
+	err := deployer.Cleanup(hubtesting.CreateTestContext(), deployData, false)
+	assert.Nil(t, err, "error")
+
+	err = crAndSecretClient.Get(context.Background(), client.ObjectKeyFromObject(packageInstall), packageInstall)
+	assert.True(t, apierrors.IsNotFound(err), "package install deleted")
+}
+
+func TestReadinessFromConditions(t *testing.T) {
+	tests := []struct {
+		name              string
+		conditions        []kappctrl.AppCondition
+		expectedReadiness string
+	}{
+		{
+			name:              "no conditions",
+			expectedReadiness: util.StateUnknown,
+		},
+		{
+			name:              "reconciling",
+			conditions:        []kappctrl.AppCondition{{Type: kappctrl.Reconciling, Status: corev1.ConditionTrue}},
+			expectedReadiness: util.StatePending,
+		},
+		{
+			name:              "reconcile succeeded",
+			conditions:        []kappctrl.AppCondition{{Type: kappctrl.ReconcileSucceeded, Status: corev1.ConditionTrue}},
+			expectedReadiness: util.StateOk,
+		},
+		{
+			name:              "reconcile failed",
+			conditions:        []kappctrl.AppCondition{{Type: kappctrl.ReconcileFailed, Status: corev1.ConditionTrue}},
+			expectedReadiness: util.StateFailed,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			packageInstall := &packaging.PackageInstall{}
+			packageInstall.Status.Conditions = test.conditions
+			assert.Equal(t, getReadinessFromConditions(packageInstall), test.expectedReadiness, "readiness")
+		})
+	}
+}
+
+func TestTypeSpecificStatusContainsVersion(t *testing.T) {
+	deployer := &packageDeployerDI{}
+	deployData := newTestDeployData(t, &apitypes.PackageSpecificData{PackageName: testPackageName}, nil)
+
+	packageInstall := &packaging.PackageInstall{}
+	packageInstall.Status.Version = "1.5.3"
+	packageInstall.Status.LastAttemptedVersion = "1.5.3"
+
+	deployer.setTypeSpecificStatus(hubtesting.CreateTestContext(), packageInstall, deployData)
+
+	var status map[string]interface{}
+	err := json.Unmarshal(deployData.ProviderStatus.TypeSpecificStatus.Raw, &status)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, status["version"], "1.5.3", "version")
+	assert.Equal(t, runningMessage(packageInstall), "Running version 1.5.3", "running message")
+}
+
+func TestTypeSpecificStatusIsBounded(t *testing.T) {
+	deployer := &packageDeployerDI{}
+	deployData := newTestDeployData(t, &apitypes.PackageSpecificData{PackageName: testPackageName}, nil)
+
+	longMessage := strings.Repeat("ä", maxMessageLength)
+	packageInstall := &packaging.PackageInstall{}
+	packageInstall.Status.UsefulErrorMessage = longMessage
+	for i := 0; i < 2*maxConditions; i++ {
+		packageInstall.Status.Conditions = append(packageInstall.Status.Conditions,
+			kappctrl.AppCondition{Type: kappctrl.ReconcileFailed, Status: corev1.ConditionTrue, Message: longMessage})
+	}
+
+	deployer.setTypeSpecificStatus(hubtesting.CreateTestContext(), packageInstall, deployData)
+
+	status := packaging.PackageInstallStatus{}
+	err := json.Unmarshal(deployData.ProviderStatus.TypeSpecificStatus.Raw, &status)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(status.Conditions), maxConditions, "number of conditions")
+	assert.True(t, len(status.UsefulErrorMessage) <= maxMessageLength+len("..."), "length of message")
+	assert.True(t, utf8.ValidString(status.UsefulErrorMessage), "message is valid utf-8")
+	assert.True(t, utf8.ValidString(status.Conditions[0].Message), "condition message is valid utf-8")
+	assert.Equal(t, len(packageInstall.Status.Conditions), 2*maxConditions, "conditions of the package install unchanged")
+}
+
+// uncachedFakeClient provides the fake client as synchronize.UncachedClient
+type uncachedFakeClient struct {
+	client.Client
+}
+
+func (c *uncachedFakeClient) GetUncached(ctx context.Context, key types.NamespacedName, obj client.Object) error {
+	return c.Get(ctx, key, obj)
+}
+
+func (c *uncachedFakeClient) ListUncached(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.List(ctx, list, opts...)
+}
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = packaging.AddToScheme(scheme)
+	return scheme
+}
+
+func newTestDeployData(t *testing.T, typeSpecificData *apitypes.PackageSpecificData, deploymentConfig *hubv1.DeploymentConfig) *deployutil.DeployData {
+	return newTestDeployDataForItem(t, "test-item", typeSpecificData, deploymentConfig)
+}
+
+func newTestDeployDataForItem(t *testing.T, name string, typeSpecificData *apitypes.PackageSpecificData,
+	deploymentConfig *hubv1.DeploymentConfig) *deployutil.DeployData {
+	deployItem := hubtesting.CreateDeployItemForConfig(t, name, testNamespace, typeSpecificData, deploymentConfig)
+	return hubtesting.CreateDeployData(t, deployItem)
+}
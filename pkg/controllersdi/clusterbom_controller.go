@@ -25,6 +25,7 @@ import (
 	"github.com/gardener/potter-controller/pkg/auditlog"
 	"github.com/gardener/potter-controller/pkg/avcheck"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	landscaper "github.com/gardener/landscaper/apis/core/v1alpha1"
@@ -60,15 +61,15 @@ type ClusterBomReconciler struct {
 	hubControllerClient synchronize.UncachedClient
 }
 
-func NewClusterBomReconciler(cli client.Client, log logr.Logger, scheme *runtime.Scheme, auditLog bool,
+func NewClusterBomReconciler(cli client.Client, log logr.Logger, scheme *runtime.Scheme, auditLogConfig *auditlog.Config,
 	blockObject *synchronize.BlockObject, av *avcheck.AVCheck, uncachedClient, hubControllerClient synchronize.UncachedClient,
 	avCheckConfig *avcheck.Configuration) (*ClusterBomReconciler, error) {
 	var auditLogger auditlog.AuditLogger
 	var err error
 
-	if auditLog {
+	if auditLogConfig.IsEnabled() {
 		log.Info("Audit logging is enabled, start audit logging")
-		auditLogger, err = auditlog.NewAuditLogger(log)
+		auditLogger, err = auditlog.NewAuditLogger(auditLogConfig, log)
 		if err != nil {
 			return nil, err
 		}
@@ -106,6 +107,10 @@ func (r *ClusterBomReconciler) SetupWithManager(mgr ctrl.Manager) error {
 }
 
 func (r *ClusterBomReconciler) Close() error {
+	if r.auditLogger == nil {
+		return nil
+	}
+
 	return r.auditLogger.Close()
 }
 
@@ -140,6 +145,14 @@ func (r *ClusterBomReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return r.returnFailure(err)
 	}
 
+	if associatedObjects.clusterbomExists {
+		ctx = tracing.ExtractFromAnnotations(ctx, &associatedObjects.clusterbom)
+	}
+
+	ctx, span := tracing.Start(ctx, "ClusterBomReconciler.Reconcile")
+	defer span.End()
+	ctx, log = tracing.EnrichContextAndLogger(ctx)
+
 	if associatedObjects.clusterbomExists {
 		d := &clusterbomDeactivator{}
 		stopReconcile, actionProgressing, err := d.handleDeactivationOrReactivation(ctx, associatedObjects, r.Client)
@@ -290,11 +303,38 @@ func (r *ClusterBomReconciler) handleNormalClusterBom(ctx context.Context, a *As
 		return r.returnFailure(err)
 	}
 
+	err = r.handleResetRetriesAnnotation(ctx, &a.clusterbom)
+	if err != nil {
+		return r.returnFailure(err)
+	}
+
+	err = r.handleResumeAnnotation(ctx, &a.clusterbom)
+	if err != nil {
+		return r.returnFailure(err)
+	}
+
 	return r.returnSuccess()
 }
 
 func (r *ClusterBomReconciler) handleReconcileAnnotation(ctx context.Context, clusterbom *hubv1.ClusterBom) error {
-	if util.HasAnnotation(clusterbom, util.AnnotationKeyReconcile, util.AnnotationValueReconcile) {
+	return r.propagateAnnotationToDeployItems(ctx, clusterbom, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
+}
+
+// handleResetRetriesAnnotation propagates the reset-retries annotation of a clusterbom to its deploy items. The
+// DeploymentReconciler then resets the retry counters of the failed deploy items and retries them immediately.
+func (r *ClusterBomReconciler) handleResetRetriesAnnotation(ctx context.Context, clusterbom *hubv1.ClusterBom) error {
+	return r.propagateAnnotationToDeployItems(ctx, clusterbom, util.AnnotationKeyResetRetries, util.AnnotationValueResetRetries)
+}
+
+// handleResumeAnnotation propagates the resume annotation of a clusterbom to its deploy items. The deployers then
+// resume the reconciliation of the applications which are paused by a circuit breaker.
+func (r *ClusterBomReconciler) handleResumeAnnotation(ctx context.Context, clusterbom *hubv1.ClusterBom) error {
+	return r.propagateAnnotationToDeployItems(ctx, clusterbom, util.AnnotationKeyResume, util.AnnotationValueResume)
+}
+
+func (r *ClusterBomReconciler) propagateAnnotationToDeployItems(ctx context.Context, clusterbom *hubv1.ClusterBom,
+	annotationKey, annotationValue string) error {
+	if util.HasAnnotation(clusterbom, annotationKey, annotationValue) {
 		log := util.GetLoggerFromContext(ctx)
 
 		deployItemList := landscaper.DeployItemList{}
@@ -306,7 +346,7 @@ func (r *ClusterBomReconciler) handleReconcileAnnotation(ctx context.Context, cl
 
 		for i := range deployItemList.Items {
 			deployItem := &deployItemList.Items[i]
-			util.AddAnnotation(deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
+			util.AddAnnotation(deployItem, annotationKey, annotationValue)
 			err := r.Client.Update(ctx, deployItem)
 			if err != nil {
 				if !apierrors.IsConflict(err) {
@@ -317,7 +357,7 @@ func (r *ClusterBomReconciler) handleReconcileAnnotation(ctx context.Context, cl
 		}
 
 		tmpKey := util.GetKey(clusterbom)
-		return r.removeReconcileAnnotation(ctx, tmpKey)
+		return r.removeAnnotation(ctx, tmpKey, annotationKey)
 	}
 
 	return nil
@@ -490,6 +530,7 @@ func (r *ClusterBomReconciler) copyAppConfigToDeployItem(appconfig *hubv1.Applic
 			TypeSpecificData:  appconfig.TypeSpecificData,
 			NoReconcile:       appconfig.NoReconcile,
 			ReadyRequirements: appconfig.ReadyRequirements,
+			RetryPolicy:       appconfig.RetryPolicy,
 		},
 	}
 
@@ -539,6 +580,8 @@ func (r *ClusterBomReconciler) updateDeployItem(ctx context.Context, deployItem
 	clusterBom *hubv1.ClusterBom) error {
 	log := util.GetLoggerFromContext(ctx)
 
+	tracing.InjectIntoAnnotations(ctx, deployItem)
+
 	log.V(util.LogLevelDebug).Info("Updating existing deploy item", util.LogKeyDeployItemName, deployItem.Name, "deployitem", deployItem)
 	err := r.Update(ctx, deployItem)
 	if err != nil {
@@ -564,6 +607,8 @@ func (r *ClusterBomReconciler) updateDeployItem(ctx context.Context, deployItem
 func (r *ClusterBomReconciler) createDeployItem(ctx context.Context, deployItem *landscaper.DeployItem) error { // nolint
 	log := util.GetLoggerFromContext(ctx)
 
+	tracing.InjectIntoAnnotations(ctx, deployItem)
+
 	log.V(util.LogLevelDebug).Info("Creating deploy item", util.LogKeyDeployItemName, deployItem.Name, "deployitem", deployItem)
 	err := r.Create(ctx, deployItem)
 	if err != nil {
@@ -647,6 +692,13 @@ func (r *ClusterBomReconciler) auditLog(ctx context.Context, action auditlog.Act
 
 	auditMsg := auditlog.NewAuditMessage(action, bomName, projectName, clusterName, userID, clusterURL, bomAsString,
 		oldBomAsString, nil)
+
+	// the requester annotation belongs to the last change of the spec, not to the deletion
+	if action == auditlog.CreateOrUpdate {
+		auditMsg.Requester = r.getRequester(ctx, &a.clusterbom)
+	}
+	auditMsg.Changes = r.getAppConfigChanges(ctx, action, a)
+
 	_, err = r.auditLogger.Log(auditMsg)
 	if err != nil {
 		log.Error(err, "Failed to write audit log message")
@@ -655,6 +707,93 @@ func (r *ClusterBomReconciler) auditLog(ctx context.Context, action auditlog.Act
 	return auditMsg
 }
 
+// getRequester returns the user who created or last changed the spec of the clusterbom, as recorded by the admission
+// webhook, or nil if the clusterbom has no requester annotation.
+func (r *ClusterBomReconciler) getRequester(ctx context.Context, clusterbom *hubv1.ClusterBom) *auditlog.Requester {
+	log := util.GetLoggerFromContext(ctx)
+
+	value, ok := util.GetAnnotation(clusterbom, util.AnnotationKeyRequester)
+	if !ok {
+		return nil
+	}
+
+	requester := &auditlog.Requester{}
+	if err := json.Unmarshal([]byte(value), requester); err != nil {
+		log.Error(err, "Failed to unmarshal requester annotation")
+		return nil
+	}
+
+	return requester
+}
+
+// getAppConfigChanges compares the application configs of the clusterbom with the existing deploy items. Application
+// configs without deploy item are added, deploy items without application config, or of a deleted clusterbom, are
+// removed.
+func (r *ClusterBomReconciler) getAppConfigChanges(ctx context.Context, action auditlog.Action,
+	a *AssociatedObjects) []auditlog.AppConfigChange {
+	log := util.GetLoggerFromContext(ctx)
+
+	changes := []auditlog.AppConfigChange{}
+	appendChange := func(change *auditlog.AppConfigChange, err error) {
+		if err != nil {
+			log.Error(err, "Failed to compute changes of application config for audit log")
+		} else if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	if action == auditlog.CreateOrUpdate && a.clusterbomExists {
+		for i := range a.clusterbom.Spec.ApplicationConfigs {
+			appconfig := &a.clusterbom.Spec.ApplicationConfigs[i]
+
+			newDeployItem := landscaper.DeployItem{}
+			if err := r.copyAppConfigToDeployItem(appconfig, &newDeployItem, &a.clusterbom); err != nil {
+				appendChange(nil, err)
+				continue
+			}
+
+			newConfig, err := getDeploymentConfig(&newDeployItem)
+			if err != nil {
+				appendChange(nil, err)
+				continue
+			}
+
+			oldDeployItem := findDeployItemInList(&a.deployItemList, appconfig.ID)
+			if oldDeployItem == nil {
+				appendChange(auditlog.DiffAppConfig("", nil, appconfig.ConfigType, newConfig))
+				continue
+			}
+
+			oldConfig, err := getDeploymentConfig(oldDeployItem)
+			if err != nil {
+				appendChange(nil, err)
+				continue
+			}
+
+			appendChange(auditlog.DiffAppConfig(string(oldDeployItem.Spec.Type), oldConfig, appconfig.ConfigType, newConfig))
+		}
+	}
+
+	for i := range a.deployItemList.Items {
+		deployItem := &a.deployItemList.Items[i]
+
+		if action == auditlog.CreateOrUpdate &&
+			findAppDeploymentConfigInList(a.clusterbom.Spec.ApplicationConfigs, util.GetAppConfigIDFromDeployItem(deployItem)) != nil {
+			continue
+		}
+
+		oldConfig, err := getDeploymentConfig(deployItem)
+		if err != nil {
+			appendChange(nil, err)
+			continue
+		}
+
+		appendChange(auditlog.DiffAppConfig(string(deployItem.Spec.Type), oldConfig, "", nil))
+	}
+
+	return changes
+}
+
 func (r *ClusterBomReconciler) auditLogResult(ctx context.Context, auditMessage *auditlog.AuditMessageInfo, success bool) {
 	log := util.GetLoggerFromContext(ctx)
 	if auditMessage == nil || r.auditLogger == nil {
@@ -688,37 +827,11 @@ func (r *ClusterBomReconciler) cleanupSecrets(ctx context.Context, objects *Asso
 	for i := range objects.secretList.Items {
 		secret := &objects.secretList.Items[i]
 
-		found := false
-		for j := range objects.clusterbom.Spec.ApplicationConfigs {
-			appConfig := &objects.clusterbom.Spec.ApplicationConfigs[j]
-
-			if appConfig.SecretValues != nil && appConfig.SecretValues.InternalSecretName == secret.Name {
-				found = true
-				break
-			}
-
-			if r.isContainedInNamedSecrets(appConfig, secret) {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			if secret.ObjectMeta.CreationTimestamp.Time.Add(time.Hour).Before(time.Now()) {
-				// error is not checked because it is not so relevant here
-				r.deleteSecret(ctx, secret) // nolint
-			}
-		}
-	}
-}
-
-func (r *ClusterBomReconciler) isContainedInNamedSecrets(appConfig *hubv1.ApplicationConfig, secret *corev1.Secret) bool {
-	for _, v := range appConfig.NamedSecretValues {
-		if v.InternalSecretName == secret.Name {
-			return true
+		if !isSecretValuesReferenced(&objects.clusterbom, secret.Name) && isSecretValuesGracePeriodOver(secret, time.Now()) {
+			// error is not checked because it is not so relevant here
+			r.deleteSecret(ctx, secret) // nolint
 		}
 	}
-	return false
 }
 
 func (r *ClusterBomReconciler) cleanupAllSecrets(ctx context.Context, objects *AssociatedObjects) error {
@@ -737,40 +850,41 @@ func (r *ClusterBomReconciler) deleteSecret(ctx context.Context, secret *corev1.
 	return deleteSecret(ctx, secret, r.Client, r.hubControllerClient)
 }
 
-func (r *ClusterBomReconciler) removeReconcileAnnotation(ctx context.Context, clusterBomKey *types.NamespacedName) error {
+func (r *ClusterBomReconciler) removeAnnotation(ctx context.Context, clusterBomKey *types.NamespacedName, annotationKey string) error {
 	log := util.GetLoggerFromContext(ctx)
 
 	var err error
 
 	util.Repeat(func() bool {
-		err = r.removeReconcileAnnotationOnce(ctx, clusterBomKey)
+		err = r.removeAnnotationOnce(ctx, clusterBomKey, annotationKey)
 		done := (err == nil) || !apierrors.IsConflict(err)
 		return done
 	}, 10, time.Second)
 
 	if err != nil {
-		log.Error(err, "error removing reconcile annotation")
+		log.Error(err, "error removing annotation", "annotation", annotationKey)
 		return err
 	}
 
 	return nil
 }
 
-func (r *ClusterBomReconciler) removeReconcileAnnotationOnce(ctx context.Context, clusterBomKey *types.NamespacedName) error {
+func (r *ClusterBomReconciler) removeAnnotationOnce(ctx context.Context, clusterBomKey *types.NamespacedName, annotationKey string) error {
 	log := util.GetLoggerFromContext(ctx)
 
 	clusterBom := hubv1.ClusterBom{}
 	err := r.Get(ctx, *clusterBomKey, &clusterBom)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			log.V(util.LogLevelWarning).Info("no clusterbom found for removing reconcile annotation", "error", err.Error())
+			log.V(util.LogLevelWarning).Info("no clusterbom found for removing annotation", "annotation", annotationKey,
+				"error", err.Error())
 			return nil
 		}
-		log.Error(err, "error fetching cluster bom for removing reconcile annotation")
+		log.Error(err, "error fetching cluster bom for removing annotation", "annotation", annotationKey)
 		return err
 	}
 
-	util.RemoveAnnotation(&clusterBom, util.AnnotationKeyReconcile)
+	util.RemoveAnnotation(&clusterBom, annotationKey)
 
 	return r.Update(ctx, &clusterBom)
 }
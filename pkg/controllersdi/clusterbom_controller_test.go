@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/auditlog"
 	"github.com/gardener/potter-controller/pkg/synchronize"
 	testing2 "github.com/gardener/potter-controller/pkg/testing"
 	"github.com/gardener/potter-controller/pkg/util"
@@ -602,3 +603,74 @@ func Test_isEqualConfig(t *testing.T) {
 	g.Expect(isEqualRawJSON(&ext1, nil)).To(gomega.BeFalse())
 	g.Expect(isEqualRawJSON(nil, &ext2)).To(gomega.BeFalse())
 }
+
+func Test_getAppConfigChanges(t *testing.T) {
+	gomega.RegisterFailHandler(ginkgo.Fail)
+	g := gomega.NewGomegaWithT(t)
+
+	newDeployItem := func(id, value string) v1alpha1.DeployItem {
+		encodedConfig, err := json.Marshal(hubv1.HubDeployItemConfiguration{
+			LocalSecretRef: "asdf",
+			DeploymentConfig: hubv1.DeploymentConfig{
+				ID:               id,
+				TypeSpecificData: *testing2.FakeRawExtensionWithProperty(value),
+			},
+		})
+		g.Expect(err).To(gomega.BeNil())
+
+		return v1alpha1.DeployItem{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   util.CreateDeployItemName(testBomName, id),
+				Labels: map[string]string{hubv1.LabelApplicationConfigID: id},
+			},
+			Spec: v1alpha1.DeployItemSpec{
+				Type:          util.ConfigTypeHelm,
+				Configuration: &runtime.RawExtension{Raw: encodedConfig},
+			},
+		}
+	}
+
+	a := &AssociatedObjects{
+		clusterbomExists: true,
+		clusterbom: hubv1.ClusterBom{
+			ObjectMeta: v1.ObjectMeta{Name: testBomName},
+			Spec: hubv1.ClusterBomSpec{
+				SecretRef: "asdf",
+				ApplicationConfigs: []hubv1.ApplicationConfig{
+					{ID: "unchanged", ConfigType: util.ConfigTypeHelm, TypeSpecificData: *testing2.FakeRawExtensionWithProperty("v1")},
+					{ID: "modified", ConfigType: util.ConfigTypeHelm, TypeSpecificData: *testing2.FakeRawExtensionWithProperty("v2")},
+					{ID: "added", ConfigType: util.ConfigTypeHelm, TypeSpecificData: *testing2.FakeRawExtensionWithProperty("v1")},
+				},
+			},
+		},
+		deployItemList: v1alpha1.DeployItemList{
+			Items: []v1alpha1.DeployItem{
+				newDeployItem("unchanged", "v1"),
+				newDeployItem("modified", "v1"),
+				newDeployItem("removed", "v1"),
+			},
+		},
+	}
+
+	r := ClusterBomReconciler{}
+	ctx, _ := util.NewContextAndLogger(ctrl.Log.WithName("test"))
+
+	changes := r.getAppConfigChanges(ctx, auditlog.CreateOrUpdate, a)
+	g.Expect(changes).To(gomega.HaveLen(3))
+	g.Expect(changes[0].ID).To(gomega.Equal("modified"))
+	g.Expect(changes[0].Change).To(gomega.Equal(auditlog.ChangeModified))
+	g.Expect(changes[0].Fields).To(gomega.Equal([]auditlog.FieldChange{
+		{Path: "typeSpecificData.fakeString", Old: `"v1"`, New: `"v2"`},
+	}))
+	g.Expect(changes[1].ID).To(gomega.Equal("added"))
+	g.Expect(changes[1].Change).To(gomega.Equal(auditlog.ChangeAdded))
+	g.Expect(changes[2].ID).To(gomega.Equal("removed"))
+	g.Expect(changes[2].Change).To(gomega.Equal(auditlog.ChangeRemoved))
+
+	// all deploy items are removed with the clusterbom
+	changes = r.getAppConfigChanges(ctx, auditlog.Delete, a)
+	g.Expect(changes).To(gomega.HaveLen(3))
+	for i := range changes {
+		g.Expect(changes[i].Change).To(gomega.Equal(auditlog.ChangeRemoved))
+	}
+}
@@ -23,7 +23,9 @@ import (
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/avcheck"
 	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/notification"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 	"github.com/gardener/potter-controller/pkg/util/predicate"
 
@@ -58,6 +60,7 @@ type ClusterBomStateReconciler struct {
 	AVCheck        *avcheck.AVCheck
 	AvCheckConfig  *avcheck.Configuration
 	UncachedClient synchronize.UncachedClient
+	Notifier       *notification.Notifier
 }
 
 func (r *ClusterBomStateReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -243,6 +246,14 @@ func (r *ClusterBomStateReconciler) reconcileDeployItemWithClusterBom(ctx contex
 		return r.returnFailure(err)
 	}
 
+	if deployItem != nil {
+		ctx = tracing.ExtractFromAnnotations(ctx, deployItem)
+	}
+
+	ctx, span := tracing.Start(ctx, "ClusterBomStateReconciler.Reconcile")
+	defer span.End()
+	ctx, logger = tracing.EnrichContextAndLogger(ctx)
+
 	// Check whether target cluster exists
 	secretKey := util.GetSecretKeyFromClusterBom(clusterBom)
 	secretExists, err := r.existsSecret(ctx, secretKey)
@@ -361,11 +372,18 @@ func (r *ClusterBomStateReconciler) adjustClusterBomStatus(ctx context.Context,
 	newStatus.OverallProgress = stat.getOverallProgress()
 	newStatus.OverallTime = metav1.Now()
 
+	// updateClusterBomStatus overwrites the status of the clusterbom, so that the old status is needed for notifications
+	oldStatus := clusterBom.Status.DeepCopy()
+
 	err = updateClusterBomStatus(ctx, r.Client, clusterBom, &newStatus, r.AvCheckConfig)
 	if err != nil {
 		return r.returnFailure(err)
 	}
 
+	if r.Notifier != nil {
+		r.Notifier.Notify(ctx, clusterBom, oldStatus, &newStatus)
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -449,6 +467,8 @@ func (r *ClusterBomStateReconciler) computeState(ctx context.Context, deployItem
 			state = util.StatePending
 		} else if deployItemStatus.LastOperation.State == util.StateOk {
 			state = util.StateOk
+		} else if deployItemStatus.LastOperation.State == util.StatePending {
+			state = util.StatePending
 		} else {
 			state = util.StateFailed
 		}
@@ -5,14 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"reflect"
+	"time"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/avcheck"
 	"github.com/gardener/potter-controller/pkg/secrets"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	landscaper "github.com/gardener/landscaper/apis/core/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,6 +23,44 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// secretValuesGracePeriod is the time for which an unreferenced secret for secret values is kept after it was created or
+// last admitted. The admission webhook creates such secrets before the clusterbom which references them is written.
+const secretValuesGracePeriod = time.Hour
+
+// isSecretValuesGracePeriodOver returns whether the grace period of an unreferenced secret for secret values is over,
+// so that it can be deleted. The admission time is renewed if the admission webhook reuses an existing secret.
+func isSecretValuesGracePeriodOver(secret *corev1.Secret, now time.Time) bool {
+	lastUsed := secret.ObjectMeta.CreationTimestamp.Time
+
+	if value, ok := util.GetAnnotation(secret, util.AnnotationKeyAdmittedAt); ok {
+		if admittedAt, err := time.Parse(time.RFC3339, value); err == nil && admittedAt.After(lastUsed) {
+			lastUsed = admittedAt
+		}
+	}
+
+	return lastUsed.Add(secretValuesGracePeriod).Before(now)
+}
+
+// isSecretValuesReferenced returns whether an application config of the clusterbom references the secret for secret
+// values or named secret values with the given name.
+func isSecretValuesReferenced(clusterBom *hubv1.ClusterBom, secretName string) bool {
+	for i := range clusterBom.Spec.ApplicationConfigs {
+		appConfig := &clusterBom.Spec.ApplicationConfigs[i]
+
+		if appConfig.SecretValues != nil && appConfig.SecretValues.InternalSecretName == secretName {
+			return true
+		}
+
+		for _, v := range appConfig.NamedSecretValues {
+			if v.InternalSecretName == secretName {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func deleteDeployItems(ctx context.Context, cli client.Client, deployItemList *landscaper.DeployItemList) error {
 	for i := range deployItemList.Items {
 		deploymentItem := &deployItemList.Items[i]
@@ -108,8 +149,12 @@ func updateClusterBomStatus(ctx context.Context, cli client.StatusClient,
 
 	log.V(util.LogLevelDebug).Info("Updating status of clusterbom")
 
+	ctx, span := tracing.Start(ctx, "updateClusterBomStatus",
+		attribute.String(tracing.AttributeOutcome, newStatus.OverallState))
+
 	clusterBom.Status = *newStatus
 	err := cli.Status().Update(ctx, clusterBom)
+	tracing.End(span, err)
 	if err != nil {
 		if util.IsConcurrentModificationErr(err) {
 			if avCheckConfig != nil && clusterBom.Name == avCheckConfig.BomName && clusterBom.Namespace == avCheckConfig.Namespace {
@@ -213,6 +258,20 @@ func findAppDeploymentConfigInList(applicationConfigs []hubv1.ApplicationConfig,
 	return nil
 }
 
+func getDeploymentConfig(deployItem *landscaper.DeployItem) (*hubv1.DeploymentConfig, error) {
+	deployItemConfig := &hubv1.HubDeployItemConfiguration{}
+
+	if deployItem.Spec.Configuration == nil {
+		return &deployItemConfig.DeploymentConfig, nil
+	}
+
+	if err := json.Unmarshal(deployItem.Spec.Configuration.Raw, deployItemConfig); err != nil {
+		return nil, err
+	}
+
+	return &deployItemConfig.DeploymentConfig, nil
+}
+
 func isEqualConfig(appConfig *hubv1.ApplicationConfig, deployItem *landscaper.DeployItem) (bool, error) {
 	deployItemConfig := &hubv1.HubDeployItemConfiguration{}
 
@@ -224,6 +283,7 @@ func isEqualConfig(appConfig *hubv1.ApplicationConfig, deployItem *landscaper.De
 		appConfig.ConfigType == string(deployItem.Spec.Type) &&
 		appConfig.NoReconcile == deployItemConfig.DeploymentConfig.NoReconcile &&
 		reflect.DeepEqual(appConfig.ReadyRequirements, deployItemConfig.DeploymentConfig.ReadyRequirements) &&
+		reflect.DeepEqual(appConfig.RetryPolicy, deployItemConfig.DeploymentConfig.RetryPolicy) &&
 		isEqualRawJSON(appConfig.Values, deployItemConfig.DeploymentConfig.Values) &&
 		isEqualRawJSON(&appConfig.TypeSpecificData, &deployItemConfig.DeploymentConfig.TypeSpecificData) &&
 		isEqualSecretValues(appConfig.SecretValues, deployItemConfig.DeploymentConfig.InternalSecretName) &&
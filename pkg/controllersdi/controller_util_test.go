@@ -2,8 +2,11 @@ package controllersdi
 
 import (
 	"testing"
+	"time"
 
 	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/deployutil"
@@ -70,3 +73,16 @@ func TestComputeOverallState(t *testing.T) {
 	overallState = clusterBomStateReconciler.computeOverallState(applicationStates)
 	assert.Equal(t, overallState, util.StateFailed, "overallState 6")
 }
+
+func TestIsSecretValuesGracePeriodOver(t *testing.T) {
+	created := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+	assert.False(t, isSecretValuesGracePeriodOver(secret, created.Add(30*time.Minute)), "grace period over after 30m")
+	assert.True(t, isSecretValuesGracePeriodOver(secret, created.Add(2*time.Hour)), "grace period over after 2h")
+
+	// the admission time is renewed when the webhook reuses the secret
+	util.AddAnnotation(secret, util.AnnotationKeyAdmittedAt, created.Add(90*time.Minute).Format(time.RFC3339))
+	assert.False(t, isSecretValuesGracePeriodOver(secret, created.Add(2*time.Hour)), "grace period over after reuse")
+	assert.True(t, isSecretValuesGracePeriodOver(secret, created.Add(3*time.Hour)), "grace period over after 3h")
+}
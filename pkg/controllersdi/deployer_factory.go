@@ -1,9 +1,18 @@
 package controllersdi
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gardener/potter-controller/pkg/carvelpackage"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
 	"github.com/gardener/potter-controller/pkg/deployutil"
 	"github.com/gardener/potter-controller/pkg/helm"
 	"github.com/gardener/potter-controller/pkg/kapp"
+	"github.com/gardener/potter-controller/pkg/kustomize"
+	"github.com/gardener/potter-controller/pkg/manifest"
+	"github.com/gardener/potter-controller/pkg/secretsync"
 	"github.com/gardener/potter-controller/pkg/synchronize"
 	"github.com/gardener/potter-controller/pkg/util"
 
@@ -11,19 +20,25 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// errUnsupportedConfigType is returned by GetDeployer if neither a built-in deployer nor a deployer plugin exists for
+// a config type
+var errUnsupportedConfigType = errors.New("unsupported configtype")
+
 type DeployerFactory interface {
-	GetDeployer(configType string) (deployutil.DeployItemDeployer, error)
+	GetDeployer(ctx context.Context, configType string) (deployutil.DeployItemDeployer, error)
 }
 
 func NewDeploymentFactory(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
-	appRepoClient client.Client, blockObject *synchronize.BlockObject,
-	reconcileIntervalMinutes int64) DeployerFactory {
+	appRepoClient client.Client, blockObject *synchronize.BlockObject, pluginClients *deployerplugin.ClientCache,
+	reconcileIntervalMinutes int64, kappDeletionTimeout time.Duration) DeployerFactory {
 	return &deployerFactoryImpl{
 		crAndSecretClient:        crAndSecretClient,
 		uncachedClient:           uncachedClient,
 		appRepoClient:            appRepoClient,
 		blockObject:              blockObject,
+		pluginClients:            pluginClients,
 		reconcileIntervalMinutes: reconcileIntervalMinutes,
+		kappDeletionTimeout:      kappDeletionTimeout,
 	}
 }
 
@@ -32,18 +47,43 @@ type deployerFactoryImpl struct {
 	uncachedClient           synchronize.UncachedClient
 	appRepoClient            client.Client
 	blockObject              *synchronize.BlockObject
+	pluginClients            *deployerplugin.ClientCache
 	reconcileIntervalMinutes int64
+	kappDeletionTimeout      time.Duration
 }
 
-func (r *deployerFactoryImpl) GetDeployer(configType string) (deployutil.DeployItemDeployer, error) {
+// GetDeployer returns the built-in deployer for a config type, or, if there is none, the deployer plugin registered
+// for the config type by a DeployerRegistration.
+func (r *deployerFactoryImpl) GetDeployer(ctx context.Context, configType string) (deployutil.DeployItemDeployer, error) {
 	var deployer deployutil.DeployItemDeployer
 	switch configType {
 	case util.ConfigTypeHelm:
 		deployer = helm.NewHelmDeployerDI(r.crAndSecretClient, r.uncachedClient, r.appRepoClient, r.blockObject)
 	case util.ConfigTypeKapp:
-		deployer = kapp.NewKappDeployerDI(r.crAndSecretClient, r.uncachedClient, r.blockObject, r.reconcileIntervalMinutes)
+		deployer = kapp.NewKappDeployerDI(r.crAndSecretClient, r.uncachedClient, r.blockObject, r.reconcileIntervalMinutes,
+			r.kappDeletionTimeout)
+	case util.ConfigTypeManifest:
+		deployer = manifest.NewManifestDeployerDI(r.crAndSecretClient, r.uncachedClient, r.blockObject)
+	case util.ConfigTypeKustomize:
+		deployer = kustomize.NewKustomizeDeployerDI(r.crAndSecretClient, r.uncachedClient, r.blockObject)
+	case util.ConfigTypePackage:
+		deployer = carvelpackage.NewPackageDeployerDI(r.crAndSecretClient, r.uncachedClient, r.blockObject, r.reconcileIntervalMinutes)
+	case util.ConfigTypeSecretSync:
+		deployer = secretsync.NewSecretSyncDeployerDI(r.crAndSecretClient, r.uncachedClient, r.blockObject)
 	default:
-		return nil, errors.New("Unsupported configtype " + configType)
+		registration, err := deployerplugin.FindRegistration(ctx, r.crAndSecretClient.List, configType)
+		if err != nil {
+			return nil, err
+		} else if registration == nil {
+			return nil, fmt.Errorf("%w %s", errUnsupportedConfigType, configType)
+		}
+
+		pluginClient, err := r.pluginClients.Get(registration)
+		if err != nil {
+			return nil, err
+		}
+
+		return deployerplugin.NewPluginDeployerDI(pluginClient, r.uncachedClient, r.blockObject), nil
 	}
 
 	return deployer, nil
@@ -25,20 +25,30 @@ import (
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/avcheck"
 	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/metrics"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
+	"github.com/gardener/potter-controller/pkg/util/predicate"
 
 	"github.com/gardener/landscaper/apis/core/v1alpha1"
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	kappctrl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/yaml"
 )
 
@@ -57,11 +67,14 @@ type DeploymentReconciler struct {
 	avCheck           *avcheck.AVCheck
 	uncachedClient    synchronize.UncachedClient
 	eventRecorder     record.EventRecorder
+	watchKappApps     bool
+	readinessTracker  *metrics.ReadinessTracker
 }
 
 func NewDeploymentReconciler(deployerFactory DeployerFactory, crAndSecretClient client.Client, log logr.Logger,
 	scheme *runtime.Scheme, threadCounterLog *util.ThreadCounterMap, blockObject *synchronize.BlockObject,
-	avCheck *avcheck.AVCheck, uncachedClient synchronize.UncachedClient, eventRecorder record.EventRecorder) *DeploymentReconciler {
+	avCheck *avcheck.AVCheck, uncachedClient synchronize.UncachedClient, eventRecorder record.EventRecorder,
+	watchKappApps bool) *DeploymentReconciler {
 	return &DeploymentReconciler{
 		deployerFactory:   deployerFactory,
 		crAndSecretClient: crAndSecretClient,
@@ -72,6 +85,8 @@ func NewDeploymentReconciler(deployerFactory DeployerFactory, crAndSecretClient
 		avCheck:           avCheck,
 		uncachedClient:    uncachedClient,
 		eventRecorder:     eventRecorder,
+		watchKappApps:     watchKappApps,
+		readinessTracker:  metrics.NewReadinessTracker(),
 	}
 }
 
@@ -111,12 +126,19 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	err = r.crAndSecretClient.Get(ctx, req.NamespacedName, deployItem)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			r.readinessTracker.Forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 
 		return r.logAndReturnHubFailure(ctx, deployutil.ReasonFailedFetchingObject, "Could not read deployment config", err)
 	}
 
+	ctx = tracing.ExtractFromAnnotations(ctx, deployItem)
+	ctx, span := tracing.Start(ctx, "DeploymentReconciler.Reconcile",
+		attribute.String(tracing.AttributeConfigType, string(deployItem.Spec.Type)))
+	defer span.End()
+	ctx, log = tracing.EnrichContextAndLogger(ctx)
+
 	d := &deploymentDeactivator{}
 	stopReconcile, err := d.handleDeactivationOrReactivation(ctx, deployItem, r.crAndSecretClient)
 	if err != nil {
@@ -136,10 +158,13 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return r.returnFailure()
 	}
 
-	deployer, err := r.deployerFactory.GetDeployer(string(deployItem.Spec.Type))
-	if err != nil {
+	deployer, err := r.deployerFactory.GetDeployer(ctx, string(deployItem.Spec.Type))
+	if errors.Is(err, errUnsupportedConfigType) {
 		log.Error(err, "wrong configtype")
 		return r.returnSuccess()
+	} else if err != nil {
+		log.Error(err, "could not get deployer")
+		return r.returnFailure()
 	}
 
 	disablePreprocess := util.GetEnvBool("DISABLE_DEPLOY_PREPROCESS", false, r.log)
@@ -147,6 +172,9 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		deployer.Preprocess(ctx, deployData)
 	}
 
+	// paused applications are resumed during the preprocessing
+	r.removeAnnotation(ctx, deployItem, util.AnnotationKeyResume, util.AnnotationValueResume)
+
 	if deployData.IsDeleteOperation() {
 		secretKey := deployData.GetSecretKey()
 		var clusterExists bool
@@ -182,13 +210,32 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: *duration}, nil
 	}
 
+	// the reset-retries annotation is removed after the status of the retry was stored, or here if there is nothing to
+	// retry
+	resetRetries := deployData.IsResetRetries()
+	if resetRetries && !deployData.IsNewOperation() && !deployData.IsFinallyFailed() && !deployData.IsLastDeployFailed() {
+		r.removeAnnotation(ctx, deployItem, util.AnnotationKeyResetRetries, util.AnnotationValueResetRetries)
+	}
+
 	lastOp := deployData.ProviderStatus.LastOperation
 
 	if deployData.IsNewOperation() {
 		log.V(util.LogLevelDebug).Info("new operation number", "observedGeneration",
 			deployData.GetObservedGeneration(), "generation", deployData.GetGeneration())
 
-		deployer.ProcessNewOperation(ctx, deployData)
+		operation := r.getNewOperation(deployData)
+		if operation != metrics.OperationRemove {
+			r.readinessTracker.Start(req.NamespacedName, deployData.GetGeneration())
+		}
+
+		r.processOperation(ctx, deployData, operation, deployer.ProcessNewOperation)
+
+		return r.updateStatus(ctx, deployData)
+	} else if resetRetries && (deployData.IsFinallyFailed() || deployData.IsLastDeployFailed()) {
+		deployutil.LogSuccess(ctx, deployutil.ReasonResetRetries, "Retries reset for application "+deployData.GetConfigID())
+
+		deployData.ResetRetries()
+		r.processOperation(ctx, deployData, metrics.OperationRetry, deployer.RetryFailedOperation)
 
 		return r.updateStatus(ctx, deployData)
 	} else if deployData.IsFinallyFailed() {
@@ -200,17 +247,32 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		log.V(util.LogLevelWarning).Info("lastOp.State == failed", "observedGeneration",
 			deployData.GetObservedGeneration(), "generation", deployData.GetGeneration())
 
-		requeue, duration := util.CalculateRequeueDurationForPrematureRetry(&lastOp)
+		if !deployData.IsLastErrorRetryable() && !deployData.IsReconcile() {
+			// e.g. invalid values or wrong credentials; an automatic retry only makes sense after a long time, in case
+			// the cause was fixed outside of the application config
+			requeue, duration := r.calculateRequeueDurationForNonRetryableError(deployData)
+			if requeue {
+				log.V(util.LogLevelWarning).Info("Last error is not retryable", "observedGeneration",
+					deployData.GetObservedGeneration(), "generation", deployData.GetGeneration(), "requeue-duration", duration)
+				return ctrl.Result{RequeueAfter: *duration}, nil
+			}
+		}
+
+		if deployData.IsRetryLimitReached() && !deployData.IsReconcile() {
+			return r.handleRetryLimitReached(ctx, deployData)
+		}
+
+		requeue, duration := r.calculateRequeueDurationForPrematureRetry(deployData)
 		if requeue {
 			log.V(util.LogLevelDebug).Info("Too early for retry", "requeue-duration", duration)
 			return ctrl.Result{RequeueAfter: *duration}, nil
 		}
 
-		deployer.RetryFailedOperation(ctx, deployData)
+		r.processOperation(ctx, deployData, metrics.OperationRetry, deployer.RetryFailedOperation)
 
 		return r.updateStatus(ctx, deployData)
 	} else if deployData.IsReconcile() {
-		deployer.ReconcileOperation(ctx, deployData)
+		r.processOperation(ctx, deployData, metrics.OperationReconcile, deployer.ReconcileOperation)
 
 		return r.updateStatus(ctx, deployData)
 	} else if deployData.IsInstallButNotReady() {
@@ -225,7 +287,17 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{RequeueAfter: *duration}, nil
 		}
 
-		deployer.ProcessPendingOperation(ctx, deployData)
+		r.processPendingOperation(ctx, deployData, deployer)
+
+		return r.updateStatus(ctx, deployData)
+	} else if deployData.IsDeletionInProgress() {
+		requeue, duration := r.calculateRequeueDurationForDeletionInProgress(deployData.ProviderStatus)
+		if requeue {
+			log.V(util.LogLevelDebug).Info("event for deletion in progress requeued")
+			return ctrl.Result{RequeueAfter: *duration}, nil
+		}
+
+		r.processPendingOperation(ctx, deployData, deployer)
 
 		return r.updateStatus(ctx, deployData)
 	} else if lastOp.Operation == util.OperationRemove {
@@ -299,9 +371,66 @@ func (r *DeploymentReconciler) removeFinalizer(ctx context.Context, deployItem *
 		return err
 	}
 
+	r.readinessTracker.Forget(*util.GetKey(deployItem))
 	return nil
 }
 
+// getNewOperation returns the operation name for the metrics of a new operation. An install of a deploy item which
+// was already successfully installed before is an upgrade.
+func (r *DeploymentReconciler) getNewOperation(deployData *deployutil.DeployData) string {
+	if deployData.IsDeleteOperation() {
+		return metrics.OperationRemove
+	}
+
+	lastOp := deployData.ProviderStatus.LastOperation
+	if lastOp.Operation == util.OperationInstall && lastOp.SuccessGeneration > 0 {
+		return metrics.OperationUpgrade
+	}
+
+	return metrics.OperationInstall
+}
+
+// processOperation lets the deployer process an operation, and records it in a span and in the metrics.
+func (r *DeploymentReconciler) processOperation(ctx context.Context, deployData *deployutil.DeployData, operation string,
+	process func(context.Context, *deployutil.DeployData)) {
+	ctx, span := tracing.Start(ctx, "Deployer."+operation)
+	defer span.End()
+
+	start := time.Now()
+	process(ctx, deployData)
+
+	outcome := r.getOutcome(deployData)
+	span.SetAttributes(attribute.String(tracing.AttributeOutcome, outcome))
+	if outcome != util.StateOk && outcome != util.StatePending {
+		span.SetStatus(codes.Error, deployData.ProviderStatus.LastOperation.Description)
+	}
+
+	metrics.ObserveDeployOperation(string(deployData.GetDeployItem().Spec.Type), operation, outcome, start)
+}
+
+// processPendingOperation lets the deployer check a pending operation, e.g. the readiness of an install.
+func (r *DeploymentReconciler) processPendingOperation(ctx context.Context, deployData *deployutil.DeployData,
+	deployer deployutil.DeployItemDeployer) {
+	ctx, span := tracing.Start(ctx, "Deployer.pending")
+	defer span.End()
+
+	deployer.ProcessPendingOperation(ctx, deployData)
+}
+
+// getOutcome returns the outcome of a processed operation. Operations which failed because the target cluster was
+// unreachable have their own outcome.
+func (r *DeploymentReconciler) getOutcome(deployData *deployutil.DeployData) string {
+	if reachability := deployData.ProviderStatus.Reachability; reachability != nil && !reachability.Reachable {
+		return metrics.OutcomeUnreachable
+	}
+
+	if outcome := deployData.ProviderStatus.LastOperation.State; outcome != "" {
+		return outcome
+	}
+
+	return util.StateUnknown
+}
+
 func (r *DeploymentReconciler) updateStatus(ctx context.Context, deployData *deployutil.DeployData) (ctrl.Result, error) {
 	log := util.GetLoggerFromContext(ctx)
 
@@ -315,7 +444,7 @@ func (r *DeploymentReconciler) updateStatus(ctx context.Context, deployData *dep
 		}
 	}
 
-	r.removeReconcileAnnotation(ctx, deployData.GetDeployItem())
+	r.removeAnnotation(ctx, deployData.GetDeployItem(), util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
 
 	newStatus, err := deployData.GetStatus()
 	if err != nil {
@@ -329,6 +458,17 @@ func (r *DeploymentReconciler) updateStatus(ctx context.Context, deployData *dep
 		return result, err
 	}
 
+	// a failed status update is requeued, and the retries must then be reset again
+	if !result.Requeue {
+		r.removeAnnotation(ctx, deployData.GetDeployItem(), util.AnnotationKeyResetRetries,
+			util.AnnotationValueResetRetries)
+	}
+
+	if !deployData.IsDeleteOperation() && deployData.IsConditionTrue(hubv1.HubDeploymentReady) {
+		r.readinessTracker.Ready(*deployData.GetDeployItemKey(), deployData.GetGeneration(),
+			string(deployData.GetDeployItem().Spec.Type))
+	}
+
 	_ = r.removeUnreferencedExportSecrets(ctx, deployData.GetDeployItem(), newStatus.ExportReference)
 	return result, err
 }
@@ -392,6 +532,8 @@ func (r *DeploymentReconciler) updateDeployItemStatus(ctx context.Context, deplo
 	status *v1alpha1.DeployItemStatus) (ctrl.Result, error) {
 	log := util.GetLoggerFromContext(ctx)
 
+	ctx, span := tracing.Start(ctx, "DeploymentReconciler.updateStatus")
+
 	var err error
 
 	util.Repeat(func() bool {
@@ -400,6 +542,8 @@ func (r *DeploymentReconciler) updateDeployItemStatus(ctx context.Context, deplo
 		return done
 	}, 10, time.Second)
 
+	tracing.End(span, err)
+
 	if err != nil {
 		return r.logAndReturnHubFailure(ctx, deployutil.ReasonFailedWriteState, "error updating deployitem status", err)
 	}
@@ -432,11 +576,19 @@ func (r *DeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		MaxConcurrentReconciles: maxThreads,
 		Reconciler:              r,
 	}
-	return ctrl.NewControllerManagedBy(mgr).
+	bldr := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.DeployItem{}).
 		Named("DeploymentReconciler").
-		WithOptions(options).
-		Complete(r)
+		WithOptions(options)
+
+	if r.watchKappApps {
+		// a kapp app has the same name and namespace as its deploy item; the removal of the app triggers the processing
+		// of a pending deletion
+		bldr = bldr.Watches(&source.Kind{Type: &kappctrl.App{}}, &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(predicate.KappAppRemoval()))
+	}
+
+	return bldr.Complete(r)
 }
 
 func (r *DeploymentReconciler) GetName() string {
@@ -497,6 +649,62 @@ func (r *DeploymentReconciler) calculateRequeueDurationForUnreachableCluster(dep
 	return false, nil
 }
 
+// Depending on the retry policy, an application whose retries are exhausted either becomes finally failed, or stays
+// failed with the reason RetriesStopped until its configuration is changed or its retries are reset.
+func (r *DeploymentReconciler) handleRetryLimitReached(ctx context.Context, deployData *deployutil.DeployData) (ctrl.Result, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	numberOfTries := deployData.ProviderStatus.LastOperation.NumberOfTries
+
+	if !deployData.GetRetryPolicy().GiveUp {
+		log.V(util.LogLevelWarning).Info("Retry limit reached", "numberOfTries", numberOfTries)
+		if deployData.IsRetriesStopped() {
+			return ctrl.Result{}, nil
+		}
+
+		message := fmt.Sprintf("Retries stopped after %d tries", numberOfTries)
+		deployutil.LogApplicationFailure(ctx, deployutil.ReasonRetriesStopped, message+" for application "+deployData.GetConfigID())
+		deployData.SetRetriesStopped(message, metav1.Now())
+
+		return r.updateStatus(ctx, deployData)
+	}
+
+	message := fmt.Sprintf("Retries exhausted after %d tries", numberOfTries)
+	deployutil.LogApplicationFailure(ctx, deployutil.ReasonRetriesExhausted, message+" for application "+deployData.GetConfigID())
+	deployData.SetFinallyFailed(message, metav1.Now())
+
+	return r.updateStatus(ctx, deployData)
+}
+
+// Conflicts with concurrent modifications and recovered stuck releases are retried without backoff. Other errors are
+// retried with exponential backoff.
+func (r *DeploymentReconciler) calculateRequeueDurationForPrematureRetry(deployData *deployutil.DeployData) (bool, *time.Duration) {
+	lastOp := deployData.ProviderStatus.LastOperation
+
+	lastErrorEntry := deployData.GetLastErrorEntry()
+	if lastErrorEntry != nil && (lastErrorEntry.Code == string(deployutil.ErrorCodeAPIConflict) ||
+		lastErrorEntry.Code == string(deployutil.ErrorCodeStuckRelease)) {
+		lastOp.NumberOfTries = 0
+	}
+
+	return util.CalculateRequeueDurationForPrematureRetry(&lastOp, deployData.GetRetryPolicy())
+}
+
+// Errors which are not retryable are retried with the fixed non retryable delay of the retry policy, without
+// exponential backoff.
+func (r *DeploymentReconciler) calculateRequeueDurationForNonRetryableError(deployData *deployutil.DeployData) (bool, *time.Duration) {
+	lastTime := deployData.ProviderStatus.LastOperation.Time
+	currentTime := time.Now()
+	nextScheduledRun := lastTime.Add(util.CalculateRequeueTimeoutForNonRetryableError(deployData.GetRetryPolicy()))
+
+	if currentTime.Before(nextScheduledRun) {
+		duration := nextScheduledRun.Sub(currentTime)
+		return true, &duration
+	}
+
+	return false, nil
+}
+
 func (r *DeploymentReconciler) calculateRequeueDurationForNotReadyInstall(deployItemStatus *hubv1.HubDeployItemProviderStatus) (bool, *time.Duration) {
 	if deployItemStatus.Readiness != nil && deployItemStatus.Readiness.State != util.StateOk {
 		lastTime := deployItemStatus.Readiness.Time
@@ -512,6 +720,21 @@ func (r *DeploymentReconciler) calculateRequeueDurationForNotReadyInstall(deploy
 	return false, nil
 }
 
+// A deletion in progress is checked again after a short time, even if the watched objects do not change, so that the
+// deletion timeout of the deployer is noticed.
+func (r *DeploymentReconciler) calculateRequeueDurationForDeletionInProgress(deployItemStatus *hubv1.HubDeployItemProviderStatus) (bool, *time.Duration) {
+	lastTime := deployItemStatus.LastOperation.Time
+	currentTime := time.Now()
+	nextScheduledRun := lastTime.Add(15 * time.Second)
+
+	if currentTime.Before(nextScheduledRun) {
+		duration := nextScheduledRun.Sub(currentTime)
+		return true, &duration
+	}
+
+	return false, nil
+}
+
 func (r *DeploymentReconciler) returnFailure() (ctrl.Result, error) {
 	return ctrl.Result{
 		Requeue: true,
@@ -522,28 +745,30 @@ func (r *DeploymentReconciler) returnSuccess() (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
-func (r *DeploymentReconciler) removeReconcileAnnotation(ctx context.Context, deployItem *v1alpha1.DeployItem) {
-	if util.HasAnnotation(deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile) {
+// removeAnnotation removes an annotation of the deploy item, if it has the expected value.
+func (r *DeploymentReconciler) removeAnnotation(ctx context.Context, deployItem *v1alpha1.DeployItem, annotationKey,
+	annotationValue string) {
+	if util.HasAnnotation(deployItem, annotationKey, annotationValue) {
 		log := util.GetLoggerFromContext(ctx)
 		storedDeployItem := v1alpha1.DeployItem{}
 		err := r.crAndSecretClient.Get(ctx, *util.GetKey(deployItem), &storedDeployItem)
 
 		if err != nil {
 			if !apierrors.IsNotFound(err) {
-				log.Error(err, "error fetching deploy item for removing annotation")
+				log.Error(err, "error fetching deploy item for removing annotation", "annotation", annotationKey)
 			}
 			return
 		}
 
-		util.RemoveAnnotation(&storedDeployItem, util.AnnotationKeyReconcile)
+		util.RemoveAnnotation(&storedDeployItem, annotationKey)
 
 		err = r.crAndSecretClient.Update(ctx, &storedDeployItem)
 
 		if err != nil {
 			if apierrors.IsConflict(err) {
-				log.V(util.LogLevelDebug).Info("updating deploy item for removing annotation had a conflict")
+				log.V(util.LogLevelDebug).Info("updating deploy item for removing annotation had a conflict", "annotation", annotationKey)
 			} else {
-				log.Error(err, "error updating deploy item for removing annotation")
+				log.Error(err, "error updating deploy item for removing annotation", "annotation", annotationKey)
 			}
 			return
 		}
@@ -4,14 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
 	"testing"
 	"time"
 
 	"go.uber.org/zap"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
+	"github.com/gardener/potter-controller/pkg/deployerplugin/reference"
 	"github.com/gardener/potter-controller/pkg/deployutil"
 	"github.com/gardener/potter-controller/pkg/helm"
+	"github.com/gardener/potter-controller/pkg/metrics"
 	"github.com/gardener/potter-controller/pkg/synchronize"
 	testUtils "github.com/gardener/potter-controller/pkg/testing"
 	"github.com/gardener/potter-controller/pkg/util"
@@ -275,6 +281,10 @@ func (h *helmFacadeMock) InstallOrUpdate(ctx context.Context, chartData *helm.Ch
 	return nil, h.iouReturn
 }
 
+func (h *helmFacadeMock) RecoverStuckRelease(ctx context.Context, chartData *helm.ChartData, namespace, targetKubeconfig string) (*helm.ReleaseRecovery, error) {
+	return nil, nil
+}
+
 func (h *helmFacadeMock) Remove(ctx context.Context, chartData *helm.ChartData, namespace, targetKubeconfig string) error {
 	h.remInstallName = chartData.InstallName
 	h.remNamespace = namespace
@@ -1044,6 +1054,7 @@ func newDeploymentReconciler(fakeClient client.Client, helmFacade helm.Facade) *
 		scheme:            &runtime.Scheme{},
 		blockObject:       blockObject,
 		uncachedClient:    uncachedClient,
+		readinessTracker:  metrics.NewReadinessTracker(),
 	}
 }
 
@@ -1054,7 +1065,588 @@ type unitTestDeployerFactory struct {
 	blockObject       *synchronize.BlockObject
 }
 
-func (r *unitTestDeployerFactory) GetDeployer(configType string) (deployutil.DeployItemDeployer, error) {
+func (r *unitTestDeployerFactory) GetDeployer(ctx context.Context, configType string) (deployutil.DeployItemDeployer, error) {
 	deployer := helm.NewHelmDeployerDIWithFacade(r.crAndSecretClient, r.uncachedClient, r.helmFacade, nil, r.blockObject)
 	return deployer, nil
 }
+
+func Test_FinallyFailed_After_Retry_Limit_Reached(t *testing.T) {
+	const (
+		operation             = "install"
+		secretName            = "test.secret"
+		actualOperationNumber = 4
+		actualNumberOfTries   = 3
+	)
+
+	typeSpecificData := map[string]interface{}{
+		"installName": "der-gute-alte-broker",
+		"namespace":   "broker-ns",
+		"tarballAccess": map[string]interface{}{
+			"url":        "https://myrepo.io/service-broker-0.5.0.tgz",
+			"authHeader": "<Insert-correct-auth-here>",
+		},
+	}
+
+	deployItemConfig := hubv1.HubDeployItemConfiguration{
+		LocalSecretRef: secretName,
+		DeploymentConfig: hubv1.DeploymentConfig{
+			ID:               "1",
+			TypeSpecificData: *util.CreateRawExtensionOrPanic(typeSpecificData),
+			RetryPolicy: &hubv1.RetryPolicy{
+				MaxAttempts: actualNumberOfTries,
+				GiveUp:      true,
+			},
+		},
+	}
+
+	encodedConfig, _ := json.Marshal(deployItemConfig)
+
+	deployItemStatus := hubv1.HubDeployItemProviderStatus{
+		LastOperation: hubv1.LastOperation{
+			Operation:     operation,
+			Time:          metav1.Time{Time: time.Now().Add(time.Second * -400)},
+			NumberOfTries: actualNumberOfTries,
+			State:         util.StateFailed,
+			Description:   "install failed",
+		},
+	}
+
+	encodedStatus, _ := json.Marshal(deployItemStatus)
+
+	newDeployItem := v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       testHDCName,
+			Namespace:  testNS,
+			Generation: actualOperationNumber,
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Type: util.ConfigTypeHelm,
+			Configuration: &runtime.RawExtension{
+				Raw: encodedConfig,
+			},
+		},
+		Status: v1alpha1.DeployItemStatus{
+			ObservedGeneration: actualOperationNumber,
+			ProviderStatus: &runtime.RawExtension{
+				Raw: encodedStatus,
+			},
+		},
+	}
+
+	fakeClient := testUtils.NewReactiveMockClient(map[string]func() error{}, &newDeployItem)
+	hFacadeMock := &helmFacadeMock{}
+	controller := newDeploymentReconciler(&fakeClient, hFacadeMock)
+
+	result, err := controller.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: testNS,
+			Name:      testHDCName,
+		},
+	})
+
+	Nil(t, err, "unexpected error returned from reconcile run")
+	Equal(t, result.RequeueAfter, time.Second*0, "result.RequeueAfter")
+	Nil(t, hFacadeMock.iouChartData, "no retry expected")
+
+	key := client.ObjectKey{
+		Namespace: newDeployItem.Namespace,
+		Name:      newDeployItem.Name,
+	}
+
+	err = fakeClient.Get(context.TODO(), key, &newDeployItem)
+	NoErr(t, err)
+
+	actualDeployItemStatus := &hubv1.HubDeployItemProviderStatus{}
+	err = json.Unmarshal(newDeployItem.Status.ProviderStatus.Raw, actualDeployItemStatus)
+	assert.Nil(t, err, "unmarshal error")
+
+	testState(t, &actualDeployItemStatus.LastOperation, util.StateFailed, "install failed", operation, int32(actualOperationNumber),
+		int32(actualNumberOfTries), "Test_FinallyFailed_After_Retry_Limit_Reached")
+	NotNil(t, actualDeployItemStatus.Readiness, "readiness")
+	Equal(t, actualDeployItemStatus.Readiness.State, util.StateFinallyFailed, "readiness state")
+	Equal(t, newDeployItem.Status.Phase, v1alpha1.ExecutionPhaseFailed, "phase")
+}
+
+// Test_ResetRetries_After_Retry_Limit_Reached tests that a deploy item is not retried after its retry limit is reached,
+// which is shown by the reason of its ready condition, and that the reset-retries annotation clears the retry counter,
+// so that it is processed again.
+func Test_ResetRetries_After_Retry_Limit_Reached(t *testing.T) {
+	const (
+		operation             = "install"
+		secretName            = "test.secret"
+		targetKubeconfig      = "123xyz"
+		errorString           = "install failed again"
+		actualOperationNumber = 4
+		actualNumberOfTries   = 3
+	)
+
+	tests := []struct {
+		name                  string
+		giveUp                bool
+		iouReturn             error
+		expectedReason        hubv1.HubDeploymentConditionReason
+		expectedState         string
+		expectedDescription   string
+		expectedNumberOfTries int32
+	}{
+		{
+			name:                  "finally failed and retry succeeds",
+			giveUp:                true,
+			expectedReason:        hubv1.ReasonRetriesExhausted,
+			expectedState:         util.StateOk,
+			expectedDescription:   operation + " successful",
+			expectedNumberOfTries: 1,
+		},
+		{
+			name:                  "finally failed and retry fails",
+			giveUp:                true,
+			iouReturn:             errors.New(errorString),
+			expectedReason:        hubv1.ReasonRetriesExhausted,
+			expectedState:         util.StateFailed,
+			expectedDescription:   errorString,
+			expectedNumberOfTries: 1,
+		},
+		{
+			name:                  "retries stopped and retry succeeds",
+			expectedReason:        hubv1.ReasonRetriesStopped,
+			expectedState:         util.StateOk,
+			expectedDescription:   operation + " successful",
+			expectedNumberOfTries: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typeSpecificData := map[string]interface{}{
+				"installName": "der-gute-alte-broker",
+				"namespace":   "broker-ns",
+				"tarballAccess": map[string]interface{}{
+					"url":        "https://myrepo.io/service-broker-0.5.0.tgz",
+					"authHeader": "<Insert-correct-auth-here>",
+				},
+			}
+
+			deployItemConfig := hubv1.HubDeployItemConfiguration{
+				LocalSecretRef: secretName,
+				DeploymentConfig: hubv1.DeploymentConfig{
+					ID:               "1",
+					TypeSpecificData: *util.CreateRawExtensionOrPanic(typeSpecificData),
+					RetryPolicy: &hubv1.RetryPolicy{
+						MaxAttempts: actualNumberOfTries,
+						GiveUp:      tt.giveUp,
+					},
+				},
+			}
+
+			encodedConfig, _ := json.Marshal(deployItemConfig)
+
+			deployItemStatus := hubv1.HubDeployItemProviderStatus{
+				LastOperation: hubv1.LastOperation{
+					Operation:     operation,
+					Time:          metav1.Time{Time: time.Now().Add(time.Second * -400)},
+					NumberOfTries: actualNumberOfTries,
+					State:         util.StateFailed,
+					Description:   "install failed",
+				},
+			}
+
+			encodedStatus, _ := json.Marshal(deployItemStatus)
+
+			newDeployItem := v1alpha1.DeployItem{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       testHDCName,
+					Namespace:  testNS,
+					Generation: actualOperationNumber,
+				},
+				Spec: v1alpha1.DeployItemSpec{
+					Type: util.ConfigTypeHelm,
+					Configuration: &runtime.RawExtension{
+						Raw: encodedConfig,
+					},
+				},
+				Status: v1alpha1.DeployItemStatus{
+					ObservedGeneration: actualOperationNumber,
+					ProviderStatus: &runtime.RawExtension{
+						Raw: encodedStatus,
+					},
+				},
+			}
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNS,
+				},
+				Data: map[string][]byte{
+					"kubeconfig": []byte(targetKubeconfig),
+				},
+				Type: corev1.SecretTypeOpaque,
+			}
+
+			fakeClient := testUtils.NewReactiveMockClient(map[string]func() error{}, &newDeployItem, secret)
+			hFacadeMock := &helmFacadeMock{iouReturn: tt.iouReturn}
+			controller := newDeploymentReconciler(&fakeClient, hFacadeMock)
+
+			request := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: testNS,
+					Name:      testHDCName,
+				},
+			}
+
+			key := client.ObjectKey{
+				Namespace: newDeployItem.Namespace,
+				Name:      newDeployItem.Name,
+			}
+
+			// the retry limit is reached
+			_, err := controller.Reconcile(context.TODO(), request)
+			Nil(t, err, "unexpected error returned from reconcile run")
+			Nil(t, hFacadeMock.iouChartData, "no retry expected")
+
+			storedDeployItem := v1alpha1.DeployItem{}
+			err = fakeClient.Get(context.TODO(), key, &storedDeployItem)
+			NoErr(t, err)
+
+			readyCondition := util.GetDeployItemCondition(&storedDeployItem, hubv1.HubDeploymentReady)
+			NotNil(t, readyCondition, "ready condition")
+			Equal(t, readyCondition.Reason, string(tt.expectedReason), "reason of ready condition")
+			Equal(t, storedDeployItem.Status.Phase, v1alpha1.ExecutionPhaseFailed, "phase")
+
+			// the reset-retries annotation triggers a retry
+			util.AddAnnotation(&storedDeployItem, util.AnnotationKeyResetRetries, util.AnnotationValueResetRetries)
+			err = fakeClient.Update(context.TODO(), &storedDeployItem)
+			NoErr(t, err)
+
+			// the annotation is kept if the status of the retry could not be stored
+			statusWriter := fakeClient.StatusWriter
+			fakeClient.StatusWriter = &testUtils.ReactiveMockStatusWriter{
+				ReactorFuncs: map[string]func() error{
+					key.String(): func() error { return errors.New("status update failed") },
+				},
+				FakeClient: statusWriter.FakeClient,
+			}
+
+			_, err = controller.Reconcile(context.TODO(), request)
+			Nil(t, err, "unexpected error returned from reconcile run")
+
+			storedDeployItem = v1alpha1.DeployItem{}
+			err = fakeClient.Get(context.TODO(), key, &storedDeployItem)
+			NoErr(t, err)
+
+			_, ok := util.GetAnnotation(&storedDeployItem, util.AnnotationKeyResetRetries)
+			True(t, ok, "reset-retries annotation kept")
+
+			fakeClient.StatusWriter = statusWriter
+			hFacadeMock.iouChartData = nil
+
+			_, err = controller.Reconcile(context.TODO(), request)
+			Nil(t, err, "unexpected error returned from reconcile run")
+			NotNil(t, hFacadeMock.iouChartData, "retry expected")
+
+			storedDeployItem = v1alpha1.DeployItem{}
+			err = fakeClient.Get(context.TODO(), key, &storedDeployItem)
+			NoErr(t, err)
+
+			_, ok = util.GetAnnotation(&storedDeployItem, util.AnnotationKeyResetRetries)
+			False(t, ok, "reset-retries annotation removed")
+
+			actualDeployItemStatus := &hubv1.HubDeployItemProviderStatus{}
+			err = json.Unmarshal(storedDeployItem.Status.ProviderStatus.Raw, actualDeployItemStatus)
+			assert.Nil(t, err, "unmarshal error")
+
+			testState(t, &actualDeployItemStatus.LastOperation, tt.expectedState, tt.expectedDescription, operation,
+				int32(actualOperationNumber), tt.expectedNumberOfTries, "Test_ResetRetries_After_Retry_Limit_Reached - "+tt.name)
+			if actualDeployItemStatus.Readiness != nil {
+				assert.NotEqual(t, actualDeployItemStatus.Readiness.State, util.StateFinallyFailed, "readiness state")
+			}
+
+			readyCondition = util.GetDeployItemCondition(&storedDeployItem, hubv1.HubDeploymentReady)
+			NotNil(t, readyCondition, "ready condition")
+			assert.NotEqual(t, readyCondition.Reason, string(tt.expectedReason), "reason of ready condition after reset")
+		})
+	}
+}
+
+// Test_NonRetryableError_Backoff tests that an operation which failed with an error that is not retryable is requeued
+// with the fixed non retryable delay of its retry policy and tried again afterwards.
+func Test_NonRetryableError_Backoff(t *testing.T) {
+	const (
+		operation             = "install"
+		secretName            = "test.secret"
+		actualOperationNumber = 4
+	)
+
+	const nonRetryableDelay = 2 * time.Hour
+
+	tests := []struct {
+		name            string
+		lastTime        time.Time
+		expectedRequeue bool
+		expectedRetry   bool
+	}{
+		{
+			name:            "backoff not yet elapsed",
+			lastTime:        time.Now().Add(-time.Hour),
+			expectedRequeue: true,
+		},
+		{
+			name:          "backoff elapsed",
+			lastTime:      time.Now().Add(-nonRetryableDelay - time.Minute),
+			expectedRetry: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typeSpecificData := map[string]interface{}{
+				"installName": "der-gute-alte-broker",
+				"namespace":   "broker-ns",
+				"tarballAccess": map[string]interface{}{
+					"url":        "https://myrepo.io/service-broker-0.5.0.tgz",
+					"authHeader": "<Insert-correct-auth-here>",
+				},
+			}
+
+			deployItemConfig := hubv1.HubDeployItemConfiguration{
+				LocalSecretRef: secretName,
+				DeploymentConfig: hubv1.DeploymentConfig{
+					ID:               "1",
+					TypeSpecificData: *util.CreateRawExtensionOrPanic(typeSpecificData),
+					RetryPolicy: &hubv1.RetryPolicy{
+						NonRetryableDelay: &metav1.Duration{Duration: nonRetryableDelay},
+					},
+				},
+			}
+
+			encodedConfig, _ := json.Marshal(deployItemConfig)
+
+			deployItemStatus := hubv1.HubDeployItemProviderStatus{
+				LastOperation: hubv1.LastOperation{
+					Operation:     operation,
+					Time:          metav1.Time{Time: tt.lastTime},
+					NumberOfTries: 1,
+					State:         util.StateFailed,
+					Description:   "install failed",
+					ErrorHistory: &hubv1.ErrorHistory{
+						ErrorEntries: []hubv1.ErrorEntry{
+							{
+								Description: "install failed",
+								Code:        string(deployutil.ErrorCodeRender),
+								Time:        metav1.Time{Time: tt.lastTime},
+							},
+						},
+					},
+				},
+			}
+
+			encodedStatus, _ := json.Marshal(deployItemStatus)
+
+			newDeployItem := v1alpha1.DeployItem{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       testHDCName,
+					Namespace:  testNS,
+					Generation: actualOperationNumber,
+				},
+				Spec: v1alpha1.DeployItemSpec{
+					Type: util.ConfigTypeHelm,
+					Configuration: &runtime.RawExtension{
+						Raw: encodedConfig,
+					},
+				},
+				Status: v1alpha1.DeployItemStatus{
+					ObservedGeneration: actualOperationNumber,
+					ProviderStatus: &runtime.RawExtension{
+						Raw: encodedStatus,
+					},
+				},
+			}
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNS,
+				},
+				Data: map[string][]byte{
+					"kubeconfig": []byte("123xyz"),
+				},
+				Type: corev1.SecretTypeOpaque,
+			}
+
+			fakeClient := testUtils.NewReactiveMockClient(map[string]func() error{}, &newDeployItem, secret)
+			hFacadeMock := &helmFacadeMock{}
+			controller := newDeploymentReconciler(&fakeClient, hFacadeMock)
+
+			request := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: testNS,
+					Name:      testHDCName,
+				},
+			}
+
+			result, err := controller.Reconcile(context.TODO(), request)
+			Nil(t, err, "unexpected error returned from reconcile run")
+			Equal(t, result.RequeueAfter > 0, tt.expectedRequeue, "requeue")
+			if tt.expectedRequeue {
+				True(t, result.RequeueAfter <= nonRetryableDelay, "requeue duration")
+			}
+			Equal(t, hFacadeMock.iouChartData != nil, tt.expectedRetry, "retry")
+		})
+	}
+}
+
+// TestPluginDeployment_NotRepeatedForSameGeneration tests that a successful operation of a deployer plugin records the
+// observed generation, so that a second reconcile of the same generation does not deploy the application again.
+func TestPluginDeployment_NotRepeatedForSameGeneration(t *testing.T) {
+	deployments := 0
+	pluginHandler := deployerplugin.NewHandler(reference.NewReferenceDeployer(), reference.Validate, ctrl.Log.WithName("plugin"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if path.Base(req.URL.Path) == deployerplugin.OperationProcessNew {
+			deployments++
+		}
+		pluginHandler.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	deployItemConfig := hubv1.HubDeployItemConfiguration{
+		LocalSecretRef: "test.secret",
+		DeploymentConfig: hubv1.DeploymentConfig{
+			ID:               "1",
+			TypeSpecificData: *util.CreateRawExtensionOrPanic(map[string]interface{}{"message": "hello"}),
+		},
+	}
+	encodedConfig, _ := json.Marshal(deployItemConfig)
+
+	deployItem := v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       testHDCName,
+			Namespace:  testNS,
+			Generation: 3,
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Type:          reference.ConfigType,
+			Configuration: &runtime.RawExtension{Raw: encodedConfig},
+		},
+	}
+
+	registration := &hubv1.DeployerRegistration{
+		ObjectMeta: metav1.ObjectMeta{Name: reference.ConfigType},
+		Spec: hubv1.DeployerRegistrationSpec{
+			ConfigType: reference.ConfigType,
+			URL:        server.URL,
+		},
+	}
+
+	fakeClient := testUtils.NewReactiveMockClient(map[string]func() error{}, &deployItem, registration)
+	controller := newDeploymentReconciler(&fakeClient, &helmFacadeMock{})
+	controller.deployerFactory = NewDeploymentFactory(&fakeClient, controller.uncachedClient, nil, controller.blockObject,
+		deployerplugin.NewClientCache(), 0, 0)
+
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNS, Name: testHDCName}}
+
+	_, err := controller.Reconcile(context.TODO(), request)
+	NoErr(t, err)
+	Equal(t, deployments, 1, "deployments after first reconcile")
+
+	err = fakeClient.Get(context.TODO(), request.NamespacedName, &deployItem)
+	NoErr(t, err)
+	Equal(t, deployItem.Status.ObservedGeneration, int64(3), "observed generation")
+
+	_, err = controller.Reconcile(context.TODO(), request)
+	NoErr(t, err)
+	Equal(t, deployments, 1, "deployments after second reconcile")
+}
+
+// TestRemoveAnnotationWithOtherValue tests that an annotation is only removed if it has the expected value
+func TestRemoveAnnotationWithOtherValue(t *testing.T) {
+	deployItem := v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        testHDCName,
+			Namespace:   testNS,
+			Annotations: map[string]string{util.AnnotationKeyReconcile: "other"},
+		},
+	}
+
+	fakeClient := testUtils.NewReactiveMockClient(map[string]func() error{}, &deployItem)
+	controller := newDeploymentReconciler(&fakeClient, &helmFacadeMock{})
+	ctx := context.WithValue(context.Background(), util.LoggerKey{}, controller.log)
+	key := client.ObjectKey{Namespace: testNS, Name: testHDCName}
+
+	controller.removeAnnotation(ctx, &deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
+
+	storedDeployItem := v1alpha1.DeployItem{}
+	err := fakeClient.Get(ctx, key, &storedDeployItem)
+	NoErr(t, err)
+	True(t, util.HasAnnotation(&storedDeployItem, util.AnnotationKeyReconcile, "other"), "annotation with other value kept")
+
+	util.AddAnnotation(&storedDeployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
+	err = fakeClient.Update(ctx, &storedDeployItem)
+	NoErr(t, err)
+
+	controller.removeAnnotation(ctx, &storedDeployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
+
+	storedDeployItem = v1alpha1.DeployItem{}
+	err = fakeClient.Get(ctx, key, &storedDeployItem)
+	NoErr(t, err)
+	_, ok := util.GetAnnotation(&storedDeployItem, util.AnnotationKeyReconcile)
+	False(t, ok, "annotation with expected value removed")
+}
+
+// TestResumeAnnotationRemoved tests that the resume annotation is removed after the deployer has resumed a paused
+// application in the preprocessing.
+func TestResumeAnnotationRemoved(t *testing.T) {
+	const secretName = "test.secret"
+
+	deployItemConfig := hubv1.HubDeployItemConfiguration{
+		LocalSecretRef: secretName,
+		DeploymentConfig: hubv1.DeploymentConfig{
+			ID: "1",
+			TypeSpecificData: *util.CreateRawExtensionOrPanic(map[string]interface{}{
+				"installName": "der-gute-alte-broker",
+				"namespace":   "broker-ns",
+				"tarballAccess": map[string]interface{}{
+					"url": "https://myrepo.io/service-broker-0.5.0.tgz",
+				},
+			}),
+		},
+	}
+
+	encodedConfig, _ := json.Marshal(deployItemConfig)
+
+	deployItem := v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        testHDCName,
+			Namespace:   testNS,
+			Generation:  1,
+			Annotations: map[string]string{util.AnnotationKeyResume: util.AnnotationValueResume},
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Type:          util.ConfigTypeHelm,
+			Configuration: &runtime.RawExtension{Raw: encodedConfig},
+		},
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: testNS,
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte("123xyz"),
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+
+	fakeClient := testUtils.NewReactiveMockClient(map[string]func() error{}, &deployItem, secret)
+	controller := newDeploymentReconciler(&fakeClient, &helmFacadeMock{})
+	key := client.ObjectKey{Namespace: testNS, Name: testHDCName}
+
+	_, err := controller.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	Nil(t, err, "unexpected error returned from reconcile run")
+
+	storedDeployItem := v1alpha1.DeployItem{}
+	err = fakeClient.Get(context.TODO(), key, &storedDeployItem)
+	NoErr(t, err)
+	_, ok := util.GetAnnotation(&storedDeployItem, util.AnnotationKeyResume)
+	False(t, ok, "resume annotation removed")
+}
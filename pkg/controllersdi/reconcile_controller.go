@@ -489,7 +489,7 @@ func (r *ReconcileController) cleanupSecrets(ctx context.Context) {
 	for i := range secretList.Items {
 		secret := &secretList.Items[i]
 
-		if !secret.ObjectMeta.CreationTimestamp.Time.Add(time.Hour).Before(time.Now()) {
+		if !isSecretValuesGracePeriodOver(secret, r.Clock.Now()) {
 			continue
 		}
 
@@ -518,6 +518,14 @@ func (r *ReconcileController) cleanupSecrets(ctx context.Context) {
 				log.Error(err, "Error fetching clusterbom", util.LogKeyClusterBomName, clusterBomKey)
 				continue
 			}
+		} else if !isSecretValuesReferenced(&clusterBom, secret.Name) {
+			// the write of the clusterbom which references the secret failed after the admission, or the clusterbom
+			// has been changed since then, and its reconcile has not yet deleted the secret
+			err = deleteSecret(ctx, secret, r.Client, r.HubControllerClient)
+			if err != nil {
+				log.Error(err, "Error deleting unreferenced secret", util.LogKeySecretName, util.GetKey(secret), util.LogKeyClusterBomName, clusterBomKey)
+				continue
+			}
 		}
 	}
 }
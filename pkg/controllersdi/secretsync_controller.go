@@ -0,0 +1,86 @@
+package controllersdi
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+	"github.com/gardener/potter-controller/pkg/util/predicate"
+
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SecretSyncReconciler watches the source secrets of config type secretsync. If a source secret changes, the deploy
+// items which copy it are annotated for reconcile, so that the copies on the target clusters are updated. Only the
+// metadata of the secrets are watched, so that the watch does not cache the content of all secrets. The deployer
+// reads the content of a source secret uncached.
+type SecretSyncReconciler struct {
+	client.Client
+	Log logr.Logger
+}
+
+func (r *SecretSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}, builder.OnlyMetadata, builder.WithPredicates(predicate.SecretSyncSource())).
+		Named("SecretSyncReconciler").
+		Complete(r)
+}
+
+func (r *SecretSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues(util.LogKeySecretName, req.NamespacedName)
+
+	deployItemList := &v1alpha1.DeployItemList{}
+	if err := r.List(ctx, deployItemList, client.InNamespace(req.Namespace)); err != nil {
+		log.Error(err, "error listing deploy items")
+		return ctrl.Result{}, err
+	}
+
+	for i := range deployItemList.Items {
+		deployItem := &deployItemList.Items[i]
+
+		if !r.copiesSecret(log, deployItem, req.Name) || !deployItem.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		if util.HasAnnotation(deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile) {
+			continue
+		}
+
+		log.V(util.LogLevelDebug).Info("source secret changed, reconciling deploy item", util.LogKeyDeployItemName, deployItem.Name)
+
+		util.AddAnnotation(deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
+		if err := r.Update(ctx, deployItem); err != nil {
+			log.Error(err, "error annotating deploy item for reconcile", util.LogKeyDeployItemName, deployItem.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *SecretSyncReconciler) copiesSecret(log logr.Logger, deployItem *v1alpha1.DeployItem, secretName string) bool {
+	if string(deployItem.Spec.Type) != util.ConfigTypeSecretSync || deployItem.Spec.Configuration == nil {
+		return false
+	}
+
+	configuration := &hubv1.HubDeployItemConfiguration{}
+	if err := json.Unmarshal(deployItem.Spec.Configuration.Raw, configuration); err != nil {
+		log.Error(err, "error unmarshaling configuration of deploy item", util.LogKeyDeployItemName, deployItem.Name)
+		return false
+	}
+
+	var secretSyncData apitypes.SecretSyncSpecificData
+	if err := json.Unmarshal(configuration.DeploymentConfig.TypeSpecificData.Raw, &secretSyncData); err != nil {
+		log.Error(err, "error unmarshaling secretsync specific data", util.LogKeyDeployItemName, deployItem.Name)
+		return false
+	}
+
+	return secretSyncData.References(secretName)
+}
@@ -0,0 +1,110 @@
+package controllersdi
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+	"github.com/gardener/potter-controller/pkg/util/predicate"
+
+	. "github.com/arschles/assert"
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestSecretSyncReconcilerAnnotatesCopyingDeployItems(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	copying := newTestSecretSyncDeployItem(t, "testbom-copying", util.ConfigTypeSecretSync, "registry")
+	other := newTestSecretSyncDeployItem(t, "testbom-other", util.ConfigTypeSecretSync, "tls")
+	helm := newTestSecretSyncDeployItem(t, "testbom-helm", util.ConfigTypeHelm, "registry")
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(copying, other, helm).Build()
+	reconciler := &SecretSyncReconciler{
+		Client: fakeClient,
+		Log:    ctrl.Log.WithName("SecretSyncReconciler Test"),
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: testNS, Name: "registry"},
+	})
+	Nil(t, err, "error")
+
+	expectedAnnotations := map[string]bool{
+		"testbom-copying": true,
+		"testbom-other":   false,
+		"testbom-helm":    false,
+	}
+
+	for name, expected := range expectedAnnotations {
+		deployItem := &v1alpha1.DeployItem{}
+		err = fakeClient.Get(context.Background(), types.NamespacedName{Namespace: testNS, Name: name}, deployItem)
+		Nil(t, err, "error")
+		Equal(t, util.HasAnnotation(deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile), expected,
+			"reconcile annotation of "+name)
+	}
+}
+
+// TestSecretSyncSourcePredicate tests the selection of the events of the metadata-only watch of the source secrets
+func TestSecretSyncSourcePredicate(t *testing.T) {
+	newMetadata := func(labeled bool, resourceVersion string) *metav1.PartialObjectMetadata {
+		obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+			Name:            "registry",
+			Namespace:       testNS,
+			ResourceVersion: resourceVersion,
+		}}
+		if labeled {
+			util.AddLabels(obj, hubv1.LabelPurpose, util.PurposeSecretSyncSource)
+		}
+		return obj
+	}
+
+	p := predicate.SecretSyncSource()
+
+	True(t, p.Create(event.CreateEvent{Object: newMetadata(true, "1")}), "create of source secret")
+	False(t, p.Create(event.CreateEvent{Object: newMetadata(false, "1")}), "create of other secret")
+	True(t, p.Update(event.UpdateEvent{ObjectOld: newMetadata(true, "1"), ObjectNew: newMetadata(true, "2")}),
+		"update of source secret")
+	True(t, p.Update(event.UpdateEvent{ObjectOld: newMetadata(false, "1"), ObjectNew: newMetadata(true, "1")}),
+		"label added")
+	False(t, p.Update(event.UpdateEvent{ObjectOld: newMetadata(true, "1"), ObjectNew: newMetadata(true, "1")}),
+		"resync of source secret")
+	False(t, p.Update(event.UpdateEvent{ObjectOld: newMetadata(false, "1"), ObjectNew: newMetadata(false, "2")}),
+		"update of other secret")
+}
+
+func newTestSecretSyncDeployItem(t *testing.T, name, configType, secretName string) *v1alpha1.DeployItem {
+	typeSpecificData, err := json.Marshal(map[string]interface{}{
+		"secrets": []interface{}{
+			map[string]interface{}{"name": secretName, "namespaces": []string{"app"}},
+		},
+	})
+	Nil(t, err, "error")
+
+	configuration, err := json.Marshal(&hubv1.HubDeployItemConfiguration{
+		DeploymentConfig: hubv1.DeploymentConfig{
+			ID:               "app",
+			TypeSpecificData: runtime.RawExtension{Raw: typeSpecificData},
+		},
+	})
+	Nil(t, err, "error")
+
+	return &v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNS,
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Type:          v1alpha1.DeployItemType(configType),
+			Configuration: &runtime.RawExtension{Raw: configuration},
+		},
+	}
+}
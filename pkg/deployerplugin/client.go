@@ -0,0 +1,215 @@
+package deployerplugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultTimeout  = 60 * time.Second
+	maxResponseSize = 10 * 1024 * 1024
+	// clientIdleTTL is the time after which the client of a registration which was not used is removed
+	clientIdleTTL = time.Hour
+)
+
+// ListFunc lists objects, e.g. the List method of a cached client or the ListUncached method of an uncached client.
+type ListFunc func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error
+
+// FindRegistration returns the deployer registration for a config type, or nil if there is none.
+func FindRegistration(ctx context.Context, list ListFunc, configType string) (*hubv1.DeployerRegistration, error) {
+	registrationList := &hubv1.DeployerRegistrationList{}
+	if err := list(ctx, registrationList); err != nil {
+		return nil, errors.Wrap(err, "could not list deployer registrations")
+	}
+
+	for i := range registrationList.Items {
+		if registrationList.Items[i].Spec.ConfigType == configType {
+			return &registrationList.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Client calls the operations of a deployer plugin.
+type Client struct {
+	configType string
+	url        string
+	httpClient *http.Client
+}
+
+func NewClient(registration *hubv1.DeployerRegistration) (*Client, error) {
+	protocolVersion := registration.Spec.ProtocolVersion
+	if protocolVersion != "" && protocolVersion != ProtocolVersionV1 {
+		return nil, fmt.Errorf("protocol version %s of deployer plugin for configtype %s is not supported",
+			protocolVersion, registration.Spec.ConfigType)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if registration.Spec.CABundle != "" {
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM([]byte(registration.Spec.CABundle)); !ok {
+			return nil, errors.New("could not parse caBundle of deployer registration " + registration.Name)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	timeout := defaultTimeout
+	if registration.Spec.TimeoutSeconds != nil {
+		timeout = time.Duration(*registration.Spec.TimeoutSeconds) * time.Second
+	}
+
+	return &Client{
+		configType: registration.Spec.ConfigType,
+		url:        strings.TrimSuffix(registration.Spec.URL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+// ClientCache contains a client per deployer registration, so that the connections to a plugin are reused by all
+// reconciles and admission reviews. The client of a registration is replaced if the registration was changed, and
+// removed if it was not used for clientIdleTTL.
+type ClientCache struct {
+	mutex   sync.Mutex
+	clients map[string]*registrationClient
+}
+
+type registrationClient struct {
+	resourceVersion string
+	client          *Client
+	lastUsed        time.Time
+}
+
+func NewClientCache() *ClientCache {
+	return &ClientCache{
+		clients: make(map[string]*registrationClient),
+	}
+}
+
+// Get returns the client for a deployer registration
+func (c *ClientCache) Get(registration *hubv1.DeployerRegistration) (*Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for name, rc := range c.clients {
+		if now.Sub(rc.lastUsed) > clientIdleTTL {
+			rc.client.closeIdleConnections()
+			delete(c.clients, name)
+		}
+	}
+
+	rc, ok := c.clients[registration.Name]
+	if !ok || rc.resourceVersion != registration.ResourceVersion {
+		pluginClient, err := NewClient(registration)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			rc.client.closeIdleConnections()
+		}
+
+		rc = &registrationClient{
+			resourceVersion: registration.ResourceVersion,
+			client:          pluginClient,
+		}
+		c.clients[registration.Name] = rc
+	}
+
+	rc.lastUsed = now
+	return rc.client, nil
+}
+
+// CallOperation sends an operation request to the plugin and returns its response.
+func (c *Client) CallOperation(ctx context.Context, request *OperationRequest) (*OperationResponse, error) {
+	request.ProtocolVersion = ProtocolVersionV1
+
+	response := &OperationResponse{}
+	if err := c.call(ctx, request.Operation, request, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Validate sends the typeSpecificData of an application config to the plugin for validation.
+func (c *Client) Validate(ctx context.Context, typeSpecificData *runtime.RawExtension) (*ValidationResponse, error) {
+	request := &ValidationRequest{
+		ProtocolVersion:  ProtocolVersionV1,
+		ConfigType:       c.configType,
+		TypeSpecificData: *typeSpecificData,
+	}
+
+	response := &ValidationResponse{}
+	if err := c.call(ctx, OperationValidate, request, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *Client) call(ctx context.Context, operation string, request, response interface{}) error {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal request for deployer plugin")
+	}
+
+	operationURL := c.url + "/" + ProtocolVersionV1 + "/" + operation
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, operationURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return errors.Wrap(err, "could not create request for deployer plugin")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "request to deployer plugin for configtype %s failed", c.configType)
+	}
+	defer res.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrapf(err, "could not read response of deployer plugin for configtype %s", c.configType)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %s to deployer plugin for configtype %s failed with status code %v: %s",
+			operation, c.configType, res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+
+	if err = json.Unmarshal(responseBody, response); err != nil {
+		return errors.Wrapf(err, "could not unmarshal response of deployer plugin for configtype %s", c.configType)
+	}
+
+	return nil
+}
+
+func (c *Client) closeIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
@@ -0,0 +1,166 @@
+package deployerplugin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const reblockDuration = 6 * time.Minute
+
+// pluginDeployerDI forwards the operations on deploy items to an out-of-process deployer plugin.
+type pluginDeployerDI struct {
+	client         *Client
+	uncachedClient synchronize.UncachedClient
+	blockObject    *synchronize.BlockObject
+}
+
+func NewPluginDeployerDI(pluginClient *Client, uncachedClient synchronize.UncachedClient,
+	blockObject *synchronize.BlockObject) deployutil.DeployItemDeployer {
+	return &pluginDeployerDI{
+		client:         pluginClient,
+		uncachedClient: uncachedClient,
+		blockObject:    blockObject,
+	}
+}
+
+func (r *pluginDeployerDI) ProcessNewOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, OperationProcessNew, "Deployment", 1)
+}
+
+func (r *pluginDeployerDI) RetryFailedOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	lastOp := deployData.ProviderStatus.LastOperation
+	r.processItem(ctx, deployData, OperationRetryFailed, "Retry of deployment", lastOp.NumberOfTries+1)
+}
+
+func (r *pluginDeployerDI) ReconcileOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, OperationReconcile, "Reconcile", 1)
+}
+
+func (r *pluginDeployerDI) ProcessPendingOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	if err := r.callOperation(ctx, deployData, OperationProcessPending, false); err != nil {
+		deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Readiness check failed for application "+configID, err)
+	}
+}
+
+func (r *pluginDeployerDI) Cleanup(ctx context.Context, deployData *deployutil.DeployData, clusterExists bool) error {
+	return r.callOperation(ctx, deployData, OperationCleanup, clusterExists)
+}
+
+func (r *pluginDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.DeployData) {
+	log := util.GetLoggerFromContext(ctx)
+
+	if err := r.callOperation(ctx, deployData, OperationPreprocess, false); err != nil {
+		log.Error(err, "preprocessing by deployer plugin failed")
+	}
+}
+
+// processItem calls an operation which acts on the target cluster. If the plugin cannot be called, the operation is
+// recorded as failed with a retryable error, so that it is repeated. If the plugin reports an error, the operation is
+// recorded as failed with the error code returned by the plugin.
+func (r *pluginDeployerDI) processItem(ctx context.Context, deployData *deployutil.DeployData, operation, operationText string,
+	numberOfTries int32) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+	_, err := r.blockObject.Reblock(ctx, clusterBomKey, r.uncachedClient, reblockDuration, true)
+	if err != nil {
+		deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, operationText+" failed for application "+configID, err)
+		deployData.SetFailedStatus(err, numberOfTries, metav1.Now())
+		return
+	}
+
+	err = r.callOperation(ctx, deployData, operation, false)
+	if err != nil {
+		if isPluginUnavailable(err) {
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, operationText+" failed for application "+configID, err)
+		} else {
+			deployutil.LogApplicationFailure(ctx, deployutil.ReasonFailedDeployment,
+				operationText+" failed for application "+configID+": "+err.Error())
+		}
+		deployData.SetFailedStatus(err, numberOfTries, metav1.Now())
+		return
+	}
+
+	if deployData.ProviderStatus.LastOperation.State == util.StateOk {
+		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, operationText+" done for application "+configID)
+	} else {
+		deployutil.LogApplicationFailure(ctx, deployutil.ReasonFailedDeployment, operationText+" failed for application "+configID)
+	}
+}
+
+// callOperation sends the deploy data to the plugin and replaces the status of the deploy data by the status returned
+// by the plugin. Errors are classified: a failed call has the code PluginUnavailable, an error reported by the plugin
+// the code returned by the plugin. The observed generation is set also for plugins which do not return it, because otherwise the
+// controller would consider the generation as unprocessed and repeat the operation forever.
+func (r *pluginDeployerDI) callOperation(ctx context.Context, deployData *deployutil.DeployData, operation string,
+	clusterExists bool) error {
+	deployItem := deployData.GetDeployItem()
+
+	request := &OperationRequest{
+		Operation:          operation,
+		DeployItem:         *deployData.GetDeployItemKey(),
+		Generation:         deployData.GetGeneration(),
+		ObservedGeneration: deployData.GetObservedGeneration(),
+		DeletionTimestamp:  deployItem.GetDeletionTimestamp(),
+		Annotations:        deployItem.GetAnnotations(),
+		TargetSecretRef:    *deployData.GetSecretKey(),
+		ClusterExists:      clusterExists,
+		Configuration:      *deployData.Configuration,
+		ProviderStatus:     *deployData.ProviderStatus,
+		Conditions:         deployItem.Status.Conditions,
+	}
+
+	response, err := r.client.CallOperation(ctx, request)
+	if err != nil {
+		return deployutil.NewDeployError(deployutil.ErrorCodePluginUnavailable, err)
+	}
+
+	if response.Error != "" {
+		return newPluginError(response)
+	}
+
+	deployData.ProviderStatus = &response.ProviderStatus
+	deployItem.Status.Conditions = response.Conditions
+	deployData.ExportValues = response.ExportValues
+
+	if response.ObservedGeneration != 0 {
+		deployItem.Status.ObservedGeneration = response.ObservedGeneration
+	} else if isDeployOperation(operation) {
+		deployItem.Status.ObservedGeneration = deployData.GetGeneration()
+	}
+
+	if response.Phase != "" {
+		deployData.SetPhase(response.Phase)
+	}
+
+	return nil
+}
+
+// newPluginError returns the error reported by the plugin with its error code, or PluginOperationFailed if the plugin
+// returned no known code
+func newPluginError(response *OperationResponse) *deployutil.DeployError {
+	code := deployutil.ErrorCodePluginFailed
+	if deployutil.IsKnownErrorCode(response.ErrorCode) {
+		code = deployutil.ErrorCode(response.ErrorCode)
+	}
+	return deployutil.NewDeployError(code, errors.New(response.Error))
+}
+
+func isPluginUnavailable(err error) bool {
+	var deployError *deployutil.DeployError
+	return errors.As(err, &deployError) && deployError.Code == deployutil.ErrorCodePluginUnavailable
+}
+
+// isDeployOperation returns whether the operation processes the current generation of the deploy item
+func isDeployOperation(operation string) bool {
+	return operation == OperationProcessNew || operation == OperationRetryFailed || operation == OperationReconcile
+}
@@ -0,0 +1,85 @@
+package deployerplugin
+
+import (
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Version 1 of the plugin protocol. The controller sends a POST request with a json body to the path of an operation
+// below the url of the plugin, e.g. https://my-plugin:8443/v1/processNewOperation. The operations mirror the methods
+// of deployutil.DeployItemDeployer. The plugin answers with status code 200 and a json body, also if the deployment
+// failed; other status codes mean that the plugin could not handle the request.
+const (
+	ProtocolVersionV1 = "v1"
+
+	OperationProcessNew     = "processNewOperation"
+	OperationRetryFailed    = "retryFailedOperation"
+	OperationReconcile      = "reconcileOperation"
+	OperationProcessPending = "processPendingOperation"
+	OperationCleanup        = "cleanup"
+	OperationPreprocess     = "preprocess"
+
+	// OperationValidate is called by the admission webhook to validate the typeSpecificData of an application config
+	OperationValidate = "validate"
+)
+
+// OperationRequest is the request body of all operations except validate.
+type OperationRequest struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	Operation       string `json:"operation"`
+
+	// DeployItem identifies the deploy item
+	DeployItem types.NamespacedName `json:"deployItem"`
+	// Generation, ObservedGeneration, DeletionTimestamp and Annotations of the deploy item. A deletion timestamp
+	// means that the application must be removed.
+	Generation         int64             `json:"generation"`
+	ObservedGeneration int64             `json:"observedGeneration"`
+	DeletionTimestamp  *metav1.Time      `json:"deletionTimestamp,omitempty"`
+	Annotations        map[string]string `json:"annotations,omitempty"`
+	// TargetSecretRef references the secret with the kubeconfig of the target cluster
+	TargetSecretRef types.NamespacedName `json:"targetSecretRef"`
+	// ClusterExists is only set for the cleanup operation
+	ClusterExists bool `json:"clusterExists,omitempty"`
+
+	Configuration  hubv1.HubDeployItemConfiguration  `json:"configuration"`
+	ProviderStatus hubv1.HubDeployItemProviderStatus `json:"providerStatus"`
+	Conditions     []v1alpha1.Condition              `json:"conditions,omitempty"`
+}
+
+// OperationResponse is the response body of all operations except validate. ProviderStatus and Conditions replace the
+// status of the deploy item, so that a plugin must return them also if it does not change them. Non-empty ExportValues
+// are written into the export secret of the deploy item. ObservedGeneration and Phase are set in the status of the deploy
+// item if they are not empty; a plugin which does not return an observed generation is treated as if it had processed
+// the generation of the request, so that the operation is not repeated.
+type OperationResponse struct {
+	ProviderStatus     hubv1.HubDeployItemProviderStatus `json:"providerStatus"`
+	Conditions         []v1alpha1.Condition              `json:"conditions,omitempty"`
+	ExportValues       map[string]interface{}            `json:"exportValues,omitempty"`
+	ObservedGeneration int64                             `json:"observedGeneration,omitempty"`
+	Phase              v1alpha1.ExecutionPhase           `json:"phase,omitempty"`
+
+	// Error means that the plugin could not process the operation; the status of the deploy item is then not changed
+	// apart from the failure of the operation. ErrorCode optionally classifies the error by one of the error codes of
+	// the controller, e.g. Timeout, which determines whether the operation is retried. Errors without a known code get
+	// the code PluginOperationFailed and are not retried.
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"errorCode,omitempty"`
+}
+
+// ValidationRequest is the request body of the validate operation.
+type ValidationRequest struct {
+	ProtocolVersion  string               `json:"protocolVersion"`
+	ConfigType       string               `json:"configType"`
+	TypeSpecificData runtime.RawExtension `json:"typeSpecificData"`
+}
+
+// ValidationResponse is the response body of the validate operation. If Allowed is false, the clusterbom is rejected
+// with the message.
+type ValidationResponse struct {
+	Allowed bool   `json:"allowed"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,118 @@
+// Package reference contains a reference deployer plugin, which demonstrates the plugin protocol and is used in tests.
+// It does not deploy anything to the target cluster, but only records the operations in the status of the deploy item.
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ConfigType is the config type for which the reference plugin is usually registered
+const ConfigType = "reference"
+
+// SpecificData is the typeSpecificData of an application config deployed by the reference plugin. The message is
+// exported; if Fail is true, all deployments fail.
+type SpecificData struct {
+	Message string `json:"message,omitempty"`
+	Fail    bool   `json:"fail,omitempty"`
+}
+
+func newSpecificData(typeSpecificData *runtime.RawExtension) (*SpecificData, error) {
+	var specificData SpecificData
+	if err := json.Unmarshal(typeSpecificData.Raw, &specificData); err != nil {
+		return nil, err
+	}
+
+	if specificData.Message == "" {
+		return nil, errors.New("property \"message\" not found")
+	}
+
+	return &specificData, nil
+}
+
+// Validate is the validator of the reference plugin.
+func Validate(ctx context.Context, configType string, typeSpecificData *runtime.RawExtension) error {
+	_, err := newSpecificData(typeSpecificData)
+	return err
+}
+
+type referenceDeployer struct{}
+
+func NewReferenceDeployer() deployutil.DeployItemDeployer {
+	return &referenceDeployer{}
+}
+
+func (r *referenceDeployer) ProcessNewOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, 1)
+}
+
+func (r *referenceDeployer) RetryFailedOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, deployData.ProviderStatus.LastOperation.NumberOfTries+1)
+}
+
+func (r *referenceDeployer) ReconcileOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	r.processItem(ctx, deployData, 1)
+}
+
+func (r *referenceDeployer) ProcessPendingOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	deployData.SetStatusForReachableCluster()
+	r.setReadiness(deployData, metav1.Now())
+}
+
+func (r *referenceDeployer) Cleanup(ctx context.Context, deployData *deployutil.DeployData, clusterExists bool) error {
+	return nil
+}
+
+func (r *referenceDeployer) Preprocess(ctx context.Context, deployData *deployutil.DeployData) {
+}
+
+func (r *referenceDeployer) processItem(ctx context.Context, deployData *deployutil.DeployData, numberOfTries int32) {
+	log := util.GetLoggerFromContext(ctx)
+	now := metav1.Now()
+
+	specificData, err := newSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		log.Error(err, "could not parse typeSpecificData")
+		deployData.SetFailedStatus(err, numberOfTries, now)
+		return
+	}
+
+	if specificData.Fail {
+		deployData.SetFailedStatus(errors.New("deployment failed as requested by property \"fail\""), numberOfTries, now)
+		return
+	}
+
+	description := "deployed"
+	if deployData.IsDeleteOperation() {
+		description = "removed"
+	}
+
+	deployData.SetStatus(util.StateOk, description, 1, now)
+	r.setReadiness(deployData, now)
+
+	if deployData.IsInstallOperation() {
+		deployData.ExportValues = map[string]interface{}{
+			"message": specificData.Message,
+		}
+	}
+}
+
+func (r *referenceDeployer) setReadiness(deployData *deployutil.DeployData, now metav1.Time) {
+	readiness := util.StateOk
+	if deployData.IsLastDeployFailed() {
+		readiness = util.StateFailed
+	}
+
+	deployData.ProviderStatus.Readiness = &hubv1.Readiness{
+		State: readiness,
+		Time:  now,
+	}
+}
@@ -0,0 +1,226 @@
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployerplugin"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	testUtils "github.com/gardener/potter-controller/pkg/testing"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestPluginDeployer(t *testing.T) {
+	tests := []struct {
+		name                string
+		typeSpecificData    string
+		expectedState       string
+		expectedReadiness   string
+		expectedExportValue interface{}
+	}{
+		{
+			name:                "successful deployment",
+			typeSpecificData:    `{"message":"hello"}`,
+			expectedState:       util.StateOk,
+			expectedReadiness:   util.StateOk,
+			expectedExportValue: "hello",
+		},
+		{
+			name:              "failed deployment",
+			typeSpecificData:  `{"message":"hello","fail":true}`,
+			expectedState:     util.StateFailed,
+			expectedReadiness: "",
+		},
+	}
+
+	server := newTestServer()
+	defer server.Close()
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			deployer := newTestPluginDeployer(t, server.URL)
+			deployData := newTestDeployData(t, test.typeSpecificData)
+
+			deployer.ProcessNewOperation(testUtils.CreateTestContext(), deployData)
+
+			lastOp := deployData.ProviderStatus.LastOperation
+			assert.Equal(t, lastOp.State, test.expectedState, "state")
+			assert.Equal(t, lastOp.NumberOfTries, int32(1), "number of tries")
+
+			readiness := ""
+			if deployData.ProviderStatus.Readiness != nil {
+				readiness = deployData.ProviderStatus.Readiness.State
+			}
+			assert.Equal(t, readiness, test.expectedReadiness, "readiness")
+			assert.Equal(t, deployData.ExportValues["message"], test.expectedExportValue, "export value")
+		})
+	}
+}
+
+func TestPluginDeployerRetry(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	deployer := newTestPluginDeployer(t, server.URL)
+	deployData := newTestDeployData(t, `{"message":"hello","fail":true}`)
+
+	deployer.ProcessNewOperation(testUtils.CreateTestContext(), deployData)
+	deployer.RetryFailedOperation(testUtils.CreateTestContext(), deployData)
+
+	lastOp := deployData.ProviderStatus.LastOperation
+	assert.Equal(t, lastOp.State, util.StateFailed, "state")
+	assert.Equal(t, lastOp.NumberOfTries, int32(2), "number of tries")
+}
+
+func TestPluginUnavailable(t *testing.T) {
+	server := newTestServer()
+	server.Close()
+
+	deployer := newTestPluginDeployer(t, server.URL)
+	deployData := newTestDeployData(t, `{"message":"hello"}`)
+
+	deployer.ProcessNewOperation(testUtils.CreateTestContext(), deployData)
+
+	assert.Equal(t, deployData.ProviderStatus.LastOperation.State, util.StateFailed, "state")
+	errorEntry := deployData.GetLastErrorEntry()
+	assert.NotNil(t, errorEntry, "error entry")
+	assert.Equal(t, errorEntry.Code, string(deployutil.ErrorCodePluginUnavailable), "error code")
+	assert.True(t, errorEntry.Retryable, "retryable")
+
+	err := deployer.Cleanup(testUtils.CreateTestContext(), deployData, true)
+	assert.NotNil(t, err, "cleanup error")
+}
+
+// TestPluginReportedError tests that an error reported by a plugin is recorded with the error code returned by the
+// plugin, and that errors without a known code are not retried
+func TestPluginReportedError(t *testing.T) {
+	tests := []struct {
+		name              string
+		errorCode         string
+		expectedCode      deployutil.ErrorCode
+		expectedRetryable bool
+	}{
+		{"without error code", "", deployutil.ErrorCodePluginFailed, false},
+		{"unknown error code", "Mysterious", deployutil.ErrorCodePluginFailed, false},
+		{"retryable error code", string(deployutil.ErrorCodeTimeout), deployutil.ErrorCodeTimeout, true},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(&deployerplugin.OperationResponse{
+					Error:     "deployment rejected",
+					ErrorCode: test.errorCode,
+				})
+			}))
+			defer server.Close()
+
+			deployer := newTestPluginDeployer(t, server.URL)
+			deployData := newTestDeployData(t, `{"message":"hello"}`)
+
+			deployer.ProcessNewOperation(testUtils.CreateTestContext(), deployData)
+
+			assert.Equal(t, deployData.ProviderStatus.LastOperation.State, util.StateFailed, "state")
+			assert.Equal(t, deployData.ProviderStatus.LastOperation.Description, "deployment rejected", "description")
+			errorEntry := deployData.GetLastErrorEntry()
+			assert.NotNil(t, errorEntry, "error entry")
+			assert.Equal(t, errorEntry.Code, string(test.expectedCode), "error code")
+			assert.Equal(t, errorEntry.Retryable, test.expectedRetryable, "retryable")
+		})
+	}
+}
+
+func TestPluginValidation(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	pluginClient, err := deployerplugin.NewClient(newTestRegistration(server.URL))
+	assert.Nil(t, err, "error")
+
+	response, err := pluginClient.Validate(context.Background(), &runtime.RawExtension{Raw: []byte(`{"message":"hello"}`)})
+	assert.Nil(t, err, "error")
+	assert.True(t, response.Allowed, "allowed")
+
+	response, err = pluginClient.Validate(context.Background(), &runtime.RawExtension{Raw: []byte(`{"fail":true}`)})
+	assert.Nil(t, err, "error")
+	assert.False(t, response.Allowed, "allowed")
+	assert.Equal(t, response.Message, "property \"message\" not found", "message")
+}
+
+// TestClientCache tests that the client of a registration is reused, and replaced if the registration was changed
+func TestClientCache(t *testing.T) {
+	pluginClients := deployerplugin.NewClientCache()
+	registration := newTestRegistration("http://localhost")
+	registration.ResourceVersion = "1"
+
+	first, err := pluginClients.Get(registration)
+	assert.Nil(t, err, "error")
+	second, err := pluginClients.Get(registration)
+	assert.Nil(t, err, "error")
+	assert.True(t, first == second, "client is reused")
+
+	registration.ResourceVersion = "2"
+	third, err := pluginClients.Get(registration)
+	assert.Nil(t, err, "error")
+	assert.True(t, third != second, "client is replaced after a change of the registration")
+
+	otherRegistration := newTestRegistration("http://localhost")
+	otherRegistration.Name = "other"
+	other, err := pluginClients.Get(otherRegistration)
+	assert.Nil(t, err, "error")
+	assert.True(t, other != third, "client per registration")
+
+	registration.ResourceVersion = "3"
+	registration.Spec.ProtocolVersion = "v2"
+	_, err = pluginClients.Get(registration)
+	assert.NotNil(t, err, "error for unsupported protocol version")
+}
+
+func TestUnsupportedProtocolVersion(t *testing.T) {
+	registration := newTestRegistration("http://localhost")
+	registration.Spec.ProtocolVersion = "v2"
+
+	_, err := deployerplugin.NewClient(registration)
+	assert.NotNil(t, err, "error")
+}
+
+func newTestServer() *httptest.Server {
+	log := ctrl.Log.WithName("Reference Plugin Test")
+	return httptest.NewServer(deployerplugin.NewHandler(NewReferenceDeployer(), Validate, log))
+}
+
+func newTestRegistration(url string) *hubv1.DeployerRegistration {
+	return &hubv1.DeployerRegistration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ConfigType,
+		},
+		Spec: hubv1.DeployerRegistrationSpec{
+			ConfigType: ConfigType,
+			URL:        url,
+		},
+	}
+}
+
+func newTestPluginDeployer(t *testing.T, url string) deployutil.DeployItemDeployer {
+	pluginClient, err := deployerplugin.NewClient(newTestRegistration(url))
+	assert.Nil(t, err, "error")
+	return deployerplugin.NewPluginDeployerDI(pluginClient, testUtils.NewUnitTestClientDi(), synchronize.NewBlockObject(nil, true))
+}
+
+func newTestDeployData(t *testing.T, typeSpecificData string) *deployutil.DeployData {
+	deployItem := testUtils.CreateDeployItemForConfig(t, "test-bom-app", "garden-test", json.RawMessage(typeSpecificData), nil)
+	deployItem.Spec.Type = ConfigType
+	return testUtils.CreateDeployData(t, deployItem)
+}
@@ -0,0 +1,180 @@
+package deployerplugin
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Validator validates the typeSpecificData of an application config. A returned error rejects the clusterbom.
+type Validator func(ctx context.Context, configType string, typeSpecificData *runtime.RawExtension) error
+
+// NewHandler returns a handler which serves the plugin protocol for a deployer written in Go, so that it can run as
+// out-of-process plugin. The deployer operates on the deploy data sent by the controller, and the resulting status is
+// returned to the controller.
+func NewHandler(deployer deployutil.DeployItemDeployer, validator Validator, log logr.Logger) http.Handler {
+	return &pluginHandler{
+		deployer:  deployer,
+		validator: validator,
+		log:       log,
+	}
+}
+
+type pluginHandler struct {
+	deployer  deployutil.DeployItemDeployer
+	validator Validator
+	log       logr.Logger
+}
+
+func (h *pluginHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	if req.Method != http.MethodPost {
+		http.Error(w, "method "+req.Method+" not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := "/" + ProtocolVersionV1 + "/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		http.Error(w, "unsupported path "+req.URL.Path, http.StatusNotFound)
+		return
+	}
+	operation := strings.TrimPrefix(req.URL.Path, prefix)
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		message := "reading request body failed"
+		h.log.Error(err, message)
+		http.Error(w, message+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var response interface{}
+	if operation == OperationValidate {
+		response, err = h.validate(req.Context(), body)
+	} else {
+		response, err = h.handleOperation(req.Context(), operation, body)
+	}
+
+	if err != nil {
+		message := "handling of operation " + operation + " failed"
+		h.log.Error(err, message)
+		http.Error(w, message+": "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		message := "marshaling response failed"
+		h.log.Error(err, message)
+		http.Error(w, message+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(responseBody); err != nil {
+		h.log.Error(err, "writing http response failed")
+	}
+}
+
+func (h *pluginHandler) validate(ctx context.Context, body []byte) (*ValidationResponse, error) {
+	request := &ValidationRequest{}
+	if err := json.Unmarshal(body, request); err != nil {
+		return nil, err
+	}
+
+	if h.validator != nil {
+		if err := h.validator(ctx, request.ConfigType, &request.TypeSpecificData); err != nil {
+			return &ValidationResponse{Allowed: false, Message: err.Error()}, nil
+		}
+	}
+
+	return &ValidationResponse{Allowed: true}, nil
+}
+
+func (h *pluginHandler) handleOperation(ctx context.Context, operation string, body []byte) (*OperationResponse, error) {
+	request := &OperationRequest{}
+	if err := json.Unmarshal(body, request); err != nil {
+		return nil, err
+	}
+
+	deployData, err := newDeployData(request)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, util.LoggerKey{}, h.log.WithValues(
+		util.LogKeyDeployItemName, request.DeployItem,
+		"operation", operation))
+
+	switch operation {
+	case OperationProcessNew:
+		h.deployer.ProcessNewOperation(ctx, deployData)
+	case OperationRetryFailed:
+		h.deployer.RetryFailedOperation(ctx, deployData)
+	case OperationReconcile:
+		h.deployer.ReconcileOperation(ctx, deployData)
+	case OperationProcessPending:
+		h.deployer.ProcessPendingOperation(ctx, deployData)
+	case OperationPreprocess:
+		h.deployer.Preprocess(ctx, deployData)
+	case OperationCleanup:
+		if err = h.deployer.Cleanup(ctx, deployData, request.ClusterExists); err != nil {
+			return &OperationResponse{Error: err.Error(), ErrorCode: string(deployutil.ClassifyError(err).Code)}, nil
+		}
+	default:
+		return &OperationResponse{Error: "unsupported operation " + operation}, nil
+	}
+
+	return &OperationResponse{
+		ProviderStatus:     *deployData.ProviderStatus,
+		Conditions:         deployData.GetDeployItem().Status.Conditions,
+		ExportValues:       deployData.ExportValues,
+		ObservedGeneration: deployData.GetObservedGeneration(),
+		Phase:              deployData.GetDeployItem().Status.Phase,
+	}, nil
+}
+
+// newDeployData reconstructs the deploy data of the controller from an operation request.
+func newDeployData(request *OperationRequest) (*deployutil.DeployData, error) {
+	rawConfiguration, err := json.Marshal(&request.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	rawProviderStatus, err := json.Marshal(&request.ProviderStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	deployItem := &v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              request.DeployItem.Name,
+			Namespace:         request.DeployItem.Namespace,
+			Generation:        request.Generation,
+			DeletionTimestamp: request.DeletionTimestamp,
+			Annotations:       request.Annotations,
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Configuration: &runtime.RawExtension{Raw: rawConfiguration},
+		},
+		Status: v1alpha1.DeployItemStatus{
+			ObservedGeneration: request.ObservedGeneration,
+			Conditions:         request.Conditions,
+			ProviderStatus:     &runtime.RawExtension{Raw: rawProviderStatus},
+		},
+	}
+
+	return deployutil.NewDeployData(deployItem)
+}
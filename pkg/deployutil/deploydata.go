@@ -3,10 +3,12 @@ package deployutil
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"sort"
 
 	"github.com/gardener/landscaper/apis/core/v1alpha1"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 )
 
@@ -121,6 +124,12 @@ func (d *DeployData) IsLastDeployFailed() bool {
 	return d.ProviderStatus.LastOperation.State == util.StateFailed
 }
 
+// IsDeletionInProgress returns true if the removal of the application was triggered, but has not yet finished.
+func (d *DeployData) IsDeletionInProgress() bool {
+	lastOp := d.ProviderStatus.LastOperation
+	return d.IsDeleteOperation() && lastOp.Operation == util.OperationRemove && lastOp.State == util.StatePending
+}
+
 func (d *DeployData) IsInstallButNotReady() bool {
 	isInstall := d.ProviderStatus.LastOperation.Operation == util.OperationInstall
 
@@ -141,6 +150,60 @@ func (d *DeployData) IsReconcile() bool {
 		util.HasAnnotation(d.deployItem, util.AnnotationKeyReconcile, util.AnnotationValueReconcile)
 }
 
+func (d *DeployData) IsResetRetries() bool {
+	return util.HasAnnotation(d.deployItem, util.AnnotationKeyResetRetries, util.AnnotationValueResetRetries)
+}
+
+func (d *DeployData) IsResume() bool {
+	return util.HasAnnotation(d.deployItem, util.AnnotationKeyResume, util.AnnotationValueResume)
+}
+
+func (d *DeployData) GetRetryPolicy() *hubv1.RetryPolicy {
+	return d.Configuration.DeploymentConfig.RetryPolicy
+}
+
+// IsRetryLimitReached returns true if the last operation has failed as often as the retry policy allows.
+func (d *DeployData) IsRetryLimitReached() bool {
+	retryPolicy := d.GetRetryPolicy()
+	return retryPolicy != nil &&
+		retryPolicy.MaxAttempts > 0 &&
+		d.ProviderStatus.LastOperation.NumberOfTries >= retryPolicy.MaxAttempts
+}
+
+// ResetRetries resets the retry counter, and removes a finallyFailed readiness, so that the next operation starts
+// with the base delay of the retry policy.
+func (d *DeployData) ResetRetries() {
+	d.ProviderStatus.LastOperation.NumberOfTries = 0
+	if d.IsFinallyFailed() {
+		d.ProviderStatus.Readiness = nil
+	}
+}
+
+// SetFinallyFailed marks a deploy item as finally failed, because its last operation has failed and the retry policy
+// allows no further retry.
+func (d *DeployData) SetFinallyFailed(message string, now metav1.Time) {
+	d.ProviderStatus.Readiness = &hubv1.Readiness{
+		State: util.StateFinallyFailed,
+		Time:  now,
+	}
+
+	d.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, v1.ConditionFalse, now, hubv1.ReasonRetriesExhausted, message)
+	d.SetPhase(v1alpha1.ExecutionPhaseFailed)
+}
+
+// SetRetriesStopped marks a deploy item whose last operation has failed as often as the retry policy allows, without
+// giving up. It stays failed and is only retried after a change of its configuration or a reset of the retries.
+func (d *DeployData) SetRetriesStopped(message string, now metav1.Time) {
+	d.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, v1.ConditionUnknown, now, hubv1.ReasonRetriesStopped, message)
+	d.SetPhase(v1alpha1.ExecutionPhaseFailed)
+}
+
+// IsRetriesStopped returns true if the retries of the last operation were stopped, because the retry limit is reached.
+func (d *DeployData) IsRetriesStopped() bool {
+	condition := d.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	return condition != nil && condition.Reason == string(hubv1.ReasonRetriesStopped)
+}
+
 func (d *DeployData) SetPhase(phase v1alpha1.ExecutionPhase) {
 	d.deployItem.Status.Phase = phase
 }
@@ -187,7 +250,25 @@ func (d *DeployData) initLastOperation() {
 	}
 }
 
+// SetStatus sets the status of an operation. Failed operations should be recorded with SetFailedStatus, so that their
+// error is classified; here they get the generic code Unknown.
 func (d *DeployData) SetStatus(lastState, description string, numberOfTries int32, currentTime metav1.Time) {
+	var deployError *DeployError
+	if lastState == util.StateFailed {
+		deployError = NewDeployError(ErrorCodeUnknown, errors.New(description))
+	}
+
+	d.setStatus(lastState, description, deployError, numberOfTries, currentTime)
+}
+
+// SetFailedStatus sets the status of a failed operation. The error is classified and its code, retryable flag and
+// remediation hint are recorded in the error history.
+func (d *DeployData) SetFailedStatus(err error, numberOfTries int32, currentTime metav1.Time) {
+	d.setStatus(util.StateFailed, err.Error(), ClassifyError(err), numberOfTries, currentTime)
+}
+
+func (d *DeployData) setStatus(lastState, description string, deployError *DeployError, numberOfTries int32,
+	currentTime metav1.Time) {
 	newSuccessGeneration := d.ProviderStatus.LastOperation.SuccessGeneration
 	if lastState == util.StateOk {
 		newSuccessGeneration = d.deployItem.GetGeneration()
@@ -212,7 +293,7 @@ func (d *DeployData) SetStatus(lastState, description string, numberOfTries int3
 			State:             lastState,
 			Time:              currentTime,
 			Description:       description,
-			ErrorHistory:      d.computeErrorHistory(lastState, description, numberOfTries, currentTime),
+			ErrorHistory:      d.computeErrorHistory(lastState, description, deployError, numberOfTries, currentTime),
 		},
 		Reachability: &hubv1.Reachability{
 			Reachable: true,
@@ -221,25 +302,18 @@ func (d *DeployData) SetStatus(lastState, description string, numberOfTries int3
 	}
 }
 
-func (d *DeployData) computeErrorHistory(lastState, description string, numberOfTries int32, currentTime metav1.Time) *hubv1.ErrorHistory {
+func (d *DeployData) computeErrorHistory(lastState, description string, deployError *DeployError, numberOfTries int32,
+	currentTime metav1.Time) *hubv1.ErrorHistory {
 	var errorHistory *hubv1.ErrorHistory
 
 	if lastState == util.StateFailed {
-		if numberOfTries < 2 || d.ProviderStatus.LastOperation.ErrorHistory == nil {
-			errorEntry := hubv1.ErrorEntry{
-				Description: description,
-				Time:        currentTime,
-			}
+		errorEntry := newErrorEntry(description, deployError, currentTime)
 
+		if numberOfTries < 2 || d.ProviderStatus.LastOperation.ErrorHistory == nil {
 			errorHistory = &hubv1.ErrorHistory{
 				ErrorEntries: []hubv1.ErrorEntry{errorEntry},
 			}
 		} else {
-			errorEntry := hubv1.ErrorEntry{
-				Description: description,
-				Time:        currentTime,
-			}
-
 			errorEntries := d.ProviderStatus.LastOperation.ErrorHistory.ErrorEntries
 			if len(errorEntries) < 5 {
 				errorEntries = append(errorEntries, errorEntry)
@@ -250,15 +324,14 @@ func (d *DeployData) computeErrorHistory(lastState, description string, numberOf
 				for i := range errorEntries {
 					nextErrorEntry := &errorEntries[i]
 					if i > 0 && nextErrorEntry.Description == description {
-						nextErrorEntry.Time = currentTime
+						*nextErrorEntry = errorEntry
 						replaced = true
 						break
 					}
 				}
 
 				if !replaced {
-					errorEntries[1].Time = currentTime
-					errorEntries[1].Description = description
+					errorEntries[1] = errorEntry
 				}
 			}
 
@@ -270,6 +343,48 @@ func (d *DeployData) computeErrorHistory(lastState, description string, numberOf
 	return errorHistory
 }
 
+func newErrorEntry(description string, deployError *DeployError, currentTime metav1.Time) hubv1.ErrorEntry {
+	errorEntry := hubv1.ErrorEntry{
+		Description: description,
+		Time:        currentTime,
+	}
+
+	if deployError != nil {
+		errorEntry.Code = string(deployError.Code)
+		errorEntry.Retryable = deployError.IsRetryable()
+		errorEntry.Hint = deployError.Hint()
+	}
+
+	return errorEntry
+}
+
+// GetLastErrorEntry returns the most recent entry of the error history, or nil if there is none.
+func (d *DeployData) GetLastErrorEntry() *hubv1.ErrorEntry {
+	errorHistory := d.ProviderStatus.LastOperation.ErrorHistory
+	if errorHistory == nil || len(errorHistory.ErrorEntries) == 0 {
+		return nil
+	}
+
+	last := &errorHistory.ErrorEntries[0]
+	for i := range errorHistory.ErrorEntries {
+		if last.Time.Time.Before(errorHistory.ErrorEntries[i].Time.Time) {
+			last = &errorHistory.ErrorEntries[i]
+		}
+	}
+	return last
+}
+
+// IsLastErrorRetryable returns false if the last operation failed with an error that is not resolved by an automatic
+// retry, for example invalid values or wrong credentials. Errors without classification are regarded as retryable.
+func (d *DeployData) IsLastErrorRetryable() bool {
+	lastErrorEntry := d.GetLastErrorEntry()
+	if lastErrorEntry == nil || lastErrorEntry.Code == "" {
+		return true
+	}
+
+	return lastErrorEntry.Retryable
+}
+
 func (d *DeployData) sortErrorEntries(errorEntries []hubv1.ErrorEntry) {
 	sort.Slice(errorEntries, func(i, j int) bool {
 		return errorEntries[i].Time.Time.Before(errorEntries[j].Time.Time)
@@ -357,10 +472,16 @@ func (d *DeployData) IsConditionTrue(conditionType hubv1.HubDeploymentConditionT
 }
 
 func (d *DeployData) ComputeReadiness(ctx context.Context, basicKubernetesObjects []BasicKubernetesObject,
-	targetClient client.Client, dynamicClient *DynamicTargetClient, namespace string) string {
+	targetClient client.Client, dynamicClient *DynamicTargetClient, namespace string) (resultReadiness string) {
 	log := ctx.Value(util.LoggerKey{}).(logr.Logger)
 
-	resultReadiness := util.StateOk
+	ctx, span := tracing.Start(ctx, "computeReadiness")
+	defer func() {
+		span.SetAttributes(attribute.String(tracing.AttributeReadiness, resultReadiness))
+		span.End()
+	}()
+
+	resultReadiness = util.StateOk
 
 	for i := range basicKubernetesObjects {
 		obj := &basicKubernetesObjects[i]
@@ -283,6 +283,16 @@ func (e *ClusterUnreachableError) Error() string {
 	return e.Err.Error()
 }
 
+// DeletionInProgressError is returned by a deployer if the removal of an application was triggered, but has not yet
+// finished. The deploy item keeps its finalizer and is processed again until the removal is done.
+type DeletionInProgressError struct {
+	Message string
+}
+
+func (e *DeletionInProgressError) Error() string {
+	return e.Message
+}
+
 func ComputeReadinessForResourceReadyRequirements(resourceReadyRequirements []hubv1.Resource,
 	resultReadiness string, dynamicClient *DynamicTargetClient, logger logr.Logger) string {
 	for i, resourceReadyRequirement := range resourceReadyRequirements {
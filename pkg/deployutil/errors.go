@@ -0,0 +1,157 @@
+package deployutil
+
+import (
+	"context"
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type ErrorCode string
+
+const (
+	ErrorCodeChartFetch         ErrorCode = "ChartFetchFailed"
+	ErrorCodeChartAuth          ErrorCode = "ChartAuthFailed"
+	ErrorCodeRender             ErrorCode = "RenderFailed"
+	ErrorCodeAPIConflict        ErrorCode = "APIConflict"
+	ErrorCodeTimeout            ErrorCode = "Timeout"
+	ErrorCodeReadiness          ErrorCode = "ReadinessFailed"
+	ErrorCodeQuotaOrAdmission   ErrorCode = "QuotaOrAdmissionRejected"
+	ErrorCodeClusterUnreachable ErrorCode = "ClusterUnreachable"
+	ErrorCodeStuckRelease       ErrorCode = "StuckReleaseRecovered"
+	ErrorCodePluginUnavailable  ErrorCode = "PluginUnavailable"
+	ErrorCodePluginFailed       ErrorCode = "PluginOperationFailed"
+	ErrorCodeObjectConflict     ErrorCode = "ObjectConflict"
+	ErrorCodeUnknown            ErrorCode = "Unknown"
+)
+
+type errorClass struct {
+	retryable bool
+	hint      string
+}
+
+// nolint
+var errorClasses = map[ErrorCode]errorClass{
+	ErrorCodeChartFetch: {
+		retryable: true,
+		hint:      "Check that the chart repository is reachable and that the chart name and version exist.",
+	},
+	ErrorCodeChartAuth: {
+		retryable: false,
+		hint:      "Check the credentials of the chart repository, e.g. the secret referenced by tarballAccess.",
+	},
+	ErrorCodeRender: {
+		retryable: false,
+		hint:      "Check the values of the application config; the templates could not be rendered with them.",
+	},
+	ErrorCodeAPIConflict: {
+		retryable: true,
+		hint:      "Another actor modified the same resources concurrently; the operation is retried.",
+	},
+	ErrorCodeTimeout: {
+		retryable: true,
+		hint:      "The operation did not finish in time; consider increasing the timeouts of the application config.",
+	},
+	ErrorCodeReadiness: {
+		retryable: true,
+		hint:      "Check the pods and jobs of the application on the target cluster.",
+	},
+	ErrorCodeQuotaOrAdmission: {
+		retryable: true,
+		hint:      "Check the resource quotas, admission policies and permissions on the target cluster.",
+	},
+	ErrorCodeClusterUnreachable: {
+		retryable: true,
+		hint:      "Check that the target cluster exists, is not hibernated and that its kubeconfig is valid.",
+	},
+	ErrorCodeStuckRelease: {
+		retryable: true,
+		hint:      "The release was stuck in a pending state, e.g. after a restart of the controller, and has been recovered; the operation is retried.",
+	},
+	ErrorCodePluginUnavailable: {
+		retryable: true,
+		hint:      "Check that the deployer plugin registered for the config type of the application is running and reachable.",
+	},
+	ErrorCodePluginFailed: {
+		retryable: false,
+		hint:      "The deployer plugin could not process the operation; see the error description and the logs of the plugin.",
+	},
+	ErrorCodeObjectConflict: {
+		retryable: false,
+		hint:      "An object on the target cluster which was not created by the application cannot be changed as required; delete or rename it.",
+	},
+	ErrorCodeUnknown: {
+		retryable: true,
+		hint:      "See the error description for details.",
+	},
+}
+
+// DeployError is an error together with its classification.
+type DeployError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func NewDeployError(code ErrorCode, err error) *DeployError {
+	return &DeployError{Code: code, Err: err}
+}
+
+func (e *DeployError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeployError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DeployError) IsRetryable() bool {
+	return getErrorClass(e.Code).retryable
+}
+
+func (e *DeployError) Hint() string {
+	return getErrorClass(e.Code).hint
+}
+
+// IsKnownErrorCode returns true if the code is one of the error codes above, e.g. for codes returned by deployer plugins.
+func IsKnownErrorCode(code string) bool {
+	_, ok := errorClasses[ErrorCode(code)]
+	return ok
+}
+
+func getErrorClass(code ErrorCode) errorClass {
+	class, ok := errorClasses[code]
+	if !ok {
+		return errorClasses[ErrorCodeUnknown]
+	}
+	return class
+}
+
+// ClassifyError returns the classification of an error. Errors which were already classified by the deployers keep their
+// code. Other errors are classified by their type, and as a last resort get the generic retryable code Unknown.
+func ClassifyError(err error) *DeployError {
+	var deployError *DeployError
+	if errors.As(err, &deployError) {
+		return NewDeployError(deployError.Code, err)
+	}
+
+	var clusterUnreachableError *ClusterUnreachableError
+	if errors.As(err, &clusterUnreachableError) {
+		return NewDeployError(ErrorCodeClusterUnreachable, err)
+	}
+
+	return NewDeployError(classifyByType(err), err)
+}
+
+// classifyByType classifies the errors of the kubernetes api, e.g. of the target cluster.
+func classifyByType(err error) ErrorCode {
+	switch {
+	case apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err):
+		return ErrorCodeAPIConflict
+	case apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case apierrors.IsForbidden(err):
+		return ErrorCodeQuotaOrAdmission
+	default:
+		return ErrorCodeUnknown
+	}
+}
@@ -0,0 +1,73 @@
+package deployutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyError(t *testing.T) {
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	tests := []struct {
+		name string
+		err  error
+		code ErrorCode
+	}{
+		{"classified", NewDeployError(ErrorCodeChartAuth, errors.New("status code 401")), ErrorCodeChartAuth},
+		{"wrapped classified", pkgerrors.Wrap(NewDeployError(ErrorCodeRender, errors.New("x")), "y"), ErrorCodeRender},
+		{"cluster unreachable", &ClusterUnreachableError{Err: errors.New("dial tcp")}, ErrorCodeClusterUnreachable},
+		{"conflict", apierrors.NewConflict(gr, "test", errors.New("modified")), ErrorCodeAPIConflict},
+		{"timeout", context.DeadlineExceeded, ErrorCodeTimeout},
+		{"wrapped timeout", pkgerrors.Wrap(context.DeadlineExceeded, "install"), ErrorCodeTimeout},
+		{"quota", apierrors.NewForbidden(gr, "test", errors.New("exceeded quota: compute")), ErrorCodeQuotaOrAdmission},
+		{"unknown", errors.New("something else"), ErrorCodeUnknown},
+		{"message only", errors.New("admission webhook \"x\" denied the request"), ErrorCodeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployError := ClassifyError(tt.err)
+			assert.Equal(t, tt.code, deployError.Code)
+			assert.Equal(t, tt.err.Error(), deployError.Error())
+			assert.NotEmpty(t, deployError.Hint())
+		})
+	}
+}
+
+func TestSetFailedStatus(t *testing.T) {
+	deployData := DeployData{
+		deployItem:     &v1alpha1.DeployItem{},
+		ProviderStatus: &hubv1.HubDeployItemProviderStatus{},
+	}
+
+	assert.True(t, deployData.IsLastErrorRetryable())
+
+	time00 := createTimeFromString("220902 050316")
+	deployData.SetFailedStatus(NewDeployError(ErrorCodeChartFetch, errors.New("error00")), 1, time00)
+
+	lastErrorEntry := deployData.GetLastErrorEntry()
+	assert.Equal(t, "error00", lastErrorEntry.Description)
+	assert.Equal(t, string(ErrorCodeChartFetch), lastErrorEntry.Code)
+	assert.True(t, lastErrorEntry.Retryable)
+	assert.NotEmpty(t, lastErrorEntry.Hint)
+	assert.True(t, deployData.IsLastErrorRetryable())
+
+	time01 := createTimeFromString("220902 050317")
+	deployData.SetFailedStatus(NewDeployError(ErrorCodeRender, errors.New("error01")), 2, time01)
+
+	assert.Equal(t, 2, len(deployData.ProviderStatus.LastOperation.ErrorHistory.ErrorEntries))
+	lastErrorEntry = deployData.GetLastErrorEntry()
+	assert.Equal(t, "error01", lastErrorEntry.Description)
+	assert.Equal(t, string(ErrorCodeRender), lastErrorEntry.Code)
+	assert.False(t, lastErrorEntry.Retryable)
+	assert.False(t, deployData.IsLastErrorRetryable())
+}
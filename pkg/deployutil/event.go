@@ -18,6 +18,11 @@ const (
 	ReasonFailedDeployment         = "FailedDeployment"
 	ReasonFailedJob                = "FailedJob"
 	ReasonFailedWriteState         = "FailedWriteState"
+	ReasonRetriesExhausted         = "RetriesExhausted"
+	ReasonRetriesStopped           = "RetriesStopped"
+	ReasonResetRetries             = "ResetRetries"
+	ReasonRecoveredStuckRelease    = "RecoveredStuckRelease"
+	ReasonDeletionTimeout          = "DeletionTimeout"
 )
 
 type EventWriterKey struct{}
@@ -16,11 +16,15 @@ import (
 	"time"
 
 	appRepov1 "github.com/gardener/potter-controller/api/external/apprepository/v1alpha1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/metrics"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	"github.com/ghodss/yaml"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/repo"
 	v1 "k8s.io/api/core/v1"
@@ -70,7 +74,7 @@ func fetchRepoIndex(ctx context.Context, netClient HTTPClient, repoURL string) (
 
 	res, err := (netClient).Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "request failed")
+		return nil, deployutil.NewDeployError(deployutil.ErrorCodeChartFetch, errors.Wrap(err, "request failed"))
 	}
 	data, err := readResponseBody(ctx, res)
 	if err != nil {
@@ -151,7 +155,12 @@ func readResponseBody(ctx context.Context, res *http.Response) ([]byte, error) {
 	if res.StatusCode != http.StatusOK {
 		logger := ctx.Value(util.LoggerKey{}).(logr.Logger)
 
-		err := errors.New(fmt.Sprintf("chart download request failed with status code %v", res.StatusCode))
+		errorCode := deployutil.ErrorCodeChartFetch
+		if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+			errorCode = deployutil.ErrorCodeChartAuth
+		}
+		err := deployutil.NewDeployError(errorCode,
+			errors.New(fmt.Sprintf("chart download request failed with status code %v", res.StatusCode)))
 
 		if logger.V(util.LogLevelDebug).Enabled() {
 			body, bodyReadErr := ioutil.ReadAll(res.Body)
@@ -210,7 +219,7 @@ func fetchChart(ctx context.Context, netClient HTTPClient, chartURL string, load
 
 	res, err := (netClient).Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "request failed")
+		return nil, deployutil.NewDeployError(deployutil.ErrorCodeChartFetch, errors.Wrap(err, "request failed"))
 	}
 	data, err := readResponseBody(ctx, res)
 	if err != nil {
@@ -232,12 +241,20 @@ func storeIndexInCache(repoURL string, index *repo.IndexFile, sha string) {
 func LoadCatalogChart(ctx context.Context, apprepo *appRepov1.AppRepository, chartName, chartVersion string, chartReader ReadChart,
 	appRepoClient client.Client) ChartLoaderFunc {
 	return func() (c *chart.Chart, err error) {
-		netClient, err := InitNetClientForCatalogChart(ctx, apprepo, appRepoClient)
+		spanCtx, span := tracing.Start(ctx, "Helm.fetchChart",
+			attribute.String(tracing.AttributeChartSource, metrics.ChartSourceCatalog))
+		start := time.Now()
+		defer func() {
+			tracing.End(span, err)
+			metrics.ObserveChartFetch(metrics.ChartSourceCatalog, start, err)
+		}()
+
+		netClient, err := InitNetClientForCatalogChart(spanCtx, apprepo, appRepoClient)
 		if err != nil {
 			return nil, err
 		}
 
-		return GetChartForCatalogChart(ctx, netClient, apprepo.Spec.URL, chartName, chartVersion, chartReader)
+		return GetChartForCatalogChart(spanCtx, netClient, apprepo.Spec.URL, chartName, chartVersion, chartReader)
 	}
 }
 
@@ -355,13 +372,21 @@ func InitNetClientForCatalogChart(ctx context.Context, appRepo *appRepov1.AppRep
 }
 
 func LoadRawURL(ctx context.Context, customCAData, authHeader, chartURL string, chartReader ReadChart) ChartLoaderFunc {
-	return func() (*chart.Chart, error) {
+	return func() (c *chart.Chart, err error) {
+		spanCtx, span := tracing.Start(ctx, "Helm.fetchChart",
+			attribute.String(tracing.AttributeChartSource, metrics.ChartSourceTarball))
+		start := time.Now()
+		defer func() {
+			tracing.End(span, err)
+			metrics.ObserveChartFetch(metrics.ChartSourceTarball, start, err)
+		}()
+
 		netClient, err := InitNetClientForRawURL(customCAData, authHeader)
 		if err != nil {
 			return nil, err
 		}
 
-		return fetchChart(ctx, netClient, chartURL, chartReader)
+		return fetchChart(spanCtx, netClient, chartURL, chartReader)
 	}
 }
 
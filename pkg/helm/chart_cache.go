@@ -0,0 +1,117 @@
+package helm
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// ChartCache keeps loaded charts for some time, so that a chart is not downloaded again and again, e.g. by the
+// admission webhook which needs the values schema of a chart for every review of a clusterbom.
+type ChartCache struct {
+	mutex      sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*chartCacheEntry
+	now        func() time.Time
+	loads      singleflight.Group
+}
+
+type chartCacheEntry struct {
+	chart    *chart.Chart
+	loadedAt time.Time
+}
+
+func NewChartCache(ttl time.Duration, maxEntries int) *ChartCache {
+	return &ChartCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]*chartCacheEntry{},
+		now:        time.Now,
+	}
+}
+
+// Load returns the cached chart with the given key, or loads and caches it if it is not cached or expired. Concurrent
+// loads of the same key are combined, so that a chart is downloaded only once. Errors are not cached, so that a failed
+// download is repeated with the next call.
+func (c *ChartCache) Load(key string, load ChartLoaderFunc) (*chart.Chart, error) {
+	if ch := c.get(key); ch != nil {
+		return ch, nil
+	}
+
+	result, err, _ := c.loads.Do(key, func() (interface{}, error) {
+		// another load might have finished since the check above
+		if ch := c.get(key); ch != nil {
+			return ch, nil
+		}
+
+		ch, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		c.put(key, ch)
+		return ch, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*chart.Chart), nil
+}
+
+func (c *ChartCache) get(key string) *chart.Chart {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if c.isExpired(entry) {
+		delete(c.entries, key)
+		return nil
+	}
+
+	return entry.chart
+}
+
+func (c *ChartCache) put(key string, ch *chart.Chart) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+
+	c.entries[key] = &chartCacheEntry{
+		chart:    ch,
+		loadedAt: c.now(),
+	}
+}
+
+// evict removes the expired entries, or the oldest entry if none is expired
+func (c *ChartCache) evict() {
+	oldestKey := ""
+	var oldestLoadedAt time.Time
+
+	for key, entry := range c.entries {
+		if c.isExpired(entry) {
+			delete(c.entries, key)
+		} else if oldestKey == "" || entry.loadedAt.Before(oldestLoadedAt) {
+			oldestKey = key
+			oldestLoadedAt = entry.loadedAt
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
+
+func (c *ChartCache) isExpired(entry *chartCacheEntry) bool {
+	return c.now().Sub(entry.loadedAt) > c.ttl
+}
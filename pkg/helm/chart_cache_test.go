@@ -0,0 +1,78 @@
+package helm
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestChartCache(t *testing.T) {
+	now := time.Now()
+	cache := NewChartCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	loads := 0
+	loader := func(name string) ChartLoaderFunc {
+		return func() (*chart.Chart, error) {
+			loads++
+			return &chart.Chart{Metadata: &chart.Metadata{Name: name}}, nil
+		}
+	}
+
+	ch, err := cache.Load("a", loader("a"))
+	assert.NoErr(t, err)
+	assert.Equal(t, ch.Name(), "a", "chart name")
+
+	_, err = cache.Load("a", loader("a"))
+	assert.NoErr(t, err)
+	assert.Equal(t, loads, 1, "loads of cached chart")
+
+	// the oldest entry is evicted if the cache is full
+	now = now.Add(time.Second)
+	_, _ = cache.Load("b", loader("b"))
+	_, _ = cache.Load("c", loader("c"))
+	assert.Equal(t, loads, 3, "loads of new charts")
+	_, _ = cache.Load("a", loader("a"))
+	assert.Equal(t, loads, 4, "loads of evicted chart")
+
+	// expired entries are loaded again
+	now = now.Add(2 * time.Minute)
+	_, _ = cache.Load("a", loader("a"))
+	assert.Equal(t, loads, 5, "loads of expired chart")
+
+	// errors are not cached
+	_, err = cache.Load("d", func() (*chart.Chart, error) { return nil, errors.New("test") })
+	assert.True(t, err != nil, "error of failed load")
+	_, err = cache.Load("d", loader("d"))
+	assert.NoErr(t, err)
+}
+
+func TestChartCacheConcurrentLoads(t *testing.T) {
+	cache := NewChartCache(time.Minute, 2)
+
+	var loads int32
+	loader := func() (*chart.Chart, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(50 * time.Millisecond)
+		return &chart.Chart{Metadata: &chart.Metadata{Name: "a"}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch, err := cache.Load("a", loader)
+			assert.NoErr(t, err)
+			assert.Equal(t, ch.Name(), "a", "chart name")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(1), "loads of concurrently requested chart")
+}
@@ -2,18 +2,44 @@ package helm
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type Facade interface {
 	GetRelease(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string) (*release.Release, error)
 	InstallOrUpdate(context.Context, *ChartData, string, string, *ReleaseMetadata) (*release.Release, error)
 	Remove(context.Context, *ChartData, string, string) error
+	RecoverStuckRelease(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string) (*ReleaseRecovery, error)
+}
+
+const (
+	RecoveryActionRollback   = "rollback"
+	RecoveryActionMarkFailed = "mark-failed"
+)
+
+// ReleaseRecovery describes how a release, that was stuck in a pending state, has been recovered.
+type ReleaseRecovery struct {
+	ReleaseName  string
+	StuckStatus  release.Status
+	StuckVersion int
+	Action       string
+}
+
+func (r *ReleaseRecovery) String() string {
+	return fmt.Sprintf("release %s was stuck in state %s with revision %d and has been recovered by action %s",
+		r.ReleaseName, r.StuckStatus, r.StuckVersion, r.Action)
 }
 
 type FacadeImpl struct {
@@ -34,7 +60,9 @@ func (fi *FacadeImpl) GetRelease(ctx context.Context, chartData *ChartData, name
 func (fi *FacadeImpl) InstallOrUpdate(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string, metadata *ReleaseMetadata) (*release.Release, error) {
 	log := util.GetLoggerFromContext(ctx)
 
+	ctx, span := startReleaseSpan(ctx, "Helm.installOrUpdate", chartData, namespace)
 	rel, err := fi.installOrUpdateInternal(ctx, chartData, namespace, targetKubeconfig, metadata)
+	tracing.End(span, err)
 
 	if err != nil {
 		rel2, err2 := fi.Client.GetRelease(ctx, chartData.InstallName, namespace, targetKubeconfig)
@@ -42,7 +70,7 @@ func (fi *FacadeImpl) InstallOrUpdate(ctx context.Context, chartData *ChartData,
 			log.Error(err2, "Error fetching helm release")
 		}
 
-		return rel2, err
+		return rel2, classifyReleaseError(err)
 	}
 
 	return rel, nil
@@ -51,7 +79,7 @@ func (fi *FacadeImpl) InstallOrUpdate(ctx context.Context, chartData *ChartData,
 func (fi *FacadeImpl) installOrUpdateInternal(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string, metadata *ReleaseMetadata) (*release.Release, error) {
 	ch, err := chartData.Load()
 	if err != nil {
-		return nil, err
+		return nil, classifyChartLoadError(err)
 	}
 	_, err = fi.Client.GetRelease(ctx, chartData.InstallName, namespace, targetKubeconfig)
 	if err != nil && IsClusterUnreachableErr(err) {
@@ -65,9 +93,15 @@ func (fi *FacadeImpl) installOrUpdateInternal(ctx context.Context, chartData *Ch
 	}
 }
 
-func (fi *FacadeImpl) Remove(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string) error {
+func (fi *FacadeImpl) Remove(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string) (err error) {
 	log := ctx.Value(util.LoggerKey{}).(logr.Logger)
-	_, err := fi.Client.GetRelease(ctx, chartData.InstallName, namespace, targetKubeconfig)
+
+	ctx, span := startReleaseSpan(ctx, "Helm.remove", chartData, namespace)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	_, err = fi.Client.GetRelease(ctx, chartData.InstallName, namespace, targetKubeconfig)
 	if err != nil && IsClusterUnreachableErr(err) {
 		return &deployutil.ClusterUnreachableError{Err: err}
 	} else if err != nil && IsReleaseNotFoundErr(err) {
@@ -77,5 +111,110 @@ func (fi *FacadeImpl) Remove(ctx context.Context, chartData *ChartData, namespac
 		log.Error(err, "unknown release state")
 		return err
 	}
-	return fi.Client.DeleteRelease(ctx, chartData, chartData.InstallName, namespace, chartData.UninstallTimeout, false, targetKubeconfig)
+	return classifyReleaseError(fi.Client.DeleteRelease(ctx, chartData, chartData.InstallName, namespace, chartData.UninstallTimeout, false, targetKubeconfig))
+}
+
+// RecoverStuckRelease checks whether the release is stuck in a pending state for longer than the timeout of the
+// corresponding operation. Helm refuses further upgrades of such a release. A stuck upgrade or rollback is rolled back
+// to the previous revision, a stuck install is marked as failed. If the rollback is not possible, the release is marked
+// as failed as well. The caller must ensure that no other operation on the release is running, i.e. it must hold the
+// lock of the clusterbom. Returns nil if the release was not stuck.
+func (fi *FacadeImpl) RecoverStuckRelease(ctx context.Context, chartData *ChartData, namespace, targetKubeconfig string) (*ReleaseRecovery, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	rel, err := fi.Client.GetRelease(ctx, chartData.InstallName, namespace, targetKubeconfig)
+	if err != nil && IsClusterUnreachableErr(err) {
+		return nil, &deployutil.ClusterUnreachableError{Err: err}
+	} else if err != nil && IsReleaseNotFoundErr(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !IsStuckRelease(rel, chartData, time.Now()) {
+		return nil, nil
+	}
+
+	ctx, span := startReleaseSpan(ctx, "Helm.recoverStuckRelease", chartData, namespace)
+	defer span.End()
+
+	recovery := &ReleaseRecovery{
+		ReleaseName:  rel.Name,
+		StuckStatus:  rel.Info.Status,
+		StuckVersion: rel.Version,
+	}
+
+	log.V(util.LogLevelWarning).Info("recovering stuck release", "status", rel.Info.Status, "version", rel.Version)
+
+	if rel.Info.Status != release.StatusPendingInstall && rel.Version > 1 {
+		_, err = fi.Client.RollbackRelease(ctx, chartData.InstallName, namespace, chartData.UpgradeTimeout, 0, targetKubeconfig)
+		if err == nil {
+			recovery.Action = RecoveryActionRollback
+			return recovery, nil
+		}
+
+		log.V(util.LogLevelWarning).Info("rollback of stuck release failed, marking it as failed instead", "error", err.Error())
+	}
+
+	description := fmt.Sprintf("marked as failed by potter, because it was stuck in state %s", rel.Info.Status)
+	_, err = fi.Client.MarkReleaseFailed(ctx, chartData.InstallName, namespace, description, targetKubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	recovery.Action = RecoveryActionMarkFailed
+	return recovery, nil
+}
+
+// startReleaseSpan starts a span for an action on a helm release.
+func startReleaseSpan(ctx context.Context, spanName string, chartData *ChartData, namespace string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, spanName,
+		attribute.String(tracing.AttributeReleaseName, chartData.InstallName),
+		attribute.String(tracing.AttributeNamespace, namespace))
+}
+
+// IsStuckRelease returns whether the release has been in a pending state for longer than the timeout of the
+// corresponding operation.
+func IsStuckRelease(rel *release.Release, chartData *ChartData, now time.Time) bool {
+	if rel == nil || rel.Info == nil || !rel.Info.Status.IsPending() {
+		return false
+	}
+
+	timeout := chartData.UpgradeTimeout
+	if rel.Info.Status == release.StatusPendingInstall {
+		timeout = chartData.InstallTimeout
+	}
+
+	return now.Sub(rel.Info.LastDeployed.Time) > timeout
+}
+
+// classifyReleaseError classifies the errors of helm operations. A timeout while helm waits for the resources means that
+// they did not become ready. Helm has no error types for failed templates and pending operations, therefore these are
+// recognized by their messages.
+func classifyReleaseError(err error) error {
+	var deployError *deployutil.DeployError
+	var clusterUnreachableError *deployutil.ClusterUnreachableError
+
+	switch {
+	case err == nil || errors.As(err, &deployError) || errors.As(err, &clusterUnreachableError):
+		return err
+	case errors.Is(err, wait.ErrWaitTimeout):
+		return deployutil.NewDeployError(deployutil.ErrorCodeReadiness, err)
+	case IsOperationInProgressErr(err):
+		return deployutil.NewDeployError(deployutil.ErrorCodeAPIConflict, err)
+	case IsRenderErr(err):
+		return deployutil.NewDeployError(deployutil.ErrorCodeRender, err)
+	default:
+		return err
+	}
+}
+
+// Errors during chart loading are chart fetch errors, unless they were already classified more precisely, e.g. as
+// authentication errors.
+func classifyChartLoadError(err error) error {
+	var deployError *deployutil.DeployError
+	if errors.As(err, &deployError) {
+		return err
+	}
+	return deployutil.NewDeployError(deployutil.ErrorCodeChartFetch, err)
 }
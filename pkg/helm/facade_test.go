@@ -4,15 +4,20 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-logr/zapr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	pkgerrors "github.com/pkg/errors"
 	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"github.com/gardener/potter-controller/pkg/deployutil"
 	"github.com/gardener/potter-controller/pkg/util"
 )
 
@@ -148,6 +153,102 @@ func TestRemoveOfNonExistentRelease(t *testing.T) {
 	Expect(err).To(BeNil())
 }
 
+func TestRecoverStuckRelease(t *testing.T) {
+	RegisterFailHandler(Fail)
+	NewGomegaWithT(t)
+
+	ctx := context.TODO()
+	ctx = context.WithValue(ctx, util.LoggerKey{}, zapr.NewLogger(zap.NewNop()))
+
+	chartData := &ChartData{
+		InstallName:    dummyChart.InstallName,
+		InstallTimeout: 5 * time.Minute,
+		UpgradeTimeout: 5 * time.Minute,
+	}
+
+	longAgo := helmtime.Time{Time: time.Now().Add(-time.Hour)}
+	recently := helmtime.Time{Time: time.Now().Add(-time.Minute)}
+
+	tests := []struct {
+		name           string
+		status         release.Status
+		version        int
+		lastDeployed   helmtime.Time
+		expectedAction string
+		expectedStatus release.Status
+	}{
+		{"deployed", release.StatusDeployed, 2, longAgo, "", release.StatusDeployed},
+		{"pending upgrade within timeout", release.StatusPendingUpgrade, 2, recently, "", release.StatusPendingUpgrade},
+		{"stuck upgrade", release.StatusPendingUpgrade, 2, longAgo, RecoveryActionRollback, release.StatusDeployed},
+		{"stuck rollback", release.StatusPendingRollback, 3, longAgo, RecoveryActionRollback, release.StatusDeployed},
+		{"stuck install", release.StatusPendingInstall, 1, longAgo, RecoveryActionMarkFailed, release.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		fakeHelmClient = FakeHelmClient{Releases: []release.Release{
+			{
+				Name:    chartData.InstallName,
+				Version: tt.version,
+				Info:    &release.Info{Status: tt.status, LastDeployed: tt.lastDeployed},
+			},
+		}}
+		facade = FacadeImpl{Client: &fakeHelmClient}
+
+		recovery, err := facade.RecoverStuckRelease(ctx, chartData, namespace, "thisIsNoKubeconfigButItWorks")
+		Expect(err).To(BeNil(), tt.name)
+
+		if tt.expectedAction == "" {
+			Expect(recovery).To(BeNil(), tt.name)
+		} else {
+			Expect(recovery).NotTo(BeNil(), tt.name)
+			Expect(recovery.Action).To(Equal(tt.expectedAction), tt.name)
+			Expect(recovery.StuckStatus).To(Equal(tt.status), tt.name)
+			Expect(recovery.StuckVersion).To(Equal(tt.version), tt.name)
+		}
+
+		Expect(fakeHelmClient.Releases[0].Info.Status).To(Equal(tt.expectedStatus), tt.name)
+	}
+}
+
+func TestRecoverStuckReleaseOfNonExistentRelease(t *testing.T) {
+	RegisterFailHandler(Fail)
+	NewGomegaWithT(t)
+
+	fakeHelmClient = FakeHelmClient{}
+	facade = FacadeImpl{Client: &fakeHelmClient}
+
+	ctx := context.TODO()
+	ctx = context.WithValue(ctx, util.LoggerKey{}, zapr.NewLogger(zap.NewNop()))
+
+	recovery, err := facade.RecoverStuckRelease(ctx, dummyChart, namespace, "thisIsNoKubeconfigButItWorks")
+	Expect(err).To(BeNil())
+	Expect(recovery).To(BeNil())
+}
+
+func TestClassifyReleaseError(t *testing.T) {
+	RegisterFailHandler(Fail)
+	NewGomegaWithT(t)
+
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode deployutil.ErrorCode
+	}{
+		{"wait timeout", pkgerrors.Wrapf(wait.ErrWaitTimeout, "release test failed"), deployutil.ErrorCodeReadiness},
+		{"pending operation", errors.New("unable to update the release: another operation (install/upgrade/rollback) is in progress"), deployutil.ErrorCodeAPIConflict},
+		{"template", errors.New("unable to create the release: parse error at (test/templates/a.yaml:3): bad"), deployutil.ErrorCodeRender},
+		{"manifest", errors.New("unable to build kubernetes objects from release manifest: error validating data"), deployutil.ErrorCodeRender},
+		{"classified", deployutil.NewDeployError(deployutil.ErrorCodeChartAuth, errors.New("parse error")), deployutil.ErrorCodeChartAuth},
+		{"other", errors.New("something else"), deployutil.ErrorCodeUnknown},
+	}
+
+	for _, tt := range tests {
+		err := classifyReleaseError(tt.err)
+		Expect(err.Error()).To(Equal(tt.err.Error()), tt.name)
+		Expect(deployutil.ClassifyError(err).Code).To(Equal(tt.expectedCode), tt.name)
+	}
+}
+
 func checkForInstalledRelease(releases []release.Release, name string) (*release.Release, error) {
 	for index := range releases {
 		if releases[index].Name == name {
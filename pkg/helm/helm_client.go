@@ -310,6 +310,26 @@ func (p *clientImpl) RollbackRelease(ctx context.Context, name, namespace string
 	return p.getRelease(ctx, kubeconfig, name, namespace)
 }
 
+// MarkReleaseFailed sets the status of the last revision of a release to failed, without touching the deployed resources
+func (p *clientImpl) MarkReleaseFailed(ctx context.Context, name, namespace, description, kubeconfig string) (*release.Release, error) {
+	config, err := initActionConfig(ctx, kubeconfig, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := config.Releases.Last(name)
+	if err != nil {
+		return nil, errors.New(prettyError(err).Error())
+	}
+
+	rel.SetStatus(release.StatusFailed, description)
+
+	if err = config.Releases.Update(rel); err != nil {
+		return nil, errors.Wrapf(err, "unable to mark the release as failed")
+	}
+	return rel, nil
+}
+
 // GetRelease returns the info of a release
 func (p *clientImpl) GetRelease(ctx context.Context, name, namespace, kubeconfig string) (*release.Release, error) {
 	return p.getRelease(ctx, kubeconfig, name, namespace)
@@ -422,6 +442,7 @@ type Client interface {
 	CreateRelease(ctx context.Context, chartData *ChartData, name, namespace string, values map[string]interface{}, metadata *ReleaseMetadata, timeout time.Duration, ch *chart.Chart, kubeconfig string) (*release.Release, error)
 	UpdateRelease(ctx context.Context, chartData *ChartData, name, namespace string, values map[string]interface{}, metadata *ReleaseMetadata, timeout time.Duration, ch *chart.Chart, kubeconfig string) (*release.Release, error)
 	RollbackRelease(ctx context.Context, name, namespace string, timeout time.Duration, revision int32, kubeconfig string) (*release.Release, error)
+	MarkReleaseFailed(ctx context.Context, name, namespace, description, kubeconfig string) (*release.Release, error)
 	GetRelease(ctx context.Context, name, namespace, kubeconfig string) (*release.Release, error)
 	DeleteRelease(ctx context.Context, chartData *ChartData, name, namespace string, timeout time.Duration, keepHistory bool, kubeconfig string) error
 }
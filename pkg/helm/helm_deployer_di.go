@@ -61,7 +61,7 @@ func (r *helmDeployerDI) ProcessNewOperation(ctx context.Context, deployData *de
 			deployData.SetStatusForUnreachableCluster()
 		default:
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Deployment failed for application "+configID, err)
-			deployData.SetStatus(util.StateFailed, err.Error(), 1, now)
+			deployData.SetFailedStatus(err, 1, now)
 		}
 	} else {
 		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Deployment done for application "+configID)
@@ -88,7 +88,7 @@ func (r *helmDeployerDI) ReconcileOperation(ctx context.Context, deployData *dep
 			deployData.SetStatusForUnreachableCluster()
 		default:
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Reconcile failed for application "+configID, err)
-			deployData.SetStatus(util.StateFailed, err.Error(), 1, now)
+			deployData.SetFailedStatus(err, 1, now)
 		}
 	} else {
 		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Reconcile done for application "+configID)
@@ -113,7 +113,7 @@ func (r *helmDeployerDI) RetryFailedOperation(ctx context.Context, deployData *d
 		default:
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment,
 				"Retry of deployment failed for application "+configID, err)
-			deployData.SetStatus(util.StateFailed, err.Error(), lastOp.NumberOfTries+1, now)
+			deployData.SetFailedStatus(err, lastOp.NumberOfTries+1, now)
 		}
 	} else {
 		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Retry of deployment done for application "+configID)
@@ -126,7 +126,9 @@ func (r *helmDeployerDI) RetryFailedOperation(ctx context.Context, deployData *d
 func (r *helmDeployerDI) ProcessPendingOperation(ctx context.Context, deployData *deployutil.DeployData) {
 	configID := deployData.Configuration.DeploymentConfig.ID
 
-	rel, err := r.getRelease(ctx, deployData)
+	lastOp := deployData.ProviderStatus.LastOperation
+
+	rel, helmChartData, err := r.getRelease(ctx, deployData)
 	now := metav1.Now()
 	if err != nil {
 		switch err.(type) {
@@ -137,6 +139,18 @@ func (r *helmDeployerDI) ProcessPendingOperation(ctx context.Context, deployData
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Readiness check failed for application "+configID, err)
 		}
 		deployData.SetStatusForUnreachableCluster()
+	} else if IsStuckRelease(rel, helmChartData, now.Time) {
+		deployData.SetStatusForReachableCluster()
+
+		err = r.recoverStuckRelease(ctx, deployData)
+		var deployError *deployutil.DeployError
+		if errors.As(err, &deployError) {
+			deployData.SetFailedStatus(err, lastOp.NumberOfTries+1, now)
+		} else if err != nil {
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Recovery of stuck release failed for application "+configID, err)
+		}
+
+		rel, _, _ = r.getRelease(ctx, deployData)
 	} else {
 		deployData.SetStatusForReachableCluster()
 	}
@@ -193,11 +207,27 @@ func (r *helmDeployerDI) processItem(ctx context.Context, deployData *deployutil
 			return nil, err
 		}
 
+		if r.isLastErrorStuckRelease(deployData) {
+			// the release could be stuck again, e.g. if the controller was restarted during the retry
+			err = r.tryRecoverStuckRelease(ctx, deployData, helmChartData, namespace, string(targetKubeconfig))
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		metadata := ReleaseMetadata{
 			BomName: clusterBomKey.Name,
 		}
 
-		return r.helmFacade.InstallOrUpdate(ctx, helmChartData, namespace, string(targetKubeconfig), &metadata)
+		rel, err := r.helmFacade.InstallOrUpdate(ctx, helmChartData, namespace, string(targetKubeconfig), &metadata)
+		if err != nil && IsOperationInProgressErr(err) {
+			recoveryErr := r.tryRecoverStuckRelease(ctx, deployData, helmChartData, namespace, string(targetKubeconfig))
+			if recoveryErr != nil {
+				return rel, recoveryErr
+			}
+		}
+
+		return rel, err
 	} else { // nolint
 		reblockDuration := helmChartData.UninstallTimeout + time.Minute
 		clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
@@ -381,7 +411,7 @@ func (r *helmDeployerDI) computeReadinessOnTargetCluster(ctx context.Context, de
 	return deployData.ComputeReadiness(ctx, basicKubernetesObjects, targetClient, dynamicTargetClient, helmSpecificData.Namespace)
 }
 
-func (r *helmDeployerDI) getRelease(ctx context.Context, deployData *deployutil.DeployData) (*release.Release, error) {
+func (r *helmDeployerDI) getRelease(ctx context.Context, deployData *deployutil.DeployData) (*release.Release, *ChartData, error) {
 	log := util.GetLoggerFromContext(ctx)
 
 	namedInternalSecretNames := deployData.Configuration.DeploymentConfig.NamedInternalSecretNames
@@ -391,7 +421,7 @@ func (r *helmDeployerDI) getRelease(ctx context.Context, deployData *deployutil.
 	if err != nil {
 		msg := couldNotParse
 		log.Error(err, msg)
-		return nil, errors.Wrap(err, msg)
+		return nil, nil, errors.Wrap(err, msg)
 	}
 
 	helmChartData, namespace, err := ParseTypeSpecificData(ctx, namedSecretResolver, &deployData.Configuration.DeploymentConfig,
@@ -399,16 +429,86 @@ func (r *helmDeployerDI) getRelease(ctx context.Context, deployData *deployutil.
 	if err != nil {
 		msg := couldNotParse
 		log.Error(err, msg)
-		return nil, errors.Wrap(err, msg)
+		return nil, nil, errors.Wrap(err, msg)
 	}
 
 	secretKey := deployData.GetSecretKey()
 	targetKubeconfig, err := deployutil.GetTargetConfig(ctx, r.crAndSecretClient, *secretKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	rel, err := r.helmFacade.GetRelease(ctx, helmChartData, namespace, string(targetKubeconfig))
+	return rel, helmChartData, err
+}
+
+// recoverStuckRelease recovers the release of a deploy item if it is stuck in a pending state. The lock of the
+// clusterbom is acquired before, so that no running operation of another controller instance is interrupted.
+func (r *helmDeployerDI) recoverStuckRelease(ctx context.Context, deployData *deployutil.DeployData) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	namedInternalSecretNames := deployData.Configuration.DeploymentConfig.NamedInternalSecretNames
+	namedSecretResolver := apitypes.NewNamedSecretResolver(r.crAndSecretClient, deployData.GetNamespace(), namedInternalSecretNames)
+
+	helmSpecificData, err := apitypes.NewHelmSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		msg := couldNotParse
+		log.Error(err, msg)
+		return errors.Wrap(err, msg)
 	}
 
-	return r.helmFacade.GetRelease(ctx, helmChartData, namespace, string(targetKubeconfig))
+	helmChartData, namespace, err := ParseTypeSpecificData(ctx, namedSecretResolver, &deployData.Configuration.DeploymentConfig,
+		helmSpecificData, true, r.appRepoClient)
+	if err != nil {
+		msg := couldNotParse
+		log.Error(err, msg)
+		return errors.Wrap(err, msg)
+	}
+
+	secretKey := deployData.GetSecretKey()
+	targetKubeconfig, err := deployutil.GetTargetConfig(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		return err
+	}
+
+	reblockDuration := max(helmChartData.InstallTimeout, helmChartData.UpgradeTimeout) + time.Minute
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+	_, err = r.blockObject.Reblock(ctx, clusterBomKey, r.uncachedClient, reblockDuration, true)
+	if err != nil {
+		return err
+	}
+
+	return r.tryRecoverStuckRelease(ctx, deployData, helmChartData, namespace, string(targetKubeconfig))
+}
+
+// isLastErrorStuckRelease returns true if the last operation failed, because its release was stuck and recovered.
+func (r *helmDeployerDI) isLastErrorStuckRelease(deployData *deployutil.DeployData) bool {
+	lastErrorEntry := deployData.GetLastErrorEntry()
+	return deployData.ProviderStatus.LastOperation.State == util.StateFailed &&
+		lastErrorEntry != nil && lastErrorEntry.Code == string(deployutil.ErrorCodeStuckRelease)
+}
+
+// tryRecoverStuckRelease recovers the release if it is stuck in a pending state. If it was recovered, the
+// recovery is returned as error, so that the operation is retried. The caller must hold the lock of the clusterbom.
+func (r *helmDeployerDI) tryRecoverStuckRelease(ctx context.Context, deployData *deployutil.DeployData,
+	helmChartData *ChartData, namespace, targetKubeconfig string) error {
+	recovery, err := r.helmFacade.RecoverStuckRelease(ctx, helmChartData, namespace, targetKubeconfig)
+	if err != nil {
+		return err
+	} else if recovery != nil {
+		return r.recoveryError(ctx, deployData, recovery)
+	}
+
+	return nil
+}
+
+// recoveryError records the recovery of a stuck release as event and returns it as error, so that it becomes part of
+// the error history and the operation is retried.
+func (r *helmDeployerDI) recoveryError(ctx context.Context, deployData *deployutil.DeployData, recovery *ReleaseRecovery) error {
+	configID := deployData.Configuration.DeploymentConfig.ID
+	deployutil.LogApplicationFailure(ctx, deployutil.ReasonRecoveredStuckRelease,
+		"Recovered stuck release of application "+configID+": "+recovery.String())
+	return deployutil.NewDeployError(deployutil.ErrorCodeStuckRelease, errors.New(recovery.String()))
 }
 
 func (r *helmDeployerDI) mergeSecretValues(ctx context.Context, deployData *deployutil.DeployData, helmChartData *ChartData,
@@ -1,10 +1,34 @@
 package helm
 
 import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+
 	"github.com/arschles/assert"
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
-
-	"testing"
 )
 
 func TestMergeValues(t *testing.T) {
@@ -15,3 +39,256 @@ func TestMergeValues(t *testing.T) {
 	assert.Nil(t, err, "error")
 	assert.Equal(t, value, "yellow", "value")
 }
+
+func TestProcessPendingOperationRecoversStuckRelease(t *testing.T) {
+	chartURL, closeServer := newTestChartServer(t)
+	defer closeServer()
+
+	tests := []struct {
+		name            string
+		status          release.Status
+		version         int
+		expectedStatus  release.Status
+		expectedVersion int
+	}{
+		{"stuck install", release.StatusPendingInstall, 1, release.StatusFailed, 1},
+		{"stuck upgrade", release.StatusPendingUpgrade, 2, release.StatusDeployed, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helmClient := &FakeHelmClient{Releases: []release.Release{
+				{
+					Name:      "test-release",
+					Namespace: "test-target-ns",
+					Version:   tt.version,
+					Info: &release.Info{
+						Status:       tt.status,
+						LastDeployed: helmtime.Time{Time: time.Now().Add(-time.Hour)},
+					},
+				},
+			}}
+			deployer := newTestHelmDeployer(helmClient)
+			deployData := newTestHelmDeployData(t, chartURL)
+			ctx := newTestHelmContext()
+
+			// the readiness check recovers the release and records a retryable error
+			deployer.ProcessPendingOperation(ctx, deployData)
+
+			assert.Equal(t, helmClient.Releases[0].Info.Status, tt.expectedStatus, "release status after recovery")
+			assert.Equal(t, helmClient.Releases[0].Version, tt.expectedVersion, "release version after recovery")
+
+			lastOp := deployData.ProviderStatus.LastOperation
+			assert.Equal(t, lastOp.State, util.StateFailed, "state after recovery")
+			assert.Equal(t, lastOp.NumberOfTries, int32(2), "number of tries after recovery")
+			assert.NotNil(t, lastOp.ErrorHistory, "error history")
+			errorEntry := lastOp.ErrorHistory.ErrorEntries[len(lastOp.ErrorHistory.ErrorEntries)-1]
+			assert.Equal(t, errorEntry.Code, string(deployutil.ErrorCodeStuckRelease), "error code")
+			assert.True(t, errorEntry.Retryable, "retryable")
+
+			// the retry deploys the release again
+			deployer.RetryFailedOperation(ctx, deployData)
+
+			lastOp = deployData.ProviderStatus.LastOperation
+			assert.Equal(t, lastOp.State, util.StateOk, "state after retry")
+			assert.Equal(t, lastOp.SuccessGeneration, int64(1), "success generation after retry")
+		})
+	}
+}
+
+func TestRetryFailedOperationRecoversOnlyStuckReleases(t *testing.T) {
+	chartURL, closeServer := newTestChartServer(t)
+	defer closeServer()
+
+	tests := []struct {
+		name               string
+		status             release.Status
+		lastDeployed       time.Time
+		lastErrorCode      deployutil.ErrorCode
+		expectedRecoveries int
+		expectedState      string
+		expectedErrorCode  deployutil.ErrorCode
+		expectedStatus     release.Status
+	}{
+		{
+			name:               "deployed release",
+			status:             release.StatusDeployed,
+			lastDeployed:       time.Now().Add(-time.Hour),
+			lastErrorCode:      deployutil.ErrorCodeTimeout,
+			expectedRecoveries: 0,
+			expectedState:      util.StateOk,
+			expectedStatus:     release.StatusDeployed,
+		},
+		{
+			name:               "last error was a stuck release",
+			status:             release.StatusDeployed,
+			lastDeployed:       time.Now().Add(-time.Hour),
+			lastErrorCode:      deployutil.ErrorCodeStuckRelease,
+			expectedRecoveries: 1,
+			expectedState:      util.StateOk,
+			expectedStatus:     release.StatusDeployed,
+		},
+		{
+			name:               "stuck release",
+			status:             release.StatusPendingUpgrade,
+			lastDeployed:       time.Now().Add(-time.Hour),
+			lastErrorCode:      deployutil.ErrorCodeTimeout,
+			expectedRecoveries: 1,
+			expectedState:      util.StateFailed,
+			expectedErrorCode:  deployutil.ErrorCodeStuckRelease,
+			expectedStatus:     release.StatusDeployed,
+		},
+		{
+			name:               "running operation",
+			status:             release.StatusPendingUpgrade,
+			lastDeployed:       time.Now(),
+			lastErrorCode:      deployutil.ErrorCodeTimeout,
+			expectedRecoveries: 1,
+			expectedState:      util.StateFailed,
+			expectedErrorCode:  deployutil.ErrorCodeAPIConflict,
+			expectedStatus:     release.StatusPendingUpgrade,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helmClient := &FakeHelmClient{Releases: []release.Release{
+				{
+					Name:      "test-release",
+					Namespace: "test-target-ns",
+					Version:   2,
+					Info: &release.Info{
+						Status:       tt.status,
+						LastDeployed: helmtime.Time{Time: tt.lastDeployed},
+					},
+				},
+			}}
+			deployer := newTestHelmDeployer(helmClient)
+			facade := &recoveryCountingFacade{Facade: deployer.helmFacade}
+			deployer.helmFacade = facade
+
+			deployData := newTestHelmDeployData(t, chartURL)
+			lastErrorTime := metav1.NewTime(time.Now().Add(-time.Minute))
+			deployData.ProviderStatus.LastOperation.State = util.StateFailed
+			deployData.ProviderStatus.LastOperation.ErrorHistory = &hubv1.ErrorHistory{
+				ErrorEntries: []hubv1.ErrorEntry{
+					{Description: "failed", Code: string(tt.lastErrorCode), Time: lastErrorTime},
+				},
+			}
+
+			deployer.RetryFailedOperation(newTestHelmContext(), deployData)
+
+			assert.Equal(t, facade.recoveries, tt.expectedRecoveries, "number of recoveries")
+			assert.Equal(t, helmClient.Releases[0].Info.Status, tt.expectedStatus, "release status")
+
+			lastOp := deployData.ProviderStatus.LastOperation
+			assert.Equal(t, lastOp.State, tt.expectedState, "state")
+			if tt.expectedState == util.StateFailed {
+				errorEntry := deployData.GetLastErrorEntry()
+				assert.NotNil(t, errorEntry, "error entry")
+				assert.Equal(t, errorEntry.Code, string(tt.expectedErrorCode), "error code")
+			}
+		})
+	}
+}
+
+// recoveryCountingFacade counts the attempts to recover a stuck release
+type recoveryCountingFacade struct {
+	Facade
+	recoveries int
+}
+
+func (f *recoveryCountingFacade) RecoverStuckRelease(ctx context.Context, chartData *ChartData, namespace,
+	targetKubeconfig string) (*ReleaseRecovery, error) {
+	f.recoveries++
+	return f.Facade.RecoverStuckRelease(ctx, chartData, namespace, targetKubeconfig)
+}
+
+func newTestHelmDeployer(helmClient *FakeHelmClient) *helmDeployerDI {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	targetSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "target-secret", Namespace: "test-namespace"},
+		Data:       map[string][]byte{"kubeconfig": []byte("thisIsNoKubeconfigButItWorks")},
+	}
+	crAndSecretClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(targetSecret).Build()
+
+	return &helmDeployerDI{
+		crAndSecretClient: crAndSecretClient,
+		helmFacade:        &FacadeImpl{Client: helmClient},
+		blockObject:       synchronize.NewBlockObject(nil, true),
+	}
+}
+
+func newTestHelmDeployData(t *testing.T, chartURL string) *deployutil.DeployData {
+	rawTypeSpecificData, err := json.Marshal(&apitypes.HelmSpecificData{
+		InstallName:   "test-release",
+		Namespace:     "test-target-ns",
+		TarballAccess: &apitypes.TarballAccess{URL: chartURL},
+	})
+	assert.Nil(t, err, "error")
+
+	encodedConfig, err := json.Marshal(&hubv1.HubDeployItemConfiguration{
+		LocalSecretRef: "target-secret",
+		DeploymentConfig: hubv1.DeploymentConfig{
+			ID:               "app",
+			TypeSpecificData: runtime.RawExtension{Raw: rawTypeSpecificData},
+		},
+	})
+	assert.Nil(t, err, "error")
+
+	encodedProviderStatus, err := json.Marshal(&hubv1.HubDeployItemProviderStatus{
+		LastOperation: hubv1.LastOperation{
+			Operation:         util.OperationInstall,
+			State:             util.StateOk,
+			NumberOfTries:     1,
+			SuccessGeneration: 1,
+		},
+	})
+	assert.Nil(t, err, "error")
+
+	deployData, err := deployutil.NewDeployData(&v1alpha1.DeployItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-item",
+			Namespace:  "test-namespace",
+			Generation: 1,
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Configuration: &runtime.RawExtension{Raw: encodedConfig},
+		},
+		Status: v1alpha1.DeployItemStatus{
+			ObservedGeneration: 1,
+			ProviderStatus:     &runtime.RawExtension{Raw: encodedProviderStatus},
+		},
+	})
+	assert.Nil(t, err, "error")
+	return deployData
+}
+
+// newTestChartServer serves a chart archive and returns its URL
+func newTestChartServer(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "chart")
+	assert.NoErr(t, err)
+
+	chartPath, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test-chart", Version: "1.0.0"},
+	}, dir)
+	assert.NoErr(t, err)
+
+	chartArchive, err := ioutil.ReadFile(chartPath)
+	assert.NoErr(t, err)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(chartArchive)
+	}))
+
+	return testServer.URL + "/test-chart-1.0.0.tgz", func() {
+		testServer.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func newTestHelmContext() context.Context {
+	return context.WithValue(context.Background(), util.LoggerKey{}, zapr.NewLogger(zap.NewNop()))
+}
@@ -81,6 +81,9 @@ func (f *FakeHelmClient) CreateRelease(ctx context.Context, chartData *ChartData
 func (f *FakeHelmClient) UpdateRelease(ctx context.Context, chartData *ChartData, name, namespace string, values map[string]interface{}, metadata *ReleaseMetadata, timeout time.Duration, ch *chart.Chart, kubeconfig string) (*release.Release, error) {
 	for _, r := range f.Releases {
 		if r.Name == name {
+			if r.Info != nil && r.Info.Status.IsPending() {
+				return nil, fmt.Errorf("another operation (install/upgrade/rollback) is in progress")
+			}
 			return &r, nil
 		}
 	}
@@ -88,14 +91,31 @@ func (f *FakeHelmClient) UpdateRelease(ctx context.Context, chartData *ChartData
 }
 
 func (f *FakeHelmClient) RollbackRelease(ctx context.Context, name, namespace string, timeout time.Duration, revision int32, kubeconfig string) (*release.Release, error) {
-	for _, r := range f.Releases {
-		if r.Name == name {
-			return &r, nil
+	for i := range f.Releases {
+		if f.Releases[i].Name == name {
+			if f.Releases[i].Info != nil {
+				f.Releases[i].Version++
+				f.Releases[i].SetStatus(release.StatusDeployed, "Rollback")
+			}
+			return &f.Releases[i], nil
 		}
 	}
 	return nil, fmt.Errorf("release %s not found", name)
 }
 
+func (f *FakeHelmClient) MarkReleaseFailed(ctx context.Context, name, namespace, description, kubeconfig string) (*release.Release, error) {
+	for i := range f.Releases {
+		if f.Releases[i].Name == name {
+			if f.Releases[i].Info == nil {
+				f.Releases[i].Info = &release.Info{}
+			}
+			f.Releases[i].SetStatus(release.StatusFailed, description)
+			return &f.Releases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release: not found")
+}
+
 func (f *FakeHelmClient) GetRelease(ctx context.Context, name, namespace, kubeconfig string) (*release.Release, error) {
 	for _, r := range f.Releases {
 		if r.Name == name {
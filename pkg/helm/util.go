@@ -143,6 +143,24 @@ func IsClusterUnreachableErr(err error) bool {
 	return strings.Contains(strings.ToLower(err.Error()), "kubernetes cluster unreachable")
 }
 
+// IsRenderErr returns true if helm could not render the templates of a chart, or could not build the kubernetes objects
+// from the rendered manifests.
+func IsRenderErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, renderErr := range []string{"parse error", "execution error", "render error", "rendering template failed",
+		"unable to build kubernetes objects from release manifest"} {
+		if strings.Contains(msg, renderErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOperationInProgressErr returns true if helm refused an operation, because the release is in a pending state.
+func IsOperationInProgressErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "another operation (install/upgrade/rollback) is in progress")
+}
+
 func getApprepository(ctx context.Context, appRepoName string, appRepoClient client.Client) (*appRepov1.AppRepository, error) {
 	namespace := util.GetApprepoNamespace()
 
@@ -0,0 +1,122 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// the delimiter of the path of a json context, which does not occur in the keys of helm values
+const jsonContextDelimiter = "\x00"
+
+// SchemaViolation is a violation of the values.schema.json of a chart (or of one of its subcharts).
+type SchemaViolation struct {
+	// Path is the JSON pointer to the offending field of the values, e.g. /image/tag
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidateValuesAgainstSchema merges the values with the default values of the chart, in the same way as an install
+// or upgrade does, and validates the result against the values schema of the chart and its subcharts. It returns the
+// violations; an error is only returned if the validation could not be done.
+func ValidateValuesAgainstSchema(ch *chart.Chart, values map[string]interface{}) ([]SchemaViolation, error) {
+	mergedValues, err := chartutil.CoalesceValues(ch, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not merge values with the default values of the chart")
+	}
+
+	return validateAgainstSchema(ch, mergedValues, "")
+}
+
+func validateAgainstSchema(ch *chart.Chart, values map[string]interface{}, pathPrefix string) ([]SchemaViolation, error) {
+	violations := []SchemaViolation{}
+
+	if ch.Schema != nil {
+		chartViolations, err := validateAgainstSingleSchema(values, ch.Schema, pathPrefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not validate values against the schema of chart %s", ch.Name())
+		}
+		violations = append(violations, chartViolations...)
+	}
+
+	for _, subchart := range ch.Dependencies() {
+		subchartValues, ok := values[subchart.Name()].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		subchartViolations, err := validateAgainstSchema(subchart, subchartValues, pathPrefix+"/"+escapeJSONPointerToken(subchart.Name()))
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, subchartViolations...)
+	}
+
+	return violations, nil
+}
+
+func validateAgainstSingleSchema(values map[string]interface{}, schema []byte, pathPrefix string) ([]SchemaViolation, error) {
+	valuesYAML, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesJSON, err := yaml.YAMLToJSON(valuesYAML)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(valuesJSON, []byte("null")) {
+		valuesJSON = []byte("{}")
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewBytesLoader(valuesJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	violations := []SchemaViolation{}
+	for _, resultError := range result.Errors() {
+		violations = append(violations, SchemaViolation{
+			Path:    pathPrefix + toJSONPointer(resultError),
+			Message: resultError.Description(),
+		})
+	}
+
+	return violations, nil
+}
+
+// toJSONPointer converts the context of a validation error into a JSON pointer. For missing and unexpected properties
+// the pointer refers to the property itself, rather than to the object containing it.
+func toJSONPointer(resultError gojsonschema.ResultError) string {
+	tokens := strings.Split(resultError.Context().String(jsonContextDelimiter), jsonContextDelimiter)[1:]
+
+	switch resultError.Type() {
+	case "required", "additional_property_not_allowed":
+		if property, ok := resultError.Details()["property"]; ok {
+			tokens = append(tokens, fmt.Sprint(property))
+		}
+	}
+
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/")
+		pointer.WriteString(escapeJSONPointerToken(token))
+	}
+
+	return pointer.String()
+}
+
+func escapeJSONPointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
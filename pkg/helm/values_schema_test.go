@@ -0,0 +1,110 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const testValuesSchema = `{
+  "type": "object",
+  "required": ["image"],
+  "additionalProperties": false,
+  "properties": {
+    "replicaCount": {"type": "integer"},
+    "image": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string"}
+      }
+    },
+    "sub/chart": {"type": "object"}
+  }
+}`
+
+func TestValidateValuesAgainstSchema(t *testing.T) {
+	tests := []struct {
+		name               string
+		values             map[string]interface{}
+		expectedViolations []string
+	}{
+		{
+			name: "valid values",
+			values: map[string]interface{}{
+				"replicaCount": 3,
+				"image":        map[string]interface{}{"tag": "1.0.0"},
+			},
+			expectedViolations: []string{},
+		},
+		{
+			name: "wrong type",
+			values: map[string]interface{}{
+				"image": map[string]interface{}{"tag": 1},
+			},
+			expectedViolations: []string{"/image/tag"},
+		},
+		{
+			name: "unknown property",
+			values: map[string]interface{}{
+				"image":        map[string]interface{}{},
+				"replicacount": 3,
+			},
+			expectedViolations: []string{"/replicacount"},
+		},
+		{
+			name: "escaped property",
+			values: map[string]interface{}{
+				"image":     map[string]interface{}{},
+				"sub/chart": "test",
+			},
+			expectedViolations: []string{"/sub~1chart"},
+		},
+		{
+			name:               "missing property",
+			values:             map[string]interface{}{},
+			expectedViolations: []string{"/image"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &chart.Chart{
+				Metadata: &chart.Metadata{Name: "test"},
+				Values:   map[string]interface{}{"replicaCount": 1},
+				Schema:   []byte(testValuesSchema),
+			}
+
+			violations, err := ValidateValuesAgainstSchema(ch, tt.values)
+			assert.NoErr(t, err)
+
+			paths := []string{}
+			for _, violation := range violations {
+				paths = append(paths, violation.Path)
+			}
+			assert.Equal(t, paths, tt.expectedViolations, "paths of violations")
+		})
+	}
+}
+
+func TestValidateValuesAgainstSubchartSchema(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "sub"},
+		Values:   map[string]interface{}{"port": 80},
+		Schema:   []byte(`{"type": "object", "properties": {"port": {"type": "integer"}}}`),
+	}
+
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "test"},
+	}
+	ch.AddDependency(subchart)
+
+	values := map[string]interface{}{
+		"sub": map[string]interface{}{"port": "http"},
+	}
+
+	violations, err := ValidateValuesAgainstSchema(ch, values)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(violations), 1, "number of violations")
+	assert.Equal(t, violations[0].Path, "/sub/port", "path of violation")
+}
@@ -1,3 +1,23 @@
 package kapp
 
 const kubeconfigSecretKey = "kubeconfig"
+
+const (
+	// secretValuesKey is the key of the secret values in the values secret of a kapp app
+	secretValuesKey    = "secret-values.yaml"
+	valuesSecretSuffix = "-secret-values"
+)
+
+const (
+	removeSuccessfulDescription   = "remove successful"
+	deletionInProgressDescription = "deletion of kapp app in progress"
+	deletionTimeoutDescription    = "kapp app not removed within deletion timeout; its finalizers were removed"
+)
+
+const (
+	// envDeletionTimeoutMinutes is the environment variable with the default time after which the finalizers of a kapp
+	// app are removed, if kapp-controller has not yet deleted it; it can be overwritten per application in the kapp
+	// specific data
+	envDeletionTimeoutMinutes     = "KAPP_DELETION_TIMEOUT_MINUTES"
+	defaultDeletionTimeoutMinutes = 30
+)
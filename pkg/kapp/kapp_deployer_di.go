@@ -10,9 +10,11 @@ import (
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/deployutil"
 	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/tracing"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	landscaper "github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	"github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -30,22 +32,31 @@ type kappDeployerDI struct {
 	uncachedClient           synchronize.UncachedClient
 	blockObject              *synchronize.BlockObject
 	reconcileIntervalMinutes int64
+	defaultDeletionTimeout   time.Duration
 }
 
 func NewKappDeployerDI(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
-	blockObject *synchronize.BlockObject, reconcileIntervalMinutes int64) deployutil.DeployItemDeployer {
+	blockObject *synchronize.BlockObject, reconcileIntervalMinutes int64, defaultDeletionTimeout time.Duration) deployutil.DeployItemDeployer {
 	return &kappDeployerDI{
 		crAndSecretClient:        crAndSecretClient,
 		uncachedClient:           uncachedClient,
 		blockObject:              blockObject,
 		reconcileIntervalMinutes: reconcileIntervalMinutes,
+		defaultDeletionTimeout:   defaultDeletionTimeout,
 	}
 }
 
+// GetDefaultDeletionTimeout returns the deletion timeout for kapp apps without own deletion timeout. It is read from the
+// environment variable KAPP_DELETION_TIMEOUT_MINUTES.
+func GetDefaultDeletionTimeout(log logr.Logger) time.Duration {
+	minutes := util.GetEnvInteger(envDeletionTimeoutMinutes, defaultDeletionTimeoutMinutes, log)
+	return time.Duration(minutes) * time.Minute
+}
+
 func (r *kappDeployerDI) ProcessNewOperation(ctx context.Context, deployData *deployutil.DeployData) {
 	configID := deployData.Configuration.DeploymentConfig.ID
 
-	err := r.processItem(ctx, deployData)
+	description, err := r.processItem(ctx, deployData)
 
 	now := metav1.Now()
 	if err != nil {
@@ -54,15 +65,17 @@ func (r *kappDeployerDI) ProcessNewOperation(ctx context.Context, deployData *de
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
 				"Deployment failed for application "+configID+", because cluster is unreachable", err)
 			deployData.SetStatusForUnreachableCluster()
+		case *deployutil.DeletionInProgressError:
+			deployData.SetStatus(util.StatePending, err.Error(), 1, now)
 		default:
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment,
 				"Deployment failed for application "+configID, err)
-			deployData.SetStatus(util.StateFailed, err.Error(), 1, now)
+			deployData.SetFailedStatus(err, 1, now)
 		}
 	} else {
 		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment,
 			"Deployment done for application "+configID)
-		deployData.SetStatus(util.StateOk, r.successDescription(deployData), 1, now)
+		deployData.SetStatus(util.StateOk, description, 1, now)
 	}
 
 	r.computeReadinessAndExport(ctx, deployData, now)
@@ -74,7 +87,7 @@ func (r *kappDeployerDI) ReconcileOperation(ctx context.Context, deployData *dep
 	log.V(util.LogLevelDebug).Info("reconcile", "observedGeneration", deployData.GetObservedGeneration(),
 		"generation", deployData.GetGeneration())
 
-	err := r.processItem(ctx, deployData)
+	description, err := r.processItem(ctx, deployData)
 	now := metav1.Now()
 	if err != nil {
 		switch err.(type) {
@@ -82,15 +95,17 @@ func (r *kappDeployerDI) ReconcileOperation(ctx context.Context, deployData *dep
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
 				"Reconcile failed for application "+configID+", because cluster is unreachable", err)
 			deployData.SetStatusForUnreachableCluster()
+		case *deployutil.DeletionInProgressError:
+			deployData.SetStatus(util.StatePending, err.Error(), 1, now)
 		default:
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Reconcile failed for application "+configID, err)
 
-			deployData.SetStatus(util.StateFailed, err.Error(), 1, now)
+			deployData.SetFailedStatus(err, 1, now)
 		}
 	} else {
 		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Reconcile done for application "+configID)
 
-		deployData.SetStatus(util.StateOk, r.successDescription(deployData), 1, now)
+		deployData.SetStatus(util.StateOk, description, 1, now)
 	}
 
 	r.computeReadinessAndExport(ctx, deployData, metav1.Now())
@@ -100,7 +115,7 @@ func (r *kappDeployerDI) RetryFailedOperation(ctx context.Context, deployData *d
 	configID := deployData.Configuration.DeploymentConfig.ID
 	lastOp := deployData.ProviderStatus.LastOperation
 
-	err := r.processItem(ctx, deployData)
+	description, err := r.processItem(ctx, deployData)
 	now := metav1.Now()
 	if err != nil {
 		switch err.(type) {
@@ -108,15 +123,16 @@ func (r *kappDeployerDI) RetryFailedOperation(ctx context.Context, deployData *d
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
 				"Retry of deployment failed for application "+configID+", because cluster is unreachable", err)
 			deployData.SetStatusForUnreachableCluster()
+		case *deployutil.DeletionInProgressError:
+			deployData.SetStatus(util.StatePending, err.Error(), 1, now)
 		default:
 			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment,
 				"Retry of deployment failed for application "+configID, err)
-			deployData.SetStatus(util.StateFailed, err.Error(), lastOp.NumberOfTries+1, now)
+			deployData.SetFailedStatus(err, lastOp.NumberOfTries+1, now)
 		}
 	} else {
 		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Retry of deployment done for application "+configID)
-		deployData.SetStatus(util.StateOk, r.successDescription(deployData),
-			1, now)
+		deployData.SetStatus(util.StateOk, description, 1, now)
 	}
 
 	r.computeReadinessAndExport(ctx, deployData, now)
@@ -125,6 +141,11 @@ func (r *kappDeployerDI) RetryFailedOperation(ctx context.Context, deployData *d
 func (r *kappDeployerDI) ProcessPendingOperation(ctx context.Context, deployData *deployutil.DeployData) {
 	configID := deployData.Configuration.DeploymentConfig.ID
 
+	if deployData.IsDeletionInProgress() {
+		r.processPendingDeletion(ctx, deployData)
+		return
+	}
+
 	// check reachability of target cluster
 	secretKey := deployData.GetSecretKey()
 	_, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *secretKey)
@@ -144,7 +165,30 @@ func (r *kappDeployerDI) ProcessPendingOperation(ctx context.Context, deployData
 	r.computeReadinessAndExport(ctx, deployData, metav1.Now())
 }
 
-func (r *kappDeployerDI) processItem(ctx context.Context, deployData *deployutil.DeployData) error {
+// processPendingDeletion checks whether kapp-controller has deleted the app, and removes the finalizers of the app if
+// the deletion timeout is exceeded.
+func (r *kappDeployerDI) processPendingDeletion(ctx context.Context, deployData *deployutil.DeployData) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	description, err := r.processItem(ctx, deployData)
+	now := metav1.Now()
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.DeletionInProgressError:
+			deployData.SetStatus(util.StatePending, err.Error(), 1, now)
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Removal failed for application "+configID, err)
+			deployData.SetFailedStatus(err, 1, now)
+		}
+	} else {
+		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Removal done for application "+configID)
+		deployData.SetStatus(util.StateOk, description, 1, now)
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, now)
+}
+
+func (r *kappDeployerDI) processItem(ctx context.Context, deployData *deployutil.DeployData) (string, error) {
 	log := util.GetLoggerFromContext(ctx)
 
 	appKey := r.getAppKey(deployData)
@@ -154,18 +198,18 @@ func (r *kappDeployerDI) processItem(ctx context.Context, deployData *deployutil
 	isRemoveOperation := deployData.IsDeleteOperation()
 
 	if isRemoveOperation {
-		return r.Cleanup(ctx, deployData, true)
+		return r.remove(ctx, deployData)
 	} else { // nolint
 		rawAppSpec := deployData.Configuration.DeploymentConfig.TypeSpecificData.Raw
 		rawAppSpec, err := r.replaceSecretNames(ctx, rawAppSpec, deployData.Configuration.DeploymentConfig.NamedInternalSecretNames)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		kappSpecificData, err := apitypes.NewKappSpecificData(rawAppSpec)
 		if err != nil {
 			log.Error(err, "error unmarshaling kapp specific data")
-			return err
+			return "", err
 		}
 
 		if kappSpecificData.AppSpec.Cluster == nil {
@@ -191,14 +235,14 @@ func (r *kappDeployerDI) processItem(ctx context.Context, deployData *deployutil
 		if kappSpecificData.AppSpec.Cluster.KubeconfigSecretRef.Name != deployData.Configuration.LocalSecretRef {
 			err = errors.New("target cluster of kapp app differs from localSecretRef")
 			log.V(util.LogLevelWarning).Info(err.Error(), util.LogKeyKappAppNamespacedName, appKey)
-			return err
+			return "", err
 		}
 
 		if kappSpecificData.AppSpec.Cluster.KubeconfigSecretRef.Key != kubeconfigSecretKey {
 			err = errors.New("the value of field cluster.kubeconfigSecretRef.key must be kubeconfig")
 			log.V(util.LogLevelWarning).Info(err.Error(), util.LogKeyKappAppNamespacedName, appKey, "kubeconfigKey",
 				kappSpecificData.AppSpec.Cluster.KubeconfigSecretRef.Key)
-			return err
+			return "", err
 		}
 
 		if kappSpecificData.AppSpec.SyncPeriod == nil {
@@ -209,10 +253,24 @@ func (r *kappDeployerDI) processItem(ctx context.Context, deployData *deployutil
 
 		err = r.createStateNamespace(ctx, deployData, kappSpecificData.AppSpec, appKey)
 		if err != nil {
-			return err
+			return "", err
+		}
+
+		valuesSecretName, err := r.applySecretValues(ctx, deployData, kappSpecificData.AppSpec)
+		if err != nil {
+			return "", err
+		}
+
+		if err = r.installOrUpdate(ctx, deployData, kappSpecificData.AppSpec); err != nil {
+			return "", err
+		}
+
+		// the values secrets of earlier secret values are only deleted once the app refers to the current one
+		if err = r.deleteValuesSecrets(ctx, deployData, valuesSecretName); err != nil {
+			return "", err
 		}
 
-		return r.installOrUpdate(ctx, deployData, kappSpecificData.AppSpec)
+		return "install successful", nil
 	}
 }
 
@@ -259,13 +317,18 @@ func (r *kappDeployerDI) createStateNamespace(ctx context.Context, deployData *d
 	return nil
 }
 
-func (r *kappDeployerDI) installOrUpdate(ctx context.Context, deployData *deployutil.DeployData, appSpec *v1alpha1.AppSpec) error {
+func (r *kappDeployerDI) installOrUpdate(ctx context.Context, deployData *deployutil.DeployData, appSpec *v1alpha1.AppSpec) (err error) {
 	log := util.GetLoggerFromContext(ctx)
 
+	ctx, span := tracing.Start(ctx, "Kapp.installOrUpdate")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	appKey := r.getAppKey(deployData)
 
 	app := v1alpha1.App{}
-	err := r.crAndSecretClient.Get(ctx, *appKey, &app)
+	err = r.crAndSecretClient.Get(ctx, *appKey, &app)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			app = v1alpha1.App{
@@ -275,6 +338,7 @@ func (r *kappDeployerDI) installOrUpdate(ctx context.Context, deployData *deploy
 				},
 				Spec: *appSpec,
 			}
+			tracing.InjectIntoAnnotations(ctx, &app)
 
 			err = r.crAndSecretClient.Create(ctx, &app)
 			if err != nil {
@@ -290,6 +354,7 @@ func (r *kappDeployerDI) installOrUpdate(ctx context.Context, deployData *deploy
 	}
 
 	app.Spec = *appSpec
+	tracing.InjectIntoAnnotations(ctx, &app)
 	err = r.crAndSecretClient.Update(ctx, &app)
 	if err != nil {
 		log.Error(err, "error updating kapp app", util.LogKeyKappAppNamespacedName, appKey)
@@ -299,74 +364,149 @@ func (r *kappDeployerDI) installOrUpdate(ctx context.Context, deployData *deploy
 	return nil
 }
 
+// Cleanup triggers the deletion of the kapp app without waiting for it. If the target cluster does not exist anymore,
+// kapp-controller cannot delete the resources of the app. In this case the finalizers of the app are removed, so that
+// the app and its values secret are deleted immediately.
 func (r *kappDeployerDI) Cleanup(ctx context.Context, deployData *deployutil.DeployData, clusterExist bool) error {
+	if clusterExist {
+		_, err := r.remove(ctx, deployData)
+		return err
+	}
+
 	log := util.GetLoggerFromContext(ctx)
 
 	appKey := r.getAppKey(deployData)
 
-	app := v1alpha1.App{}
-	err := r.crAndSecretClient.Get(ctx, *appKey, &app)
+	app := &v1alpha1.App{}
+	err := r.crAndSecretClient.Get(ctx, *appKey, app)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return nil
+			return r.deleteValuesSecrets(ctx, deployData, "")
 		}
 
 		log.Error(err, "error fetching kapp app for deletion", util.LogKeyKappAppNamespacedName, appKey)
 		return err
 	}
 
-	if !clusterExist {
-		app.SetFinalizers([]string{})
-
-		err = r.crAndSecretClient.Update(ctx, &app)
-		if err != nil {
-			if !util.IsConcurrentModificationErr(err) {
-				log.Error(err, "error removing finalizers from kapp app", util.LogKeyKappAppNamespacedName, appKey)
-			}
+	if err = r.removeAppFinalizers(ctx, app); err != nil {
+		return err
+	}
 
+	if app.ObjectMeta.DeletionTimestamp == nil {
+		err = r.crAndSecretClient.Delete(ctx, app)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "error deleting kapp app", util.LogKeyKappAppNamespacedName, appKey)
 			return err
 		}
+	}
 
-		app = v1alpha1.App{}
-		err = r.crAndSecretClient.Get(ctx, *appKey, &app)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				return nil
-			}
+	return r.deleteValuesSecrets(ctx, deployData, "")
+}
 
-			log.Error(err, "error fetching kapp app for deletion", util.LogKeyKappAppNamespacedName, appKey)
-			return err
+// remove triggers the deletion of the kapp app without waiting for it. As long as the app exists, a
+// DeletionInProgressError is returned, and the deploy item is processed again when the app changes. The values secret
+// is deleted together with the app. If kapp-controller has not removed the app within the deletion timeout, the
+// finalizers of the app are removed, so that the removal of the deploy item is not blocked forever. Resources of the
+// app might then remain on the target cluster, which is shown by the reason DeletionTimeout of the ready condition.
+func (r *kappDeployerDI) remove(ctx context.Context, deployData *deployutil.DeployData) (string, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	appKey := r.getAppKey(deployData)
+
+	app := &v1alpha1.App{}
+	err := r.crAndSecretClient.Get(ctx, *appKey, app)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return removeSuccessfulDescription, r.deleteValuesSecrets(ctx, deployData, "")
 		}
+
+		log.Error(err, "error fetching kapp app for deletion", util.LogKeyKappAppNamespacedName, appKey)
+		return "", err
 	}
 
 	if app.ObjectMeta.DeletionTimestamp == nil {
-		err = r.crAndSecretClient.Delete(ctx, &app)
+		err = r.crAndSecretClient.Delete(ctx, app)
 		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return removeSuccessfulDescription, r.deleteValuesSecrets(ctx, deployData, "")
+			}
+
 			log.Error(err, "error deleting kapp app", util.LogKeyKappAppNamespacedName, appKey)
-			return err
+			return "", err
 		}
+
+		return "", &deployutil.DeletionInProgressError{Message: deletionInProgressDescription}
 	}
 
-	for i := 0; i < 5; i++ {
-		// wait and see if deleted
-		time.Sleep(3 * time.Second)
+	deletionTimeout := r.getDeletionTimeout(ctx, deployData)
+	if time.Since(app.ObjectMeta.DeletionTimestamp.Time) < deletionTimeout {
+		return "", &deployutil.DeletionInProgressError{Message: deletionInProgressDescription}
+	}
 
-		err = r.crAndSecretClient.Get(ctx, *appKey, &app)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				return nil
-			}
+	log.V(util.LogLevelWarning).Info("kapp app not removed within deletion timeout, removing its finalizers",
+		util.LogKeyKappAppNamespacedName, appKey, "deletionTimeout", deletionTimeout.String())
 
-			log.Error(err, "error fetching kapp app for deletion", util.LogKeyKappAppNamespacedName, appKey)
-			return err
+	if err = r.removeAppFinalizers(ctx, app); err != nil {
+		return "", err
+	}
+
+	if err = r.deleteValuesSecrets(ctx, deployData, ""); err != nil {
+		return "", err
+	}
+
+	deployutil.LogApplicationFailure(ctx, deployutil.ReasonDeletionTimeout,
+		"Finalizers of kapp app removed after deletion timeout of "+deletionTimeout.String()+" for application "+
+			deployData.GetConfigID())
+
+	deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionTrue, metav1.Now(), hubv1.ReasonDeletionTimeout,
+		"Removed after deletion timeout; resources might remain on the target cluster")
+
+	return deletionTimeoutDescription, nil
+}
+
+func (r *kappDeployerDI) removeAppFinalizers(ctx context.Context, app *v1alpha1.App) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	if len(app.GetFinalizers()) == 0 {
+		return nil
+	}
+
+	app.SetFinalizers([]string{})
+
+	err := r.crAndSecretClient.Update(ctx, app)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		if !util.IsConcurrentModificationErr(err) {
+			log.Error(err, "error removing finalizers from kapp app", util.LogKeyKappAppNamespacedName, util.GetKey(app))
 		}
+
+		return err
 	}
 
-	err = errors.New("kapp controller app resource not removed within sleep period")
-	log.Error(err, "could not remove kapp controller app", util.LogKeyKappAppNamespacedName, appKey)
-	return err
+	return nil
 }
 
+// getDeletionTimeout returns the deletion timeout of the application. If the kapp specific data cannot be parsed, the
+// default deletion timeout is returned.
+func (r *kappDeployerDI) getDeletionTimeout(ctx context.Context, deployData *deployutil.DeployData) time.Duration {
+	log := util.GetLoggerFromContext(ctx)
+
+	kappSpecificData, err := apitypes.NewKappSpecificData(deployData.Configuration.DeploymentConfig.TypeSpecificData.Raw)
+	if err != nil {
+		log.Error(err, couldNotParse)
+		return r.defaultDeletionTimeout
+	}
+
+	return kappSpecificData.GetDeletionTimeout(r.defaultDeletionTimeout)
+}
+
+// Preprocess implements a circuit breaker for kapp apps: if an app has problems for longer than the problem threshold
+// of its circuit breaker policy, its reconciliation is paused for the pause duration. Afterwards the app is reconciled
+// again, and paused again if the problems persist. A change of the application config, the resume annotation or a reset
+// of its retries resumes a paused app immediately.
 func (r *kappDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.DeployData) {
 	log := util.GetLoggerFromContext(ctx)
 
@@ -391,6 +531,13 @@ func (r *kappDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.
 		return
 	}
 
+	policy := r.getCircuitBreakerPolicy(ctx, deployData)
+
+	if policy.IsDisabled() || deployData.IsNewOperation() || deployData.IsResume() || deployData.IsResetRetries() {
+		r.updateAppPausedStatus(ctx, app, oldPauseStatus, &PauseStatus{})
+		return
+	}
+
 	if app.GetGeneration() != app.Status.ObservedGeneration && !oldPauseStatus.Paused {
 		// no need to store something
 		return
@@ -417,7 +564,7 @@ func (r *kappDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.
 	// oldPauseStatus.Problem is now true
 
 	if !oldPauseStatus.Paused {
-		if oldPauseStatus.ProblemSince.Add(15 * time.Minute).After(time.Now()) {
+		if oldPauseStatus.ProblemSince.Add(policy.GetProblemThreshold()).After(time.Now()) {
 			newPauseStatus := PauseStatus{
 				Paused:       false,
 				PausedSince:  time.Time{},
@@ -428,6 +575,9 @@ func (r *kappDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.
 			return
 		}
 
+		log.V(util.LogLevelWarning).Info("pausing kapp app, because it has problems since " +
+			oldPauseStatus.ProblemSince.Format(time.RFC3339))
+
 		newPauseStatus := PauseStatus{
 			Paused:       true,
 			PausedSince:  time.Now(),
@@ -441,7 +591,7 @@ func (r *kappDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.
 
 	// oldPauseStatus.Paused is now true
 
-	if oldPauseStatus.PausedSince.Add(5 * time.Minute).After(time.Now()) {
+	if oldPauseStatus.PausedSince.Add(policy.GetPauseDuration()).After(time.Now()) {
 		return
 	}
 
@@ -455,6 +605,41 @@ func (r *kappDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.
 	r.updateAppPausedStatus(ctx, app, oldPauseStatus, &newPauseStatus)
 }
 
+// getCircuitBreakerPolicy returns the circuit breaker policy of the application. If the kapp specific data cannot be
+// parsed, nil is returned, which stands for the default policy.
+func (r *kappDeployerDI) getCircuitBreakerPolicy(ctx context.Context, deployData *deployutil.DeployData) *apitypes.CircuitBreakerPolicy {
+	log := util.GetLoggerFromContext(ctx)
+
+	kappSpecificData, err := apitypes.NewKappSpecificData(deployData.Configuration.DeploymentConfig.TypeSpecificData.Raw)
+	if err != nil {
+		log.Error(err, couldNotParse)
+		return nil
+	}
+
+	return kappSpecificData.CircuitBreaker
+}
+
+// setPausedCondition reports whether the circuit breaker has paused the reconciliation of the app. The condition is
+// only added when an app is paused for the first time. A paused app is resumed with the annotation
+// potter.gardener.cloud/resume: resume on its clusterbom, which is propagated to the deploy item (see Preprocess).
+func (r *kappDeployerDI) setPausedCondition(ctx context.Context, deployData *deployutil.DeployData, app *v1alpha1.App,
+	now metav1.Time) {
+	pauseStatus, err := GetOldOrInitialPauseStatus(ctx, app)
+	if err != nil {
+		return
+	}
+
+	if pauseStatus.Paused {
+		message := "Reconciliation paused by circuit breaker since " + pauseStatus.PausedSince.Format(time.RFC3339) +
+			", because the app has problems since " + pauseStatus.ProblemSince.Format(time.RFC3339)
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentPaused, corev1.ConditionTrue, now,
+			hubv1.ReasonCircuitBreakerOpen, message)
+	} else if deployData.GetDeployItemCondition(hubv1.HubDeploymentPaused) != nil {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentPaused, corev1.ConditionFalse, now,
+			hubv1.ReasonCircuitBreakerClosed, "Reconciliation not paused")
+	}
+}
+
 func (r *kappDeployerDI) updateAppPausedStatus(ctx context.Context, app *v1alpha1.App, oldStatus, newStatus *PauseStatus) {
 	if !reflect.DeepEqual(oldStatus, newStatus) {
 		log := util.GetLoggerFromContext(ctx)
@@ -538,6 +723,9 @@ func (r *kappDeployerDI) computeExports(ctx context.Context, deployData *deployu
 func (r *kappDeployerDI) computeReadiness(ctx context.Context, deployData *deployutil.DeployData, now metav1.Time) {
 	log := util.GetLoggerFromContext(ctx)
 
+	ctx, span := tracing.Start(ctx, "computeReadiness")
+	defer span.End()
+
 	appKey := r.getAppKey(deployData)
 	app := &v1alpha1.App{}
 
@@ -578,6 +766,7 @@ func (r *kappDeployerDI) computeReadiness(ctx context.Context, deployData *deplo
 		}
 
 		r.setTypeSpecificStatus(ctx, app, deployData)
+		r.setPausedCondition(ctx, deployData, app, now)
 	case util.OperationRemove:
 		err := r.crAndSecretClient.Get(ctx, *appKey, app)
 		if err != nil {
@@ -627,9 +816,16 @@ func (r *kappDeployerDI) computeReadiness(ctx context.Context, deployData *deplo
 			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending, "Remove pending")
 			deployData.SetPhase(landscaper.ExecutionPhaseProgressing)
 		} else if deployData.ProviderStatus.LastOperation.Operation == util.OperationRemove {
-			if deployData.ProviderStatus.LastOperation.State == util.StateOk {
+			if deployData.ProviderStatus.LastOperation.State == util.StateOk && isRemovedAfterDeletionTimeout(deployData) {
+				// the ready condition was set when the finalizers of the app were removed
+				deployData.SetPhase(landscaper.ExecutionPhaseSucceeded)
+			} else if deployData.ProviderStatus.LastOperation.State == util.StateOk {
 				deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionTrue, now, hubv1.ReasonRemoved, "Removed")
 				deployData.SetPhase(landscaper.ExecutionPhaseSucceeded)
+			} else if deployData.ProviderStatus.LastOperation.State == util.StatePending {
+				deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending,
+					"Deletion in progress")
+				deployData.SetPhase(landscaper.ExecutionPhaseDeleting)
 			} else {
 				deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending,
 					"Last try to remove is pending")
@@ -639,6 +835,11 @@ func (r *kappDeployerDI) computeReadiness(ctx context.Context, deployData *deplo
 	}
 }
 
+func isRemovedAfterDeletionTimeout(deployData *deployutil.DeployData) bool {
+	readyCondition := deployData.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	return readyCondition != nil && readyCondition.Reason == string(hubv1.ReasonDeletionTimeout)
+}
+
 func (r *kappDeployerDI) setReadiness(deployData *deployutil.DeployData, readinessState string, now metav1.Time) {
 	deployData.ProviderStatus.Readiness = &hubv1.Readiness{
 		State: readinessState,
@@ -646,6 +847,10 @@ func (r *kappDeployerDI) setReadiness(deployData *deployutil.DeployData, readine
 	}
 }
 
+// setTypeSpecificStatus stores a summary of the status of the kapp app as type specific status. For installed apps
+// the summary contains the resources deployed on the target cluster. They are only listed again if the app was deployed
+// since the last listing, or with a backoff if the last listing failed, so that readiness checks do not query the
+// target cluster each time.
 func (r *kappDeployerDI) setTypeSpecificStatus(ctx context.Context, app *v1alpha1.App, deployData *deployutil.DeployData) {
 	log := util.GetLoggerFromContext(ctx)
 
@@ -654,17 +859,59 @@ func (r *kappDeployerDI) setTypeSpecificStatus(ctx context.Context, app *v1alpha
 		return
 	}
 
-	appStatusJSON, err := json.Marshal(app.Status)
+	status := newStatus(app, r.isDebugStatus(ctx, deployData))
+
+	previousStatus := parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+
+	if deployData.IsInstallOperation() && previousStatus.needsResourceListing(app, time.Now()) {
+		resources, truncated, err := r.listDeployedResources(ctx, deployData, app)
+		status.setResources(app, previousStatus, resources, truncated, err, metav1.Now())
+	} else if deployData.IsInstallOperation() && previousStatus.hasResourcesOfDeploy(app) {
+		status.copyResources(previousStatus)
+	}
+
+	statusJSON, err := json.Marshal(status)
 	if err != nil {
 		log.Error(err, "error marshaling status of kapp app")
 		return
 	}
 
 	deployData.ProviderStatus.TypeSpecificStatus = &runtime.RawExtension{
-		Raw: appStatusJSON,
+		Raw: statusJSON,
 	}
 }
 
+func (r *kappDeployerDI) listDeployedResources(ctx context.Context, deployData *deployutil.DeployData,
+	app *v1alpha1.App) ([]Resource, bool, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	targetClient, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *deployData.GetSecretKey())
+	if err != nil {
+		log.Error(err, "error fetching target client to list deployed resources")
+		return nil, false, err
+	}
+
+	resources, truncated, err := listDeployedResources(ctx, targetClient, targetClient.RESTMapper(), app)
+	if err != nil {
+		log.Error(err, "error listing resources deployed by kapp app")
+	}
+
+	return resources, truncated, err
+}
+
+// isDebugStatus returns whether the unmodified status of the kapp app should be added to the type specific status
+func (r *kappDeployerDI) isDebugStatus(ctx context.Context, deployData *deployutil.DeployData) bool {
+	log := util.GetLoggerFromContext(ctx)
+
+	kappSpecificData, err := apitypes.NewKappSpecificData(deployData.Configuration.DeploymentConfig.TypeSpecificData.Raw)
+	if err != nil {
+		log.Error(err, couldNotParse)
+		return false
+	}
+
+	return kappSpecificData.DebugStatus
+}
+
 func (r *kappDeployerDI) getAppKey(deployData *deployutil.DeployData) *types.NamespacedName {
 	return deployData.GetDeployItemKey()
 }
@@ -682,14 +929,6 @@ func (r *kappDeployerDI) hasAppCondition(app *v1alpha1.App, appConditionType v1a
 	return false
 }
 
-func (r *kappDeployerDI) successDescription(deployData *deployutil.DeployData) string {
-	if deployData.IsInstallOperation() {
-		return "install successful"
-	}
-
-	return "remove successful"
-}
-
 // Replaces logical secret names by the corresponding internal secret names.
 // Input: kapp specific data as []byte, and the mapping from logical to internal secret names.
 func (r *kappDeployerDI) replaceSecretNames(ctx context.Context, raw []byte, mapping map[string]string) ([]byte, error) {
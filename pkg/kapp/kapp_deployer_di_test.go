@@ -0,0 +1,359 @@
+package kapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	hubtesting "github.com/gardener/potter-controller/pkg/testing"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/go-logr/zapr"
+	kappctrl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testNamespace = "garden-test"
+	testAppName   = "test-item"
+)
+
+var testAppKey = types.NamespacedName{Namespace: testNamespace, Name: testAppName}
+
+const testDeletionTimeout = defaultDeletionTimeoutMinutes * time.Minute
+
+func TestRemoveTriggersDeletion(t *testing.T) {
+	crAndSecretClient := newTestClient(newTestApp(nil))
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient, defaultDeletionTimeout: testDeletionTimeout}
+
+	description, err := deployer.remove(newTestContext(), newTestDeployData(t))
+	assert.Equal(t, description, "", "description")
+
+	_, ok := err.(*deployutil.DeletionInProgressError)
+	assert.True(t, ok, "deletion in progress")
+
+	err = crAndSecretClient.Get(context.Background(), testAppKey, &kappctrl.App{})
+	assert.True(t, apierrors.IsNotFound(err), "app deleted")
+}
+
+func TestRemoveWithinDeletionTimeout(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-time.Minute))
+	crAndSecretClient := newTestClient(newTestApp(&deletionTimestamp))
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient, defaultDeletionTimeout: testDeletionTimeout}
+
+	_, err := deployer.remove(newTestContext(), newTestDeployData(t))
+	_, ok := err.(*deployutil.DeletionInProgressError)
+	assert.True(t, ok, "deletion in progress")
+
+	app := &kappctrl.App{}
+	err = crAndSecretClient.Get(context.Background(), testAppKey, app)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(app.GetFinalizers()), 1, "number of finalizers")
+}
+
+func TestRemoveAfterDeletionTimeout(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-2 * testDeletionTimeout))
+	crAndSecretClient := newTestClient(newTestApp(&deletionTimestamp))
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient, defaultDeletionTimeout: testDeletionTimeout}
+
+	deployData := newTestDeployData(t)
+	description, err := deployer.remove(newTestContext(), deployData)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, description, deletionTimeoutDescription, "description")
+
+	condition := deployData.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	assert.NotNil(t, condition, "ready condition")
+	assert.Equal(t, condition.Reason, string(hubv1.ReasonDeletionTimeout), "reason")
+
+	app := &kappctrl.App{}
+	err = crAndSecretClient.Get(context.Background(), testAppKey, app)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(app.GetFinalizers()), 0, "number of finalizers")
+}
+
+// TestRemoveWithOwnDeletionTimeout tests that the deletion timeout of the kapp specific data takes precedence over the
+// default deletion timeout
+func TestRemoveWithOwnDeletionTimeout(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	crAndSecretClient := newTestClient(newTestApp(&deletionTimestamp))
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient, defaultDeletionTimeout: testDeletionTimeout}
+
+	deployItem := newTestDeployItem(t, &apitypes.KappSpecificData{DeletionTimeout: &metav1.Duration{Duration: 5 * time.Minute}})
+	deployItem.DeletionTimestamp = &deletionTimestamp
+
+	description, err := deployer.remove(newTestContext(), newTestDeployDataForItem(t, deployItem))
+	assert.Nil(t, err, "error")
+	assert.Equal(t, description, deletionTimeoutDescription, "description")
+}
+
+func TestRemoveWithoutApp(t *testing.T) {
+	deployer := &kappDeployerDI{
+		crAndSecretClient:      newTestClient(),
+		defaultDeletionTimeout: testDeletionTimeout,
+	}
+
+	description, err := deployer.remove(newTestContext(), newTestDeployData(t))
+	assert.Nil(t, err, "error")
+	assert.Equal(t, description, removeSuccessfulDescription, "description")
+}
+
+func TestCleanupWithoutCluster(t *testing.T) {
+	crAndSecretClient := newTestClient(newTestApp(nil))
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	err := deployer.Cleanup(newTestContext(), newTestDeployData(t), false)
+	assert.Nil(t, err, "error")
+
+	err = crAndSecretClient.Get(context.Background(), testAppKey, &kappctrl.App{})
+	assert.True(t, apierrors.IsNotFound(err), "app deleted")
+}
+
+func TestProcessNewOperationSetsDeletionInProgress(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-time.Minute))
+	deployer := &kappDeployerDI{
+		crAndSecretClient:      newTestClient(newTestApp(&deletionTimestamp)),
+		defaultDeletionTimeout: testDeletionTimeout,
+	}
+	deployData := newTestDeployData(t)
+
+	deployer.ProcessNewOperation(newTestContext(), deployData)
+
+	lastOp := deployData.ProviderStatus.LastOperation
+	assert.Equal(t, lastOp.Operation, util.OperationRemove, "operation")
+	assert.Equal(t, lastOp.State, util.StatePending, "state")
+	assert.Equal(t, lastOp.Description, deletionInProgressDescription, "description")
+	assert.True(t, deployData.IsDeletionInProgress(), "deletion in progress")
+
+	condition := deployData.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	assert.Equal(t, condition.Reason, string(hubv1.ReasonRemovePending), "reason")
+}
+
+// TestProcessNewOperationAfterDeletionTimeout tests that a removal after the deletion timeout is shown by the reason
+// of the ready condition, independent of the description of the last operation
+func TestProcessNewOperationAfterDeletionTimeout(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-2 * testDeletionTimeout))
+	deployer := &kappDeployerDI{
+		crAndSecretClient:      newTestClient(newTestApp(&deletionTimestamp)),
+		defaultDeletionTimeout: testDeletionTimeout,
+	}
+	deployData := newTestDeployData(t)
+
+	deployer.ProcessNewOperation(newTestContext(), deployData)
+
+	lastOp := deployData.ProviderStatus.LastOperation
+	assert.Equal(t, lastOp.Operation, util.OperationRemove, "operation")
+	assert.Equal(t, lastOp.State, util.StateOk, "state")
+
+	condition := deployData.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	assert.Equal(t, condition.Reason, string(hubv1.ReasonDeletionTimeout), "reason")
+	assert.Equal(t, deployData.GetDeployItem().Status.Phase, v1alpha1.ExecutionPhaseSucceeded, "phase")
+}
+
+func TestPreprocessPausesAppWithProblem(t *testing.T) {
+	tests := []struct {
+		name           string
+		circuitBreaker *apitypes.CircuitBreakerPolicy
+		problemSince   time.Duration
+		expectedPaused bool
+	}{
+		{
+			name:           "problem shorter than default threshold",
+			problemSince:   10 * time.Minute,
+			expectedPaused: false,
+		},
+		{
+			name:           "problem longer than default threshold",
+			problemSince:   20 * time.Minute,
+			expectedPaused: true,
+		},
+		{
+			name:           "problem shorter than custom threshold",
+			circuitBreaker: &apitypes.CircuitBreakerPolicy{ProblemThreshold: &metav1.Duration{Duration: time.Hour}},
+			problemSince:   20 * time.Minute,
+			expectedPaused: false,
+		},
+		{
+			name:           "disabled circuit breaker",
+			circuitBreaker: &apitypes.CircuitBreakerPolicy{Disabled: true},
+			problemSince:   20 * time.Minute,
+			expectedPaused: false,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestAppWithProblem(&PauseStatus{Problem: true, ProblemSince: time.Now().Add(-test.problemSince)})
+			crAndSecretClient := newTestClient(app)
+			deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+			deployData := newTestDeployDataWithoutNewOperation(t, test.circuitBreaker)
+			deployer.Preprocess(newTestContext(), deployData)
+
+			assert.Equal(t, getTestPauseStatus(t, crAndSecretClient).Paused, test.expectedPaused, "paused")
+		})
+	}
+}
+
+func TestPreprocessResumesPausedApp(t *testing.T) {
+	problemSince := time.Now().Add(-time.Hour)
+	pausedSince := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		circuitBreaker *apitypes.CircuitBreakerPolicy
+		annotations    map[string]string
+		newOperation   bool
+		expectedPaused bool
+	}{
+		{
+			name:           "within pause duration",
+			expectedPaused: true,
+		},
+		{
+			name:           "after custom pause duration",
+			circuitBreaker: &apitypes.CircuitBreakerPolicy{PauseDuration: &metav1.Duration{Duration: 30 * time.Second}},
+			expectedPaused: false,
+		},
+		{
+			name:           "resume annotation",
+			annotations:    map[string]string{util.AnnotationKeyResume: util.AnnotationValueResume},
+			expectedPaused: false,
+		},
+		{
+			name:           "resume annotation with other value",
+			annotations:    map[string]string{util.AnnotationKeyResume: "true"},
+			expectedPaused: true,
+		},
+		{
+			name:           "reset of retries",
+			annotations:    map[string]string{util.AnnotationKeyResetRetries: util.AnnotationValueResetRetries},
+			expectedPaused: false,
+		},
+		{
+			name:           "changed application config",
+			newOperation:   true,
+			expectedPaused: false,
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestAppWithProblem(&PauseStatus{Paused: true, PausedSince: pausedSince, Problem: true, ProblemSince: problemSince})
+			crAndSecretClient := newTestClient(app)
+			deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+			deployItem := newTestDeployItem(t, &apitypes.KappSpecificData{CircuitBreaker: test.circuitBreaker})
+			if !test.newOperation {
+				deployItem.Status.ObservedGeneration = deployItem.Generation
+			}
+			deployItem.Annotations = test.annotations
+
+			deployer.Preprocess(newTestContext(), newTestDeployDataForItem(t, deployItem))
+
+			pauseStatus := getTestPauseStatus(t, crAndSecretClient)
+			assert.Equal(t, pauseStatus.Paused, test.expectedPaused, "paused")
+		})
+	}
+}
+
+func TestPausedCondition(t *testing.T) {
+	deployer := &kappDeployerDI{}
+	deployData := newTestDeployDataWithoutNewOperation(t, nil)
+	now := metav1.Now()
+
+	// no condition for apps which were never paused
+	deployer.setPausedCondition(newTestContext(), deployData, newTestAppWithProblem(&PauseStatus{}), now)
+	assert.Nil(t, deployData.GetDeployItemCondition(hubv1.HubDeploymentPaused), "paused condition")
+
+	pausedApp := newTestAppWithProblem(&PauseStatus{Paused: true, PausedSince: time.Now(), Problem: true, ProblemSince: time.Now()})
+	deployer.setPausedCondition(newTestContext(), deployData, pausedApp, now)
+	condition := deployData.GetDeployItemCondition(hubv1.HubDeploymentPaused)
+	assert.Equal(t, string(condition.Status), string(corev1.ConditionTrue), "status")
+	assert.Equal(t, condition.Reason, string(hubv1.ReasonCircuitBreakerOpen), "reason")
+
+	deployer.setPausedCondition(newTestContext(), deployData, newTestAppWithProblem(&PauseStatus{}), now)
+	condition = deployData.GetDeployItemCondition(hubv1.HubDeploymentPaused)
+	assert.Equal(t, string(condition.Status), string(corev1.ConditionFalse), "status")
+	assert.Equal(t, condition.Reason, string(hubv1.ReasonCircuitBreakerClosed), "reason")
+}
+
+func newTestAppWithProblem(pauseStatus *PauseStatus) *kappctrl.App {
+	app := newTestApp(nil)
+	app.Generation = 1
+	app.Status.ObservedGeneration = 1
+	app.Status.Deploy = &kappctrl.AppStatusDeploy{ExitCode: 1}
+	SetPauseStatus(app, pauseStatus)
+	return app
+}
+
+func getTestPauseStatus(t *testing.T, crAndSecretClient client.Client) *PauseStatus {
+	app := &kappctrl.App{}
+	err := crAndSecretClient.Get(context.Background(), testAppKey, app)
+	assert.Nil(t, err, "error")
+
+	pauseStatus, err := GetOldOrInitialPauseStatus(newTestContext(), app)
+	assert.Nil(t, err, "error")
+	return pauseStatus
+}
+
+func newTestDeployDataWithoutNewOperation(t *testing.T, circuitBreaker *apitypes.CircuitBreakerPolicy) *deployutil.DeployData {
+	deployItem := newTestDeployItem(t, &apitypes.KappSpecificData{CircuitBreaker: circuitBreaker})
+	deployItem.Status.ObservedGeneration = deployItem.Generation
+	return newTestDeployDataForItem(t, deployItem)
+}
+
+func newTestApp(deletionTimestamp *metav1.Time) *kappctrl.App {
+	return &kappctrl.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              testAppName,
+			Namespace:         testNamespace,
+			Finalizers:        []string{"finalizers.kapp-ctrl.k14s.io/delete"},
+			DeletionTimestamp: deletionTimestamp,
+		},
+	}
+}
+
+func newTestClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = kappctrl.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func newTestContext() context.Context {
+	return context.WithValue(context.Background(), util.LoggerKey{}, zapr.NewLogger(zap.NewNop()))
+}
+
+func newTestDeployData(t *testing.T) *deployutil.DeployData {
+	deletionTimestamp := metav1.Now()
+	deployItem := newTestDeployItem(t, &apitypes.KappSpecificData{})
+	deployItem.DeletionTimestamp = &deletionTimestamp
+	return newTestDeployDataForItem(t, deployItem)
+}
+
+func newTestDeployItem(t *testing.T, kappSpecificData *apitypes.KappSpecificData) *v1alpha1.DeployItem {
+	deployItem := hubtesting.CreateDeployItemForConfig(t, testAppName, testNamespace, kappSpecificData, nil)
+	deployItem.Generation = 2
+	deployItem.Status.ObservedGeneration = 1
+	return deployItem
+}
+
+func newTestDeployDataForItem(t *testing.T, deployItem *v1alpha1.DeployItem) *deployutil.DeployData {
+	deployData, err := deployutil.NewDeployData(deployItem)
+	assert.Nil(t, err, "error")
+	return deployData
+}
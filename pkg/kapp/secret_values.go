@@ -0,0 +1,198 @@
+package kapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/pkg/errors"
+	"github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applySecretValues provides the secret values of the application in a values secret next to the kapp app, and adds
+// the values secret to the value sources of the ytt and helmTemplate steps of the app. It returns the name of the
+// values secret, or an empty string if the application has no secret values. The name changes with the secret values,
+// so that the spec of the app changes, and kapp-controller deploys the app again; see deleteValuesSecrets for the
+// removal of the values secrets which are no longer used.
+func (r *kappDeployerDI) applySecretValues(ctx context.Context, deployData *deployutil.DeployData, appSpec *v1alpha1.AppSpec) (string, error) {
+	internalSecretName := deployData.Configuration.DeploymentConfig.InternalSecretName
+	if internalSecretName == "" {
+		return "", nil
+	}
+
+	secretValues, err := r.getSecretValues(ctx, deployData, internalSecretName)
+	if err != nil {
+		return "", err
+	}
+
+	secretKey := r.getValuesSecretKey(deployData, internalSecretName)
+	if err = r.applyValuesSecret(ctx, deployData, secretKey, secretValues); err != nil {
+		return "", err
+	}
+
+	addValuesSecretRef(appSpec, secretKey.Name)
+	return secretKey.Name, nil
+}
+
+// getSecretValues reads the secret values from the internal secret of the application. They are stored as json, which
+// is also valid yaml.
+func (r *kappDeployerDI) getSecretValues(ctx context.Context, deployData *deployutil.DeployData, internalSecretName string) ([]byte, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	secretKey := types.NamespacedName{
+		Name:      internalSecretName,
+		Namespace: deployData.GetNamespace(),
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.crAndSecretClient.Get(ctx, secretKey, secret); err != nil {
+		msg := "could not read secret values"
+		log.Error(err, msg, util.LogKeySecretName, internalSecretName)
+		return nil, errors.Wrap(err, msg)
+	}
+
+	secretValues, ok := secret.Data[util.SecretValuesKey]
+	if !ok {
+		err := errors.New("secret values not found in internal secret")
+		log.Error(err, "could not read secret values", util.LogKeySecretName, internalSecretName)
+		return nil, err
+	}
+
+	return secretValues, nil
+}
+
+func (r *kappDeployerDI) applyValuesSecret(ctx context.Context, deployData *deployutil.DeployData,
+	secretKey *types.NamespacedName, secretValues []byte) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+	valuesData := map[string][]byte{secretValuesKey: secretValues}
+
+	secret := corev1.Secret{}
+	err := r.crAndSecretClient.Get(ctx, *secretKey, &secret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "error fetching values secret", util.LogKeySecretName, secretKey.Name)
+			return err
+		}
+
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretKey.Name,
+				Namespace: secretKey.Namespace,
+				Labels: map[string]string{
+					hubv1.LabelClusterBomName:      clusterBomKey.Name,
+					hubv1.LabelApplicationConfigID: deployData.GetConfigID(),
+					hubv1.LabelPurpose:             util.PurposeKappSecretValues,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: valuesData,
+		}
+
+		err = r.crAndSecretClient.Create(ctx, &secret)
+		if err != nil {
+			log.Error(err, "error creating values secret", util.LogKeySecretName, secretKey.Name)
+		}
+
+		return err
+	}
+
+	secret.Data = valuesData
+	err = r.crAndSecretClient.Update(ctx, &secret)
+	if err != nil {
+		log.Error(err, "error updating values secret", util.LogKeySecretName, secretKey.Name)
+	}
+
+	return err
+}
+
+// deleteValuesSecrets deletes the values secrets of the kapp app, except the one with name keepName, which is used by
+// the current spec of the app. Values secrets of earlier versions, whose name did not depend on the secret values, are
+// deleted as well.
+func (r *kappDeployerDI) deleteValuesSecrets(ctx context.Context, deployData *deployutil.DeployData, keepName string) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	appKey := r.getAppKey(deployData)
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+
+	secretList := &corev1.SecretList{}
+	err := r.crAndSecretClient.List(ctx, secretList, client.InNamespace(appKey.Namespace), client.MatchingLabels{
+		hubv1.LabelClusterBomName:      clusterBomKey.Name,
+		hubv1.LabelApplicationConfigID: deployData.GetConfigID(),
+		hubv1.LabelPurpose:             util.PurposeKappSecretValues,
+	})
+	if err != nil {
+		log.Error(err, "error listing values secrets for deletion", util.LogKeyKappAppNamespacedName, appKey)
+		return err
+	}
+
+	secretNames := []string{appKey.Name + valuesSecretSuffix}
+	for i := range secretList.Items {
+		secretNames = append(secretNames, secretList.Items[i].Name)
+	}
+
+	for _, secretName := range secretNames {
+		if secretName == keepName {
+			continue
+		}
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: appKey.Namespace,
+			},
+		}
+
+		err = r.crAndSecretClient.Delete(ctx, secret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "error deleting values secret", util.LogKeySecretName, secretName)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getValuesSecretKey returns the key of the values secret. Its name is derived from the name of the internal secret,
+// which changes whenever the secret values change. It is not derived from the secret values themselves, because
+// everyone who can read the kapp app could then check guessed values against it.
+func (r *kappDeployerDI) getValuesSecretKey(deployData *deployutil.DeployData, internalSecretName string) *types.NamespacedName {
+	appKey := r.getAppKey(deployData)
+	hash := sha256.Sum256([]byte(internalSecretName))
+	return &types.NamespacedName{
+		Name:      appKey.Name + valuesSecretSuffix + "-" + hex.EncodeToString(hash[:5]),
+		Namespace: appKey.Namespace,
+	}
+}
+
+// addValuesSecretRef appends the values secret to the value sources of all ytt and helmTemplate steps, so that the
+// secret values take precedence over the other values.
+func addValuesSecretRef(appSpec *v1alpha1.AppSpec, secretName string) {
+	valuesSource := v1alpha1.AppTemplateValuesSource{
+		SecretRef: &v1alpha1.AppTemplateValuesSourceRef{
+			Name: secretName,
+		},
+	}
+
+	for i := range appSpec.Template {
+		template := &appSpec.Template[i]
+
+		if template.Ytt != nil {
+			template.Ytt.ValuesFrom = append(template.Ytt.ValuesFrom, valuesSource)
+		}
+
+		if template.HelmTemplate != nil {
+			template.HelmTemplate.ValuesFrom = append(template.HelmTemplate.ValuesFrom, valuesSource)
+		}
+	}
+}
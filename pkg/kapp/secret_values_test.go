@@ -0,0 +1,171 @@
+package kapp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	kappctrl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testInternalSecretName = "test-internal-secret"
+
+// testLegacyValuesSecretKey is the key of a values secret whose name does not depend on the secret values
+var testLegacyValuesSecretKey = types.NamespacedName{Namespace: testNamespace, Name: testAppName + valuesSecretSuffix}
+
+func TestApplySecretValues(t *testing.T) {
+	crAndSecretClient := newTestClient(newTestInternalSecret(`{"password":"secret"}`))
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	deployData := newTestDeployDataWithSecretValues(t, testInternalSecretName)
+	appSpec := newTestAppSpecWithTemplates()
+	valuesSecretName, err := deployer.applySecretValues(newTestContext(), deployData, appSpec)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, valuesSecretName, deployer.getValuesSecretKey(deployData, testInternalSecretName).Name, "name of values secret")
+
+	valuesSecret := &corev1.Secret{}
+	err = crAndSecretClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: valuesSecretName}, valuesSecret)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, valuesSecret.Labels[hubv1.LabelPurpose], util.PurposeKappSecretValues, "purpose")
+	assert.Equal(t, string(valuesSecret.Data[secretValuesKey]), `{"password":"secret"}`, "secret values")
+
+	assert.Equal(t, len(appSpec.Template[0].Ytt.ValuesFrom), 2, "number of ytt value sources")
+	assert.Equal(t, appSpec.Template[0].Ytt.ValuesFrom[0].ConfigMapRef.Name, "values", "first ytt value source")
+	assert.Equal(t, appSpec.Template[0].Ytt.ValuesFrom[1].SecretRef.Name, valuesSecretName, "second ytt value source")
+	assert.Equal(t, len(appSpec.Template[1].HelmTemplate.ValuesFrom), 1, "number of helmTemplate value sources")
+	assert.Equal(t, appSpec.Template[1].HelmTemplate.ValuesFrom[0].SecretRef.Name, valuesSecretName, "helmTemplate value source")
+
+	// replaced secret values result in a new values secret, and therefore in a changed app spec
+	err = crAndSecretClient.Create(context.Background(), newTestInternalSecretWithName("test-internal-secret-2", `{"password":"new"}`))
+	assert.Nil(t, err, "error")
+
+	deployData = newTestDeployDataWithSecretValues(t, "test-internal-secret-2")
+	newAppSpec := newTestAppSpecWithTemplates()
+	newValuesSecretName, err := deployer.applySecretValues(newTestContext(), deployData, newAppSpec)
+	assert.Nil(t, err, "error")
+	assert.True(t, newValuesSecretName != valuesSecretName, "name of values secret changed")
+	assert.Equal(t, newAppSpec.Template[0].Ytt.ValuesFrom[1].SecretRef.Name, newValuesSecretName, "ytt value source")
+
+	valuesSecret = &corev1.Secret{}
+	err = crAndSecretClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: newValuesSecretName}, valuesSecret)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, string(valuesSecret.Data[secretValuesKey]), `{"password":"new"}`, "secret values")
+
+	// the values secret of the replaced secret values is deleted, once the app refers to the new one
+	err = deployer.deleteValuesSecrets(newTestContext(), deployData, newValuesSecretName)
+	assert.Nil(t, err, "error")
+
+	err = crAndSecretClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: valuesSecretName}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "old values secret deleted")
+	err = crAndSecretClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: newValuesSecretName}, &corev1.Secret{})
+	assert.Nil(t, err, "current values secret kept")
+}
+
+func TestApplySecretValuesWithoutSecretValues(t *testing.T) {
+	crAndSecretClient := newTestClient(newTestValuesSecret())
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	deployData := newTestDeployDataWithSecretValues(t, "")
+	appSpec := newTestAppSpecWithTemplates()
+	valuesSecretName, err := deployer.applySecretValues(newTestContext(), deployData, appSpec)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, valuesSecretName, "", "name of values secret")
+	assert.Equal(t, len(appSpec.Template[0].Ytt.ValuesFrom), 1, "number of ytt value sources")
+	assert.Equal(t, len(appSpec.Template[1].HelmTemplate.ValuesFrom), 0, "number of helmTemplate value sources")
+
+	err = deployer.deleteValuesSecrets(newTestContext(), deployData, valuesSecretName)
+	assert.Nil(t, err, "error")
+
+	err = crAndSecretClient.Get(context.Background(), testLegacyValuesSecretKey, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "values secret deleted")
+}
+
+func TestApplySecretValuesWithoutInternalSecret(t *testing.T) {
+	deployer := &kappDeployerDI{crAndSecretClient: newTestClient()}
+
+	_, err := deployer.applySecretValues(newTestContext(), newTestDeployDataWithSecretValues(t, testInternalSecretName),
+		newTestAppSpecWithTemplates())
+	assert.NotNil(t, err, "error")
+}
+
+func TestRemoveDeletesValuesSecret(t *testing.T) {
+	crAndSecretClient := newTestClient(newTestValuesSecret())
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	description, err := deployer.remove(newTestContext(), newTestDeployData(t))
+	assert.Nil(t, err, "error")
+	assert.Equal(t, description, removeSuccessfulDescription, "description")
+
+	err = crAndSecretClient.Get(context.Background(), testLegacyValuesSecretKey, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "values secret deleted")
+}
+
+func TestCleanupWithoutClusterDeletesValuesSecret(t *testing.T) {
+	crAndSecretClient := newTestClient(newTestApp(nil), newTestValuesSecret())
+	deployer := &kappDeployerDI{crAndSecretClient: crAndSecretClient}
+
+	err := deployer.Cleanup(newTestContext(), newTestDeployData(t), false)
+	assert.Nil(t, err, "error")
+
+	err = crAndSecretClient.Get(context.Background(), testLegacyValuesSecretKey, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "values secret deleted")
+}
+
+func newTestDeployDataWithSecretValues(t *testing.T, internalSecretName string) *deployutil.DeployData {
+	deployData := newTestDeployDataForItem(t, newTestDeployItem(t, &apitypes.KappSpecificData{}))
+	deployData.Configuration.DeploymentConfig.InternalSecretName = internalSecretName
+	return deployData
+}
+
+func newTestAppSpecWithTemplates() *kappctrl.AppSpec {
+	return &kappctrl.AppSpec{
+		Template: []kappctrl.AppTemplate{
+			{
+				Ytt: &kappctrl.AppTemplateYtt{
+					ValuesFrom: []kappctrl.AppTemplateValuesSource{
+						{ConfigMapRef: &kappctrl.AppTemplateValuesSourceRef{Name: "values"}},
+					},
+				},
+			},
+			{
+				HelmTemplate: &kappctrl.AppTemplateHelmTemplate{},
+			},
+			{
+				Kbld: &kappctrl.AppTemplateKbld{},
+			},
+		},
+	}
+}
+
+func newTestInternalSecret(secretValues string) *corev1.Secret {
+	return newTestInternalSecretWithName(testInternalSecretName, secretValues)
+}
+
+func newTestInternalSecretWithName(name, secretValues string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{util.SecretValuesKey: []byte(secretValues)},
+	}
+}
+
+func newTestValuesSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testLegacyValuesSecretKey.Name,
+			Namespace: testLegacyValuesSecretKey.Namespace,
+		},
+		Data: map[string][]byte{secretValuesKey: []byte(`{"password":"old"}`)},
+	}
+}
@@ -0,0 +1,319 @@
+package kapp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxExcerptLength is the maximal length of error messages and error output in the status
+	maxExcerptLength = 500
+	// maxResources is the maximal number of deployed resources in the status
+	maxResources = 200
+
+	kappAppSuffix  = "-ctrl"
+	kappAppMetaKey = "spec"
+
+	// resourcesRetryInterval is the time after which a failed listing of the deployed resources is repeated. It
+	// doubles with every further failure up to maxResourcesRetryInterval.
+	resourcesRetryInterval    = time.Minute
+	maxResourcesRetryInterval = 30 * time.Minute
+)
+
+// Status is stored as type specific status of a deploy item with config type kapp. It summarizes the status of the
+// kapp app.
+type Status struct {
+	ObservedGeneration            int64       `json:"observedGeneration,omitempty"`
+	FriendlyDescription           string      `json:"friendlyDescription,omitempty"`
+	UsefulErrorMessage            string      `json:"usefulErrorMessage,omitempty"`
+	ConsecutiveReconcileSuccesses int         `json:"consecutiveReconcileSuccesses,omitempty"`
+	ConsecutiveReconcileFailures  int         `json:"consecutiveReconcileFailures,omitempty"`
+	Fetch                         *StepStatus `json:"fetch,omitempty"`
+	Template                      *StepStatus `json:"template,omitempty"`
+	Deploy                        *StepStatus `json:"deploy,omitempty"`
+	Resources                     []Resource  `json:"resources,omitempty"`
+	ResourcesTruncated            bool        `json:"resourcesTruncated,omitempty"`
+	// ResourcesGeneration and ResourcesDeployedAt identify the deploy step of the app for which the resources were
+	// listed. They are also set if no resources were found or the listing failed, so that the resources are not listed
+	// again for the same deploy step.
+	ResourcesGeneration int64        `json:"resourcesGeneration,omitempty"`
+	ResourcesDeployedAt *metav1.Time `json:"resourcesDeployedAt,omitempty"`
+	// ResourcesError is the error of the last listing, which is repeated with a backoff after ResourcesListedAt
+	ResourcesError    string       `json:"resourcesError,omitempty"`
+	ResourcesFailures int          `json:"resourcesFailures,omitempty"`
+	ResourcesListedAt *metav1.Time `json:"resourcesListedAt,omitempty"`
+	// RawStatus is the unmodified status of the kapp app. It is only set if debugStatus is enabled in the
+	// typeSpecificData.
+	RawStatus *v1alpha1.AppStatus `json:"rawStatus,omitempty"`
+}
+
+// StepStatus is the result of the fetch, template or deploy step of a kapp app. Error messages and error output are
+// truncated.
+type StepStatus struct {
+	ExitCode      int         `json:"exitCode"`
+	Error         string      `json:"error,omitempty"`
+	StderrExcerpt string      `json:"stderrExcerpt,omitempty"`
+	UpdatedAt     metav1.Time `json:"updatedAt,omitempty"`
+}
+
+// Resource identifies an object on the target cluster which was deployed by kapp.
+type Resource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// appMeta is the content of the app ConfigMap, in which kapp stores the label of the deployed resources and their
+// kinds.
+type appMeta struct {
+	LabelKey   string              `json:"labelKey"`
+	LabelValue string              `json:"labelValue"`
+	UsedGKs    *[]schema.GroupKind `json:"usedGKs,omitempty"`
+}
+
+func newStatus(app *v1alpha1.App, debugStatus bool) *Status {
+	status := &Status{
+		ObservedGeneration:            app.Status.ObservedGeneration,
+		FriendlyDescription:           app.Status.FriendlyDescription,
+		UsefulErrorMessage:            truncate(app.Status.UsefulErrorMessage),
+		ConsecutiveReconcileSuccesses: app.Status.ConsecutiveReconcileSuccesses,
+		ConsecutiveReconcileFailures:  app.Status.ConsecutiveReconcileFailures,
+	}
+
+	if app.Status.Fetch != nil {
+		status.Fetch = newStepStatus(app.Status.Fetch.ExitCode, app.Status.Fetch.Error, app.Status.Fetch.Stderr,
+			app.Status.Fetch.UpdatedAt)
+	}
+
+	if app.Status.Template != nil {
+		status.Template = newStepStatus(app.Status.Template.ExitCode, app.Status.Template.Error, app.Status.Template.Stderr,
+			app.Status.Template.UpdatedAt)
+	}
+
+	if app.Status.Deploy != nil {
+		status.Deploy = newStepStatus(app.Status.Deploy.ExitCode, app.Status.Deploy.Error, app.Status.Deploy.Stderr,
+			app.Status.Deploy.UpdatedAt)
+	}
+
+	if debugStatus {
+		status.RawStatus = app.Status.DeepCopy()
+	}
+
+	return status
+}
+
+func newStepStatus(exitCode int, errorMessage, stderr string, updatedAt metav1.Time) *StepStatus {
+	return &StepStatus{
+		ExitCode:      exitCode,
+		Error:         truncate(errorMessage),
+		StderrExcerpt: truncateFront(stderr),
+		UpdatedAt:     updatedAt,
+	}
+}
+
+// parseStatus returns the type specific status of a deploy item, or nil if it is missing or invalid
+func parseStatus(raw *runtime.RawExtension) *Status {
+	if raw == nil || len(raw.Raw) == 0 {
+		return nil
+	}
+
+	status := &Status{}
+	if err := json.Unmarshal(raw.Raw, status); err != nil {
+		return nil
+	}
+
+	return status
+}
+
+// hasResourcesOfDeploy returns whether the resources were listed for the current deploy step of the app, also if none
+// were found or the listing failed
+func (s *Status) hasResourcesOfDeploy(app *v1alpha1.App) bool {
+	return s != nil && s.ResourcesDeployedAt != nil && app.Status.Deploy != nil &&
+		s.ResourcesGeneration == app.Status.ObservedGeneration &&
+		s.ResourcesDeployedAt.Equal(&app.Status.Deploy.UpdatedAt)
+}
+
+// needsResourceListing returns whether the deployed resources must be listed on the target cluster: after a new deploy
+// step of the app, or if the last listing failed and its backoff has expired
+func (s *Status) needsResourceListing(app *v1alpha1.App, now time.Time) bool {
+	if app.Status.Deploy == nil {
+		return false
+	}
+
+	if !s.hasResourcesOfDeploy(app) {
+		return true
+	}
+
+	return s.ResourcesError != "" && (s.ResourcesListedAt == nil || !now.Before(s.ResourcesListedAt.Add(s.resourcesBackoff())))
+}
+
+func (s *Status) resourcesBackoff() time.Duration {
+	backoff := resourcesRetryInterval
+	for i := 1; i < s.ResourcesFailures && backoff < maxResourcesRetryInterval; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxResourcesRetryInterval {
+		return maxResourcesRetryInterval
+	}
+
+	return backoff
+}
+
+// copyResources takes over the result of the last listing of the resources from the previous status
+func (s *Status) copyResources(previousStatus *Status) {
+	s.Resources = previousStatus.Resources
+	s.ResourcesTruncated = previousStatus.ResourcesTruncated
+	s.ResourcesGeneration = previousStatus.ResourcesGeneration
+	s.ResourcesDeployedAt = previousStatus.ResourcesDeployedAt
+	s.ResourcesError = previousStatus.ResourcesError
+	s.ResourcesFailures = previousStatus.ResourcesFailures
+	s.ResourcesListedAt = previousStatus.ResourcesListedAt
+}
+
+// setResources records the result of a listing of the resources for the current deploy step of the app. Failures for
+// the same deploy step are counted, so that the backoff grows.
+func (s *Status) setResources(app *v1alpha1.App, previousStatus *Status, resources []Resource, truncated bool, err error,
+	now metav1.Time) {
+	deployedAt := app.Status.Deploy.UpdatedAt
+	s.ResourcesGeneration = app.Status.ObservedGeneration
+	s.ResourcesDeployedAt = &deployedAt
+	s.ResourcesListedAt = &now
+
+	if err == nil {
+		s.Resources = resources
+		s.ResourcesTruncated = truncated
+		return
+	}
+
+	s.ResourcesError = truncate(err.Error())
+	s.ResourcesFailures = 1
+	if previousStatus.hasResourcesOfDeploy(app) {
+		s.ResourcesFailures = previousStatus.ResourcesFailures + 1
+		s.Resources = previousStatus.Resources
+		s.ResourcesTruncated = previousStatus.ResourcesTruncated
+	}
+}
+
+// truncate keeps the beginning of a message. It does not split multi-byte characters.
+func truncate(s string) string {
+	if len(s) <= maxExcerptLength {
+		return s
+	}
+
+	end := maxExcerptLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	return s[:end] + "..."
+}
+
+// truncateFront keeps the end of an output, where the relevant errors usually are. It does not split multi-byte
+// characters.
+func truncateFront(s string) string {
+	if len(s) <= maxExcerptLength {
+		return s
+	}
+
+	start := len(s) - maxExcerptLength
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+
+	return "..." + s[start:]
+}
+
+// listDeployedResources returns the resources which kapp has deployed for an app. The label and the kinds of the
+// resources are read from the app ConfigMap of kapp on the target cluster. Kinds which are unknown on the target
+// cluster are skipped.
+func listDeployedResources(ctx context.Context, targetClient client.Reader, mapper meta.RESTMapper,
+	app *v1alpha1.App) ([]Resource, bool, error) {
+	kappMeta, err := readAppMeta(ctx, targetClient, app)
+	if err != nil || kappMeta.UsedGKs == nil {
+		return nil, false, err
+	}
+
+	resources := []Resource{}
+
+	for _, groupKind := range *kappMeta.UsedGKs {
+		mapping, err := mapper.RESTMapping(groupKind)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+
+			return nil, false, err
+		}
+
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(mapping.GroupVersionKind.GroupVersion().WithKind(groupKind.Kind + "List"))
+
+		err = targetClient.List(ctx, list, client.MatchingLabels{kappMeta.LabelKey: kappMeta.LabelValue})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for i := range list.Items {
+			item := &list.Items[i]
+			resources = append(resources, Resource{
+				APIVersion: item.GetAPIVersion(),
+				Kind:       item.GetKind(),
+				Namespace:  item.GetNamespace(),
+				Name:       item.GetName(),
+			})
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		if resources[i].Namespace != resources[j].Namespace {
+			return resources[i].Namespace < resources[j].Namespace
+		}
+		return resources[i].Name < resources[j].Name
+	})
+
+	if len(resources) > maxResources {
+		return resources[:maxResources], true, nil
+	}
+
+	return resources, false, nil
+}
+
+// readAppMeta reads the app ConfigMap of kapp. kapp-controller deploys an app under the name <app name>-ctrl into the
+// namespace specified in the cluster section of the app.
+func readAppMeta(ctx context.Context, targetClient client.Reader, app *v1alpha1.App) (*appMeta, error) {
+	namespace := "default"
+	if app.Spec.Cluster != nil && app.Spec.Cluster.Namespace != "" {
+		namespace = app.Spec.Cluster.Namespace
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Namespace: namespace, Name: app.GetName() + kappAppSuffix}
+	if err := targetClient.Get(ctx, configMapKey, configMap); err != nil {
+		return nil, err
+	}
+
+	kappMeta := &appMeta{}
+	if err := json.Unmarshal([]byte(configMap.Data[kappAppMetaKey]), kappMeta); err != nil {
+		return nil, err
+	}
+
+	return kappMeta, nil
+}
@@ -0,0 +1,271 @@
+package kapp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+
+	"github.com/arschles/assert"
+	kappctrl "github.com/vmware-tanzu/carvel-kapp-controller/pkg/apis/kappctrl/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testKappLabelKey = "kapp.k14s.io/app"
+
+func TestNewStatus(t *testing.T) {
+	longStderr := strings.Repeat("x", 2*maxExcerptLength) + "kapp: Error: timed out"
+	longError := "Deploying: " + strings.Repeat("y", 2*maxExcerptLength)
+
+	app := newTestApp(nil)
+	app.Status = kappctrl.AppStatus{
+		Fetch:    &kappctrl.AppStatusFetch{ExitCode: 0, Stdout: strings.Repeat("z", 2*maxExcerptLength)},
+		Template: &kappctrl.AppStatusTemplate{ExitCode: 0},
+		Deploy: &kappctrl.AppStatusDeploy{
+			ExitCode: 1,
+			Error:    longError,
+			Stderr:   longStderr,
+			Stdout:   strings.Repeat("z", 2*maxExcerptLength),
+		},
+		GenericStatus: kappctrl.GenericStatus{
+			ObservedGeneration:  3,
+			FriendlyDescription: "Reconcile failed: Deploying: Error (see .status.usefulErrorMessage for details)",
+			UsefulErrorMessage:  longError,
+		},
+		ConsecutiveReconcileFailures: 2,
+	}
+
+	status := newStatus(app, false)
+	assert.Equal(t, status.ObservedGeneration, int64(3), "observed generation")
+	assert.Equal(t, status.ConsecutiveReconcileFailures, 2, "consecutive reconcile failures")
+	assert.Equal(t, status.UsefulErrorMessage, longError[:maxExcerptLength]+"...", "useful error message")
+	assert.NotNil(t, status.Fetch, "fetch status")
+	assert.NotNil(t, status.Template, "template status")
+	assert.NotNil(t, status.Deploy, "deploy status")
+	assert.Equal(t, status.Deploy.ExitCode, 1, "deploy exit code")
+	assert.Equal(t, status.Deploy.Error, longError[:maxExcerptLength]+"...", "deploy error")
+	assert.True(t, strings.HasSuffix(status.Deploy.StderrExcerpt, "kapp: Error: timed out"), "stderr excerpt contains end of output")
+	assert.Equal(t, len(status.Deploy.StderrExcerpt), maxExcerptLength+3, "length of stderr excerpt")
+	assert.Nil(t, status.RawStatus, "raw status")
+
+	statusJSON, err := json.Marshal(status)
+	assert.Nil(t, err, "error")
+	assert.False(t, strings.Contains(string(statusJSON), "zzz"), "status contains stdout")
+
+	status = newStatus(app, true)
+	assert.NotNil(t, status.RawStatus, "raw status")
+	assert.Equal(t, status.RawStatus.Deploy.Stderr, longStderr, "raw stderr")
+}
+
+func TestNewStatusWithoutSteps(t *testing.T) {
+	status := newStatus(newTestApp(nil), false)
+	assert.Nil(t, status.Fetch, "fetch status")
+	assert.Nil(t, status.Template, "template status")
+	assert.Nil(t, status.Deploy, "deploy status")
+}
+
+func TestTruncateKeepsCharacters(t *testing.T) {
+	s := strings.Repeat("ä", maxExcerptLength)
+
+	truncated := truncate(s)
+	assert.True(t, utf8.ValidString(truncated), "valid utf8")
+	assert.True(t, len(truncated) <= maxExcerptLength+3, "length")
+	assert.Equal(t, truncated, strings.Repeat("ä", maxExcerptLength/2)+"...", "truncated")
+
+	truncated = truncateFront("x" + s)
+	assert.True(t, utf8.ValidString(truncated), "valid utf8")
+	assert.True(t, len(truncated) <= maxExcerptLength+3, "length")
+	assert.Equal(t, truncated, "..."+strings.Repeat("ä", maxExcerptLength/2), "truncated front")
+}
+
+func TestSetTypeSpecificStatusReusesResources(t *testing.T) {
+	// without a target cluster secret the resources cannot be listed again
+	deployer := &kappDeployerDI{crAndSecretClient: newTestClient()}
+	deployData := newTestDeployDataForItem(t, newTestDeployItem(t, &apitypes.KappSpecificData{}))
+
+	resources := []Resource{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "target-ns", Name: "config"}}
+	app := newTestApp(nil)
+	app.Status.ObservedGeneration = 2
+	app.Status.Deploy = &kappctrl.AppStatusDeploy{UpdatedAt: metav1.Unix(1000, 0)}
+	previousStatus := newStatus(app, false)
+	previousStatus.setResources(app, nil, resources, false, nil, metav1.Now())
+	setTestStatus(t, deployData, previousStatus)
+
+	deployer.setTypeSpecificStatus(newTestContext(), app, deployData)
+	status := parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+	assert.NotNil(t, status, "status")
+	assert.Equal(t, len(status.Resources), 1, "number of resources")
+	assert.Equal(t, status.Resources[0], resources[0], "resource")
+	assert.Equal(t, status.ResourcesError, "", "listing error")
+
+	// an empty listing of the same deploy step is not repeated
+	previousStatus.setResources(app, nil, nil, false, nil, metav1.Now())
+	setTestStatus(t, deployData, previousStatus)
+	deployer.setTypeSpecificStatus(newTestContext(), app, deployData)
+	status = parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+	assert.Equal(t, len(status.Resources), 0, "number of resources of empty listing")
+	assert.Equal(t, status.ResourcesError, "", "listing error of empty listing")
+	assert.True(t, status.hasResourcesOfDeploy(app), "listed for deploy step")
+
+	app.Status.Deploy.UpdatedAt = metav1.Unix(2000, 0)
+	deployer.setTypeSpecificStatus(newTestContext(), app, deployData)
+	status = parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+	assert.NotNil(t, status, "status")
+	assert.Equal(t, len(status.Resources), 0, "number of resources after new deploy")
+	assert.True(t, status.hasResourcesOfDeploy(app), "listed for new deploy step")
+	assert.True(t, status.ResourcesError != "", "listing error after new deploy")
+	assert.Equal(t, status.ResourcesFailures, 1, "listing failures after new deploy")
+}
+
+func TestSetTypeSpecificStatusBacksOffAfterListingError(t *testing.T) {
+	// without a target cluster secret the listing of the resources fails
+	deployer := &kappDeployerDI{crAndSecretClient: newTestClient()}
+	deployData := newTestDeployDataForItem(t, newTestDeployItem(t, &apitypes.KappSpecificData{}))
+
+	app := newTestApp(nil)
+	app.Status.ObservedGeneration = 2
+	app.Status.Deploy = &kappctrl.AppStatusDeploy{UpdatedAt: metav1.Unix(1000, 0)}
+
+	deployer.setTypeSpecificStatus(newTestContext(), app, deployData)
+	status := parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+	assert.True(t, status.ResourcesError != "", "listing error")
+	assert.Equal(t, status.ResourcesFailures, 1, "listing failures")
+	listedAt := status.ResourcesListedAt
+
+	// within the backoff the listing is not repeated
+	deployer.setTypeSpecificStatus(newTestContext(), app, deployData)
+	status = parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+	assert.Equal(t, status.ResourcesFailures, 1, "listing failures within backoff")
+	assert.True(t, status.ResourcesListedAt.Equal(listedAt), "listing time within backoff")
+
+	// after the backoff the listing is repeated and the backoff grows
+	expired := metav1.NewTime(time.Now().Add(-resourcesRetryInterval))
+	status.ResourcesListedAt = &expired
+	setTestStatus(t, deployData, status)
+	deployer.setTypeSpecificStatus(newTestContext(), app, deployData)
+	status = parseStatus(deployData.ProviderStatus.TypeSpecificStatus)
+	assert.Equal(t, status.ResourcesFailures, 2, "listing failures after backoff")
+	assert.Equal(t, status.resourcesBackoff(), 2*resourcesRetryInterval, "backoff")
+	assert.False(t, status.needsResourceListing(app, time.Now()), "listing needed within backoff")
+
+	status.ResourcesFailures = 10
+	assert.Equal(t, status.resourcesBackoff(), maxResourcesRetryInterval, "maximum backoff")
+}
+
+func setTestStatus(t *testing.T, deployData *deployutil.DeployData, status *Status) {
+	statusJSON, err := json.Marshal(status)
+	assert.Nil(t, err, "error")
+	deployData.ProviderStatus.TypeSpecificStatus = &runtime.RawExtension{Raw: statusJSON}
+}
+
+func TestIsDebugStatus(t *testing.T) {
+	deployer := &kappDeployerDI{}
+
+	deployData := newTestDeployDataForItem(t, newTestDeployItem(t, &apitypes.KappSpecificData{DebugStatus: true}))
+	assert.True(t, deployer.isDebugStatus(newTestContext(), deployData), "debug status")
+
+	deployData = newTestDeployDataForItem(t, newTestDeployItem(t, &apitypes.KappSpecificData{}))
+	assert.False(t, deployer.isDebugStatus(newTestContext(), deployData), "debug status")
+}
+
+func TestListDeployedResources(t *testing.T) {
+	app := newTestApp(nil)
+	app.Spec.Cluster = &kappctrl.AppCluster{Namespace: "target-ns"}
+
+	targetClient := newTestTargetClient(
+		newTestAppMeta(t, "target-ns", []schema.GroupKind{
+			{Group: "apps", Kind: "Deployment"},
+			{Group: "", Kind: "ConfigMap"},
+			{Group: "example.org", Kind: "Unknown"},
+		}),
+		newTestDeployment("target-ns", "web", "1"),
+		newTestDeployment("other-ns", "api", "1"),
+		newTestDeployment("target-ns", "foreign", "2"),
+		newTestConfigMap("target-ns", "config", "1"),
+	)
+
+	resources, truncated, err := listDeployedResources(newTestContext(), targetClient, newTestRESTMapper(), app)
+	assert.Nil(t, err, "error")
+	assert.False(t, truncated, "truncated")
+	assert.Equal(t, len(resources), 3, "number of resources")
+	assert.Equal(t, resources[0], Resource{APIVersion: "v1", Kind: "ConfigMap", Namespace: "target-ns", Name: "config"}, "resource")
+	assert.Equal(t, resources[1], Resource{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "other-ns", Name: "api"}, "resource")
+	assert.Equal(t, resources[2], Resource{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "target-ns", Name: "web"}, "resource")
+}
+
+func TestListDeployedResourcesTruncated(t *testing.T) {
+	app := newTestApp(nil)
+
+	objects := []client.Object{newTestAppMeta(t, "default", []schema.GroupKind{{Group: "", Kind: "ConfigMap"}})}
+	for i := 0; i < maxResources+10; i++ {
+		objects = append(objects, newTestConfigMap("default", "config-"+strings.Repeat("a", i+1), "1"))
+	}
+
+	resources, truncated, err := listDeployedResources(newTestContext(), newTestTargetClient(objects...), newTestRESTMapper(), app)
+	assert.Nil(t, err, "error")
+	assert.True(t, truncated, "truncated")
+	assert.Equal(t, len(resources), maxResources, "number of resources")
+}
+
+func TestListDeployedResourcesWithoutAppMeta(t *testing.T) {
+	_, _, err := listDeployedResources(newTestContext(), newTestTargetClient(), newTestRESTMapper(), newTestApp(nil))
+	assert.NotNil(t, err, "error")
+}
+
+func newTestTargetClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func newTestRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion, appsv1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	return mapper
+}
+
+func newTestAppMeta(t *testing.T, namespace string, usedGKs []schema.GroupKind) *corev1.ConfigMap {
+	kappMeta, err := json.Marshal(&appMeta{LabelKey: testKappLabelKey, LabelValue: "1", UsedGKs: &usedGKs})
+	assert.Nil(t, err, "error")
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testAppName + kappAppSuffix,
+			Namespace: namespace,
+		},
+		Data: map[string]string{kappAppMetaKey: string(kappMeta)},
+	}
+}
+
+func newTestDeployment(namespace, name, labelValue string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{testKappLabelKey: labelValue},
+		},
+	}
+}
+
+func newTestConfigMap(namespace, name, labelValue string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{testKappLabelKey: labelValue},
+		},
+	}
+}
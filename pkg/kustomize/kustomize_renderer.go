@@ -0,0 +1,282 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strconv"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/manifest"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	couldNotParse = "could not parse typeSpecificData"
+
+	// overlayDir is the directory of the overlay which adds the generated secrets and the secret patches to the
+	// kustomization of the source
+	overlayDir = "/potter-overlay"
+)
+
+// NewKustomizeDeployerDI returns a deployer which builds a kustomization and applies the result like the manifest
+// deployer.
+func NewKustomizeDeployerDI(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
+	blockObject *synchronize.BlockObject) deployutil.DeployItemDeployer {
+	renderer := &kustomizeRenderer{crAndSecretClient: crAndSecretClient}
+	return manifest.NewManifestDeployerDIWithRenderer(crAndSecretClient, uncachedClient, blockObject, renderer)
+}
+
+type kustomizeRenderer struct {
+	crAndSecretClient client.Client
+}
+
+func (r *kustomizeRenderer) Render(ctx context.Context, deployData *deployutil.DeployData) ([]*unstructured.Unstructured, string, error) {
+	kustomizeSpecificData, err := apitypes.NewKustomizeSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return nil, "", errors.Wrap(err, couldNotParse)
+	}
+
+	fSys, err := loadSource(ctx, kustomizeSpecificData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	kustomizationDir := path.Join(sourceDir, kustomizeSpecificData.Path)
+
+	if len(kustomizeSpecificData.Secrets) > 0 || len(kustomizeSpecificData.SecretPatches) > 0 {
+		err = r.writeOverlay(ctx, deployData, fSys, kustomizationDir, kustomizeSpecificData)
+		if err != nil {
+			return nil, "", err
+		}
+
+		kustomizationDir = overlayDir
+	}
+
+	objects, err := build(fSys, kustomizationDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objects, kustomizeSpecificData.Namespace, nil
+}
+
+func (r *kustomizeRenderer) InternalExport(deployData *deployutil.DeployData) (map[string]apitypes.InternalExportEntry, error) {
+	kustomizeSpecificData, err := apitypes.NewKustomizeSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return nil, err
+	}
+
+	return kustomizeSpecificData.InternalExport, nil
+}
+
+// build runs the kustomization in the given directory. Errors are render errors, because they are caused by the
+// content of the kustomization.
+func build(fSys filesys.FileSystem, kustomizationDir string) ([]*unstructured.Unstructured, error) {
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, kustomizationDir)
+	if err != nil {
+		return nil, deployutil.NewDeployError(deployutil.ErrorCodeRender, errors.Wrap(err, "kustomize build failed"))
+	}
+
+	manifests, err := resMap.AsYaml()
+	if err != nil {
+		return nil, deployutil.NewDeployError(deployutil.ErrorCodeRender, errors.Wrap(err, "could not serialize result of kustomize build"))
+	}
+
+	return manifest.ParseManifests(manifests)
+}
+
+// writeOverlay writes a kustomization which contains the kustomization of the source as base, the generated secrets
+// as resources and the secret patches as patches.
+func (r *kustomizeRenderer) writeOverlay(ctx context.Context, deployData *deployutil.DeployData, fSys filesys.FileSystem,
+	baseDir string, kustomizeSpecificData *apitypes.KustomizeSpecificData) error {
+	kustomization := kustypes.Kustomization{
+		TypeMeta: kustypes.TypeMeta{
+			APIVersion: kustypes.KustomizationVersion,
+			Kind:       kustypes.KustomizationKind,
+		},
+		Resources: []string{".." + baseDir},
+	}
+
+	for i := range kustomizeSpecificData.Secrets {
+		secret, err := r.generateSecret(ctx, deployData, &kustomizeSpecificData.Secrets[i])
+		if err != nil {
+			return err
+		}
+
+		fileName := "secret-" + strconv.Itoa(i) + ".yaml"
+		if err = r.writeYaml(fSys, path.Join(overlayDir, fileName), secret); err != nil {
+			return err
+		}
+
+		kustomization.Resources = append(kustomization.Resources, fileName)
+	}
+
+	for i := range kustomizeSpecificData.SecretPatches {
+		secretPatch := &kustomizeSpecificData.SecretPatches[i]
+
+		data, err := r.readNamedSecret(ctx, deployData, secretPatch.SecretRef)
+		if err != nil {
+			return err
+		}
+
+		patch, ok := data[secretPatch.Key]
+		if !ok {
+			return errors.Errorf("named secret values %s have no key %s", secretPatch.SecretRef, secretPatch.Key)
+		}
+
+		fileName := "patch-" + strconv.Itoa(i) + ".yaml"
+		if err = fSys.WriteFile(path.Join(overlayDir, fileName), patch); err != nil {
+			return err
+		}
+
+		kustomization.Patches = append(kustomization.Patches, kustypes.Patch{
+			Path:   fileName,
+			Target: toSelector(secretPatch.Target),
+		})
+	}
+
+	return r.writeYaml(fSys, path.Join(overlayDir, "kustomization.yaml"), &kustomization)
+}
+
+// generateSecret returns a secret with the named secret values referenced by SecretRef, or, if SecretRef is empty,
+// with the secret values of the application. Every top level key of the secret values becomes a key of the secret.
+func (r *kustomizeRenderer) generateSecret(ctx context.Context, deployData *deployutil.DeployData,
+	kustomizeSecret *apitypes.KustomizeSecret) (*corev1.Secret, error) {
+	var data map[string][]byte
+	var err error
+	if kustomizeSecret.SecretRef != "" {
+		data, err = r.readNamedSecret(ctx, deployData, kustomizeSecret.SecretRef)
+	} else {
+		data, err = r.readSecretValues(ctx, deployData)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kustomizeSecret.Name,
+			Namespace: kustomizeSecret.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}, nil
+}
+
+func (r *kustomizeRenderer) readSecretValues(ctx context.Context, deployData *deployutil.DeployData) (map[string][]byte, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	internalSecretName := deployData.Configuration.DeploymentConfig.InternalSecretName
+	if internalSecretName == "" {
+		return nil, errors.New("a secret without secretRef requires secret values")
+	}
+
+	secret, err := r.readSecret(ctx, deployData, internalSecretName)
+	if err != nil {
+		msg := "could not read secret values"
+		log.Error(err, msg, util.LogKeySecretName, internalSecretName)
+		return nil, errors.Wrap(err, msg)
+	}
+
+	var secretValues map[string]interface{}
+	err = json.Unmarshal(secret.Data[util.SecretValuesKey], &secretValues)
+	if err != nil {
+		msg := "could not unmarshal secret values"
+		log.Error(err, msg, util.LogKeySecretName, internalSecretName)
+		return nil, errors.Wrap(err, msg)
+	}
+
+	data := make(map[string][]byte)
+	for key, value := range secretValues {
+		if stringValue, ok := value.(string); ok {
+			data[key] = []byte(stringValue)
+			continue
+		}
+
+		rawValue, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal secret value "+key)
+		}
+		data[key] = rawValue
+	}
+
+	return data, nil
+}
+
+func (r *kustomizeRenderer) readNamedSecret(ctx context.Context, deployData *deployutil.DeployData, logicalSecretName string) (map[string][]byte, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	internalSecretName, ok := deployData.Configuration.DeploymentConfig.NamedInternalSecretNames[logicalSecretName]
+	if !ok {
+		return nil, errors.New("no named secret values found for " + logicalSecretName)
+	}
+
+	secret, err := r.readSecret(ctx, deployData, internalSecretName)
+	if err != nil {
+		msg := "could not read named secret values for " + logicalSecretName
+		log.Error(err, msg, util.LogKeySecretName, internalSecretName)
+		return nil, errors.Wrap(err, msg)
+	}
+
+	return secret.Data, nil
+}
+
+func (r *kustomizeRenderer) readSecret(ctx context.Context, deployData *deployutil.DeployData, secretName string) (*corev1.Secret, error) {
+	secretKey := types.NamespacedName{
+		Name:      secretName,
+		Namespace: deployData.GetNamespace(),
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.crAndSecretClient.Get(ctx, secretKey, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+func (r *kustomizeRenderer) writeYaml(fSys filesys.FileSystem, filePath string, obj interface{}) error {
+	content, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	return fSys.WriteFile(filePath, content)
+}
+
+func toSelector(target *apitypes.KustomizePatchTarget) *kustypes.Selector {
+	if target == nil {
+		return nil
+	}
+
+	selector := &kustypes.Selector{
+		AnnotationSelector: target.AnnotationSelector,
+		LabelSelector:      target.LabelSelector,
+	}
+	selector.Group = target.Group
+	selector.Version = target.Version
+	selector.Kind = target.Kind
+	selector.Name = target.Name
+	selector.Namespace = target.Namespace
+
+	return selector
+}
@@ -0,0 +1,178 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	hubtesting "github.com/gardener/potter-controller/pkg/testing"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testNamespace = "garden-test"
+
+	testKustomization = `
+namespace: app
+resources:
+- deployment.yaml
+`
+
+	testDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0.0
+`
+
+	testReplicasPatch = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+`
+)
+
+func TestRenderFiles(t *testing.T) {
+	typeSpecificData := &apitypes.KustomizeSpecificData{
+		Namespace: "default-ns",
+		Files: map[string]string{
+			"base/kustomization.yaml": testKustomization,
+			"base/deployment.yaml":    testDeployment,
+		},
+		Path: "base",
+	}
+
+	renderer := &kustomizeRenderer{crAndSecretClient: fake.NewFakeClientWithScheme(runtime.NewScheme())} // nolint
+	objects, namespace, err := renderer.Render(hubtesting.CreateTestContext(), newTestDeployData(t, typeSpecificData, nil))
+	assert.Nil(t, err, "error")
+	assert.Equal(t, namespace, "default-ns", "namespace")
+	assert.Equal(t, len(objects), 1, "number of objects")
+	assert.Equal(t, objects[0].GetKind(), "Deployment", "kind")
+	assert.Equal(t, objects[0].GetNamespace(), "app", "namespace of object")
+}
+
+func TestRenderWithSecrets(t *testing.T) {
+	typeSpecificData := &apitypes.KustomizeSpecificData{
+		Files: map[string]string{
+			"kustomization.yaml": testKustomization,
+			"deployment.yaml":    testDeployment,
+		},
+		Secrets: []apitypes.KustomizeSecret{
+			{Name: "credentials"},
+			{Name: "tls", SecretRef: "certs"},
+		},
+		SecretPatches: []apitypes.KustomizeSecretPatch{
+			{SecretRef: "patches", Key: "replicas"},
+		},
+	}
+
+	deploymentConfig := &hubv1.DeploymentConfig{
+		InternalSecretName: "secret-values",
+		NamedInternalSecretNames: map[string]string{
+			"certs":   "named-secret-certs",
+			"patches": "named-secret-patches",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	crAndSecretClient := fake.NewFakeClientWithScheme(scheme, // nolint
+		newTestSecret("secret-values", util.SecretValuesKey, `{"user":"admin","ports":[1,2]}`),
+		newTestSecret("named-secret-certs", "tls.crt", "cert"),
+		newTestSecret("named-secret-patches", "replicas", testReplicasPatch),
+	)
+
+	renderer := &kustomizeRenderer{crAndSecretClient: crAndSecretClient}
+	objects, _, err := renderer.Render(hubtesting.CreateTestContext(), newTestDeployData(t, typeSpecificData, deploymentConfig))
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(objects), 3, "number of objects")
+
+	objectsByName := map[string]*unstructured.Unstructured{}
+	for _, obj := range objects {
+		objectsByName[obj.GetName()] = obj
+	}
+
+	replicas, _, _ := unstructured.NestedFloat64(objectsByName["app"].Object, "spec", "replicas")
+	assert.Equal(t, replicas, float64(3), "patched replicas")
+
+	user, _, _ := unstructured.NestedString(objectsByName["credentials"].Object, "data", "user")
+	assert.Equal(t, user, "YWRtaW4=", "secret value user")
+	ports, _, _ := unstructured.NestedString(objectsByName["credentials"].Object, "data", "ports")
+	assert.Equal(t, ports, "WzEsMl0=", "secret value ports")
+	assert.Equal(t, objectsByName["credentials"].GetNamespace(), "", "namespace of secret")
+
+	cert, _, _ := unstructured.NestedString(objectsByName["tls"].Object, "data", "tls.crt")
+	assert.Equal(t, cert, "Y2VydA==", "named secret value")
+}
+
+func TestRenderWithMissingNamedSecret(t *testing.T) {
+	typeSpecificData := &apitypes.KustomizeSpecificData{
+		Files: map[string]string{
+			"kustomization.yaml": testKustomization,
+			"deployment.yaml":    testDeployment,
+		},
+		SecretPatches: []apitypes.KustomizeSecretPatch{
+			{SecretRef: "patches", Key: "replicas"},
+		},
+	}
+
+	renderer := &kustomizeRenderer{crAndSecretClient: fake.NewFakeClientWithScheme(runtime.NewScheme())} // nolint
+	_, _, err := renderer.Render(hubtesting.CreateTestContext(), newTestDeployData(t, typeSpecificData, &hubv1.DeploymentConfig{}))
+	assert.NotNil(t, err, "error")
+}
+
+func TestRenderInvalidKustomization(t *testing.T) {
+	typeSpecificData := &apitypes.KustomizeSpecificData{
+		Files: map[string]string{
+			"kustomization.yaml": "resources:\n- missing.yaml\n",
+		},
+	}
+
+	renderer := &kustomizeRenderer{crAndSecretClient: fake.NewFakeClientWithScheme(runtime.NewScheme())} // nolint
+	_, _, err := renderer.Render(hubtesting.CreateTestContext(), newTestDeployData(t, typeSpecificData, nil))
+	assert.NotNil(t, err, "error")
+
+	deployErr, ok := err.(*deployutil.DeployError)
+	assert.True(t, ok, "error is a deploy error")
+	assert.Equal(t, deployErr.Code, deployutil.ErrorCodeRender, "error code")
+}
+
+func TestWriteSourceFileOutsideOfSource(t *testing.T) {
+	err := loadFiles(nil, map[string]string{"../kustomization.yaml": ""})
+	assert.NotNil(t, err, "error")
+}
+
+func newTestDeployData(t *testing.T, typeSpecificData *apitypes.KustomizeSpecificData, deploymentConfig *hubv1.DeploymentConfig) *deployutil.DeployData {
+	deployItem := hubtesting.CreateDeployItemForConfig(t, "test-item", testNamespace, typeSpecificData, deploymentConfig)
+	return hubtesting.CreateDeployData(t, deployItem)
+}
+
+func newTestSecret(name, key, value string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{
+			key: []byte(value),
+		},
+	}
+}
@@ -0,0 +1,364 @@
+package kustomize
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/api/filesys"
+)
+
+const (
+	// sourceDir is the directory of the in-memory file system into which the source is loaded
+	sourceDir = "/source"
+
+	downloadTimeout = 60 * time.Second
+	gitTimeout      = 2 * time.Minute
+
+	// maxSourceSize and maxSourceFiles limit the total size and the number of the files of a source, so that an archive
+	// with a high compression ratio or a large repository cannot exhaust the memory of the controller
+	maxSourceSize  = 50 * 1024 * 1024
+	maxSourceFiles = 1000
+
+	// maxCloneSize limits the bytes written by a git clone, which contains the packed objects and the checked out files
+	maxCloneSize = 2 * maxSourceSize
+)
+
+// defaultGitCloneDepth is the depth of git clones; only the files of the ref are needed, but not its history
+const defaultGitCloneDepth = 1
+
+var gitCloneDepth = defaultGitCloneDepth
+
+// sourceLimiter counts the files and bytes which are loaded from a source
+type sourceLimiter struct {
+	files int
+	size  int64
+}
+
+// add counts a file and returns an error if the limits of the source are exceeded
+func (l *sourceLimiter) add(size int64) error {
+	l.files++
+	l.size += size
+
+	if l.files > maxSourceFiles {
+		return fmt.Errorf("kustomization source contains more than %d files", maxSourceFiles)
+	}
+
+	if l.size > maxSourceSize {
+		return fmt.Errorf("kustomization source is larger than %d bytes", maxSourceSize)
+	}
+
+	return nil
+}
+
+// remaining returns the number of bytes which may still be loaded
+func (l *sourceLimiter) remaining() int64 {
+	return maxSourceSize - l.size
+}
+
+// loadSource loads the files of the kustomization source into an in-memory file system below sourceDir.
+func loadSource(ctx context.Context, kustomizeSpecificData *apitypes.KustomizeSpecificData) (filesys.FileSystem, error) {
+	fSys := filesys.MakeFsInMemory()
+
+	var err error
+	switch {
+	case kustomizeSpecificData.Tarball != nil:
+		err = loadTarball(ctx, fSys, kustomizeSpecificData.Tarball)
+	case kustomizeSpecificData.Git != nil:
+		err = loadGitRepository(ctx, fSys, kustomizeSpecificData.Git)
+	default:
+		err = loadFiles(fSys, kustomizeSpecificData.Files)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fSys, nil
+}
+
+func loadFiles(fSys filesys.FileSystem, files map[string]string) error {
+	for filePath, content := range files {
+		if err := writeSourceFile(fSys, filePath, []byte(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadTarball(ctx context.Context, fSys filesys.FileSystem, tarball *apitypes.KustomizeTarball) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tarball.URL, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not create request for kustomization tarball")
+	}
+
+	httpClient, err := newHTTPClient(tarball.CustomCAData)
+	if err != nil {
+		return err
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "kustomization tarball download request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("kustomization tarball download request failed with status code %v", res.StatusCode)
+	}
+
+	gzipReader, err := gzip.NewReader(io.LimitReader(res.Body, maxSourceSize))
+	if err != nil {
+		return errors.Wrap(err, "kustomization tarball is not gzipped")
+	}
+	defer gzipReader.Close()
+
+	limiter := &sourceLimiter{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "could not read kustomization tarball")
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// the size in the header is not trusted; at most one byte more than allowed is read to detect the violation
+		content, err := ioutil.ReadAll(io.LimitReader(tarReader, limiter.remaining()+1))
+		if err != nil {
+			return errors.Wrap(err, "could not read kustomization tarball")
+		}
+
+		if err := limiter.add(int64(len(content))); err != nil {
+			return err
+		}
+
+		if err := writeSourceFile(fSys, header.Name, content); err != nil {
+			return err
+		}
+	}
+}
+
+func newHTTPClient(customCAData string) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if customCAData != "" {
+		caCertPool, err := x509.SystemCertPool()
+		if err != nil {
+			caCertPool = x509.NewCertPool()
+		}
+
+		if ok := caCertPool.AppendCertsFromPEM([]byte(customCAData)); !ok {
+			return nil, errors.New("could not append custom CA data")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	return &http.Client{
+		Timeout: downloadTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// loadGitRepository clones the repository into a temporary directory and copies its files into the in-memory file
+// system. The clone uses go-git, because the image of the controller contains no git command line tool.
+func loadGitRepository(ctx context.Context, fSys filesys.FileSystem, gitSource *apitypes.KustomizeGit) error {
+	if err := apitypes.ValidateGitURL(gitSource.URL); err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "kustomize-")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary directory for git repository")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	repoDir, err := cloneGitRepository(ctx, tmpDir, gitSource)
+	if err != nil {
+		return err
+	}
+
+	limiter := &sourceLimiter{}
+	return filepath.Walk(repoDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if err = limiter.add(info.Size()); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(repoDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		return writeSourceFile(fSys, filepath.ToSlash(relPath), content)
+	})
+}
+
+// cloneGitRepository clones the ref of the repository with depth 1 below tmpDir and returns the directory of the
+// clone. The ref is a branch or a tag, as with "git clone --branch"; without ref the default branch is cloned. The clone
+// is aborted as soon as it has written more than maxCloneSize bytes.
+func cloneGitRepository(ctx context.Context, tmpDir string, gitSource *apitypes.KustomizeGit) (string, error) {
+	referenceNames := []plumbing.ReferenceName{""}
+	if gitSource.Ref != "" {
+		referenceNames = []plumbing.ReferenceName{
+			plumbing.NewBranchReferenceName(gitSource.Ref),
+			plumbing.NewTagReferenceName(gitSource.Ref),
+		}
+	}
+
+	var err error
+	for i, referenceName := range referenceNames {
+		repoDir := filepath.Join(tmpDir, fmt.Sprintf("repo-%d", i))
+		limiter := &writeLimiter{limit: maxCloneSize}
+		storage := filesystem.NewStorage(newLimitedFilesystem(osfs.New(filepath.Join(repoDir, git.GitDirName)), limiter),
+			cache.NewObjectLRUDefault())
+
+		_, err = git.CloneContext(ctx, storage, newLimitedFilesystem(osfs.New(repoDir), limiter), &git.CloneOptions{
+			URL:           gitSource.URL,
+			ReferenceName: referenceName,
+			SingleBranch:  true,
+			Depth:         gitCloneDepth,
+			Tags:          git.NoTags,
+		})
+		if err == nil {
+			return repoDir, nil
+		} else if limiter.exceeded() {
+			return "", fmt.Errorf("git repository is larger than %d bytes", maxCloneSize)
+		} else if !errors.As(err, &git.NoMatchingRefSpecError{}) {
+			break
+		}
+	}
+
+	return "", errors.Wrap(err, "could not clone git repository")
+}
+
+// writeLimiter counts the bytes written to the file systems of a git clone
+type writeLimiter struct {
+	written int64
+	limit   int64
+}
+
+var errWriteLimitExceeded = errors.New("write limit exceeded")
+
+func (l *writeLimiter) add(n int) error {
+	if atomic.AddInt64(&l.written, int64(n)) > l.limit {
+		return errWriteLimitExceeded
+	}
+	return nil
+}
+
+func (l *writeLimiter) exceeded() bool {
+	return atomic.LoadInt64(&l.written) > l.limit
+}
+
+// limitedFilesystem fails the writes to its files once the limiter is exceeded, so that a clone of a large repository
+// is aborted instead of filling the disk
+type limitedFilesystem struct {
+	billy.Filesystem
+	limiter *writeLimiter
+}
+
+func newLimitedFilesystem(fs billy.Filesystem, limiter *writeLimiter) billy.Filesystem {
+	return &limitedFilesystem{Filesystem: fs, limiter: limiter}
+}
+
+func (fs *limitedFilesystem) Create(filename string) (billy.File, error) {
+	return fs.wrap(fs.Filesystem.Create(filename))
+}
+
+func (fs *limitedFilesystem) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	return fs.wrap(fs.Filesystem.OpenFile(filename, flag, perm))
+}
+
+func (fs *limitedFilesystem) TempFile(dir, prefix string) (billy.File, error) {
+	return fs.wrap(fs.Filesystem.TempFile(dir, prefix))
+}
+
+func (fs *limitedFilesystem) Chroot(path string) (billy.Filesystem, error) {
+	chrootFS, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+	return newLimitedFilesystem(chrootFS, fs.limiter), nil
+}
+
+func (fs *limitedFilesystem) wrap(file billy.File, err error) (billy.File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFile{File: file, limiter: fs.limiter}, nil
+}
+
+type limitedFile struct {
+	billy.File
+	limiter *writeLimiter
+}
+
+func (f *limitedFile) Write(p []byte) (int, error) {
+	if err := f.limiter.add(len(p)); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+// writeSourceFile writes a file of the source. Paths leaving the source directory are rejected.
+func writeSourceFile(fSys filesys.FileSystem, filePath string, content []byte) error {
+	fullPath := path.Join(sourceDir, filePath)
+	if !strings.HasPrefix(fullPath, sourceDir+"/") {
+		return errors.Errorf("file %s is outside of the kustomization source", filePath)
+	}
+
+	return fSys.WriteFile(fullPath, content)
+}
@@ -0,0 +1,257 @@
+package kustomize
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+
+	"github.com/arschles/assert"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// testGitURL is the url of the test repository, which is served in-process by the test protocol
+const testGitURL = "test://example.com/app.git"
+
+func TestLoadTarball(t *testing.T) {
+	server := newTestTarballServer(t, map[string][]byte{
+		"kustomization.yaml": []byte(testKustomization),
+		"deployment.yaml":    []byte(testDeployment),
+	})
+	defer server.Close()
+
+	fSys, err := loadSource(context.Background(), &apitypes.KustomizeSpecificData{
+		Tarball: &apitypes.KustomizeTarball{URL: server.URL},
+	})
+	assert.NoErr(t, err)
+	assertSourceFile(t, fSys.ReadFile, "kustomization.yaml", testKustomization)
+	assertSourceFile(t, fSys.ReadFile, "deployment.yaml", testDeployment)
+}
+
+func TestLoadTarballLimits(t *testing.T) {
+	manyFiles := map[string][]byte{}
+	for i := 0; i <= maxSourceFiles; i++ {
+		manyFiles[fmt.Sprintf("file-%d.yaml", i)] = nil
+	}
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+	}{
+		{
+			// compresses to less than 100 kilobytes
+			name:  "too large",
+			files: map[string][]byte{"large.yaml": make([]byte, maxSourceSize+1)},
+		},
+		{
+			name: "too large in total",
+			files: map[string][]byte{
+				"first.yaml":  make([]byte, maxSourceSize/2+1),
+				"second.yaml": make([]byte, maxSourceSize/2+1),
+			},
+		},
+		{
+			name:  "too many files",
+			files: manyFiles,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestTarballServer(t, tt.files)
+			defer server.Close()
+
+			_, err := loadSource(context.Background(), &apitypes.KustomizeSpecificData{
+				Tarball: &apitypes.KustomizeTarball{URL: server.URL},
+			})
+			assert.NotNil(t, err, "error")
+		})
+	}
+}
+
+func TestLoadGitRepository(t *testing.T) {
+	installTestGitProtocol(t, map[string]string{
+		"main":      testKustomization,
+		"v1.0.0":    "# tagged\n" + testKustomization,
+		"feature-1": "# feature\n" + testKustomization,
+	})
+
+	tests := []struct {
+		name                  string
+		ref                   string
+		expectedKustomization string
+		expectedError         bool
+	}{
+		{name: "default branch", expectedKustomization: testKustomization},
+		{name: "branch", ref: "feature-1", expectedKustomization: "# feature\n" + testKustomization},
+		{name: "tag", ref: "v1.0.0", expectedKustomization: "# tagged\n" + testKustomization},
+		{name: "unknown ref", ref: "unknown", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fSys, err := loadSource(context.Background(), &apitypes.KustomizeSpecificData{
+				Git: &apitypes.KustomizeGit{URL: testGitURL, Ref: tt.ref},
+			})
+
+			if tt.expectedError {
+				assert.NotNil(t, err, "error")
+				return
+			}
+
+			assert.NoErr(t, err)
+			assertSourceFile(t, fSys.ReadFile, "kustomization.yaml", tt.expectedKustomization)
+			assertSourceFile(t, fSys.ReadFile, "deployment.yaml", testDeployment)
+			assert.False(t, fSys.Exists(sourceDir+"/.git"), "git directory copied")
+		})
+	}
+}
+
+func TestLoadUnknownGitRepository(t *testing.T) {
+	installTestGitProtocol(t, map[string]string{"main": testKustomization})
+
+	_, err := loadSource(context.Background(), &apitypes.KustomizeSpecificData{
+		Git: &apitypes.KustomizeGit{URL: "test://example.com/unknown.git"},
+	})
+	assert.NotNil(t, err, "error")
+}
+
+func TestLoadGitRepositoryWithForbiddenScheme(t *testing.T) {
+	for _, url := range []string{"file:///etc", "/etc", "http://example.com/app.git"} {
+		_, err := loadSource(context.Background(), &apitypes.KustomizeSpecificData{
+			Git: &apitypes.KustomizeGit{URL: url},
+		})
+		assert.NotNil(t, err, "error for "+url)
+	}
+}
+
+func TestLimitedFilesystem(t *testing.T) {
+	limiter := &writeLimiter{limit: 10}
+	fs := newLimitedFilesystem(memfs.New(), limiter)
+
+	file, err := fs.Create("first")
+	assert.NoErr(t, err)
+	_, err = file.Write(make([]byte, 6))
+	assert.NoErr(t, err)
+
+	chrootFS, err := fs.Chroot("dir")
+	assert.NoErr(t, err)
+	file, err = chrootFS.TempFile("", "second")
+	assert.NoErr(t, err)
+	_, err = file.Write(make([]byte, 6))
+	assert.NotNil(t, err, "error when the limit is exceeded")
+	assert.True(t, limiter.exceeded(), "limit exceeded")
+}
+
+func assertSourceFile(t *testing.T, readFile func(string) ([]byte, error), name, expectedContent string) {
+	content, err := readFile(sourceDir + "/" + name)
+	assert.NoErr(t, err)
+	assert.Equal(t, string(content), expectedContent, "content of "+name)
+}
+
+func newTestTarballServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, content := range files {
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		assert.NoErr(t, err)
+		_, err = tarWriter.Write(content)
+		assert.NoErr(t, err)
+	}
+
+	assert.NoErr(t, tarWriter.Close())
+	assert.NoErr(t, gzipWriter.Close())
+	tarball := buf.Bytes()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(tarball)
+	}))
+}
+
+// installTestGitProtocol serves a repository with the url testGitURL in-process. The kustomization of each branch or
+// tag is given; "main" is the default branch, and refs starting with "v" are tags.
+func installTestGitProtocol(t *testing.T, kustomizations map[string]string) {
+	storage := memory.NewStorage()
+	worktreeFS := memfs.New()
+	repo, err := git.Init(storage, worktreeFS)
+	assert.NoErr(t, err)
+
+	worktree, err := repo.Worktree()
+	assert.NoErr(t, err)
+
+	writeFile := func(name, content string) {
+		file, err := worktreeFS.Create(name)
+		assert.NoErr(t, err)
+		_, err = file.Write([]byte(content))
+		assert.NoErr(t, err)
+		assert.NoErr(t, file.Close())
+		_, err = worktree.Add(name)
+		assert.NoErr(t, err)
+	}
+
+	commit := func(message string) plumbing.Hash {
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		assert.NoErr(t, err)
+		return hash
+	}
+
+	writeFile("deployment.yaml", testDeployment)
+	writeFile("kustomization.yaml", kustomizations["main"])
+	mainHash := commit("main")
+
+	for ref, kustomization := range kustomizations {
+		if ref == "main" {
+			continue
+		}
+
+		err = worktree.Checkout(&git.CheckoutOptions{Hash: mainHash, Force: true})
+		assert.NoErr(t, err)
+		writeFile("kustomization.yaml", kustomization)
+		hash := commit(ref)
+
+		if ref[0] == 'v' {
+			_, err = repo.CreateTag(ref, hash, nil)
+		} else {
+			err = storage.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(ref), hash))
+		}
+		assert.NoErr(t, err)
+	}
+
+	// the commits are made on the default branch; it is reset to its own commit
+	err = storage.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), mainHash))
+	assert.NoErr(t, err)
+	err = storage.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("master")))
+	assert.NoErr(t, err)
+
+	endpoint, err := transport.NewEndpoint(testGitURL)
+	assert.NoErr(t, err)
+
+	// the in-process server does not support shallow clones
+	gitCloneDepth = 0
+	allowedGitSchemes := apitypes.AllowedGitSchemes
+	apitypes.AllowedGitSchemes = append([]string{"test"}, allowedGitSchemes...)
+	client.InstallProtocol("test", server.NewClient(server.MapLoader{endpoint.String(): storage}))
+	t.Cleanup(func() {
+		gitCloneDepth = defaultGitCloneDepth
+		apitypes.AllowedGitSchemes = allowedGitSchemes
+		client.InstallProtocol("test", nil)
+	})
+}
@@ -0,0 +1,143 @@
+package manifest
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gardener/potter-controller/pkg/deployutil"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Status is stored as type specific status of a deploy item with config type manifest or kustomize.
+type Status struct {
+	Inventory Inventory `json:"inventory,omitempty"`
+}
+
+// InventoryEntry identifies an object on the target cluster which was applied by the manifest deployer.
+type InventoryEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+type Inventory []InventoryEntry
+
+func newInventoryEntry(obj *unstructured.Unstructured) InventoryEntry {
+	return InventoryEntry{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+func (e *InventoryEntry) GroupKind() schema.GroupKind {
+	return schema.FromAPIVersionAndKind(e.APIVersion, e.Kind).GroupKind()
+}
+
+// sameObject compares entries independent of the api version, so that a change of the api version of an object
+// does not lead to its deletion.
+func (e *InventoryEntry) sameObject(other *InventoryEntry) bool {
+	return e.GroupKind() == other.GroupKind() && e.Namespace == other.Namespace && e.Name == other.Name
+}
+
+func (e *InventoryEntry) String() string {
+	if e.Namespace == "" {
+		return e.Kind + " " + e.Name
+	}
+	return e.Kind + " " + e.Namespace + "/" + e.Name
+}
+
+func (e *InventoryEntry) toUnstructured() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(e.APIVersion)
+	obj.SetKind(e.Kind)
+	obj.SetNamespace(e.Namespace)
+	obj.SetName(e.Name)
+	return obj
+}
+
+func (e *InventoryEntry) toBasicKubernetesObject() deployutil.BasicKubernetesObject {
+	return deployutil.BasicKubernetesObject{
+		APIVersion: e.APIVersion,
+		Kind:       e.Kind,
+		ObjectMeta: types.NamespacedName{
+			Namespace: e.Namespace,
+			Name:      e.Name,
+		},
+	}
+}
+
+func (inv Inventory) contains(entry *InventoryEntry) bool {
+	for i := range inv {
+		if inv[i].sameObject(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns the entries of the inventory which are not contained in the other inventory.
+func (inv Inventory) without(other Inventory) Inventory {
+	var result Inventory
+	for i := range inv {
+		if !other.contains(&inv[i]) {
+			result = append(result, inv[i])
+		}
+	}
+	return result
+}
+
+// union returns the entries of both inventories without duplicates. Entries of the other inventory take precedence.
+func (inv Inventory) union(other Inventory) Inventory {
+	result := append(Inventory{}, other...)
+	return append(result, inv.without(other)...)
+}
+
+func (inv Inventory) basicKubernetesObjects(filter func(object *deployutil.BasicKubernetesObject) bool) []deployutil.BasicKubernetesObject {
+	var result []deployutil.BasicKubernetesObject
+	for i := range inv {
+		obj := inv[i].toBasicKubernetesObject()
+		if filter(&obj) {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// sortForDeletion sorts the inventory such that namespaces and custom resource definitions are deleted last.
+func (inv Inventory) sortForDeletion() {
+	sort.SliceStable(inv, func(i, j int) bool {
+		return kindPriority(inv[i].Kind) > kindPriority(inv[j].Kind)
+	})
+}
+
+func readStatus(deployData *deployutil.DeployData) (*Status, error) {
+	status := &Status{}
+
+	typeSpecificStatus := deployData.ProviderStatus.TypeSpecificStatus
+	if typeSpecificStatus == nil || len(typeSpecificStatus.Raw) == 0 {
+		return status, nil
+	}
+
+	if err := json.Unmarshal(typeSpecificStatus.Raw, status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func writeStatus(deployData *deployutil.DeployData, status *Status) error {
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	deployData.ProviderStatus.TypeSpecificStatus = &runtime.RawExtension{Raw: raw}
+	return nil
+}
@@ -0,0 +1,539 @@
+package manifest
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	couldNotParse = "could not parse typeSpecificData"
+
+	defaultNamespace = "default"
+
+	// duration for which the clusterbom is blocked while the manifests are applied or deleted
+	reblockDuration = 6 * time.Minute
+)
+
+// Renderer computes the objects which are applied to the target cluster. The manifest deployer is reused by config
+// types which only differ in the way how the objects are computed.
+type Renderer interface {
+	// Render returns the objects of a deploy item and the namespace for namespaced objects without namespace.
+	Render(ctx context.Context, deployData *deployutil.DeployData) ([]*unstructured.Unstructured, string, error)
+	// InternalExport returns the export entries of the type specific data of a deploy item.
+	InternalExport(deployData *deployutil.DeployData) (map[string]apitypes.InternalExportEntry, error)
+}
+
+type manifestDeployerDI struct {
+	crAndSecretClient client.Client
+	uncachedClient    synchronize.UncachedClient
+	blockObject       *synchronize.BlockObject
+	renderer          Renderer
+}
+
+func NewManifestDeployerDI(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
+	blockObject *synchronize.BlockObject) deployutil.DeployItemDeployer {
+	return NewManifestDeployerDIWithRenderer(crAndSecretClient, uncachedClient, blockObject, newManifestRenderer(uncachedClient))
+}
+
+func NewManifestDeployerDIWithRenderer(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
+	blockObject *synchronize.BlockObject, renderer Renderer) deployutil.DeployItemDeployer {
+	return &manifestDeployerDI{
+		crAndSecretClient: crAndSecretClient,
+		uncachedClient:    uncachedClient,
+		blockObject:       blockObject,
+		renderer:          renderer,
+	}
+}
+
+func (r *manifestDeployerDI) ProcessNewOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	inventory, err := r.processItem(ctx, deployData)
+	now := metav1.Now()
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.ClusterUnreachableError:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
+				"Deployment failed for application "+configID+", because cluster is unreachable", err)
+			deployData.SetStatusForUnreachableCluster()
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Deployment failed for application "+configID, err)
+			deployData.SetFailedStatus(err, 1, now)
+			r.setInventory(ctx, deployData, inventory)
+		}
+	} else {
+		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Deployment done for application "+configID)
+		deployData.SetStatus(util.StateOk, r.successDescription(deployData), 1, now)
+		r.setInventory(ctx, deployData, inventory)
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, now)
+}
+
+func (r *manifestDeployerDI) ReconcileOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	log := util.GetLoggerFromContext(ctx)
+	configID := deployData.Configuration.DeploymentConfig.ID
+	log.V(util.LogLevelDebug).Info("reconcile",
+		"observedGeneration", deployData.GetObservedGeneration(),
+		"generation", deployData.GetGeneration())
+
+	inventory, err := r.processItem(ctx, deployData)
+	now := metav1.Now()
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.ClusterUnreachableError:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
+				"Reconcile failed for application "+configID+", because cluster is unreachable", err)
+			deployData.SetStatusForUnreachableCluster()
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Reconcile failed for application "+configID, err)
+			deployData.SetFailedStatus(err, 1, now)
+			r.setInventory(ctx, deployData, inventory)
+		}
+	} else {
+		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Reconcile done for application "+configID)
+		deployData.SetStatus(util.StateOk, r.successDescription(deployData), 1, now)
+		r.setInventory(ctx, deployData, inventory)
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, now)
+}
+
+func (r *manifestDeployerDI) RetryFailedOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+	lastOp := deployData.ProviderStatus.LastOperation
+
+	inventory, err := r.processItem(ctx, deployData)
+	now := metav1.Now()
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.ClusterUnreachableError:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
+				"Retry of deployment failed for application "+configID+", because cluster is unreachable", err)
+			deployData.SetStatusForUnreachableCluster()
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment,
+				"Retry of deployment failed for application "+configID, err)
+			deployData.SetFailedStatus(err, lastOp.NumberOfTries+1, now)
+			r.setInventory(ctx, deployData, inventory)
+		}
+	} else {
+		deployutil.LogSuccess(ctx, deployutil.ReasonSuccessDeployment, "Retry of deployment done for application "+configID)
+		deployData.SetStatus(util.StateOk, r.successDescription(deployData), 1, now)
+		r.setInventory(ctx, deployData, inventory)
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, now)
+}
+
+func (r *manifestDeployerDI) ProcessPendingOperation(ctx context.Context, deployData *deployutil.DeployData) {
+	configID := deployData.Configuration.DeploymentConfig.ID
+
+	// check reachability of target cluster
+	secretKey := deployData.GetSecretKey()
+	_, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		switch err.(type) {
+		case *deployutil.ClusterUnreachableError:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedClusterUnreachable,
+				"Readiness check failed for application "+configID+", because cluster is unreachable", err)
+		default:
+			deployutil.LogHubFailure(ctx, deployutil.ReasonFailedDeployment, "Readiness check failed for application "+configID, err)
+		}
+		deployData.SetStatusForUnreachableCluster()
+	} else {
+		deployData.SetStatusForReachableCluster()
+	}
+
+	r.computeReadinessAndExport(ctx, deployData, metav1.Now())
+}
+
+func (r *manifestDeployerDI) Cleanup(ctx context.Context, deployData *deployutil.DeployData, clusterExists bool) error {
+	return nil
+}
+
+func (r *manifestDeployerDI) Preprocess(ctx context.Context, deployData *deployutil.DeployData) {
+}
+
+// processItem applies the manifests and prunes the objects of the previous inventory which are no longer contained in
+// the manifests. For a remove operation all objects of the inventory are deleted. Returns the inventory of objects
+// which might exist on the target cluster; in case of an error this includes the objects of the previous inventory.
+func (r *manifestDeployerDI) processItem(ctx context.Context, deployData *deployutil.DeployData) (Inventory, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	status, err := readStatus(deployData)
+	if err != nil {
+		log.Error(err, "could not parse inventory")
+		return nil, errors.Wrap(err, "could not parse inventory")
+	}
+	oldInventory := status.Inventory
+
+	secretKey := deployData.GetSecretKey()
+	targetClient, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		return oldInventory, err
+	}
+
+	clusterBomKey := util.GetClusterBomKeyFromDeployItemKey(deployData.GetDeployItemKey())
+	_, err = r.blockObject.Reblock(ctx, clusterBomKey, r.uncachedClient, reblockDuration, true)
+	if err != nil {
+		return oldInventory, err
+	}
+
+	return r.processItemOnTargetCluster(ctx, deployData, targetClient, oldInventory)
+}
+
+// processItemOnTargetCluster applies the manifests and prunes or deletes the objects of the previous inventory with the
+// client of the target cluster.
+func (r *manifestDeployerDI) processItemOnTargetCluster(ctx context.Context, deployData *deployutil.DeployData,
+	targetClient client.Client, oldInventory Inventory) (Inventory, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	if deployData.IsDeleteOperation() {
+		return r.deleteObjects(ctx, targetClient, oldInventory)
+	}
+
+	objects, namespace, err := r.renderer.Render(ctx, deployData)
+	if err != nil {
+		log.Error(err, "could not render manifests")
+		return oldInventory, err
+	}
+
+	inventory, err := r.applyObjects(ctx, targetClient, objects, namespace, oldInventory)
+	if err != nil {
+		return oldInventory.union(inventory), err
+	}
+
+	remainingInventory, err := r.deleteObjects(ctx, targetClient, oldInventory.without(inventory))
+	if err != nil {
+		return remainingInventory.union(inventory), err
+	}
+
+	return inventory, nil
+}
+
+// applyObjects applies the objects with server-side apply. Returns the inventory of the objects which have been
+// applied successfully.
+func (r *manifestDeployerDI) applyObjects(ctx context.Context, targetClient client.Client, objects []*unstructured.Unstructured,
+	namespace string, oldInventory Inventory) (Inventory, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return kindPriority(objects[i].GetKind()) < kindPriority(objects[j].GetKind())
+	})
+
+	var inventory Inventory
+
+	for _, obj := range objects {
+		if err := validateObject(obj); err != nil {
+			return inventory, deployutil.NewDeployError(deployutil.ErrorCodeRender, err)
+		}
+
+		if err := r.setNamespace(targetClient, obj, namespace); err != nil {
+			return inventory, err
+		}
+
+		if err := r.deleteSecretWithOtherType(ctx, targetClient, obj, oldInventory); err != nil {
+			return inventory, err
+		}
+
+		obj.SetManagedFields(nil)
+		obj.SetResourceVersion("")
+
+		err := targetClient.Patch(ctx, obj, client.Apply, client.FieldOwner(util.FieldManager), client.ForceOwnership)
+		if err != nil {
+			entry := newInventoryEntry(obj)
+			log.Error(err, "error applying object", "object", entry.String())
+			return inventory, errors.Wrapf(err, "error applying %s", entry.String())
+		}
+
+		inventory = append(inventory, newInventoryEntry(obj))
+	}
+
+	return inventory, nil
+}
+
+// deleteSecretWithOtherType deletes an existing secret whose type differs from the type of the secret to be applied,
+// because the type of a secret is immutable. Only secrets of the previous inventory are deleted; for other secrets an
+// error is returned which is not retried.
+func (r *manifestDeployerDI) deleteSecretWithOtherType(ctx context.Context, targetClient client.Client,
+	obj *unstructured.Unstructured, oldInventory Inventory) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	gvk := obj.GroupVersionKind()
+	if gvk.Group != "" || gvk.Kind != "Secret" {
+		return nil
+	}
+
+	newType, _, _ := unstructured.NestedString(obj.Object, "type")
+	if newType == "" {
+		newType = string(corev1.SecretTypeOpaque)
+	}
+
+	existingSecret := &corev1.Secret{}
+	err := targetClient.Get(ctx, client.ObjectKeyFromObject(obj), existingSecret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "error reading secret %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+
+	if string(existingSecret.Type) == newType {
+		return nil
+	}
+
+	entry := newInventoryEntry(obj)
+	if !oldInventory.contains(&entry) {
+		return deployutil.NewDeployError(deployutil.ErrorCodeObjectConflict, errors.Errorf(
+			"%s exists with type %s and was not created by this application, so that it cannot be replaced by a secret with type %s",
+			entry.String(), existingSecret.Type, newType))
+	}
+
+	log.V(util.LogLevelWarning).Info("deleting secret because its type changed", "object", entry.String(),
+		"oldType", existingSecret.Type, "newType", newType)
+
+	err = targetClient.Delete(ctx, existingSecret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "error deleting %s to change its type", entry.String())
+	}
+
+	return nil
+}
+
+// setNamespace sets the default namespace for namespaced objects without namespace and removes the namespace of
+// cluster scoped objects.
+func (r *manifestDeployerDI) setNamespace(targetClient client.Client, obj *unstructured.Unstructured, namespace string) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := targetClient.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return errors.Wrapf(err, "could not determine resource of %s", gvk.String())
+	}
+
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+	} else {
+		obj.SetNamespace("")
+	}
+
+	return nil
+}
+
+// deleteObjects deletes the objects of the inventory. Returns the inventory of objects whose deletion has failed.
+func (r *manifestDeployerDI) deleteObjects(ctx context.Context, targetClient client.Client, inventory Inventory) (Inventory, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	objectsToDelete := append(Inventory{}, inventory...)
+	objectsToDelete.sortForDeletion()
+
+	var remainingInventory Inventory
+	var firstErr error
+
+	for i := range objectsToDelete {
+		entry := &objectsToDelete[i]
+
+		err := targetClient.Delete(ctx, entry.toUnstructured(), client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			log.Error(err, "error deleting object", "object", entry.String())
+			remainingInventory = append(remainingInventory, *entry)
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "error deleting %s", entry.String())
+			}
+		}
+	}
+
+	return remainingInventory, firstErr
+}
+
+func (r *manifestDeployerDI) setInventory(ctx context.Context, deployData *deployutil.DeployData, inventory Inventory) {
+	log := util.GetLoggerFromContext(ctx)
+
+	if err := writeStatus(deployData, &Status{Inventory: inventory}); err != nil {
+		log.Error(err, "could not write inventory")
+	}
+}
+
+func (r *manifestDeployerDI) computeReadinessAndExport(ctx context.Context, deployData *deployutil.DeployData, now metav1.Time) {
+	r.computeReadiness(ctx, deployData, now)
+
+	readyCondition := deployData.GetDeployItemCondition(hubv1.HubDeploymentReady)
+	if readyCondition != nil && readyCondition.Status == v1alpha1.ConditionTrue {
+		err := r.computeExports(ctx, deployData)
+		if err != nil {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now,
+				hubv1.ReasonCouldNotGetExport, "Could not get export data")
+
+			deployData.SetPhase(v1alpha1.ExecutionPhaseProgressing)
+		}
+	}
+}
+
+func (r *manifestDeployerDI) computeReadiness(ctx context.Context, deployData *deployutil.DeployData, now metav1.Time) {
+	log := util.GetLoggerFromContext(ctx)
+
+	// special case for unreachable cluster - just replace condition to unknown state
+	if deployData.ProviderStatus.Reachability != nil && !deployData.ProviderStatus.Reachability.Reachable {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now,
+			hubv1.ReasonClusterUnreachable, "Cluster is unreachable")
+		return
+	}
+
+	// compute readiness
+	var readinessState string
+	if deployData.ProviderStatus.LastOperation.Operation == util.OperationInstall {
+		if deployData.ProviderStatus.LastOperation.SuccessGeneration > 0 {
+			readinessState = r.computeReadinessOnTargetCluster(ctx, deployData)
+		} else {
+			// The operation has never succeeded
+			readinessState = util.StateFailed
+		}
+	} else if deployData.ProviderStatus.LastOperation.Operation == util.OperationRemove {
+		readinessState = util.StateNotRelevant
+	} else {
+		log.Error(nil, "Unexpected operation", "operation", deployData.ProviderStatus.LastOperation.Operation)
+		readinessState = util.StateUnknown
+	}
+
+	deployData.ProviderStatus.Readiness = &hubv1.Readiness{
+		State: readinessState,
+		Time:  now,
+	}
+
+	// compute condition
+	if deployData.GetObservedGeneration() == 0 {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonInitialState, "No deployment executed until now")
+		deployData.SetPhase(v1alpha1.ExecutionPhaseProgressing)
+	} else if deployData.IsInstallOperation() {
+		if deployData.GetGeneration() != deployData.ProviderStatus.LastOperation.SuccessGeneration {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonUpgradePending, "Upgrade pending")
+			deployData.SetPhase(v1alpha1.ExecutionPhaseProgressing)
+		} else if readinessState == util.StateOk {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionTrue, now, hubv1.ReasonRunning, "Running")
+			deployData.SetPhase(v1alpha1.ExecutionPhaseSucceeded)
+		} else if readinessState == util.StateFinallyFailed {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionFalse, now, hubv1.ReasonFinallyFailed, "Finally Failed")
+			deployData.SetPhase(v1alpha1.ExecutionPhaseFailed)
+		} else {
+			deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonNotRunning, "Readiness is "+readinessState)
+			deployData.SetPhase(v1alpha1.ExecutionPhaseProgressing)
+		}
+	} else if deployData.ProviderStatus.LastOperation.Operation == util.OperationInstall {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending, "Remove pending")
+		deployData.SetPhase(v1alpha1.ExecutionPhaseProgressing)
+	} else if deployData.ProviderStatus.LastOperation.State == util.StateOk {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionTrue, now, hubv1.ReasonRemoved, "Removed")
+		deployData.SetPhase(v1alpha1.ExecutionPhaseSucceeded)
+	} else {
+		deployData.ReplaceDeployItemCondition(hubv1.HubDeploymentReady, corev1.ConditionUnknown, now, hubv1.ReasonRemovePending,
+			"Last try to remove has failed")
+		deployData.SetPhase(v1alpha1.ExecutionPhaseDeleting)
+	}
+}
+
+func (r *manifestDeployerDI) computeReadinessOnTargetCluster(ctx context.Context, deployData *deployutil.DeployData) string {
+	log := util.GetLoggerFromContext(ctx)
+
+	status, err := readStatus(deployData)
+	if err != nil {
+		log.Error(err, "could not parse inventory")
+		return util.StateUnknown
+	}
+
+	secretKey := deployData.GetSecretKey()
+	targetClient, err := deployutil.GetTargetClient(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		log.Error(err, "Error fetching target client")
+		return util.StateUnknown
+	}
+
+	dynamicTargetClient, err := deployutil.NewDynamicTargetClient(ctx, r.crAndSecretClient, *secretKey)
+	if err != nil {
+		log.Error(err, "Error fetching dynamic target client")
+		return util.StateUnknown
+	}
+
+	basicKubernetesObjects := status.Inventory.basicKubernetesObjects(deployutil.ReadinessFilter)
+	return deployData.ComputeReadiness(ctx, basicKubernetesObjects, targetClient, dynamicTargetClient, defaultNamespace)
+}
+
+func (r *manifestDeployerDI) computeExports(ctx context.Context, deployData *deployutil.DeployData) error {
+	log := util.GetLoggerFromContext(ctx)
+
+	internalExport, err := r.renderer.InternalExport(deployData)
+	if err != nil {
+		log.Error(err, couldNotParse)
+		return err
+	}
+
+	if len(internalExport) > 0 {
+		secretKey := deployData.GetSecretKey()
+		dynamicTargetClient, err := deployutil.NewDynamicTargetClient(ctx, r.crAndSecretClient, *secretKey)
+		if err != nil {
+			log.Error(err, "Error fetching dynamic target client")
+			return err
+		}
+
+		newExportData := make(map[string]interface{})
+
+		for key, exportEntry := range internalExport {
+			exportData, err := dynamicTargetClient.GetResourceData(exportEntry.APIVersion, exportEntry.Resource,
+				exportEntry.Namespace, exportEntry.Name, exportEntry.FieldPath)
+
+			if err != nil {
+				log.Error(err, "Could not fetch resource: "+exportEntry.String())
+				return err
+			}
+
+			newExportData[key] = exportData
+		}
+
+		if len(newExportData) > 0 {
+			deployData.ExportValues = newExportData
+		}
+	}
+
+	return nil
+}
+
+func (r *manifestDeployerDI) successDescription(deployData *deployutil.DeployData) string {
+	if deployData.IsInstallOperation() {
+		return "install successful"
+	}
+
+	return "remove successful"
+}
+
+// kindPriority determines the order in which objects are applied. Namespaces and custom resource definitions are
+// applied first, because other objects might depend on them.
+func kindPriority(kind string) int {
+	switch kind {
+	case util.KindNamespace:
+		return 0
+	case util.KindCustomResourceDefinition:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,245 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	hubtesting "github.com/gardener/potter-controller/pkg/testing"
+
+	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testTargetNamespace = "target-ns"
+
+func TestProcessItemAppliesObjects(t *testing.T) {
+	targetClient := newTestTargetClient()
+	deployer := newTestDeployer(newTestConfigMap("", "config", "new"), newTestNamespace(testTargetNamespace))
+
+	inventory, err := deployer.processItemOnTargetCluster(hubtesting.CreateTestContext(), newTestDeployData(t, false), targetClient, nil)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(inventory), 2, "number of objects in inventory")
+	assert.Equal(t, inventory[0], InventoryEntry{APIVersion: "v1", Kind: "Namespace", Name: testTargetNamespace}, "namespace applied first")
+	assert.Equal(t, inventory[1], InventoryEntry{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "config"},
+		"config map in default namespace")
+
+	configMap := &corev1.ConfigMap{}
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "config"}, configMap)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, configMap.Data["value"], "new", "value")
+}
+
+func TestProcessItemPrunesRemovedObjects(t *testing.T) {
+	targetClient := newTestTargetClient(
+		newTestConfigMap(testTargetNamespace, "config", "old"),
+		newTestConfigMap(testTargetNamespace, "removed", "old"),
+	)
+	deployer := newTestDeployer(newTestConfigMap("", "config", "new"))
+	oldInventory := Inventory{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "config"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "removed"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "already-deleted"},
+	}
+
+	inventory, err := deployer.processItemOnTargetCluster(hubtesting.CreateTestContext(), newTestDeployData(t, false), targetClient, oldInventory)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(inventory), 1, "number of objects in inventory")
+	assert.Equal(t, inventory[0].Name, "config", "name")
+
+	configMap := &corev1.ConfigMap{}
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "config"}, configMap)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, configMap.Data["value"], "new", "value")
+
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "removed"}, configMap)
+	assert.True(t, apierrors.IsNotFound(err), "removed object is deleted")
+}
+
+func TestProcessItemKeepsInventoryOnFailure(t *testing.T) {
+	targetClient := newTestTargetClient(newTestConfigMap(testTargetNamespace, "removed", "old"))
+	deployer := newTestDeployer(newTestConfigMap("", "config", "new"), newTestConfigMap("", "", "invalid"))
+	oldInventory := Inventory{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "removed"},
+	}
+
+	inventory, err := deployer.processItemOnTargetCluster(hubtesting.CreateTestContext(), newTestDeployData(t, false), targetClient, oldInventory)
+	assert.NotNil(t, err, "error")
+	assert.Equal(t, len(inventory), 2, "number of objects in inventory")
+	assert.True(t, inventory.contains(&oldInventory[0]), "old object in inventory")
+
+	configMap := &corev1.ConfigMap{}
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "removed"}, configMap)
+	assert.Nil(t, err, "object is not pruned after failure")
+}
+
+func TestProcessItemDeletesObjects(t *testing.T) {
+	targetClient := newTestTargetClient(
+		newTestNamespace(testTargetNamespace),
+		newTestConfigMap(testTargetNamespace, "config", "old"),
+	)
+	deployer := newTestDeployer(newTestConfigMap("", "config", "new"))
+	oldInventory := Inventory{
+		{APIVersion: "v1", Kind: "Namespace", Name: testTargetNamespace},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "config"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: testTargetNamespace, Name: "already-deleted"},
+	}
+
+	inventory, err := deployer.processItemOnTargetCluster(hubtesting.CreateTestContext(), newTestDeployData(t, true), targetClient, oldInventory)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(inventory), 0, "number of objects in inventory")
+
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "config"}, &corev1.ConfigMap{})
+	assert.True(t, apierrors.IsNotFound(err), "config map is deleted")
+
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Name: testTargetNamespace}, &corev1.Namespace{})
+	assert.True(t, apierrors.IsNotFound(err), "namespace is deleted")
+}
+
+func TestProcessItemRecreatesSecretWithOtherType(t *testing.T) {
+	targetClient := newTestTargetClient(newTestSecret(testTargetNamespace, "copy", corev1.SecretTypeOpaque))
+	deployer := newTestDeployer(newTestSecret("", "copy", corev1.SecretTypeDockerConfigJson))
+	oldInventory := Inventory{
+		{APIVersion: "v1", Kind: "Secret", Namespace: testTargetNamespace, Name: "copy"},
+	}
+
+	inventory, err := deployer.processItemOnTargetCluster(hubtesting.CreateTestContext(), newTestDeployData(t, false), targetClient, oldInventory)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(inventory), 1, "number of objects in inventory")
+
+	secret := &corev1.Secret{}
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "copy"}, secret)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, secret.Type, corev1.SecretTypeDockerConfigJson, "type")
+}
+
+func TestProcessItemKeepsForeignSecretWithOtherType(t *testing.T) {
+	targetClient := newTestTargetClient(newTestSecret(testTargetNamespace, "foreign", corev1.SecretTypeOpaque))
+	deployer := newTestDeployer(newTestSecret("", "foreign", corev1.SecretTypeDockerConfigJson))
+
+	_, err := deployer.processItemOnTargetCluster(hubtesting.CreateTestContext(), newTestDeployData(t, false), targetClient, nil)
+	assert.NotNil(t, err, "error")
+
+	var deployError *deployutil.DeployError
+	assert.True(t, errors.As(err, &deployError), "deploy error")
+	assert.Equal(t, deployError.Code, deployutil.ErrorCodeObjectConflict, "error code")
+	assert.False(t, deployError.IsRetryable(), "retryable")
+
+	secret := &corev1.Secret{}
+	err = targetClient.Get(hubtesting.CreateTestContext(), types.NamespacedName{Namespace: testTargetNamespace, Name: "foreign"}, secret)
+	assert.Nil(t, err, "foreign secret is not deleted")
+	assert.Equal(t, secret.Type, corev1.SecretTypeOpaque, "type")
+}
+
+// applyingFakeClient adds server-side apply and a RESTMapper to the fake client, which supports neither
+type applyingFakeClient struct {
+	client.Client
+}
+
+func (c *applyingFakeClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if apierrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+
+	if existing.GetKind() == "Secret" && existing.Object["type"] != obj.(*unstructured.Unstructured).Object["type"] {
+		return apierrors.NewInvalid(existing.GroupVersionKind().GroupKind(), existing.GetName(), nil)
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return c.Client.Update(ctx, obj)
+}
+
+func (c *applyingFakeClient) RESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	return mapper
+}
+
+// testRenderer returns fixed objects
+type testRenderer struct {
+	objects []*unstructured.Unstructured
+}
+
+func (r *testRenderer) Render(ctx context.Context, deployData *deployutil.DeployData) ([]*unstructured.Unstructured, string, error) {
+	objects := make([]*unstructured.Unstructured, len(r.objects))
+	for i := range r.objects {
+		objects[i] = r.objects[i].DeepCopy()
+	}
+	return objects, testTargetNamespace, nil
+}
+
+func (r *testRenderer) InternalExport(deployData *deployutil.DeployData) (map[string]apitypes.InternalExportEntry, error) {
+	return nil, nil
+}
+
+func newTestDeployer(objects ...*unstructured.Unstructured) *manifestDeployerDI {
+	return &manifestDeployerDI{renderer: &testRenderer{objects: objects}}
+}
+
+func newTestTargetClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	return &applyingFakeClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()}
+}
+
+func newTestDeployData(t *testing.T, deleted bool) *deployutil.DeployData {
+	deployItem := hubtesting.CreateDeployItemForConfig(t, "test-item", "test-namespace", json.RawMessage("{}"), nil)
+
+	if deleted {
+		deletionTimestamp := metav1.Now()
+		deployItem.DeletionTimestamp = &deletionTimestamp
+	}
+
+	return hubtesting.CreateDeployData(t, deployItem)
+}
+
+func newTestNamespace(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Namespace")
+	obj.SetName(name)
+	return obj
+}
+
+func newTestConfigMap(namespace, name, value string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	_ = unstructured.SetNestedField(obj.Object, value, "data", "value")
+	return obj
+}
+
+func newTestSecret(namespace, name string, secretType corev1.SecretType) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Secret")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	_ = unstructured.SetNestedField(obj.Object, string(secretType), "type")
+	return obj
+}
@@ -0,0 +1,44 @@
+package manifest
+
+import (
+	"context"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// manifestRenderer provides the objects of deploy items with config type manifest.
+type manifestRenderer struct {
+	uncachedClient synchronize.UncachedClient
+}
+
+func newManifestRenderer(uncachedClient synchronize.UncachedClient) Renderer {
+	return &manifestRenderer{uncachedClient: uncachedClient}
+}
+
+func (r *manifestRenderer) Render(ctx context.Context, deployData *deployutil.DeployData) ([]*unstructured.Unstructured, string, error) {
+	manifestSpecificData, err := apitypes.NewManifestSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return nil, "", errors.Wrap(err, couldNotParse)
+	}
+
+	objects, err := loadManifests(ctx, r.uncachedClient, deployData.GetNamespace(), manifestSpecificData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objects, manifestSpecificData.Namespace, nil
+}
+
+func (r *manifestRenderer) InternalExport(deployData *deployutil.DeployData) (map[string]apitypes.InternalExportEntry, error) {
+	manifestSpecificData, err := apitypes.NewManifestSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifestSpecificData.InternalExport, nil
+}
@@ -0,0 +1,104 @@
+package manifest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gardener/potter-controller/pkg/deployutil"
+
+	"github.com/arschles/assert"
+)
+
+func TestParseManifests(t *testing.T) {
+	data := []byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+---
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: cm1
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: cm2
+    namespace: test
+`)
+
+	objects, err := ParseManifests(data)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(objects), 3, "number of objects")
+	assert.Equal(t, objects[0].GetKind(), "Namespace", "kind")
+	assert.Equal(t, objects[1].GetName(), "cm1", "name")
+	assert.Equal(t, objects[2].GetNamespace(), "test", "namespace")
+
+	_, err = ParseManifests([]byte("kind: [unclosed"))
+	assert.NotNil(t, err, "error")
+}
+
+func TestInventory(t *testing.T) {
+	oldInventory := Inventory{
+		{APIVersion: "v1", Kind: "Namespace", Name: "test"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "test", Name: "a"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "b"},
+	}
+
+	newInventory := Inventory{
+		{APIVersion: "v1", Kind: "Namespace", Name: "test"},
+		{APIVersion: "apps/v1beta2", Kind: "Deployment", Namespace: "test", Name: "a"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "c"},
+	}
+
+	pruned := oldInventory.without(newInventory)
+	assert.Equal(t, len(pruned), 1, "number of pruned objects")
+	assert.Equal(t, pruned[0].Name, "b", "name of pruned object")
+
+	union := pruned.union(newInventory)
+	assert.Equal(t, len(union), 4, "number of objects in union")
+
+	union.sortForDeletion()
+	assert.Equal(t, union[len(union)-1].Kind, "Namespace", "namespace deleted last")
+
+	basicObjects := newInventory.basicKubernetesObjects(deployutil.ReadinessFilter)
+	assert.Equal(t, len(basicObjects), 1, "number of objects relevant for readiness")
+	assert.Equal(t, basicObjects[0].ObjectMeta.Namespace, "test", "namespace")
+}
+
+func TestDownloadManifests(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		expectError bool
+	}{
+		{"maximum size", maxManifestSize, false},
+		{"oversized", maxManifestSize + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "#" + strings.Repeat("x", tt.size-1)
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(body))
+			}))
+			defer testServer.Close()
+
+			data, err := downloadManifests(context.Background(), testServer.URL)
+			if tt.expectError {
+				assert.NotNil(t, err, "error")
+				assert.Nil(t, data, "data")
+			} else {
+				assert.Nil(t, err, "error")
+				assert.Equal(t, len(data), tt.size, "size of manifests")
+			}
+		})
+	}
+}
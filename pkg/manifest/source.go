@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	downloadTimeout = 30 * time.Second
+	maxManifestSize = 10 * 1024 * 1024
+)
+
+// loadManifests returns the objects of the manifests, which are specified inline, by a url, or by a config map in
+// the namespace of the clusterbom.
+func loadManifests(ctx context.Context, uncachedClient synchronize.UncachedClient, namespace string,
+	manifestSpecificData *apitypes.ManifestSpecificData) ([]*unstructured.Unstructured, error) {
+	switch {
+	case len(manifestSpecificData.Manifests) > 0:
+		var objects []*unstructured.Unstructured
+		for i := range manifestSpecificData.Manifests {
+			objs, err := ParseManifests(manifestSpecificData.Manifests[i].Raw)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not parse manifest %d", i)
+			}
+			objects = append(objects, objs...)
+		}
+		return objects, nil
+
+	case manifestSpecificData.URL != "":
+		data, err := downloadManifests(ctx, manifestSpecificData.URL)
+		if err != nil {
+			return nil, err
+		}
+		return ParseManifests(data)
+
+	case manifestSpecificData.ConfigMapRef != nil:
+		configMapKey := types.NamespacedName{
+			Namespace: namespace,
+			Name:      manifestSpecificData.ConfigMapRef.Name,
+		}
+
+		configMap := &corev1.ConfigMap{}
+		if err := uncachedClient.GetUncached(ctx, configMapKey, configMap); err != nil {
+			return nil, errors.Wrapf(err, "could not fetch config map %s", configMapKey)
+		}
+
+		data, ok := configMap.Data[manifestSpecificData.GetConfigMapKey()]
+		if !ok {
+			return nil, errors.Errorf("config map %s has no key %s", configMapKey, manifestSpecificData.GetConfigMapKey())
+		}
+		return ParseManifests([]byte(data))
+
+	default:
+		return nil, errors.New("no manifests specified")
+	}
+}
+
+func downloadManifests(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create request for manifests")
+	}
+
+	httpClient := &http.Client{
+		Timeout: downloadTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "manifest download request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("manifest download request failed with status code %v", res.StatusCode)
+	}
+
+	// read one byte more than allowed to detect manifests exceeding the limit, rather than pruning their truncated rest
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read manifests")
+	}
+
+	if len(data) > maxManifestSize {
+		return nil, errors.Errorf("manifests exceed the maximum size of %d bytes", maxManifestSize)
+	}
+
+	return data, nil
+}
+
+// ParseManifests parses a multi document yaml or a json object. Empty documents and lists are supported.
+func ParseManifests(data []byte) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		if obj.IsList() {
+			err = obj.EachListItem(func(item runtime.Object) error {
+				objects = append(objects, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		objects = append(objects, obj)
+	}
+}
+
+func validateObject(obj *unstructured.Unstructured) error {
+	if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+		return errors.New("manifest without apiVersion or kind")
+	}
+
+	if obj.GetName() == "" {
+		return errors.Errorf("manifest of kind %s without metadata.name", obj.GetKind())
+	}
+
+	return nil
+}
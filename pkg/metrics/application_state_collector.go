@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	collectTimeout = 10 * time.Second
+
+	// stateUnknown is used for applications without state
+	stateUnknown = "unknown"
+)
+
+// ApplicationStateCollector computes the application state metrics from the status of the clusterboms when the
+// metrics are scraped. It reads the clusterboms from the cache of the manager, so that a scrape does not cause
+// requests to the API server.
+type ApplicationStateCollector struct {
+	reader client.Reader
+	log    logr.Logger
+
+	applications            *prometheus.Desc
+	unreachableApplications *prometheus.Desc
+}
+
+// RegisterApplicationStateCollector registers an ApplicationStateCollector in the registry of controller-runtime.
+func RegisterApplicationStateCollector(reader client.Reader, log logr.Logger) {
+	metrics.Registry.MustRegister(NewApplicationStateCollector(reader, log))
+}
+
+func NewApplicationStateCollector(reader client.Reader, log logr.Logger) *ApplicationStateCollector {
+	return &ApplicationStateCollector{
+		reader: reader,
+		log:    log,
+		applications: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "applications"),
+			"Current number of applications by clusterbom namespace and state.",
+			[]string{LabelNamespace, LabelState}, nil,
+		),
+		unreachableApplications: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "applications_unreachable"),
+			"Current number of applications whose target cluster is unreachable, by clusterbom namespace.",
+			[]string{LabelNamespace}, nil,
+		),
+	}
+}
+
+func (c *ApplicationStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.applications
+	ch <- c.unreachableApplications
+}
+
+func (c *ApplicationStateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	clusterBomList := &hubv1.ClusterBomList{}
+	if err := c.reader.List(ctx, clusterBomList); err != nil {
+		c.log.Error(err, "error listing clusterboms for metrics")
+		return
+	}
+
+	applications := make(map[string]map[string]int)
+	unreachableApplications := make(map[string]int)
+
+	for i := range clusterBomList.Items {
+		clusterBom := &clusterBomList.Items[i]
+		namespace := clusterBom.Namespace
+
+		if applications[namespace] == nil {
+			applications[namespace] = make(map[string]int)
+			unreachableApplications[namespace] = 0
+		}
+
+		for j := range clusterBom.Status.ApplicationStates {
+			applicationState := &clusterBom.Status.ApplicationStates[j]
+
+			state := applicationState.State
+			if state == "" {
+				state = stateUnknown
+			}
+			applications[namespace][state]++
+
+			reachability := applicationState.DetailedState.Reachability
+			if reachability != nil && !reachability.Reachable {
+				unreachableApplications[namespace]++
+			}
+		}
+	}
+
+	for namespace, states := range applications {
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(c.applications, prometheus.GaugeValue, float64(count), namespace, state)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.unreachableApplications, prometheus.GaugeValue,
+			float64(unreachableApplications[namespace]), namespace)
+	}
+}
@@ -0,0 +1,285 @@
+// Package metrics contains the Prometheus metrics of the potter controller. The metrics are registered in the registry
+// of controller-runtime and served on the metrics address of the manager. All labels have values from small fixed
+// sets, or are namespaces, so that the number of time series does not grow with the number of clusterboms.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "potter"
+
+const (
+	LabelConfigType  = "config_type"
+	LabelOperation   = "operation"
+	LabelOutcome     = "outcome"
+	LabelNamespace   = "namespace"
+	LabelState       = "state"
+	LabelResult      = "result"
+	LabelSource      = "source"
+	LabelResource    = "resource"
+	LabelDecision    = "decision"
+	LabelReason      = "reason"
+	LabelEventType   = "event_type"
+	LabelBackend     = "backend"
+	LabelCertificate = "certificate"
+)
+
+// operations of deploy items
+const (
+	OperationInstall   = "install"
+	OperationUpgrade   = "upgrade"
+	OperationRemove    = "remove"
+	OperationRetry     = "retry"
+	OperationReconcile = "reconcile"
+)
+
+// results of an attempt to get the block of a clusterbom
+const (
+	BlockResultAcquired = "acquired"
+	BlockResultBlocked  = "blocked"
+	BlockResultFailed   = "failed"
+)
+
+// sources of helm charts
+const (
+	ChartSourceCatalog = "catalog"
+	ChartSourceTarball = "tarball"
+)
+
+const (
+	ResultOk     = "ok"
+	ResultFailed = "failed"
+
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+
+	// OutcomeUnreachable is the outcome of operations which failed, because the target cluster was unreachable
+	OutcomeUnreachable = "unreachable"
+)
+
+// reasons for dropped audit log messages
+const (
+	AuditLogDroppedSpoolFull  = "spool_full"
+	AuditLogDroppedSpoolError = "spool_error"
+	AuditLogDroppedCorrupt    = "corrupt"
+)
+
+// results of notifications
+const (
+	NotificationDelivered   = "delivered"
+	NotificationFailed      = "failed"
+	NotificationDuplicate   = "duplicate"
+	NotificationRateLimited = "rate_limited"
+	NotificationDropped     = "dropped"
+)
+
+// certificates of the admission webhook
+const (
+	CertificateCA      = "ca"
+	CertificateServing = "serving"
+)
+
+var (
+	deployOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deploy_operations_total",
+			Help:      "Number of processed deploy item operations by config type, operation and outcome.",
+		},
+		[]string{LabelConfigType, LabelOperation, LabelOutcome},
+	)
+
+	deployOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "deploy_operation_duration_seconds",
+			Help:      "Duration of the processing of deploy item operations by config type and operation.",
+			Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{LabelConfigType, LabelOperation},
+	)
+
+	readinessLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "readiness_latency_seconds",
+			Help:      "Time from the processing of a new generation of a deploy item until it is ready, by config type.",
+			Buckets:   prometheus.ExponentialBuckets(5, 2, 10),
+		},
+		[]string{LabelConfigType},
+	)
+
+	blockWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "block_wait_seconds",
+			Help:      "Duration of the attempts to get the block of a clusterbom, by result.",
+			Buckets:   []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		[]string{LabelResult},
+	)
+
+	blockHold = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "block_hold_seconds",
+			Help:      "Time for which the controller held the block of a clusterbom.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		},
+	)
+
+	chartFetchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "chart_fetch_duration_seconds",
+			Help:      "Duration of the download of helm charts, by source and result.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{LabelSource, LabelResult},
+	)
+
+	admissionDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_decisions_total",
+			Help:      "Number of admission webhook decisions by resource, decision and reason.",
+		},
+		[]string{LabelResource, LabelDecision, LabelReason},
+	)
+
+	auditLogPending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "auditlog_messages_pending",
+			Help:      "Number of audit log messages in the spool which are not yet delivered.",
+		},
+	)
+
+	auditLogDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "auditlog_messages_dropped_total",
+			Help:      "Number of audit log messages which were dropped, by reason.",
+		},
+		[]string{LabelReason},
+	)
+
+	auditLogDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "auditlog_deliveries_total",
+			Help:      "Number of attempts to deliver audit log messages, by backend and result.",
+		},
+		[]string{LabelBackend, LabelResult},
+	)
+
+	notifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "notifications_total",
+			Help:      "Number of notifications on state transitions by event type and result.",
+		},
+		[]string{LabelEventType, LabelResult},
+	)
+
+	webhookCertificateExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_certificate_expiry_timestamp_seconds",
+			Help:      "Time at which the certificates of the admission webhook expire, in seconds since the epoch, by certificate.",
+		},
+		[]string{LabelCertificate},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		deployOperations,
+		deployOperationDuration,
+		readinessLatency,
+		blockWait,
+		blockHold,
+		chartFetchDuration,
+		admissionDecisions,
+		auditLogPending,
+		auditLogDropped,
+		auditLogDeliveries,
+		notifications,
+		webhookCertificateExpiry,
+	)
+}
+
+// ObserveDeployOperation records a processed operation of a deploy item.
+func ObserveDeployOperation(configType, operation, outcome string, start time.Time) {
+	deployOperations.WithLabelValues(configType, operation, outcome).Inc()
+	deployOperationDuration.WithLabelValues(configType, operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveReadinessLatency records the time from the processing of a new generation of a deploy item until it is ready.
+func ObserveReadinessLatency(configType string, latency time.Duration) {
+	readinessLatency.WithLabelValues(configType).Observe(latency.Seconds())
+}
+
+// ObserveBlockWait records an attempt to get the block of a clusterbom.
+func ObserveBlockWait(result string, start time.Time) {
+	blockWait.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveBlockHold records the time for which the block of a clusterbom was held.
+func ObserveBlockHold(duration time.Duration) {
+	blockHold.Observe(duration.Seconds())
+}
+
+// ObserveChartFetch records the download of a helm chart.
+func ObserveChartFetch(source string, start time.Time, err error) {
+	chartFetchDuration.WithLabelValues(source, resultOf(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveAdmissionDecision records a decision of the admission webhook. The reason of allowed requests is empty.
+func ObserveAdmissionDecision(resource string, allowed bool, reason string) {
+	decision := DecisionDenied
+	if allowed {
+		decision = DecisionAllowed
+	}
+
+	admissionDecisions.WithLabelValues(resource, decision, reason).Inc()
+}
+
+// SetAuditLogPending sets the number of audit log messages which are not yet delivered.
+func SetAuditLogPending(pending int) {
+	auditLogPending.Set(float64(pending))
+}
+
+// ObserveAuditLogDropped records an audit log message which was dropped. The reason is one of the AuditLogDropped*
+// constants.
+func ObserveAuditLogDropped(reason string) {
+	auditLogDropped.WithLabelValues(reason).Inc()
+}
+
+// ObserveAuditLogDelivery records an attempt to deliver an audit log message.
+func ObserveAuditLogDelivery(backend string, err error) {
+	auditLogDeliveries.WithLabelValues(backend, resultOf(err)).Inc()
+}
+
+// ObserveNotification records a notification for a policy. The result is one of the Notification* constants.
+func ObserveNotification(eventType, result string) {
+	notifications.WithLabelValues(eventType, result).Inc()
+}
+
+// SetWebhookCertificateExpiry sets the expiry time of a certificate of the admission webhook. The certificate is one of
+// the Certificate* constants.
+func SetWebhookCertificateExpiry(certificate string, notAfter time.Time) {
+	webhookCertificateExpiry.WithLabelValues(certificate).Set(float64(notAfter.Unix()))
+}
+
+func resultOf(err error) string {
+	if err != nil {
+		return ResultFailed
+	}
+
+	return ResultOk
+}
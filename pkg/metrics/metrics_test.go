@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+
+	"github.com/arschles/assert"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReadinessTracker(t *testing.T) {
+	const configType = "test-readiness"
+	key := types.NamespacedName{Namespace: "test-namespace", Name: "test-item"}
+	tracker := NewReadinessTracker()
+
+	// ready without start is not observed
+	tracker.Ready(key, 1, configType)
+	assert.Equal(t, testutil.CollectAndCount(readinessLatency), 0, "number of observed config types")
+
+	// ready of another generation is not observed
+	tracker.Start(key, 1)
+	tracker.Ready(key, 2, configType)
+	assert.Equal(t, testutil.CollectAndCount(readinessLatency), 0, "number of observed config types")
+
+	tracker.Start(key, 2)
+	tracker.Ready(key, 2, configType)
+	assert.Equal(t, testutil.CollectAndCount(readinessLatency), 1, "number of observed config types")
+
+	// a forgotten deploy item is not observed
+	tracker.Start(key, 3)
+	tracker.Forget(key)
+	tracker.Ready(key, 3, configType)
+	assert.Equal(t, len(tracker.starts), 0, "number of started generations")
+}
+
+func TestApplicationStateCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = hubv1.AddToScheme(scheme)
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newTestClusterBom("namespace-1", "bom-1",
+			hubv1.ApplicationState{State: "ok"},
+			hubv1.ApplicationState{State: "failed", DetailedState: hubv1.DetailedState{
+				Reachability: &hubv1.Reachability{Reachable: false},
+			}}),
+		newTestClusterBom("namespace-1", "bom-2",
+			hubv1.ApplicationState{State: "ok"},
+			hubv1.ApplicationState{}),
+		newTestClusterBom("namespace-2", "bom-3"),
+	).Build()
+
+	collector := NewApplicationStateCollector(reader, ctrl.Log.WithName("test"))
+
+	expected := `
+# HELP potter_applications Current number of applications by clusterbom namespace and state.
+# TYPE potter_applications gauge
+potter_applications{namespace="namespace-1",state="failed"} 1
+potter_applications{namespace="namespace-1",state="ok"} 2
+potter_applications{namespace="namespace-1",state="unknown"} 1
+# HELP potter_applications_unreachable Current number of applications whose target cluster is unreachable, by clusterbom namespace.
+# TYPE potter_applications_unreachable gauge
+potter_applications_unreachable{namespace="namespace-1"} 1
+potter_applications_unreachable{namespace="namespace-2"} 0
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected))
+	assert.Nil(t, err, "error")
+}
+
+func newTestClusterBom(namespace, name string, applicationStates ...hubv1.ApplicationState) *hubv1.ClusterBom {
+	return &hubv1.ClusterBom{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Status: hubv1.ClusterBomStatus{
+			ApplicationStates: applicationStates,
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ReadinessTracker measures the readiness latency of deploy items: the time from the processing of a new generation
+// of a deploy item until the deploy item is ready. The start times are only kept in memory, so that generations whose
+// processing started before a restart of the controller are not measured.
+type ReadinessTracker struct {
+	mutex  sync.Mutex
+	starts map[types.NamespacedName]readinessStart
+}
+
+type readinessStart struct {
+	generation int64
+	time       time.Time
+}
+
+func NewReadinessTracker() *ReadinessTracker {
+	return &ReadinessTracker{
+		starts: make(map[types.NamespacedName]readinessStart),
+	}
+}
+
+// Start records the start of the processing of a generation of a deploy item. If the processing of the same
+// generation was already started, the earlier start time is kept.
+func (t *ReadinessTracker) Start(key types.NamespacedName, generation int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if start, ok := t.starts[key]; ok && start.generation == generation {
+		return
+	}
+
+	t.starts[key] = readinessStart{generation: generation, time: time.Now()}
+}
+
+// Ready records that a generation of a deploy item is ready. The readiness latency is observed, if the processing of
+// this generation was started before.
+func (t *ReadinessTracker) Ready(key types.NamespacedName, generation int64, configType string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	start, ok := t.starts[key]
+	if !ok || start.generation != generation {
+		return
+	}
+
+	delete(t.starts, key)
+	ObserveReadinessLatency(configType, time.Since(start.time))
+}
+
+// Forget removes a deploy item, e.g. because it is deleted.
+func (t *ReadinessTracker) Forget(key types.NamespacedName) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.starts, key)
+}
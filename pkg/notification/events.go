@@ -0,0 +1,205 @@
+// Package notification sends notifications on state transitions of clusterboms and their applications to the http
+// endpoints configured by NotificationPolicies. The transitions are detected by the ClusterBomStateReconciler when it
+// updates the status of a clusterbom.
+package notification
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Event describes a state transition of a clusterbom or of one of its applications. It is the payload of the
+// notifications.
+type Event struct {
+	// ID identifies the transition; notifications with the same id are sent only once per policy
+	ID         string                      `json:"id"`
+	Type       hubv1.NotificationEventType `json:"type"`
+	Time       metav1.Time                 `json:"time"`
+	ClusterBom ClusterBomReference         `json:"clusterBom"`
+	// AppID is the id of the application; empty for events of the clusterbom
+	AppID         string              `json:"appID,omitempty"`
+	Operation     string              `json:"operation,omitempty"`
+	State         string              `json:"state,omitempty"`
+	PreviousState string              `json:"previousState,omitempty"`
+	Reasons       []string            `json:"reasons,omitempty"`
+	ErrorHistory  *hubv1.ErrorHistory `json:"errorHistory,omitempty"`
+}
+
+type ClusterBomReference struct {
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	Generation int64             `json:"generation"`
+	SecretRef  string            `json:"secretRef,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// ComputeEvents returns the events for the transitions from the old to the new status of a clusterbom.
+func ComputeEvents(clusterBom *hubv1.ClusterBom, oldStatus, newStatus *hubv1.ClusterBomStatus) []Event {
+	events := []Event{}
+
+	for i := range newStatus.ApplicationStates {
+		newAppState := &newStatus.ApplicationStates[i]
+		oldAppState := findApplicationState(oldStatus, newAppState.ID)
+		if event := computeApplicationEvent(clusterBom, newStatus, oldAppState, newAppState); event != nil {
+			events = append(events, *event)
+		}
+	}
+
+	newReachable := util.GetClusterBomConditionFromStatus(newStatus, hubv1.ClusterReachable)
+	oldReachable := util.GetClusterBomConditionFromStatus(oldStatus, hubv1.ClusterReachable)
+	if hasChangedToStatus(oldReachable, newReachable, corev1.ConditionFalse) {
+		event := newEvent(clusterBom, newStatus, hubv1.EventClusterUnreachable, "",
+			newReachable.LastTransitionTime.UTC().Format(time.RFC3339))
+		event.State = string(newReachable.Status)
+		event.PreviousState = conditionStatus(oldReachable)
+		event.Reasons = conditionReasons(newReachable)
+		events = append(events, *event)
+	}
+
+	newReady := util.GetClusterBomConditionFromStatus(newStatus, hubv1.ClusterBomReady)
+	oldReady := util.GetClusterBomConditionFromStatus(oldStatus, hubv1.ClusterBomReady)
+	if hasChangedToStatus(oldReady, newReady, corev1.ConditionTrue) {
+		event := newEvent(clusterBom, newStatus, hubv1.EventClusterBomReady, "",
+			newReady.LastTransitionTime.UTC().Format(time.RFC3339))
+		event.State = string(newReady.Status)
+		event.PreviousState = conditionStatus(oldReady)
+		event.Reasons = conditionReasons(newReady)
+		events = append(events, *event)
+	}
+
+	return events
+}
+
+// computeApplicationEvent returns the event for the transition of an application, or nil if there is none. An
+// application which is finally failed is also failed; only the ApplicationFinallyFailed event is returned in this case.
+func computeApplicationEvent(clusterBom *hubv1.ClusterBom, newStatus *hubv1.ClusterBomStatus,
+	oldAppState, newAppState *hubv1.ApplicationState) *Event {
+	oldState := ""
+	if oldAppState != nil {
+		oldState = oldAppState.State
+	}
+
+	var eventType hubv1.NotificationEventType
+	switch {
+	case readinessState(newAppState) == util.StateFinallyFailed && readinessState(oldAppState) != util.StateFinallyFailed:
+		eventType = hubv1.EventApplicationFinallyFailed
+	case newAppState.State == util.StateFailed && oldState != util.StateFailed:
+		eventType = hubv1.EventApplicationFailed
+	case newAppState.State == util.StateOk && oldState != util.StateOk:
+		eventType = hubv1.EventApplicationOk
+	default:
+		return nil
+	}
+
+	detailedState := &newAppState.DetailedState
+
+	event := newEvent(clusterBom, newStatus, eventType, newAppState.ID, applicationTransition(detailedState))
+	event.Operation = detailedState.LastOperation.Operation
+	event.State = newAppState.State
+	event.PreviousState = oldState
+	event.ErrorHistory = detailedState.LastOperation.ErrorHistory
+
+	if detailedState.LastOperation.Description != "" {
+		event.Reasons = append(event.Reasons, detailedState.LastOperation.Description)
+	}
+
+	for _, condition := range detailedState.HdcConditions {
+		if condition.Status != corev1.ConditionTrue {
+			event.Reasons = append(event.Reasons, formatReason(string(condition.Type), string(condition.Reason), condition.Message))
+		}
+	}
+
+	return event
+}
+
+// newEvent creates an event. Its id is derived from the clusterbom, the event type, the application and the given
+// discriminator, which distinguishes repeated transitions of the same kind, e.g. the transition time of a condition.
+func newEvent(clusterBom *hubv1.ClusterBom, newStatus *hubv1.ClusterBomStatus, eventType hubv1.NotificationEventType,
+	appID, discriminator string) *Event {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		clusterBom.Namespace, clusterBom.Name, string(clusterBom.UID), string(eventType), appID, discriminator,
+	}, "/")))
+
+	return &Event{
+		ID:   hex.EncodeToString(hash[:16]),
+		Type: eventType,
+		Time: newStatus.OverallTime,
+		ClusterBom: ClusterBomReference{
+			Namespace:  clusterBom.Namespace,
+			Name:       clusterBom.Name,
+			Generation: clusterBom.Generation,
+			SecretRef:  clusterBom.Spec.SecretRef,
+			Labels:     clusterBom.Labels,
+		},
+		AppID: appID,
+	}
+}
+
+// applicationTransition returns a discriminator for a transition of an application. Besides the generation it contains
+// the time of the last operation or readiness check, so that a repeated transition of the same generation, e.g. a
+// failure after a retry, gets a new event id.
+func applicationTransition(detailedState *hubv1.DetailedState) string {
+	transitionTime := detailedState.LastOperation.Time
+	if detailedState.Readiness != nil && transitionTime.Before(&detailedState.Readiness.Time) {
+		transitionTime = detailedState.Readiness.Time
+	}
+
+	return strconv.FormatInt(detailedState.Generation, 10) + "/" + transitionTime.UTC().Format(time.RFC3339)
+}
+
+func findApplicationState(status *hubv1.ClusterBomStatus, appID string) *hubv1.ApplicationState {
+	for i := range status.ApplicationStates {
+		if status.ApplicationStates[i].ID == appID {
+			return &status.ApplicationStates[i]
+		}
+	}
+
+	return nil
+}
+
+func readinessState(appState *hubv1.ApplicationState) string {
+	if appState == nil || appState.DetailedState.Readiness == nil {
+		return ""
+	}
+
+	return appState.DetailedState.Readiness.State
+}
+
+func hasChangedToStatus(oldCondition, newCondition *hubv1.ClusterBomCondition, status corev1.ConditionStatus) bool {
+	return newCondition != nil && newCondition.Status == status &&
+		(oldCondition == nil || oldCondition.Status != status)
+}
+
+func conditionStatus(condition *hubv1.ClusterBomCondition) string {
+	if condition == nil {
+		return ""
+	}
+
+	return string(condition.Status)
+}
+
+func conditionReasons(condition *hubv1.ClusterBomCondition) []string {
+	if condition.Reason == "" && condition.Message == "" {
+		return nil
+	}
+
+	return []string{formatReason(string(condition.Type), string(condition.Reason), condition.Message)}
+}
+
+func formatReason(conditionType, reason, message string) string {
+	if message == "" {
+		return fmt.Sprintf("%s: %s", conditionType, reason)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", conditionType, reason, message)
+}
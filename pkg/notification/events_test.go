@@ -0,0 +1,160 @@
+package notification
+
+import (
+	"testing"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputeApplicationEvents(t *testing.T) {
+	clusterBom := newTestClusterBom()
+
+	errorHistory := &hubv1.ErrorHistory{ErrorEntries: []hubv1.ErrorEntry{{Description: "install failed", Code: "ChartFetchFailed"}}}
+
+	oldStatus := &hubv1.ClusterBomStatus{
+		ApplicationStates: []hubv1.ApplicationState{
+			newTestApplicationState("app-failed", util.StatePending, ""),
+			newTestApplicationState("app-finally-failed", util.StateFailed, util.StateFailed),
+			newTestApplicationState("app-ok", util.StateFailed, util.StateFailed),
+			newTestApplicationState("app-unchanged", util.StateFailed, util.StateFailed),
+		},
+	}
+
+	newStatus := &hubv1.ClusterBomStatus{
+		ApplicationStates: []hubv1.ApplicationState{
+			newTestApplicationState("app-failed", util.StateFailed, util.StateFailed),
+			newTestApplicationState("app-finally-failed", util.StateFailed, util.StateFinallyFailed),
+			newTestApplicationState("app-ok", util.StateOk, util.StateOk),
+			newTestApplicationState("app-unchanged", util.StateFailed, util.StateFailed),
+			newTestApplicationState("app-new", util.StateOk, util.StateOk),
+		},
+	}
+	newStatus.ApplicationStates[0].DetailedState.LastOperation.Description = "install failed"
+	newStatus.ApplicationStates[0].DetailedState.LastOperation.ErrorHistory = errorHistory
+
+	events := ComputeEvents(clusterBom, oldStatus, newStatus)
+	assert.Equal(t, len(events), 4, "number of events")
+
+	assert.Equal(t, events[0].Type, hubv1.EventApplicationFailed, "event type")
+	assert.Equal(t, events[0].AppID, "app-failed", "app id")
+	assert.Equal(t, events[0].State, util.StateFailed, "state")
+	assert.Equal(t, events[0].PreviousState, util.StatePending, "previous state")
+	assert.Equal(t, events[0].ClusterBom.Name, clusterBom.Name, "clusterbom name")
+	assert.Equal(t, events[0].Reasons, []string{"install failed"}, "reasons")
+	assert.Equal(t, events[0].ErrorHistory, errorHistory, "error history")
+
+	assert.Equal(t, events[1].Type, hubv1.EventApplicationFinallyFailed, "event type")
+	assert.Equal(t, events[1].AppID, "app-finally-failed", "app id")
+
+	assert.Equal(t, events[2].Type, hubv1.EventApplicationOk, "event type")
+	assert.Equal(t, events[2].AppID, "app-ok", "app id")
+
+	assert.Equal(t, events[3].Type, hubv1.EventApplicationOk, "event type")
+	assert.Equal(t, events[3].AppID, "app-new", "app id")
+	assert.Equal(t, events[3].PreviousState, "", "previous state")
+
+	// the ids are stable and distinguish the events
+	assert.Equal(t, ComputeEvents(clusterBom, oldStatus, newStatus)[0].ID, events[0].ID, "event id")
+	assert.True(t, events[0].ID != events[1].ID, "event ids differ")
+}
+
+func TestComputeClusterBomEvents(t *testing.T) {
+	clusterBom := newTestClusterBom()
+
+	oldStatus := &hubv1.ClusterBomStatus{
+		Conditions: []hubv1.ClusterBomCondition{
+			{Type: hubv1.ClusterReachable, Status: corev1.ConditionTrue},
+			{Type: hubv1.ClusterBomReady, Status: corev1.ConditionFalse},
+		},
+	}
+
+	newStatus := &hubv1.ClusterBomStatus{
+		Conditions: []hubv1.ClusterBomCondition{
+			{Type: hubv1.ClusterReachable, Status: corev1.ConditionFalse, Reason: hubv1.ReasonClusterNotReachable, Message: "timeout"},
+			{Type: hubv1.ClusterBomReady, Status: corev1.ConditionTrue},
+		},
+	}
+
+	events := ComputeEvents(clusterBom, oldStatus, newStatus)
+	assert.Equal(t, len(events), 2, "number of events")
+
+	assert.Equal(t, events[0].Type, hubv1.EventClusterUnreachable, "event type")
+	assert.Equal(t, events[0].AppID, "", "app id")
+	assert.Equal(t, events[0].PreviousState, string(corev1.ConditionTrue), "previous state")
+	assert.Equal(t, events[0].Reasons, []string{"ClusterReachable: ReasonClusterNotReachable: timeout"}, "reasons")
+
+	assert.Equal(t, events[1].Type, hubv1.EventClusterBomReady, "event type")
+
+	// no events if the conditions are unchanged
+	events = ComputeEvents(clusterBom, newStatus, newStatus)
+	assert.Equal(t, len(events), 0, "number of events")
+}
+
+func TestRepeatedTransitionsGetNewIDs(t *testing.T) {
+	clusterBom := newTestClusterBom()
+
+	okStatus := &hubv1.ClusterBomStatus{
+		ApplicationStates: []hubv1.ApplicationState{newTestApplicationState("app", util.StateOk, util.StateOk)},
+		Conditions:        []hubv1.ClusterBomCondition{{Type: hubv1.ClusterBomReady, Status: corev1.ConditionTrue}},
+	}
+	failedStatus := &hubv1.ClusterBomStatus{
+		ApplicationStates: []hubv1.ApplicationState{newTestApplicationState("app", util.StateFailed, util.StateFailed)},
+		Conditions:        []hubv1.ClusterBomCondition{{Type: hubv1.ClusterBomReady, Status: corev1.ConditionFalse}},
+	}
+	failedStatus.ApplicationStates[0].DetailedState.LastOperation.Time = metav1.Unix(1000, 0)
+	okStatus.Conditions[0].LastTransitionTime = metav1.Unix(1000, 0)
+
+	failedEvents := ComputeEvents(clusterBom, okStatus, failedStatus)
+	assert.Equal(t, len(failedEvents), 1, "number of events of first failure")
+	readyEvents := ComputeEvents(clusterBom, failedStatus, okStatus)
+	assert.Equal(t, len(readyEvents), 2, "number of events of first recovery")
+
+	// the application fails again in the same generation and the clusterbom becomes ready again
+	failedStatus.ApplicationStates[0].DetailedState.LastOperation.Time = metav1.Unix(2000, 0)
+	okStatus.Conditions[0].LastTransitionTime = metav1.Unix(2000, 0)
+
+	repeatedFailedEvents := ComputeEvents(clusterBom, okStatus, failedStatus)
+	assert.Equal(t, len(repeatedFailedEvents), 1, "number of events of repeated failure")
+	assert.Equal(t, repeatedFailedEvents[0].Type, hubv1.EventApplicationFailed, "event type")
+	assert.True(t, repeatedFailedEvents[0].ID != failedEvents[0].ID, "event id of repeated failure")
+
+	repeatedReadyEvents := ComputeEvents(clusterBom, failedStatus, okStatus)
+	assert.Equal(t, repeatedReadyEvents[1].Type, hubv1.EventClusterBomReady, "event type")
+	assert.True(t, repeatedReadyEvents[1].ID != readyEvents[1].ID, "event id of repeated ready")
+
+	// the same transition gets the same id
+	assert.Equal(t, ComputeEvents(clusterBom, okStatus, failedStatus)[0].ID, repeatedFailedEvents[0].ID, "event id")
+}
+
+func newTestClusterBom() *hubv1.ClusterBom {
+	return &hubv1.ClusterBom{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "test-namespace",
+			Name:       "test-bom",
+			UID:        "test-uid",
+			Generation: 3,
+			Labels:     map[string]string{"landscape": "dev"},
+		},
+	}
+}
+
+func newTestApplicationState(appID, state, readiness string) hubv1.ApplicationState {
+	applicationState := hubv1.ApplicationState{
+		ID:    appID,
+		State: state,
+		DetailedState: hubv1.DetailedState{
+			Generation: 2,
+		},
+	}
+
+	if readiness != "" {
+		applicationState.DetailedState.Readiness = &hubv1.Readiness{State: readiness}
+	}
+
+	return applicationState
+}
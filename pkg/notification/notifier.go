@@ -0,0 +1,320 @@
+package notification
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/metrics"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	queueSize  = 1000
+	numWorkers = 4
+
+	defaultMaxRetries         = 5
+	defaultMaxEventsPerMinute = 60
+
+	defaultRetryBaseDelay = 2 * time.Second
+	maxRetryDelay         = time.Minute
+
+	// dedupTTL is the time for which the id of a sent event is remembered
+	dedupTTL = time.Hour
+)
+
+// Notifier sends the events of state transitions to the sinks of the matching notification policies. Events are
+// queued and sent by background workers, so that the reconciliation of clusterboms is not delayed by slow sinks.
+// The Notifier must be added to the manager, which starts the workers.
+type Notifier struct {
+	client         client.Client
+	log            logr.Logger
+	queue          chan *delivery
+	dedup          *dedupCache
+	limiters       *limiterMap
+	clients        *clientCache
+	retryBaseDelay time.Duration
+}
+
+// delivery is an event which is to be sent to the sink of a policy
+type delivery struct {
+	policy *hubv1.NotificationPolicy
+	event  *Event
+}
+
+func NewNotifier(cl client.Client, restrictions *SinkRestrictions, log logr.Logger) *Notifier {
+	return &Notifier{
+		client:         cl,
+		log:            log,
+		queue:          make(chan *delivery, queueSize),
+		dedup:          newDedupCache(dedupTTL),
+		limiters:       newLimiterMap(),
+		clients:        newClientCache(restrictions),
+		retryBaseDelay: defaultRetryBaseDelay,
+	}
+}
+
+// Notify queues the events for the transitions from the old to the new status of a clusterbom. Errors are only
+// logged, because notifications must not interfere with the reconciliation of the clusterbom.
+func (n *Notifier) Notify(ctx context.Context, clusterBom *hubv1.ClusterBom, oldStatus, newStatus *hubv1.ClusterBomStatus) {
+	log := util.GetLoggerFromContext(ctx)
+
+	events := ComputeEvents(clusterBom, oldStatus, newStatus)
+	if len(events) == 0 {
+		return
+	}
+
+	policyList := &hubv1.NotificationPolicyList{}
+	if err := n.client.List(ctx, policyList, client.InNamespace(clusterBom.Namespace)); err != nil {
+		log.Error(err, "could not list notification policies")
+		return
+	}
+
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+
+		for j := range events {
+			event := &events[j]
+
+			ok, err := matches(&policy.Spec.Filter, event)
+			if err != nil {
+				log.Error(err, "could not evaluate filter of notification policy", "policy", policy.Name)
+				break
+			}
+
+			if ok {
+				n.enqueue(log, policy, event)
+			}
+		}
+	}
+}
+
+func (n *Notifier) enqueue(log logr.Logger, policy *hubv1.NotificationPolicy, event *Event) {
+	eventType := string(event.Type)
+	dedupKey := dedupKeyOf(policy, event)
+
+	if !n.dedup.add(dedupKey) {
+		log.V(util.LogLevelDebug).Info("notification already sent", "policy", policy.Name, "eventID", event.ID)
+		metrics.ObserveNotification(eventType, metrics.NotificationDuplicate)
+		return
+	}
+
+	if !n.limiters.allow(policy) {
+		log.V(util.LogLevelWarning).Info("notification dropped due to rate limit", "policy", policy.Name, "eventID", event.ID)
+		metrics.ObserveNotification(eventType, metrics.NotificationRateLimited)
+		return
+	}
+
+	select {
+	case n.queue <- &delivery{policy: policy, event: event}:
+	default:
+		n.dedup.remove(dedupKey)
+		log.V(util.LogLevelWarning).Info("notification dropped, because the queue is full", "policy", policy.Name, "eventID", event.ID)
+		metrics.ObserveNotification(eventType, metrics.NotificationDropped)
+	}
+}
+
+// Start runs the workers until the context is done. It implements manager.Runnable.
+func (n *Notifier) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.work(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+func (n *Notifier) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-n.queue:
+			n.deliver(ctx, d)
+		}
+	}
+}
+
+// deliver sends an event to the sink of a policy. Failed requests are retried with exponential backoff if the error
+// is retryable. If the delivery fails finally, the event is removed from the deduplication cache, so that a later
+// transition with the same id is sent again.
+func (n *Notifier) deliver(ctx context.Context, d *delivery) {
+	eventType := string(d.event.Type)
+	log := n.log.WithValues(util.LogKeyClusterBomName, types.NamespacedName{Namespace: d.event.ClusterBom.Namespace, Name: d.event.ClusterBom.Name},
+		"policy", d.policy.Name, "eventType", eventType, "eventID", d.event.ID)
+
+	err := n.send(ctx, d)
+	if err != nil {
+		n.dedup.remove(dedupKeyOf(d.policy, d.event))
+		log.Error(err, "notification could not be sent")
+		metrics.ObserveNotification(eventType, metrics.NotificationFailed)
+		return
+	}
+
+	log.V(util.LogLevelDebug).Info("notification sent")
+	metrics.ObserveNotification(eventType, metrics.NotificationDelivered)
+}
+
+func (n *Notifier) send(ctx context.Context, d *delivery) error {
+	s, err := newSender(ctx, n.client, n.clients, d.policy)
+	if err != nil {
+		return err
+	}
+
+	maxRetries := defaultMaxRetries
+	if d.policy.Spec.Delivery.MaxRetries != nil {
+		maxRetries = int(*d.policy.Spec.Delivery.MaxRetries)
+	}
+
+	delay := n.retryBaseDelay
+	for retry := 0; ; retry++ {
+		err = s.send(ctx, d.event)
+		if err == nil || !isRetryable(err) || retry >= maxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// matches checks whether an event is selected by a filter
+func matches(filter *hubv1.NotificationFilter, event *Event) (bool, error) {
+	if len(filter.EventTypes) > 0 && !containsEventType(filter.EventTypes, event.Type) {
+		return false, nil
+	}
+
+	if event.AppID != "" && len(filter.AppIDs) > 0 && !util.ContainsString(event.AppID, filter.AppIDs) {
+		return false, nil
+	}
+
+	if filter.ClusterBomSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(filter.ClusterBomSelector)
+		if err != nil {
+			return false, err
+		}
+
+		if !selector.Matches(labels.Set(event.ClusterBom.Labels)) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func containsEventType(eventTypes []hubv1.NotificationEventType, eventType hubv1.NotificationEventType) bool {
+	for _, t := range eventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func dedupKeyOf(policy *hubv1.NotificationPolicy, event *Event) string {
+	return string(policy.UID) + "/" + event.ID
+}
+
+// dedupCache remembers keys for a fixed time
+type dedupCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]time.Time
+}
+
+func newDedupCache(ttl time.Duration) *dedupCache {
+	return &dedupCache{
+		ttl:     ttl,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// add adds a key and returns false if the key was already contained
+func (c *dedupCache) add(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for k, expiry := range c.entries {
+		if now.After(expiry) {
+			delete(c.entries, k)
+		}
+	}
+
+	if _, ok := c.entries[key]; ok {
+		return false
+	}
+
+	c.entries[key] = now.Add(c.ttl)
+	return true
+}
+
+func (c *dedupCache) remove(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+}
+
+// limiterMap contains a rate limiter per notification policy
+type limiterMap struct {
+	mutex    sync.Mutex
+	limiters map[types.UID]*policyLimiter
+}
+
+type policyLimiter struct {
+	maxEventsPerMinute int
+	limiter            *rate.Limiter
+}
+
+func newLimiterMap() *limiterMap {
+	return &limiterMap{
+		limiters: make(map[types.UID]*policyLimiter),
+	}
+}
+
+// allow checks whether the policy may send another event. The limiter of a policy is replaced if its rate limit
+// was changed.
+func (m *limiterMap) allow(policy *hubv1.NotificationPolicy) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	maxEventsPerMinute := defaultMaxEventsPerMinute
+	if policy.Spec.Delivery.MaxEventsPerMinute != nil {
+		maxEventsPerMinute = int(*policy.Spec.Delivery.MaxEventsPerMinute)
+	}
+
+	l, ok := m.limiters[policy.UID]
+	if !ok || l.maxEventsPerMinute != maxEventsPerMinute {
+		l = &policyLimiter{
+			maxEventsPerMinute: maxEventsPerMinute,
+			limiter:            rate.NewLimiter(rate.Every(time.Minute/time.Duration(maxEventsPerMinute)), maxEventsPerMinute),
+		}
+		m.limiters[policy.UID] = l
+	}
+
+	return l.limiter.Allow()
+}
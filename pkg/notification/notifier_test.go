@@ -0,0 +1,288 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type receivedRequest struct {
+	contentType   string
+	authorization string
+	body          []byte
+}
+
+func TestNotifier(t *testing.T) {
+	var calls int32
+	received := make(chan receivedRequest, 10)
+
+	// the sink fails once with a retryable error
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(req.Body)
+		received <- receivedRequest{
+			contentType:   req.Header.Get("Content-Type"),
+			authorization: req.Header.Get("Authorization"),
+			body:          body,
+		}
+	}))
+	defer server.Close()
+
+	policy := newTestPolicy("test-policy", server.URL)
+	policy.Spec.Sink.CABundle = caBundleOf(server)
+	policy.Spec.Sink.SecretRef = &corev1.LocalObjectReference{Name: "test-secret"}
+	policy.Spec.Filter.EventTypes = []hubv1.NotificationEventType{hubv1.EventApplicationFailed}
+
+	otherPolicy := newTestPolicy("other-policy", server.URL)
+	otherPolicy.Spec.Sink.CABundle = caBundleOf(server)
+	otherPolicy.Spec.Filter.AppIDs = []string{"other-app"}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "test-secret"},
+		Data:       map[string][]byte{authorizationKey: []byte("Bearer test-token")},
+	}
+
+	notifier := NewNotifier(newTestClient(policy, otherPolicy, secret), testRestrictions, ctrl.Log.WithName("test"))
+	notifier.retryBaseDelay = time.Millisecond
+
+	ctx, _ := util.NewContextAndLogger(ctrl.Log.WithName("test"))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() { _ = notifier.Start(ctx) }()
+
+	clusterBom := newTestClusterBom()
+	oldStatus := &hubv1.ClusterBomStatus{
+		ApplicationStates: []hubv1.ApplicationState{newTestApplicationState("test-app", util.StatePending, "")},
+	}
+	newStatus := &hubv1.ClusterBomStatus{
+		ApplicationStates: []hubv1.ApplicationState{newTestApplicationState("test-app", util.StateFailed, util.StateFailed)},
+	}
+
+	notifier.Notify(ctx, clusterBom, oldStatus, newStatus)
+
+	request := waitForRequest(t, received)
+	assert.Equal(t, request.contentType, contentTypeCloudEvents, "content type")
+	assert.Equal(t, request.authorization, "Bearer test-token", "authorization")
+
+	ce := cloudEvent{}
+	assert.NoErr(t, json.Unmarshal(request.body, &ce))
+	assert.Equal(t, ce.SpecVersion, cloudEventsSpecVersion, "spec version")
+	assert.Equal(t, ce.Type, cloudEventsTypePrefix+string(hubv1.EventApplicationFailed), "type")
+	assert.Equal(t, ce.Source, "/apis/hub.k8s.sap.com/v1/namespaces/test-namespace/clusterboms/test-bom", "source")
+	assert.Equal(t, ce.Subject, "test-app", "subject")
+	assert.Equal(t, ce.Data.AppID, "test-app", "app id")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2), "number of calls")
+
+	// the same transition is not sent again
+	notifier.Notify(ctx, clusterBom, oldStatus, newStatus)
+	select {
+	case <-received:
+		t.Fatal("duplicate notification was sent")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNotifierWithoutRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	policy := newTestPolicy("test-policy", server.URL)
+	policy.Spec.Sink.CABundle = caBundleOf(server)
+	policy.Spec.Sink.Format = hubv1.NotificationFormatJSON
+
+	notifier := NewNotifier(newTestClient(policy), testRestrictions, ctrl.Log.WithName("test"))
+	notifier.retryBaseDelay = time.Millisecond
+
+	d := &delivery{policy: policy, event: &Event{ID: "test-id", Type: hubv1.EventApplicationOk}}
+	err := notifier.send(context.Background(), d)
+	assert.True(t, err != nil, "client errors are returned")
+	assert.False(t, isRetryable(err), "client errors are not retryable")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1), "number of calls")
+}
+
+// TestSinkRestrictions tests that events are only sent to https urls of allowed hosts, and not to internal addresses
+// if no hosts are allowed explicitly
+func TestSinkRestrictions(t *testing.T) {
+	var calls int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		allowedHosts  []string
+		expectedError bool
+	}{
+		{"allowed host", server.URL, []string{"127.0.0.1"}, false},
+		{"allowed domain", "https://receiver.example.com", []string{".example.com"}, false},
+		{"http url", "http://127.0.0.1", []string{"127.0.0.1"}, true},
+		{"other host", server.URL, []string{"receiver.example.com"}, true},
+		{"other domain", "https://receiver.example.org", []string{".example.com"}, true},
+		{"internal address without allowed hosts", server.URL, nil, true},
+		{"link-local address without allowed hosts", "https://169.254.169.254/latest", nil, true},
+		{"private address without allowed hosts", "https://10.1.2.3", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restrictions := &SinkRestrictions{AllowedHosts: tt.allowedHosts}
+			err := restrictions.checkURL(tt.url)
+			if err == nil {
+				policy := newTestPolicy("test-policy", tt.url)
+				policy.Spec.Sink.CABundle = caBundleOf(server)
+
+				var httpClient *http.Client
+				httpClient, err = newClientCache(restrictions).get(policy)
+				assert.NoErr(t, err)
+
+				if tt.url == server.URL || tt.allowedHosts == nil {
+					var res *http.Response
+					res, err = httpClient.Post(tt.url, contentTypeJSON, nil)
+					if err == nil {
+						res.Body.Close()
+					}
+				}
+			}
+
+			assert.Equal(t, err != nil, tt.expectedError, "error: "+fmt.Sprint(err))
+		})
+	}
+
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1), "number of calls")
+}
+
+// TestClientCache tests that the http client of a policy is reused, and replaced if the sink was changed
+func TestClientCache(t *testing.T) {
+	clients := newClientCache(testRestrictions)
+	policy := newTestPolicy("test-policy", "https://127.0.0.1")
+
+	first, err := clients.get(policy)
+	assert.NoErr(t, err)
+	second, err := clients.get(policy)
+	assert.NoErr(t, err)
+	assert.True(t, first == second, "client is reused")
+
+	timeoutSeconds := int32(5)
+	policy.Spec.Sink.TimeoutSeconds = &timeoutSeconds
+	third, err := clients.get(policy)
+	assert.NoErr(t, err)
+	assert.True(t, third != second, "client is replaced after a change of the sink")
+	assert.Equal(t, third.Timeout, 5*time.Second, "timeout")
+
+	otherPolicy := newTestPolicy("other-policy", "https://127.0.0.1")
+	other, err := clients.get(otherPolicy)
+	assert.NoErr(t, err)
+	assert.True(t, other != third, "client per policy")
+	assert.Equal(t, len(clients.clients), 2, "number of clients")
+
+	policy.Spec.Sink.CABundle = "invalid"
+	_, err = clients.get(policy)
+	assert.NotNil(t, err, "error for invalid caBundle")
+}
+
+func TestMatches(t *testing.T) {
+	event := &Event{
+		Type:       hubv1.EventApplicationOk,
+		AppID:      "test-app",
+		ClusterBom: ClusterBomReference{Labels: map[string]string{"landscape": "dev"}},
+	}
+
+	tests := []struct {
+		name     string
+		filter   hubv1.NotificationFilter
+		expected bool
+	}{
+		{"empty filter", hubv1.NotificationFilter{}, true},
+		{"matching event type", hubv1.NotificationFilter{EventTypes: []hubv1.NotificationEventType{hubv1.EventApplicationOk}}, true},
+		{"other event type", hubv1.NotificationFilter{EventTypes: []hubv1.NotificationEventType{hubv1.EventApplicationFailed}}, false},
+		{"matching app id", hubv1.NotificationFilter{AppIDs: []string{"test-app"}}, true},
+		{"other app id", hubv1.NotificationFilter{AppIDs: []string{"other-app"}}, false},
+		{"matching selector", hubv1.NotificationFilter{ClusterBomSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"landscape": "dev"}}}, true},
+		{"other selector", hubv1.NotificationFilter{ClusterBomSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"landscape": "live"}}}, false},
+	}
+
+	for _, test := range tests {
+		ok, err := matches(&test.filter, event)
+		assert.NoErr(t, err)
+		assert.Equal(t, ok, test.expected, test.name)
+	}
+
+	// events of the clusterbom are not filtered by app ids
+	ok, err := matches(&hubv1.NotificationFilter{AppIDs: []string{"other-app"}}, &Event{Type: hubv1.EventClusterBomReady})
+	assert.NoErr(t, err)
+	assert.True(t, ok, "clusterbom event matches")
+}
+
+func TestRateLimit(t *testing.T) {
+	maxEventsPerMinute := int32(2)
+	policy := newTestPolicy("test-policy", "http://localhost")
+	policy.Spec.Delivery.MaxEventsPerMinute = &maxEventsPerMinute
+
+	limiters := newLimiterMap()
+	assert.True(t, limiters.allow(policy), "first event")
+	assert.True(t, limiters.allow(policy), "second event")
+	assert.False(t, limiters.allow(policy), "third event")
+}
+
+// testRestrictions allow the test servers, which listen on the loopback address
+var testRestrictions = &SinkRestrictions{AllowedHosts: []string{"127.0.0.1"}}
+
+func caBundleOf(server *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func newTestPolicy(name, url string) *hubv1.NotificationPolicy {
+	return &hubv1.NotificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: name, UID: types.UID("uid-" + name)},
+		Spec: hubv1.NotificationPolicySpec{
+			Sink: hubv1.NotificationSink{URL: url},
+		},
+	}
+}
+
+func newTestClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = hubv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func waitForRequest(t *testing.T, received chan receivedRequest) receivedRequest {
+	select {
+	case request := <-received:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not sent")
+		return receivedRequest{}
+	}
+}
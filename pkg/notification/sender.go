@@ -0,0 +1,361 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+
+	// authorizationKey is the key of the secret of a sink whose value is sent as Authorization header
+	authorizationKey = "authorization"
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsTypePrefix  = "com.sap.k8s.hub."
+
+	contentTypeCloudEvents = "application/cloudevents+json"
+	contentTypeJSON        = "application/json"
+
+	maxResponseSize = 64 * 1024
+
+	// clientIdleTTL is the time after which the http client of a policy without notifications is removed
+	clientIdleTTL = time.Hour
+	// idleConnTimeout is the time after which an idle connection to a sink is closed
+	idleConnTimeout = 90 * time.Second
+)
+
+// internalNetworks are the address ranges to which no events are sent if no allowed hosts are configured, in addition
+// to loopback, link-local, multicast and unspecified addresses
+var internalNetworks = parseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+
+// SinkRestrictions restrict the sinks of notification policies. The policies are created by the users of the
+// namespaces, but the requests are sent from the network of the controller, which must not be used to reach internal
+// endpoints. Sinks must always use https.
+type SinkRestrictions struct {
+	// AllowedHosts are the hosts, or domains with a leading dot, to which events may be sent. If empty, events may be
+	// sent to all hosts except those with private, loopback or link-local addresses.
+	AllowedHosts []string
+}
+
+// cloudEvent is an event in the structured content mode of CloudEvents 1.0
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            metav1.Time `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            *Event      `json:"data"`
+}
+
+// sender sends events to the sink of a policy
+type sender struct {
+	url           string
+	format        hubv1.NotificationFormat
+	authorization string
+	httpClient    *http.Client
+}
+
+// sendError is the error of a request to a sink
+type sendError struct {
+	err       error
+	retryable bool
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+func isRetryable(err error) bool {
+	var sendErr *sendError
+	return errors.As(err, &sendErr) && sendErr.retryable
+}
+
+func newSender(ctx context.Context, cl client.Client, clients *clientCache, policy *hubv1.NotificationPolicy) (*sender, error) {
+	sink := &policy.Spec.Sink
+
+	if err := clients.restrictions.checkURL(sink.URL); err != nil {
+		return nil, errors.Wrapf(err, "sink of notification policy %s is not allowed", policy.Name)
+	}
+
+	httpClient, err := clients.get(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := ""
+	if sink.SecretRef != nil {
+		secret := &corev1.Secret{}
+		secretKey := types.NamespacedName{Namespace: policy.Namespace, Name: sink.SecretRef.Name}
+		if err := cl.Get(ctx, secretKey, secret); err != nil {
+			return nil, errors.Wrapf(err, "could not read secret %s of notification policy %s", secretKey, policy.Name)
+		}
+
+		value, ok := secret.Data[authorizationKey]
+		if !ok {
+			return nil, fmt.Errorf("secret %s of notification policy %s has no key %s", secretKey, policy.Name, authorizationKey)
+		}
+		authorization = string(value)
+	}
+
+	format := sink.Format
+	if format == "" {
+		format = hubv1.NotificationFormatCloudEvents
+	}
+
+	return &sender{
+		url:           sink.URL,
+		format:        format,
+		authorization: authorization,
+		httpClient:    httpClient,
+	}, nil
+}
+
+// send posts an event to the sink. Network errors, server errors and throttling responses are retryable.
+func (s *sender) send(ctx context.Context, event *Event) error {
+	body, contentType, err := s.marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not create notification request")
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	if s.authorization != "" {
+		req.Header.Set("Authorization", s.authorization)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return &sendError{err: errors.Wrap(err, "notification request failed"), retryable: true}
+	}
+	defer res.Body.Close()
+
+	// the response body is read, so that the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, maxResponseSize))
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	return &sendError{
+		err:       fmt.Errorf("notification request returned status %d", res.StatusCode),
+		retryable: res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500,
+	}
+}
+
+func (s *sender) marshal(event *Event) ([]byte, string, error) {
+	if s.format == hubv1.NotificationFormatJSON {
+		body, err := json.Marshal(event)
+		return body, contentTypeJSON, err
+	}
+
+	body, err := json.Marshal(&cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          fmt.Sprintf("/apis/%s/namespaces/%s/clusterboms/%s", hubv1.GroupVersion, event.ClusterBom.Namespace, event.ClusterBom.Name),
+		Type:            cloudEventsTypePrefix + string(event.Type),
+		Subject:         event.AppID,
+		Time:            event.Time,
+		DataContentType: contentTypeJSON,
+		Data:            event,
+	})
+	return body, contentTypeCloudEvents, err
+}
+
+// checkURL checks that the url of a sink uses https and, if allowed hosts are configured, that its host is one of them
+func (r *SinkRestrictions) checkURL(rawURL string) error {
+	sinkURL, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid url")
+	}
+
+	if sinkURL.Scheme != "https" {
+		return errors.New("url must use https")
+	}
+
+	if len(r.AllowedHosts) > 0 && !r.isAllowedHost(sinkURL.Hostname()) {
+		return fmt.Errorf("host %s is not allowed", sinkURL.Hostname())
+	}
+
+	return nil
+}
+
+func (r *SinkRestrictions) isAllowedHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowedHost := range r.AllowedHosts {
+		allowedHost = strings.ToLower(allowedHost)
+		if host == allowedHost || (strings.HasPrefix(allowedHost, ".") && strings.HasSuffix(host, allowedHost)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAddress rejects connections to internal addresses. It is called with the resolved address for each connection,
+// so that a host name cannot be changed to an internal address after the check of the url.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isInternalIP(ip) {
+		return fmt.Errorf("connection to internal address %s is not allowed", host)
+	}
+
+	return nil
+}
+
+func isInternalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// clientCache contains an http client per notification policy, so that the connections to a sink are reused. The
+// client of a policy is replaced if its caBundle or timeout was changed, and removed if the policy has sent no events
+// for clientIdleTTL.
+type clientCache struct {
+	mutex        sync.Mutex
+	restrictions SinkRestrictions
+	clients      map[types.UID]*policyClient
+}
+
+type policyClient struct {
+	caBundle string
+	timeout  time.Duration
+	client   *http.Client
+	lastUsed time.Time
+}
+
+func newClientCache(restrictions *SinkRestrictions) *clientCache {
+	return &clientCache{
+		restrictions: *restrictions,
+		clients:      make(map[types.UID]*policyClient),
+	}
+}
+
+// get returns the http client for the sink of a policy
+func (c *clientCache) get(policy *hubv1.NotificationPolicy) (*http.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for uid, pc := range c.clients {
+		if now.Sub(pc.lastUsed) > clientIdleTTL {
+			pc.client.CloseIdleConnections()
+			delete(c.clients, uid)
+		}
+	}
+
+	sink := &policy.Spec.Sink
+	timeout := defaultTimeout
+	if sink.TimeoutSeconds != nil {
+		timeout = time.Duration(*sink.TimeoutSeconds) * time.Second
+	}
+
+	pc, ok := c.clients[policy.UID]
+	if !ok || pc.caBundle != sink.CABundle || pc.timeout != timeout {
+		httpClient, err := c.newHTTPClient(policy, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			pc.client.CloseIdleConnections()
+		}
+
+		pc = &policyClient{
+			caBundle: sink.CABundle,
+			timeout:  timeout,
+			client:   httpClient,
+		}
+		c.clients[policy.UID] = pc
+	}
+
+	pc.lastUsed = now
+	return pc.client, nil
+}
+
+// newHTTPClient creates an http client which trusts the caBundle of the sink. It uses no proxy and follows no
+// redirects, because both would bypass the check of the sink.
+func (c *clientCache) newHTTPClient(policy *hubv1.NotificationPolicy, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if policy.Spec.Sink.CABundle != "" {
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM([]byte(policy.Spec.Sink.CABundle)); !ok {
+			return nil, errors.New("could not parse caBundle of notification policy " + policy.Name)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if len(c.restrictions.AllowedHosts) == 0 {
+		dialer.Control = checkAddress
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:     dialer.DialContext,
+			TLSClientConfig: tlsConfig,
+			IdleConnTimeout: idleConnTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
@@ -0,0 +1,153 @@
+package secretsync
+
+import (
+	"context"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	"github.com/gardener/potter-controller/pkg/manifest"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const couldNotParse = "could not parse typeSpecificData"
+
+// NewSecretSyncDeployerDI returns a deployer which copies secrets from the namespace of the clusterbom into the target
+// cluster. The copies are applied like the objects of the manifest deployer, so that copies which are no longer
+// specified are deleted, as well as all copies when the application is removed.
+func NewSecretSyncDeployerDI(crAndSecretClient client.Client, uncachedClient synchronize.UncachedClient,
+	blockObject *synchronize.BlockObject) deployutil.DeployItemDeployer {
+	renderer := &secretSyncRenderer{uncachedClient: uncachedClient}
+	return manifest.NewManifestDeployerDIWithRenderer(crAndSecretClient, uncachedClient, blockObject, renderer)
+}
+
+type secretSyncRenderer struct {
+	uncachedClient synchronize.UncachedClient
+}
+
+func (r *secretSyncRenderer) Render(ctx context.Context, deployData *deployutil.DeployData) ([]*unstructured.Unstructured, string, error) {
+	secretSyncSpecificData, err := apitypes.NewSecretSyncSpecificData(&deployData.Configuration.DeploymentConfig.TypeSpecificData)
+	if err != nil {
+		return nil, "", errors.Wrap(err, couldNotParse)
+	}
+
+	objects := []*unstructured.Unstructured{}
+
+	for i := range secretSyncSpecificData.Secrets {
+		syncedSecret := &secretSyncSpecificData.Secrets[i]
+
+		sourceSecret, err := r.readSourceSecret(ctx, deployData.GetNamespace(), syncedSecret.Name)
+		if err != nil {
+			return nil, "", err
+		}
+
+		data, err := copyData(sourceSecret, syncedSecret.Keys)
+		if err != nil {
+			return nil, "", err
+		}
+
+		secretType := syncedSecret.Type
+		if secretType == "" {
+			secretType = sourceSecret.Type
+		}
+
+		for _, namespace := range syncedSecret.Namespaces {
+			obj, err := newSecretCopy(syncedSecret.GetTargetName(), namespace, secretType, data)
+			if err != nil {
+				return nil, "", err
+			}
+
+			objects = append(objects, obj)
+		}
+	}
+
+	return objects, "", nil
+}
+
+func (r *secretSyncRenderer) InternalExport(deployData *deployutil.DeployData) (map[string]apitypes.InternalExportEntry, error) {
+	return nil, nil
+}
+
+// readSourceSecret reads a secret in the namespace of the clusterbom. Only secrets with the label
+// hub.k8s.sap.com/purpose: secretsync-source can be copied. The secret is read uncached, because only the metadata of
+// the source secrets are watched.
+func (r *secretSyncRenderer) readSourceSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	log := util.GetLoggerFromContext(ctx)
+
+	secret := &corev1.Secret{}
+	err := r.uncachedClient.GetUncached(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.New("source secret " + name + " not found")
+		}
+
+		log.Error(err, "could not read source secret", util.LogKeySecretName, name)
+		return nil, errors.Wrap(err, "could not read source secret "+name)
+	}
+
+	if !util.HasLabel(secret, hubv1.LabelPurpose, util.PurposeSecretSyncSource) {
+		return nil, errors.Errorf("source secret %s has not the label %s: %s", name, hubv1.LabelPurpose,
+			util.PurposeSecretSyncSource)
+	}
+
+	return secret, nil
+}
+
+// copyData returns the data of the copies. If keys are specified, only these keys are copied, with their target keys.
+func copyData(sourceSecret *corev1.Secret, keys []apitypes.SyncedSecretKey) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+
+	if len(keys) == 0 {
+		for key, value := range sourceSecret.Data {
+			data[key] = value
+		}
+
+		return data, nil
+	}
+
+	for i := range keys {
+		value, ok := sourceSecret.Data[keys[i].Key]
+		if !ok {
+			return nil, errors.Errorf("source secret %s has no key %s", sourceSecret.Name, keys[i].Key)
+		}
+
+		data[keys[i].GetTargetKey()] = value
+	}
+
+	return data, nil
+}
+
+func newSecretCopy(name, namespace string, secretType corev1.SecretType, data map[string][]byte) (*unstructured.Unstructured, error) {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: secretType,
+		Data: data,
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not convert secret "+namespace+"/"+name)
+	}
+
+	// remove empty fields, so that they are not applied
+	unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
@@ -0,0 +1,163 @@
+package secretsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/potter-controller/api/apitypes"
+	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
+	hubtesting "github.com/gardener/potter-controller/pkg/testing"
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/arschles/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "garden-test"
+
+func TestRenderAllKeys(t *testing.T) {
+	renderer := newTestRenderer(newTestSourceSecret("registry", true))
+
+	deployData := newTestDeployData(t, &apitypes.SecretSyncSpecificData{
+		Secrets: []apitypes.SyncedSecret{
+			{Name: "registry", Namespaces: []string{"app1", "app2"}},
+		},
+	})
+
+	objects, namespace, err := renderer.Render(hubtesting.CreateTestContext(), deployData)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, namespace, "", "namespace")
+	assert.Equal(t, len(objects), 2, "number of objects")
+
+	firstCopy := toSecret(t, objects[0])
+	assert.Equal(t, firstCopy.Name, "registry", "name")
+	assert.Equal(t, firstCopy.Namespace, "app1", "namespace")
+	assert.Equal(t, firstCopy.Type, corev1.SecretTypeOpaque, "type")
+	assert.Equal(t, len(firstCopy.Data), 2, "number of keys")
+	assert.Equal(t, string(firstCopy.Data["username"]), "user", "username")
+
+	secondCopy := toSecret(t, objects[1])
+	assert.Equal(t, secondCopy.Namespace, "app2", "namespace")
+}
+
+func TestRenderSelectedKeys(t *testing.T) {
+	renderer := newTestRenderer(newTestSourceSecret("registry", true))
+
+	deployData := newTestDeployData(t, &apitypes.SecretSyncSpecificData{
+		Secrets: []apitypes.SyncedSecret{
+			{
+				Name:       "registry",
+				TargetName: "pull-secret",
+				Namespaces: []string{"app"},
+				Keys:       []apitypes.SyncedSecretKey{{Key: "password", TargetKey: "token"}},
+				Type:       corev1.SecretTypeBasicAuth,
+			},
+		},
+	})
+
+	objects, _, err := renderer.Render(hubtesting.CreateTestContext(), deployData)
+	assert.Nil(t, err, "error")
+	assert.Equal(t, len(objects), 1, "number of objects")
+
+	secretCopy := toSecret(t, objects[0])
+	assert.Equal(t, secretCopy.Name, "pull-secret", "name")
+	assert.Equal(t, secretCopy.Type, corev1.SecretTypeBasicAuth, "type")
+	assert.Equal(t, len(secretCopy.Data), 1, "number of keys")
+	assert.Equal(t, string(secretCopy.Data["token"]), "secret", "renamed key")
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		syncedSecret apitypes.SyncedSecret
+	}{
+		{
+			name:         "missing source secret",
+			syncedSecret: apitypes.SyncedSecret{Name: "missing", Namespaces: []string{"app"}},
+		},
+		{
+			name:         "source secret without label",
+			syncedSecret: apitypes.SyncedSecret{Name: "unlabeled", Namespaces: []string{"app"}},
+		},
+		{
+			name: "missing key",
+			syncedSecret: apitypes.SyncedSecret{
+				Name:       "registry",
+				Namespaces: []string{"app"},
+				Keys:       []apitypes.SyncedSecretKey{{Key: "token"}},
+			},
+		},
+	}
+
+	renderer := newTestRenderer(newTestSourceSecret("registry", true), newTestSourceSecret("unlabeled", false))
+
+	for i := range tests {
+		test := &tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			deployData := newTestDeployData(t, &apitypes.SecretSyncSpecificData{
+				Secrets: []apitypes.SyncedSecret{test.syncedSecret},
+			})
+
+			_, _, err := renderer.Render(hubtesting.CreateTestContext(), deployData)
+			assert.NotNil(t, err, "error")
+		})
+	}
+}
+
+func newTestRenderer(secrets ...runtime.Object) *secretSyncRenderer {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	return &secretSyncRenderer{uncachedClient: &uncachedFakeClient{fake.NewFakeClientWithScheme(scheme, secrets...)}} // nolint
+}
+
+// uncachedFakeClient provides the fake client as synchronize.UncachedClient
+type uncachedFakeClient struct {
+	client.Client
+}
+
+func (c *uncachedFakeClient) GetUncached(ctx context.Context, key types.NamespacedName, obj client.Object) error {
+	return c.Get(ctx, key, obj)
+}
+
+func (c *uncachedFakeClient) ListUncached(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.List(ctx, list, opts...)
+}
+
+func newTestSourceSecret(name string, labeled bool) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"username": []byte("user"),
+			"password": []byte("secret"),
+		},
+	}
+
+	if labeled {
+		secret.Labels = map[string]string{hubv1.LabelPurpose: util.PurposeSecretSyncSource}
+	}
+
+	return secret
+}
+
+func toSecret(t *testing.T, obj *unstructured.Unstructured) *corev1.Secret {
+	secret := &corev1.Secret{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, secret)
+	assert.Nil(t, err, "error")
+	return secret
+}
+
+func newTestDeployData(t *testing.T, typeSpecificData *apitypes.SecretSyncSpecificData) *deployutil.DeployData {
+	deployItem := hubtesting.CreateDeployItemForConfig(t, "test-item", testNamespace, typeSpecificData, nil)
+	return hubtesting.CreateDeployData(t, deployItem)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-logr/logr"
 
+	"github.com/gardener/potter-controller/pkg/metrics"
 	"github.com/gardener/potter-controller/pkg/util"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
@@ -69,9 +70,59 @@ func (r *mutexMap) releaseMutex(key *types.NamespacedName) {
 	}
 }
 
+// holdMap tracks since when blocks of clusterboms are held by this controller, so that the hold time can be measured.
+// Several reconcilers can hold the block of the same clusterbom at the same time; the hold time ends when the last of
+// them releases the block.
+type holdMap struct {
+	mutex   sync.Mutex
+	holdMap map[types.NamespacedName]*holdMapEntry
+}
+
+type holdMapEntry struct {
+	since   time.Time
+	counter int
+}
+
+func newHoldMap() *holdMap {
+	return &holdMap{
+		holdMap: make(map[types.NamespacedName]*holdMapEntry),
+	}
+}
+
+func (r *holdMap) hold(key *types.NamespacedName) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry := r.holdMap[*key]
+	if entry == nil {
+		entry = &holdMapEntry{since: time.Now()}
+		r.holdMap[*key] = entry
+	}
+
+	entry.counter++
+}
+
+func (r *holdMap) release(key *types.NamespacedName) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry := r.holdMap[*key]
+	if entry == nil {
+		return
+	}
+
+	entry.counter--
+
+	if entry.counter == 0 {
+		delete(r.holdMap, *key)
+		metrics.ObserveBlockHold(time.Since(entry.since))
+	}
+}
+
 func NewBlockObject(excludedBoms []types.NamespacedName, syncDisabled bool) *BlockObject {
 	return &BlockObject{
 		mutexHelper:  newMutexMap(),
+		holdHelper:   newHoldMap(),
 		uniqueID:     string(uuid.NewUUID()),
 		excludedBoms: excludedBoms,
 		clock:        &util.RealClock{},
@@ -81,6 +132,7 @@ func NewBlockObject(excludedBoms []types.NamespacedName, syncDisabled bool) *Blo
 
 type BlockObject struct {
 	mutexHelper  *mutexMap
+	holdHelper   *holdMap
 	uniqueID     string
 	excludedBoms []types.NamespacedName
 	clock        util.Clock
@@ -92,7 +144,7 @@ type BlockObject struct {
 // in case of successful getting the log return (true, undefined, nil)
 // otherwise return (false, duration to wait until retry, nil)
 func (r *BlockObject) Block(ctx context.Context, clusterbomKey *types.NamespacedName, clt UncachedClient,
-	duration time.Duration, ignoreExclusionList bool) (bool, time.Duration, error) {
+	duration time.Duration, ignoreExclusionList bool) (ok bool, retryDuration time.Duration, err error) {
 	if r.syncDisabled {
 		return true, time.Second, nil
 	}
@@ -101,6 +153,11 @@ func (r *BlockObject) Block(ctx context.Context, clusterbomKey *types.Namespaced
 		return true, time.Second, nil
 	}
 
+	start := time.Now()
+	defer func() {
+		r.observeBlock(clusterbomKey, start, ok, err)
+	}()
+
 	syncObject := r.mutexHelper.getMutex(clusterbomKey)
 	defer r.mutexHelper.releaseMutex(clusterbomKey)
 
@@ -111,7 +168,7 @@ func (r *BlockObject) Block(ctx context.Context, clusterbomKey *types.Namespaced
 
 	var clusterBomSync hubv1.ClusterBomSync
 
-	err := clt.GetUncached(ctx, *clusterbomKey, &clusterBomSync)
+	err = clt.GetUncached(ctx, *clusterbomKey, &clusterBomSync)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			clusterBomSync = r.newClusterBomSync(clusterbomKey, r.uniqueID, duration)
@@ -140,7 +197,7 @@ func (r *BlockObject) Block(ctx context.Context, clusterbomKey *types.Namespaced
 		log.V(util.LogLevelDebug).Info("Blocked by someone else", "now", now, "blocked-until", clusterBomSync.Spec.Until.Time,
 			"blocked-by", clusterBomSync.Spec.ID, "own-id", r.uniqueID)
 
-		retryDuration := clusterBomSync.Spec.Until.Time.Add(timeBuffer).Add(time.Second * 5).Sub(r.clock.Now())
+		retryDuration = clusterBomSync.Spec.Until.Time.Add(timeBuffer).Add(time.Second * 5).Sub(r.clock.Now())
 
 		if retryDuration < 1*time.Second {
 			retryDuration = 1 * time.Second
@@ -244,6 +301,8 @@ func (r *BlockObject) Release(ctx context.Context, clusterbomKey *types.Namespac
 	if !ignoreExclusionList && r.isExcluded(clusterbomKey) {
 		return
 	}
+
+	r.holdHelper.release(clusterbomKey)
 }
 
 func (r *BlockObject) DeleteBlock(ctx context.Context, clusterbomKey *types.NamespacedName, clt UncachedClient) error {
@@ -277,6 +336,18 @@ func (r *BlockObject) DeleteBlock(ctx context.Context, clusterbomKey *types.Name
 	return nil
 }
 
+func (r *BlockObject) observeBlock(clusterbomKey *types.NamespacedName, start time.Time, ok bool, err error) {
+	switch {
+	case err != nil:
+		metrics.ObserveBlockWait(metrics.BlockResultFailed, start)
+	case ok:
+		metrics.ObserveBlockWait(metrics.BlockResultAcquired, start)
+		r.holdHelper.hold(clusterbomKey)
+	default:
+		metrics.ObserveBlockWait(metrics.BlockResultBlocked, start)
+	}
+}
+
 func (r *BlockObject) isExcluded(key *types.NamespacedName) bool {
 	for _, nextBom := range r.excludedBoms {
 		if nextBom == *key {
@@ -50,6 +50,32 @@ func TestMutexMap(t *testing.T) {
 	assert.Equal(t, len(mutexMap.syncMap), 0, "length")
 }
 
+func TestHoldMap(t *testing.T) {
+	keyA := types.NamespacedName{Name: "A"}
+	keyB := types.NamespacedName{Name: "B"}
+
+	holdMap := newHoldMap()
+
+	holdMap.hold(&keyA)
+	since := holdMap.holdMap[keyA].since
+	holdMap.hold(&keyA)
+	holdMap.hold(&keyB)
+	assert.Equal(t, len(holdMap.holdMap), 2, "length")
+	assert.Equal(t, holdMap.holdMap[keyA].counter, 2, "counter for key A")
+	assert.Equal(t, holdMap.holdMap[keyA].since, since, "hold of key A starts with first holder")
+
+	holdMap.release(&keyA)
+	assert.Equal(t, holdMap.holdMap[keyA].counter, 1, "counter for key A")
+
+	holdMap.release(&keyA)
+	holdMap.release(&keyB)
+	assert.Equal(t, len(holdMap.holdMap), 0, "length")
+
+	// releasing a block which is not held has no effect
+	holdMap.release(&keyA)
+	assert.Equal(t, len(holdMap.holdMap), 0, "length")
+}
+
 func TestBlockObject_Block_Extend(t *testing.T) {
 	key := types.NamespacedName{Namespace: "testNamespace", Name: "testName"}
 	ctx := newTestContext()
@@ -285,6 +311,7 @@ func (c *testClock) SetTime(t time.Time) {
 func newTestBlockObject(uniqueID string, clock util.Clock) *BlockObject {
 	return &BlockObject{
 		mutexHelper:  newMutexMap(),
+		holdHelper:   newHoldMap(),
 		uniqueID:     uniqueID,
 		excludedBoms: nil,
 		clock:        clock,
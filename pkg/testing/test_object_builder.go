@@ -1,17 +1,25 @@
 package testing
 
 import (
+	"context"
 	"encoding/json"
+	gotesting "testing"
 
+	"github.com/arschles/assert"
 	"github.com/gardener/landscaper/apis/core/v1alpha1"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
+	"github.com/gardener/potter-controller/pkg/deployutil"
 	"github.com/gardener/potter-controller/pkg/util"
 )
 
+const TargetSecretName = "target-secret"
+
 func CreateClusterBom(clusterBomName, overallState string) *hubv1.ClusterBom {
 	testClusterBom := &hubv1.ClusterBom{
 		ObjectMeta: v1.ObjectMeta{
@@ -110,3 +118,46 @@ func CreateSecret(name string) *corev1.Secret {
 		},
 	}
 }
+
+// CreateTestContext returns a context with a logger which discards all messages.
+func CreateTestContext() context.Context {
+	return context.WithValue(context.Background(), util.LoggerKey{}, zapr.NewLogger(zap.NewNop()))
+}
+
+// CreateDeployItemForConfig returns a deploy item of generation 1 for the application "app". The type specific data is
+// marshaled into the deployment config, which may be nil.
+func CreateDeployItemForConfig(t *gotesting.T, name, namespace string, typeSpecificData interface{},
+	deploymentConfig *hubv1.DeploymentConfig) *v1alpha1.DeployItem {
+	if deploymentConfig == nil {
+		deploymentConfig = &hubv1.DeploymentConfig{}
+	}
+
+	rawTypeSpecificData, err := json.Marshal(typeSpecificData)
+	assert.Nil(t, err, "error")
+
+	deploymentConfig.ID = "app"
+	deploymentConfig.TypeSpecificData = runtime.RawExtension{Raw: rawTypeSpecificData}
+
+	encodedConfig, err := json.Marshal(&hubv1.HubDeployItemConfiguration{
+		LocalSecretRef:   TargetSecretName,
+		DeploymentConfig: *deploymentConfig,
+	})
+	assert.Nil(t, err, "error")
+
+	return &v1alpha1.DeployItem{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			Generation: 1,
+		},
+		Spec: v1alpha1.DeployItemSpec{
+			Configuration: &runtime.RawExtension{Raw: encodedConfig},
+		},
+	}
+}
+
+func CreateDeployData(t *gotesting.T, deployItem *v1alpha1.DeployItem) *deployutil.DeployData {
+	deployData, err := deployutil.NewDeployData(deployItem)
+	assert.Nil(t, err, "error")
+	return deployData
+}
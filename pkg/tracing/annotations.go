@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// annotationKeyPrefix is the prefix of the annotations which contain the fields of the W3C trace context, i.e.
+// potter.gardener.cloud/traceparent and potter.gardener.cloud/tracestate
+const annotationKeyPrefix = "potter.gardener.cloud/"
+
+var annotationKeys = []string{
+	annotationKeyPrefix + "traceparent",
+	annotationKeyPrefix + "tracestate",
+}
+
+// annotationCarrier stores the trace context in a map of annotations
+type annotationCarrier map[string]string
+
+func (c annotationCarrier) Get(key string) string {
+	return c[annotationKeyPrefix+key]
+}
+
+func (c annotationCarrier) Set(key, value string) {
+	c[annotationKeyPrefix+key] = value
+}
+
+func (c annotationCarrier) Keys() []string {
+	keys := []string{}
+	for key := range c {
+		if strings.HasPrefix(key, annotationKeyPrefix) {
+			keys = append(keys, strings.TrimPrefix(key, annotationKeyPrefix))
+		}
+	}
+
+	return keys
+}
+
+// InjectIntoAnnotations writes the trace context of the span in the given context into the annotations of the object.
+// The annotations remain unchanged if the context has no valid span, e.g. because tracing is disabled.
+func InjectIntoAnnotations(ctx context.Context, obj metav1.Object) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	obj.SetAnnotations(InjectIntoMap(ctx, obj.GetAnnotations()))
+}
+
+// InjectIntoMap returns a copy of the given annotations with the trace context of the span in the given context.
+// A trace context from the given annotations is replaced.
+func InjectIntoMap(ctx context.Context, annotations map[string]string) map[string]string {
+	result := make(map[string]string, len(annotations)+len(annotationKeys))
+	for key, value := range annotations {
+		result[key] = value
+	}
+
+	for _, key := range annotationKeys {
+		delete(result, key)
+	}
+
+	propagator.Inject(ctx, annotationCarrier(result))
+	return result
+}
+
+// ExtractFromAnnotations returns a context with the trace context from the annotations of the object as remote
+// parent, so that spans started with the returned context belong to the trace of the object. The given context is
+// returned unchanged if the object has no trace context.
+func ExtractFromAnnotations(ctx context.Context, obj metav1.Object) context.Context {
+	if _, ok := util.GetAnnotation(obj, annotationKeys[0]); !ok {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, annotationCarrier(obj.GetAnnotations()))
+}
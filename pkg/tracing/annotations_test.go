@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arschles/assert"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInjectAndExtract(t *testing.T) {
+	ctx := newTestContext(t)
+
+	obj := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"other":                            "value",
+				annotationKeyPrefix + "tracestate": "outdated=state",
+			},
+		},
+	}
+
+	InjectIntoAnnotations(ctx, obj)
+
+	annotations := obj.GetAnnotations()
+	assert.Equal(t, annotations["other"], "value", "other annotation")
+	assert.Equal(t, annotations[annotationKeyPrefix+"traceparent"],
+		"00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01", "traceparent annotation")
+	_, ok := annotations[annotationKeyPrefix+"tracestate"]
+	assert.False(t, ok, "outdated tracestate annotation removed")
+
+	extractedContext := ExtractFromAnnotations(context.Background(), obj)
+	spanContext := trace.SpanContextFromContext(extractedContext)
+	assert.True(t, spanContext.IsRemote(), "remote span context")
+	assert.Equal(t, spanContext.TraceID(), trace.SpanContextFromContext(ctx).TraceID(), "trace id")
+	assert.Equal(t, spanContext.SpanID(), trace.SpanContextFromContext(ctx).SpanID(), "span id")
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	obj := &corev1.ConfigMap{}
+
+	InjectIntoAnnotations(context.Background(), obj)
+
+	assert.Nil(t, obj.GetAnnotations(), "annotations")
+}
+
+func TestExtractWithoutTraceContext(t *testing.T) {
+	ctx := context.Background()
+
+	extractedContext := ExtractFromAnnotations(ctx, &corev1.ConfigMap{})
+
+	assert.Equal(t, extractedContext, ctx, "context")
+}
+
+func newTestContext(t *testing.T) context.Context {
+	traceID, err := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	assert.Nil(t, err, "error")
+	spanID, err := trace.SpanIDFromHex("0102030405060708")
+	assert.Nil(t, err, "error")
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	return trace.ContextWithSpanContext(context.Background(), spanContext)
+}
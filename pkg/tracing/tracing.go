@@ -0,0 +1,114 @@
+// Package tracing contains the OpenTelemetry tracing of the potter controller. A change of a clusterbom is traced
+// from the admission webhook through the ClusterBomReconciler, the DeploymentReconciler and the deployers to the
+// ClusterBomStateReconciler. The trace context is handed over between these components in annotations of the
+// clusterbom and its deploy items.
+//
+// Tracing is disabled unless an OTLP endpoint is configured. In this case the global tracer provider of OpenTelemetry
+// is a no-op provider, spans are not recorded, and no annotations are written.
+package tracing
+
+import (
+	"context"
+
+	"github.com/gardener/potter-controller/pkg/util"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/gardener/potter-controller"
+	serviceName = "potter-controller"
+)
+
+// keys of span attributes
+const (
+	AttributeConfigType   = "potter.config_type"
+	AttributeOutcome      = "potter.outcome"
+	AttributeOperation    = "potter.operation"
+	AttributeChartSource  = "potter.chart.source"
+	AttributeReleaseName  = "potter.release.name"
+	AttributeNamespace    = "potter.namespace"
+	AttributeReadiness    = "potter.readiness"
+	AttributeAllowed      = "potter.admission.allowed"
+	AttributeAdmissionUID = "potter.admission.uid"
+)
+
+// propagator defines the format of the trace context in http headers and annotations (W3C trace context)
+var propagator = propagation.TraceContext{}
+
+type Config struct {
+	// Endpoint is the address (host:port) of the OTLP gRPC receiver, e.g. an OpenTelemetry collector. Tracing is
+	// disabled if the endpoint is empty.
+	Endpoint string
+	// Insecure disables TLS for the connection to the endpoint.
+	Insecure bool
+	// SampleRatio is the fraction of new traces which are sampled. Spans with a sampled parent are always sampled.
+	SampleRatio float64
+}
+
+// Setup registers a tracer provider which exports the spans to the configured OTLP endpoint. The returned function
+// flushes the remaining spans and must be called before the process terminates.
+func Setup(ctx context.Context, config *Config, log logr.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	if config.Endpoint == "" {
+		log.V(util.LogLevelWarning).Info("tracing is not enabled")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	log.V(util.LogLevelWarning).Info("tracing is enabled", "endpoint", config.Endpoint, "sampleRatio", config.SampleRatio)
+	return tracerProvider.Shutdown, nil
+}
+
+// Start starts a span as child of the span in the given context.
+func Start(ctx context.Context, spanName string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, spanName, trace.WithAttributes(attributes...))
+}
+
+// End records the error, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// EnrichContextAndLogger adds the trace id of the span in the given context to the logger, so that log entries can be
+// related to the trace.
+func EnrichContextAndLogger(ctx context.Context) (context.Context, logr.Logger) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ctx, util.GetLoggerFromContext(ctx)
+	}
+
+	return util.EnrichContextAndLogger(ctx, util.LogKeyTraceID, spanContext.TraceID().String())
+}
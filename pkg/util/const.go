@@ -25,17 +25,21 @@ const (
 	LogKeyDeployItemName        = "deployitem-name"
 	LogKeyInstallationName      = "installation-name"
 	LogKeyCorrelationID         = "correlation-id"
+	LogKeyTraceID               = "trace-id"
 	LogKeyInterval              = "interval"
 	LogKeyConfigmap             = "configmap"
 	LogKeyResponseBody          = "response-body"
 	LogKeySecretName            = "secret-name"
 	LogKeyKappAppNamespacedName = "kappapp-name"
+	LogKeyPackageInstallName    = "packageinstall-name"
 
 	defaultNamespace = "hub"
 
 	requeueTimeoutBase = 10 * time.Second
 	requeueTimeoutMax  = 1 * time.Hour
 
+	nonRetryableDelayDefault = 6 * time.Hour
+
 	TextShootNotExisting = "shoot cluster does not exist"
 
 	APIVersionExtensionsV1beta1 = "extensions/v1beta1"
@@ -48,16 +52,37 @@ const (
 	KindDeployment  = "Deployment"
 	KindStatefulSet = "StatefulSet"
 	KindJob         = "Job"
+	KindNamespace   = "Namespace"
+
+	KindCustomResourceDefinition = "CustomResourceDefinition"
 
-	PurposeSecretValues = "secret-values"
-	PurposeDiExportData = "di-export-data"
+	PurposeSecretValues      = "secret-values"
+	PurposeDiExportData      = "di-export-data"
+	PurposePackageValues     = "package-values"
+	PurposePackageRepository = "package-repository"
+	PurposeKappSecretValues  = "kapp-secret-values"
+	PurposeSecretSyncSource  = "secretsync-source"
 
 	// annotations
 	AnnotationKeyReconcile   = "hub.k8s.sap.com/reconcile"
 	AnnotationValueReconcile = "reconcile"
 
+	AnnotationKeyResetRetries   = "potter.gardener.cloud/reset-retries"
+	AnnotationValueResetRetries = "reset"
+
+	// AnnotationKeyResume resumes the reconciliation of applications which are paused by a circuit breaker
+	AnnotationKeyResume   = "potter.gardener.cloud/resume"
+	AnnotationValueResume = "resume"
+
 	AnnotationKeyInstallationHash = "potter.gardener.cloud/installation-hash"
 
+	// AnnotationKeyRequester is set by the admission webhook to the user who created or changed the spec of a clusterbom
+	AnnotationKeyRequester = "potter.gardener.cloud/requester"
+
+	// AnnotationKeyAdmittedAt is set by the admission webhook to the time when it last admitted a clusterbom which
+	// references the annotated secret for secret values. Unreferenced secrets are deleted some time after it.
+	AnnotationKeyAdmittedAt = "potter.gardener.cloud/admitted-at"
+
 	AnnotationActionIgnoreKey = "potter.gardener.cloud/action-ignore"
 	Deactivate                = "deactivate"
 	Reactivate                = "reactivate"
@@ -72,8 +97,15 @@ const (
 	OperationInstall = "install"
 	OperationRemove  = "remove"
 
-	ConfigTypeHelm = "helm"
-	ConfigTypeKapp = "kapp"
+	ConfigTypeHelm       = "helm"
+	ConfigTypeKapp       = "kapp"
+	ConfigTypeManifest   = "manifest"
+	ConfigTypeKustomize  = "kustomize"
+	ConfigTypePackage    = "package"
+	ConfigTypeSecretSync = "secretsync"
+
+	// FieldManager is the field manager for server-side apply
+	FieldManager = "potter-controller"
 
 	HubControllerFinalizer = "hub-controller"
 
@@ -1,6 +1,8 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 
 	hubv1 "github.com/gardener/potter-controller/api/v1"
@@ -25,6 +27,19 @@ func CreateSecretName(clusterBomName, appConfigID string) string {
 	return clusterBomName + Separator + appConfigID + Separator + uniqueString
 }
 
+// CreateSecretNameForRequest returns a secret name which is derived from the uid of an admission request and the
+// logical name of the secret, so that the same request always results in the same name, e.g. if the admission webhook
+// is called repeatedly for it. The name must not be derived from the secret values, because everyone who can read the
+// clusterbom could then check guessed values against it. Without a request uid, a random name is returned.
+func CreateSecretNameForRequest(clusterBomName, appConfigID string, requestUID types.UID, logicalSecretName string) string {
+	if requestUID == "" {
+		return CreateSecretName(clusterBomName, appConfigID)
+	}
+
+	hash := sha256.Sum256([]byte(string(requestUID) + "\x00" + appConfigID + "\x00" + logicalSecretName))
+	return clusterBomName + Separator + appConfigID + Separator + hex.EncodeToString(hash[:16])
+}
+
 func GetClusterBomKeyFromDeployItemKey(deployItemKey *types.NamespacedName) *types.NamespacedName {
 	index := strings.LastIndex(deployItemKey.Name, DoubleSeparator)
 	if index == -1 {
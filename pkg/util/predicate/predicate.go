@@ -4,6 +4,7 @@ import (
 	hubv1 "github.com/gardener/potter-controller/api/v1"
 	"github.com/gardener/potter-controller/pkg/util"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
@@ -53,3 +54,61 @@ func (n *not) Update(e event.UpdateEvent) bool {
 func (n *not) Generic(e event.GenericEvent) bool {
 	return !n.p.Generic(e)
 }
+
+// SecretSyncSource selects the source secrets of config type secretsync. It is used with a metadata-only watch, so that
+// the content of the secrets is not cached. Therefore updates are selected if the label was added, or if the resource
+// version has changed, which includes changes of the content.
+func SecretSyncSource() predicate.Predicate {
+	return &secretSyncSource{}
+}
+
+type secretSyncSource struct{}
+
+func (s *secretSyncSource) Create(ev event.CreateEvent) bool {
+	return isSecretSyncSource(ev.Object)
+}
+
+func (s *secretSyncSource) Delete(ev event.DeleteEvent) bool {
+	return false
+}
+
+func (s *secretSyncSource) Update(ev event.UpdateEvent) bool {
+	if !isSecretSyncSource(ev.ObjectNew) {
+		return false
+	}
+
+	return !isSecretSyncSource(ev.ObjectOld) || ev.ObjectOld.GetResourceVersion() != ev.ObjectNew.GetResourceVersion()
+}
+
+func (s *secretSyncSource) Generic(ev event.GenericEvent) bool {
+	return false
+}
+
+func isSecretSyncSource(obj client.Object) bool {
+	return util.HasLabel(obj, hubv1.LabelPurpose, util.PurposeSecretSyncSource)
+}
+
+// KappAppRemoval selects the events of kapp apps which concern their removal: the deletion of an app, the start of its
+// deletion and changes of its generation. Status updates of kapp-controller are not selected.
+func KappAppRemoval() predicate.Predicate {
+	return &kappAppRemoval{}
+}
+
+type kappAppRemoval struct{}
+
+func (k *kappAppRemoval) Create(ev event.CreateEvent) bool {
+	return false
+}
+
+func (k *kappAppRemoval) Delete(ev event.DeleteEvent) bool {
+	return true
+}
+
+func (k *kappAppRemoval) Update(ev event.UpdateEvent) bool {
+	deletionStarted := ev.ObjectOld.GetDeletionTimestamp() == nil && ev.ObjectNew.GetDeletionTimestamp() != nil
+	return deletionStarted || ev.ObjectOld.GetGeneration() != ev.ObjectNew.GetGeneration()
+}
+
+func (k *kappAppRemoval) Generic(ev event.GenericEvent) bool {
+	return false
+}
@@ -72,10 +72,46 @@ func CalculateRequeueTimeout(numberOfTries int32) time.Duration {
 	return requeueTimeout
 }
 
-func CalculateRequeueDurationForPrematureRetry(lastOp *hubv1.LastOperation) (bool, *time.Duration) {
+// CalculateRequeueTimeoutForRetryPolicy computes an exponential backoff with the base and max delay of the retry policy.
+// Without retry policy, or if it specifies no delays, the result is the same as of CalculateRequeueTimeout.
+func CalculateRequeueTimeoutForRetryPolicy(numberOfTries int32, retryPolicy *hubv1.RetryPolicy) time.Duration {
+	if retryPolicy == nil || (retryPolicy.BaseDelay == nil && retryPolicy.MaxDelay == nil) {
+		return CalculateRequeueTimeout(numberOfTries)
+	}
+
+	baseDelay := requeueTimeoutBase
+	if retryPolicy.BaseDelay != nil && retryPolicy.BaseDelay.Duration > 0 {
+		baseDelay = retryPolicy.BaseDelay.Duration
+	}
+
+	maxDelay := requeueTimeoutMax
+	if retryPolicy.MaxDelay != nil && retryPolicy.MaxDelay.Duration > 0 {
+		maxDelay = retryPolicy.MaxDelay.Duration
+	}
+
+	requeueTimeout := baseDelay
+	for i := int32(0); i < numberOfTries && requeueTimeout < maxDelay; i++ {
+		requeueTimeout *= 2
+	}
+
+	if requeueTimeout > maxDelay {
+		return maxDelay
+	}
+	return requeueTimeout
+}
+
+// CalculateRequeueTimeoutForNonRetryableError returns the non retryable delay of the retry policy, or its default.
+func CalculateRequeueTimeoutForNonRetryableError(retryPolicy *hubv1.RetryPolicy) time.Duration {
+	if retryPolicy == nil || retryPolicy.NonRetryableDelay == nil || retryPolicy.NonRetryableDelay.Duration <= 0 {
+		return nonRetryableDelayDefault
+	}
+	return retryPolicy.NonRetryableDelay.Duration
+}
+
+func CalculateRequeueDurationForPrematureRetry(lastOp *hubv1.LastOperation, retryPolicy *hubv1.RetryPolicy) (bool, *time.Duration) {
 	lastTime := lastOp.Time.Time
 	currentTime := time.Now()
-	nextScheduledRun := lastTime.Add(CalculateRequeueTimeout(lastOp.NumberOfTries))
+	nextScheduledRun := lastTime.Add(CalculateRequeueTimeoutForRetryPolicy(lastOp.NumberOfTries, retryPolicy))
 
 	if currentTime.Before(nextScheduledRun) {
 		duration := nextScheduledRun.Sub(currentTime)
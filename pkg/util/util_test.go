@@ -11,6 +11,8 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+
+	hubv1 "github.com/gardener/potter-controller/api/v1"
 )
 
 func Test_DecodeBasicAuthCredentials_Successfully(t *testing.T) {
@@ -83,6 +85,91 @@ func Test_CalculateRequeueTimeout(t *testing.T) {
 	}
 }
 
+func Test_CalculateRequeueTimeoutForRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name                   string
+		numberOfTries          int32
+		retryPolicy            *hubv1.RetryPolicy
+		expectedRequeueTimeout time.Duration
+	}{
+		{
+			name:                   "no retry policy",
+			numberOfTries:          2,
+			retryPolicy:            nil,
+			expectedRequeueTimeout: 4 * requeueTimeoutBase,
+		},
+		{
+			name:                   "retry policy without delays",
+			numberOfTries:          10000,
+			retryPolicy:            &hubv1.RetryPolicy{MaxAttempts: 3},
+			expectedRequeueTimeout: requeueTimeoutMax,
+		},
+		{
+			name:                   "base delay",
+			numberOfTries:          3,
+			retryPolicy:            &hubv1.RetryPolicy{BaseDelay: &v1.Duration{Duration: time.Second}},
+			expectedRequeueTimeout: 8 * time.Second,
+		},
+		{
+			name:          "max delay",
+			numberOfTries: 3,
+			retryPolicy: &hubv1.RetryPolicy{
+				BaseDelay: &v1.Duration{Duration: time.Second},
+				MaxDelay:  &v1.Duration{Duration: 5 * time.Second},
+			},
+			expectedRequeueTimeout: 5 * time.Second,
+		},
+		{
+			name:          "number of tries greater than max",
+			numberOfTries: 10000,
+			retryPolicy: &hubv1.RetryPolicy{
+				MaxDelay: &v1.Duration{Duration: 2 * time.Minute},
+			},
+			expectedRequeueTimeout: 2 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			requeueTimeout := CalculateRequeueTimeoutForRetryPolicy(tt.numberOfTries, tt.retryPolicy)
+			assert.Equal(t, tt.expectedRequeueTimeout, requeueTimeout, "requeueTimeout")
+		})
+	}
+}
+
+func Test_CalculateRequeueTimeoutForNonRetryableError(t *testing.T) {
+	tests := []struct {
+		name                   string
+		retryPolicy            *hubv1.RetryPolicy
+		expectedRequeueTimeout time.Duration
+	}{
+		{
+			name:                   "no retry policy",
+			retryPolicy:            nil,
+			expectedRequeueTimeout: nonRetryableDelayDefault,
+		},
+		{
+			name:                   "retry policy without non retryable delay",
+			retryPolicy:            &hubv1.RetryPolicy{MaxDelay: &v1.Duration{Duration: 2 * time.Minute}},
+			expectedRequeueTimeout: nonRetryableDelayDefault,
+		},
+		{
+			name:                   "non retryable delay",
+			retryPolicy:            &hubv1.RetryPolicy{NonRetryableDelay: &v1.Duration{Duration: 30 * time.Minute}},
+			expectedRequeueTimeout: 30 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			requeueTimeout := CalculateRequeueTimeoutForNonRetryableError(tt.retryPolicy)
+			assert.Equal(t, tt.expectedRequeueTimeout, requeueTimeout, "requeueTimeout")
+		})
+	}
+}
+
 func TestWithBasicAuth(t *testing.T) {
 	tests := []struct {
 		name             string
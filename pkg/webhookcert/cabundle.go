@@ -0,0 +1,97 @@
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+// injectCABundle sets the caBundle of all webhooks of the configured webhook configurations
+func (m *Manager) injectCABundle(ctx context.Context, caBundle []byte) error {
+	for _, name := range m.config.WebhookConfigurations {
+		if err := m.injectCABundleInto(ctx, name, caBundle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// injectCABundleInto sets the caBundle of a mutating webhook configuration, or of a validating webhook configuration
+// if there is no mutating one with the name. A missing webhook configuration is skipped, because it might be deployed
+// after the controller. A kind of webhook configuration which the controller may not read is treated as missing, so
+// that the controller still starts if it only has the permissions for the kind of its webhook configurations.
+func (m *Manager) injectCABundleInto(ctx context.Context, name string, caBundle []byte) error {
+	key := types.NamespacedName{Name: name}
+
+	mutatingConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := m.webhookClient.GetUncached(ctx, key, mutatingConfig)
+	if err == nil {
+		changed := false
+		for i := range mutatingConfig.Webhooks {
+			changed = setCABundle(&mutatingConfig.Webhooks[i].ClientConfig, caBundle) || changed
+		}
+
+		return m.updateWebhookConfiguration(ctx, name, mutatingConfig, changed)
+	} else if !isNotFoundOrForbidden(err) {
+		return errors.Wrapf(err, "could not read mutating webhook configuration %s", name)
+	}
+	m.logNotReadable(err, "mutating", name)
+
+	validatingConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err = m.webhookClient.GetUncached(ctx, key, validatingConfig)
+	if err == nil {
+		changed := false
+		for i := range validatingConfig.Webhooks {
+			changed = setCABundle(&validatingConfig.Webhooks[i].ClientConfig, caBundle) || changed
+		}
+
+		return m.updateWebhookConfiguration(ctx, name, validatingConfig, changed)
+	} else if !isNotFoundOrForbidden(err) {
+		return errors.Wrapf(err, "could not read validating webhook configuration %s", name)
+	}
+	m.logNotReadable(err, "validating", name)
+
+	m.log.V(util.LogLevelWarning).Info("Webhook configuration not found", "webhookConfiguration", name)
+	return nil
+}
+
+func (m *Manager) logNotReadable(err error, kind, name string) {
+	if apierrors.IsForbidden(err) {
+		m.log.V(util.LogLevelWarning).Info("No permission to read "+kind+" webhook configuration",
+			"webhookConfiguration", name, "error", err.Error())
+	}
+}
+
+func isNotFoundOrForbidden(err error) bool {
+	return apierrors.IsNotFound(err) || apierrors.IsForbidden(err)
+}
+
+func (m *Manager) updateWebhookConfiguration(ctx context.Context, name string, webhookConfig client.Object, changed bool) error {
+	if !changed {
+		return nil
+	}
+
+	if err := m.webhookClient.Update(ctx, webhookConfig); err != nil {
+		return errors.Wrapf(err, "could not update caBundle of webhook configuration %s", name)
+	}
+
+	m.log.V(util.LogLevelWarning).Info("Updated caBundle of webhook configuration", "webhookConfiguration", name)
+	return nil
+}
+
+func setCABundle(clientConfig *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	if bytes.Equal(clientConfig.CABundle, caBundle) {
+		return false
+	}
+
+	clientConfig.CABundle = caBundle
+	return true
+}
@@ -0,0 +1,178 @@
+package webhookcert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	pemTypeCertificate = "CERTIFICATE"
+	pemTypePrivateKey  = "EC PRIVATE KEY"
+
+	caCommonName = "potter-webhook-ca"
+
+	// backdate is subtracted from the start of the validity of new certificates to tolerate clock skew
+	backdate = 5 * time.Minute
+)
+
+// keyPair is a certificate with its private key, in parsed and in PEM encoded form
+type keyPair struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newCA(now time.Time, validity time.Duration) (*keyPair, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	return newKeyPair(template, nil)
+}
+
+func newServingCertificate(ca *keyPair, dnsNames []string, now time.Time, validity time.Duration) (*keyPair, error) {
+	if len(dnsNames) == 0 {
+		return nil, errors.New("no dns names for the serving certificate")
+	}
+
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-backdate),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	return newKeyPair(template, ca)
+}
+
+// newKeyPair creates a key and a certificate from the template, signed by the issuer, or self signed if the issuer is nil
+func newKeyPair(template *x509.Certificate, issuer *keyPair) (*keyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate serial number")
+	}
+	template.SerialNumber = serialNumber
+
+	parent := template
+	var signer crypto.Signer = key
+	if issuer != nil {
+		parent = issuer.cert
+		signer = issuer.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create certificate")
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal key")
+	}
+
+	return parseKeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: keyDER}))
+}
+
+func parseKeyPair(certPEM, keyPEM []byte) (*keyPair, error) {
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil || keyBlock.Type != pemTypePrivateKey {
+		return nil, errors.New("no private key found")
+	}
+
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse private key")
+	}
+
+	return &keyPair{
+		cert:    cert,
+		key:     key,
+		certPEM: certPEM,
+		keyPEM:  keyPEM,
+	}, nil
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != pemTypeCertificate {
+		return nil, errors.New("no certificate found")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse certificate")
+	}
+
+	return cert, nil
+}
+
+// needsRenewal returns whether the remaining validity of the certificate is less than a third of its total validity
+func needsRenewal(cert *x509.Certificate, now time.Time) bool {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return now.Add(validity / 3).After(cert.NotAfter)
+}
+
+// isIssuedFor returns whether the serving certificate is signed by the CA and valid for exactly the given dns names
+func isIssuedFor(cert *x509.Certificate, ca *x509.Certificate, dnsNames []string) bool {
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return false
+	}
+
+	if len(cert.DNSNames) != len(dnsNames) {
+		return false
+	}
+
+	for i := range dnsNames {
+		if cert.DNSNames[i] != dnsNames[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// buildCABundle concatenates the PEM encoded CA certificates
+func buildCABundle(caCertPEMs ...[]byte) []byte {
+	var buffer bytes.Buffer
+	for _, caCertPEM := range caCertPEMs {
+		if len(caCertPEM) > 0 {
+			buffer.Write(caCertPEM)
+		}
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,262 @@
+// Package webhookcert manages the serving certificate of the admission webhook without an external cert-manager. The
+// controller creates its own CA and a serving certificate signed by it, stores both in a secret, so that all replicas
+// share them, renews them before they expire, and writes the CA into the caBundle of the webhook configurations.
+package webhookcert
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/gardener/potter-controller/pkg/metrics"
+	"github.com/gardener/potter-controller/pkg/synchronize"
+	"github.com/gardener/potter-controller/pkg/util"
+)
+
+// keys of the certificate secret in addition to tls.crt and tls.key
+const (
+	keyCACert         = "ca.crt"
+	keyCAKey          = "ca.key"
+	keyPreviousCACert = "ca-previous.crt"
+)
+
+const (
+	defaultCAValidity    = 2 * 365 * 24 * time.Hour
+	defaultCertValidity  = 90 * 24 * time.Hour
+	defaultCheckInterval = 10 * time.Minute
+)
+
+type Config struct {
+	// Namespace and SecretName identify the secret in which the certificates are stored
+	Namespace  string
+	SecretName string
+	// DNSNames are the names of the webhook server in the serving certificate
+	DNSNames []string
+	// WebhookConfigurations are the names of the mutating or validating webhook configurations whose caBundle is set
+	WebhookConfigurations []string
+}
+
+// Manager keeps the certificates in the secret valid and provides the current serving certificate to the webhook
+// server. It checks the secret periodically, so that every replica loads a certificate renewed by another replica.
+// The Manager must be added to the manager, which starts the periodic check.
+type Manager struct {
+	secretClient  synchronize.UncachedClient
+	webhookClient synchronize.UncachedClient
+	config        Config
+	log           logr.Logger
+
+	now           func() time.Time
+	caValidity    time.Duration
+	certValidity  time.Duration
+	checkInterval time.Duration
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+}
+
+// NewManager creates a Manager which stores the certificates with the secretClient, and sets the caBundle of the
+// webhook configurations with the webhookClient. Both clients differ if the webhook configurations are in another
+// cluster than the controller.
+func NewManager(secretClient, webhookClient synchronize.UncachedClient, config *Config, log logr.Logger) *Manager {
+	return &Manager{
+		secretClient:  secretClient,
+		webhookClient: webhookClient,
+		config:        *config,
+		log:           log,
+		now:           time.Now,
+		caValidity:    defaultCAValidity,
+		certValidity:  defaultCertValidity,
+		checkInterval: defaultCheckInterval,
+	}
+}
+
+// GetCertificate returns the current serving certificate. It is used in the tls.Config of the webhook server, so that
+// new connections use a renewed certificate without a restart of the server.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.certificate == nil {
+		return nil, errors.New("serving certificate of the webhook not yet loaded")
+	}
+
+	return m.certificate, nil
+}
+
+// Start checks the certificates periodically until the context is done. It implements manager.Runnable.
+func (m *Manager) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.Reconcile(ctx); err != nil {
+				m.log.Error(err, "Error checking webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, because every replica serves the webhook and must load the certificates.
+func (m *Manager) NeedLeaderElection() bool {
+	return false
+}
+
+// Reconcile creates or renews the certificates in the secret if necessary, sets the caBundle of the webhook
+// configurations, and loads the serving certificate. The serving certificate is only replaced after the caBundle was
+// set, so that the API server never receives a certificate whose CA it does not yet trust.
+func (m *Manager) Reconcile(ctx context.Context) error {
+	var secret *corev1.Secret
+	err := retry.OnError(retry.DefaultRetry, isConcurrentChange, func() error {
+		var err error
+		secret, err = m.ensureSecret(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	serving, err := parseKeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return errors.Wrap(err, "could not load serving certificate")
+	}
+
+	certificate, err := tls.X509KeyPair(serving.certPEM, serving.keyPEM)
+	if err != nil {
+		return errors.Wrap(err, "could not load serving certificate")
+	}
+	certificate.Leaf = serving.cert
+
+	ca, err := parseCertificate(secret.Data[keyCACert])
+	if err != nil {
+		return errors.Wrap(err, "could not load CA certificate")
+	}
+
+	if err = m.injectCABundle(ctx, buildCABundle(secret.Data[keyCACert], secret.Data[keyPreviousCACert])); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.certificate = &certificate
+	m.mutex.Unlock()
+
+	metrics.SetWebhookCertificateExpiry(metrics.CertificateCA, ca.NotAfter)
+	metrics.SetWebhookCertificateExpiry(metrics.CertificateServing, serving.cert.NotAfter)
+
+	return nil
+}
+
+// ensureSecret reads the secret with the certificates and creates or updates it if the certificates are missing or
+// must be renewed
+func (m *Manager) ensureSecret(ctx context.Context) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	secretKey := types.NamespacedName{Namespace: m.config.Namespace, Name: m.config.SecretName}
+
+	err := m.secretClient.GetUncached(ctx, secretKey, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: m.config.Namespace,
+				Name:      m.config.SecretName,
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+
+		if _, err = m.updateSecretData(secret); err != nil {
+			return nil, err
+		}
+
+		if err = m.secretClient.Create(ctx, secret); err != nil {
+			return nil, errors.Wrapf(err, "could not create certificate secret %s", secretKey)
+		}
+
+		m.log.V(util.LogLevelWarning).Info("Created webhook certificates", util.LogKeySecretName, secretKey)
+		return secret, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "could not read certificate secret %s", secretKey)
+	}
+
+	changed, err := m.updateSecretData(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		if err = m.secretClient.Update(ctx, secret); err != nil {
+			return nil, errors.Wrapf(err, "could not update certificate secret %s", secretKey)
+		}
+
+		m.log.V(util.LogLevelWarning).Info("Renewed webhook certificates", util.LogKeySecretName, secretKey)
+	}
+
+	return secret, nil
+}
+
+// updateSecretData creates a new CA if it is missing or must be renewed, and a new serving certificate if it is
+// missing, must be renewed, or does not match the CA or the dns names. A replaced CA remains in the caBundle until it
+// expires, so that the API server trusts the serving certificates of replicas which have not yet loaded the new one.
+// Returns whether the data were changed.
+func (m *Manager) updateSecretData(secret *corev1.Secret) (bool, error) {
+	now := m.now()
+	changed := false
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	ca, err := parseKeyPair(secret.Data[keyCACert], secret.Data[keyCAKey])
+	if err != nil || needsRenewal(ca.cert, now) {
+		if err == nil {
+			secret.Data[keyPreviousCACert] = ca.certPEM
+		}
+
+		ca, err = newCA(now, m.caValidity)
+		if err != nil {
+			return false, errors.Wrap(err, "could not create CA")
+		}
+
+		secret.Data[keyCACert] = ca.certPEM
+		secret.Data[keyCAKey] = ca.keyPEM
+		changed = true
+	}
+
+	if previousCACertPEM, ok := secret.Data[keyPreviousCACert]; ok {
+		previousCACert, err := parseCertificate(previousCACertPEM)
+		if err != nil || now.After(previousCACert.NotAfter) {
+			delete(secret.Data, keyPreviousCACert)
+			changed = true
+		}
+	}
+
+	serving, err := parseKeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil || needsRenewal(serving.cert, now) || !isIssuedFor(serving.cert, ca.cert, m.config.DNSNames) {
+		serving, err = newServingCertificate(ca, m.config.DNSNames, now, m.certValidity)
+		if err != nil {
+			return false, errors.Wrap(err, "could not create serving certificate")
+		}
+
+		secret.Data[corev1.TLSCertKey] = serving.certPEM
+		secret.Data[corev1.TLSPrivateKeyKey] = serving.keyPEM
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// isConcurrentChange returns whether the secret was created or changed by another replica at the same time
+func isConcurrentChange(err error) bool {
+	cause := errors.Cause(err)
+	return apierrors.IsConflict(cause) || apierrors.IsAlreadyExists(cause)
+}
@@ -0,0 +1,329 @@
+package webhookcert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testNamespace      = "potter"
+	testSecretName     = "potter-webhook-certs"
+	testMutatingName   = "clusterbomadmission.hub.k8s.sap.com"
+	testValidatingName = "validation.hub.k8s.sap.com"
+)
+
+var testDNSNames = []string{"potter.potter.svc", "potter.example.com"}
+
+func TestReconcileCreatesCertificates(t *testing.T) {
+	m, cl := newTestManager(t)
+	ctx := context.Background()
+
+	err := m.Reconcile(ctx)
+	assert.NoErr(t, err)
+
+	secret := getSecret(t, cl)
+	for _, key := range []string{keyCACert, keyCAKey, corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		assert.True(t, len(secret.Data[key]) > 0, "secret data "+key)
+	}
+	_, ok := secret.Data[keyPreviousCACert]
+	assert.False(t, ok, "previous CA")
+
+	certificate, err := m.GetCertificate(nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, certificate.Leaf.DNSNames, testDNSNames, "dns names")
+
+	mutatingConfig, validatingConfig := getWebhookConfigurations(t, cl)
+	for _, caBundle := range [][]byte{
+		mutatingConfig.Webhooks[0].ClientConfig.CABundle,
+		mutatingConfig.Webhooks[1].ClientConfig.CABundle,
+		validatingConfig.Webhooks[0].ClientConfig.CABundle,
+	} {
+		assert.Equal(t, string(caBundle), string(secret.Data[keyCACert]), "caBundle")
+		assertVerifies(t, certificate.Leaf, caBundle)
+	}
+
+	// a second replica loads the same certificate
+	other := NewManager(cl, cl, &m.config, m.log)
+	err = other.Reconcile(ctx)
+	assert.NoErr(t, err)
+	otherCertificate, err := other.GetCertificate(nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, otherCertificate.Leaf.SerialNumber, certificate.Leaf.SerialNumber, "serial number")
+}
+
+func TestReconcileRenewsCertificates(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		elapsed            time.Duration
+		dnsNames           []string
+		expectedNewCA      bool
+		expectedNewServing bool
+	}{
+		{
+			name:    "valid certificates",
+			elapsed: 30 * 24 * time.Hour,
+		},
+		{
+			name:               "serving certificate expires soon",
+			elapsed:            70 * 24 * time.Hour,
+			expectedNewServing: true,
+		},
+		{
+			name:               "dns names changed",
+			elapsed:            time.Hour,
+			dnsNames:           []string{"potter.potter.svc"},
+			expectedNewServing: true,
+		},
+		{
+			name:               "CA expires soon",
+			elapsed:            500 * 24 * time.Hour,
+			expectedNewCA:      true,
+			expectedNewServing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, cl := newTestManager(t)
+			ctx := context.Background()
+
+			m.now = func() time.Time { return start }
+			err := m.Reconcile(ctx)
+			assert.NoErr(t, err)
+			oldSecret := getSecret(t, cl)
+			oldCertificate, _ := m.GetCertificate(nil)
+
+			m.now = func() time.Time { return start.Add(tt.elapsed) }
+			if tt.dnsNames != nil {
+				m.config.DNSNames = tt.dnsNames
+			}
+			err = m.Reconcile(ctx)
+			assert.NoErr(t, err)
+			secret := getSecret(t, cl)
+			certificate, _ := m.GetCertificate(nil)
+
+			assert.Equal(t, string(secret.Data[keyCACert]) != string(oldSecret.Data[keyCACert]), tt.expectedNewCA, "new CA")
+			assert.Equal(t, certificate.Leaf.SerialNumber.Cmp(oldCertificate.Leaf.SerialNumber) != 0, tt.expectedNewServing, "new serving certificate")
+
+			mutatingConfig, _ := getWebhookConfigurations(t, cl)
+			caBundle := mutatingConfig.Webhooks[0].ClientConfig.CABundle
+			assertVerifies(t, certificate.Leaf, caBundle)
+			if tt.expectedNewCA {
+				// the old CA remains trusted for replicas which have not yet loaded the new certificate
+				assert.Equal(t, string(secret.Data[keyPreviousCACert]), string(oldSecret.Data[keyCACert]), "previous CA")
+				assertVerifies(t, oldCertificate.Leaf, caBundle)
+			}
+		})
+	}
+}
+
+// TestServerUsesRenewedCertificate tests that a TLS server with GetCertificate of the manager uses a renewed
+// certificate for new connections without a restart.
+func TestServerUsesRenewedCertificate(t *testing.T) {
+	m, cl := newTestManager(t)
+	ctx := context.Background()
+	m.config.DNSNames = []string{"webhook-1.potter.svc"}
+
+	err := m.Reconcile(ctx)
+	assert.NoErr(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{GetCertificate: m.GetCertificate, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	firstSerial := getServerCertificate(t, cl, server.Listener.Addr().String(), "webhook-1.potter.svc").SerialNumber
+
+	m.config.DNSNames = []string{"webhook-2.potter.svc"}
+	err = m.Reconcile(ctx)
+	assert.NoErr(t, err)
+
+	secondCertificate := getServerCertificate(t, cl, server.Listener.Addr().String(), "webhook-2.potter.svc")
+	assert.True(t, secondCertificate.SerialNumber.Cmp(firstSerial) != 0, "renewed certificate served")
+	assert.Equal(t, secondCertificate.DNSNames, []string{"webhook-2.potter.svc"}, "dns names")
+}
+
+func TestGetCertificateBeforeReconcile(t *testing.T) {
+	m, _ := newTestManager(t)
+	_, err := m.GetCertificate(nil)
+	assert.NotNil(t, err, "error")
+}
+
+func TestReconcileWithoutDNSNames(t *testing.T) {
+	m, _ := newTestManager(t)
+	m.config.DNSNames = nil
+	err := m.Reconcile(context.Background())
+	assert.NotNil(t, err, "error")
+}
+
+// TestReconcileWithoutPermissionForValidatingConfigurations tests that a webhook configuration kind which the controller
+// may not read is skipped
+func TestReconcileWithoutPermissionForValidatingConfigurations(t *testing.T) {
+	m, cl := newTestManager(t)
+	m.webhookClient = &restrictedWebhookClient{uncachedFakeClient: cl, forbidValidatingGet: true}
+
+	err := m.Reconcile(context.Background())
+	assert.NoErr(t, err)
+
+	mutatingConfig, validatingConfig := getWebhookConfigurations(t, cl)
+	assert.True(t, len(mutatingConfig.Webhooks[0].ClientConfig.CABundle) > 0, "caBundle of mutating webhook configuration")
+	assert.Equal(t, len(validatingConfig.Webhooks[0].ClientConfig.CABundle), 0, "caBundle of validating webhook configuration")
+}
+
+// TestReconcileKeepsCertificateIfCABundleFails tests that a renewed serving certificate is not used before the caBundle
+// with its CA is set
+func TestReconcileKeepsCertificateIfCABundleFails(t *testing.T) {
+	m, cl := newTestManager(t)
+	ctx := context.Background()
+
+	err := m.Reconcile(ctx)
+	assert.NoErr(t, err)
+	oldCertificate, _ := m.GetCertificate(nil)
+
+	// the CA is renewed, but the webhook configurations cannot be updated
+	restrictedClient := &restrictedWebhookClient{uncachedFakeClient: cl, forbidUpdate: true}
+	m.webhookClient = restrictedClient
+	m.now = func() time.Time { return time.Now().Add(500 * 24 * time.Hour) }
+
+	err = m.Reconcile(ctx)
+	assert.NotNil(t, err, "error")
+	certificate, _ := m.GetCertificate(nil)
+	assert.Equal(t, certificate.Leaf.SerialNumber, oldCertificate.Leaf.SerialNumber, "serial number")
+
+	restrictedClient.forbidUpdate = false
+	err = m.Reconcile(ctx)
+	assert.NoErr(t, err)
+	certificate, _ = m.GetCertificate(nil)
+	assert.True(t, certificate.Leaf.SerialNumber.Cmp(oldCertificate.Leaf.SerialNumber) != 0, "new serving certificate")
+}
+
+// uncachedFakeClient provides the fake client as synchronize.UncachedClient
+type uncachedFakeClient struct {
+	client.Client
+}
+
+func (c *uncachedFakeClient) GetUncached(ctx context.Context, key types.NamespacedName, obj client.Object) error {
+	return c.Get(ctx, key, obj)
+}
+
+func (c *uncachedFakeClient) ListUncached(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.List(ctx, list, opts...)
+}
+
+// restrictedWebhookClient simulates missing permissions for the webhook configurations
+type restrictedWebhookClient struct {
+	*uncachedFakeClient
+	forbidValidatingGet bool
+	forbidUpdate        bool
+}
+
+func (c *restrictedWebhookClient) GetUncached(ctx context.Context, key types.NamespacedName, obj client.Object) error {
+	if _, ok := obj.(*admissionregistrationv1.ValidatingWebhookConfiguration); ok && c.forbidValidatingGet {
+		return apierrors.NewForbidden(admissionregistrationv1.Resource("validatingwebhookconfigurations"), key.Name, nil)
+	}
+	return c.uncachedFakeClient.GetUncached(ctx, key, obj)
+}
+
+func (c *restrictedWebhookClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.forbidUpdate {
+		return apierrors.NewForbidden(admissionregistrationv1.Resource("webhookconfigurations"), obj.GetName(), nil)
+	}
+	return c.uncachedFakeClient.Update(ctx, obj, opts...)
+}
+
+func newTestManager(t *testing.T) (*Manager, *uncachedFakeClient) {
+	scheme := runtime.NewScheme()
+	err := clientgoscheme.AddToScheme(scheme)
+	assert.NoErr(t, err)
+
+	mutatingConfig := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: testMutatingName},
+		Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "first"}, {Name: "second"}},
+	}
+	validatingConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: testValidatingName},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "first"}},
+	}
+
+	cl := &uncachedFakeClient{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(mutatingConfig, validatingConfig).Build(),
+	}
+
+	m := NewManager(cl, cl, &Config{
+		Namespace:             testNamespace,
+		SecretName:            testSecretName,
+		DNSNames:              testDNSNames,
+		WebhookConfigurations: []string{testMutatingName, testValidatingName, "missing"},
+	}, ctrl.Log.WithName("webhookcert test"))
+
+	return m, cl
+}
+
+func getSecret(t *testing.T, cl *uncachedFakeClient) *corev1.Secret {
+	secret := &corev1.Secret{}
+	err := cl.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: testSecretName}, secret)
+	assert.NoErr(t, err)
+	return secret
+}
+
+func getWebhookConfigurations(t *testing.T, cl *uncachedFakeClient) (*admissionregistrationv1.MutatingWebhookConfiguration,
+	*admissionregistrationv1.ValidatingWebhookConfiguration) {
+	mutatingConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := cl.Get(context.Background(), types.NamespacedName{Name: testMutatingName}, mutatingConfig)
+	assert.NoErr(t, err)
+
+	validatingConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err = cl.Get(context.Background(), types.NamespacedName{Name: testValidatingName}, validatingConfig)
+	assert.NoErr(t, err)
+
+	return mutatingConfig, validatingConfig
+}
+
+func assertVerifies(t *testing.T, cert *x509.Certificate, caBundle []byte) {
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(caBundle), "caBundle contains certificates")
+
+	_, err := cert.Verify(x509.VerifyOptions{
+		DNSName:     cert.DNSNames[0],
+		Roots:       roots,
+		CurrentTime: cert.NotBefore.Add(time.Hour),
+	})
+	assert.NoErr(t, err)
+}
+
+// getServerCertificate connects to the server and verifies its certificate with the caBundle of the webhook
+// configuration
+func getServerCertificate(t *testing.T, cl *uncachedFakeClient, addr, serverName string) *x509.Certificate {
+	mutatingConfig, _ := getWebhookConfigurations(t, cl)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(mutatingConfig.Webhooks[0].ClientConfig.CABundle)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:    roots,
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	})
+	assert.NoErr(t, err)
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0]
+}